@@ -47,6 +47,11 @@ func buildAgentConfig() (agent.AgentConfig, error) {
 		}
 	}
 
+	// Override the provider if provided
+	if agentModelClass != "" {
+		modelConfig.Class = agentModelClass
+	}
+
 	// Merge system prompts from config file and command-line
 	if agentSystemPrompt != "" {
 		// Join system prompts from config with command-line system prompt
@@ -72,11 +77,9 @@ func buildAgentConfig() (agent.AgentConfig, error) {
 		modelConfig.APIURL = agentOpenAIApiURL
 	}
 
-	// Handle environment variable substitution for API key
-	switch modelConfig.APIKey {
-	case "${OPENAI_API_KEY}":
-		// Handle environment variable substitution
-		modelConfig.APIKey = os.Getenv("OPENAI_API_KEY")
+	// Handle environment variable substitution for API key (ie, "${ANTHROPIC_API_KEY}")
+	if strings.HasPrefix(modelConfig.APIKey, "${") && strings.HasSuffix(modelConfig.APIKey, "}") {
+		modelConfig.APIKey = os.Getenv(strings.TrimSuffix(strings.TrimPrefix(modelConfig.APIKey, "${"), "}"))
 	}
 
 	// Resolve multiple config files into a single merged config file
@@ -94,6 +97,8 @@ func buildAgentConfig() (agent.AgentConfig, error) {
 		ToolsFile:   localConfigPath,
 		UserPrompt:  agentUserPrompt,
 		Once:        agentOnce,
+		Resume:      agentResume,
+		Limits:      config.Agent.Limits.Merge(agentLimits),
 		Version:     version,
 		ModelConfig: modelConfig,
 	}, nil
@@ -117,6 +122,12 @@ $ mcpshell agent --tools=examples/config.yaml \
      --system-prompt "You are a helpful assistant that debugs performance issues" \
      --user-prompt "I am having trouble with my computer. It is slow and I think it is due to the CPU usage."
 
+Other providers can be selected with --provider ("openai", "anthropic" or "ollama"):
+
+$ ANTHROPIC_API_KEY=... mcpshell agent --tools=examples/config.yaml \
+     --provider anthropic --model "claude-sonnet-4-5" \
+     --user-prompt "Why is my disk full?"
+
 If a model is configured as default in the agent configuration file, you can omit the --model flag:
 
 $ mcpshell agent --tools=examples/config.yaml \
@@ -127,6 +138,10 @@ You can also provide the initial user prompt as positional arguments:
 $ mcpshell agent I am having trouble with my computer. It is slow and I think it is due to the CPU usage.
 
 The agent will try to debug the issue with the given tools.
+
+Conversations are saved in ~/.mcpshell/conversations, and they can be continued later:
+
+$ mcpshell agent --tools=examples/config.yaml --resume 1a2b3c4d5e6f
 `,
 	Args: cobra.ArbitraryArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -274,12 +289,23 @@ func init() {
 
 	// Add agent-specific flags
 	agentCommand.Flags().StringVarP(&agentModel, "model", "m", "", "LLM model to use (required)")
+	agentCommand.Flags().StringVar(&agentModelClass, "provider", "", "LLM provider of the model: openai, anthropic or ollama (default: openai)")
 	agentCommand.Flags().StringVarP(&agentSystemPrompt, "system-prompt", "s", "You are a helpful assistant.", "System prompt for the LLM")
 	agentCommand.Flags().StringVarP(&agentUserPrompt, "user-prompt", "u", "", "Initial user prompt for the LLM")
-	agentCommand.Flags().StringVarP(&agentOpenAIApiKey, "openai-api-key", "k", "", "OpenAI API key (or set OPENAI_API_KEY environment variable)")
+	agentCommand.Flags().StringVarP(&agentOpenAIApiKey, "openai-api-key", "k", "", "API key for the LLM provider (defaults to ANTHROPIC_API_KEY for anthropic models)")
 	agentCommand.Flags().StringVarP(&agentOpenAIApiURL, "openai-api-url", "b", "", "Base URL for the OpenAI API (optional)")
 	agentCommand.Flags().BoolVarP(&agentOnce, "once", "o", false, "Exit after receiving a final response from the LLM (one-shot mode)")
+	agentCommand.Flags().IntVar(&agentLimits.MaxToolCallsPerTurn, "max-tool-calls-per-turn", 0, "Maximum number of tool calls executed for a single LLM response (0 for no limit)")
+	agentCommand.Flags().IntVar(&agentLimits.MaxToolCalls, "max-tool-calls", 0, "Maximum number of tool calls executed in total (0 for no limit)")
+	agentCommand.Flags().StringVar(&agentLimits.MaxTime, "max-time", "", "Maximum wall time of the agent (ie, 10m)")
+	agentCommand.Flags().Float64Var(&agentLimits.MaxCost, "max-cost", 0, "Maximum cost of the LLM calls, using the pricing of the model (0 for no limit)")
+	agentCommand.Flags().StringVar(&agentResume, "resume", "", "Resume a previous conversation with the given ID")
+
+	_ = agentCommand.RegisterFlagCompletionFunc("provider", cobra.FixedCompletions(
+		[]string{"openai", "anthropic", "ollama"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = agentCommand.RegisterFlagCompletionFunc("resume", completeConversations)
 
 	// Add config subcommand
 	agentCommand.AddCommand(agentConfigCommand)
+	agentCommand.AddCommand(agentConversationsCommand)
 }
@@ -3,6 +3,7 @@ package root
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -123,7 +124,7 @@ $ mcpshell agent config show
 			fmt.Printf("  Default: %t\n", model.Default)
 
 			if model.APIKey != "" {
-				if model.APIKey == "${OPENAI_API_KEY}" {
+				if strings.HasPrefix(model.APIKey, "${") {
 					fmt.Printf("  API Key: %s (from environment)\n", model.APIKey)
 				} else {
 					fmt.Printf("  API Key: %s\n", maskAPIKey(model.APIKey))
@@ -0,0 +1,100 @@
+package root
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/agent"
+)
+
+// agentConversationsCommand lists the saved agent conversations
+var agentConversationsCommand = &cobra.Command{
+	Use:   "conversations",
+	Short: "List the saved agent conversations",
+	Long: `
+
+Lists the conversations of the agent saved in ~/.mcpshell/conversations,
+the most recent first. Any of them can be continued with --resume <id>.
+
+Example:
+$ mcpshell agent conversations
+$ mcpshell agent conversations show 1a2b3c4d5e6f
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		conversations, err := agent.ListConversations()
+		if err != nil {
+			logger.Error("Failed to list conversations: %v", err)
+			return fmt.Errorf("failed to list conversations: %w", err)
+		}
+
+		if len(conversations) == 0 {
+			fmt.Println("No conversations found.")
+			return nil
+		}
+
+		for _, conv := range conversations {
+			summary := strings.Join(strings.Fields(conv.Summary()), " ")
+			fmt.Printf("%s  %s  %-20s  %s\n", conv.ID, conv.Updated.Format("2006-01-02 15:04"),
+				truncateString(conv.Model, 20), truncateString(summary, 60))
+		}
+
+		return nil
+	},
+}
+
+// agentConversationsShowCommand displays all the messages of a conversation
+var agentConversationsShowCommand = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Display all the messages of a conversation",
+	Long: `
+
+Displays all the messages of a saved conversation, including the calls
+to tools and their results.
+
+Example:
+$ mcpshell agent conversations show 1a2b3c4d5e6f
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConversationID,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		conv, err := agent.LoadConversation(args[0])
+		if err != nil {
+			logger.Error("Failed to load conversation: %v", err)
+			return err
+		}
+
+		fmt.Printf("Conversation: %s\n", conv.ID)
+		fmt.Printf("Model: %s\n", conv.Model)
+		fmt.Printf("Created: %s\n", conv.Created.Format("2006-01-02 15:04:05"))
+		fmt.Printf("Updated: %s\n", conv.Updated.Format("2006-01-02 15:04:05"))
+
+		for _, msg := range conv.Messages {
+			fmt.Println()
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				fmt.Printf("[%s] %s\n", msg.Role, msg.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				fmt.Printf("[%s] call %s(%s)\n", msg.Role, call.Function.Name, call.Function.Arguments)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	agentConversationsCommand.AddCommand(agentConversationsShowCommand)
+}
@@ -0,0 +1,257 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+var (
+	// benchParams are the parameters of the tool calls, as "name=value"
+	benchParams []string
+
+	// benchCalls is the number of calls
+	benchCalls int
+
+	// benchConcurrency is the number of calls in progress at the same time
+	benchConcurrency int
+)
+
+// benchPercentiles are the percentiles of the latencies reported
+var benchPercentiles = []float64{50, 90, 95, 99}
+
+// benchCommand measures the latency of a tool
+var benchCommand = &cobra.Command{
+	Use:   "bench <tool>",
+	Short: "Measure the latency of a MCP tool",
+	Long: `
+Measure the latency of a MCP tool, calling it several times.
+
+The tool is called with the parameters given, through the same pipeline as the
+calls of the clients (constraints, runners, output processing...), and the
+percentiles of the latencies, the failure rate and the sizes of the outputs are
+reported. It helps tuning the timeouts and the concurrency limits with real numbers.
+
+For example:
+
+$ mcpshell bench --tools examples/config.yaml hello_world --params name=John --n 50 --concurrency 5
+`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		// Check if config file is provided
+		if len(toolsFiles) == 0 {
+			logger.Error("Tools configuration file(s) are required")
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+		if benchCalls < 1 || benchConcurrency < 1 {
+			return fmt.Errorf("the number of calls and the concurrency must be positive")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := common.GetLogger()
+		defer common.RecoverPanic()
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		report, err := benchTool(localConfigPath, args[0], benchParams, benchCalls, benchConcurrency, logger)
+		if err != nil {
+			return err
+		}
+		report.print(cmd.OutOrStdout())
+		return nil
+	},
+}
+
+// benchReport is the result of measuring the latency of a tool
+type benchReport struct {
+	tool        string
+	params      map[string]interface{}
+	concurrency int
+	elapsed     time.Duration   // the time taken by all the calls
+	latencies   []time.Duration // the latencies of the calls, sorted
+	sizes       []int           // the sizes of the outputs of the calls, sorted
+	failures    int
+	errors      map[string]int // the number of calls failed with every error
+}
+
+// benchTool calls a tool several times, with some concurrency, measuring the latencies
+// of the calls and the sizes of their outputs
+func benchTool(configFile string, toolName string, paramArgs []string, calls int, concurrency int, logger *common.Logger) (*benchReport, error) {
+	cfg, err := config.NewConfigFromFile(configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration: %v", err)
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	tool, err := findToolConfig(cfg, toolName)
+	if err != nil {
+		logger.Error("%v", err)
+		return nil, err
+	}
+	params, err := parseParamArgs(tool, paramArgs)
+	if err != nil {
+		logger.Error("%v", err)
+		return nil, err
+	}
+
+	srv := server.New(server.Config{ConfigFile: configFile, Logger: logger, Version: version})
+	if err := srv.CreateServer(); err != nil {
+		logger.Error("Failed to create the server: %v", err)
+		return nil, fmt.Errorf("failed to create the server: %w", err)
+	}
+	defer srv.Close()
+
+	report := &benchReport{
+		tool:        toolName,
+		params:      params,
+		concurrency: min(concurrency, calls),
+		latencies:   make([]time.Duration, calls),
+		sizes:       make([]int, calls),
+		errors:      map[string]int{},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	pending := make(chan int, calls)
+	for i := 0; i < calls; i++ {
+		pending <- i
+	}
+	close(pending)
+
+	start := time.Now()
+	for w := 0; w < report.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				callStart := time.Now()
+				result, err := srv.CallTool(context.Background(), toolName, params)
+				latency := time.Since(callStart)
+
+				var output string
+				if result != nil {
+					output = benchOutput(result)
+				}
+
+				mu.Lock()
+				report.latencies[i] = latency
+				report.sizes[i] = len(output)
+				switch {
+				case err != nil:
+					report.failures++
+					report.errors[err.Error()]++
+				case result.IsError:
+					// the first line of the output, as the errors of the commands can be long
+					report.failures++
+					report.errors[strings.TrimSpace(strings.SplitN(output, "\n", 2)[0])]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	report.elapsed = time.Since(start)
+
+	slices.Sort(report.latencies)
+	slices.Sort(report.sizes)
+	return report, nil
+}
+
+// benchOutput returns the text of the output of a call
+func benchOutput(result *mcp.CallToolResult) string {
+	var output string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			output += text.Text
+		}
+	}
+	return output
+}
+
+// percentile returns a percentile of the latencies (with the nearest-rank method)
+func (r *benchReport) percentile(p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(r.latencies))))
+	return r.latencies[max(rank-1, 0)]
+}
+
+// print prints the report
+func (r *benchReport) print(w io.Writer) {
+	calls := len(r.latencies)
+	_, _ = fmt.Fprintf(w, "Tool:         %s (%s)\n", r.tool, formatParams(r.params))
+	_, _ = fmt.Fprintf(w, "Calls:        %d (concurrency %d) in %s, %.1f calls/s\n",
+		calls, r.concurrency, formatLatency(r.elapsed), float64(calls)/r.elapsed.Seconds())
+	_, _ = fmt.Fprintf(w, "Failures:     %d (%.1f%%)\n", r.failures, 100*float64(r.failures)/float64(calls))
+
+	_, _ = fmt.Fprintf(w, "Latency:      min %s", formatLatency(r.latencies[0]))
+	for _, p := range benchPercentiles {
+		_, _ = fmt.Fprintf(w, ", p%g %s", p, formatLatency(r.percentile(p)))
+	}
+	_, _ = fmt.Fprintf(w, ", max %s\n", formatLatency(r.latencies[calls-1]))
+
+	total := 0
+	for _, size := range r.sizes {
+		total += size
+	}
+	_, _ = fmt.Fprintf(w, "Output size:  min %d B, avg %d B, max %d B\n", r.sizes[0], total/calls, r.sizes[calls-1])
+
+	if len(r.errors) > 0 {
+		errors := make([]string, 0, len(r.errors))
+		for err := range r.errors {
+			errors = append(errors, err)
+		}
+		slices.Sort(errors)
+		_, _ = fmt.Fprintln(w, "Errors:")
+		for _, err := range errors {
+			_, _ = fmt.Fprintf(w, "  %d x %s\n", r.errors[err], err)
+		}
+	}
+}
+
+// formatLatency formats a latency with a precision that depends on its size
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// init adds the bench command to the root command
+func init() {
+	rootCmd.AddCommand(benchCommand)
+
+	benchCommand.Flags().StringArrayVarP(&benchParams, "params", "p", []string{}, "Parameter of the tool calls, as name=value (can be specified multiple times)")
+	benchCommand.Flags().IntVarP(&benchCalls, "n", "n", 10, "Number of calls")
+	benchCommand.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 1, "Number of calls in progress at the same time")
+	benchCommand.Flags().StringArrayVar(&config.Overrides, "set", []string{}, setFlagUsage)
+	_ = benchCommand.MarkFlagRequired("tools")
+
+	benchCommand.ValidArgsFunction = completeToolArgs
+}
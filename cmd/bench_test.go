@@ -0,0 +1,98 @@
+package root
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestBenchTool(t *testing.T) {
+	testLogger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "greet"
+      description: "Greet someone"
+      params:
+        name:
+          type: string
+          required: true
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "fail"
+      description: "Always fails"
+      run:
+        command: "echo something went wrong >&2; exit 1"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	report, err := benchTool(configFile, "greet", []string{"name=John"}, 6, 3, testLogger)
+	if err != nil {
+		t.Fatalf("benchTool() error = %v", err)
+	}
+	if len(report.latencies) != 6 || report.concurrency != 3 || report.failures != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.sizes[0] != len("hello John") || report.latencies[0] <= 0 || report.percentile(50) > report.latencies[5] {
+		t.Errorf("Unexpected measures: %+v", report)
+	}
+
+	var out bytes.Buffer
+	report.print(&out)
+	for _, expected := range []string{
+		"Tool:         greet (name=John)",
+		"Calls:        6 (concurrency 3)",
+		"Failures:     0 (0.0%)",
+		"Latency:      min ",
+		", p99 ",
+		"Output size:  min 10 B, avg 10 B, max 10 B",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the report:\n%s", expected, out.String())
+		}
+	}
+
+	// the failures are counted by error
+	report, err = benchTool(configFile, "fail", nil, 4, 10, testLogger)
+	if err != nil {
+		t.Fatalf("benchTool() error = %v", err)
+	}
+	out.Reset()
+	report.print(&out)
+	if report.failures != 4 || report.concurrency != 4 || !strings.Contains(out.String(), "4 x something went wrong") {
+		t.Errorf("Unexpected report of the failures:\n%s", out.String())
+	}
+
+	// the parameters must be defined in the tool
+	if _, err := benchTool(configFile, "greet", []string{"age=3"}, 1, 1, testLogger); err == nil {
+		t.Errorf("Expected an error with an unknown parameter")
+	}
+	if _, err := benchTool(configFile, "missing", nil, 1, 1, testLogger); err == nil {
+		t.Errorf("Expected an error with an unknown tool")
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := map[time.Duration]string{
+		1234567 * time.Nanosecond:  "1.2ms",
+		1234567 * time.Microsecond: "1.235s",
+		12345 * time.Nanosecond:    "12µs",
+		250 * time.Millisecond:     "250ms",
+	}
+	for d, expected := range tests {
+		if got := formatLatency(d); got != expected {
+			t.Errorf("formatLatency(%v) = %q, want %q", d, got, expected)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package root
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/agent"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// loadCompletionConfig loads the tools configuration given with --tools for
+// completing the command line, returning nil when it cannot be loaded.
+// Nothing is logged, as the output would be mixed with the completions.
+func loadCompletionConfig() *config.ToolsConfig {
+	// flags are parsed more than once when completing, so the same files can be repeated
+	var files []string
+	for _, file := range toolsFiles {
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		return nil
+	}
+	common.SetLogger(logger)
+
+	localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(files, logger)
+	if err != nil {
+		return nil
+	}
+	defer cleanup()
+
+	cfg, err := config.NewConfigFromFile(localConfigPath)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// completeToolArgs completes the arguments of the commands receiving a tool
+// name followed by "name=value" parameters (ie, exe and describe)
+func completeToolArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if len(args) == 0 {
+		return toolNameCompletions(cfg.GetTools()), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	toolConfig, err := findToolConfig(cfg, args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return paramCompletions(toolConfig, args[1:], toComplete)
+}
+
+// toolNameCompletions returns the names (and aliases) of the tools, with their descriptions
+func toolNameCompletions(tools []config.Tool) []string {
+	var res []string
+	for _, tool := range tools {
+		for _, name := range tool.Config.Names() {
+			res = append(res, completion(name, tool.Config.Description))
+		}
+	}
+	return res
+}
+
+// paramCompletions returns the completions for a "name=value" parameter of a tool:
+// the names of the parameters not given yet, or the values of boolean parameters and
+// of parameters with enums
+func paramCompletions(toolConfig *config.MCPToolConfig, given []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if name, _, found := strings.Cut(toComplete, "="); found {
+		if param, exists := toolConfig.Params[name]; exists && param.Type == "boolean" {
+			return []string{name + "=true", name + "=false"}, cobra.ShellCompDirectiveNoFileComp
+		}
+		if param, exists := toolConfig.Params[name]; exists && param.HasEnum() {
+			values, _ := param.EnumValues()
+			var res []string
+			for _, value := range values {
+				res = append(res, name+"="+value)
+			}
+			return res, cobra.ShellCompDirectiveNoFileComp
+		}
+		// the values of other types are free
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var used []string
+	for _, arg := range given {
+		name, _, _ := strings.Cut(arg, "=")
+		used = append(used, name)
+	}
+
+	var res []string
+	for name, param := range toolConfig.Params {
+		if param.Hidden || slices.Contains(used, name) {
+			continue
+		}
+		res = append(res, completion(name+"=", param.Description))
+	}
+	slices.Sort(res)
+	return res, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTags completes the tags used in the tools
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var res []string
+	for _, tool := range cfg.GetTools() {
+		for _, tag := range tool.Config.Tags {
+			if !slices.Contains(res, tag) {
+				res = append(res, tag)
+			}
+		}
+	}
+	slices.Sort(res)
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeToolNames completes the names of the tools (ie, for the flags receiving tools)
+func completeToolNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadCompletionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return toolNameCompletions(cfg.GetTools()), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConversations completes the IDs of the saved agent conversations
+func completeConversations(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	conversations, err := agent.ListConversations()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var res []string
+	for _, conv := range conversations {
+		summary := strings.Join(strings.Fields(conv.Summary()), " ")
+		res = append(res, completion(conv.ID, truncateString(summary, 60)))
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConversationID completes the ID of a conversation, given as the only argument
+func completeConversationID(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeConversations(cmd, args, toComplete)
+}
+
+// completion returns a completion with the first line of a description (if any), as expected by cobra
+func completion(value, description string) string {
+	description, _, _ = strings.Cut(strings.TrimSpace(description), "\n")
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
@@ -0,0 +1,101 @@
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestToolNameCompletions(t *testing.T) {
+	tools := config.NewTools([]config.MCPToolConfig{
+		{
+			Name:        "git_log",
+			Aliases:     []string{"log"},
+			Description: "Show the git log\nfor a repository",
+			Run:         config.MCPToolRunConfig{Command: "git log"},
+		},
+	})
+
+	expected := []string{"git_log\tShow the git log", "log\tShow the git log"}
+	if got := toolNameCompletions(tools); !slices.Equal(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestParamCompletions(t *testing.T) {
+	playbooks := t.TempDir()
+	for _, name := range []string{"site.yaml", "db.yaml"} {
+		if err := os.WriteFile(filepath.Join(playbooks, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to write playbook: %v", err)
+		}
+	}
+
+	toolConfig := &config.MCPToolConfig{
+		Name: "deploy",
+		Params: map[string]common.ParamConfig{
+			"service":  {Type: "string", Description: "The service"},
+			"dry_run":  {Type: "boolean"},
+			"token":    {Type: "string", Hidden: true, Default: "secret"},
+			"playbook": {Type: "string", EnumGlob: filepath.Join(playbooks, "*.yaml")},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		given      []string
+		toComplete string
+		expected   []string
+		directive  cobra.ShellCompDirective
+	}{
+		{
+			name:      "parameter names",
+			expected:  []string{"dry_run=", "playbook=", "service=\tThe service"},
+			directive: cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:      "parameters already given are skipped",
+			given:     []string{"service=web"},
+			expected:  []string{"dry_run=", "playbook="},
+			directive: cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:       "boolean values",
+			toComplete: "dry_run=",
+			expected:   []string{"dry_run=true", "dry_run=false"},
+			directive:  cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:       "enum values",
+			toComplete: "playbook=",
+			expected: []string{
+				"playbook=" + filepath.Join(playbooks, "db.yaml"),
+				"playbook=" + filepath.Join(playbooks, "site.yaml"),
+			},
+			directive: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:       "free values",
+			toComplete: "service=",
+			expected:   nil,
+			directive:  cobra.ShellCompDirectiveNoFileComp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, directive := paramCompletions(toolConfig, tt.given, tt.toComplete)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if directive != tt.directive {
+				t.Errorf("Expected directive %v, got %v", tt.directive, directive)
+			}
+		})
+	}
+}
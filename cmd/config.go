@@ -0,0 +1,147 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/utils"
+)
+
+// configMigrateDryRun prints the migrated files instead of rewriting them
+var configMigrateDryRun bool
+
+// configCommand is the parent command for the subcommands managing the tools configuration files
+var configCommand = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the tools configuration files",
+	Long: `
+
+The config command provides subcommands to manage the tools configuration files.
+
+Available subcommands:
+- migrate: Migrate the configuration files to the current version of the format
+`,
+}
+
+// configMigrateCommand rewrites the tools configuration files in the current version of the format
+var configMigrateCommand = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the tools configuration files to the current version of the format",
+	Long: `
+
+Rewrites the tools configuration files in older versions of the format in the
+current version, keeping their comments. Files in older versions are still
+loaded (and migrated in memory), but they should be migrated, as the support
+for old versions can be removed in the future.
+
+With a directory, all the YAML files in it are migrated. Remote files (URLs)
+cannot be migrated. Signed files must be signed again after migrating them.
+
+Example:
+$ mcpshell config migrate --tools=tools.yaml
+$ mcpshell config migrate --tools=tools.yaml --dry-run
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		if len(toolsFiles) == 0 {
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		files, err := localConfigFiles(toolsFiles)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+
+			migrated, version, changes, err := config.MigrateConfig(data)
+			if err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", file, err)
+			}
+			if bytes.Equal(data, migrated) {
+				_, _ = fmt.Fprintf(out, "%s: already in version %d of the format\n", file, config.CurrentConfigVersion)
+				continue
+			}
+
+			if configMigrateDryRun {
+				_, _ = fmt.Fprintf(out, "# %s (migrated from version %d to %d)\n%s\n", file, version, config.CurrentConfigVersion, migrated)
+				continue
+			}
+
+			info, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, migrated, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			logger.Info("Migrated %s from version %d to %d", file, version, config.CurrentConfigVersion)
+
+			_, _ = fmt.Fprintf(out, "%s: migrated from version %d to %d\n", file, version, config.CurrentConfigVersion)
+			for _, change := range changes {
+				_, _ = fmt.Fprintf(out, "  - %s\n", change)
+			}
+		}
+
+		return nil
+	},
+}
+
+// localConfigFiles returns the local configuration files in some paths, with all
+// the YAML files in the directories
+func localConfigFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if parsedURL, err := url.Parse(path); err == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") {
+			return nil, fmt.Errorf("cannot migrate remote file %s: download it first", path)
+		}
+
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				ext := strings.ToLower(filepath.Ext(file))
+				if !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+					files = append(files, file)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan directory %s: %w", path, err)
+			}
+			continue
+		}
+
+		file, err := utils.ResolveToolsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCommand)
+	configCommand.AddCommand(configMigrateCommand)
+
+	configMigrateCommand.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Print the migrated files instead of rewriting them")
+}
@@ -0,0 +1,195 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+var (
+	// constraintsParams are the parameters of the call evaluated, as "name=value"
+	constraintsParams []string
+
+	// constraintsClient is the name of the client of the call evaluated
+	constraintsClient string
+
+	// constraintsGroups are the groups of the client of the call evaluated
+	constraintsGroups []string
+
+	// constraintsTime is the time of the call evaluated (now by default)
+	constraintsTime string
+)
+
+// constraintsCommand is the parent command for the subcommands about the constraints of the tools
+var constraintsCommand = &cobra.Command{
+	Use:   "constraints",
+	Short: "Debug the constraints of the tools",
+	Long: `
+
+The constraints command provides subcommands to debug the constraints of the tools.
+
+Available subcommands:
+- eval: Evaluate the constraints of a tool for some parameters
+`,
+}
+
+// constraintsEvalCommand evaluates the constraints of a tool for some parameters
+var constraintsEvalCommand = &cobra.Command{
+	Use:   "eval <tool>",
+	Short: "Evaluate the constraints of a tool for some parameters",
+	Long: `
+
+Evaluates all the constraints of a tool for some parameters, as in a call of a
+client (with the default values of the parameters not given), and prints the
+result of every constraint with the values of the variables it uses. It is useful
+for finding out why a legitimate call is rejected, without attaching a client.
+The command is never run.
+
+The context of the call available in the constraints (the "request" variable) can
+be simulated with --client, --groups and --time.
+
+Example:
+$ mcpshell constraints eval --tools=examples/config.yaml hello_world --params name=John
+$ mcpshell constraints eval --tools=examples/config.yaml delete_pod --params name=web \
+    --client admin --time 2025-06-07T22:00:00Z
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+		defer common.RecoverPanic()
+
+		if len(toolsFiles) == 0 {
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		cfg, err := config.NewConfigFromFile(localConfigPath)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		toolConfig, err := findToolConfig(cfg, args[0])
+		if err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+
+		params, err := parseParamArgs(toolConfig, constraintsParams)
+		if err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+
+		request := &common.RequestInfo{Client: constraintsClient, Groups: constraintsGroups, Time: time.Now()}
+		if constraintsTime != "" {
+			request.Time, err = time.Parse(time.RFC3339, constraintsTime)
+			if err != nil {
+				return fmt.Errorf("invalid time '%s': it must be in RFC 3339 format (ie, 2025-01-02T15:04:05Z)", constraintsTime)
+			}
+		}
+
+		return evalConstraints(cmd.OutOrStdout(), cfg, toolConfig, params, request, logger)
+	},
+}
+
+// evalConstraints prints the result of every constraint of a tool for some parameters
+// and the context of a request
+func evalConstraints(w io.Writer, cfg *config.ToolsConfig, toolConfig *config.MCPToolConfig, params map[string]interface{}, request *common.RequestInfo, logger *common.Logger) error {
+	secrets, err := common.NewSecrets(cfg.Secrets)
+	if err != nil {
+		return fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+	handler, err := command.NewCommandHandler(config.Tool{
+		MCPTool: config.CreateMCPTool(*toolConfig),
+		Config:  *toolConfig,
+		Secrets: secrets,
+	}, toolConfig.Params, cfg.MCP.Run.Shell, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create command handler: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Tool: %s (%s)\n", toolConfig.Name, formatParams(params))
+	results, err := handler.ExplainConstraints(params, request)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "\nRejected before the constraints: %v\n", err)
+		return nil
+	}
+	if len(results) == 0 {
+		_, _ = fmt.Fprintln(w, "\nThe tool has no constraints: the call would be accepted")
+		return nil
+	}
+
+	failed := 0
+	for i, result := range results {
+		_, _ = fmt.Fprintf(w, "\n%d. %s\n", i+1, result.Expression)
+		switch {
+		case result.Error != nil:
+			failed++
+			_, _ = fmt.Fprintf(w, "   result: error (%v)\n", result.Error)
+		case result.Passed:
+			_, _ = fmt.Fprintln(w, "   result: passed")
+		default:
+			failed++
+			_, _ = fmt.Fprintln(w, "   result: FAILED")
+		}
+
+		names := make([]string, 0, len(result.Variables))
+		for name := range result.Variables {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(w, "   %s = %s\n", name, formatConstraintValue(result.Variables[name]))
+		}
+	}
+
+	if failed > 0 {
+		_, _ = fmt.Fprintf(w, "\nThe call would be rejected: %d of %d constraints failed\n", failed, len(results))
+	} else {
+		_, _ = fmt.Fprintf(w, "\nThe call would be accepted: all the %d constraints passed\n", len(results))
+	}
+	return nil
+}
+
+// formatConstraintValue formats the value of a variable of a constraint (as JSON,
+// so the strings are quoted)
+func formatConstraintValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// init adds the constraints commands to the root command
+func init() {
+	rootCmd.AddCommand(constraintsCommand)
+	constraintsCommand.AddCommand(constraintsEvalCommand)
+
+	constraintsEvalCommand.Flags().StringArrayVarP(&constraintsParams, "params", "p", []string{}, "Parameter of the call, as name=value (can be specified multiple times)")
+	constraintsEvalCommand.Flags().StringVar(&constraintsClient, "client", "", "Name of the client of the call")
+	constraintsEvalCommand.Flags().StringSliceVar(&constraintsGroups, "groups", []string{}, "Groups of the client of the call")
+	constraintsEvalCommand.Flags().StringVar(&constraintsTime, "time", "", "Time of the call, in RFC 3339 format (now by default)")
+	constraintsEvalCommand.Flags().StringArrayVar(&config.Overrides, "set", []string{}, setFlagUsage)
+
+	constraintsEvalCommand.ValidArgsFunction = completeToolArgs
+}
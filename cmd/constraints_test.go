@@ -0,0 +1,113 @@
+package root
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestEvalConstraints(t *testing.T) {
+	testLogger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "list_dir"
+      description: "List a directory"
+      params:
+        path:
+          type: string
+          required: true
+        depth:
+          type: number
+          default: 1
+      constraints:
+        - "path.startsWith('/home/')"
+        - "!path.contains('..')"
+        - "depth <= 3.0"
+      run:
+        command: "find {{ .path }} -maxdepth {{ .depth }}"
+    - name: "free"
+      description: "No constraints"
+      run:
+        command: "echo free"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := config.NewConfigFromFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     []string
+		expected []string
+	}{
+		{
+			name: "accepted",
+			tool: "list_dir",
+			args: []string{"path=/home/john"},
+			expected: []string{
+				"Tool: list_dir (path=/home/john)",
+				"1. path.startsWith('/home/')\n   result: passed\n   path = \"/home/john\"",
+				"3. depth <= 3.0\n   result: passed\n   depth = 1",
+				"The call would be accepted: all the 3 constraints passed",
+			},
+		},
+		{
+			name: "rejected",
+			tool: "list_dir",
+			args: []string{"path=/etc/../root", "depth=5"},
+			expected: []string{
+				"1. path.startsWith('/home/')\n   result: FAILED\n   path = \"/etc/../root\"",
+				"2. !path.contains('..')\n   result: FAILED",
+				"3. depth <= 3.0\n   result: FAILED\n   depth = 5",
+				"The call would be rejected: 3 of 3 constraints failed",
+			},
+		},
+		{
+			name:     "missing parameter",
+			tool:     "list_dir",
+			expected: []string{"Rejected before the constraints: required parameter missing: path"},
+		},
+		{
+			name:     "no constraints",
+			tool:     "free",
+			expected: []string{"The tool has no constraints: the call would be accepted"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolConfig, err := findToolConfig(cfg, tt.tool)
+			if err != nil {
+				t.Fatalf("Failed to find tool: %v", err)
+			}
+			params, err := parseParamArgs(toolConfig, tt.args)
+			if err != nil {
+				t.Fatalf("Failed to parse the parameters: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := evalConstraints(&out, cfg, toolConfig, params, nil, testLogger); err != nil {
+				t.Fatalf("evalConstraints() error = %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected %q in the output:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// describeCommand prints everything about a tool
+var describeCommand = &cobra.Command{
+	Use:   "describe <tool> [name=value...]",
+	Short: "Describe a MCP tool",
+	Long: `
+Describe a MCP tool, as it is seen by clients and as it is run.
+
+This command prints the JSON schema of the parameters of the tool, its
+constraints, the runner selected in this environment, the timeout, and
+the command that would be executed for some sample parameters (without
+running it). It is useful for understanding why a call is rejected.
+
+For example:
+
+$ mcpshell describe --tools examples/config.yaml "hello_world" "name=John"
+
+The secrets are never obtained: they are shown as placeholders in the command.
+`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		// Check if config file is provided
+		if len(toolsFiles) == 0 {
+			logger.Error("Tools configuration file(s) are required")
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the logger
+		logger := common.GetLogger()
+
+		// Setup panic handler
+		defer common.RecoverPanic()
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		cfg, err := config.NewConfigFromFile(localConfigPath)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		toolConfig, err := findToolConfig(cfg, args[0])
+		if err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+
+		params, err := parseParamArgs(toolConfig, args[1:])
+		if err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+
+		return describeTool(os.Stdout, cfg, toolConfig, params, logger)
+	},
+}
+
+// describeTool prints the description of a tool, rendering the command for some parameters
+func describeTool(w io.Writer, cfg *config.ToolsConfig, toolConfig *config.MCPToolConfig, params map[string]interface{}, logger *common.Logger) error {
+	// Find the tool as it is loaded in this environment (with the runner selected)
+	var tool *config.Tool
+	tools := cfg.GetTools()
+	for i := range tools {
+		if tools[i].Config.Name == toolConfig.Name {
+			tool = &tools[i]
+			break
+		}
+	}
+
+	fmt.Fprintf(w, "Tool: %s\n", toolConfig.Name)
+	if len(toolConfig.Aliases) > 0 {
+		fmt.Fprintf(w, "Aliases: %s\n", strings.Join(toolConfig.Aliases, ", "))
+	}
+	if len(toolConfig.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(toolConfig.Tags, ", "))
+	}
+	if toolConfig.Type != "" {
+		fmt.Fprintf(w, "Type: %s\n", toolConfig.Type)
+	}
+	if toolConfig.Async {
+		fmt.Fprintln(w, "Async: yes (runs as a background job)")
+	}
+	if tool == nil {
+		fmt.Fprintln(w, "Available: no (disabled, prerequisites not met, or no runner meets its requirements)")
+	}
+
+	mcpTool := config.CreateMCPTool(*toolConfig)
+	fmt.Fprintf(w, "\nDescription:\n%s\n", indent(mcpTool.Description))
+	if toolConfig.Guidance != "" {
+		fmt.Fprintf(w, "\nGuidance:\n%s\n", indent(toolConfig.Guidance))
+	}
+
+	schema, err := json.MarshalIndent(mcpTool.InputSchema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format the input schema: %w", err)
+	}
+	fmt.Fprintf(w, "\nInput schema:\n%s\n", indent(string(schema)))
+
+	if toolConfig.OutputSchema != nil {
+		schema, err := json.MarshalIndent(toolConfig.OutputSchema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format the output schema: %w", err)
+		}
+		fmt.Fprintf(w, "\nOutput schema:\n%s\n", indent(string(schema)))
+	}
+
+	if len(toolConfig.Constraints) > 0 {
+		fmt.Fprintln(w, "\nConstraints:")
+		for i, constraint := range toolConfig.Constraints {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, constraint)
+		}
+	}
+
+	if len(toolConfig.Requires) > 0 || len(toolConfig.Prerequisites) > 0 {
+		fmt.Fprintln(w, "\nPrerequisites:")
+		for _, req := range toolConfig.Requires {
+			name := fmt.Sprintf("binary '%s'", req.Binary)
+			if req.Version != "" {
+				name += fmt.Sprintf(" %s", req.Version)
+			}
+			fmt.Fprintf(w, "  - %s: %s\n", name, formatCheck(req.Check(), config.PrerequisitePolicySkip))
+		}
+		for _, result := range toolConfig.CheckPrerequisites() {
+			fmt.Fprintf(w, "  - %s: %s\n", result.Prerequisite, formatCheck(result.Err, result.Prerequisite.GetPolicy()))
+		}
+	}
+
+	fmt.Fprintln(w, "\nExecution:")
+	if tool != nil {
+		fmt.Fprintf(w, "  Runner: %s\n", tool.GetEffectiveRunner())
+		if options := tool.GetEffectiveOptions(); len(options) > 0 {
+			names := make([]string, 0, len(options))
+			for name := range options {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "    %s: %v\n", name, options[name])
+			}
+		}
+	} else {
+		fmt.Fprintln(w, "  Runner: none")
+	}
+	timeout := toolConfig.Run.Timeout
+	if timeout == "" {
+		timeout = "none"
+	}
+	fmt.Fprintf(w, "  Timeout: %s\n", timeout)
+	if len(toolConfig.Run.Termination) > 0 {
+		var steps []string
+		for _, step := range toolConfig.Run.Termination {
+			if step.Wait != "" {
+				steps = append(steps, fmt.Sprintf("%s (wait %s)", step.Signal, step.Wait))
+			} else {
+				steps = append(steps, step.Signal)
+			}
+		}
+		fmt.Fprintf(w, "  Termination: %s\n", strings.Join(steps, ", "))
+	}
+
+	if tool == nil {
+		return nil
+	}
+
+	// Render the command for the sample parameters
+	handler, err := command.NewCommandHandler(*tool, tool.Config.Params, cfg.MCP.Run.Shell, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create command handler: %w", err)
+	}
+	fmt.Fprintf(w, "\nCommand for %s:\n", formatParams(params))
+	rendered, failedConstraints, err := handler.RenderCommand(params)
+	switch {
+	case len(failedConstraints) > 0:
+		fmt.Fprintln(w, "  (rejected by the constraints)")
+		for _, failed := range failedConstraints {
+			fmt.Fprintf(w, "  - %s\n", failed)
+		}
+	case err != nil:
+		fmt.Fprintf(w, "  (rejected: %v)\n", err)
+	default:
+		fmt.Fprintf(w, "%s\n", indent(strings.TrimSpace(rendered)))
+	}
+
+	return nil
+}
+
+// formatParams formats the parameters given by the user for describing a command
+func formatParams(params map[string]interface{}) string {
+	if len(params) == 0 {
+		return "no parameters"
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var res []string
+	for _, name := range names {
+		res = append(res, fmt.Sprintf("%s=%v", name, params[name]))
+	}
+	return strings.Join(res, " ")
+}
+
+// indent indents all the lines of a text
+func indent(text string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n  ")
+}
+
+// init adds the describe command to the root command
+func init() {
+	rootCmd.AddCommand(describeCommand)
+
+	_ = describeCommand.MarkFlagRequired("tools")
+
+	describeCommand.ValidArgsFunction = completeToolArgs
+}
+
+// formatCheck formats the result of checking a prerequisite with some policy
+func formatCheck(err error, policy string) string {
+	if err == nil {
+		return "ok"
+	}
+	return fmt.Sprintf("not met (%s): %v", policy, err)
+}
@@ -0,0 +1,158 @@
+package root
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestDescribeTool(t *testing.T) {
+	testLogger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `secrets:
+  - name: TOKEN
+    provider: env
+    path: MCPSHELL_TEST_DESCRIBE_TOKEN
+mcp:
+  tools:
+    - name: "hello_world"
+      description: "Say hello to someone"
+      tags: ["greetings", "demo"]
+      params:
+        name:
+          type: string
+          description: "Name of the person to greet"
+          required: true
+      constraints:
+        - "name.size() <= 10"
+      run:
+        timeout: 5s
+        command: "echo 'Hello {{ .name }}' {{ .Secrets.TOKEN }}"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := config.NewConfigFromFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	toolConfig, err := findToolConfig(cfg, "hello_world")
+	if err != nil {
+		t.Fatalf("Failed to find tool: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name: "valid parameters",
+			args: []string{"name=John"},
+			expected: []string{
+				"Tool: hello_world",
+				"Tags: greetings, demo",
+				`"required": [`,
+				"1. name.size() <= 10",
+				"Runner: exec",
+				"Timeout: 5s",
+				"Command for name=John:",
+				"echo 'Hello John' <secret:TOKEN>",
+			},
+		},
+		{
+			name: "rejected by constraints",
+			args: []string{"name=Johnathan Smith"},
+			expected: []string{
+				"(rejected by the constraints)",
+				"name.size() <= 10",
+			},
+		},
+		{
+			name: "missing parameters",
+			args: nil,
+			expected: []string{
+				"Command for no parameters:",
+				"(rejected: ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseParamArgs(toolConfig, tt.args)
+			if err != nil {
+				t.Fatalf("Failed to parse parameters: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := describeTool(&out, cfg, toolConfig, params, testLogger); err != nil {
+				t.Fatalf("describeTool failed: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeTool_Prerequisites(t *testing.T) {
+	testLogger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	t.Setenv("MCPSHELL_TEST_DESCRIBE_SET", "yes")
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "deploy"
+      description: "Deploy the app"
+      prerequisites:
+        - env: MCPSHELL_TEST_DESCRIBE_SET
+        - env: MCPSHELL_TEST_DESCRIBE_UNSET
+          policy: warn
+      run:
+        command: "echo deploying"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := config.NewConfigFromFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	toolConfig, err := findToolConfig(cfg, "deploy")
+	if err != nil {
+		t.Fatalf("Failed to find tool: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := describeTool(&out, cfg, toolConfig, map[string]interface{}{}, testLogger); err != nil {
+		t.Fatalf("describeTool failed: %v", err)
+	}
+	for _, expected := range []string{
+		"Prerequisites:",
+		"- env 'MCPSHELL_TEST_DESCRIBE_SET': ok",
+		"- env 'MCPSHELL_TEST_DESCRIBE_UNSET': not met (warn): environment variable 'MCPSHELL_TEST_DESCRIBE_UNSET' not set",
+		"Runner: exec",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package root
+
+import (
+	// The drivers of the SQL history stores ("sqlite" and "postgres"), in pure Go
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
@@ -0,0 +1,40 @@
+package root
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+func TestHistoryDrivers(t *testing.T) {
+	store, err := server.NewHistoryStore("sqlite", map[string]interface{}{
+		"dsn": filepath.Join(t.TempDir(), "history.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create the sqlite store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	entry := server.HistoryEntry{Time: time.Now().UTC().Truncate(time.Second), Client: "ci", Tool: "deploy", Status: "ok"}
+	if err := store.Record(entry); err != nil {
+		t.Fatalf("Failed to record an entry: %v", err)
+	}
+	entries, err := store.Query(server.HistoryFilter{Client: "ci"})
+	if err != nil {
+		t.Fatalf("Failed to query the history: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Tool != "deploy" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	// the postgres driver is linked too (but there is no database)
+	_, err = server.NewHistoryStore("postgres", map[string]interface{}{
+		"dsn": "postgres://mcpshell@127.0.0.1:1/mcpshell?connect_timeout=1",
+	})
+	if err == nil || strings.Contains(err.Error(), "must be imported") {
+		t.Errorf("Expected an error connecting to the database, got %v", err)
+	}
+}
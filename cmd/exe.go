@@ -2,6 +2,7 @@ package root
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/inercia/MCPShell/pkg/command"
@@ -86,53 +87,44 @@ will be reported.
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		// Find the requested tool in the configuration
-		var targetTool *config.MCPToolConfig
-		for _, toolConfig := range cfg.MCP.Tools {
-			if toolConfig.Name == toolName {
-				targetTool = &toolConfig
-				break
-			}
+		// Find the requested tool in the configuration (by name or alias)
+		targetTool, err := findToolConfig(cfg, toolName)
+		if err != nil {
+			logger.Error("%v", err)
+			return err
 		}
 
-		if targetTool == nil {
-			logger.Error("Tool not found: %s", toolName)
-			return fmt.Errorf("tool not found: %s", toolName)
+		// Check the tool is enabled in this environment
+		enabled, err := targetTool.IsEnabled()
+		if err != nil {
+			logger.Error("Invalid enabled condition for tool %s: %v", toolName, err)
+			return fmt.Errorf("invalid enabled condition for tool %s: %w", toolName, err)
+		}
+		if !enabled {
+			logger.Error("Tool is disabled: %s", toolName)
+			return fmt.Errorf("tool is disabled: %s", toolName)
 		}
 
 		// Parse parameters from the remaining arguments
-		params := make(map[string]interface{})
-		for _, arg := range args[1:] {
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) != 2 {
-				logger.Error("Invalid parameter format: %s (expected name=value)", arg)
-				return fmt.Errorf("invalid parameter format: %s (expected name=value)", arg)
-			}
-			paramName := parts[0]
-			paramValue := parts[1]
-
-			// Check if parameter is defined in the tool
-			paramConfig, exists := targetTool.Params[paramName]
-			if !exists {
-				logger.Error("Parameter not defined in tool: %s", paramName)
-				return fmt.Errorf("parameter not defined in tool: %s", paramName)
-			}
-
-			// Convert parameter value to appropriate type based on parameter config
-			typedValue, err := common.ConvertStringToType(paramValue, paramConfig.Type)
-			if err != nil {
-				logger.Error("Failed to convert parameter value: %v", err)
-				return fmt.Errorf("failed to convert parameter value: %w", err)
-			}
-
-			params[paramName] = typedValue
+		params, err := parseParamArgs(targetTool, args[1:])
+		if err != nil {
+			logger.Error("%v", err)
+			return err
 		}
 
 		// Apply default values for parameters that aren't provided but have defaults
 		for paramName, paramConfig := range targetTool.Params {
-			if _, exists := params[paramName]; !exists && paramConfig.Default != nil {
-				logger.Info("Using default value for parameter '%s': %v", paramName, paramConfig.Default)
-				params[paramName] = paramConfig.Default
+			if _, exists := params[paramName]; exists {
+				continue
+			}
+			defaultValue, err := paramConfig.GetDefault()
+			if err != nil {
+				logger.Error("Failed to obtain default value for parameter '%s': %v", paramName, err)
+				return fmt.Errorf("failed to obtain default value for parameter '%s': %w", paramName, err)
+			}
+			if defaultValue != nil {
+				logger.Info("Using default value for parameter '%s': %v", paramName, defaultValue)
+				params[paramName] = defaultValue
 			}
 		}
 
@@ -152,10 +144,18 @@ will be reported.
 			shell = "sh"
 		}
 
+		// Create the secrets the tool can reference
+		secrets, err := common.NewSecrets(cfg.Secrets)
+		if err != nil {
+			logger.Error("Invalid secrets configuration: %v", err)
+			return fmt.Errorf("invalid secrets configuration: %w", err)
+		}
+
 		// Create a command handler
 		handler, err := command.NewCommandHandler(config.Tool{
 			MCPTool: config.CreateMCPTool(*targetTool),
 			Config:  *targetTool,
+			Secrets: secrets,
 		}, targetTool.Params, shell, logger)
 		if err != nil {
 			logger.Error("Failed to create command handler: %v", err)
@@ -175,11 +175,63 @@ will be reported.
 	},
 }
 
+// findToolConfig finds a tool (by name or alias) in a configuration,
+// including the tools in the scripts directories
+func findToolConfig(cfg *config.ToolsConfig, toolName string) (*config.MCPToolConfig, error) {
+	// Load the tools in the scripts directories
+	scriptTools, err := cfg.GetScriptTools()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tools from scripts: %w", err)
+	}
+
+	for _, toolConfig := range append(cfg.MCP.Tools, scriptTools...) {
+		if slices.Contains(toolConfig.Names(), toolName) {
+			return &toolConfig, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tool not found: %s", toolName)
+}
+
+// parseParamArgs parses the parameters of a tool given as "name=value" arguments,
+// converting the values to the types of the parameters
+func parseParamArgs(tool *config.MCPToolConfig, args []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	for _, arg := range args {
+		paramName, paramValue, found := strings.Cut(arg, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid parameter format: %s (expected name=value)", arg)
+		}
+
+		// Check if parameter is defined in the tool
+		paramConfig, exists := tool.Params[paramName]
+		if !exists {
+			return nil, fmt.Errorf("parameter not defined in tool: %s", paramName)
+		}
+		if paramConfig.Hidden {
+			return nil, fmt.Errorf("parameter is hidden and cannot be set: %s", paramName)
+		}
+
+		// Convert parameter value to appropriate type based on parameter config
+		typedValue, err := common.ConvertStringToType(paramValue, paramConfig.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert parameter value: %w", err)
+		}
+
+		params[paramName] = typedValue
+	}
+	return params, nil
+}
+
 // init adds the exe command to the root command
 func init() {
 	// Add exe command to root
 	rootCmd.AddCommand(exeCommand)
 
+	exeCommand.Flags().StringArrayVar(&config.Overrides, "set", []string{}, setFlagUsage)
+
 	// Mark required flags
 	_ = exeCommand.MarkFlagRequired("tools")
+
+	exeCommand.ValidArgsFunction = completeToolArgs
 }
@@ -0,0 +1,206 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+var (
+	exportTags   []string
+	exportFormat string
+)
+
+// exporter writes the definitions of the tools in some format, for the
+// configuration files given (ie, for generating code running the tools)
+type exporter func(w io.Writer, tools []config.Tool, toolsFiles []string) error
+
+// exporters are the formats supported by the export command
+var exporters = map[string]exporter{
+	"openai-functions":     jsonExporter(exportOpenAIFunctions),
+	"anthropic-tools":      jsonExporter(exportAnthropicTools),
+	"langchain-python":     exportPythonAdapter,
+	"langchain-typescript": exportTypeScriptAdapter,
+}
+
+// jsonExporter returns an exporter writing the definitions returned by a function as JSON
+func jsonExporter(definitions func(tools []config.Tool) (interface{}, error)) exporter {
+	return func(w io.Writer, tools []config.Tool, toolsFiles []string) error {
+		defs, err := definitions(tools)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(defs)
+	}
+}
+
+// exportFormats returns the names of the formats supported by the export command
+func exportFormats() []string {
+	formats := make([]string, 0, len(exporters))
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// exportCommand prints the definitions of the tools for other platforms
+var exportCommand = &cobra.Command{
+	Use:   "export",
+	Short: "Export the MCP tools as definitions for other platforms",
+	Long: `
+Export the tools that a configuration exposes in this environment as the
+definitions used by other platforms, so the tools can be wired directly into
+their APIs with the same configuration as the source of truth.
+
+Only the tools that would be served are exported: disabled tools and tools
+without a runner meeting its requirements in this host are not exported.
+
+For example:
+
+$ mcpshell export --tools examples/config.yaml --format openai-functions
+$ mcpshell export --tools examples/config.yaml --format anthropic-tools
+$ mcpshell export --tools examples/config.yaml --format langchain-python > tools.py
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		// Check if config file is provided
+		if len(toolsFiles) == 0 {
+			logger.Error("Tools configuration file(s) are required")
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the logger
+		logger := common.GetLogger()
+
+		// Setup panic handler
+		defer common.RecoverPanic()
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		cfg, err := config.NewConfigFromFile(localConfigPath)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		return exportTools(os.Stdout, cfg.GetTools(), toolsFiles, exportTags, exportFormat)
+	},
+}
+
+// exportTools prints the definitions of the tools with any of the tags given
+// (or all of them when no tags are given) in some format
+func exportTools(w io.Writer, tools []config.Tool, toolsFiles []string, tags []string, format string) error {
+	export, found := exporters[format]
+	if !found {
+		return fmt.Errorf("unknown format '%s': use %s", format, strings.Join(exportFormats(), ", "))
+	}
+
+	var selected []config.Tool
+	for _, tool := range tools {
+		if len(tags) > 0 && !tool.Config.HasAnyTag(tags) {
+			continue
+		}
+		selected = append(selected, tool)
+	}
+
+	return export(w, selected, toolsFiles)
+}
+
+// exportOpenAIFunctions exports the tools as the function definitions of the tools
+// in the OpenAI API (the "tools" of the chat completions), with the same descriptions
+// and schemas the MCP clients get
+func exportOpenAIFunctions(tools []config.Tool) (interface{}, error) {
+	type function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	}
+	type definition struct {
+		Type     string   `json:"type"`
+		Function function `json:"function"`
+	}
+
+	definitions := []definition{}
+	for _, tool := range tools {
+		mcpTool := config.CreateMCPTool(tool.Config)
+		schema, err := toolInputSchema(mcpTool)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition{
+			Type: "function",
+			Function: function{
+				Name:        mcpTool.Name,
+				Description: mcpTool.Description,
+				Parameters:  schema,
+			},
+		})
+	}
+	return definitions, nil
+}
+
+// exportAnthropicTools exports the tools as the tool definitions of the Anthropic
+// Messages API (the "tools" of the messages), with the same descriptions and schemas
+// the MCP clients get
+func exportAnthropicTools(tools []config.Tool) (interface{}, error) {
+	type definition struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		InputSchema map[string]interface{} `json:"input_schema"`
+	}
+
+	definitions := []definition{}
+	for _, tool := range tools {
+		mcpTool := config.CreateMCPTool(tool.Config)
+		schema, err := toolInputSchema(mcpTool)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition{
+			Name:        mcpTool.Name,
+			Description: mcpTool.Description,
+			InputSchema: schema,
+		})
+	}
+	return definitions, nil
+}
+
+// init adds the export command to the root command
+func init() {
+	rootCmd.AddCommand(exportCommand)
+
+	exportCommand.Flags().StringSliceVar(&exportTags, "tags", []string{}, "Only export the tools with any of these tags")
+	exportCommand.Flags().StringVarP(&exportFormat, "format", "f", "openai-functions", "Output format: "+strings.Join(exportFormats(), ", "))
+
+	_ = exportCommand.MarkFlagRequired("tools")
+
+	_ = exportCommand.RegisterFlagCompletionFunc("tags", completeTags)
+	_ = exportCommand.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		exportFormats(), cobra.ShellCompDirectiveNoFileComp))
+}
@@ -0,0 +1,181 @@
+package root
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// pythonAdapterTemplate is the template of the Python adapter of the tools
+//
+//go:embed export_python.tpl
+var pythonAdapterTemplate string
+
+// typeScriptAdapterTemplate is the template of the TypeScript adapter of the tools
+//
+//go:embed export_typescript.tpl
+var typeScriptAdapterTemplate string
+
+// pythonKeywords are the reserved words of Python, that cannot be used as identifiers
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+	"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+	"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return",
+	"try", "while", "with", "yield",
+}
+
+// pythonTypes are the Python types of the types of the parameters
+var pythonTypes = map[string]string{
+	"number":  "float",
+	"integer": "int",
+	"boolean": "bool",
+}
+
+// pythonType returns the Python type of a type of parameters
+func pythonType(paramType string) string {
+	if t, found := pythonTypes[paramType]; found {
+		return t
+	}
+	return "str"
+}
+
+// invalidIdentifierChars matches the characters not valid in identifiers
+var invalidIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// adapterParam is a parameter of a tool in an adapter
+type adapterParam struct {
+	Name     string // the name of the parameter
+	Ident    string // the identifier of the parameter in Python
+	Type     string // the type in Python
+	Required bool
+}
+
+// adapterTool is a tool in an adapter
+type adapterTool struct {
+	Name        string // the name of the tool
+	Ident       string // the identifier of the function in Python
+	Description string
+	Schema      map[string]interface{} // the JSON schema of the arguments
+	Params      []adapterParam         // the required parameters first
+}
+
+// adapterData is the data for the templates of the adapters
+type adapterData struct {
+	ToolsFiles []string
+	Tools      []adapterTool
+}
+
+// pythonIdentifier converts a name to a valid Python identifier
+func pythonIdentifier(name string) string {
+	ident := invalidIdentifierChars.ReplaceAllString(name, "_")
+	if ident == "" || (ident[0] >= '0' && ident[0] <= '9') {
+		ident = "_" + ident
+	}
+	if slices.Contains(pythonKeywords, ident) {
+		ident += "_"
+	}
+	return ident
+}
+
+// newAdapterData collects the data of the tools for the templates of the adapters
+func newAdapterData(tools []config.Tool, toolsFiles []string) (adapterData, error) {
+	data := adapterData{ToolsFiles: toolsFiles, Tools: []adapterTool{}}
+	if data.ToolsFiles == nil {
+		data.ToolsFiles = []string{}
+	}
+
+	for _, tool := range tools {
+		mcpTool := config.CreateMCPTool(tool.Config)
+		schema, err := toolInputSchema(mcpTool)
+		if err != nil {
+			return data, err
+		}
+
+		var params []adapterParam
+		for name, param := range tool.Config.Params {
+			// the hidden parameters cannot be set by the clients
+			if param.Hidden {
+				continue
+			}
+			params = append(params, adapterParam{
+				Name:     name,
+				Ident:    pythonIdentifier(name),
+				Type:     pythonType(param.Type),
+				Required: param.Required,
+			})
+		}
+		sort.Slice(params, func(i, j int) bool {
+			if params[i].Required != params[j].Required {
+				return params[i].Required
+			}
+			return params[i].Name < params[j].Name
+		})
+
+		data.Tools = append(data.Tools, adapterTool{
+			Name:        mcpTool.Name,
+			Ident:       pythonIdentifier(mcpTool.Name),
+			Description: mcpTool.Description,
+			Schema:      schema,
+			Params:      params,
+		})
+	}
+	return data, nil
+}
+
+// executeAdapterTemplate renders the template of an adapter
+func executeAdapterTemplate(w io.Writer, name string, text string, data adapterData) error {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		// literals are written as JSON, valid in both Python and TypeScript
+		"literal": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse the %s template: %w", name, err)
+	}
+	return tmpl.Execute(w, data)
+}
+
+// exportPythonAdapter exports the tools as a Python module with a function for every
+// tool, running the tool with "mcpshell exe", and helpers for using them as LangChain
+// or LlamaIndex tools
+func exportPythonAdapter(w io.Writer, tools []config.Tool, toolsFiles []string) error {
+	data, err := newAdapterData(tools, toolsFiles)
+	if err != nil {
+		return err
+	}
+	return executeAdapterTemplate(w, "python", pythonAdapterTemplate, data)
+}
+
+// exportTypeScriptAdapter exports the tools as a TypeScript module with the definitions
+// of the tools, running them with "mcpshell exe", and helpers for using them as LangChain
+// or LlamaIndex tools
+func exportTypeScriptAdapter(w io.Writer, tools []config.Tool, toolsFiles []string) error {
+	data, err := newAdapterData(tools, toolsFiles)
+	if err != nil {
+		return err
+	}
+	return executeAdapterTemplate(w, "typescript", typeScriptAdapterTemplate, data)
+}
+
+// PythonSignature returns the parameters of the function of a tool in Python
+func (t adapterTool) PythonSignature() string {
+	var args []string
+	for _, param := range t.Params {
+		if param.Required {
+			args = append(args, fmt.Sprintf("%s: %s", param.Ident, param.Type))
+		} else {
+			args = append(args, fmt.Sprintf("%s: Optional[%s] = None", param.Ident, param.Type))
+		}
+	}
+	return strings.Join(args, ", ")
+}
@@ -0,0 +1,185 @@
+package root
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestExportTools_OpenAIFunctions(t *testing.T) {
+	tools := config.NewTools([]config.MCPToolConfig{
+		{
+			Name:        "get_pods",
+			Description: "List the pods",
+			Tags:        []string{"k8s"},
+			Params: map[string]common.ParamConfig{
+				"namespace": {Type: "string", Description: "The namespace", Required: true},
+			},
+			Run: config.MCPToolRunConfig{Command: "kubectl get pods -n {{ .namespace }}"},
+		},
+		{
+			Name:        "git_log",
+			Description: "Show the git log",
+			Tags:        []string{"git"},
+			Run:         config.MCPToolRunConfig{Command: "git log"},
+		},
+	})
+
+	var out bytes.Buffer
+	if err := exportTools(&out, tools, nil, []string{"k8s"}, "openai-functions"); err != nil {
+		t.Fatalf("exportTools failed: %v", err)
+	}
+
+	var definitions []struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Parameters  struct {
+				Type       string                            `json:"type"`
+				Properties map[string]map[string]interface{} `json:"properties"`
+				Required   []string                          `json:"required"`
+			} `json:"parameters"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &definitions); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, out.String())
+	}
+	if len(definitions) != 1 {
+		t.Fatalf("Expected 1 definition, got %d:\n%s", len(definitions), out.String())
+	}
+
+	def := definitions[0]
+	if def.Type != "function" || def.Function.Name != "get_pods" || def.Function.Description != "List the pods" {
+		t.Errorf("Unexpected definition: %+v", def)
+	}
+	params := def.Function.Parameters
+	if params.Type != "object" || params.Properties["namespace"]["type"] != "string" ||
+		len(params.Required) != 1 || params.Required[0] != "namespace" {
+		t.Errorf("Unexpected parameters: %+v", params)
+	}
+
+	err := exportTools(&bytes.Buffer{}, tools, nil, nil, "xml")
+	if err == nil || !strings.Contains(err.Error(), "openai-functions") {
+		t.Errorf("Expected an error listing the formats, got %v", err)
+	}
+}
+
+func TestExportTools_AnthropicTools(t *testing.T) {
+	tools := config.NewTools([]config.MCPToolConfig{
+		{
+			Name:        "get_pods",
+			Description: "List the pods",
+			Params: map[string]common.ParamConfig{
+				"namespace": {Type: "string", Description: "The namespace", Required: true},
+			},
+			Run: config.MCPToolRunConfig{Command: "kubectl get pods -n {{ .namespace }}"},
+		},
+		{
+			Name:        "git_log",
+			Description: "Show the git log",
+			Run:         config.MCPToolRunConfig{Command: "git log"},
+		},
+	})
+
+	var out bytes.Buffer
+	if err := exportTools(&out, tools, nil, nil, "anthropic-tools"); err != nil {
+		t.Fatalf("exportTools failed: %v", err)
+	}
+
+	var definitions []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		InputSchema struct {
+			Type       string                            `json:"type"`
+			Properties map[string]map[string]interface{} `json:"properties"`
+			Required   []string                          `json:"required"`
+		} `json:"input_schema"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &definitions); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, out.String())
+	}
+	if len(definitions) != 2 {
+		t.Fatalf("Expected 2 definitions, got %d:\n%s", len(definitions), out.String())
+	}
+
+	def := definitions[0]
+	if def.Name != "get_pods" || def.Description != "List the pods" || def.InputSchema.Type != "object" ||
+		def.InputSchema.Properties["namespace"]["description"] != "The namespace" {
+		t.Errorf("Unexpected definition: %+v", def)
+	}
+	if definitions[1].Name != "git_log" || definitions[1].InputSchema.Type != "object" {
+		t.Errorf("Unexpected definition: %+v", definitions[1])
+	}
+}
+
+func TestExportTools_Adapters(t *testing.T) {
+	tools := config.NewTools([]config.MCPToolConfig{
+		{
+			Name:        "get-pods",
+			Description: "List the \"pods\"",
+			Params: map[string]common.ParamConfig{
+				"namespace": {Type: "string", Description: "The namespace", Required: true},
+				"from":      {Type: "integer", Description: "The first pod"},
+				"token":     {Type: "string", Description: "A token", Hidden: true, Default: "x"},
+			},
+			Run: config.MCPToolRunConfig{Command: "kubectl get pods -n {{ .namespace }}"},
+		},
+	})
+
+	t.Run("python", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := exportTools(&out, tools, []string{"k8s.yaml"}, nil, "langchain-python"); err != nil {
+			t.Fatalf("exportTools failed: %v", err)
+		}
+		for _, expected := range []string{
+			`TOOLS_FILES: List[str] = ["k8s.yaml"]`,
+			`def get_pods(namespace: str, from_: Optional[int] = None) -> str:`,
+			`    "List the \"pods\""`,
+			`return call_tool("get-pods", {"namespace": namespace, "from": from_})`,
+			`"get-pods": get_pods,`,
+		} {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "token") {
+			t.Errorf("Unexpected hidden parameter in output:\n%s", out.String())
+		}
+	})
+
+	t.Run("typescript", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := exportTools(&out, tools, []string{"k8s.yaml"}, nil, "langchain-typescript"); err != nil {
+			t.Fatalf("exportTools failed: %v", err)
+		}
+		for _, expected := range []string{
+			`const TOOLS_FILES: string[] = ["k8s.yaml"];`,
+			`name: "get-pods",`,
+			`"required":["namespace"]`,
+			`invoke: (args) => callTool("get-pods", args),`,
+		} {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+			}
+		}
+	})
+}
+
+func TestPythonIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{
+		"get_pods":  "get_pods",
+		"get-pods":  "get_pods",
+		"k8s.apply": "k8s_apply",
+		"3d":        "_3d",
+		"lambda":    "lambda_",
+	} {
+		if got := pythonIdentifier(name); got != expected {
+			t.Errorf("pythonIdentifier(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package root
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// starterTemplates are the starter configurations written by the init command
+//
+//go:embed templates/*.yaml
+var starterTemplates embed.FS
+
+// defaultInitFile is the file written by the init command when none is given
+const defaultInitFile = "tools.yaml"
+
+var (
+	initTemplate string
+	initForce    bool
+)
+
+// initCommand writes a starter configuration file
+var initCommand = &cobra.Command{
+	Use:   "init [file]",
+	Short: "Create a starter tools configuration file",
+	Long: `
+
+Creates a tools configuration file from a template, with commented example
+tools, constraints and runners, ready to be customized. The file is written
+in tools.yaml unless another one is given, and existing files are not
+overwritten unless --force is used.
+
+Available templates:
+- empty: a single example tool, for starting from scratch
+- git: read-only tools for inspecting Git repositories
+- kubernetes: read-only tools for inspecting a Kubernetes cluster
+- sysadmin: read-only tools for diagnosing the local system, in a sandbox
+
+Example:
+$ mcpshell init --template kubernetes k8s.yaml
+$ mcpshell mcp --tools k8s.yaml
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		file := defaultInitFile
+		if len(args) > 0 {
+			file = args[0]
+		}
+
+		if err := writeStarterConfig(cmd.OutOrStdout(), initTemplate, file, initForce); err != nil {
+			logger.Error("Failed to create the configuration: %v", err)
+			return err
+		}
+		logger.Info("Created %s from template %s", file, initTemplate)
+		return nil
+	},
+}
+
+// starterTemplateNames returns the names of the starter templates
+func starterTemplateNames() []string {
+	entries, _ := starterTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// writeStarterConfig writes the starter configuration of a template in a file,
+// printing the next steps
+func writeStarterConfig(out io.Writer, template string, file string, force bool) error {
+	if !slices.Contains(starterTemplateNames(), template) {
+		return fmt.Errorf("unknown template '%s' (available templates: %s)", template, strings.Join(starterTemplateNames(), ", "))
+	}
+
+	content, err := starterTemplates.ReadFile("templates/" + template + ".yaml")
+	if err != nil {
+		return err
+	}
+	content = []byte(strings.ReplaceAll(string(content), "{{ .File }}", file))
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(file, flags, 0o644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists (use --force for overwriting it)", file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", file, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+
+	_, _ = fmt.Fprintf(out, "Created %s from the '%s' template. Next steps:\n\n", file, template)
+	_, _ = fmt.Fprintf(out, "  1. Edit the tools in %s\n", file)
+	_, _ = fmt.Fprintf(out, "  2. Check the configuration:   mcpshell validate --tools %s\n", file)
+	_, _ = fmt.Fprintf(out, "  3. List the tools:            mcpshell list --tools %s\n", file)
+	_, _ = fmt.Fprintf(out, "  4. Run the MCP server:        mcpshell mcp --tools %s\n", file)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(initCommand)
+
+	initCommand.Flags().StringVarP(&initTemplate, "template", "t", "empty", "The template of the configuration ("+strings.Join(starterTemplateNames(), ", ")+")")
+	initCommand.Flags().BoolVar(&initForce, "force", false, "Overwrite the file if it already exists")
+
+	_ = initCommand.RegisterFlagCompletionFunc("template", cobra.FixedCompletions(
+		starterTemplateNames(), cobra.ShellCompDirectiveNoFileComp))
+}
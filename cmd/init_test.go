@@ -0,0 +1,93 @@
+package root
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+func TestWriteStarterConfig(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	names := starterTemplateNames()
+	for _, expected := range []string{"empty", "git", "kubernetes", "sysadmin"} {
+		if !strings.Contains(strings.Join(names, ","), expected) {
+			t.Errorf("Expected template %s, got %v", expected, names)
+		}
+	}
+
+	// all the templates are valid configurations in the current format
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tools.yaml")
+
+			var out bytes.Buffer
+			if err := writeStarterConfig(&out, name, file, false); err != nil {
+				t.Fatalf("Failed to write the template: %v", err)
+			}
+			if !strings.Contains(out.String(), "mcpshell mcp --tools "+file) {
+				t.Errorf("Expected the next steps, got %s", out.String())
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read the file: %v", err)
+			}
+			if !strings.Contains(string(data), "mcpshell validate --tools "+file) {
+				t.Errorf("Expected the file name in the comments, got:\n%s", data)
+			}
+
+			cfg, err := config.NewConfigFromFile(file)
+			if err != nil {
+				t.Fatalf("Invalid template: %v", err)
+			}
+			if cfg.Version != config.CurrentConfigVersion || len(cfg.MCP.Tools) == 0 {
+				t.Errorf("Expected tools in the current format, got version %d and %d tools", cfg.Version, len(cfg.MCP.Tools))
+			}
+
+			srv := server.New(server.Config{ConfigFile: file, Logger: logger})
+			if err := srv.Validate(); err != nil {
+				t.Errorf("Template does not validate: %v", err)
+			}
+		})
+	}
+
+	t.Run("existing file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "tools.yaml")
+		if err := os.WriteFile(file, []byte("mine"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		var out bytes.Buffer
+		if err := writeStarterConfig(&out, "git", file, false); err == nil || !strings.Contains(err.Error(), "--force") {
+			t.Errorf("Expected an error for the existing file, got %v", err)
+		}
+		if data, _ := os.ReadFile(file); string(data) != "mine" {
+			t.Errorf("Expected the existing file to be kept, got %s", data)
+		}
+
+		if err := writeStarterConfig(&out, "git", file, true); err != nil {
+			t.Errorf("Expected the file to be overwritten, got %v", err)
+		}
+		if data, _ := os.ReadFile(file); !strings.Contains(string(data), "git_status") {
+			t.Errorf("Expected the template, got %s", data)
+		}
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		var out bytes.Buffer
+		err := writeStarterConfig(&out, "windows", filepath.Join(t.TempDir(), "tools.yaml"), false)
+		if err == nil || !strings.Contains(err.Error(), "available templates: empty, git") {
+			t.Errorf("Expected an error listing the templates, got %v", err)
+		}
+	})
+}
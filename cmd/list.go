@@ -0,0 +1,183 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+var (
+	listTags   []string
+	listFormat string
+)
+
+// listedTool is a tool as it is printed by the list command
+type listedTool struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Aliases     []string               `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Description string                 `json:"description" yaml:"description"`
+	Type        string                 `json:"type,omitempty" yaml:"type,omitempty"`
+	Async       bool                   `json:"async,omitempty" yaml:"async,omitempty"`
+	Tags        []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Runner      string                 `json:"runner" yaml:"runner"`
+	InputSchema map[string]interface{} `json:"input_schema" yaml:"input_schema"`
+}
+
+// listCommand prints the tools available in this environment
+var listCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List the MCP tools available in this environment",
+	Long: `
+List the MCP tools that a configuration exposes in this environment.
+
+Only the tools that would be served are listed: disabled tools and tools
+without a runner meeting its requirements in this host are not shown.
+The tools can be filtered by tags, and the list can be printed as JSON or
+YAML for scripts.
+
+For example:
+
+$ mcpshell list --tools examples/config.yaml
+$ mcpshell list --tools examples/config.yaml --tags k8s --format json
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		// Check if config file is provided
+		if len(toolsFiles) == 0 {
+			logger.Error("Tools configuration file(s) are required")
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the logger
+		logger := common.GetLogger()
+
+		// Setup panic handler
+		defer common.RecoverPanic()
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		cfg, err := config.NewConfigFromFile(localConfigPath)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		return listTools(os.Stdout, cfg.GetTools(), listTags, listFormat)
+	},
+}
+
+// listTools prints the tools with any of the tags given (or all of them when
+// no tags are given) in some format: "text", "json" or "yaml"
+func listTools(w io.Writer, tools []config.Tool, tags []string, format string) error {
+	listed := []listedTool{}
+	for i := range tools {
+		toolConfig := tools[i].Config
+		if len(tags) > 0 && !toolConfig.HasAnyTag(tags) {
+			continue
+		}
+
+		schema, err := toolInputSchema(config.CreateMCPTool(toolConfig))
+		if err != nil {
+			return err
+		}
+
+		listed = append(listed, listedTool{
+			Name:        toolConfig.Name,
+			Aliases:     toolConfig.Aliases,
+			Description: toolConfig.Description,
+			Type:        toolConfig.Type,
+			Async:       toolConfig.Async,
+			Tags:        toolConfig.Tags,
+			Runner:      tools[i].GetEffectiveRunner(),
+			InputSchema: schema,
+		})
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listed)
+
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(listed); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case "", "text":
+		if len(listed) == 0 {
+			_, err := fmt.Fprintln(w, "No tools found.")
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tRUNNER\tTAGS\tDESCRIPTION")
+		for _, tool := range listed {
+			name := tool.Name
+			if len(tool.Aliases) > 0 {
+				name += " (" + strings.Join(tool.Aliases, ", ") + ")"
+			}
+			description, _, _ := strings.Cut(strings.TrimSpace(tool.Description), "\n")
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, tool.Runner, strings.Join(tool.Tags, ","),
+				truncateString(description, 60))
+		}
+		return tw.Flush()
+
+	default:
+		return fmt.Errorf("unknown format '%s': use text, json or yaml", format)
+	}
+}
+
+// toolInputSchema returns the JSON schema of the arguments of a tool, as a generic map
+func toolInputSchema(mcpTool mcp.Tool) (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	data, err := json.Marshal(mcpTool.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the input schema of '%s': %w", mcpTool.Name, err)
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to format the input schema of '%s': %w", mcpTool.Name, err)
+	}
+	return schema, nil
+}
+
+// init adds the list command to the root command
+func init() {
+	rootCmd.AddCommand(listCommand)
+
+	listCommand.Flags().StringSliceVar(&listTags, "tags", []string{}, "Only list the tools with any of these tags")
+	listCommand.Flags().StringVarP(&listFormat, "format", "f", "text", "Output format: text, json or yaml")
+
+	_ = listCommand.MarkFlagRequired("tools")
+
+	_ = listCommand.RegisterFlagCompletionFunc("tags", completeTags)
+	_ = listCommand.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{"text", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -0,0 +1,83 @@
+package root
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestListTools(t *testing.T) {
+	tools := config.NewTools([]config.MCPToolConfig{
+		{
+			Name:        "get_pods",
+			Description: "List the pods\nin a namespace",
+			Tags:        []string{"k8s", "readonly"},
+			Run:         config.MCPToolRunConfig{Command: "kubectl get pods"},
+		},
+		{
+			Name:        "git_log",
+			Aliases:     []string{"log"},
+			Description: "Show the git log",
+			Tags:        []string{"git"},
+			Run:         config.MCPToolRunConfig{Command: "git log"},
+		},
+		{
+			Name:        "disabled",
+			Description: "Never listed",
+			Tags:        []string{"k8s"},
+			Enabled:     "false",
+			Run:         config.MCPToolRunConfig{Command: "true"},
+		},
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := listTools(&out, tools, nil, "text"); err != nil {
+			t.Fatalf("listTools failed: %v", err)
+		}
+		for _, expected := range []string{"NAME", "get_pods", "k8s,readonly", "List the pods", "git_log (log)"} {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "in a namespace") || strings.Contains(out.String(), "disabled") {
+			t.Errorf("Unexpected output:\n%s", out.String())
+		}
+	})
+
+	t.Run("json with tags", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := listTools(&out, tools, []string{"k8s"}, "json"); err != nil {
+			t.Fatalf("listTools failed: %v", err)
+		}
+		var listed []listedTool
+		if err := json.Unmarshal(out.Bytes(), &listed); err != nil {
+			t.Fatalf("Invalid JSON output: %v\n%s", err, out.String())
+		}
+		if len(listed) != 1 || listed[0].Name != "get_pods" || listed[0].Runner != "exec" {
+			t.Fatalf("Unexpected tools listed: %+v", listed)
+		}
+		if listed[0].InputSchema["type"] != "object" {
+			t.Errorf("Expected an input schema, got %v", listed[0].InputSchema)
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := listTools(&out, tools, []string{"db"}, "json"); err != nil {
+			t.Fatalf("listTools failed: %v", err)
+		}
+		if strings.TrimSpace(out.String()) != "[]" {
+			t.Errorf("Expected an empty list, got %s", out.String())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := listTools(&bytes.Buffer{}, tools, nil, "xml"); err == nil {
+			t.Error("Expected an error for an unknown format")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package root
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/inercia/MCPShell/pkg/common"
 	"github.com/inercia/MCPShell/pkg/config"
@@ -11,8 +12,15 @@ import (
 )
 
 var (
-	useHTTP  bool
-	httpPort int
+	useHTTP      bool
+	httpPort     int
+	useREST      bool
+	restInsecure bool
+	drainTimeout time.Duration
+	recordFile   string
+	selfTest     bool
+	onlyTags     []string
+	excludeTools []string
 )
 
 // mcpCommand represents the run command which starts the MCP server
@@ -75,6 +83,13 @@ available to AI applications via the MCP protocol.
 			Descriptions:        description,
 			DescriptionFiles:    descriptionFile,
 			DescriptionOverride: descriptionOverride,
+			DrainTimeout:        drainTimeout,
+			REST:                useREST,
+			RESTInsecure:        restInsecure,
+			RecordFile:          recordFile,
+			SelfTest:            selfTest,
+			OnlyTags:            onlyTags,
+			ExcludeTools:        excludeTools,
 		})
 
 		if useHTTP {
@@ -84,6 +99,10 @@ available to AI applications via the MCP protocol.
 	},
 }
 
+// setFlagUsage is the help of the --set flag
+const setFlagUsage = "Override a value of the configuration as path=value (ie, tools.deploy.run.timeout=30s),\n" +
+	"with the items of the lists addressed by name (can be specified multiple times)"
+
 // init adds flags to the run command
 func init() {
 	rootCmd.AddCommand(mcpCommand)
@@ -92,10 +111,28 @@ func init() {
 	mcpCommand.Flags().StringSliceVarP(&description, "description", "d", []string{}, "MCP server description (optional, can be specified multiple times)")
 	mcpCommand.Flags().StringSliceVarP(&descriptionFile, "description-file", "", []string{}, "Read the MCP server description from files (optional, can be specified multiple times)")
 	mcpCommand.Flags().BoolVarP(&descriptionOverride, "description-override", "", false, "Override the description found in the config file")
+	mcpCommand.Flags().StringArrayVar(&config.Overrides, "set", []string{}, setFlagUsage)
 
 	// Add HTTP server flags
 	mcpCommand.Flags().BoolVar(&useHTTP, "http", false, "Enable HTTP server mode (serve MCP over HTTP/SSE instead of stdio)")
 	mcpCommand.Flags().IntVar(&httpPort, "port", 8080, "Port for HTTP server (default: 8080, only used with --http)")
+	mcpCommand.Flags().BoolVar(&useREST, "rest", false, "Serve the tools as REST endpoints in /tools too (only used with --http)")
+	mcpCommand.Flags().BoolVar(&restInsecure, "rest-insecure", false, "Serve the REST endpoints without authenticating the clients (only used with --rest)")
+
+	// Add shutdown flags
+	mcpCommand.Flags().DurationVar(&drainTimeout, "drain-timeout", 30*time.Second, "Time for finishing the tool calls in progress when shutting down, before terminating them")
+
+	// Add recording flags
+	mcpCommand.Flags().StringVar(&recordFile, "record", "", "Record the MCP requests and responses, and the commands run, in a file (for 'mcpshell replay')")
+
+	// Add self-test flags
+	mcpCommand.Flags().BoolVar(&selfTest, "self-test", false, "Run the smoke tests of the tools when starting, not advertising the tools that fail them")
+
+	// Add tool selection flags
+	mcpCommand.Flags().StringSliceVar(&onlyTags, "only-tags", []string{}, "Only serve the tools with any of these tags")
+	mcpCommand.Flags().StringSliceVar(&excludeTools, "exclude-tools", []string{}, "Do not serve these tools (names or glob patterns, like 'k8s__delete_*')")
+	_ = mcpCommand.RegisterFlagCompletionFunc("only-tags", completeTags)
+	_ = mcpCommand.RegisterFlagCompletionFunc("exclude-tools", completeToolNames)
 
 	// Mark required flags
 	_ = mcpCommand.MarkFlagRequired("tools")
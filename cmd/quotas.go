@@ -0,0 +1,211 @@
+package root
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+var (
+	// quotasTool selects the counters of a tool
+	quotasTool string
+
+	// quotasClient selects the counters of a client
+	quotasClient string
+
+	// quotasPeriod selects the counters of a period ("day" or "week")
+	quotasPeriod string
+
+	// quotasAll resets all the counters
+	quotasAll bool
+)
+
+// quotasCommand is the parent command for the subcommands about the quotas of the tools
+var quotasCommand = &cobra.Command{
+	Use:   "quotas",
+	Short: "Inspect and reset the quotas of the tools",
+	Long: `
+
+The quotas command provides subcommands to inspect and reset the counters of the
+daily and weekly quotas of the tools.
+
+The counters are read from the quotas store of the configuration given with --tools
+(~/.mcpshell/quotas.json by default). The counters reset are seen by the servers
+running with the same store.
+
+Available subcommands:
+- list: List the counters of the quotas in the current periods
+- reset: Reset some counters of the quotas
+`,
+}
+
+// quotasListCommand lists the counters of the quotas
+var quotasListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List the counters of the quotas in the current periods",
+	Long: `
+
+Lists the number of calls of the tools in the current day and week, with the limits
+of their quotas (when the configuration of the tools is given with --tools).
+
+Example:
+$ mcpshell quotas list --tools=examples/config.yaml
+$ mcpshell quotas list --tools=examples/config.yaml --tool geocode --client ci
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, cfg, cleanup, err := openQuotaStore()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		return listQuotas(cmd.OutOrStdout(), store, cfg, quotaFilter())
+	},
+}
+
+// quotasResetCommand resets the counters of the quotas
+var quotasResetCommand = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset some counters of the quotas",
+	Long: `
+
+Resets the counters of the quotas selected by the tool, the client and the period,
+so the calls are allowed again (ie, after raising the limits of a paid API).
+
+Example:
+$ mcpshell quotas reset --tools=examples/config.yaml --tool geocode
+$ mcpshell quotas reset --tools=examples/config.yaml --client ci --period day
+$ mcpshell quotas reset --tools=examples/config.yaml --all
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := quotaFilter()
+		if filter == (server.QuotaFilter{}) && !quotasAll {
+			return fmt.Errorf("select the counters with --tool, --client or --period, or use --all for resetting all of them")
+		}
+
+		store, _, cleanup, err := openQuotaStore()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		removed, err := store.Reset(filter)
+		if err != nil {
+			return fmt.Errorf("failed to reset the quotas: %w", err)
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Reset %d counter(s)\n", removed)
+		return nil
+	},
+}
+
+// quotaFilter returns the filter of the counters selected in the command line
+func quotaFilter() server.QuotaFilter {
+	return server.QuotaFilter{Tool: quotasTool, Client: quotasClient, Period: quotasPeriod}
+}
+
+// openQuotaStore opens the quotas store of the configuration of the tools (if given),
+// returning the configuration (nil when not given) and a function for closing the store
+func openQuotaStore() (server.QuotaStore, *config.ToolsConfig, func(), error) {
+	logger, err := initLogger()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer common.RecoverPanic()
+
+	if quotasPeriod != "" && quotasPeriod != server.QuotaDay && quotasPeriod != server.QuotaWeek {
+		return nil, nil, nil, fmt.Errorf("invalid period '%s': use %s or %s", quotasPeriod, server.QuotaDay, server.QuotaWeek)
+	}
+
+	var cfg *config.ToolsConfig
+	if len(toolsFiles) > 0 {
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		cfg, err = config.NewConfigFromFile(localConfigPath)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	var quotasConfig config.MCPQuotasConfig
+	if cfg != nil {
+		quotasConfig = cfg.MCP.Quotas
+	}
+	store, err := server.NewQuotaStore(quotasConfig.Backend, quotasConfig.Options)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid quota store: %w", err)
+	}
+	return store, cfg, func() { _ = store.Close() }, nil
+}
+
+// listQuotas prints the counters of the quotas selected by a filter, with their
+// limits in a configuration (when not nil)
+func listQuotas(w io.Writer, store server.QuotaStore, cfg *config.ToolsConfig, filter server.QuotaFilter) error {
+	counters, err := store.List(filter)
+	if err != nil {
+		return fmt.Errorf("failed to list the quotas: %w", err)
+	}
+	if len(counters) == 0 {
+		_, _ = fmt.Fprintln(w, "No calls counted in the current periods")
+		return nil
+	}
+
+	slices.SortFunc(counters, func(a, b server.QuotaCounter) int {
+		return strings.Compare(a.Tool+"\x00"+a.Client+"\x00"+a.Period, b.Tool+"\x00"+b.Client+"\x00"+b.Period)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TOOL\tCLIENT\tPERIOD\tCALLS\tLIMIT\tRESETS")
+	for _, counter := range counters {
+		client := counter.Client
+		if client == "" {
+			client = "(all)"
+		}
+		limit := "-"
+		if cfg != nil {
+			if tool, err := findToolConfig(cfg, counter.Tool); err == nil {
+				switch counter.Period {
+				case server.QuotaDay:
+					limit = strconv.Itoa(tool.Quota.Daily)
+				case server.QuotaWeek:
+					limit = strconv.Itoa(tool.Quota.Weekly)
+				}
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", counter.Tool, client, counter.Period,
+			counter.Calls, limit, counter.End().Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+// init adds the quotas commands to the root command
+func init() {
+	rootCmd.AddCommand(quotasCommand)
+	quotasCommand.AddCommand(quotasListCommand)
+	quotasCommand.AddCommand(quotasResetCommand)
+
+	for _, subcommand := range []*cobra.Command{quotasListCommand, quotasResetCommand} {
+		subcommand.Flags().StringVar(&quotasTool, "tool", "", "Select the counters of a tool")
+		subcommand.Flags().StringVar(&quotasClient, "client", "", "Select the counters of a client")
+		subcommand.Flags().StringVar(&quotasPeriod, "period", "", "Select the counters of a period (day or week)")
+		subcommand.Flags().StringArrayVar(&config.Overrides, "set", []string{}, setFlagUsage)
+	}
+	quotasResetCommand.Flags().BoolVar(&quotasAll, "all", false, "Reset all the counters")
+}
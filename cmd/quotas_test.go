@@ -0,0 +1,55 @@
+package root
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+func TestListQuotas(t *testing.T) {
+	store, err := server.NewQuotaStore("file", map[string]interface{}{"path": filepath.Join(t.TempDir(), "quotas.json")})
+	if err != nil {
+		t.Fatalf("Failed to create the store: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := listQuotas(&out, store, nil, server.QuotaFilter{}); err != nil {
+		t.Fatalf("listQuotas() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No calls counted") {
+		t.Errorf("Unexpected output for an empty store: %q", out.String())
+	}
+
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, client := range []string{"ci", ""} {
+		limits := []server.QuotaLimit{{QuotaCounter: server.QuotaCounter{Tool: "geocode", Client: client, Period: server.QuotaDay, Start: start}, Limit: 5}}
+		if _, err := store.Take(limits); err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+	}
+
+	cfg := &config.ToolsConfig{}
+	cfg.MCP.Tools = []config.MCPToolConfig{{Name: "geocode", Quota: config.MCPToolQuota{Daily: 5}}}
+
+	out.Reset()
+	if err := listQuotas(&out, store, cfg, server.QuotaFilter{Tool: "geocode"}); err != nil {
+		t.Fatalf("listQuotas() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 counters, got:\n%s", out.String())
+	}
+	for _, expected := range []string{"(all)", "ci", "day", "5", start.AddDate(0, 0, 1).Format(time.RFC3339)} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the output:\n%s", expected, out.String())
+		}
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 6 || fields[3] != "1" || fields[4] != "5" {
+		t.Errorf("Unexpected counter: %q", lines[1])
+	}
+}
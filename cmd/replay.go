@@ -0,0 +1,169 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+var (
+	// replayDryRun shows the commands of the calls replayed instead of running them
+	replayDryRun bool
+
+	// replayCheck fails when the outputs of the calls replayed differ from the recorded ones
+	replayCheck bool
+)
+
+// replayCommand re-sends the tool calls of a recording
+var replayCommand = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "Replay the tool calls recorded in a MCP session",
+	Long: `
+Replay the tool calls recorded with "mcpshell mcp --record <file>".
+
+The tool calls in the recording are sent again, in order, to a server with the
+tools configuration given, and their outputs are compared with the recorded ones.
+It is useful for reproducing bugs and for building regression suites from real
+sessions of the agents.
+
+For example:
+
+$ mcpshell mcp --tools examples/config.yaml --record session.jsonl
+$ mcpshell replay --tools examples/config.yaml session.jsonl
+$ mcpshell replay --tools examples/config.yaml --dry-run session.jsonl
+$ mcpshell replay --tools examples/config.yaml --check session.jsonl
+
+With --dry-run, the commands are shown instead of being run. With --check, the
+command fails when an output is different from the one recorded.
+`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		logger, err := initLogger()
+		if err != nil {
+			return err
+		}
+
+		// Check if config file is provided
+		if len(toolsFiles) == 0 {
+			logger.Error("Tools configuration file(s) are required")
+			return fmt.Errorf("tools configuration file(s) are required. Use --tools flag to specify the path(s)")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := common.GetLogger()
+		defer common.RecoverPanic()
+
+		calls, err := server.ReadRecordedToolCalls(args[0])
+		if err != nil {
+			logger.Error("Failed to read the recording: %v", err)
+			return err
+		}
+
+		// Load the configuration file(s) (local or remote)
+		localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths(toolsFiles, logger)
+		if err != nil {
+			logger.Error("Failed to load configuration: %v", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		defer cleanup()
+
+		differences, err := replayToolCalls(cmd.OutOrStdout(), localConfigPath, calls, replayDryRun, logger)
+		if err != nil {
+			return err
+		}
+		if replayCheck && differences > 0 {
+			return fmt.Errorf("%d of %d tool calls returned a different output than the recorded one", differences, len(calls))
+		}
+		return nil
+	},
+}
+
+// dryRunRunner is a runner that shows the commands instead of running them
+type dryRunRunner struct{}
+
+func (dryRunRunner) Run(ctx context.Context, shell string, command string, env []string, params map[string]interface{}, tmpfile bool) (string, error) {
+	return "[dry-run] " + command, nil
+}
+
+func (dryRunRunner) RunArgv(ctx context.Context, argv []string, env []string) (string, error) {
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		quoted = append(quoted, common.QuoteShellArg(common.ShellPOSIX, arg))
+	}
+	return "[dry-run] " + strings.Join(quoted, " "), nil
+}
+
+func (dryRunRunner) CheckImplicitRequirements() error {
+	return nil
+}
+
+// replayToolCalls sends some tool calls to a server with a configuration, printing
+// their outputs and how they compare with the recorded ones. It returns the number
+// of calls with outputs different from the recorded ones.
+func replayToolCalls(w io.Writer, configFile string, calls []server.RecordedToolCall, dryRun bool, logger *common.Logger) (int, error) {
+	cfg, err := config.NewConfigFromFile(configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration: %v", err)
+		return 0, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	srvConfig := server.Config{ConfigFile: configFile, Logger: logger, Version: version}
+	if dryRun {
+		srvConfig.Runner = dryRunRunner{}
+	}
+	srv := server.New(srvConfig)
+	if err := srv.CreateServer(); err != nil {
+		logger.Error("Failed to create the server: %v", err)
+		return 0, fmt.Errorf("failed to create the server: %w", err)
+	}
+	defer srv.Close()
+
+	differences := 0
+	for i, call := range calls {
+		_, _ = fmt.Fprintf(w, "=== [%d/%d] %s %s\n", i+1, len(calls), call.Name, formatParams(call.Arguments))
+
+		// the shell sessions do not use the runners, so they cannot be run in dry-run
+		if tool, err := findToolConfig(cfg, call.Name); dryRun && err == nil && tool.Type == config.ToolTypeShellSession {
+			_, _ = fmt.Fprintln(w, "(skipped: shell sessions cannot be replayed in dry-run)")
+			continue
+		}
+
+		output, err := srv.ExecuteTool(context.Background(), call.Name, call.Arguments)
+		same := strings.TrimSpace(output) == strings.TrimSpace(call.Output)
+		if err != nil {
+			// the errors of the protocol are recorded without the name of the tool
+			output = err.Error()
+			same = call.IsError && strings.HasSuffix(output, call.Output)
+		}
+		_, _ = fmt.Fprintln(w, strings.TrimRight(output, "\n"))
+
+		switch {
+		case dryRun || !call.Answered:
+		case same:
+			_, _ = fmt.Fprintln(w, "--- same output as recorded")
+		default:
+			differences++
+			_, _ = fmt.Fprintln(w, "--- different output than recorded:")
+			_, _ = fmt.Fprint(w, common.UnifiedDiff(call.Output, output, "recorded", "replayed", 3))
+		}
+	}
+	return differences, nil
+}
+
+func init() {
+	rootCmd.AddCommand(replayCommand)
+
+	replayCommand.Flags().BoolVar(&replayDryRun, "dry-run", false, "Show the commands of the tool calls instead of running them")
+	replayCommand.Flags().BoolVar(&replayCheck, "check", false, "Fail when the output of a tool call is different from the recorded one")
+	_ = replayCommand.MarkFlagRequired("tools")
+}
@@ -0,0 +1,103 @@
+package root
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+func TestReplayToolCalls(t *testing.T) {
+	testLogger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeConfig := func(greeting string) string {
+		configFile := filepath.Join(dir, greeting+".yaml")
+		configContent := `mcp:
+  tools:
+    - name: "greet"
+      description: "Greet someone"
+      params:
+        name:
+          type: string
+          required: true
+      run:
+        command: "echo ` + greeting + ` {{ .name }}"
+`
+		if err := os.WriteFile(configFile, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return configFile
+	}
+	configFile := writeConfig("hello")
+
+	// record a session
+	recording := filepath.Join(dir, "session.jsonl")
+	srv := server.New(server.Config{ConfigFile: configFile, Shell: "sh", Logger: testLogger, RecordFile: recording})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	for _, name := range []string{"John", "Jane"} {
+		if _, err := srv.ExecuteTool(context.Background(), "greet", map[string]interface{}{"name": name}); err != nil {
+			t.Fatalf("Failed to execute the tool: %v", err)
+		}
+	}
+	srv.Close()
+
+	calls, err := server.ReadRecordedToolCalls(recording)
+	if err != nil {
+		t.Fatalf("Failed to read the recording: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		configFile  string
+		dryRun      bool
+		differences int
+		expected    []string
+	}{
+		{
+			name:       "same configuration",
+			configFile: configFile,
+			expected:   []string{"=== [1/2] greet name=John", "hello John", "hello Jane", "--- same output as recorded"},
+		},
+		{
+			name:        "different configuration",
+			configFile:  writeConfig("bye"),
+			differences: 2,
+			expected:    []string{"bye John", "--- different output than recorded:", "-hello John", "+bye John"},
+		},
+		{
+			name:       "dry-run",
+			configFile: configFile,
+			dryRun:     true,
+			expected:   []string{"[dry-run] echo hello John", "[dry-run] echo hello Jane"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			differences, err := replayToolCalls(&out, tt.configFile, calls, tt.dryRun, testLogger)
+			if err != nil {
+				t.Fatalf("replayToolCalls() error = %v", err)
+			}
+			if differences != tt.differences {
+				t.Errorf("Expected %d differences, got %d:\n%s", tt.differences, differences, out.String())
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected %q in the output:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/inercia/MCPShell/pkg/agent"
 	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -30,11 +32,14 @@ var (
 
 	// Agent-specific flags
 	agentModel        string
+	agentModelClass   string
 	agentSystemPrompt string
 	agentUserPrompt   string
 	agentOpenAIApiKey string
 	agentOpenAIApiURL string
 	agentOnce         bool
+	agentResume       string
+	agentLimits       agent.Limits
 
 	// Application version (can be overridden at build time)
 	version = "1.0.0"
@@ -92,6 +97,13 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&logFile, "logfile", "l", "", "Path to the log file (optional)")
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "", "info", "Log level: none, error, info, debug")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging (sets log level to debug)")
+	rootCmd.PersistentFlags().StringVar(&config.Locale, "locale", "", "Locale of the descriptions of the tools (ie, es or pt-BR), overriding the locale in the configuration files")
+	rootCmd.PersistentFlags().StringSliceVar(&config.VerifyKeys, "verify-key", []string{}, "Public key(s) (minisign or PEM), or file(s) with them, for verifying the signatures of the configuration files.\nFiles without a valid detached signature (.minisig or .sig) are refused")
+
+	_ = rootCmd.RegisterFlagCompletionFunc("tools", cobra.FixedCompletions(
+		[]string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-level", cobra.FixedCompletions(
+		[]string{"none", "error", "info", "debug"}, cobra.ShellCompDirectiveNoFileComp))
 
 	// Add version flag to all commands
 	rootCmd.PersistentFlags().Bool("version", false, "Print version information")
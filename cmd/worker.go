@@ -0,0 +1,96 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/workers"
+)
+
+var (
+	workerJoin        string
+	workerName        string
+	workerLabels      []string
+	workerTokenEnv    string
+	workerConcurrency int
+)
+
+// workerCommand runs a worker of the pool of an MCP server
+var workerCommand = &cobra.Command{
+	Use:   "worker",
+	Short: "Run the tools of an MCP server as a remote worker",
+	Long: `
+Join the pool of workers of an MCP server (running in HTTP mode, with the workers
+enabled in its configuration), and run the commands of the tools that must run on
+any of the labels of this worker (the tools with "runs_on").
+
+The worker runs the commands in this host, with the exec runner, and sends their
+outputs back to the server. It authenticates with the token in the environment
+variable given (MCPSHELL_WORKER_TOKEN by default).
+
+For example:
+
+$ export MCPSHELL_WORKER_TOKEN=...
+$ mcpshell worker --join https://mcp.example.com:8080 --labels gpu,prod-bastion
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize logger
+		_, err := initLogger()
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the logger
+		logger := common.GetLogger()
+
+		// Setup panic handler
+		defer common.RecoverPanic()
+
+		token := os.Getenv(workerTokenEnv)
+		if token == "" {
+			return fmt.Errorf("the token of the worker is not set in %s", workerTokenEnv)
+		}
+
+		name := workerName
+		if name == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("failed to get the hostname (use --name): %w", err)
+			}
+			name = hostname
+		}
+
+		// Stop the worker on interrupt
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return workers.RunWorker(ctx, workers.WorkerOptions{
+			URL:         workerJoin,
+			Name:        name,
+			Labels:      workerLabels,
+			Token:       token,
+			Concurrency: workerConcurrency,
+			Logger:      logger,
+		})
+	},
+}
+
+// init adds the worker command to the root command
+func init() {
+	rootCmd.AddCommand(workerCommand)
+
+	workerCommand.Flags().StringVar(&workerJoin, "join", "", "URL of the MCP server coordinating the workers")
+	workerCommand.Flags().StringVar(&workerName, "name", "", "Name of the worker (the hostname by default)")
+	workerCommand.Flags().StringSliceVar(&workerLabels, "labels", []string{}, "Labels of the worker, matched with the runs_on of the tools")
+	workerCommand.Flags().StringVar(&workerTokenEnv, "token-env", "MCPSHELL_WORKER_TOKEN", "Environment variable with the token for joining the server")
+	workerCommand.Flags().IntVar(&workerConcurrency, "concurrency", 1, "Number of commands run at the same time")
+
+	_ = workerCommand.MarkFlagRequired("join")
+	_ = workerCommand.MarkFlagRequired("labels")
+}
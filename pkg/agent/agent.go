@@ -7,6 +7,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -23,14 +24,17 @@ type AgentConfig struct {
 	ToolsFile   string // Path to the YAML configuration file defining available tools
 	UserPrompt  string // Initial user prompt to send to the LLM
 	Once        bool   // Whether to run in one-shot mode (exit after first response)
+	Resume      string // ID of a previous conversation to resume (optional)
+	Limits      Limits // Limits of the run (tool calls, time and cost)
 	Version     string // Version information for the agent
 	ModelConfig        // Embedded model configuration (Model, APIKey, APIURL, Prompts)
 }
 
 // Agent represents an MCP agent
 type Agent struct {
-	config AgentConfig
-	logger *common.Logger
+	config      AgentConfig
+	logger      *common.Logger
+	toolsPrompt string // system prompt and usage guidance from the tools configuration
 }
 
 // New creates a new agent instance
@@ -49,6 +53,18 @@ func (a *Agent) Validate() error {
 		return fmt.Errorf("tools configuration file is required")
 	}
 
+	// A resumed conversation needs a new prompt in one-shot mode
+	if a.config.Once && a.config.Resume != "" && a.config.UserPrompt == "" {
+		a.logger.Error("A user prompt is required for resuming a conversation in one-shot mode")
+		return fmt.Errorf("a user prompt is required for resuming a conversation in one-shot mode")
+	}
+
+	// Validate the limits
+	if err := a.config.Limits.Validate(a.config.Pricing); err != nil {
+		a.logger.Error("Invalid agent limits: %v", err)
+		return fmt.Errorf("invalid agent limits: %w", err)
+	}
+
 	// Validate model configuration using the model manager
 	if err := ValidateModelConfig(a.config.ModelConfig, a.logger); err != nil {
 		a.logger.Error("Model configuration validation failed: %v", err)
@@ -71,6 +87,7 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 		return err
 	}
 	defer cleanup() // Ensure cleanup is called
+	a.toolsPrompt = srv.GetSystemPrompt()
 
 	// Initialize model client
 	client, err := a.initializeModelClient()
@@ -88,8 +105,27 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 	}
 	a.logger.Info("Retrieved %d tools from MCP server", len(openaiTools))
 
-	// Setup conversation
-	messages := a.setupConversation()
+	// Setup conversation (or resume a previous one), saving it after every change
+	conv, err := a.loadConversation()
+	if err != nil {
+		agentOutput <- fmt.Sprintf("Error: %v", err)
+		return err
+	}
+	messages := conv.Messages
+	save := func() {
+		conv.Messages = messages
+		if err := conv.Save(); err != nil {
+			a.logger.Error("Failed to save conversation: %v", err)
+		}
+	}
+	save()
+	agentOutput <- fmt.Sprintf("Conversation %s (resume it with --resume %s)", conv.ID, conv.ID)
+
+	// When the last message of a resumed conversation is a final answer, wait for the user
+	waitForUser := false
+	if last := messages[len(messages)-1]; last.Role == openai.ChatMessageRoleAssistant && len(last.ToolCalls) == 0 {
+		waitForUser = true
+	}
 
 	// Create a single-run context if in --once mode
 	if a.config.Once {
@@ -100,11 +136,34 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 		a.logger.Info("Running in one-shot mode with 30s safety timeout")
 	}
 
+	// Limit the wall time of the whole run
+	budget := newBudget(a.config.Limits, a.config.Pricing)
+	if maxTime, _ := a.config.Limits.maxTime(); maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, maxTime,
+			fmt.Errorf("%w: the maximum time (%s) was reached", ErrBudgetExceeded, maxTime))
+		defer cancel()
+	}
+
 	// Main interaction loop
 	for {
+		if waitForUser {
+			waitForUser = false
+			agentOutput <- "\nYou: "
+			input, err := a.readUserInput(ctx, userInput, agentOutput)
+			if err != nil || input == nil {
+				return a.checkBudgetExceeded(ctx, err, agentOutput)
+			}
+			messages = append(messages, *input)
+			save()
+		}
+
 		// Get response from the model
 		resp, err := a.callLLM(ctx, client, messages, openaiTools)
 		if err != nil {
+			if err := a.checkBudgetExceeded(ctx, err, agentOutput); errors.Is(err, ErrBudgetExceeded) {
+				return err
+			}
 			agentOutput <- fmt.Sprintf("Error: %v", err)
 			return err
 		}
@@ -120,6 +179,16 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 		respMsg := resp.Choices[0].Message
 		messages = append(messages, respMsg)
 
+		// Account for the cost, not running the tools when it is too high
+		if err := budget.addUsage(resp.Usage); err != nil {
+			if respMsg.Content != "" {
+				agentOutput <- fmt.Sprintf("Assistant: %s", respMsg.Content)
+			}
+			messages = append(messages, skippedToolCalls(respMsg.ToolCalls, err.Error())...)
+			save()
+			return a.stopForBudget(err, agentOutput)
+		}
+
 		// Check for tool calls
 		if len(respMsg.ToolCalls) > 0 {
 			// Process tool calls
@@ -128,9 +197,15 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 			}
 
 			// Execute the tool calls
-			toolMessages := a.executeToolCalls(ctx, srv, respMsg.ToolCalls, agentOutput)
+			toolMessages, err := a.executeToolCalls(ctx, srv, respMsg.ToolCalls, budget, agentOutput)
 			messages = append(messages, toolMessages...)
+			save()
+			if err != nil {
+				return a.stopForBudget(err, agentOutput)
+			}
 		} else {
+			save()
+
 			// No tool calls, just print the message
 			agentOutput <- fmt.Sprintf("Assistant: %s", respMsg.Content)
 
@@ -156,22 +231,92 @@ func (a *Agent) Run(ctx context.Context, userInput chan string, agentOutput chan
 
 		// Get user input for the next interaction (skipped in one-shot mode)
 		agentOutput <- "\nYou: "
-		select {
-		case <-ctx.Done():
-			a.logger.Info("Context cancelled, terminating agent Run loop.")
-			return ctx.Err()
-		case input, ok := <-userInput:
-			if !ok {
-				a.logger.Info("User input channel closed, terminating conversation.")
-				agentOutput <- "User input closed. Conversation terminated."
-				return nil
-			}
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleUser,
-				Content: input,
-			})
+		input, err := a.readUserInput(ctx, userInput, agentOutput)
+		if err != nil || input == nil {
+			return a.checkBudgetExceeded(ctx, err, agentOutput)
 		}
+		messages = append(messages, *input)
+		save()
+	}
+}
+
+// checkBudgetExceeded checks if an error was caused by the maximum time of the run,
+// stopping the agent gracefully in that case. Other errors are returned as they are.
+func (a *Agent) checkBudgetExceeded(ctx context.Context, err error, agentOutput chan string) error {
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, ErrBudgetExceeded) {
+		return a.stopForBudget(cause, agentOutput)
 	}
+	return err
+}
+
+// stopForBudget reports the agent is stopping because some limit was reached
+func (a *Agent) stopForBudget(err error, agentOutput chan string) error {
+	a.logger.Info("Stopping the agent: %v", err)
+	agentOutput <- fmt.Sprintf("Stopping: %v", err)
+	return err
+}
+
+// skippedToolCalls returns the results for tool calls that were not executed
+func skippedToolCalls(toolCalls []openai.ToolCall, reason string) []openai.ChatCompletionMessage {
+	var res []openai.ChatCompletionMessage
+	for _, call := range toolCalls {
+		res = append(res, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    fmt.Sprintf("Error: not executed: %s", reason),
+			ToolCallID: call.ID,
+		})
+	}
+	return res
+}
+
+// readUserInput waits for the next message of the user, returning nil when
+// the input is closed.
+func (a *Agent) readUserInput(ctx context.Context, userInput chan string, agentOutput chan string) (*openai.ChatCompletionMessage, error) {
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Context cancelled, terminating agent Run loop.")
+		return nil, ctx.Err()
+	case input, ok := <-userInput:
+		if !ok {
+			a.logger.Info("User input channel closed, terminating conversation.")
+			agentOutput <- "User input closed. Conversation terminated."
+			return nil, nil
+		}
+		return &openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: input,
+		}, nil
+	}
+}
+
+// loadConversation creates a new conversation, or loads the conversation
+// being resumed (adding the new user prompt, if any)
+func (a *Agent) loadConversation() (*Conversation, error) {
+	if a.config.Resume == "" {
+		conv, err := NewConversation(a.config.Model)
+		if err != nil {
+			return nil, err
+		}
+		conv.Messages = a.setupConversation()
+		return conv, nil
+	}
+
+	conv, err := LoadConversation(a.config.Resume)
+	if err != nil {
+		a.logger.Error("Failed to resume conversation: %v", err)
+		return nil, fmt.Errorf("failed to resume conversation: %w", err)
+	}
+	if len(conv.Messages) == 0 {
+		conv.Messages = a.setupConversation()
+	} else if a.config.UserPrompt != "" {
+		conv.Messages = append(conv.Messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: a.config.UserPrompt,
+		})
+	}
+	a.logger.Info("Resuming conversation %s with %d messages", conv.ID, len(conv.Messages))
+
+	return conv, nil
 }
 
 // setupServer initializes and creates the MCP server
@@ -218,6 +363,11 @@ func (a *Agent) setupConversation() []openai.ChatCompletionMessage {
 		a.logger.Info("Using system prompt from config")
 	}
 
+	// Add the prompt shipped with the tools (with the guidance for using them)
+	if a.toolsPrompt != "" {
+		systemPrompt += "\n\n" + a.toolsPrompt
+	}
+
 	if !strings.Contains(systemPrompt, "terminate the conversation") {
 		systemPrompt += "\n\nWhen you have completed your task, please type 'TERMINATE' to end the conversation."
 	}
@@ -242,12 +392,26 @@ func (a *Agent) setupConversation() []openai.ChatCompletionMessage {
 	return messages
 }
 
-// executeToolCalls processes and executes tool calls from the LLM response
-func (a *Agent) executeToolCalls(ctx context.Context, srv *server.Server, toolCalls []openai.ToolCall, agentOutput chan string) []openai.ChatCompletionMessage {
+// executeToolCalls processes and executes tool calls from the LLM response.
+// Calls not allowed by the budget are skipped, returning an error wrapping
+// ErrBudgetExceeded when the agent must stop.
+func (a *Agent) executeToolCalls(ctx context.Context, srv *server.Server, toolCalls []openai.ToolCall, budget *budget, agentOutput chan string) ([]openai.ChatCompletionMessage, error) {
 	var toolMessages []openai.ChatCompletionMessage
 
 	// Process each tool call
-	for _, call := range toolCalls {
+	for i, call := range toolCalls {
+		reason, err := budget.allowToolCall(i)
+		if err != nil {
+			agentOutput <- fmt.Sprintf("Tool %s not executed: %v", call.Function.Name, err)
+			return append(toolMessages, skippedToolCalls(toolCalls[i:], err.Error())...), err
+		}
+		if reason != "" {
+			a.logger.Info("Tool call '%s' skipped: %s", call.Function.Name, reason)
+			agentOutput <- fmt.Sprintf("Tool %s not executed: %s", call.Function.Name, reason)
+			toolMessages = append(toolMessages, skippedToolCalls([]openai.ToolCall{call}, reason)...)
+			continue
+		}
+
 		a.logger.Info("Processing tool call: %s", call.Function.Name)
 		a.logger.Debug("Raw tool arguments: %s", call.Function.Arguments)
 
@@ -302,7 +466,7 @@ func (a *Agent) executeToolCalls(ctx context.Context, srv *server.Server, toolCa
 		agentOutput <- fmt.Sprintf("Tool %s result: %s", call.Function.Name, toolResult)
 	}
 
-	return toolMessages
+	return toolMessages, nil
 }
 
 // callLLM makes a chat completion call to the LLM with context cancellation support
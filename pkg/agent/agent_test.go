@@ -237,6 +237,25 @@ func TestSetupConversation(t *testing.T) {
 	}
 }
 
+func TestSetupConversation_ToolsPrompt(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelError, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	agent := New(AgentConfig{
+		ModelConfig: ModelConfig{
+			Prompts: common.PromptsConfig{System: []string{"model prompt"}},
+		},
+	}, logger)
+	agent.toolsPrompt = "Guidance for using the tools:\n\n- cleanup: Ask first."
+
+	messages := agent.setupConversation()
+	if !strings.HasPrefix(messages[0].Content, "model prompt\n\nGuidance for using the tools:") {
+		t.Errorf("Expected the tools prompt after the model prompt, got %q", messages[0].Content)
+	}
+}
+
 func TestInitializeModelClient(t *testing.T) {
 	logger, err := common.NewLogger("", "", common.LogLevelError, false)
 	if err != nil {
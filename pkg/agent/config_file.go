@@ -25,11 +25,13 @@ type ModelConfig struct {
 	APIKey  string               `yaml:"api-key,omitempty"` // API key, optional
 	APIURL  string               `yaml:"api-url,omitempty"` // API URL, optional
 	Prompts common.PromptsConfig `yaml:"prompts,omitempty"` // Prompts configuration, optional
+	Pricing ModelPricing         `yaml:"pricing,omitempty"` // Price per million tokens, optional
 }
 
 // AgentConfigFile holds the agent configuration from file
 type AgentConfigFile struct {
 	Models []ModelConfig `yaml:"models"`
+	Limits Limits        `yaml:"limits,omitempty"` // Limits of the runs, optional
 }
 
 // Config holds the complete agent configuration
@@ -0,0 +1,163 @@
+// Package agent provides the persistence of the agent conversations
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/inercia/MCPShell/pkg/utils"
+)
+
+// ConversationsDir is the directory where the conversations are kept.
+// When empty, the conversations directory in the MCPShell home is used.
+var ConversationsDir = ""
+
+// conversationIDRegex matches the valid conversation IDs
+var conversationIDRegex = regexp.MustCompile(`^[a-f0-9]{12}$`)
+
+// Conversation is a conversation with the LLM, with all the messages
+// (including the tool calls and their results)
+type Conversation struct {
+	ID       string                         `json:"id"`
+	Model    string                         `json:"model"`
+	Created  time.Time                      `json:"created"`
+	Updated  time.Time                      `json:"updated"`
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+}
+
+// NewConversation creates a new (empty) conversation with a random ID
+func NewConversation(model string) (*Conversation, error) {
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to create conversation ID: %w", err)
+	}
+
+	now := time.Now()
+	return &Conversation{
+		ID:      hex.EncodeToString(idBytes),
+		Model:   model,
+		Created: now,
+		Updated: now,
+	}, nil
+}
+
+// conversationsDir returns the directory where the conversations are kept, creating it if needed
+func conversationsDir() (string, error) {
+	dir := ConversationsDir
+	if dir == "" {
+		var err error
+		dir, err = utils.GetMCPShellConversationsDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine conversations directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+	return dir, nil
+}
+
+// conversationPath returns the path of the file of a conversation
+func conversationPath(id string) (string, error) {
+	if !conversationIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid conversation ID: '%s'", id)
+	}
+	dir, err := conversationsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// LoadConversation reads a conversation from disk
+func LoadConversation(id string) (*Conversation, error) {
+	path, err := conversationPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown conversation: '%s'", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read conversation %s: %w", id, err)
+	}
+
+	var conv Conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return nil, fmt.Errorf("failed to read conversation %s: %w", id, err)
+	}
+	return &conv, nil
+}
+
+// ListConversations returns all the conversations on disk, the most recently updated first
+func ListConversations() ([]*Conversation, error) {
+	dir, err := conversationsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversations directory: %w", err)
+	}
+
+	var res []*Conversation
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if !found || !conversationIDRegex.MatchString(id) {
+			continue
+		}
+		conv, err := LoadConversation(id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, conv)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Updated.After(res[j].Updated)
+	})
+	return res, nil
+}
+
+// Save persists the conversation, updating its modification time
+func (c *Conversation) Save() error {
+	path, err := conversationPath(c.ID)
+	if err != nil {
+		return err
+	}
+
+	c.Updated = time.Now()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// conversations can contain sensitive outputs of the tools
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save conversation %s: %w", c.ID, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Summary returns the first message of the user in the conversation
+func (c *Conversation) Summary() string {
+	for _, msg := range c.Messages {
+		if msg.Role == openai.ChatMessageRoleUser {
+			return msg.Content
+		}
+	}
+	return ""
+}
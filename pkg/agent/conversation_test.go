@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestConversation_SaveAndLoad(t *testing.T) {
+	ConversationsDir = t.TempDir()
+	defer func() { ConversationsDir = "" }()
+
+	conv, err := NewConversation("gpt-4o")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	conv.Messages = []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful assistant."},
+		{Role: openai.ChatMessageRoleUser, Content: "Why is my disk full?"},
+		{
+			Role: openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{{
+				ID:       "call_1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "disk_usage", Arguments: `{"path":"/"}`},
+			}},
+		},
+		{Role: openai.ChatMessageRoleTool, Content: "95% used", ToolCallID: "call_1"},
+	}
+	if err := conv.Save(); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	loaded, err := LoadConversation(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to load conversation: %v", err)
+	}
+	if loaded.Model != "gpt-4o" || len(loaded.Messages) != 4 {
+		t.Fatalf("Unexpected conversation loaded: %+v", loaded)
+	}
+	if loaded.Messages[2].ToolCalls[0].Function.Name != "disk_usage" || loaded.Messages[3].ToolCallID != "call_1" {
+		t.Errorf("Tool calls not preserved: %+v", loaded.Messages)
+	}
+	if loaded.Summary() != "Why is my disk full?" {
+		t.Errorf("Unexpected summary %q", loaded.Summary())
+	}
+
+	// a newer conversation is listed first
+	time.Sleep(10 * time.Millisecond)
+	other, err := NewConversation("llama3")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+	list, err := ListConversations()
+	if err != nil {
+		t.Fatalf("Failed to list conversations: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != other.ID || list[1].ID != conv.ID {
+		t.Errorf("Unexpected conversations listed: %+v", list)
+	}
+
+	if _, err := LoadConversation("000000000000"); err == nil {
+		t.Error("Expected error for unknown conversation")
+	}
+	if _, err := LoadConversation("../agent"); err == nil {
+		t.Error("Expected error for invalid conversation ID")
+	}
+}
+
+func TestAgent_LoadConversation(t *testing.T) {
+	ConversationsDir = t.TempDir()
+	defer func() { ConversationsDir = "" }()
+
+	logger, err := common.NewLogger("", "", common.LogLevelError, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	// a new conversation starts with the system and user prompts
+	a := New(AgentConfig{UserPrompt: "hello", ModelConfig: ModelConfig{Model: "gpt-4o"}}, logger)
+	conv, err := a.loadConversation()
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if len(conv.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(conv.Messages))
+	}
+	conv.Messages = append(conv.Messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "hi"})
+	if err := conv.Save(); err != nil {
+		t.Fatalf("Failed to save conversation: %v", err)
+	}
+
+	// a resumed conversation keeps the messages, adding the new prompt
+	a = New(AgentConfig{UserPrompt: "how are you?", Resume: conv.ID, ModelConfig: ModelConfig{Model: "gpt-4o"}}, logger)
+	resumed, err := a.loadConversation()
+	if err != nil {
+		t.Fatalf("Failed to resume conversation: %v", err)
+	}
+	if resumed.ID != conv.ID || len(resumed.Messages) != 4 {
+		t.Fatalf("Unexpected resumed conversation: %+v", resumed)
+	}
+	if last := resumed.Messages[3]; last.Role != openai.ChatMessageRoleUser || last.Content != "how are you?" {
+		t.Errorf("Unexpected last message: %+v", last)
+	}
+
+	a = New(AgentConfig{Resume: "abcdefabcdef", ModelConfig: ModelConfig{Model: "gpt-4o"}}, logger)
+	if _, err := a.loadConversation(); err == nil {
+		t.Error("Expected error when resuming an unknown conversation")
+	}
+}
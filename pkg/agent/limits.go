@@ -0,0 +1,111 @@
+// Package agent provides the limits of the agent, stopping it before it does too much
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrBudgetExceeded is returned when the agent stops because some limit was reached
+var ErrBudgetExceeded = errors.New("agent budget exceeded")
+
+// Limits are the limits of the agent in a run. Zero values mean no limit.
+type Limits struct {
+	MaxToolCallsPerTurn int     `yaml:"max-tool-calls-per-turn,omitempty"` // Maximum tool calls executed for a single LLM response
+	MaxToolCalls        int     `yaml:"max-tool-calls,omitempty"`          // Maximum tool calls executed in total
+	MaxTime             string  `yaml:"max-time,omitempty"`                // Maximum wall time (ie, "10m")
+	MaxCost             float64 `yaml:"max-cost,omitempty"`                // Maximum cost, using the pricing of the model
+}
+
+// ModelPricing is the price of a model, per million tokens
+type ModelPricing struct {
+	Input  float64 `yaml:"input,omitempty"`  // Price of one million input (prompt) tokens
+	Output float64 `yaml:"output,omitempty"` // Price of one million output (completion) tokens
+}
+
+// Merge returns the limits, with the (non-zero) values of other overriding them
+func (l Limits) Merge(other Limits) Limits {
+	if other.MaxToolCallsPerTurn != 0 {
+		l.MaxToolCallsPerTurn = other.MaxToolCallsPerTurn
+	}
+	if other.MaxToolCalls != 0 {
+		l.MaxToolCalls = other.MaxToolCalls
+	}
+	if other.MaxTime != "" {
+		l.MaxTime = other.MaxTime
+	}
+	if other.MaxCost != 0 {
+		l.MaxCost = other.MaxCost
+	}
+	return l
+}
+
+// Validate checks the limits are valid
+func (l Limits) Validate(pricing ModelPricing) error {
+	if l.MaxToolCallsPerTurn < 0 || l.MaxToolCalls < 0 || l.MaxCost < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	if _, err := l.maxTime(); err != nil {
+		return err
+	}
+	if l.MaxCost > 0 && pricing.Input == 0 && pricing.Output == 0 {
+		return fmt.Errorf("a maximum cost requires the pricing of the model")
+	}
+	return nil
+}
+
+// maxTime returns the maximum wall time, or zero when there is no limit
+func (l Limits) maxTime() (time.Duration, error) {
+	if l.MaxTime == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(l.MaxTime)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid maximum time '%s'", l.MaxTime)
+	}
+	return d, nil
+}
+
+// budget keeps track of the resources used by the agent
+type budget struct {
+	limits  Limits
+	pricing ModelPricing
+
+	toolCalls int     // tool calls executed
+	cost      float64 // accumulated cost of the LLM calls
+}
+
+// newBudget creates a new budget for some limits and the pricing of the model
+func newBudget(limits Limits, pricing ModelPricing) *budget {
+	return &budget{limits: limits, pricing: pricing}
+}
+
+// addUsage accounts for the tokens used in a LLM call, returning an
+// error when the maximum cost is exceeded
+func (b *budget) addUsage(usage openai.Usage) error {
+	b.cost += float64(usage.PromptTokens)*b.pricing.Input/1e6 +
+		float64(usage.CompletionTokens)*b.pricing.Output/1e6
+
+	if b.limits.MaxCost > 0 && b.cost >= b.limits.MaxCost {
+		return fmt.Errorf("%w: the cost (%.4f) reached the maximum (%.4f)", ErrBudgetExceeded, b.cost, b.limits.MaxCost)
+	}
+	return nil
+}
+
+// allowToolCall checks if the tool call number n (starting at 0) in the current
+// turn can be executed, accounting for it when it is allowed.
+// It returns an error wrapping ErrBudgetExceeded when the agent must stop, or
+// a reason when only this call must be skipped.
+func (b *budget) allowToolCall(n int) (string, error) {
+	if b.limits.MaxToolCalls > 0 && b.toolCalls >= b.limits.MaxToolCalls {
+		return "", fmt.Errorf("%w: the maximum number of tool calls (%d) was reached", ErrBudgetExceeded, b.limits.MaxToolCalls)
+	}
+	if b.limits.MaxToolCallsPerTurn > 0 && n >= b.limits.MaxToolCallsPerTurn {
+		return fmt.Sprintf("only %d tool calls can be executed at a time", b.limits.MaxToolCallsPerTurn), nil
+	}
+	b.toolCalls++
+	return "", nil
+}
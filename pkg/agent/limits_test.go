@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		pricing ModelPricing
+		wantErr bool
+	}{
+		{"no limits", Limits{}, ModelPricing{}, false},
+		{"all limits", Limits{MaxToolCallsPerTurn: 2, MaxToolCalls: 10, MaxTime: "5m", MaxCost: 1}, ModelPricing{Input: 3, Output: 15}, false},
+		{"negative", Limits{MaxToolCalls: -1}, ModelPricing{}, true},
+		{"invalid time", Limits{MaxTime: "soon"}, ModelPricing{}, true},
+		{"cost without pricing", Limits{MaxCost: 1}, ModelPricing{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate(tt.pricing)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimits_Merge(t *testing.T) {
+	limits := Limits{MaxToolCalls: 10, MaxTime: "5m"}.Merge(Limits{MaxToolCalls: 3, MaxCost: 2})
+	expected := Limits{MaxToolCalls: 3, MaxTime: "5m", MaxCost: 2}
+	if limits != expected {
+		t.Errorf("Expected %+v, got %+v", expected, limits)
+	}
+}
+
+func TestBudget_ToolCalls(t *testing.T) {
+	b := newBudget(Limits{MaxToolCallsPerTurn: 2, MaxToolCalls: 3}, ModelPricing{})
+
+	// first turn: the third call is skipped
+	for i, wantSkipped := range []bool{false, false, true} {
+		reason, err := b.allowToolCall(i)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if (reason != "") != wantSkipped {
+			t.Errorf("Call %d: expected skipped=%v, got reason %q", i, wantSkipped, reason)
+		}
+	}
+
+	// second turn: the total is reached after one call
+	if _, err := b.allowToolCall(0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := b.allowToolCall(1); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+}
+
+func TestBudget_Cost(t *testing.T) {
+	b := newBudget(Limits{MaxCost: 0.05}, ModelPricing{Input: 3, Output: 15})
+
+	// 10000*3/1e6 + 1000*15/1e6 = 0.045
+	if err := b.addUsage(openai.Usage{PromptTokens: 10000, CompletionTokens: 1000}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := b.addUsage(openai.Usage{PromptTokens: 2000}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+
+	// no limits
+	b = newBudget(Limits{}, ModelPricing{Input: 3, Output: 15})
+	if err := b.addUsage(openai.Usage{PromptTokens: 1e9}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package agent
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/inercia/MCPShell/pkg/common"
 	"github.com/sashabaranov/go-openai"
@@ -36,6 +37,7 @@ func NewModelManager(logger *common.Logger) *ModelManager {
 	// Register all supported providers
 	manager.RegisterProvider("openai", &OpenAIProvider{})
 	manager.RegisterProvider("ollama", &OllamaProvider{})
+	manager.RegisterProvider("anthropic", &AnthropicProvider{})
 
 	return manager
 }
@@ -142,6 +144,60 @@ func (p *OllamaProvider) GetProviderName() string {
 	return "Ollama"
 }
 
+// AnthropicProvider implements ModelProvider for Anthropic (Claude) models
+type AnthropicProvider struct{}
+
+// The default URL of the OpenAI-compatible API of Anthropic, and the environment
+// variable used for the API key when none is configured
+const (
+	AnthropicDefaultAPIURL = "https://api.anthropic.com/v1/"
+	AnthropicAPIKeyEnv     = "ANTHROPIC_API_KEY"
+)
+
+func (p *AnthropicProvider) InitializeClient(config ModelConfig, logger *common.Logger) (*openai.Client, error) {
+	apiKey := p.getAPIKey(config)
+	if apiKey == "" {
+		logger.Error("API key is required for Anthropic models")
+		return nil, fmt.Errorf("API key is required for Anthropic models")
+	}
+
+	// Anthropic provides an OpenAI-compatible API (including tool calls)
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.BaseURL = AnthropicDefaultAPIURL
+	if config.APIURL != "" {
+		clientConfig.BaseURL = config.APIURL
+	}
+
+	client := openai.NewClientWithConfig(clientConfig)
+	logger.Info("Initialized Anthropic client with model: %s", config.Model)
+	return client, nil
+}
+
+func (p *AnthropicProvider) ValidateConfig(config ModelConfig, logger *common.Logger) error {
+	if config.Model == "" {
+		return fmt.Errorf("model name is required for Anthropic models")
+	}
+
+	if p.getAPIKey(config) == "" {
+		return fmt.Errorf("API key is required for Anthropic models (set %s or pass via config/flags)", AnthropicAPIKeyEnv)
+	}
+
+	logger.Debug("Anthropic model configuration validated: %s", config.Model)
+	return nil
+}
+
+func (p *AnthropicProvider) GetProviderName() string {
+	return "Anthropic"
+}
+
+// getAPIKey returns the API key in the configuration, or the one in the environment
+func (p *AnthropicProvider) getAPIKey(config ModelConfig) string {
+	if config.APIKey != "" {
+		return config.APIKey
+	}
+	return os.Getenv(AnthropicAPIKeyEnv)
+}
+
 // GenericProvider implements ModelProvider for unknown/generic model types
 // This allows for extensibility with other OpenAI-compatible APIs
 type GenericProvider struct {
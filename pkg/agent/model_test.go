@@ -27,7 +27,7 @@ func TestNewModelManager(t *testing.T) {
 	}
 
 	// Test that default providers are registered
-	expectedProviders := []string{"openai", "ollama"}
+	expectedProviders := []string{"openai", "ollama", "anthropic"}
 	for _, providerClass := range expectedProviders {
 		if _, exists := manager.providers[providerClass]; !exists {
 			t.Errorf("Expected provider '%s' to be registered", providerClass)
@@ -378,6 +378,72 @@ func TestOllamaProvider(t *testing.T) {
 	})
 }
 
+func TestAnthropicProvider(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelError, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	provider := &AnthropicProvider{}
+
+	t.Run("GetProviderName", func(t *testing.T) {
+		name := provider.GetProviderName()
+		if name != "Anthropic" {
+			t.Errorf("Expected provider name 'Anthropic', got '%s'", name)
+		}
+	})
+
+	t.Run("InitializeClient", func(t *testing.T) {
+		config := ModelConfig{
+			Model:  "claude-sonnet-4-5",
+			APIKey: "test-key",
+		}
+
+		client, err := provider.InitializeClient(config, logger)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if client == nil {
+			t.Error("Expected client to be initialized")
+		}
+	})
+
+	t.Run("API key from the environment", func(t *testing.T) {
+		t.Setenv(AnthropicAPIKeyEnv, "env-key")
+		config := ModelConfig{
+			Model: "claude-sonnet-4-5",
+		}
+
+		if err := provider.ValidateConfig(config, logger); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := provider.InitializeClient(config, logger); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ValidateConfig missing API key", func(t *testing.T) {
+		t.Setenv(AnthropicAPIKeyEnv, "")
+		config := ModelConfig{
+			Model: "claude-sonnet-4-5",
+		}
+
+		if err := provider.ValidateConfig(config, logger); err == nil {
+			t.Error("Expected error for missing API key")
+		}
+	})
+
+	t.Run("ValidateConfig missing model", func(t *testing.T) {
+		config := ModelConfig{
+			APIKey: "test-key",
+		}
+
+		if err := provider.ValidateConfig(config, logger); err == nil {
+			t.Error("Expected error for missing model")
+		}
+	})
+}
+
 func TestGenericProvider(t *testing.T) {
 	logger, err := common.NewLogger("", "", common.LogLevelError, false)
 	if err != nil {
@@ -0,0 +1,93 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// MaxPreviousOutputs is the maximum number of outputs kept for comparing them with the
+// next runs of the tools (with output.diff). The oldest outputs are discarded when the
+// limit is reached.
+var MaxPreviousOutputs = 1000
+
+// diffContextLines is the number of unchanged lines shown around the changes
+const diffContextLines = 3
+
+// previousOutput is the last successful output of a tool for some arguments
+type previousOutput struct {
+	output  string
+	updated time.Time
+}
+
+// previousOutputsStore keeps the last successful outputs of the tools, by tool and arguments
+type previousOutputsStore struct {
+	mu      sync.Mutex
+	outputs map[string]*previousOutput
+}
+
+// previousOutputs is the store shared by all the tools
+var previousOutputs = &previousOutputsStore{outputs: map[string]*previousOutput{}}
+
+// swap stores the new output for a key, returning the previous one (if any)
+func (s *previousOutputsStore) swap(key string, output string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, found := s.outputs[key]
+	if found {
+		old := previous.output
+		previous.output, previous.updated = output, time.Now()
+		return old, true
+	}
+
+	for len(s.outputs) >= MaxPreviousOutputs && len(s.outputs) > 0 {
+		oldestKey := ""
+		for k, stored := range s.outputs {
+			if oldestKey == "" || stored.updated.Before(s.outputs[oldestKey].updated) {
+				oldestKey = k
+			}
+		}
+		delete(s.outputs, oldestKey)
+	}
+	s.outputs[key] = &previousOutput{output: output, updated: time.Now()}
+	return "", false
+}
+
+// previousOutputKey returns the key of the outputs of the tool for some arguments
+// (only the parameters of the tool are considered, with their defaults applied)
+func (h *CommandHandler) previousOutputKey(params map[string]interface{}) string {
+	args := make(map[string]interface{}, len(h.params))
+	for name := range h.params {
+		if value, exists := params[name]; exists {
+			args[name] = value
+		}
+	}
+	// maps are marshalled with their keys sorted, so the key is stable
+	data, err := json.Marshal(args)
+	if err != nil {
+		return h.toolName
+	}
+	return h.toolName + "\x00" + string(data)
+}
+
+// diffWithPrevious returns the differences between an output and the previous output of
+// the tool for the same arguments, followed by the output, and stores it for the next run
+func (h *CommandHandler) diffWithPrevious(key string, output string) string {
+	previous, found := previousOutputs.swap(key, output)
+	switch {
+	case !found:
+		h.logger.Debug("No previous output of '%s' to compare with", h.toolName)
+		return "No previous output to compare with.\n\nCurrent output:\n\n" + output
+	case previous == output:
+		return "No changes since the previous run.\n\nCurrent output:\n\n" + output
+	default:
+		diff := common.UnifiedDiff(previous, output, "previous", "current", diffContextLines)
+		return "Changes since the previous run:\n\n" + strings.TrimRight(diff, "\n") +
+			"\n\nCurrent output:\n\n" + output
+	}
+}
@@ -3,12 +3,45 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
 )
 
+// toolRunnerOptions are the runner options owned by the tool (set from its configuration,
+// or by the handler), that the clients cannot set in the options of the calls
+var toolRunnerOptions = []string{
+	"workdir",         // validated with the roots of the working directory of the tool
+	"env_passthrough", // the allowlist of the environment inherited by the commands
+	"log_file",        // only set for the background jobs
+	"max_memory",      // the resource limits of the commands
+	"max_cpu_time",
+	"max_processes",
+	"volumes",         // validated with the mount roots of the tool
+	"mounts",          // the host paths mounted by the container runners
+	"docker_run_opts", // the extra arguments of "docker run" (ie, other mounts)
+	"seccomp",         // the seccomp profile of the tool (ie, not "unconfined")
+	"devices",         // the devices of the host available in the containers
+	"gpus",
+}
+
+// executionResult holds the results of executing a tool command
+type executionResult struct {
+	output      string        // the (processed) command output
+	files       []outputFile  // the files produced by the command
+	usage       ResourceUsage // the resources used by the command
+	format      string        // the format detected in the output (with output.format)
+	contentType string        // the MIME type of the output (with output.format)
+	data        []byte        // the binary output (with output.format)
+	image       *outputFile   // the image produced by the command (with output.image_file)
+	audio       *outputFile   // the audio produced by the command (with output.audio_file)
+}
+
 // executeToolCommand handles the core logic of executing a command with the given parameters.
 // This is a common implementation used by both direct execution and MCP handler.
 //
@@ -18,127 +51,373 @@ import (
 //   - extraRunnerOpts: Additional runner options to apply
 //
 // Returns:
-//   - The command output as a string
+//   - The execution result, with the command output, any files produced and the resources
+//     used (also when the command fails)
 //   - A slice of failed constraint messages
 //   - An error if command execution fails
-func (h *CommandHandler) executeToolCommand(ctx context.Context, params map[string]interface{}, extraRunnerOpts map[string]interface{}) (string, []string, error) {
+func (h *CommandHandler) executeToolCommand(ctx context.Context, params map[string]interface{}, extraRunnerOpts map[string]interface{}) (executionResult, []string, error) {
 	// Log the tool execution
 	h.logger.Info("Tool execution requested for '%s'", h.toolName)
 	h.logger.Info("Arguments: %v", params)
 
-	// Apply default values for parameters that aren't provided but have defaults
-	for paramName, paramConfig := range h.params {
-		if _, exists := params[paramName]; !exists && paramConfig.Default != nil {
-			h.logger.Debug("Using default value for parameter '%s': %v", paramName, paramConfig.Default)
-			params[paramName] = paramConfig.Default
-		}
+	// Clients can send no arguments at all
+	if params == nil {
+		params = map[string]interface{}{}
 	}
 
-	// Check for required parameters that weren't provided and don't have defaults
-	for paramName, paramConfig := range h.params {
-		if paramConfig.Required {
-			if _, exists := params[paramName]; !exists {
-				h.logger.Error("Required parameter missing: %s", paramName)
-				return "", nil, fmt.Errorf("required parameter missing: %s", paramName)
-			}
-		}
+	// Check the parameters and the constraints
+	if failedConstraints, err := h.checkParams(ctx, params); err != nil {
+		return executionResult{}, failedConstraints, err
 	}
 
-	// Validate constraints before executing command
-	var failedConstraints []string
-	if h.constraintsCompiled != nil {
-		h.logger.Debug("Checking %d constraints", len(h.constraints))
-		satisfied, failed, err := h.constraintsCompiled.Evaluate(params, h.params)
+	// The outputs are compared with the previous output for the same arguments
+	var previousKey string
+	if h.output.Diff {
+		previousKey = h.previousOutputKey(params)
+	}
+
+	// Create the ephemeral workspace (if enabled), removing it when done
+	workspace, cleanupWorkspace, err := h.createWorkspace()
+	if err != nil {
+		h.logger.Error("Error creating workspace: %v", err)
+		return executionResult{}, nil, err
+	}
+	succeeded := false
+	defer func() { cleanupWorkspace(!succeeded) }()
+	if workspace != "" {
+		params[WorkspaceParam] = workspace
+	}
+
+	// Write any file content parameters, replacing their values by the file paths
+	cleanupFiles, err := h.writeFileContentParams(params, workspace)
+	if err != nil {
+		h.logger.Error("Error writing file content parameters: %v", err)
+		return executionResult{}, nil, err
+	}
+	defer cleanupFiles()
+
+	// Obtain the secrets referenced in the command, standard input, environment and computed values
+	if h.secrets != nil {
+		templates := append(append([]string{h.cmd, h.stdin}, h.argv...), h.envVars...)
+		for _, step := range h.expect {
+			templates = append(templates, step.Send)
+		}
+		secrets, err := h.secrets.Resolve(ctx, append(templates, h.computed.Templates()...)...)
 		if err != nil {
-			h.logger.Error("Error evaluating constraints: %v", err)
-			return "", nil, fmt.Errorf("error evaluating constraints: %v", err)
+			h.logger.Error("Error obtaining secrets: %v", err)
+			return executionResult{}, nil, err
 		}
-		if !satisfied {
-			h.logger.Info("Constraints not satisfied, blocking execution")
-			failedConstraints = failed
-			errorMsg := "command execution blocked by constraints"
+		if len(secrets) > 0 {
+			params[common.SecretsParam] = secrets
+		}
+	}
 
-			// Add details about which constraints failed
-			if len(failedConstraints) > 0 {
-				errorMsg += ":\n"
-				for i, fc := range failedConstraints {
-					errorMsg += fmt.Sprintf("- Constraint %d: %s", i+1, fc)
-					if i < len(failedConstraints)-1 {
-						errorMsg += "\n"
-					}
-				}
-			}
+	// Make the state of the session available to templates (empty without a session)
+	params[StateParam] = sessionStates.get(sessionKey(ctx))
 
-			return "", failedConstraints, fmt.Errorf("%s", errorMsg)
-		}
-		h.logger.Debug("All constraints satisfied")
+	// Add the values computed from the parameters
+	if err := h.computed.Evaluate(params); err != nil {
+		h.logger.Error("Error evaluating computed values: %v", err)
+		return executionResult{}, nil, err
 	}
 
-	// Process the command template with the tool arguments
+	// Process the command template (or the arguments of the command) with the tool arguments
 	// h.logger.Debug("Processing command template:\n%s", h.cmd)
 
-	cmd, err := common.ProcessTemplate(h.cmd, params)
+	cmd, argv, err := h.renderCommand(params)
 	if err != nil {
 		h.logger.Error("Error processing command template: %v", err)
-		return "", nil, fmt.Errorf("error processing command template: %v", err)
+		return executionResult{}, nil, err
 	}
 
 	// h.logger.Debug("Processed command: %s", cmd)
 
-	// Prepare environment variables
-	env := h.getEnvironmentVariables(params)
+	// Process the standard input template (if configured)
+	var stdin string
+	if h.stdin != "" {
+		stdin, err = common.ProcessTemplate(h.stdin, params)
+		if err != nil {
+			h.logger.Error("Error processing standard input template: %v", err)
+			return executionResult{}, nil, fmt.Errorf("error processing standard input template: %v", err)
+		}
+	}
+
+	// Prepare environment variables (the ones in the context take precedence)
+	env := append(h.getEnvironmentVariables(params), extraEnv(ctx)...)
+
+	// Get the working directory (if configured)
+	workdir, err := h.getWorkdir(params)
+	if err != nil {
+		h.logger.Error("Invalid working directory: %v", err)
+		return executionResult{}, nil, err
+	}
+
+	// Take a snapshot of the output files, so we can detect the files produced
+	var outputFiles *outputFilesSnapshot
+	if len(h.output.Files) > 0 {
+		baseDir := workdir
+		if baseDir == "" {
+			baseDir = workspace
+		}
+		outputFiles, err = newOutputFilesSnapshot(h.output.Files, baseDir, params)
+		if err != nil {
+			h.logger.Error("Error processing output files: %v", err)
+			return executionResult{}, nil, err
+		}
+	}
 
-	h.logger.Info("Executing command:")
-	h.logger.Info("\n------------------------------------------------------\n%s\n------------------------------------------------------\n", cmd)
+	if h.fn == nil {
+		h.logger.Info("Executing command:")
+		h.logger.Info("\n------------------------------------------------------\n%s\n------------------------------------------------------\n", cmd)
+	}
 
 	// Determine which runner to use based on the configuration
 	runnerType := RunnerTypeExec // default runner
 	if h.runnerType != "" {
 		h.logger.Debug("Using configured runner type: %s", h.runnerType)
-		switch h.runnerType {
-		case string(RunnerTypeExec):
-			runnerType = RunnerTypeExec
-		case string(RunnerTypeSandboxExec):
-			runnerType = RunnerTypeSandboxExec
-		case string(RunnerTypeFirejail):
-			runnerType = RunnerTypeFirejail
-		default:
-			h.logger.Error("Unknown runner type '%s', falling back to default runner", h.runnerType)
-		}
+		runnerType = RunnerType(h.runnerType)
 	}
 
-	// Start with the configured runner options from the tool definition
-	runnerOptions := RunnerOptions{}
-	for k, v := range h.runnerOpts {
-		runnerOptions[k] = v
+	// Start with the configured runner options from the tool definition, processing
+	// the templates in them with the tool arguments
+	runnerOptions, err := renderRunnerOptions(h.runnerOpts, params)
+	if err != nil {
+		h.logger.Error("Invalid runner options: %v", err)
+		return executionResult{}, nil, err
 	}
 
-	// Add or override with any options from the parameters if present
+	// Add or override with any options from the parameters if present, but the ones
+	// owned by the tool (that the clients cannot set)
 	if extraRunnerOpts != nil {
 		h.logger.Debug("Found runner options in parameters: %v", extraRunnerOpts)
 		for k, v := range extraRunnerOpts {
+			if slices.Contains(toolRunnerOptions, k) {
+				h.logger.Info("Ignoring runner option '%s' in the call: it is owned by the tool", k)
+				continue
+			}
 			runnerOptions[k] = v
 		}
 	}
 
-	// Create the appropriate runner with options
-	h.logger.Debug("Creating runner of type %s and checking implicit requirements", runnerType)
-	runner, err := NewRunner(runnerType, runnerOptions, h.logger.Logger)
-	if err != nil {
-		h.logger.Error("Error creating runner: %v", err)
-		return "", nil, fmt.Errorf("error creating runner: %v", err)
+	// Check the runner options with the constraints of the runner
+	if failed, err := h.checkRunnerConstraints(runnerOptions); err != nil {
+		h.logger.Error("Runner options rejected: %v", err)
+		return executionResult{}, failed, err
+	}
+
+	// Only inherit the allowed environment variables from the parent process (unless the
+	// runner options of the tool set their own list)
+	if _, exists := runnerOptions["env_passthrough"]; !exists {
+		envPassthrough := h.envPassthrough
+		if len(envPassthrough) == 0 {
+			envPassthrough = DefaultEnvPassthrough
+		}
+		runnerOptions["env_passthrough"] = envPassthrough
+	}
+
+	// Use the tool working directory, unless the runner options of the tool set their own
+	if workdir != "" {
+		if _, exists := runnerOptions["workdir"]; !exists {
+			h.logger.Debug("Using working directory: %s", workdir)
+			runnerOptions["workdir"] = workdir
+		}
+	}
+
+	// Mount the host paths (and temporary filesystems) in the containers
+	if len(h.mounts) > 0 {
+		mounts, err := h.getMounts(params)
+		if err != nil {
+			h.logger.Error("Invalid mounts: %v", err)
+			return executionResult{}, nil, err
+		}
+		runnerOptions["volumes"] = mounts
+	}
+
+	// Apply the seccomp profile of the tool
+	if h.seccomp != "" {
+		runnerOptions["seccomp"] = h.seccomp
+	}
+
+	// Pass the standard input to the command
+	if stdin != "" {
+		h.logger.Debug("Passing %d bytes to the standard input", len(stdin))
+		runnerOptions["stdin"] = stdin
+	}
+
+	// Run the command in a pseudo-terminal
+	if h.pty {
+		runnerOptions["pty"] = true
+		runnerOptions["pty_rows"] = h.terminal.Rows
+		runnerOptions["pty_cols"] = h.terminal.Cols
+	}
+
+	// Copy the output to the log file of the job (only when running in a job)
+	if logFile := jobLogFile(ctx); logFile != "" {
+		runnerOptions["log_file"] = logFile
+	}
+
+	// Terminate the command with the configured signals
+	if len(h.termination) > 0 {
+		runnerOptions["termination"] = h.termination
+	}
+
+	// Limit the time the command can run
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	// Answer the prompts of the command with the processed responses
+	if len(h.expect) > 0 {
+		steps := make([]ExpectStep, 0, len(h.expect))
+		for _, step := range h.expect {
+			send, err := common.ProcessTemplate(step.Send, params)
+			if err != nil {
+				h.logger.Error("Error processing response template: %v", err)
+				return executionResult{}, nil, fmt.Errorf("error processing response for prompt '%s': %v", step.Expect, err)
+			}
+			steps = append(steps, ExpectStep{Expect: step.Expect, Send: send})
+		}
+		runnerOptions["expect"] = steps
 	}
 
-	// Execute the command
-	commandOutput, err := runner.Run(ctx, h.shell, cmd, env, params, true)
+	// Measure the resources used by the command
+	ctx, recorder := withUsageRecorder(ctx)
+	start := time.Now()
+
+	var commandOutput string
+	switch {
+	case h.fn != nil:
+		// Call the function implementing the tool (without the secrets, only used in templates)
+		h.logger.Info("Calling the function of tool '%s'", h.toolName)
+		args := make(map[string]interface{}, len(params))
+		for k, v := range params {
+			if k != common.SecretsParam {
+				args[k] = v
+			}
+		}
+		commandOutput, err = h.fn(ctx, args)
+	case h.toolType == config.ToolTypeShellSession:
+		// Run the command in the long-lived shell of the session
+		commandOutput, err = h.runInShellSession(ctx, cmd, env, runnerOptions)
+		notifyCommand(ctx, ExecutedCommand{Tool: h.toolName, Runner: config.ToolTypeShellSession, Command: cmd,
+			Output: commandOutput, Duration: time.Since(start)}, err)
+	default:
+		// Create the appropriate runner with options (unless a runner has been set)
+		runner := h.runner
+		if runner == nil {
+			h.logger.Debug("Creating runner of type %s and checking implicit requirements", runnerType)
+			runner, err = NewRunner(runnerType, runnerOptions, h.logger.Logger)
+			if err != nil {
+				h.logger.Error("Error creating runner: %v", err)
+				return executionResult{}, nil, fmt.Errorf("error creating runner: %v", err)
+			}
+		}
+
+		commandOutput, err = h.runCommand(ctx, runner, runnerType, cmd, argv, env, params)
+		notifyCommand(ctx, ExecutedCommand{Tool: h.toolName, Runner: string(runnerType), Command: cmd, Argv: argv,
+			Output: commandOutput, Duration: time.Since(start)}, err)
+	}
+	usage := recorder.get()
+	usage.WallTime = time.Since(start)
+	usage.OutputSize = len(commandOutput)
+	h.logger.Debug("Command used %.3fs of wall time, %.3fs of CPU time and %d bytes of memory, with %d bytes of output",
+		usage.WallTime.Seconds(), usage.CPUTime.Seconds(), usage.MaxRSS, usage.OutputSize)
 	if err != nil {
 		h.logger.Error("Error executing command: %v", err)
-		return "", nil, err
+		if h.timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("command timed out after %s: %w", h.timeout, err)
+		}
+		return executionResult{usage: usage}, nil, errors.New(common.Redact(err.Error()))
+	}
+
+	// Collect the files produced by the command
+	var files []outputFile
+	if outputFiles != nil {
+		files, err = outputFiles.Changed()
+		if err != nil {
+			h.logger.Error("Error collecting output files: %v", err)
+			return executionResult{}, nil, err
+		}
+		h.logger.Debug("Command produced %d output files", len(files))
+	}
+
+	// Read the image and the audio produced by the command
+	baseDir := workdir
+	if baseDir == "" {
+		baseDir = workspace
+	}
+	var image, audio *outputFile
+	for _, media := range []struct {
+		name string
+		path string
+		file **outputFile
+	}{
+		{"image", h.output.ImageFile, &image},
+		{"audio", h.output.AudioFile, &audio},
+	} {
+		if media.path == "" {
+			continue
+		}
+		file, err := readMediaFile(media.path, baseDir, params, media.name)
+		if err != nil {
+			h.logger.Error("Error reading the %s: %v", media.name, err)
+			return executionResult{usage: usage}, nil, err
+		}
+		h.logger.Debug("Command produced %s %s (%s, %d bytes)", media.name, file.path, file.mimeType, file.size)
+		*media.file = &file
+	}
+
+	// Convert tabular outputs (before validating them, as the schema describes the converted output)
+	if h.output.Convert != "" {
+		commandOutput, err = common.ConvertOutput(h.output.Convert, commandOutput)
+		if err != nil {
+			h.logger.Error("Error converting the output with %s: %v", h.output.Convert, err)
+			return executionResult{}, nil, errors.New(common.Redact(fmt.Sprintf("error converting the output: %v", err)))
+		}
+	}
+
+	// Detect the format of the output, parsing it (or returning it as a blob when binary)
+	var format, contentType string
+	var data []byte
+	switch h.output.Format {
+	case common.FormatCSV, common.FormatTSV:
+		format, contentType = h.output.Format, "application/json"
+		if commandOutput, err = common.ParseDelimitedOutput(format, h.output.CSV, commandOutput); err != nil {
+			h.logger.Error("Error parsing the %s output: %v", format, err)
+			return executionResult{}, nil, errors.New(common.Redact(fmt.Sprintf("error parsing the output: %v", err)))
+		}
+	case common.OutputFormatAuto:
+		format = common.DetectOutputFormat(commandOutput)
+		contentType = common.OutputContentType(format, commandOutput)
+		h.logger.Debug("Output detected as %s (%s)", format, contentType)
+		if format == common.FormatBinary {
+			data = []byte(commandOutput)
+			commandOutput = fmt.Sprintf("Binary output (%s, %d bytes)", contentType, len(data))
+		} else if commandOutput, err = common.ParseOutputFormat(format, commandOutput); err != nil {
+			h.logger.Error("Error parsing the %s output: %v", format, err)
+			return executionResult{}, nil, errors.New(common.Redact(fmt.Sprintf("error parsing the output: %v", err)))
+		}
+	}
+
+	// Check the output conforms to the schema promised to clients
+	if h.output.Validate && h.outputSchema != nil {
+		if err := h.outputSchema.ValidateJSON(commandOutput); err != nil {
+			h.logger.Error("Output does not conform to the output schema: %v", err)
+			return executionResult{}, nil, errors.New(common.Redact(fmt.Sprintf("output does not conform to the output schema: %v", err)))
+		}
+		h.logger.Debug("Output conforms to the output schema")
 	}
 
 	// Process the output
 	finalOutput := commandOutput
 
+	// Show the changes since the previous run
+	if h.output.Diff {
+		finalOutput = h.diffWithPrevious(previousKey, commandOutput)
+	}
+
 	// Apply prefix if provided
 	if h.output.Prefix != "" {
 		h.logger.Debug("Applying output prefix template: %s", h.output.Prefix)
@@ -147,7 +426,7 @@ func (h *CommandHandler) executeToolCommand(ctx context.Context, params map[stri
 		prefix, err := common.ProcessTemplate(h.output.Prefix, params)
 		if err != nil {
 			h.logger.Error("Error processing output prefix template: %v", err)
-			return "", nil, fmt.Errorf("error processing output prefix template: %v", err)
+			return executionResult{}, nil, fmt.Errorf("error processing output prefix template: %v", err)
 		}
 
 		// Combine prefix and command output
@@ -155,8 +434,261 @@ func (h *CommandHandler) executeToolCommand(ctx context.Context, params map[stri
 		h.logger.Debug("Final output with prefix:\n--------------------------------\n%s\n--------------------------------", finalOutput)
 	}
 
+	// Add links to the files produced
+	if len(files) > 0 {
+		finalOutput = strings.TrimRight(finalOutput, "\n") + "\n\n" + formatOutputFiles(files)
+	}
+
+	// Never return any secrets to the client
+	finalOutput = common.Redact(finalOutput)
+
 	h.logger.Info("Tool execution completed successfully")
-	return finalOutput, nil, nil
+	succeeded = true
+	return executionResult{
+		output:      finalOutput,
+		files:       files,
+		usage:       usage,
+		format:      format,
+		contentType: contentType,
+		data:        data,
+		image:       image,
+		audio:       audio,
+	}, nil, nil
+}
+
+// checkParams prepares the parameters of an execution, ignoring the values of hidden
+// parameters and applying the defaults, and then checks the required parameters
+// and the constraints.
+//
+// Parameters:
+//   - ctx: Context of the call, with the information of the request for the constraints (if any)
+//   - params: Map of parameter names to their values (modified in place)
+//
+// Returns:
+//   - A slice of failed constraint messages
+//   - An error if some parameter is missing or some constraint is not satisfied
+func (h *CommandHandler) checkParams(ctx context.Context, params map[string]interface{}) ([]string, error) {
+	if err := h.prepareParams(params); err != nil {
+		return nil, err
+	}
+
+	// Check the tool can be called now (ie, in a maintenance window)
+	if h.window != nil {
+		now := time.Now()
+		if request := common.RequestInfoFromContext(ctx); request != nil && !request.Time.IsZero() {
+			now = request.Time
+		}
+		if !h.window.Allows(now) {
+			h.logger.Info("Tool '%s' called outside of its allowed time window: %s", h.toolName, h.window)
+			return nil, fmt.Errorf("tool '%s' can only be called in %s: try again later", h.toolName, h.window)
+		}
+	}
+
+	// Validate constraints before executing command
+	var failedConstraints []string
+	if h.constraintsCompiled != nil {
+		h.logger.Debug("Checking %d constraints", len(h.constraints))
+		satisfied, failed, err := h.constraintsCompiled.EvaluateRequest(params, h.params, common.RequestInfoFromContext(ctx))
+		if err != nil {
+			h.logger.Error("Error evaluating constraints: %v", err)
+			return nil, fmt.Errorf("error evaluating constraints: %v", err)
+		}
+		if !satisfied {
+			h.logger.Info("Constraints not satisfied, blocking execution")
+			failedConstraints = failed
+			errorMsg := "command execution blocked by constraints"
+
+			// Add details about which constraints failed
+			if len(failedConstraints) > 0 {
+				errorMsg += ":\n"
+				for i, fc := range failedConstraints {
+					errorMsg += fmt.Sprintf("- Constraint %d: %s", i+1, fc)
+					if i < len(failedConstraints)-1 {
+						errorMsg += "\n"
+					}
+				}
+			}
+
+			return failedConstraints, fmt.Errorf("%s", errorMsg)
+		}
+		h.logger.Debug("All constraints satisfied")
+	}
+
+	return nil, nil
+}
+
+// prepareParams ignores the values of the hidden parameters and applies the defaults,
+// checking the required parameters are provided (modifying the parameters in place)
+func (h *CommandHandler) prepareParams(params map[string]interface{}) error {
+	// Hidden parameters cannot be set by clients: they always get their default value
+	for paramName, paramConfig := range h.params {
+		if _, exists := params[paramName]; exists && paramConfig.Hidden {
+			h.logger.Info("Ignoring value provided for hidden parameter '%s'", paramName)
+			delete(params, paramName)
+		}
+	}
+
+	// Apply default values for parameters that aren't provided but have defaults
+	for paramName, paramConfig := range h.params {
+		if _, exists := params[paramName]; exists {
+			continue
+		}
+		defaultValue, err := paramConfig.GetDefault()
+		if err != nil {
+			h.logger.Error("Error obtaining default value for parameter '%s': %v", paramName, err)
+			return fmt.Errorf("error obtaining default value for parameter '%s': %w", paramName, err)
+		}
+		if defaultValue != nil {
+			h.logger.Debug("Using default value for parameter '%s': %v", paramName, defaultValue)
+			params[paramName] = defaultValue
+		}
+	}
+
+	// Check for required parameters that weren't provided and don't have defaults
+	for paramName, paramConfig := range h.params {
+		if paramConfig.Required {
+			if _, exists := params[paramName]; !exists {
+				h.logger.Error("Required parameter missing: %s", paramName)
+				return fmt.Errorf("required parameter missing: %s", paramName)
+			}
+		}
+	}
+
+	// Check the values of the types with a format (ie, the URLs are allowed), normalizing them
+	for paramName, paramConfig := range h.params {
+		if value, exists := params[paramName]; exists {
+			normalized, err := paramConfig.NormalizeValue(value)
+			if err != nil {
+				h.logger.Info("Invalid value for parameter '%s': %v", paramName, err)
+				return fmt.Errorf("invalid value for parameter '%s': %w", paramName, err)
+			}
+			params[paramName] = normalized
+		}
+	}
+
+	return nil
+}
+
+// ExplainConstraints evaluates all the constraints of the tool for some parameters
+// (after applying the defaults) and the context of the request (if any), returning the
+// result of every constraint with the values of the variables it references. It is used
+// for debugging why calls are rejected.
+func (h *CommandHandler) ExplainConstraints(params map[string]interface{}, request *common.RequestInfo) ([]common.ConstraintResult, error) {
+	if err := h.prepareParams(params); err != nil {
+		return nil, err
+	}
+	return h.constraintsCompiled.Explain(params, h.params, request), nil
+}
+
+// RenderCommand returns the command that would be executed for some parameters,
+// without running it. It is used for describing the tools. The secrets are replaced
+// by placeholders, and the state of the session is empty.
+//
+// Parameters:
+//   - params: Map of parameter names to their values (modified in place)
+//
+// Returns:
+//   - The command
+//   - A slice of failed constraint messages
+//   - An error if the parameters are not valid or the command cannot be rendered
+func (h *CommandHandler) RenderCommand(params map[string]interface{}) (string, []string, error) {
+	if failedConstraints, err := h.checkParams(context.Background(), params); err != nil {
+		return "", failedConstraints, err
+	}
+
+	if h.workspace.Enabled {
+		params[WorkspaceParam] = "<workspace>"
+	}
+	secrets := map[string]string{}
+	for _, name := range common.SecretReferences(append(append([]string{h.cmd}, h.argv...), h.computed.Templates()...)...) {
+		secrets[name] = fmt.Sprintf("<secret:%s>", name)
+	}
+	params[common.SecretsParam] = secrets
+	params[StateParam] = map[string]string{}
+
+	if err := h.computed.Evaluate(params); err != nil {
+		return "", nil, err
+	}
+
+	cmd, _, err := h.renderCommand(params)
+	if err != nil {
+		return "", nil, err
+	}
+	return cmd, nil, nil
+}
+
+// runCommand runs a command with a runner: preparing the runner (when it implements
+// RunnerPreparer), running the command (directly, without shell, when it is given as
+// a list of arguments) and killing it when the context is cancelled while it runs
+// (when the runner implements RunnerKiller)
+func (h *CommandHandler) runCommand(ctx context.Context, runner Runner, runnerType RunnerType,
+	cmd string, argv []string, env []string, params map[string]interface{},
+) (string, error) {
+	if preparer, ok := runner.(RunnerPreparer); ok {
+		h.logger.Debug("Preparing the '%s' runner", runnerType)
+		if err := preparer.Prepare(ctx); err != nil {
+			return "", fmt.Errorf("failed to prepare the '%s' runner: %w", runnerType, err)
+		}
+	}
+
+	if killer, ok := runner.(RunnerKiller); ok {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				h.logger.Info("Killing the command of tool '%s': %v", h.toolName, ctx.Err())
+				if err := killer.Kill(); err != nil {
+					h.logger.Error("Failed to kill the command of tool '%s': %v", h.toolName, err)
+				}
+			case <-done:
+			}
+		}()
+	}
+
+	if argv != nil {
+		argvRunner, ok := runner.(ArgvRunner)
+		if !ok {
+			return "", fmt.Errorf("exec arguments are not supported by the '%s' runner", runnerType)
+		}
+		return argvRunner.RunArgv(ctx, argv, env)
+	}
+	return runner.Run(ctx, h.shell, cmd, env, params, true)
+}
+
+// renderCommand processes the command template with the parameters. For commands
+// given as a list of arguments, it also returns the processed arguments (without the
+// optional ones that are empty, see common.IsConditionalTemplate), and the command is
+// only a representation of them for the logs.
+func (h *CommandHandler) renderCommand(params map[string]interface{}) (string, []string, error) {
+	if len(h.argv) == 0 {
+		cmd, err := common.ProcessShellTemplate(h.cmd, params, h.shellKind, h.quoteParams)
+		if err != nil {
+			return "", nil, fmt.Errorf("error processing command template: %v", err)
+		}
+		return cmd, nil, nil
+	}
+
+	argv := make([]string, 0, len(h.argv))
+	for i, arg := range h.argv {
+		processed, err := common.ProcessTemplate(arg, params)
+		if err != nil {
+			return "", nil, fmt.Errorf("error processing argument %d of the command: %v", i, err)
+		}
+		if processed == "" && common.IsConditionalTemplate(arg) {
+			continue
+		}
+		argv = append(argv, processed)
+	}
+	if len(argv) == 0 {
+		return "", nil, fmt.Errorf("the command has no arguments")
+	}
+
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		quoted = append(quoted, common.QuoteShellArg(common.ShellPOSIX, arg))
+	}
+	return strings.Join(quoted, " "), argv, nil
 }
 
 // ExecuteCommand handles the direct execution of a command without going through the MCP server.
@@ -184,12 +716,12 @@ func (h *CommandHandler) ExecuteCommand(params map[string]interface{}) (string,
 	}
 
 	// Use the common implementation
-	output, failedConstraints, err := h.executeToolCommand(context.Background(), params, runnerOpts)
+	result, failedConstraints, err := h.executeToolCommand(WithLocalSession(context.Background()), params, runnerOpts)
 
 	// If constraints failed, format the error message
 	if err != nil && len(failedConstraints) > 0 {
 		return "", err
 	}
 
-	return output, err
+	return result.output, err
 }
@@ -0,0 +1,250 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// MaxOutputFileSize is the maximum size of a file produced by a command
+// that will be returned to the client. Bigger files are only linked.
+const MaxOutputFileSize = 10 * 1024 * 1024
+
+// outputFile is a file produced by a command execution
+type outputFile struct {
+	path     string // the absolute path of the file
+	mimeType string // the detected MIME type
+	size     int64  // the size of the file
+	data     []byte // the file contents (nil if too big)
+}
+
+// URI returns the file:// URI for the file
+func (f outputFile) URI() string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(f.path)}).String()
+}
+
+// ToMCPContent returns the file as a MCP embedded resource, using text contents
+// for text files and base64-encoded blobs for anything else.
+// It returns nil if the file contents are not available.
+func (f outputFile) ToMCPContent() mcp.Content {
+	if f.data == nil {
+		return nil
+	}
+	if strings.HasPrefix(f.mimeType, "text/") {
+		return mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      f.URI(),
+			MIMEType: f.mimeType,
+			Text:     string(f.data),
+		})
+	}
+	return mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+		URI:      f.URI(),
+		MIMEType: f.mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(f.data),
+	})
+}
+
+// outputFilesSnapshot records the modification times of the files matching
+// some glob patterns, so we can detect the files created or modified later on.
+type outputFilesSnapshot struct {
+	patterns []string
+	files    map[string]time.Time
+}
+
+// newOutputFilesSnapshot processes the output files patterns with the given params,
+// making them absolute with baseDir, and takes a snapshot of the matching files.
+func newOutputFilesSnapshot(patterns []string, baseDir string, params map[string]interface{}) (*outputFilesSnapshot, error) {
+	snapshot := &outputFilesSnapshot{
+		files: map[string]time.Time{},
+	}
+
+	for _, pattern := range patterns {
+		processed, err := common.ProcessTemplate(pattern, params)
+		if err != nil {
+			return nil, fmt.Errorf("error processing output files pattern '%s': %w", pattern, err)
+		}
+		processed = strings.TrimSpace(processed)
+		if processed == "" {
+			continue
+		}
+		if !filepath.IsAbs(processed) && baseDir != "" {
+			processed = filepath.Join(baseDir, processed)
+		}
+		if _, err := filepath.Match(processed, ""); err != nil {
+			return nil, fmt.Errorf("invalid output files pattern '%s': %w", processed, err)
+		}
+		snapshot.patterns = append(snapshot.patterns, processed)
+	}
+
+	snapshot.files = snapshot.matches()
+	return snapshot, nil
+}
+
+// matches returns all the regular files matching the patterns, with their modification times
+func (s *outputFilesSnapshot) matches() map[string]time.Time {
+	res := map[string]time.Time{}
+	for _, pattern := range s.patterns {
+		matches, _ := filepath.Glob(pattern) // patterns have already been validated
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if abs, err := filepath.Abs(match); err == nil {
+				match = abs
+			}
+			res[match] = info.ModTime()
+		}
+	}
+	return res
+}
+
+// Changed returns the files that have been created or modified since the snapshot was taken
+func (s *outputFilesSnapshot) Changed() ([]outputFile, error) {
+	var res []outputFile
+
+	current := s.matches()
+	paths := make([]string, 0, len(current))
+	for path, modTime := range current {
+		if prevModTime, existed := s.files[path]; existed && prevModTime.Equal(modTime) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat output file %s: %w", path, err)
+		}
+
+		file := outputFile{
+			path:     path,
+			size:     info.Size(),
+			mimeType: mime.TypeByExtension(filepath.Ext(path)),
+		}
+
+		if info.Size() <= MaxOutputFileSize {
+			file.data, err = os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read output file %s: %w", path, err)
+			}
+		}
+
+		if file.mimeType == "" {
+			if file.data != nil {
+				file.mimeType = http.DetectContentType(file.data)
+			} else {
+				file.mimeType = "application/octet-stream"
+			}
+		}
+
+		res = append(res, file)
+	}
+
+	return res, nil
+}
+
+// formatOutputFiles returns a text listing the files produced by the command,
+// suitable for appending to the command output.
+func formatOutputFiles(files []outputFile) string {
+	var sb strings.Builder
+	sb.WriteString("Files:")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "\n- %s (%s, %d bytes)", f.URI(), f.mimeType, f.size)
+		if f.data == nil {
+			sb.WriteString(" [too big to be returned]")
+		}
+	}
+	return sb.String()
+}
+
+// audioTypes are the MIME types of the usual audio files, by extension (as they are
+// not always known by the system)
+var audioTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".weba": "audio/webm",
+}
+
+// mediaType returns the MIME type of an image or an audio file, from its content or, when
+// it is not detected, from its extension (ie, for SVG images or FLAC files)
+func mediaType(path string, data []byte, media string) string {
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "audio/wave":
+		mimeType = "audio/wav"
+	case "application/ogg":
+		mimeType = "audio/ogg"
+	}
+	if strings.HasPrefix(mimeType, media+"/") {
+		return mimeType
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if mimeType, found := audioTypes[ext]; found {
+		return mimeType
+	}
+	mimeType, _, _ = strings.Cut(mime.TypeByExtension(ext), ";")
+	return mimeType
+}
+
+// readMediaFile reads an image or an audio file (with media "image" or "audio") produced
+// by a command, in a path (a template processed with the given params, relative to baseDir)
+func readMediaFile(pathTemplate string, baseDir string, params map[string]interface{}, media string) (outputFile, error) {
+	path, err := common.ProcessTemplate(pathTemplate, params)
+	if err != nil {
+		return outputFile{}, fmt.Errorf("error processing %s file '%s': %w", media, pathTemplate, err)
+	}
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return outputFile{}, fmt.Errorf("the command did not produce the %s %s", media, path)
+	}
+	if info.Size() > MaxOutputFileSize {
+		return outputFile{}, fmt.Errorf("the %s %s is too big (%d bytes)", media, path, info.Size())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return outputFile{}, fmt.Errorf("failed to read the %s %s: %w", media, path, err)
+	}
+
+	mimeType := mediaType(path, data, media)
+	if !strings.HasPrefix(mimeType, media+"/") {
+		return outputFile{}, fmt.Errorf("the file %s is not an %s", path, media)
+	}
+	return outputFile{path: path, mimeType: mimeType, size: info.Size(), data: data}, nil
+}
+
+// newAudioContent returns an audio content. The audio contents are not supported by
+// mcp-go yet, but they have the same fields as the images (with the "audio" type).
+func newAudioContent(data []byte, mimeType string) mcp.Content {
+	return mcp.ImageContent{
+		Type:     "audio",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MIMEType: mimeType,
+	}
+}
@@ -0,0 +1,712 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// JobStatusToolName is the name of the built-in tool for getting the status of background jobs
+const JobStatusToolName = "job_status"
+
+// JobLogsToolName is the name of the built-in tool for getting the output of background jobs
+const JobLogsToolName = "job_logs"
+
+// JobKillToolName is the name of the built-in tool for stopping background jobs
+const JobKillToolName = "job_kill"
+
+// The status of the background jobs
+const (
+	JobStatusQueued    = "queued" // waiting in the queue of the jobs backend
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusKilled    = "killed"
+	JobStatusLost      = "lost" // the server stopped while the job was running
+)
+
+// JobsDir is the directory where the background jobs are kept.
+// When empty, the jobs directory in the MCPShell home is used.
+var JobsDir = ""
+
+// JobsRetention is the time finished jobs are kept
+var JobsRetention = 24 * time.Hour
+
+// JobsHeartbeat is the time between the updates of the running jobs. The jobs not
+// updated in three times this time (and not running in this process) are lost.
+var JobsHeartbeat = 10 * time.Second
+
+// DefaultJobLogsLines is the number of lines returned by the logs tool by default
+const DefaultJobLogsLines = 100
+
+// jobIDRegex matches the valid job IDs
+var jobIDRegex = regexp.MustCompile(`^[a-f0-9]{12}$`)
+
+// Job is a command running in background
+type Job struct {
+	ID        string     `json:"id"`
+	Tool      string     `json:"tool"`
+	Owner     string     `json:"owner,omitempty"` // the client (or the session) that started the job
+	Status    string     `json:"status"`
+	Started   time.Time  `json:"started"`
+	Heartbeat *time.Time `json:"heartbeat,omitempty"` // the last time the job was seen running
+	Finished  *time.Time `json:"finished,omitempty"`
+	Output    string     `json:"output,omitempty"` // the (processed) output, when succeeded
+	Error     string     `json:"error,omitempty"`  // the error, when failed
+
+	// the arguments and the runner options of the queued jobs, for running them in any instance
+	Args    map[string]interface{} `json:"args,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// jobFunc runs a job, copying its output to a log file
+type jobFunc func(ctx context.Context, logFile string) (string, error)
+
+// jobsStore keeps the background jobs in a backend
+type jobsStore struct {
+	mu       sync.Mutex
+	backend  JobsBackend
+	running  map[string]context.CancelFunc // the jobs run by this process that are still running
+	logFiles map[string]string             // the log files of the jobs running in this process
+	killed   map[string]bool               // the running jobs that have been killed
+	handlers map[string]*CommandHandler    // the handlers of the async tools, for running the queued jobs
+}
+
+// jobs is the store shared by all the tools
+var jobs = &jobsStore{
+	backend:  &dirJobsBackend{},
+	running:  map[string]context.CancelFunc{},
+	logFiles: map[string]string{},
+	killed:   map[string]bool{},
+	handlers: map[string]*CommandHandler{},
+}
+
+// SetJobsBackend sets the backend where the background jobs are kept (the jobs
+// directory when nil). The jobs are queued when the backend implements JobsQueue,
+// and they are run by the instances consuming the queue (see RunJobsConsumers).
+func SetJobsBackend(backend JobsBackend) {
+	if backend == nil {
+		backend = &dirJobsBackend{}
+	}
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.backend = backend
+}
+
+// queue returns the queue of the backend, or nil when the jobs are not queued
+func (s *jobsStore) queue() JobsQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue, _ := s.backend.(JobsQueue)
+	return queue
+}
+
+// load reads a job. It must be called with the lock held.
+// Running jobs are reported as lost when they are not updated (ie, they were running
+// in a previous execution of the server).
+func (s *jobsStore) load(id string) (*Job, error) {
+	if !jobIDRegex.MatchString(id) {
+		return nil, fmt.Errorf("invalid job ID: '%s'", id)
+	}
+	job, err := s.backend.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	s.checkLost(job)
+	return job, nil
+}
+
+// checkLost marks a job as lost when it is running, but not in this process, and it has
+// not been updated recently. It must be called with the lock held.
+func (s *jobsStore) checkLost(job *Job) {
+	if _, running := s.running[job.ID]; job.Status != JobStatusRunning || running {
+		return
+	}
+	last := job.Started
+	if job.Heartbeat != nil {
+		last = *job.Heartbeat
+	}
+	if time.Since(last) > 3*JobsHeartbeat {
+		job.Status = JobStatusLost
+	}
+}
+
+// newJob creates a job for a tool, owned by the client in the context
+func newJob(ctx context.Context, tool string, status string) (*Job, error) {
+	idBytes := make([]byte, 6)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to create job ID: %w", err)
+	}
+	return &Job{
+		ID:      hex.EncodeToString(idBytes),
+		Tool:    tool,
+		Owner:   jobOwner(ctx),
+		Status:  status,
+		Started: time.Now(),
+	}, nil
+}
+
+// start starts a job running a function in background, returning it (still running).
+// The function gets the path of the log file where the output must be copied.
+func (s *jobsStore) start(ctx context.Context, tool string, run jobFunc) (*Job, error) {
+	job, err := newJob(ctx, tool, JobStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+
+	if err := s.backend.Save(job); err != nil {
+		return nil, err
+	}
+
+	// the job outlives the request that started it
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	logFile, err := s.begin(job, cancel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go s.execute(jobCtx, job, logFile, run)
+
+	res := *job
+	return &res, nil
+}
+
+// enqueue adds a job for a tool to the queue of the backend, returning it (queued)
+func (s *jobsStore) enqueue(ctx context.Context, queue JobsQueue, tool string,
+	args map[string]interface{}, options map[string]interface{},
+) (*Job, error) {
+	job, err := newJob(ctx, tool, JobStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	job.Args, job.Options = args, options
+
+	s.mu.Lock()
+	s.expire()
+	err = s.backend.Save(job)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := queue.Enqueue(ctx, job.ID); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+	res := *job
+	return &res, nil
+}
+
+// begin registers a job as running in this process, returning its log file.
+// It must be called with the lock held.
+func (s *jobsStore) begin(job *Job, cancel context.CancelFunc) (string, error) {
+	logFile, err := s.backend.LogFile(job.ID)
+	if err != nil {
+		return "", err
+	}
+	s.running[job.ID] = cancel
+	s.logFiles[job.ID] = logFile
+	return logFile, nil
+}
+
+// execute runs a job (registered as running), updating it while it runs and saving
+// its result when it finishes
+func (s *jobsStore) execute(ctx context.Context, job *Job, logFile string, run jobFunc) {
+	// update the job while it runs, and stop it when it is killed in another instance
+	done := make(chan struct{})
+	var heartbeats sync.WaitGroup
+	heartbeats.Add(1)
+	go func() {
+		defer heartbeats.Done()
+		ticker := time.NewTicker(JobsHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.heartbeat(job, logFile)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// a panic fails the job, instead of crashing the server
+	output, err := func() (output string, err error) {
+		defer common.CatchPanic(&err)
+		return run(ctx, logFile)
+	}()
+	close(done)
+	heartbeats.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	finished := time.Now()
+	res := *job
+	res.Finished = &finished
+	res.Heartbeat = nil
+	switch {
+	case s.killed[job.ID]:
+		res.Status = JobStatusKilled
+	case err != nil:
+		res.Status = JobStatusFailed
+		res.Error = err.Error()
+	default:
+		res.Status = JobStatusSucceeded
+		res.Output = output
+	}
+	s.running[job.ID]()
+	delete(s.running, job.ID)
+	delete(s.logFiles, job.ID)
+	delete(s.killed, job.ID)
+	_ = s.backend.SyncLog(job.ID, logFile, true)
+	_ = s.backend.Save(&res)
+}
+
+// heartbeat updates a running job, publishing its output, and stops it when it has
+// been killed in another instance
+func (s *jobsStore) heartbeat(job *Job, logFile string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	res := *job
+	res.Heartbeat = &now
+	_ = s.backend.Save(&res)
+	_ = s.backend.SyncLog(job.ID, logFile, false)
+
+	if killed, _ := s.backend.KillRequested(job.ID); killed && !s.killed[job.ID] {
+		s.killed[job.ID] = true
+		s.running[job.ID]()
+	}
+}
+
+// consume runs the next job in the queue (if any), waiting for it to finish
+func (s *jobsStore) consume(ctx context.Context, queue JobsQueue, logger *common.Logger) error {
+	id, err := queue.Dequeue(ctx)
+	if err != nil || id == "" {
+		return err
+	}
+
+	s.mu.Lock()
+	job, err := s.load(id)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if job.Status != JobStatusQueued {
+		s.mu.Unlock()
+		return nil
+	}
+
+	fail := func(status string, message string) error {
+		finished := time.Now()
+		job.Status, job.Error, job.Finished = status, message, &finished
+		defer s.mu.Unlock()
+		return s.backend.Save(job)
+	}
+	if killed, _ := s.backend.KillRequested(id); killed {
+		return fail(JobStatusKilled, "")
+	}
+	handler, found := s.handlers[job.Tool]
+	if !found {
+		return fail(JobStatusFailed, fmt.Sprintf("tool '%s' is not available in the instance running the job", job.Tool))
+	}
+
+	logger.Info("Running queued job %s of tool '%s'", id, job.Tool)
+	args, options := job.Args, job.Options
+	job.Status, job.Args, job.Options = JobStatusRunning, nil, nil
+	now := time.Now()
+	job.Heartbeat = &now
+	if err := s.backend.Save(job); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	logFile, err := s.begin(job, cancel)
+	s.mu.Unlock()
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	s.execute(jobCtx, job, logFile, handler.jobFunc(args, options))
+	return nil
+}
+
+// RunJobsConsumers runs the jobs queued in the backend of the jobs (by this instance or any
+// other one), with some jobs running at the same time, until the context is done.
+// It returns immediately when the backend does not queue the jobs.
+//
+// Parameters:
+//   - ctx: The context, that stops consuming jobs when done (the running jobs are not stopped)
+//   - concurrency: The number of jobs run at the same time
+//   - logger: The logger
+func RunJobsConsumers(ctx context.Context, concurrency int, logger *common.Logger) {
+	queue := jobs.queue()
+	if queue == nil {
+		return
+	}
+	concurrency = max(concurrency, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := jobs.consume(ctx, queue, logger); err != nil && ctx.Err() == nil {
+					logger.Error("Failed to run queued job: %v", err)
+					select {
+					case <-time.After(time.Second):
+					case <-ctx.Done():
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// loadAccessible reads a job the client in the context can access. The jobs of other
+// clients are reported as unknown. It must be called with the lock held.
+func (s *jobsStore) loadAccessible(ctx context.Context, id string) (*Job, error) {
+	job, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccessJob(ctx, job) {
+		return nil, fmt.Errorf("unknown job: '%s'", id)
+	}
+	return job, nil
+}
+
+// get returns a job
+func (s *jobsStore) get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadAccessible(ctx, id)
+}
+
+// list returns all the jobs the client in the context can access, the most recent first
+func (s *jobsStore) list(ctx context.Context) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.backend.List()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Job, 0, len(all))
+	for _, job := range all {
+		if canAccessJob(ctx, job) {
+			s.checkLost(job)
+			res = append(res, job)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Started.After(res[j].Started) })
+	return res, nil
+}
+
+// kill stops a running (or queued) job
+func (s *jobsStore) kill(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.loadAccessible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cancel, running := s.running[id]; running {
+		s.killed[id] = true
+		cancel()
+		return job, nil
+	}
+	if job.Status != JobStatusRunning && job.Status != JobStatusQueued {
+		return nil, fmt.Errorf("job %s is not running (status: %s)", id, job.Status)
+	}
+
+	// the job is stopped by the instance running it (or not run, when queued)
+	if err := s.backend.RequestKill(id); err != nil {
+		return nil, fmt.Errorf("failed to kill job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// logs returns the last lines of the output of a job
+func (s *jobsStore) logs(ctx context.Context, id string, lines int) (string, error) {
+	s.mu.Lock()
+	_, err := s.loadAccessible(ctx, id)
+	logFile, local := s.logFiles[id]
+	s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	if local {
+		data, err = os.ReadFile(logFile)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		data, err = s.backend.ReadLog(id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of job %s: %w", id, err)
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	all := strings.Split(strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+	if lines > 0 && len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return common.Redact(strings.Join(all, "\n")), nil
+}
+
+// expire removes the jobs that finished long ago. It must be called with the lock held.
+func (s *jobsStore) expire() {
+	all, err := s.backend.List()
+	if err != nil {
+		return
+	}
+	for _, job := range all {
+		if job.Status == JobStatusRunning || job.Status == JobStatusQueued {
+			continue
+		}
+		last := job.Started
+		if job.Finished != nil {
+			last = *job.Finished
+		}
+		if time.Since(last) > JobsRetention {
+			_ = s.backend.Remove(job.ID)
+		}
+	}
+}
+
+// String returns a description of the job
+func (j *Job) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Job: %s\n", j.ID)
+	fmt.Fprintf(&sb, "Tool: %s\n", j.Tool)
+	fmt.Fprintf(&sb, "Status: %s\n", j.Status)
+	fmt.Fprintf(&sb, "Started: %s\n", j.Started.Format(time.RFC3339))
+	if j.Finished != nil {
+		fmt.Fprintf(&sb, "Finished: %s (after %s)\n", j.Finished.Format(time.RFC3339), j.Finished.Sub(j.Started).Round(time.Second))
+	} else if j.Status == JobStatusRunning {
+		fmt.Fprintf(&sb, "Running for: %s\n", time.Since(j.Started).Round(time.Second))
+	}
+	if j.Error != "" {
+		fmt.Fprintf(&sb, "\nError:\n%s\n", j.Error)
+	}
+	if j.Output != "" {
+		fmt.Fprintf(&sb, "\nOutput:\n%s\n", j.Output)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// startJob starts the execution of the tool as a background job, or queues it when
+// the backend of the jobs has a queue
+func (h *CommandHandler) startJob(ctx context.Context, params map[string]interface{}, runnerOpts map[string]interface{}) (*Job, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	// fail fast when the parameters are not valid
+	if _, err := h.checkParams(ctx, params); err != nil {
+		return nil, err
+	}
+
+	if queue := jobs.queue(); queue != nil {
+		return jobs.enqueue(ctx, queue, h.toolName, params, runnerOpts)
+	}
+	return jobs.start(ctx, h.toolName, h.jobFunc(params, runnerOpts))
+}
+
+// jobFunc returns the function running the tool as a background job
+func (h *CommandHandler) jobFunc(params map[string]interface{}, runnerOpts map[string]interface{}) jobFunc {
+	return func(ctx context.Context, logFile string) (string, error) {
+		result, _, err := h.executeToolCommand(withJobLogFile(ctx, logFile), params, runnerOpts)
+		return result.output, err
+	}
+}
+
+// jobLogFileKey is the key of the log file of a job in the context
+type jobLogFileKey struct{}
+
+// withJobLogFile returns a context where the output of the command is copied to the
+// log file of a job (while it runs)
+func withJobLogFile(ctx context.Context, logFile string) context.Context {
+	return context.WithValue(ctx, jobLogFileKey{}, logFile)
+}
+
+// jobLogFile returns the log file of the job in a context (empty when not in a job)
+func jobLogFile(ctx context.Context) string {
+	logFile, _ := ctx.Value(jobLogFileKey{}).(string)
+	return logFile
+}
+
+// jobsAccessKey is the key of the access to the background jobs in the context
+type jobsAccessKey struct{}
+
+// jobsAccess is the access of a client to the background jobs
+type jobsAccess struct {
+	owner   string                 // the owner of the jobs started by the client
+	allowed func(tool string) bool // checks the tools of the jobs the client can access (all when nil)
+}
+
+// WithJobsAccess returns a context where the background jobs are owned by a client (ie, the
+// name of the authenticated client), that can only access its own jobs of the tools allowed.
+// Without an owner, the jobs are owned by the MCP session.
+func WithJobsAccess(ctx context.Context, owner string, allowed func(tool string) bool) context.Context {
+	return context.WithValue(ctx, jobsAccessKey{}, jobsAccess{owner: owner, allowed: allowed})
+}
+
+// jobOwner returns the owner of the jobs started in a context: the client (when
+// set with WithJobsAccess), the MCP session or nobody
+func jobOwner(ctx context.Context) string {
+	if access, _ := ctx.Value(jobsAccessKey{}).(jobsAccess); access.owner != "" {
+		return "client:" + access.owner
+	}
+	if id := sessionID(ctx); id != "" {
+		return "session:" + id
+	}
+	return ""
+}
+
+// canAccessJob returns true if the client in a context can access a job: it must be
+// the owner of the job, and be allowed to use the tool of the job
+func canAccessJob(ctx context.Context, job *Job) bool {
+	if job.Owner != jobOwner(ctx) {
+		return false
+	}
+	access, _ := ctx.Value(jobsAccessKey{}).(jobsAccess)
+	return access.allowed == nil || access.allowed(job.Tool)
+}
+
+// registerJobHandler registers the handler of an async tool, for running its queued jobs
+func registerJobHandler(h *CommandHandler) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.handlers[h.toolName] = h
+}
+
+// GetJobTools returns the built-in tools for managing the background jobs
+func GetJobTools() []mcp.Tool {
+	return []mcp.Tool{
+		mcp.NewTool(JobStatusToolName,
+			mcp.WithDescription("Get the status of a background job, including its output when it has finished, "+
+				"or list all the jobs when no job ID is provided."),
+			mcp.WithString("job_id",
+				mcp.Description("The ID of the job (optional)")),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:          "Get job status",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+			}),
+		),
+		mcp.NewTool(JobLogsToolName,
+			mcp.WithDescription("Get the last lines of the output of a background job, even while it is running."),
+			mcp.WithString("job_id",
+				mcp.Description("The ID of the job"),
+				mcp.Required()),
+			mcp.WithNumber("lines",
+				mcp.Description(fmt.Sprintf("The number of lines to return (default: %d, 0 for all the output)", DefaultJobLogsLines))),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:          "Get job logs",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+			}),
+		),
+		mcp.NewTool(JobKillToolName,
+			mcp.WithDescription("Stop a running background job."),
+			mcp.WithString("job_id",
+				mcp.Description("The ID of the job"),
+				mcp.Required()),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:           "Kill job",
+				DestructiveHint: true,
+			}),
+		),
+	}
+}
+
+// JobStatusHandler handles the calls to the built-in tool for getting the status of jobs
+func JobStatusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if id, _ := request.Params.Arguments["job_id"].(string); id != "" {
+		job, err := jobs.get(ctx, id)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(job.String()), nil
+	}
+
+	all, err := jobs.list(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(all) == 0 {
+		return mcp.NewToolResultText("There are no jobs"), nil
+	}
+
+	var sb strings.Builder
+	for _, job := range all {
+		fmt.Fprintf(&sb, "%s %s %s (started %s)\n", job.ID, job.Tool, job.Status, job.Started.Format(time.RFC3339))
+	}
+	return mcp.NewToolResultText(strings.TrimRight(sb.String(), "\n")), nil
+}
+
+// JobLogsHandler handles the calls to the built-in tool for getting the output of jobs
+func JobLogsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["job_id"].(string)
+	if id == "" {
+		return mcp.NewToolResultError("the job ID is required"), nil
+	}
+	lines := DefaultJobLogsLines
+	if n, ok := request.Params.Arguments["lines"].(float64); ok {
+		lines = int(n)
+	}
+
+	logs, err := jobs.logs(ctx, id, lines)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if logs == "" {
+		return mcp.NewToolResultText("The job has not produced any output yet"), nil
+	}
+	return mcp.NewToolResultText(logs), nil
+}
+
+// JobKillHandler handles the calls to the built-in tool for stopping jobs
+func JobKillHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["job_id"].(string)
+	if id == "" {
+		return mcp.NewToolResultError("the job ID is required"), nil
+	}
+
+	if _, err := jobs.kill(ctx, id); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Job %s is being stopped", id)), nil
+}
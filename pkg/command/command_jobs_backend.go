@@ -0,0 +1,263 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/inercia/MCPShell/pkg/utils"
+)
+
+// JobsBackend keeps the background jobs and their logs, so they can be shared by several
+// instances of MCPShell (ie, behind a load balancer) and they survive restarts
+type JobsBackend interface {
+	// Save stores a job, replacing the previous version
+	Save(job *Job) error
+
+	// Load returns a job, or an error when it is unknown
+	Load(id string) (*Job, error)
+
+	// List returns all the jobs (in any order)
+	List() ([]*Job, error)
+
+	// Remove removes a job and its log
+	Remove(id string) error
+
+	// LogFile returns the local file where the output of a job running in this
+	// process must be copied
+	LogFile(id string) (string, error)
+
+	// SyncLog publishes the output copied in the log file of a job (ie, while it runs
+	// and when it finishes), so it can be read by other instances
+	SyncLog(id string, logFile string, finished bool) error
+
+	// ReadLog returns the output of a job
+	ReadLog(id string) ([]byte, error)
+
+	// RequestKill asks the instance running a job to stop it
+	RequestKill(id string) error
+
+	// KillRequested returns true when a job must be stopped
+	KillRequested(id string) (bool, error)
+}
+
+// JobsQueue is implemented by the backends that queue the jobs, so they are run by
+// any of the instances consuming the queue (instead of the instance starting them)
+type JobsQueue interface {
+	// Enqueue adds a job to the queue
+	Enqueue(ctx context.Context, id string) error
+
+	// Dequeue takes a job from the queue, waiting for some time. It returns an empty
+	// ID when there are no jobs.
+	Dequeue(ctx context.Context) (string, error)
+}
+
+// JobsBackendFactory creates a backend with its options
+type JobsBackendFactory func(options map[string]interface{}) (JobsBackend, error)
+
+var (
+	jobsBackendsMu sync.RWMutex
+	jobsBackends   = map[string]JobsBackendFactory{
+		"dir": func(options map[string]interface{}) (JobsBackend, error) {
+			dir, _ := options["dir"].(string)
+			return &dirJobsBackend{dir: dir}, nil
+		},
+		"redis": func(options map[string]interface{}) (JobsBackend, error) {
+			return newRedisJobsBackend(options)
+		},
+	}
+)
+
+// RegisterJobsBackend registers a type of backend for the jobs, so it can be selected by
+// name in the configuration (ie, for adding backends from programs embedding MCPShell)
+//
+// Parameters:
+//   - name: The name of the backend
+//   - factory: The function creating the backend with its options
+//
+// Returns:
+//   - An error if there is another backend with that name
+func RegisterJobsBackend(name string, factory JobsBackendFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("invalid jobs backend")
+	}
+
+	jobsBackendsMu.Lock()
+	defer jobsBackendsMu.Unlock()
+	if _, found := jobsBackends[name]; found {
+		return fmt.Errorf("jobs backend '%s' already registered", name)
+	}
+	jobsBackends[name] = factory
+	return nil
+}
+
+// NewJobsBackend creates a backend for the jobs of some type
+func NewJobsBackend(name string, options map[string]interface{}) (JobsBackend, error) {
+	jobsBackendsMu.RLock()
+	factory, found := jobsBackends[name]
+	var names []string
+	for n := range jobsBackends {
+		names = append(names, n)
+	}
+	jobsBackendsMu.RUnlock()
+
+	if !found {
+		slices.Sort(names)
+		return nil, fmt.Errorf("unknown jobs backend '%s' (available backends: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(options)
+}
+
+// dirJobsBackend keeps the jobs in a local directory (the default backend)
+type dirJobsBackend struct {
+	dir string // the directory (JobsDir or the jobs directory in the MCPShell home when empty)
+}
+
+// path returns the directory of the jobs, creating it if needed
+func (b *dirJobsBackend) path() (string, error) {
+	dir := b.dir
+	if dir == "" {
+		dir = JobsDir
+	}
+	if dir == "" {
+		var err error
+		dir, err = utils.GetMCPShellJobsDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine jobs directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+	return dir, nil
+}
+
+// file returns the path of a file of a job, with some extension
+func (b *dirJobsBackend) file(id string, ext string) (string, error) {
+	if !jobIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid job ID: '%s'", id)
+	}
+	dir, err := b.path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+ext), nil
+}
+
+func (b *dirJobsBackend) Save(job *Job) error {
+	metaPath, err := b.file(job.ID, ".json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := metaPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	return os.Rename(tmp, metaPath)
+}
+
+func (b *dirJobsBackend) Load(id string) (*Job, error) {
+	metaPath, err := b.file(id, ".json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(metaPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown job: '%s'", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (b *dirJobsBackend) List() ([]*Job, error) {
+	dir, err := b.path()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
+	}
+
+	var res []*Job
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if !found || !jobIDRegex.MatchString(id) {
+			continue
+		}
+		if job, err := b.Load(id); err == nil {
+			res = append(res, job)
+		}
+	}
+	return res, nil
+}
+
+func (b *dirJobsBackend) Remove(id string) error {
+	for _, ext := range []string{".json", ".log", ".kill"} {
+		path, err := b.file(id, ext)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *dirJobsBackend) LogFile(id string) (string, error) {
+	return b.file(id, ".log")
+}
+
+func (b *dirJobsBackend) SyncLog(id string, logFile string, finished bool) error {
+	// the log file is already in the directory
+	return nil
+}
+
+func (b *dirJobsBackend) ReadLog(id string) ([]byte, error) {
+	path, err := b.file(id, ".log")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (b *dirJobsBackend) RequestKill(id string) error {
+	path, err := b.file(id, ".kill")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, nil, 0o600)
+}
+
+func (b *dirJobsBackend) KillRequested(id string) (bool, error) {
+	path, err := b.file(id, ".kill")
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
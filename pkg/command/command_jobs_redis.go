@@ -0,0 +1,347 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisDequeueTimeout is the time a consumer waits for a job in the queue of Redis
+var redisDequeueTimeout = 5 * time.Second
+
+// redisJobsBackend keeps the jobs in Redis, with a queue of the jobs waiting to run:
+//
+//   - <prefix>:job:<id>: the job (JSON)
+//   - <prefix>:log:<id>: the output of the job
+//   - <prefix>:kill:<id>: set when the job must be stopped
+//   - <prefix>:jobs: a sorted set with the IDs of the jobs (by start time)
+//   - <prefix>:queue: a list with the IDs of the jobs waiting to run
+type redisJobsBackend struct {
+	client  *redisClient
+	prefix  string
+	logsDir string // the local directory for the logs of the jobs running in this process
+}
+
+// newRedisJobsBackend creates a Redis backend with the options:
+//
+//   - url: the URL of the Redis server (ie, "redis://localhost:6379/0", or "rediss://" for TLS)
+//   - password_env: the environment variable with the password (instead of the one in the URL)
+//   - prefix: the prefix of the keys ("mcpshell" by default)
+func newRedisJobsBackend(options map[string]interface{}) (*redisJobsBackend, error) {
+	rawURL, _ := options["url"].(string)
+	if rawURL == "" {
+		return nil, fmt.Errorf("the redis jobs backend requires an url")
+	}
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if env, _ := options["password_env"].(string); env != "" {
+		client.password = os.Getenv(env)
+	}
+
+	prefix, _ := options["prefix"].(string)
+	if prefix == "" {
+		prefix = "mcpshell"
+	}
+
+	logsDir, err := os.MkdirTemp("", "mcpshell-jobs-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the logs directory: %w", err)
+	}
+	return &redisJobsBackend{client: client, prefix: prefix, logsDir: logsDir}, nil
+}
+
+func (b *redisJobsBackend) key(parts ...string) string {
+	return b.prefix + ":" + strings.Join(parts, ":")
+}
+
+func (b *redisJobsBackend) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", b.key("job", job.ID), string(data)}
+	if job.Finished != nil {
+		args = append(args, "EX", strconv.Itoa(int(JobsRetention.Seconds())))
+	}
+	if _, err := b.client.do(context.Background(), args...); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	_, err = b.client.do(context.Background(), "ZADD", b.key("jobs"), strconv.FormatInt(job.Started.Unix(), 10), job.ID)
+	return err
+}
+
+func (b *redisJobsBackend) Load(id string) (*Job, error) {
+	reply, err := b.client.do(context.Background(), "GET", b.key("job", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unknown job: '%s'", id)
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (b *redisJobsBackend) List() ([]*Job, error) {
+	reply, err := b.client.do(context.Background(), "ZRANGE", b.key("jobs"), "0", "-1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	ids, _ := reply.([]interface{})
+
+	var res []*Job
+	for _, id := range ids {
+		id, _ := id.(string)
+		job, err := b.Load(id)
+		if err != nil {
+			// the job has expired
+			_, _ = b.client.do(context.Background(), "ZREM", b.key("jobs"), id)
+			continue
+		}
+		res = append(res, job)
+	}
+	return res, nil
+}
+
+func (b *redisJobsBackend) Remove(id string) error {
+	_, err := b.client.do(context.Background(), "DEL", b.key("job", id), b.key("log", id), b.key("kill", id))
+	if err != nil {
+		return err
+	}
+	_, err = b.client.do(context.Background(), "ZREM", b.key("jobs"), id)
+	return err
+}
+
+func (b *redisJobsBackend) LogFile(id string) (string, error) {
+	return filepath.Join(b.logsDir, id+".log"), nil
+}
+
+func (b *redisJobsBackend) SyncLog(id string, logFile string, finished bool) error {
+	data, err := os.ReadFile(logFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	args := []string{"SET", b.key("log", id), string(data)}
+	if finished {
+		args = append(args, "EX", strconv.Itoa(int(JobsRetention.Seconds())))
+		_ = os.Remove(logFile)
+	}
+	_, err = b.client.do(context.Background(), args...)
+	return err
+}
+
+func (b *redisJobsBackend) ReadLog(id string) ([]byte, error) {
+	reply, err := b.client.do(context.Background(), "GET", b.key("log", id))
+	if err != nil {
+		return nil, err
+	}
+	data, _ := reply.(string)
+	return []byte(data), nil
+}
+
+func (b *redisJobsBackend) RequestKill(id string) error {
+	_, err := b.client.do(context.Background(), "SET", b.key("kill", id), "1",
+		"EX", strconv.Itoa(int(JobsRetention.Seconds())))
+	return err
+}
+
+func (b *redisJobsBackend) KillRequested(id string) (bool, error) {
+	reply, err := b.client.do(context.Background(), "EXISTS", b.key("kill", id))
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+func (b *redisJobsBackend) Enqueue(ctx context.Context, id string) error {
+	_, err := b.client.do(ctx, "LPUSH", b.key("queue"), id)
+	return err
+}
+
+func (b *redisJobsBackend) Dequeue(ctx context.Context) (string, error) {
+	timeout := strconv.Itoa(max(int(redisDequeueTimeout.Seconds()), 1))
+	reply, err := b.client.do(ctx, "BRPOP", b.key("queue"), timeout)
+	if err != nil {
+		return "", err
+	}
+	// the reply is the name of the list and the element (or nil on timeout)
+	pair, _ := reply.([]interface{})
+	if len(pair) != 2 {
+		return "", nil
+	}
+	id, _ := pair[1].(string)
+	return id, nil
+}
+
+// redisError is an error returned by the Redis server
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// redisClient is a minimal client of Redis (with the RESP protocol), opening a
+// connection for every command
+type redisClient struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+}
+
+// newRedisClient creates a client for a "redis://[user:password@]host:port[/db]" URL
+func newRedisClient(rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid redis URL: unknown scheme '%s'", u.Scheme)
+	}
+
+	c := &redisClient{addr: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database: '%s'", db)
+		}
+	}
+	return c, nil
+}
+
+// do runs a command in the server, returning its reply: a string, an int64, a list of
+// replies or nil
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// the blocking commands wait up to their timeout
+	deadline := time.Now().Add(30 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	var commands [][]string
+	if c.password != "" {
+		if c.username != "" {
+			commands = append(commands, []string{"AUTH", c.username, c.password})
+		} else {
+			commands = append(commands, []string{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	commands = append(commands, args)
+
+	var reply interface{}
+	for _, command := range commands {
+		if _, err := conn.Write(encodeRedisCommand(command)); err != nil {
+			return nil, fmt.Errorf("failed to send redis command: %w", err)
+		}
+		if reply, err = readRedisReply(r); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("redis %s failed: %w", command[0], err)
+		}
+	}
+	return reply, nil
+}
+
+// encodeRedisCommand encodes a command as an array of bulk strings
+func encodeRedisCommand(args []string) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(sb.String())
+}
+
+// readRedisReply reads a reply of the server
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		res := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readRedisReply(r)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, item)
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply: %q", line)
+	}
+}
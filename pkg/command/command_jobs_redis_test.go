@@ -0,0 +1,294 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// fakeRedis is a Redis server with the commands used by the jobs backend
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	zsets   map[string]map[string]float64
+	lists   map[string][]string
+	pushed  chan struct{} // closed (and replaced) when an element is pushed to a list
+	auth    string        // the password required (if any)
+}
+
+func startFakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	f := &fakeRedis{
+		strings: map[string]string{},
+		zsets:   map[string]map[string]float64{},
+		lists:   map[string][]string{},
+		pushed:  make(chan struct{}),
+		auth:    password,
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	authenticated := f.auth == ""
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		var args []string
+		for _, item := range items {
+			s, _ := item.(string)
+			args = append(args, s)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			authenticated = args[len(args)-1] == f.auth
+			if !authenticated {
+				_, _ = conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+				continue
+			}
+			_, _ = conn.Write([]byte("+OK\r\n"))
+			continue
+		case !authenticated:
+			_, _ = conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		_, _ = conn.Write([]byte(f.do(cmd, args[1:])))
+	}
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (f *fakeRedis) do(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch cmd {
+	case "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		f.strings[args[0]] = args[1]
+		return "+OK\r\n"
+	case "GET":
+		if v, found := f.strings[args[0]]; found {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case "EXISTS":
+		if _, found := f.strings[args[0]]; found {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "DEL":
+		for _, k := range args {
+			delete(f.strings, k)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args))
+	case "ZADD":
+		if f.zsets[args[0]] == nil {
+			f.zsets[args[0]] = map[string]float64{}
+		}
+		score, _ := strconv.ParseFloat(args[1], 64)
+		f.zsets[args[0]][args[2]] = score
+		return ":1\r\n"
+	case "ZREM":
+		delete(f.zsets[args[0]], args[1])
+		return ":1\r\n"
+	case "ZRANGE":
+		var members []string
+		for m := range f.zsets[args[0]] {
+			members = append(members, m)
+		}
+		sort.Strings(members)
+		res := fmt.Sprintf("*%d\r\n", len(members))
+		for _, m := range members {
+			res += bulk(m)
+		}
+		return res
+	case "LPUSH":
+		f.lists[args[0]] = append([]string{args[1]}, f.lists[args[0]]...)
+		close(f.pushed)
+		f.pushed = make(chan struct{})
+		return ":1\r\n"
+	case "BRPOP":
+		timeout, _ := strconv.Atoi(args[1])
+		deadline := time.After(time.Duration(timeout) * time.Second)
+		for {
+			if list := f.lists[args[0]]; len(list) > 0 {
+				f.lists[args[0]] = list[:len(list)-1]
+				return "*2\r\n" + bulk(args[0]) + bulk(list[len(list)-1])
+			}
+			pushed := f.pushed
+			f.mu.Unlock()
+			select {
+			case <-pushed:
+				f.mu.Lock()
+			case <-deadline:
+				f.mu.Lock()
+				return "*-1\r\n"
+			}
+		}
+	}
+	return "-ERR unknown command '" + cmd + "'\r\n"
+}
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		url      string
+		addr     string
+		password string
+		db       int
+		tls      bool
+		wantErr  bool
+	}{
+		{url: "redis://localhost", addr: "localhost:6379"},
+		{url: "redis://:secret@redis:6380/2", addr: "redis:6380", password: "secret", db: 2},
+		{url: "rediss://cache.example.com:6390", addr: "cache.example.com:6390", tls: true},
+		{url: "http://localhost", wantErr: true},
+		{url: "redis://localhost/db", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := newRedisClient(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newRedisClient(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if c.addr != tt.addr || c.password != tt.password || c.db != tt.db || c.useTLS != tt.tls {
+			t.Errorf("newRedisClient(%q) = %+v", tt.url, c)
+		}
+	}
+}
+
+func TestCommandHandlerAsyncRedis(t *testing.T) {
+	addr := startFakeRedis(t, "secret")
+	t.Setenv("MCPSHELL_TEST_REDIS_PASSWORD", "secret")
+
+	backend, err := NewJobsBackend("redis", map[string]interface{}{
+		"url":          "redis://" + addr + "/1",
+		"password_env": "MCPSHELL_TEST_REDIS_PASSWORD",
+	})
+	if err != nil {
+		t.Fatalf("NewJobsBackend() error = %v", err)
+	}
+	SetJobsBackend(backend)
+	defer SetJobsBackend(nil)
+
+	oldTimeout := redisDequeueTimeout
+	redisDequeueTimeout = time.Second
+	defer func() { redisDequeueTimeout = oldTimeout }()
+
+	tool := config.Tool{
+		MCPTool: mcp.Tool{Name: "test-queued-tool"},
+		Config: config.MCPToolConfig{
+			Async: true,
+			Run:   config.MCPToolRunConfig{Command: "echo hello {{ .name }}"},
+		},
+	}
+	params := map[string]common.ParamConfig{"name": {Type: "string", Required: true}}
+	handler, err := NewCommandHandler(tool, params, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create command handler: %v", err)
+	}
+
+	callTool := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) string {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := handler(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+	startJob := func(name string) string {
+		text := callTool(handler.GetMCPHandler(), map[string]interface{}{"name": name})
+		match := regexp.MustCompile(`job ([a-f0-9]{12})`).FindStringSubmatch(text)
+		if match == nil {
+			t.Fatalf("No job ID found in %q", text)
+		}
+		return match[1]
+	}
+
+	// the jobs are queued until an instance runs them
+	id := startJob("world")
+	if text := callTool(JobStatusHandler, map[string]interface{}{"job_id": id}); !strings.Contains(text, "Status: queued") {
+		t.Errorf("Expected a queued job, got %q", text)
+	}
+
+	// queued jobs can be killed before running
+	killedID := startJob("nobody")
+	if text := callTool(JobKillHandler, map[string]interface{}{"job_id": killedID}); !strings.Contains(text, "being stopped") {
+		t.Errorf("Expected the job to be killed, got %q", text)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunJobsConsumers(ctx, 2, testLogger)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitStatus := func(id string, status string) string {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			text := callTool(JobStatusHandler, map[string]interface{}{"job_id": id})
+			if strings.Contains(text, "Status: "+status) {
+				return text
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Job %s did not reach status '%s': %s", id, status, text)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	if text := waitStatus(id, JobStatusSucceeded); !strings.HasSuffix(text, "Output:\nhello world") {
+		t.Errorf("Expected the output in the job status, got %q", text)
+	}
+	if logs := callTool(JobLogsHandler, map[string]interface{}{"job_id": id}); logs != "hello world" {
+		t.Errorf("Expected the logs of the job from Redis, got %q", logs)
+	}
+	waitStatus(killedID, JobStatusKilled)
+
+	if text := callTool(JobStatusHandler, nil); strings.Count(text, "\n") != 1 {
+		t.Errorf("Expected two jobs in the list, got %q", text)
+	}
+}
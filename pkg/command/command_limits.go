@@ -0,0 +1,79 @@
+package command
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LimitExceededError is the error of a call rejected because the tool has been
+// called too many times (ie, in the same session, or in the same day)
+type LimitExceededError struct {
+	Tool   string    // the name of the tool
+	Scope  string    // the scope of the limit (ie, "session", "day" or "week")
+	Limit  int       // the maximum number of calls in the scope
+	Client string    // the client with the limit (empty when the limit is shared by all the clients)
+	Reset  time.Time // the time the limit is reset (zero when unknown)
+}
+
+func (e *LimitExceededError) Error() string {
+	msg := fmt.Sprintf("limit exceeded: tool '%s' can only be called %d times per %s", e.Tool, e.Limit, e.Scope)
+	if e.Client != "" {
+		msg += fmt.Sprintf(" by client '%s'", e.Client)
+	}
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(": try again after %s", e.Reset.UTC().Format(time.RFC3339))
+	}
+	return msg
+}
+
+// Meta returns the details of the error, for the metadata of the results
+func (e *LimitExceededError) Meta() map[string]interface{} {
+	meta := map[string]interface{}{
+		"error": "limit_exceeded",
+		"tool":  e.Tool,
+		"scope": e.Scope,
+		"limit": e.Limit,
+	}
+	if e.Client != "" {
+		meta["client"] = e.Client
+	}
+	if !e.Reset.IsZero() {
+		meta["reset"] = e.Reset.UTC().Format(time.RFC3339)
+	}
+	return meta
+}
+
+// sessionCallStore keeps the number of calls of every tool in every session
+type sessionCallStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]int
+}
+
+// sessionCalls is the store shared by all the tools
+var sessionCalls = &sessionCallStore{sessions: map[string]map[string]int{}}
+
+// take counts a call of a tool in a session, returning false (without counting it)
+// when the tool has already been called the maximum number of times in the session
+func (s *sessionCallStore) take(id string, tool string, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls, exists := s.sessions[id]
+	if !exists {
+		calls = map[string]int{}
+		s.sessions[id] = calls
+	}
+	if calls[tool] >= limit {
+		return false
+	}
+	calls[tool]++
+	return true
+}
+
+// clear removes the calls of a session
+func (s *sessionCallStore) clear(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
@@ -0,0 +1,41 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+// ExecutedCommand is a command run for a tool call, with its result
+type ExecutedCommand struct {
+	Tool     string        // the name of the tool
+	Runner   string        // the runner of the command (or "shell_session")
+	Command  string        // the command (or a representation of the arguments, for commands without shell)
+	Argv     []string      // the arguments of the command run without shell (if any)
+	Output   string        // the output of the command
+	Error    string        // the error of the command (if it failed)
+	Duration time.Duration // the time the command took
+}
+
+// CommandObserver is notified of the commands run for the tool calls
+type CommandObserver func(ctx context.Context, cmd ExecutedCommand)
+
+// commandObserverKey is the key of the observer of the commands in the context
+type commandObserverKey struct{}
+
+// WithCommandObserver returns a context where the commands run for the tool calls are
+// notified to an observer (ie, for recording the sessions)
+func WithCommandObserver(ctx context.Context, observer CommandObserver) context.Context {
+	return context.WithValue(ctx, commandObserverKey{}, observer)
+}
+
+// notifyCommand notifies a command run to the observer in the context, if any
+func notifyCommand(ctx context.Context, cmd ExecutedCommand, err error) {
+	observer, _ := ctx.Value(commandObserverKey{}).(CommandObserver)
+	if observer == nil {
+		return
+	}
+	if err != nil {
+		cmd.Error = err.Error()
+	}
+	observer(ctx, cmd)
+}
@@ -0,0 +1,163 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ReadMoreToolName is the name of the built-in tool for reading the
+// pages of the outputs that exceed the maximum size of their tools
+const ReadMoreToolName = "read_more"
+
+// OutputPagesTTL is the time the full outputs are kept for reading more pages
+var OutputPagesTTL = 30 * time.Minute
+
+// MaxOutputPages is the maximum number of outputs kept for reading more pages.
+// The oldest outputs are discarded when the limit is reached.
+var MaxOutputPages = 100
+
+// outputPage is an output stored for reading more pages
+type outputPage struct {
+	output   string    // the full output
+	pageSize int       // the maximum size of every page
+	created  time.Time // when the output was stored
+}
+
+// outputPagesStore keeps the outputs that exceeded the maximum size
+type outputPagesStore struct {
+	mu      sync.Mutex
+	outputs map[string]*outputPage
+}
+
+// outputPages is the store shared by all the tools
+var outputPages = &outputPagesStore{outputs: map[string]*outputPage{}}
+
+// paginate returns the first page of an output when it is bigger than the page
+// size, storing the full output and adding a note with the cursor for the next page.
+// The output is returned unmodified when it fits in a page.
+func (s *outputPagesStore) paginate(output string, pageSize int) string {
+	if pageSize <= 0 || len(output) <= pageSize {
+		return output
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		// this should never happen, but we cannot lose the output
+		return output
+	}
+	id := hex.EncodeToString(idBytes)
+
+	s.mu.Lock()
+	s.expire()
+	s.outputs[id] = &outputPage{output: output, pageSize: pageSize, created: time.Now()}
+	s.mu.Unlock()
+
+	return s.page(id, output, 0, pageSize)
+}
+
+// next returns the page of an output starting at the cursor
+func (s *outputPagesStore) next(cursor string) (string, error) {
+	id, offsetStr, found := strings.Cut(cursor, ":")
+	offset, err := strconv.Atoi(offsetStr)
+	if !found || err != nil || offset < 0 {
+		return "", fmt.Errorf("invalid cursor: %s", cursor)
+	}
+
+	s.mu.Lock()
+	s.expire()
+	stored, exists := s.outputs[id]
+	s.mu.Unlock()
+
+	if !exists {
+		return "", fmt.Errorf("unknown or expired cursor: %s (run the tool again)", cursor)
+	}
+	if offset >= len(stored.output) {
+		return "", fmt.Errorf("invalid cursor: %s (no more output)", cursor)
+	}
+
+	return s.page(id, stored.output, offset, stored.pageSize), nil
+}
+
+// page returns the page of an output starting at some offset, adding a note with
+// the cursor for the next page (if there is more output).
+// Pages end at line boundaries (when possible) and never split UTF-8 characters.
+func (s *outputPagesStore) page(id string, output string, offset int, pageSize int) string {
+	end := offset + pageSize
+	if end >= len(output) {
+		return output[offset:] + fmt.Sprintf("\n\n[End of output: showing bytes %d-%d of %d]", offset, len(output), len(output))
+	}
+
+	if nl := strings.LastIndexByte(output[offset:end], '\n'); nl > 0 {
+		end = offset + nl + 1
+	} else {
+		for end > offset+1 && !utf8.RuneStart(output[end]) {
+			end--
+		}
+	}
+
+	return output[offset:end] + fmt.Sprintf("\n\n[Output truncated: showing bytes %d-%d of %d. "+
+		"Call the '%s' tool with cursor \"%s:%d\" for reading more]", offset, end, len(output), ReadMoreToolName, id, end)
+}
+
+// expire removes the expired outputs, as well as the oldest ones when there are too many.
+// It must be called with the lock held.
+func (s *outputPagesStore) expire() {
+	now := time.Now()
+	for id, stored := range s.outputs {
+		if now.Sub(stored.created) > OutputPagesTTL {
+			delete(s.outputs, id)
+		}
+	}
+
+	for len(s.outputs) >= MaxOutputPages && len(s.outputs) > 0 {
+		oldestID := ""
+		for id, stored := range s.outputs {
+			if oldestID == "" || stored.created.Before(s.outputs[oldestID].created) {
+				oldestID = id
+			}
+		}
+		delete(s.outputs, oldestID)
+	}
+}
+
+// GetReadMoreTool returns the built-in tool for reading the following
+// pages of the outputs that exceeded the maximum size of their tools
+func GetReadMoreTool() mcp.Tool {
+	return mcp.NewTool(ReadMoreToolName,
+		mcp.WithDescription("Read the next page of the output of a tool that was too big to be returned at once. "+
+			"Use the cursor provided at the end of the truncated output."),
+		mcp.WithString("cursor",
+			mcp.Description("The cursor provided at the end of the truncated output"),
+			mcp.Required()),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:          "Read more output",
+			ReadOnlyHint:   true,
+			IdempotentHint: true,
+		}),
+	)
+}
+
+// ReadMoreHandler handles the calls to the built-in tool for reading more pages
+func ReadMoreHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cursor, ok := request.Params.Arguments["cursor"].(string)
+	if !ok || cursor == "" {
+		return mcp.NewToolResultError("the cursor is required"), nil
+	}
+
+	page, err := outputPages.next(cursor)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	return mcp.NewToolResultText(page), nil
+}
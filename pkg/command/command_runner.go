@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// runnerOptionUnsafeChars are the characters not allowed in the values of the templated
+// runner options: some runners (ie, "docker") build shell commands with the options, so
+// the values coming from the parameters of the tools cannot break them
+const runnerOptionUnsafeChars = " \t\r\n;&|$`<>(){}[]*?!#~'\"\\"
+
+// runnerOptionsParams returns the configurations of the variables available in the
+// constraints of the runner options: the options of the runner with string values
+func runnerOptionsParams(options RunnerOptions) map[string]common.ParamConfig {
+	params := map[string]common.ParamConfig{}
+	for name, value := range options {
+		if _, ok := value.(string); ok {
+			params[name] = common.ParamConfig{Type: "string"}
+		}
+	}
+	return params
+}
+
+// renderRunnerOptions returns a copy of the runner options with the templates in the
+// string values (including the ones in lists) processed with the parameters of a call
+func renderRunnerOptions(options RunnerOptions, params map[string]interface{}) (RunnerOptions, error) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := RunnerOptions{}
+	for _, name := range names {
+		value, err := renderRunnerOption(name, options[name], params)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = value
+	}
+	return res, nil
+}
+
+// renderRunnerOption processes the templates in the value of a runner option
+func renderRunnerOption(name string, value interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		rendered, err := common.ProcessTemplate(v, params)
+		if err != nil {
+			return nil, fmt.Errorf("error processing runner option '%s': %w", name, err)
+		}
+		if strings.ContainsAny(rendered, runnerOptionUnsafeChars) {
+			return nil, fmt.Errorf("invalid value for runner option '%s': %q contains characters not allowed", name, rendered)
+		}
+		return rendered, nil
+
+	case []string:
+		res := make([]string, len(v))
+		for i, item := range v {
+			rendered, err := renderRunnerOption(name, item, params)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = rendered.(string)
+		}
+		return res, nil
+
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := renderRunnerOption(name, item, params)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = rendered
+		}
+		return res, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// checkRunnerConstraints checks the runner options of a call with the constraints of
+// the runner, returning the constraints failed (if any)
+func (h *CommandHandler) checkRunnerConstraints(options RunnerOptions) ([]string, error) {
+	if h.runnerConstraints == nil {
+		return nil, nil
+	}
+
+	args := map[string]interface{}{}
+	for name := range h.runnerConstraintsParams {
+		if value, ok := options[name].(string); ok {
+			args[name] = value
+		}
+	}
+	satisfied, failed, err := h.runnerConstraints.Evaluate(args, h.runnerConstraintsParams)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating runner constraints: %v", err)
+	}
+	if !satisfied {
+		return failed, fmt.Errorf("runner options blocked by constraints: %s", strings.Join(failed, "; "))
+	}
+	return nil, nil
+}
@@ -0,0 +1,284 @@
+package command
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestCommandHandlerRunnerTemplates(t *testing.T) {
+	var last RunnerOptions
+	err := RegisterRunner("test-templated", RunnerRegistration{
+		New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+			last = options
+			return &remoteRunner{killed: make(chan struct{})}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+
+	tool := config.Tool{
+		MCPTool: mcp.Tool{Name: "test-tool"},
+		Config: config.MCPToolConfig{
+			Run: config.MCPToolRunConfig{Command: "echo hello"},
+		},
+		SelectedRunner: &config.MCPToolRunner{
+			Name: "test-templated",
+			Options: map[string]interface{}{
+				"image":  "registry.example.com/{{ .image }}",
+				"mounts": []interface{}{"/data/{{ .dir }}:/data:ro"},
+				"user":   "nobody",
+			},
+			Constraints: []string{
+				"image.matches('^registry.example.com/[a-z]+:[0-9.]+$')",
+				"user == 'nobody'",
+			},
+		},
+	}
+	params := map[string]common.ParamConfig{
+		"image": {Type: "string"},
+		"dir":   {Type: "string"},
+	}
+
+	handler, err := NewCommandHandler(tool, params, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	// the templates in the options are processed with the arguments of the call
+	if _, err := handler.ExecuteCommand(map[string]interface{}{"image": "alpine:3.19", "dir": "project"}); err != nil {
+		t.Fatalf("ExecuteCommand() error = %v", err)
+	}
+	if got := last["image"]; got != "registry.example.com/alpine:3.19" {
+		t.Errorf("Unexpected image: %v", got)
+	}
+	if got := last["mounts"]; !reflect.DeepEqual(got, []interface{}{"/data/project:/data:ro"}) {
+		t.Errorf("Unexpected mounts: %v", got)
+	}
+	// ... without modifying the options of the tool
+	if got := handler.runnerOpts["image"]; got != "registry.example.com/{{ .image }}" {
+		t.Errorf("Unexpected options in the handler: %v", got)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"rejected by the constraints", map[string]interface{}{"image": "alpine:latest", "dir": "project"}, "blocked by constraints"},
+		{"unsafe characters", map[string]interface{}{"image": "alpine:3.19 --privileged", "dir": "project"}, "not allowed"},
+		{"unsafe characters in lists", map[string]interface{}{"image": "alpine:3.19", "dir": "x;id"}, "runner option 'mounts'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last = nil
+			if _, err := handler.ExecuteCommand(tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error with %q, got %v", tt.wantErr, err)
+			}
+			if last != nil {
+				t.Errorf("Expected no runner to be created")
+			}
+		})
+	}
+
+	// the constraints must be valid
+	tool.SelectedRunner.Constraints = []string{"image.startsWith("}
+	if _, err := NewCommandHandler(tool, params, "sh", testLogger); err == nil {
+		t.Errorf("Expected an error for an invalid runner constraint")
+	}
+}
+
+func TestCommandHandlerMounts(t *testing.T) {
+	var last RunnerOptions
+	err := RegisterRunner("test-mounts", RunnerRegistration{
+		New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+			last = options
+			return &remoteRunner{killed: make(chan struct{})}, nil
+		},
+		Capabilities: RunnerCapabilities{Mounts: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+
+	root := t.TempDir()
+	tool := config.Tool{
+		MCPTool: mcp.Tool{Name: "test-tool"},
+		Config: config.MCPToolConfig{
+			Run: config.MCPToolRunConfig{
+				Command: "ls /src",
+				Mounts: []config.MCPToolMountConfig{
+					{HostPath: root + "/{{ .project }}", ContainerPath: "/src", ReadOnly: true},
+					{ContainerPath: "/tmp", Tmpfs: true},
+				},
+				MountRoots: []string{root},
+			},
+		},
+		SelectedRunner: &config.MCPToolRunner{Name: "test-mounts"},
+	}
+	params := map[string]common.ParamConfig{"project": {Type: "string"}}
+
+	handler, err := NewCommandHandler(tool, params, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	// the host paths are processed with the arguments of the call
+	if _, err := handler.ExecuteCommand(map[string]interface{}{"project": "website"}); err != nil {
+		t.Fatalf("ExecuteCommand() error = %v", err)
+	}
+	expected := []Mount{
+		{HostPath: common.ResolvePath(root) + "/website", ContainerPath: "/src", ReadOnly: true},
+		{ContainerPath: "/tmp", Tmpfs: true},
+	}
+	if got := last["volumes"]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected volumes: %+v", got)
+	}
+
+	// the clients cannot mount other paths in the options of the calls
+	if _, err := handler.ExecuteCommand(map[string]interface{}{
+		"project": "website",
+		"options": map[string]interface{}{
+			"volumes":         []interface{}{map[string]interface{}{"host_path": "/", "container_path": "/src"}},
+			"mounts":          []interface{}{"/:/host"},
+			"docker_run_opts": "-v /:/host",
+		},
+	}); err != nil {
+		t.Fatalf("ExecuteCommand() error = %v", err)
+	}
+	if got := last["volumes"]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected volumes: %+v", got)
+	}
+	if _, found := last["mounts"]; found {
+		t.Errorf("Unexpected mounts: %+v", last["mounts"])
+	}
+	if _, found := last["docker_run_opts"]; found {
+		t.Errorf("Unexpected docker_run_opts: %+v", last["docker_run_opts"])
+	}
+
+	// ... and they must be inside the mount roots
+	last = nil
+	if _, err := handler.ExecuteCommand(map[string]interface{}{"project": "../etc"}); err == nil || !strings.Contains(err.Error(), "mount roots") {
+		t.Errorf("Expected an error for a host path outside the roots, got %v", err)
+	}
+	if last != nil {
+		t.Errorf("Expected no runner to be created")
+	}
+
+	// the runners must support the mounts
+	tool.SelectedRunner = &config.MCPToolRunner{Name: "exec"}
+	if _, err := NewCommandHandler(tool, params, "sh", testLogger); err == nil || !strings.Contains(err.Error(), "mounts are not supported") {
+		t.Errorf("Expected an error for a runner without mounts, got %v", err)
+	}
+}
+
+func TestCommandHandlerDevices(t *testing.T) {
+	var last RunnerOptions
+	err := RegisterRunner("test-devices", RunnerRegistration{
+		New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+			last = options
+			return &remoteRunner{killed: make(chan struct{})}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+
+	tool := config.Tool{
+		MCPTool: mcp.Tool{Name: "test-tool"},
+		Config: config.MCPToolConfig{
+			Run: config.MCPToolRunConfig{Command: "nvidia-smi"},
+		},
+		SelectedRunner: &config.MCPToolRunner{
+			Name:    "test-devices",
+			Options: map[string]interface{}{"devices": []interface{}{"/dev/dri"}},
+		},
+	}
+	handler, err := NewCommandHandler(tool, nil, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	// the clients cannot add devices nor GPUs in the options of the calls
+	if _, err := handler.ExecuteCommand(map[string]interface{}{
+		"options": map[string]interface{}{"devices": []interface{}{"/dev/mem"}, "gpus": "all"},
+	}); err != nil {
+		t.Fatalf("ExecuteCommand() error = %v", err)
+	}
+	if got := last["devices"]; !reflect.DeepEqual(got, []interface{}{"/dev/dri"}) {
+		t.Errorf("Unexpected devices: %+v", got)
+	}
+	if _, found := last["gpus"]; found {
+		t.Errorf("Unexpected gpus: %+v", last["gpus"])
+	}
+}
+
+func TestCommandHandlerSeccomp(t *testing.T) {
+	newTool := func(runner string, options map[string]interface{}, seccomp string) config.Tool {
+		return config.Tool{
+			MCPTool: mcp.Tool{Name: "test-tool"},
+			Config: config.MCPToolConfig{
+				Run: config.MCPToolRunConfig{Command: "ls", Seccomp: seccomp},
+			},
+			SelectedRunner: &config.MCPToolRunner{Name: runner, Options: options},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		tool    config.Tool
+		wantErr string
+	}{
+		{"docker", newTool("docker", map[string]interface{}{"image": "alpine"}, "no-network-no-exec"), ""},
+		{"firejail", newTool("firejail", nil, "no-network-no-exec"), ""},
+		{"custom profile in firejail", newTool("firejail", nil, "/etc/seccomp.json"), "not supported by firejail"},
+		{"runner without seccomp", newTool("exec", nil, "default"), "seccomp profiles are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCommandHandler(tt.tool, nil, "sh", testLogger)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected an error with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	var last RunnerOptions
+	err := RegisterRunner("test-seccomp", RunnerRegistration{
+		New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+			last = options
+			return &remoteRunner{killed: make(chan struct{})}, nil
+		},
+		Capabilities: RunnerCapabilities{Seccomp: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+
+	// the clients cannot change the profile in the options of the calls
+	for _, seccomp := range []string{"", "default"} {
+		handler, err := NewCommandHandler(newTool("test-seccomp", nil, seccomp), nil, "sh", testLogger)
+		if err != nil {
+			t.Fatalf("Failed to create handler: %v", err)
+		}
+		last = nil
+		if _, err := handler.ExecuteCommand(map[string]interface{}{
+			"options": map[string]interface{}{"seccomp": "unconfined"},
+		}); err != nil {
+			t.Fatalf("ExecuteCommand() error = %v", err)
+		}
+		if got, _ := last["seccomp"].(string); got != seccomp {
+			t.Errorf("Expected the seccomp profile %q, got %q", seccomp, got)
+		}
+	}
+}
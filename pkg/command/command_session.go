@@ -0,0 +1,281 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// ShellSessionCommandTimeout is the maximum time a command can run in a shell session.
+// The shell session is restarted when a command takes longer.
+var ShellSessionCommandTimeout = 5 * time.Minute
+
+// shellSession is a long-lived shell process, that keeps its
+// working directory and environment between commands
+type shellSession struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string   // the lines of output (closed when the shell exits)
+	done   chan struct{} // closed when the shell exits
+	stop   chan struct{} // closed when the session is closed
+	marker string        // printed (with the exit status) when a command finishes
+	closed atomic.Bool   // set when the session has been closed
+	logger *log.Logger
+}
+
+// shellSessionsStore keeps the shell sessions of all the tools
+type shellSessionsStore struct {
+	mu       sync.Mutex
+	sessions map[string]*shellSession // indexed by tool name and session key
+}
+
+// shellSessions is the store shared by all the tools
+var shellSessions = &shellSessionsStore{sessions: map[string]*shellSession{}}
+
+// shellSessionKey returns the key of the shell session of a tool in some session
+func shellSessionKey(toolName string, sessionKey string) string {
+	return toolName + "\x00" + sessionKey
+}
+
+// get returns the shell session of a tool in a session, starting a new one
+// (with the given shell, environment and working directory) when needed
+func (s *shellSessionsStore) get(toolName string, sessionKey string, shell string, env []string, workdir string, logger *log.Logger) (*shellSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := shellSessionKey(toolName, sessionKey)
+	if session, exists := s.sessions[key]; exists {
+		if !session.ended() {
+			return session, nil
+		}
+		logger.Printf("Shell session for tool '%s' has ended: starting a new one", toolName)
+	}
+
+	session, err := startShellSession(shell, env, workdir, logger)
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[key] = session
+	return session, nil
+}
+
+// closeMatching closes and removes the sessions with keys matching a function
+func (s *shellSessionsStore) closeMatching(match func(key string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, session := range s.sessions {
+		if match(key) {
+			session.close()
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// CloseShellSessions closes the shell sessions started in a MCP session (ie, when the session ends)
+func CloseShellSessions(sessionID string) {
+	shellSessions.closeMatching(func(key string) bool {
+		return strings.HasSuffix(key, "\x00session:"+sessionID)
+	})
+}
+
+// CloseAllShellSessions closes all the shell sessions (ie, when the server stops)
+func CloseAllShellSessions() {
+	shellSessions.closeMatching(func(string) bool { return true })
+}
+
+// startShellSession starts a new shell process
+func startShellSession(shell string, env []string, workdir string, logger *log.Logger) (*shellSession, error) {
+	markerBytes := make([]byte, 8)
+	if _, err := rand.Read(markerBytes); err != nil {
+		return nil, fmt.Errorf("failed to create shell session marker: %w", err)
+	}
+
+	configShell := getShell(shell)
+	if kind := common.GetShellKind(configShell); kind != common.ShellPOSIX {
+		return nil, fmt.Errorf("shell sessions require a POSIX shell (not %s)", configShell)
+	}
+
+	cmd := exec.Command(configShell)
+	cmd.Env = env
+	cmd.Dir = workdir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shell session: %w", err)
+	}
+
+	// stdout and stderr are merged, as in a terminal
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shell session: %w", err)
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	if err := cmd.Start(); err != nil {
+		_ = reader.Close()
+		_ = writer.Close()
+		return nil, fmt.Errorf("failed to start shell session: %w", err)
+	}
+	_ = writer.Close()
+	logger.Printf("Started shell session with %s (pid %d)", configShell, cmd.Process.Pid)
+
+	session := &shellSession{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan string, 1024),
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		marker: "__MCPSHELL_" + hex.EncodeToString(markerBytes) + "__",
+		logger: logger,
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			select {
+			case session.lines <- scanner.Text():
+			case <-session.stop:
+				// nobody will read the output anymore
+			}
+		}
+		close(session.lines)
+		_ = reader.Close()
+		_ = cmd.Wait()
+		close(session.done)
+	}()
+
+	return session, nil
+}
+
+// run runs a command in the shell session, returning its output (stdout and stderr)
+// and its exit status. Commands are run with the standard input closed.
+func (s *shellSession) run(ctx context.Context, command string) (string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a syntax error would make the shell exit, losing the session
+	var stderr bytes.Buffer
+	check := exec.CommandContext(ctx, s.cmd.Path, "-n", "-c", command)
+	check.Stderr = &stderr
+	if err := check.Run(); err != nil {
+		return "", 0, fmt.Errorf("invalid command: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	quoted := "'" + strings.ReplaceAll(command, "'", `'\''`) + "'"
+	script := fmt.Sprintf("eval %s </dev/null\nprintf '\\n%s %%d\\n' \"$?\"\n", quoted, s.marker)
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return "", 0, fmt.Errorf("failed to send command to the shell session: %w", err)
+	}
+
+	timeout := time.NewTimer(ShellSessionCommandTimeout)
+	defer timeout.Stop()
+
+	var output []string
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				return strings.TrimSpace(strings.Join(output, "\n")), 0,
+					errors.New("the shell session has ended (it will be restarted in the next call)")
+			}
+			if status, found := strings.CutPrefix(line, s.marker+" "); found {
+				exitStatus, _ := strconv.Atoi(status)
+				return strings.TrimSpace(strings.Join(output, "\n")), exitStatus, nil
+			}
+			output = append(output, line)
+
+		case <-ctx.Done():
+			s.close()
+			return strings.TrimSpace(strings.Join(output, "\n")), 0,
+				fmt.Errorf("command cancelled: the shell session has been closed (%w)", ctx.Err())
+
+		case <-timeout.C:
+			s.close()
+			return strings.TrimSpace(strings.Join(output, "\n")), 0,
+				fmt.Errorf("command timed out after %s: the shell session has been closed", ShellSessionCommandTimeout)
+		}
+	}
+}
+
+// ended returns true if the shell process has exited or the session has been closed
+func (s *shellSession) ended() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return s.closed.Load()
+	}
+}
+
+// close stops the shell process
+func (s *shellSession) close() {
+	if !s.closed.CompareAndSwap(false, true) {
+		return
+	}
+	close(s.stop)
+	_ = s.stdin.Close()
+	select {
+	case <-s.done:
+		return
+	case <-time.After(time.Second):
+	}
+	if s.cmd.Process != nil {
+		s.logger.Printf("Killing shell session (pid %d)", s.cmd.Process.Pid)
+		_ = s.cmd.Process.Kill()
+	}
+}
+
+// runInShellSession runs a command in the shell session of the tool for the current
+// session (see sessionKey), starting the shell (with the environment and working
+// directory of this execution) when there is no session yet.
+func (h *CommandHandler) runInShellSession(ctx context.Context, command string, env []string, runnerOptions RunnerOptions) (string, error) {
+	opts, err := NewRunnerExecOptions(runnerOptions)
+	if err != nil {
+		return "", fmt.Errorf("invalid options for shell session: %w", err)
+	}
+
+	// Calls from different clients cannot share a shell
+	key := sessionKey(ctx)
+	if key == "" {
+		return "", fmt.Errorf("shell sessions are only available in MCP sessions or for authenticated clients")
+	}
+
+	session, err := shellSessions.get(h.toolName, key, h.shell,
+		buildEnvironment(opts.EnvPassthrough, env), opts.Workdir, h.logger.Logger)
+	if err != nil {
+		return "", err
+	}
+
+	output, exitStatus, err := session.run(ctx, command)
+	if err != nil {
+		if output != "" {
+			return "", fmt.Errorf("%w\n\n%s", err, output)
+		}
+		return "", err
+	}
+
+	if exitStatus != 0 {
+		output = strings.TrimLeft(output+fmt.Sprintf("\n\n[exit status %d]", exitStatus), "\n")
+	}
+	return output, nil
+}
@@ -0,0 +1,213 @@
+// Package command provides functions for creating and executing command handlers.
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// StateParam is the name of the template variable holding the
+// key/value state of the current session (ie, {{ .State.target_host }})
+const StateParam = "State"
+
+// StateSetToolName is the name of the built-in tool for setting values in the session state
+const StateSetToolName = "state_set"
+
+// StateGetToolName is the name of the built-in tool for getting values from the session state
+const StateGetToolName = "state_get"
+
+// MaxStateKeys is the maximum number of keys in the state of a session
+var MaxStateKeys = 100
+
+// MaxStateValueSize is the maximum size (in bytes) of a value in the state of a session
+var MaxStateValueSize = 64 * 1024
+
+// sessionStateStore keeps the key/value state of every session
+type sessionStateStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]string // indexed by session key
+}
+
+// sessionStates is the store shared by all the tools
+var sessionStates = &sessionStateStore{sessions: map[string]map[string]string{}}
+
+// sessionID returns the ID of the MCP session in a context, or an empty
+// string when there is no session (ie, when running tools directly)
+func sessionID(ctx context.Context) string {
+	if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// localSessionKey is the key of the in-process calls in the context
+type localSessionKey struct{}
+
+// WithLocalSession returns a context for the calls made in-process (ie, by the agent or
+// with "mcpshell exe"), that share a local session when they are not in a MCP session.
+func WithLocalSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localSessionKey{}, true)
+}
+
+// sessionKey returns the key of the session of the calls in a context (for their state,
+// shells and limits): the MCP session, the authenticated client for the calls without a
+// session (ie, in the REST API) or the local session of the in-process calls. It is empty
+// otherwise.
+func sessionKey(ctx context.Context) string {
+	if id := sessionID(ctx); id != "" {
+		return "session:" + id
+	}
+	if access, _ := ctx.Value(jobsAccessKey{}).(jobsAccess); access.owner != "" {
+		return "client:" + access.owner
+	}
+	if local, _ := ctx.Value(localSessionKey{}).(bool); local {
+		return "local"
+	}
+	return ""
+}
+
+// get returns a copy of the state of a session
+func (s *sessionStateStore) get(id string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[string]string, len(s.sessions[id]))
+	for k, v := range s.sessions[id] {
+		res[k] = v
+	}
+	return res
+}
+
+// set sets a value in the state of a session, removing the key when the value is empty
+func (s *sessionStateStore) set(id string, key string, value string) error {
+	if len(value) > MaxStateValueSize {
+		return fmt.Errorf("value is too big (%d bytes, the maximum is %d)", len(value), MaxStateValueSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.sessions[id]
+	if !exists {
+		state = map[string]string{}
+		s.sessions[id] = state
+	}
+
+	if value == "" {
+		delete(state, key)
+		return nil
+	}
+	if _, exists := state[key]; !exists && len(state) >= MaxStateKeys {
+		return fmt.Errorf("too many keys in the session state (the maximum is %d)", MaxStateKeys)
+	}
+	state[key] = value
+	return nil
+}
+
+// ClearSessionState removes the state of a session, as well as its number of calls
+// of the tools (ie, when the session ends)
+func ClearSessionState(id string) {
+	sessionStates.mu.Lock()
+	defer sessionStates.mu.Unlock()
+	delete(sessionStates.sessions, "session:"+id)
+
+	sessionCalls.clear("session:" + id)
+}
+
+// GetStateTools returns the built-in tools for managing the session state
+func GetStateTools() []mcp.Tool {
+	return []mcp.Tool{
+		mcp.NewTool(StateSetToolName,
+			mcp.WithDescription("Save a value in the state of the current session, so it can be used by the "+
+				"following tool calls without passing it again (ie, the current target host). "+
+				"Setting an empty value removes the key."),
+			mcp.WithString("key",
+				mcp.Description("The name of the value"),
+				mcp.Required()),
+			mcp.WithString("value",
+				mcp.Description("The value to save (empty for removing the key)"),
+				mcp.Required()),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:          "Set session state",
+				IdempotentHint: true,
+			}),
+		),
+		mcp.NewTool(StateGetToolName,
+			mcp.WithDescription("Get a value from the state of the current session, "+
+				"or all the values when no key is provided."),
+			mcp.WithString("key",
+				mcp.Description("The name of the value (optional)")),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:          "Get session state",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+			}),
+		),
+	}
+}
+
+// StateSetHandler handles the calls to the built-in tool for setting values in the session state
+func StateSetHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	key, _ := request.Params.Arguments["key"].(string)
+	if key == "" {
+		return mcp.NewToolResultError("the key is required"), nil
+	}
+	value := fmt.Sprintf("%v", request.Params.Arguments["value"])
+	if request.Params.Arguments["value"] == nil {
+		value = ""
+	}
+
+	// Calls from different clients cannot share a state
+	session := sessionKey(ctx)
+	if session == "" {
+		return mcp.NewToolResultError("the state is only available in MCP sessions or for authenticated clients"), nil
+	}
+
+	if err := sessionStates.set(session, key, value); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if value == "" {
+		return mcp.NewToolResultText(fmt.Sprintf("Removed '%s' from the session state", key)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Saved '%s' in the session state", key)), nil
+}
+
+// StateGetHandler handles the calls to the built-in tool for getting values from the session state
+func StateGetHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	session := sessionKey(ctx)
+	if session == "" {
+		return mcp.NewToolResultError("the state is only available in MCP sessions or for authenticated clients"), nil
+	}
+	state := sessionStates.get(session)
+
+	if key, _ := request.Params.Arguments["key"].(string); key != "" {
+		value, exists := state[key]
+		if !exists {
+			return mcp.NewToolResultError(fmt.Sprintf("'%s' is not set in the session state", key)), nil
+		}
+		return mcp.NewToolResultText(value), nil
+	}
+
+	if len(state) == 0 {
+		return mcp.NewToolResultText("The session state is empty"), nil
+	}
+
+	keys := make([]string, 0, len(state))
+	for k := range state {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%s\n", k, state[k])
+	}
+	return mcp.NewToolResultText(strings.TrimRight(sb.String(), "\n")), nil
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 )
 
 // RunnerType is an identifier for the type of runner to use.
@@ -30,6 +36,53 @@ const (
 	RunnerTypeDocker RunnerType = "docker"
 )
 
+// DefaultEnvPassthrough is the list of environment variables inherited
+// from the parent process when no other list has been configured.
+var DefaultEnvPassthrough = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "TERM", "TMPDIR", "TZ",
+}
+
+// buildEnvironment returns the environment for a command: the variables of
+// the parent process included in passthrough, followed by env.
+// All the variables are inherited when passthrough is nil or contains "*".
+func buildEnvironment(passthrough []string, env []string) []string {
+	if passthrough == nil {
+		return append(os.Environ(), env...)
+	}
+
+	allowed := make(map[string]bool, len(passthrough))
+	for _, name := range passthrough {
+		if name == "*" {
+			return append(os.Environ(), env...)
+		}
+		allowed[name] = true
+	}
+
+	res := make([]string, 0, len(passthrough)+len(env))
+	for _, e := range os.Environ() {
+		name, _, _ := strings.Cut(e, "=")
+		if allowed[name] {
+			res = append(res, e)
+		}
+	}
+
+	return append(res, env...)
+}
+
+// openLogFile opens the file where the output of a command is copied while it runs
+// (ie, for background jobs), returning a function for closing it.
+// Nothing is copied when the path is empty.
+func openLogFile(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return io.Discard, func() {}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
 // RunnerOptions is a map of options for the runner
 type RunnerOptions map[string]interface{}
 
@@ -38,32 +91,207 @@ func (ro RunnerOptions) ToJSON() (string, error) {
 	return string(json), err
 }
 
-// Runner is an interface for running commands
+// Runner is an interface for running commands. A runner is created for every command,
+// with the options of the tool, and it is not reused.
+//
+// Runners can implement some optional interfaces too: RunnerPreparer (for preparing the
+// execution of the command), RunnerKiller (for terminating the commands that are not
+// terminated when their context is cancelled) and ArgvRunner (for running commands given
+// as lists of arguments).
 type Runner interface {
+	// Run runs a command with a shell, returning its output. The command must be
+	// terminated when the context is cancelled.
 	Run(ctx context.Context, shell string, command string, env []string, params map[string]interface{}, tmpfile bool) (string, error)
+
+	// CheckImplicitRequirements checks the requirements of the runner are met
+	// in this host (ie, the executables it needs)
 	CheckImplicitRequirements() error
 }
 
+// RunnerPreparer is implemented by the runners that prepare the execution of the commands
+// (ie, pulling an image or creating a container). Prepare is called before running the command.
+type RunnerPreparer interface {
+	Prepare(ctx context.Context) error
+}
+
+// RunnerKiller is implemented by the runners whose commands are not terminated by
+// cancelling their context (ie, the commands running in other hosts). Kill is called
+// when the context of a command is cancelled (or times out) while it is running, so it
+// can be called while Run returns.
+type RunnerKiller interface {
+	Kill() error
+}
+
+// ArgvRunner is implemented by the runners that can run commands given as a list of
+// arguments, without a shell. They must have the Argv capability.
+type ArgvRunner interface {
+	RunArgv(ctx context.Context, argv []string, env []string) (string, error)
+}
+
+// RunnerCapabilities are the features supported by a type of runner, besides running
+// commands with a shell
+type RunnerCapabilities struct {
+	Argv          bool // running commands given as a list of arguments (implementing ArgvRunner)
+	PTY           bool // running commands in a pseudo-terminal (with the "pty" options)
+	ShellSessions bool // running the commands in the host, as the shell sessions do
+	Mounts        bool // mounting the host paths of the tools (with the "volumes" option)
+	Seccomp       bool // applying the seccomp profiles of the tools (with the "seccomp" option)
+}
+
+// Mount is a host path (or a temporary filesystem) mounted by the runners with the
+// Mounts capability, passed in the "volumes" option
+type Mount struct {
+	HostPath      string `json:"host_path,omitempty"` // the path in the host (empty for temporary filesystems)
+	ContainerPath string `json:"container_path"`      // the path where it is mounted
+	ReadOnly      bool   `json:"read_only,omitempty"` // mount it as read-only
+	Tmpfs         bool   `json:"tmpfs,omitempty"`     // mount a temporary filesystem
+}
+
+// RunnerFactory creates a runner with the options of a tool
+type RunnerFactory func(options RunnerOptions, logger *log.Logger) (Runner, error)
+
+// RunnerRegistration is a type of runner, that can be selected by name in the configuration
+type RunnerRegistration struct {
+	New          RunnerFactory      // creates the runners
+	Capabilities RunnerCapabilities // the features supported by the runners
+
+	// Options is the schema of the options of the runners: a struct with a field for every
+	// option (named by its JSON tag). When set, the options of the tools are checked against it.
+	Options interface{}
+}
+
+var (
+	runnersMu sync.RWMutex
+	runners   = map[RunnerType]RunnerRegistration{
+		RunnerTypeExec: {
+			New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+				return NewRunnerExec(options, logger)
+			},
+			Capabilities: RunnerCapabilities{Argv: true, PTY: true, ShellSessions: true},
+			Options:      RunnerExecOptions{},
+		},
+		RunnerTypeSandboxExec: {
+			New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+				return NewRunnerSandboxExec(options, logger)
+			},
+			Options: RunnerSandboxExecOptions{},
+		},
+		RunnerTypeFirejail: {
+			New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+				return NewRunnerFirejail(options, logger)
+			},
+			Capabilities: RunnerCapabilities{Seccomp: true},
+			Options:      RunnerFirejailOptions{},
+		},
+		RunnerTypeDocker: {
+			New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+				return NewDockerRunner(options, logger)
+			},
+			Capabilities: RunnerCapabilities{Mounts: true, Seccomp: true},
+			Options:      DockerRunnerOptions{},
+		},
+	}
+)
+
+// RegisterRunner registers a type of runner, so it can be selected by name in the runners
+// of the tools (ie, for adding runners from other programs embedding MCPShell).
+//
+// Parameters:
+//   - runnerType: The name of the runner
+//   - registration: The factory and the capabilities of the runner
+//
+// Returns:
+//   - An error if the registration is not valid, or there is another runner with that name
+func RegisterRunner(runnerType RunnerType, registration RunnerRegistration) error {
+	if runnerType == "" {
+		return fmt.Errorf("the runner has no name")
+	}
+	if registration.New == nil {
+		return fmt.Errorf("runner '%s' has no factory", runnerType)
+	}
+
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	if _, found := runners[runnerType]; found {
+		return fmt.Errorf("runner '%s' already registered", runnerType)
+	}
+	runners[runnerType] = registration
+	return nil
+}
+
+// GetRunnerRegistration returns the registration of a type of runner
+func GetRunnerRegistration(runnerType RunnerType) (RunnerRegistration, bool) {
+	runnersMu.RLock()
+	defer runnersMu.RUnlock()
+	registration, found := runners[runnerType]
+	return registration, found
+}
+
+// RunnerTypes returns the names of the runners registered, sorted
+func RunnerTypes() []RunnerType {
+	runnersMu.RLock()
+	defer runnersMu.RUnlock()
+	res := make([]RunnerType, 0, len(runners))
+	for runnerType := range runners {
+		res = append(res, runnerType)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// runnerTypesList returns the names of the runners registered, for the error messages
+func runnerTypesList() string {
+	var names []string
+	for _, runnerType := range RunnerTypes() {
+		names = append(names, string(runnerType))
+	}
+	return strings.Join(names, ", ")
+}
+
+// ValidateRunnerOptions checks the options of a tool are valid for a type of runner: all
+// of them must be in the schema of the options of the runner, with values of the right type.
+// The options of the runners without a schema are not checked.
+//
+// Returns:
+//   - An error if the runner is unknown, or some option is unknown or has an invalid value
+func ValidateRunnerOptions(runnerType RunnerType, options RunnerOptions) error {
+	registration, found := GetRunnerRegistration(runnerType)
+	if !found {
+		return fmt.Errorf("unknown runner '%s' (available runners: %s)", runnerType, runnerTypesList())
+	}
+	if registration.Options == nil || len(options) == 0 {
+		return nil
+	}
+
+	data, err := options.ToJSON()
+	if err != nil {
+		return fmt.Errorf("invalid options for runner '%s': %w", runnerType, err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	schema := reflect.New(reflect.TypeOf(registration.Options)).Interface()
+	if err := decoder.Decode(schema); err != nil {
+		return fmt.Errorf("invalid options for runner '%s': %s", runnerType, strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	// The schemas can check the values of the options too
+	if validator, ok := schema.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("invalid options for runner '%s': %w", runnerType, err)
+		}
+	}
+	return nil
+}
+
 // NewRunner creates a new Runner based on the given type
 func NewRunner(runnerType RunnerType, options RunnerOptions, logger *log.Logger) (Runner, error) {
-	var runner Runner
-	var err error
-
-	// Create the runner instance based on type
-	switch runnerType {
-	case RunnerTypeExec:
-		runner, err = NewRunnerExec(options, logger)
-	case RunnerTypeSandboxExec:
-		runner, err = NewRunnerSandboxExec(options, logger)
-	case RunnerTypeFirejail:
-		runner, err = NewRunnerFirejail(options, logger)
-	case RunnerTypeDocker:
-		runner, err = NewDockerRunner(options, logger)
-	default:
+	registration, found := GetRunnerRegistration(runnerType)
+	if !found {
 		return nil, fmt.Errorf("unknown runner type: %s", runnerType)
 	}
 
-	// Check if runner creation failed
+	// Create the runner instance
+	runner, err := registration.New(options, logger)
 	if err != nil {
 		return nil, err
 	}
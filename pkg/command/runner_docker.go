@@ -3,34 +3,66 @@ package command
 
 import (
 	"context"
+	_ "embed"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
 )
 
+//go:embed runner_docker_seccomp.json
+var dockerSeccompProfile string
+
 // DockerRunner executes commands inside a Docker container.
 type DockerRunner struct {
 	logger *log.Logger
 	opts   DockerRunnerOptions
 }
 
+// The policies for pulling the images of the Docker runner
+const (
+	PullPolicyAlways       = "always"         // pull the image before running every command
+	PullPolicyIfNotPresent = "if-not-present" // pull the image only when it is not present
+	PullPolicyNever        = "never"          // never pull the image (it must be present)
+)
+
+// imageDigestRegexp matches the digests the images can be pinned to
+var imageDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
 // DockerRunnerOptions represents configuration options for the Docker runner.
 type DockerRunnerOptions struct {
 	// The Docker image to use (required)
 	Image string `json:"image"`
 
+	// The digest the image is pinned to (e.g. "sha256:..."), so a tag moved to
+	// another image is never used
+	ImageDigest string `json:"image_digest"`
+
+	// When the image is pulled: "always", "if-not-present" or "never" (by default,
+	// the image is pulled by Docker when it is not present)
+	PullPolicy string `json:"pull_policy"`
+
+	// Whether to pull the image when the server starts, so the first calls are not
+	// slowed down (or broken) by pulling it
+	PrePull bool `json:"pre_pull"`
+
 	// Additional Docker run options
 	DockerRunOpts string `json:"docker_run_opts"`
 
 	// Mount points in the format "hostpath:containerpath"
 	Mounts []string `json:"mounts"`
 
+	// The mounts of the tool (host paths and temporary filesystems)
+	Volumes []Mount `json:"volumes"`
+
 	// Whether to allow networking in the container
 	AllowNetworking bool `json:"allow_networking"`
 
@@ -72,6 +104,24 @@ type DockerRunnerOptions struct {
 
 	// Set platform if server is multi-platform capable (e.g., "linux/amd64", "linux/arm64")
 	Platform string `json:"platform"`
+
+	// Host devices to add to the container (e.g. "/dev/dri", "/dev/fuse:/dev/fuse:rwm")
+	Devices []string `json:"devices"`
+
+	// GPUs to add to the container (e.g. "all", "2" or "device=0,1"), for ML tools
+	GPUs string `json:"gpus"`
+
+	// The seccomp profile of the tool: "default", "no-network-no-exec" or the path to a JSON profile
+	Seccomp string `json:"seccomp"`
+
+	// The standard input of the command (keeping the container input open)
+	Stdin string `json:"stdin"`
+
+	// The termination sequence for the docker command (signals are forwarded to the container)
+	Termination []TerminationStep `json:"termination"`
+
+	// The file where the output is copied while the command runs
+	LogFile string `json:"log_file"`
 }
 
 // GetBaseDockerCommand creates the common parts of a docker run command with all configured options.
@@ -80,6 +130,11 @@ func (o *DockerRunnerOptions) GetBaseDockerCommand(env []string) []string {
 	// Start with basic docker run command
 	parts := []string{"docker run --rm"}
 
+	// Keep the standard input open when there is something to read
+	if o.Stdin != "" {
+		parts = append(parts, "-i")
+	}
+
 	// Add networking option
 	if !o.AllowNetworking {
 		parts = append(parts, "--network none")
@@ -138,6 +193,24 @@ func (o *DockerRunnerOptions) GetBaseDockerCommand(env []string) []string {
 		parts = append(parts, fmt.Sprintf("--platform %s", o.Platform))
 	}
 
+	// Add the devices and GPUs
+	for _, device := range o.Devices {
+		parts = append(parts, "--device "+common.QuoteShellArg(common.ShellPOSIX, device))
+	}
+	if o.GPUs != "" {
+		parts = append(parts, "--gpus "+common.QuoteShellArg(common.ShellPOSIX, dockerGPUs(o.GPUs)))
+	}
+
+	// Add the seccomp profile (the default one is applied by Docker)
+	if o.Seccomp != "" && o.Seccomp != config.SeccompDefault {
+		parts = append(parts, "--security-opt "+common.QuoteShellArg(common.ShellPOSIX, "seccomp="+o.Seccomp))
+	}
+
+	// Add the pull policy if specified
+	if o.PullPolicy != "" {
+		parts = append(parts, fmt.Sprintf("--pull %s", dockerPullPolicy(o.PullPolicy)))
+	}
+
 	// Add custom docker run options
 	if o.DockerRunOpts != "" {
 		parts = append(parts, o.DockerRunOpts)
@@ -148,6 +221,11 @@ func (o *DockerRunnerOptions) GetBaseDockerCommand(env []string) []string {
 		parts = append(parts, fmt.Sprintf("-v %s", mount))
 	}
 
+	// Add the mounts of the tool
+	for _, volume := range o.Volumes {
+		parts = append(parts, volume.dockerArgs())
+	}
+
 	// Add environment variables
 	for _, e := range env {
 		parts = append(parts, fmt.Sprintf("-e %s", e))
@@ -167,7 +245,7 @@ func (o *DockerRunnerOptions) GetDockerCommand(scriptFile string, env []string)
 	parts = append(parts, fmt.Sprintf("-v %s:%s", scriptFile, containerScriptPath))
 
 	// Add image and the command to execute the script
-	parts = append(parts, o.Image)
+	parts = append(parts, o.ImageRef())
 	parts = append(parts, fmt.Sprintf("sh %s", containerScriptPath))
 
 	// Join all parts
@@ -181,13 +259,125 @@ func (o *DockerRunnerOptions) GetDirectExecutionCommand(cmd string, env []string
 	parts := o.GetBaseDockerCommand(env)
 
 	// Add image and direct command
-	parts = append(parts, o.Image)
+	parts = append(parts, o.ImageRef())
 	parts = append(parts, cmd)
 
 	// Join all parts into a single command
 	return strings.Join(parts, " ")
 }
 
+// dockerArgs returns the arguments of "docker run" for mounting a path
+func (m Mount) dockerArgs() string {
+	if m.Tmpfs {
+		target := m.ContainerPath
+		if m.ReadOnly {
+			target += ":ro"
+		}
+		return "--tmpfs " + common.QuoteShellArg(common.ShellPOSIX, target)
+	}
+
+	spec := fmt.Sprintf("type=bind,source=%s,target=%s", m.HostPath, m.ContainerPath)
+	if m.ReadOnly {
+		spec += ",readonly"
+	}
+	return "--mount " + common.QuoteShellArg(common.ShellPOSIX, spec)
+}
+
+// ImageRef returns the reference of the image run, pinned to the digest (if any).
+func (o *DockerRunnerOptions) ImageRef() string {
+	if o.ImageDigest == "" || strings.Contains(o.Image, "@") {
+		return o.Image
+	}
+	return o.Image + "@" + o.ImageDigest
+}
+
+// dockerGPUs returns the value of the "--gpus" flag of Docker for some GPUs: a list of
+// devices must be quoted (ie, '"device=0,1"'), as the value is parsed as CSV
+func dockerGPUs(gpus string) string {
+	if strings.HasPrefix(gpus, "device=") && strings.Contains(gpus, ",") {
+		return `"` + gpus + `"`
+	}
+	return gpus
+}
+
+// checkDevices checks the devices are given by their (absolute) paths in the host
+func (o *DockerRunnerOptions) checkDevices() error {
+	for _, device := range o.Devices {
+		if !strings.HasPrefix(device, "/") {
+			return fmt.Errorf("invalid device '%s': it must be an absolute path in the host", device)
+		}
+	}
+	return nil
+}
+
+// dockerPullPolicy returns the value of the "--pull" flag of Docker for a pull policy
+func dockerPullPolicy(policy string) string {
+	if policy == PullPolicyIfNotPresent {
+		return "missing"
+	}
+	return policy
+}
+
+// checkImage checks the pull policy and the digest of the image are valid
+func (o *DockerRunnerOptions) checkImage() error {
+	switch o.PullPolicy {
+	case "", PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever:
+	default:
+		return fmt.Errorf("invalid pull policy '%s' (must be '%s', '%s' or '%s')",
+			o.PullPolicy, PullPolicyAlways, PullPolicyIfNotPresent, PullPolicyNever)
+	}
+
+	if o.ImageDigest != "" {
+		if !imageDigestRegexp.MatchString(o.ImageDigest) {
+			return fmt.Errorf("invalid image digest '%s' (must be 'sha256:<hex>')", o.ImageDigest)
+		}
+		if _, digest, found := strings.Cut(o.Image, "@"); found && digest != o.ImageDigest {
+			return fmt.Errorf("image '%s' is pinned to another digest than '%s'", o.Image, o.ImageDigest)
+		}
+	}
+	return nil
+}
+
+// Validate checks the values of the options when the configuration is loaded (the
+// values with templates are checked when the runner is created).
+func (o *DockerRunnerOptions) Validate() error {
+	for _, value := range append([]string{o.Image, o.ImageDigest, o.PullPolicy}, o.Devices...) {
+		if strings.Contains(value, "{{") {
+			return nil
+		}
+	}
+	if err := o.checkDevices(); err != nil {
+		return err
+	}
+	return o.checkImage()
+}
+
+// PullImage makes sure the image is present following the pull policy: it is pulled when
+// the policy is "always", or when it is not present (unless the policy is "never", when
+// an error is returned for a missing image).
+func (o *DockerRunnerOptions) PullImage(ctx context.Context) error {
+	ref := o.ImageRef()
+
+	if o.PullPolicy != PullPolicyAlways {
+		if err := exec.CommandContext(ctx, "docker", "image", "inspect", ref).Run(); err == nil {
+			return nil
+		}
+		if o.PullPolicy == PullPolicyNever {
+			return fmt.Errorf("image '%s' not present (and the pull policy is '%s')", ref, PullPolicyNever)
+		}
+	}
+
+	args := []string{"pull"}
+	if o.Platform != "" {
+		args = append(args, "--platform", o.Platform)
+	}
+	output, err := exec.CommandContext(ctx, "docker", append(args, ref)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull image '%s': %w: %s", ref, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // NewDockerRunnerOptions extracts Docker-specific options from generic runner options.
 func NewDockerRunnerOptions(genericOpts RunnerOptions) (DockerRunnerOptions, error) {
 	opts := DockerRunnerOptions{
@@ -204,6 +394,20 @@ func NewDockerRunnerOptions(genericOpts RunnerOptions) (DockerRunnerOptions, err
 		return opts, fmt.Errorf("docker runner requires 'image' option")
 	}
 
+	// Parse the image digest, the pull policy and the pre-pull option
+	if imageDigest, ok := genericOpts["image_digest"].(string); ok {
+		opts.ImageDigest = imageDigest
+	}
+	if pullPolicy, ok := genericOpts["pull_policy"].(string); ok {
+		opts.PullPolicy = pullPolicy
+	}
+	if prePull, ok := genericOpts["pre_pull"].(bool); ok {
+		opts.PrePull = prePull
+	}
+	if err := opts.checkImage(); err != nil {
+		return opts, err
+	}
+
 	// Parse optional docker run options
 	if dockerRunOpts, ok := genericOpts["docker_run_opts"].(string); ok {
 		opts.DockerRunOpts = dockerRunOpts
@@ -218,6 +422,25 @@ func NewDockerRunnerOptions(genericOpts RunnerOptions) (DockerRunnerOptions, err
 		}
 	}
 
+	// Parse the mounts of the tool (given by the command handler, or in the options)
+	switch volumes := genericOpts["volumes"].(type) {
+	case []Mount:
+		opts.Volumes = volumes
+	case []interface{}:
+		data, err := json.Marshal(volumes)
+		if err == nil {
+			err = json.Unmarshal(data, &opts.Volumes)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid volumes: %w", err)
+		}
+	}
+	for _, volume := range opts.Volumes {
+		if strings.ContainsAny(volume.HostPath+volume.ContainerPath, ",\n") {
+			return opts, fmt.Errorf("invalid mount '%s': the paths cannot contain commas or newlines", volume.ContainerPath)
+		}
+	}
+
 	// Parse networking option
 	if allowNetworking, ok := genericOpts["allow_networking"].(bool); ok {
 		opts.AllowNetworking = allowNetworking
@@ -304,6 +527,41 @@ func NewDockerRunnerOptions(genericOpts RunnerOptions) (DockerRunnerOptions, err
 		opts.Platform = platform
 	}
 
+	// Parse the devices and GPUs
+	if devices, ok := genericOpts["devices"].([]interface{}); ok {
+		for _, device := range devices {
+			if deviceStr, ok := device.(string); ok {
+				opts.Devices = append(opts.Devices, deviceStr)
+			}
+		}
+	}
+	if gpus, ok := genericOpts["gpus"].(string); ok {
+		opts.GPUs = gpus
+	}
+
+	// Parse the seccomp profile
+	if seccomp, ok := genericOpts["seccomp"].(string); ok {
+		opts.Seccomp = seccomp
+	}
+	if err := opts.checkDevices(); err != nil {
+		return opts, err
+	}
+
+	// Parse standard input option
+	if stdin, ok := genericOpts["stdin"].(string); ok {
+		opts.Stdin = stdin
+	}
+
+	// Parse log file option
+	if logFile, ok := genericOpts["log_file"].(string); ok {
+		opts.LogFile = logFile
+	}
+
+	// Parse termination option
+	if termination, ok := genericOpts["termination"].([]TerminationStep); ok {
+		opts.Termination = termination
+	}
+
 	return opts, nil
 }
 
@@ -349,11 +607,30 @@ func (r *DockerRunner) CheckImplicitRequirements() error {
 // Run executes the command using Docker.
 func (r *DockerRunner) Run(ctx context.Context, shell string, cmd string, env []string, params map[string]interface{}, tmpfile bool) (string, error) {
 	// Create an exec runner that we'll use to execute the docker command
-	execRunner, err := NewRunnerExec(RunnerOptions{}, r.logger)
+	execRunner, err := NewRunnerExec(RunnerOptions{
+		"stdin":       r.opts.Stdin,
+		"termination": r.opts.Termination,
+		"log_file":    r.opts.LogFile,
+	}, r.logger)
 	if err != nil {
 		return "", fmt.Errorf("failed to create exec runner: %w", err)
 	}
 
+	// Write the built-in seccomp profile (if used) to a temporary file
+	opts := r.opts
+	if opts.Seccomp == config.SeccompNoNetworkNoExec {
+		profileFile, err := r.createSeccompFile()
+		if err != nil {
+			return "", fmt.Errorf("failed to write seccomp profile: %w", err)
+		}
+		defer func() {
+			if err := os.Remove(profileFile); err != nil {
+				r.logger.Printf("Warning: failed to remove seccomp profile %s: %v", profileFile, err)
+			}
+		}()
+		opts.Seccomp = profileFile
+	}
+
 	var dockerCmd string
 
 	// Determine if we should run directly or via script
@@ -361,7 +638,7 @@ func (r *DockerRunner) Run(ctx context.Context, shell string, cmd string, env []
 		r.logger.Printf("Optimization: running single executable command directly in Docker: %s", cmd)
 
 		// Build docker command to directly execute the command without a temp script
-		dockerCmd = r.opts.GetDirectExecutionCommand(cmd, env)
+		dockerCmd = opts.GetDirectExecutionCommand(cmd, env)
 	} else {
 		// Create a temporary script file
 		scriptFile, err := r.createScriptFile(shell, cmd, env)
@@ -379,7 +656,7 @@ func (r *DockerRunner) Run(ctx context.Context, shell string, cmd string, env []
 		r.logger.Printf("Created temporary script file: %s", scriptFile)
 
 		// Construct the docker run command with the script file
-		dockerCmd = r.opts.GetDockerCommand(scriptFile, env)
+		dockerCmd = opts.GetDockerCommand(scriptFile, env)
 	}
 
 	r.logger.Printf("Running command in Docker: %s", dockerCmd)
@@ -393,6 +670,25 @@ func (r *DockerRunner) Run(ctx context.Context, shell string, cmd string, env []
 	return output, nil
 }
 
+// createSeccompFile writes the built-in seccomp profile to a temporary file (read by
+// the docker client).
+func (r *DockerRunner) createSeccompFile() (string, error) {
+	tmpFile, err := os.CreateTemp("", "mcpshell-seccomp-*.json")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmpFile.WriteString(dockerSeccompProfile); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
 // createScriptFile writes the command to a temporary script file.
 func (r *DockerRunner) createScriptFile(shell string, cmd string, env []string) (string, error) {
 	// Create a temporary file with a specific pattern
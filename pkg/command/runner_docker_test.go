@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
 	"os/exec"
@@ -403,6 +404,162 @@ func TestNewDockerRunnerOptions(t *testing.T) {
 	}
 }
 
+func TestDockerRunnerOptionsImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	opts, err := NewDockerRunnerOptions(RunnerOptions{
+		"image":        "alpine:3.20",
+		"image_digest": digest,
+		"pull_policy":  "if-not-present",
+		"pre_pull":     true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.PrePull {
+		t.Errorf("Expected the image to be pulled when starting")
+	}
+	cmd := opts.GetDirectExecutionCommand("ls", nil)
+	if !strings.Contains(cmd, "--pull missing") || !strings.HasSuffix(cmd, " alpine:3.20@"+digest+" ls") {
+		t.Errorf("Unexpected command: %s", cmd)
+	}
+
+	// the image can be pinned in the image too
+	opts, err = NewDockerRunnerOptions(RunnerOptions{"image": "alpine@" + digest, "image_digest": digest})
+	if err != nil || opts.ImageRef() != "alpine@"+digest {
+		t.Errorf("Unexpected image %q (%v)", opts.ImageRef(), err)
+	}
+
+	testCases := []struct {
+		name    string
+		options RunnerOptions
+		wantErr string
+	}{
+		{"invalid pull policy", RunnerOptions{"image": "alpine", "pull_policy": "sometimes"}, "invalid pull policy"},
+		{"invalid digest", RunnerOptions{"image": "alpine", "image_digest": "latest"}, "invalid image digest"},
+		{"other digest", RunnerOptions{"image": "alpine@sha256:" + strings.Repeat("cd", 32), "image_digest": digest}, "another digest"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewDockerRunnerOptions(tc.options); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected an error with %q, got %v", tc.wantErr, err)
+			}
+			// ... also reported when validating the configuration
+			if err := ValidateRunnerOptions(RunnerTypeDocker, tc.options); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected a validation error with %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// the values with templates are checked when the runner is created
+	if err := ValidateRunnerOptions(RunnerTypeDocker, RunnerOptions{"image": "alpine", "pull_policy": "{{ .policy }}"}); err != nil {
+		t.Errorf("Unexpected error for a templated option: %v", err)
+	}
+}
+
+func TestDockerRunnerOptionsVolumes(t *testing.T) {
+	opts, err := NewDockerRunnerOptions(RunnerOptions{
+		"image": "alpine",
+		"volumes": []Mount{
+			{HostPath: "/srv/my data", ContainerPath: "/data", ReadOnly: true},
+			{ContainerPath: "/tmp", Tmpfs: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cmd := opts.GetDirectExecutionCommand("ls", nil)
+	for _, arg := range []string{"--mount 'type=bind,source=/srv/my data,target=/data,readonly'", "--tmpfs '/tmp'"} {
+		if !strings.Contains(cmd, arg) {
+			t.Errorf("Expected %q in the command: %s", arg, cmd)
+		}
+	}
+
+	// the volumes can be given in the options too
+	opts, err = NewDockerRunnerOptions(RunnerOptions{
+		"image":   "alpine",
+		"volumes": []interface{}{map[string]interface{}{"host_path": "/srv", "container_path": "/srv"}},
+	})
+	if err != nil || len(opts.Volumes) != 1 || opts.Volumes[0].HostPath != "/srv" {
+		t.Errorf("Unexpected volumes %+v (%v)", opts.Volumes, err)
+	}
+
+	// the paths cannot break the mount specification
+	if _, err := NewDockerRunnerOptions(RunnerOptions{
+		"image":   "alpine",
+		"volumes": []Mount{{HostPath: "/srv,target=/etc", ContainerPath: "/srv"}},
+	}); err == nil {
+		t.Errorf("Expected an error for a path with commas")
+	}
+}
+
+func TestDockerRunnerOptionsDevices(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  RunnerOptions
+		expected []string
+	}{
+		{"devices", RunnerOptions{"image": "alpine", "devices": []interface{}{"/dev/dri", "/dev/fuse:/dev/fuse:rwm"}},
+			[]string{"--device '/dev/dri'", "--device '/dev/fuse:/dev/fuse:rwm'"}},
+		{"all the GPUs", RunnerOptions{"image": "alpine", "gpus": "all"}, []string{"--gpus 'all'"}},
+		{"some GPUs", RunnerOptions{"image": "alpine", "gpus": "device=0,1"}, []string{`--gpus '"device=0,1"'`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := NewDockerRunnerOptions(tc.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			cmd := opts.GetDirectExecutionCommand("nvidia-smi", nil)
+			for _, arg := range tc.expected {
+				if !strings.Contains(cmd, arg) {
+					t.Errorf("Expected %q in the command: %s", arg, cmd)
+				}
+			}
+		})
+	}
+
+	// the devices are paths in the host
+	options := RunnerOptions{"image": "alpine", "devices": []interface{}{"dri"}}
+	if _, err := NewDockerRunnerOptions(options); err == nil {
+		t.Errorf("Expected an error for a relative device")
+	}
+	if err := ValidateRunnerOptions(RunnerTypeDocker, options); err == nil || !strings.Contains(err.Error(), "invalid device") {
+		t.Errorf("Expected a validation error for a relative device, got %v", err)
+	}
+}
+
+func TestDockerRunnerOptionsSeccomp(t *testing.T) {
+	opts, err := NewDockerRunnerOptions(RunnerOptions{"image": "alpine", "seccomp": "/etc/mcpshell/seccomp.json"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd := opts.GetDirectExecutionCommand("ls", nil); !strings.Contains(cmd, "--security-opt 'seccomp=/etc/mcpshell/seccomp.json'") {
+		t.Errorf("Expected the seccomp profile in the command: %s", cmd)
+	}
+
+	// the default profile is applied by Docker
+	opts, _ = NewDockerRunnerOptions(RunnerOptions{"image": "alpine", "seccomp": "default"})
+	if cmd := opts.GetDirectExecutionCommand("ls", nil); strings.Contains(cmd, "seccomp") {
+		t.Errorf("Unexpected seccomp profile in the command: %s", cmd)
+	}
+
+	// the built-in profile is a valid profile
+	var profile struct {
+		DefaultAction string `json:"defaultAction"`
+		Syscalls      []struct {
+			Names  []string `json:"names"`
+			Action string   `json:"action"`
+		} `json:"syscalls"`
+	}
+	if err := json.Unmarshal([]byte(dockerSeccompProfile), &profile); err != nil {
+		t.Fatalf("Invalid built-in seccomp profile: %v", err)
+	}
+	if profile.DefaultAction == "" || len(profile.Syscalls) == 0 {
+		t.Errorf("Unexpected built-in seccomp profile: %+v", profile)
+	}
+}
+
 // Helper function to compare string slices
 func compareStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -5,24 +5,45 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+
+	"github.com/inercia/MCPShell/pkg/common"
 )
 
 // RunnerExec implements the Runner interface
 type RunnerExec struct {
 	logger  *log.Logger
 	options RunnerExecOptions
+	limits  processLimits
 }
 
 // RunnerExecOptions is the options for the RunnerExec
 type RunnerExecOptions struct {
-	Shell string `json:"shell"`
+	Shell          string            `json:"shell"`
+	Workdir        string            `json:"workdir"`
+	EnvPassthrough []string          `json:"env_passthrough"`
+	Stdin          string            `json:"stdin"`
+	PTY            bool              `json:"pty"`
+	PTYRows        int               `json:"pty_rows"`
+	PTYCols        int               `json:"pty_cols"`
+	Expect         []ExpectStep      `json:"expect"`
+	Termination    []TerminationStep `json:"termination"`
+	LogFile        string            `json:"log_file"`
+	MaxMemory      string            `json:"max_memory"`
+	MaxCPUTime     string            `json:"max_cpu_time"`
+	MaxProcesses   int               `json:"max_processes"`
 }
 
+// The default size of the pseudo-terminals
+const (
+	DefaultPTYRows = 24
+	DefaultPTYCols = 80
+)
+
 // NewRunnerExecOptions creates a new RunnerExecOptions from a RunnerOptions
 func NewRunnerExecOptions(options RunnerOptions) (RunnerExecOptions, error) {
 	var reopts RunnerExecOptions
@@ -48,9 +69,15 @@ func NewRunnerExec(options RunnerOptions, logger *log.Logger) (*RunnerExec, erro
 		return nil, err
 	}
 
+	limits, err := newProcessLimits(execOptions.MaxMemory, execOptions.MaxCPUTime, execOptions.MaxProcesses)
+	if err != nil {
+		return nil, err
+	}
+
 	return &RunnerExec{
 		logger:  logger,
 		options: execOptions,
+		limits:  limits,
 	}, nil
 }
 
@@ -75,13 +102,6 @@ func (r *RunnerExec) Run(ctx context.Context, shell string,
 	if isSingleExecutableCommand(command) {
 		r.logger.Printf("Optimization: running single executable command directly: %s", command)
 		execCmd = exec.Command(command)
-		if len(env) > 0 {
-			r.logger.Printf("Adding %d environment variables to command", len(env))
-			for _, e := range env {
-				r.logger.Printf("... adding environment variable: %s", e)
-			}
-			execCmd.Env = append(os.Environ(), env...)
-		}
 		r.logger.Printf("Created command: %s", command)
 	} else if tmpfile {
 		// Create a temporary file for the command
@@ -97,55 +117,119 @@ func (r *RunnerExec) Run(ctx context.Context, shell string,
 			}
 		}()
 
-		// Format the command with proper shell syntax
-		var scriptContent strings.Builder
-		scriptContent.WriteString("#!/bin/sh\n")
-		scriptContent.WriteString(command)
+		// Set up the command
+		configShell := getShell(shell)
+		r.logger.Printf("Using shell: %s", configShell)
 
-		tmpFile := filepath.Join(tmpDir, "script.sh")
-		err = os.WriteFile(tmpFile, []byte(scriptContent.String()), 0o700)
+		// Write the script (with the syntax of the shell) and create the command to execute it
+		var tmpFile string
+		execCmd, tmpFile, err = shellScriptCommand(configShell, tmpDir, command)
 		if err != nil {
 			r.logger.Printf("Failed to write temporary file: %v", err)
 			return "", err
 		}
-
 		r.logger.Printf("Created temporary script file at: %s", tmpFile)
-
-		// Set up the command
-		configShell := getShell(shell)
-		r.logger.Printf("Using shell: %s", configShell)
-
-		// Create the command to execute the script file
-		execCmd = exec.Command(configShell, tmpFile)
-		r.logger.Printf("Created command: %s %s", configShell, tmpFile)
+		r.logger.Printf("Created command: %s", execCmd)
 	} else {
 		// Execute the command directly without a temporary file
 		configShell := getShell(shell)
 		r.logger.Printf("Using shell: %s", configShell)
 
-		// Simple command without arguments
-		execCmd = exec.Command(configShell, "-c", command)
-		r.logger.Printf("Created command: %s -c %s", configShell, command)
+		execCmd = shellCommand(configShell, command)
+		r.logger.Printf("Created command: %s (%s)", execCmd, command)
 	}
 
+	return r.execute(ctx, execCmd, env)
+}
+
+// RunArgv executes a program with its arguments, without any shell, and returns the output.
+// The arguments are passed as they are, so they are never interpreted.
+func (r *RunnerExec) RunArgv(ctx context.Context, argv []string, env []string) (string, error) {
+	// Check if context is done
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+		// Continue execution
+	}
+
+	if len(argv) == 0 {
+		return "", errors.New("no program to execute")
+	}
+
+	execCmd := exec.Command(argv[0], argv[1:]...)
+	r.logger.Printf("Created command (without shell): %q", argv)
+
+	return r.execute(ctx, execCmd, env)
+}
+
+// execute runs a command with the environment and the options of the runner, returning the output
+func (r *RunnerExec) execute(ctx context.Context, execCmd *exec.Cmd, env []string) (string, error) {
 	// Set environment variables if provided
 	if len(env) > 0 {
 		r.logger.Printf("Adding %d environment variables to command", len(env))
 		for _, e := range env {
 			r.logger.Printf("... adding environment variable: %s", e)
 		}
-		execCmd.Env = append(os.Environ(), env...)
+	}
+	if r.options.EnvPassthrough != nil {
+		r.logger.Printf("Inheriting environment variables: %v", r.options.EnvPassthrough)
+	}
+	execCmd.Env = buildEnvironment(r.options.EnvPassthrough, env)
+
+	// Set the working directory if provided
+	if r.options.Workdir != "" {
+		r.logger.Printf("Using working directory: %s", r.options.Workdir)
+		execCmd.Dir = r.options.Workdir
+	}
+
+	// Provide the standard input (if any)
+	if r.options.Stdin != "" {
+		execCmd.Stdin = strings.NewReader(r.options.Stdin)
+	}
+
+	// Copy the output to the log file (if any) while the command runs
+	logFile, closeLogFile, err := openLogFile(r.options.LogFile)
+	if err != nil {
+		return "", err
+	}
+	defer closeLogFile()
+
+	// Run the command in a pseudo-terminal, where stdout and stderr are merged
+	if r.options.PTY {
+		rows, cols := r.options.PTYRows, r.options.PTYCols
+		if rows <= 0 {
+			rows = DefaultPTYRows
+		}
+		if cols <= 0 {
+			cols = DefaultPTYCols
+		}
+		r.logger.Printf("Executing command in a pseudo-terminal (%dx%d)", cols, rows)
+
+		output, err := runWithPTY(ctx, execCmd, rows, cols, r.options.Expect, r.options.Termination, r.limits, logFile, r.logger)
+		if err != nil {
+			r.logger.Printf("Command failed with error: %v", err)
+			return "", err
+		}
+		r.logger.Printf("Command executed successfully, output length: %d bytes", len(output))
+		return output, nil
 	}
 
 	// Capture output
 	var stdout, stderr bytes.Buffer
-	execCmd.Stdout = &stdout
-	execCmd.Stderr = &stderr
+	execCmd.Stdout = io.MultiWriter(&stdout, logFile)
+	execCmd.Stderr = io.MultiWriter(&stderr, logFile)
 
-	// Run the command
+	// Run the command, terminating it when the context is cancelled
 	r.logger.Printf("Executing command")
 
-	err := execCmd.Run()
+	setProcessGroup(execCmd)
+	err = execCmd.Start()
+	if err == nil {
+		trackProcessTree(execCmd, r.limits, r.logger)
+		err = waitWithTermination(ctx, execCmd, r.options.Termination, r.logger)
+		releaseProcessTree(execCmd)
+	}
 	if err != nil {
 		// If there's error output, include it in the error
 		if stderr.Len() > 0 {
@@ -171,7 +255,8 @@ func (r *RunnerExec) Run(ctx context.Context, shell string,
 
 // getShell returns the shell to use for command execution,
 // using the provided shell, falling back to $SHELL env var,
-// and finally using /bin/sh as a last resort.
+// the command interpreter in Windows, and finally using /bin/sh
+// as a last resort.
 //
 // Parameters:
 //   - configShell: The configured shell to use (can be empty)
@@ -183,12 +268,7 @@ func getShell(configShell string) string {
 		return configShell
 	}
 
-	shell := os.Getenv("SHELL")
-	if shell != "" {
-		return shell
-	}
-
-	return "/bin/sh"
+	return common.DefaultShell()
 }
 
 // CheckImplicitRequirements checks if the runner meets its implicit requirements
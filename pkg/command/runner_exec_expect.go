@@ -0,0 +1,38 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxExpectBuffer is the maximum size of the output kept while waiting for a prompt
+const maxExpectBuffer = 64 * 1024
+
+// ExpectStep is a prompt expected in the output of a command run
+// in a pseudo-terminal, and the response written when it shows up
+type ExpectStep struct {
+	Expect string `json:"expect"` // a regular expression matching the prompt
+	Send   string `json:"send"`   // the response (a newline is added)
+}
+
+// compiledExpectStep is an ExpectStep with the regular expression compiled
+type compiledExpectStep struct {
+	pattern *regexp.Regexp
+	send    string
+}
+
+// compileExpectSteps compiles the regular expressions of some expect steps
+func compileExpectSteps(steps []ExpectStep) ([]compiledExpectStep, error) {
+	res := make([]compiledExpectStep, 0, len(steps))
+	for i, step := range steps {
+		if step.Expect == "" {
+			return nil, fmt.Errorf("missing the prompt to expect in step %d", i+1)
+		}
+		pattern, err := regexp.Compile(step.Expect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt to expect in step %d: %w", i+1, err)
+		}
+		res = append(res, compiledExpectStep{pattern: pattern, send: step.Send})
+	}
+	return res, nil
+}
@@ -0,0 +1,163 @@
+//go:build linux || darwin
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// runWithPTY runs a command with a pseudo-terminal as its standard input, output
+// and error, returning everything written to the terminal.
+//
+// Parameters:
+//   - ctx: The context of the execution (the command is terminated when cancelled)
+//   - execCmd: The command to run (not started yet)
+//   - rows: The number of rows of the terminal
+//   - cols: The number of columns of the terminal
+//   - expect: The prompts to answer, in order (can be empty)
+//   - termination: The termination sequence (can be empty)
+//   - limits: The limits on the resources used by the command
+//   - logFile: Where the output is copied while the command runs
+//   - logger: Logger for the termination steps
+//
+// Returns:
+//   - The output of the command
+//   - An error (with the output as the message, if any) when the command fails
+func runWithPTY(ctx context.Context, execCmd *exec.Cmd, rows int, cols int,
+	expect []ExpectStep, termination []TerminationStep, limits processLimits, logFile io.Writer, logger *log.Logger,
+) (string, error) {
+	steps, err := compileExpectSteps(expect)
+	if err != nil {
+		return "", err
+	}
+
+	master, tty, err := openPTY()
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate a pseudo-terminal: %w", err)
+	}
+	defer func() {
+		_ = master.Close()
+	}()
+
+	err = ptyControl(master, func(fd int) error {
+		return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(rows), Col: uint16(cols)})
+	})
+	if err != nil {
+		_ = tty.Close()
+		return "", fmt.Errorf("failed to set the size of the pseudo-terminal: %w", err)
+	}
+
+	execCmd.Stdin = tty
+	execCmd.Stdout = tty
+	execCmd.Stderr = tty
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if !hasEnvVar(execCmd.Env, "TERM") {
+		execCmd.Env = append(execCmd.Env, "TERM=xterm")
+	}
+
+	if err := execCmd.Start(); err != nil {
+		_ = tty.Close()
+		return "", err
+	}
+	// only the command keeps the terminal open, so we get an EOF (or EIO) when it exits
+	_ = tty.Close()
+	trackProcessTree(execCmd, limits, logger)
+
+	var output bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		if len(steps) > 0 {
+			_, _ = io.Copy(io.MultiWriter(&output, logFile, &ptyExpecter{steps: steps, terminal: master}), master)
+		} else {
+			_, _ = io.Copy(io.MultiWriter(&output, logFile), master)
+		}
+		close(copied)
+	}()
+
+	runErr := waitWithTermination(ctx, execCmd, termination, logger)
+
+	// processes started in background could keep the terminal open
+	select {
+	case <-copied:
+	case <-time.After(time.Second):
+		_ = master.Close()
+		<-copied
+	}
+
+	// terminals translate newlines to CR+LF
+	res := strings.TrimSpace(strings.ReplaceAll(output.String(), "\r\n", "\n"))
+	if runErr != nil {
+		if res != "" {
+			return "", errors.New(res)
+		}
+		return "", runErr
+	}
+	return res, nil
+}
+
+// ptyControl calls a function with the file descriptor of a terminal
+func ptyControl(f *os.File, fn func(fd int) error) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := conn.Control(func(fd uintptr) {
+		fnErr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// hasEnvVar returns true if a variable is defined in an environment
+func hasEnvVar(env []string, name string) bool {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// ptyExpecter answers the prompts written to a terminal, writing the response
+// of every step when its pattern matches the output after the previous match
+type ptyExpecter struct {
+	steps    []compiledExpectStep
+	terminal io.Writer // where the responses are written
+	pending  []byte    // the output since the last match
+}
+
+// Write checks the output of the command, answering the prompts found
+func (e *ptyExpecter) Write(p []byte) (int, error) {
+	e.pending = append(e.pending, p...)
+	for len(e.steps) > 0 {
+		loc := e.steps[0].pattern.FindIndex(e.pending)
+		if loc == nil {
+			break
+		}
+		if _, err := io.WriteString(e.terminal, e.steps[0].send+"\n"); err != nil {
+			break
+		}
+		e.pending = e.pending[loc[1]:]
+		e.steps = e.steps[1:]
+	}
+
+	// do not keep the whole output when the prompt does not show up
+	if len(e.pending) > maxExpectBuffer {
+		e.pending = e.pending[len(e.pending)-maxExpectBuffer:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"runtime"
+)
+
+// runWithPTY is not supported in this platform
+func runWithPTY(ctx context.Context, execCmd *exec.Cmd, rows int, cols int,
+	expect []ExpectStep, termination []TerminationStep, limits processLimits, logFile io.Writer, logger *log.Logger,
+) (string, error) {
+	return "", fmt.Errorf("pseudo-terminals are not supported in %s", runtime.GOOS)
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// shellCommand creates the command for running a command line in a shell,
+// passing it in the way expected by the kind of shell
+func shellCommand(shell string, command string) *exec.Cmd {
+	switch common.GetShellKind(shell) {
+	case common.ShellCmd:
+		return cmdShellCommand(shell, command)
+
+	case common.ShellPowerShell:
+		// an encoded command is not mangled by the parsing of the command line
+		return exec.Command(shell, "-NoProfile", "-NonInteractive", "-EncodedCommand", encodePowerShellCommand(command))
+
+	default:
+		return exec.Command(shell, "-c", command)
+	}
+}
+
+// shellScriptCommand writes a command line to a script in a directory, with
+// the extension and line endings expected by the kind of shell, returning
+// the command for running it
+func shellScriptCommand(shell string, dir string, command string) (*exec.Cmd, string, error) {
+	var filename, content string
+	switch common.GetShellKind(shell) {
+	case common.ShellCmd:
+		filename = "script.cmd"
+		content = "@echo off\r\n" + strings.ReplaceAll(strings.ReplaceAll(command, "\r\n", "\n"), "\n", "\r\n")
+	case common.ShellPowerShell:
+		filename = "script.ps1"
+		content = command
+	default:
+		filename = "script.sh"
+		content = "#!/bin/sh\n" + command
+	}
+
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, []byte(content), 0o700); err != nil {
+		return nil, "", err
+	}
+
+	switch common.GetShellKind(shell) {
+	case common.ShellCmd:
+		return cmdShellCommand(shell, `"`+path+`"`), path, nil
+	case common.ShellPowerShell:
+		return exec.Command(shell, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", path), path, nil
+	default:
+		return exec.Command(shell, path), path, nil
+	}
+}
+
+// encodePowerShellCommand encodes a command for the -EncodedCommand argument of PowerShell
+func encodePowerShellCommand(command string) string {
+	codes := utf16.Encode([]rune(command))
+	buf := make([]byte, 2*len(codes))
+	for i, code := range codes {
+		buf[2*i] = byte(code)
+		buf[2*i+1] = byte(code >> 8)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		shell    string
+		expected []string
+	}{
+		{"posix", "/bin/bash", []string{"/bin/bash", "-c", "echo hi"}},
+		{"powershell", "pwsh", []string{"pwsh", "-NoProfile", "-NonInteractive", "-EncodedCommand", "ZQBjAGgAbwAgAGgAaQA="}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCmd := shellCommand(tt.shell, "echo hi")
+			if !slices.Equal(execCmd.Args, tt.expected) {
+				t.Errorf("Expected args %q, got %q", tt.expected, execCmd.Args)
+			}
+		})
+	}
+}
+
+func TestShellScriptCommand(t *testing.T) {
+	tests := []struct {
+		name            string
+		shell           string
+		expectedFile    string
+		expectedContent string
+	}{
+		{"posix", "/bin/sh", "script.sh", "#!/bin/sh\necho a\necho b"},
+		{"cmd", "cmd.exe", "script.cmd", "@echo off\r\necho a\r\necho b"},
+		{"powershell", "powershell.exe", "script.ps1", "echo a\necho b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			execCmd, path, err := shellScriptCommand(tt.shell, dir, "echo a\necho b")
+			if err != nil {
+				t.Fatalf("shellScriptCommand failed: %v", err)
+			}
+			if path != filepath.Join(dir, tt.expectedFile) {
+				t.Errorf("Expected script %s, got %s", tt.expectedFile, path)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read the script: %v", err)
+			}
+			if string(content) != tt.expectedContent {
+				t.Errorf("Expected content %q, got %q", tt.expectedContent, string(content))
+			}
+			if runtime.GOOS != "windows" && !strings.Contains(strings.Join(execCmd.Args, " "), path) {
+				t.Errorf("Expected the command to run the script, got %q", execCmd.Args)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package command
+
+import (
+	"os/exec"
+)
+
+// cmdShellCommand creates the command for running a command line in cmd.exe
+func cmdShellCommand(shell string, command string) *exec.Cmd {
+	return exec.Command(shell, "/d", "/s", "/c", command)
+}
@@ -0,0 +1,18 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// cmdShellCommand creates the command for running a command line in cmd.exe.
+// cmd.exe does not parse its command line like other programs, so the command
+// is passed verbatim (with /s, only the outer quotes are removed).
+func cmdShellCommand(shell string, command string) *exec.Cmd {
+	execCmd := exec.Command(shell)
+	execCmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: fmt.Sprintf(`%s /d /s /c "%s"`, syscall.EscapeArg(shell), command),
+	}
+	return execCmd
+}
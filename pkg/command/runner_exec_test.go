@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -186,3 +187,35 @@ func TestRunnerExec_Optimization_SingleExecutable(t *testing.T) {
 		t.Logf("Expected failure for /bin/ls -l as a single executable: %v", err2)
 	}
 }
+
+func TestRunnerExec_RunWithPTY(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("pseudo-terminals are not supported in " + runtime.GOOS)
+	}
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no pseudo-terminals available")
+	}
+
+	logger := log.New(os.Stderr, "test-runner-exec-pty: ", log.LstdFlags)
+	r, err := NewRunnerExec(RunnerOptions{"pty": true, "pty_rows": 30, "pty_cols": 100}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create RunnerExec: %v", err)
+	}
+
+	// the command sees a terminal of the configured size, and stderr is merged
+	output, err := r.Run(context.Background(), "sh",
+		`if [ -t 0 ] && [ -t 1 ]; then echo tty; fi; stty size; echo "$TERM"; echo oops >&2`,
+		nil, nil, false)
+	if err != nil {
+		t.Fatalf("RunnerExec.Run() error = %v", err)
+	}
+	if output != "tty\n30 100\nxterm\noops" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	// the output is returned as the error when the command fails
+	_, err = r.Run(context.Background(), "sh", "echo failed; exit 1", nil, nil, false)
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Expected the output as the error, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestRunnerExec_WindowsShells(t *testing.T) {
+	logger := log.New(os.Stderr, "test-runner-exec: ", log.LstdFlags)
+	runner, err := NewRunnerExec(RunnerOptions{}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create runner: %v", err)
+	}
+
+	shells := map[string]string{
+		"cmd":        "cmd.exe",
+		"powershell": "powershell.exe",
+	}
+
+	for name, shell := range shells {
+		if _, err := exec.LookPath(shell); err != nil {
+			t.Logf("Skipping %s: not found", shell)
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			kind := common.GetShellKind(shell)
+			value := `a & b | "c" %PATH% $env:PATH it's`
+			command := "echo " + common.QuoteShellArg(kind, value)
+			if kind == common.ShellPowerShell {
+				command = "Write-Output " + common.QuoteShellArg(kind, value)
+			}
+
+			for _, tmpfile := range []bool{false, true} {
+				output, err := runner.Run(context.Background(), shell, command, nil, nil, tmpfile)
+				if err != nil {
+					t.Fatalf("Failed to run command (tmpfile=%v): %v", tmpfile, err)
+				}
+				if !strings.Contains(output, "a & b | ") || !strings.Contains(output, "it's") {
+					t.Errorf("Unexpected output (tmpfile=%v): %q", tmpfile, output)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerExec_WindowsProcessTreeTermination(t *testing.T) {
+	logger := log.New(os.Stderr, "test-runner-exec: ", log.LstdFlags)
+	runner, err := NewRunnerExec(RunnerOptions{}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create runner: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// the child process started by cmd.exe must be terminated too
+	start := time.Now()
+	_, err = runner.Run(ctx, "cmd.exe", "ping -n 30 127.0.0.1 > nul", nil, nil, false)
+	if err == nil {
+		t.Fatal("Expected the command to be terminated")
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("The command was not terminated in time (%s)", elapsed)
+	}
+}
+
+func TestRunnerExec_WindowsJobLimits(t *testing.T) {
+	logger := log.New(os.Stderr, "test-runner-exec: ", log.LstdFlags)
+
+	t.Run("processes", func(t *testing.T) {
+		runner, err := NewRunnerExec(RunnerOptions{"max_processes": 1}, logger)
+		if err != nil {
+			t.Fatalf("Failed to create runner: %v", err)
+		}
+
+		// cmd.exe is the only process allowed in the job, so it cannot start another one
+		output, err := runner.Run(context.Background(), "cmd.exe", "ping -n 1 127.0.0.1 && echo started", nil, nil, false)
+		if err == nil && strings.Contains(output, "started") {
+			t.Errorf("Expected the child process to fail, got %q", output)
+		}
+	})
+
+	t.Run("cpu time", func(t *testing.T) {
+		runner, err := NewRunnerExec(RunnerOptions{"max_cpu_time": "1s"}, logger)
+		if err != nil {
+			t.Fatalf("Failed to create runner: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		_, err = runner.Run(ctx, "powershell.exe", "while ($true) {}", nil, nil, false)
+		if err == nil {
+			t.Fatal("Expected the command to be killed")
+		}
+		if ctx.Err() != nil {
+			t.Fatal("The command was not killed by the CPU time limit")
+		}
+	})
+}
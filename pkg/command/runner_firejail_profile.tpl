@@ -5,7 +5,7 @@
 # Applied restrictions based on provided options
 
 # Network restrictions
-{{ if .AllowNetworking }}
+{{ if and .AllowNetworking (ne .Seccomp "no-network-no-exec") }}
 # Allow networking
 {{ else }}
 # Disable networking
@@ -37,7 +37,13 @@ whitelist {{ . }}
 {{ end }}
 
 # Always apply basic security features
+{{ if eq .Seccomp "no-network-no-exec" }}
+# Only Unix sockets, and no running other programs (the default list plus execve)
+protocol unix
+seccomp execve,execveat
+{{ else }}
 seccomp
+{{ end }}
 caps.drop all
 noroot
 {{ end }} 
\ No newline at end of file
@@ -128,3 +128,39 @@ func TestRunnerFirejail_Optimization_SingleExecutable(t *testing.T) {
 		t.Logf("Expected failure for /bin/ls -l as a single executable: %v", err2)
 	}
 }
+
+func TestRunnerFirejailSeccomp(t *testing.T) {
+	render := func(options RunnerOptions) string {
+		t.Helper()
+		runner, err := NewRunnerFirejail(options, nil)
+		if err != nil {
+			t.Fatalf("Failed to create firejail runner: %v", err)
+		}
+		var profile strings.Builder
+		if err := runner.profileTpl.Execute(&profile, runner.options); err != nil {
+			t.Fatalf("Failed to render the profile: %v", err)
+		}
+		return profile.String()
+	}
+
+	profile := render(RunnerOptions{"allow_networking": true, "seccomp": "default"})
+	if strings.Contains(profile, "net none") || strings.Contains(profile, "execve") {
+		t.Errorf("Unexpected restrictions in the default profile:\n%s", profile)
+	}
+
+	// the built-in profile disables the network (even when allowed) and running programs
+	profile = render(RunnerOptions{"allow_networking": true, "seccomp": "no-network-no-exec"})
+	for _, line := range []string{"net none", "protocol unix", "seccomp execve,execveat"} {
+		if !strings.Contains(profile, line) {
+			t.Errorf("Expected %q in the profile:\n%s", line, profile)
+		}
+	}
+
+	// custom seccomp profiles are not supported
+	if _, err := NewRunnerFirejail(RunnerOptions{"seccomp": "/etc/seccomp.json"}, nil); err == nil {
+		t.Errorf("Expected an error for a custom seccomp profile")
+	}
+	if err := ValidateRunnerOptions(RunnerTypeFirejail, RunnerOptions{"custom_profile": "net none", "seccomp": "no-network-no-exec"}); err == nil {
+		t.Errorf("Expected an error for a seccomp profile with a custom profile")
+	}
+}
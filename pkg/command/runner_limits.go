@@ -0,0 +1,74 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processLimits are the limits on the resources used by a command (and the processes it starts)
+type processLimits struct {
+	memory    uint64        // maximum memory, in bytes
+	cpuTime   time.Duration // maximum CPU time
+	processes int           // maximum number of processes
+}
+
+// newProcessLimits parses the limits of the options of a runner (ie, "512m" and "30s")
+func newProcessLimits(maxMemory string, maxCPUTime string, maxProcesses int) (processLimits, error) {
+	var res processLimits
+
+	if maxMemory != "" {
+		memory, err := parseMemorySize(maxMemory)
+		if err != nil {
+			return processLimits{}, fmt.Errorf("invalid max_memory: %w", err)
+		}
+		res.memory = memory
+	}
+
+	if maxCPUTime != "" {
+		cpuTime, err := time.ParseDuration(maxCPUTime)
+		if err != nil || cpuTime <= 0 {
+			return processLimits{}, fmt.Errorf("invalid max_cpu_time '%s'", maxCPUTime)
+		}
+		res.cpuTime = cpuTime
+	}
+
+	if maxProcesses < 0 {
+		return processLimits{}, fmt.Errorf("invalid max_processes %d", maxProcesses)
+	}
+	res.processes = maxProcesses
+
+	return res, nil
+}
+
+// isZero returns true when there are no limits
+func (l processLimits) isZero() bool {
+	return l == processLimits{}
+}
+
+// parseMemorySize parses a memory size in bytes, with an optional unit
+// (k, m or g, as in "512m" or "1g")
+func parseMemorySize(size string) (uint64, error) {
+	s := strings.ToLower(strings.TrimSpace(size))
+	s = strings.TrimSuffix(s, "b")
+
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "g"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid memory size '%s' (expected a number with an optional k, m or g unit)", size)
+	}
+	return n * multiplier, nil
+}
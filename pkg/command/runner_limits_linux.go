@@ -0,0 +1,34 @@
+package command
+
+import (
+	"log"
+	"math"
+
+	"golang.org/x/sys/unix"
+)
+
+// applyProcessLimits sets the resource limits of a (started) process, inherited
+// by all the processes it starts. The memory is limited by the address space
+// of every process.
+func applyProcessLimits(pid int, limits processLimits, logger *log.Logger) {
+	if limits.memory > 0 {
+		rlimit := unix.Rlimit{Cur: limits.memory, Max: limits.memory}
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, &rlimit, nil); err != nil {
+			logger.Printf("Failed to limit the memory of the command: %v", err)
+		}
+	}
+
+	if limits.cpuTime > 0 {
+		seconds := uint64(math.Ceil(limits.cpuTime.Seconds()))
+		// SIGXCPU is sent when the soft limit is reached, and SIGKILL one second later
+		rlimit := unix.Rlimit{Cur: seconds, Max: seconds + 1}
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &rlimit, nil); err != nil {
+			logger.Printf("Failed to limit the CPU time of the command: %v", err)
+		}
+	}
+
+	if limits.processes > 0 {
+		// RLIMIT_NPROC counts all the processes of the user, not only the ones of the command
+		logger.Printf("Ignoring max_processes: only supported in Windows")
+	}
+}
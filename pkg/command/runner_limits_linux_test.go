@@ -0,0 +1,80 @@
+package command
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestRunnerExec_LinuxLimits(t *testing.T) {
+	logger := log.New(os.Stderr, "test-runner-exec: ", log.LstdFlags)
+
+	t.Run("cpu time", func(t *testing.T) {
+		runner, err := NewRunnerExec(RunnerOptions{"max_cpu_time": "1s"}, logger)
+		if err != nil {
+			t.Fatalf("Failed to create runner: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err = runner.Run(ctx, "/bin/sh", "while true; do :; done", nil, nil, false)
+		if err == nil {
+			t.Fatal("Expected the command to be killed")
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("The command was not killed by the CPU time limit (after %s)", time.Since(start))
+		}
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		runner, err := NewRunnerExec(RunnerOptions{"max_memory": "64m"}, logger)
+		if err != nil {
+			t.Fatalf("Failed to create runner: %v", err)
+		}
+
+		// the shell cannot allocate a 128MB string
+		_, err = runner.Run(context.Background(), "/bin/sh",
+			`x=$(head -c 134217728 /dev/zero | tr '\0' 'x'); echo ${#x}`, nil, nil, false)
+		if err == nil {
+			t.Fatal("Expected the command to fail")
+		}
+	})
+}
+
+func TestCommandHandlerCallLimits(t *testing.T) {
+	tool := config.Tool{
+		MCPTool: mcp.Tool{
+			Name: "test-tool",
+		},
+		Config: config.MCPToolConfig{
+			Run: config.MCPToolRunConfig{
+				Command: "ulimit -t",
+			},
+		},
+	}
+
+	handler, err := NewCommandHandler(tool, nil, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create command handler: %v", err)
+	}
+
+	// the limits are owned by the tool, so the clients cannot change them in the calls
+	output, err := handler.ExecuteCommand(map[string]interface{}{
+		"options": map[string]interface{}{"max_cpu_time": "1s", "max_memory": "64m", "max_processes": 1},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(output) != "unlimited" {
+		t.Errorf("Expected no CPU time limit from the call, got %q", output)
+	}
+}
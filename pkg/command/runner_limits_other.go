@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package command
+
+import (
+	"log"
+	"runtime"
+)
+
+// applyProcessLimits is not supported in this platform
+func applyProcessLimits(pid int, limits processLimits, logger *log.Logger) {
+	logger.Printf("Ignoring the resource limits of the command: not supported in %s", runtime.GOOS)
+}
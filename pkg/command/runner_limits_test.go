@@ -0,0 +1,47 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewProcessLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxMemory    string
+		maxCPUTime   string
+		maxProcesses int
+		want         processLimits
+		wantErr      bool
+	}{
+		{name: "no limits", want: processLimits{}},
+		{name: "bytes", maxMemory: "4096", want: processLimits{memory: 4096}},
+		{name: "kilobytes", maxMemory: "64k", want: processLimits{memory: 64 << 10}},
+		{name: "megabytes", maxMemory: "512M", want: processLimits{memory: 512 << 20}},
+		{name: "gigabytes", maxMemory: "1gb", want: processLimits{memory: 1 << 30}},
+		{name: "cpu time", maxCPUTime: "1m30s", want: processLimits{cpuTime: 90 * time.Second}},
+		{name: "processes", maxProcesses: 5, want: processLimits{processes: 5}},
+		{name: "invalid memory", maxMemory: "lots", wantErr: true},
+		{name: "zero memory", maxMemory: "0m", wantErr: true},
+		{name: "invalid cpu time", maxCPUTime: "10", wantErr: true},
+		{name: "negative processes", maxProcesses: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newProcessLimits(tt.maxMemory, tt.maxCPUTime, tt.maxProcesses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newProcessLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("newProcessLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRunnerExec_InvalidLimits(t *testing.T) {
+	if _, err := NewRunnerExec(RunnerOptions{"max_memory": "a lot"}, nil); err == nil {
+		t.Error("Expected an error for an invalid memory limit")
+	}
+}
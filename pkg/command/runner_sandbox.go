@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -29,12 +30,17 @@ type RunnerSandboxExec struct {
 
 // RunnerSandboxExecOptions is the options for the RunnerSandboxExec
 type RunnerSandboxExecOptions struct {
-	Shell             string   `json:"shell"`
-	AllowNetworking   bool     `json:"allow_networking"`
-	AllowUserFolders  bool     `json:"allow_user_folders"`
-	AllowReadFolders  []string `json:"allow_read_folders"`
-	AllowWriteFolders []string `json:"allow_write_folders"`
-	CustomProfile     string   `json:"custom_profile"`
+	Shell             string            `json:"shell"`
+	AllowNetworking   bool              `json:"allow_networking"`
+	AllowUserFolders  bool              `json:"allow_user_folders"`
+	AllowReadFolders  []string          `json:"allow_read_folders"`
+	AllowWriteFolders []string          `json:"allow_write_folders"`
+	CustomProfile     string            `json:"custom_profile"`
+	Workdir           string            `json:"workdir"`
+	EnvPassthrough    []string          `json:"env_passthrough"`
+	Stdin             string            `json:"stdin"`
+	Termination       []TerminationStep `json:"termination"`
+	LogFile           string            `json:"log_file"`
 }
 
 // NewRunnerSandboxExecOptions creates a new RunnerSandboxExecOptions from a RunnerOptions
@@ -201,18 +207,44 @@ func (r *RunnerSandboxExec) Run(ctx context.Context, shell string, command strin
 		for _, e := range env {
 			r.logger.Printf("... adding environment variable: %s", e)
 		}
-		execCmd.Env = append(os.Environ(), env...)
+	}
+	if r.options.EnvPassthrough != nil {
+		r.logger.Printf("Inheriting environment variables: %v", r.options.EnvPassthrough)
+	}
+	execCmd.Env = buildEnvironment(r.options.EnvPassthrough, env)
+
+	// Set the working directory if provided
+	if r.options.Workdir != "" {
+		r.logger.Printf("Using working directory: %s", r.options.Workdir)
+		execCmd.Dir = r.options.Workdir
 	}
 
+	// Provide the standard input (if any)
+	if r.options.Stdin != "" {
+		execCmd.Stdin = strings.NewReader(r.options.Stdin)
+	}
+
+	// Copy the output to the log file (if any) while the command runs
+	logFile, closeLogFile, err := openLogFile(r.options.LogFile)
+	if err != nil {
+		return "", err
+	}
+	defer closeLogFile()
+
 	// Capture output
 	var stdout, stderr bytes.Buffer
-	execCmd.Stdout = &stdout
-	execCmd.Stderr = &stderr
+	execCmd.Stdout = io.MultiWriter(&stdout, logFile)
+	execCmd.Stderr = io.MultiWriter(&stderr, logFile)
 
-	// Run the command
+	// Run the command, terminating it when the context is cancelled
 	r.logger.Printf("Executing command")
 
-	if err := execCmd.Run(); err != nil {
+	setProcessGroup(execCmd)
+	err = execCmd.Start()
+	if err == nil {
+		err = waitWithTermination(ctx, execCmd, r.options.Termination, r.logger)
+	}
+	if err != nil {
 		// If there's error output, include it in the error
 		if stderr.Len() > 0 {
 			errMsg := strings.TrimSpace(stderr.String())
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// TerminationStep is a signal sent to a command when it is cancelled (or it
+// times out), and the time to wait for the command to exit before the next step
+type TerminationStep struct {
+	Signal string        `json:"signal"` // the signal name (ie, "SIGINT")
+	Wait   time.Duration `json:"wait"`   // the time to wait before the next step
+}
+
+// DefaultTermination is the sequence used for terminating commands
+// when the tool does not define its own
+var DefaultTermination = []TerminationStep{
+	{Signal: "SIGTERM", Wait: 5 * time.Second},
+	{Signal: "SIGKILL"},
+}
+
+// terminationSignals are the names of the signals that can be used for terminating commands
+var terminationSignals = []string{"SIGINT", "SIGTERM", "SIGKILL", "SIGHUP", "SIGQUIT", "SIGUSR1", "SIGUSR2"}
+
+// NormalizeSignalName returns the canonical name of a signal (ie, "int" -> "SIGINT"),
+// or an error if the signal cannot be used for terminating commands
+func NormalizeSignalName(name string) (string, error) {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(n, "SIG") {
+		n = "SIG" + n
+	}
+	for _, s := range terminationSignals {
+		if s == n {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported signal '%s' (supported: %s)", name, strings.Join(terminationSignals, ", "))
+}
+
+// waitWithTermination waits for a (started) command to exit, running the termination
+// sequence when the context is cancelled. The command is killed when it is still
+// running after the last step. The resource usage of the command is recorded in
+// the context (if requested).
+//
+// Parameters:
+//   - ctx: The context of the execution
+//   - execCmd: The command (already started)
+//   - steps: The termination sequence (the default one is used when empty)
+//   - logger: Logger for the termination steps
+//
+// Returns:
+//   - The error returned by the command, or the context error when the command was terminated
+func waitWithTermination(ctx context.Context, execCmd *exec.Cmd, steps []TerminationStep, logger *log.Logger) error {
+	if len(steps) == 0 {
+		steps = DefaultTermination
+	}
+
+	done := make(chan struct{})
+	terminated := make(chan struct{})
+	go func() {
+		defer close(terminated)
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		for _, step := range steps {
+			logger.Printf("Terminating command (pid %d) with %s", execCmd.Process.Pid, step.Signal)
+			if err := signalProcessGroup(execCmd, step.Signal); err != nil {
+				logger.Printf("Failed to send %s to the command: %v", step.Signal, err)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(step.Wait):
+			}
+		}
+
+		logger.Printf("Command (pid %d) still running after the termination sequence: killing it", execCmd.Process.Pid)
+		_ = signalProcessGroup(execCmd, "SIGKILL")
+	}()
+
+	err := execCmd.Wait()
+	close(done)
+	<-terminated
+	recordProcessUsage(ctx, execCmd.ProcessState)
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("command terminated: %w", ctxErr)
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+package command
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+)
+
+// signalsByName maps the names of the termination signals to their values
+var signalsByName = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// setProcessGroup makes a command run in its own process group,
+// so the signals reach all the processes started by the command
+func setProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.SysProcAttr == nil {
+		execCmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	execCmd.SysProcAttr.Setpgid = true
+}
+
+// trackProcessTree applies the resource limits to a (started) command. The process
+// group of the command already includes all the processes it starts.
+func trackProcessTree(execCmd *exec.Cmd, limits processLimits, logger *log.Logger) {
+	if !limits.isZero() {
+		applyProcessLimits(execCmd.Process.Pid, limits, logger)
+	}
+}
+
+// releaseProcessTree does nothing in Unix
+func releaseProcessTree(execCmd *exec.Cmd) {}
+
+// signalProcessGroup sends a signal to the process group of a command
+func signalProcessGroup(execCmd *exec.Cmd, name string) error {
+	sig, ok := signalsByName[name]
+	if !ok {
+		sig = syscall.SIGKILL
+	}
+	if err := syscall.Kill(-execCmd.Process.Pid, sig); err != nil {
+		// the command could not be the leader of its group
+		return execCmd.Process.Signal(sig)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"log"
+	"os/exec"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processTrees are the job objects with all the processes started by the running commands
+var processTrees sync.Map // *exec.Cmd -> windows.Handle
+
+// setProcessGroup makes a command run in its own process group, so it
+// can receive a Ctrl+Break without affecting MCPShell
+func setProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.SysProcAttr == nil {
+		execCmd.SysProcAttr = &windows.SysProcAttr{}
+	}
+	execCmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+}
+
+// trackProcessTree assigns a (started) command to a job object, so all the
+// processes it starts can be terminated together, and the resource limits
+// apply to all of them. The processes still running are killed when the job
+// is released. When the job cannot be created, only the command will be terminated.
+func trackProcessTree(execCmd *exec.Cmd, limits processLimits, logger *log.Logger) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		logger.Printf("Failed to create job object for the command: %v", err)
+		return
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits.memory > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+		info.JobMemoryLimit = uintptr(limits.memory)
+	}
+	if limits.cpuTime > 0 {
+		// in units of 100 nanoseconds
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_TIME
+		info.BasicLimitInformation.PerJobUserTimeLimit = int64(limits.cpuTime / 100)
+	}
+	if limits.processes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		info.BasicLimitInformation.ActiveProcessLimit = uint32(limits.processes)
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		logger.Printf("Failed to set the limits of the job object for the command: %v", err)
+	}
+
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(execCmd.Process.Pid))
+	if err != nil {
+		logger.Printf("Failed to open the process of the command: %v", err)
+		_ = windows.CloseHandle(job)
+		return
+	}
+	defer func() { _ = windows.CloseHandle(process) }()
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		logger.Printf("Failed to assign the command to a job object: %v", err)
+		_ = windows.CloseHandle(job)
+		return
+	}
+
+	processTrees.Store(execCmd, job)
+}
+
+// releaseProcessTree releases the job object of a command (once it has finished),
+// killing the processes started by the command that are still running
+func releaseProcessTree(execCmd *exec.Cmd) {
+	if job, ok := processTrees.LoadAndDelete(execCmd); ok {
+		_ = windows.CloseHandle(job.(windows.Handle))
+	}
+}
+
+// signalProcessGroup terminates a command. As Windows has no signals, SIGINT is
+// sent as a Ctrl+Break to the process group of the command, and all the other
+// signals terminate all the processes in the job of the command.
+func signalProcessGroup(execCmd *exec.Cmd, name string) error {
+	if name == "SIGINT" {
+		return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(execCmd.Process.Pid))
+	}
+	if job, ok := processTrees.Load(execCmd); ok {
+		return windows.TerminateJobObject(job.(windows.Handle), 1)
+	}
+	return execCmd.Process.Kill()
+}
@@ -1,12 +1,19 @@
 package command
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/mark3labs/mcp-go/mcp"
+
 	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
 )
 
 // TestImplicitRequirements tests the implicit requirements checking
@@ -96,3 +103,186 @@ func TestImplicitRequirements(t *testing.T) {
 		}
 	})
 }
+
+// TestBuildEnvironment tests the filtering of the environment inherited by commands
+func TestBuildEnvironment(t *testing.T) {
+	t.Setenv("MCPSHELL_TEST_ALLOWED", "allowed")
+	t.Setenv("MCPSHELL_TEST_SECRET", "secret")
+
+	tests := []struct {
+		name        string
+		passthrough []string
+		env         []string
+		want        []string
+		notWant     []string
+	}{
+		{
+			name:        "nil passthrough inherits everything",
+			passthrough: nil,
+			want:        []string{"MCPSHELL_TEST_ALLOWED=allowed", "MCPSHELL_TEST_SECRET=secret"},
+		},
+		{
+			name:        "wildcard inherits everything",
+			passthrough: []string{"*"},
+			want:        []string{"MCPSHELL_TEST_ALLOWED=allowed", "MCPSHELL_TEST_SECRET=secret"},
+		},
+		{
+			name:        "only allowed variables are inherited",
+			passthrough: []string{"MCPSHELL_TEST_ALLOWED"},
+			env:         []string{"EXTRA=value"},
+			want:        []string{"MCPSHELL_TEST_ALLOWED=allowed", "EXTRA=value"},
+			notWant:     []string{"MCPSHELL_TEST_SECRET=secret"},
+		},
+		{
+			name:        "empty passthrough inherits nothing",
+			passthrough: []string{},
+			notWant:     []string{"MCPSHELL_TEST_ALLOWED=allowed", "MCPSHELL_TEST_SECRET=secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(buildEnvironment(tt.passthrough, tt.env), "\n") + "\n"
+			for _, w := range tt.want {
+				if !strings.Contains(got, w+"\n") {
+					t.Errorf("Expected %q in the environment", w)
+				}
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(got, nw+"\n") {
+					t.Errorf("Did not expect %q in the environment", nw)
+				}
+			}
+		})
+	}
+}
+
+// remoteRunner is a runner whose commands are only terminated when killed
+type remoteRunner struct {
+	prepared bool
+	killed   chan struct{}
+}
+
+func (r *remoteRunner) Prepare(ctx context.Context) error {
+	r.prepared = true
+	return nil
+}
+
+func (r *remoteRunner) Run(ctx context.Context, shell string, command string, env []string, params map[string]interface{}, tmpfile bool) (string, error) {
+	if !strings.Contains(command, "sleep") {
+		return "remote: " + command, nil
+	}
+	<-r.killed
+	return "", fmt.Errorf("killed")
+}
+
+func (r *remoteRunner) Kill() error {
+	close(r.killed)
+	return nil
+}
+
+func (r *remoteRunner) CheckImplicitRequirements() error {
+	return nil
+}
+
+func TestRegisterRunner(t *testing.T) {
+	var last *remoteRunner
+	err := RegisterRunner("test-remote", RunnerRegistration{
+		New: func(options RunnerOptions, logger *log.Logger) (Runner, error) {
+			last = &remoteRunner{killed: make(chan struct{})}
+			return last, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+	if err := RegisterRunner("test-remote", RunnerRegistration{New: func(RunnerOptions, *log.Logger) (Runner, error) { return nil, nil }}); err == nil {
+		t.Errorf("Expected an error for a runner already registered")
+	}
+	if err := RegisterRunner(RunnerTypeExec, RunnerRegistration{}); err == nil {
+		t.Errorf("Expected an error for a runner without factory")
+	}
+	if !slices.Contains(RunnerTypes(), "test-remote") {
+		t.Errorf("Expected the runner in %v", RunnerTypes())
+	}
+
+	newTool := func(command string, runner string) config.Tool {
+		return config.Tool{
+			MCPTool: mcp.Tool{Name: "test-tool"},
+			Config: config.MCPToolConfig{
+				Run: config.MCPToolRunConfig{Command: command, Timeout: "100ms"},
+			},
+			SelectedRunner: &config.MCPToolRunner{Name: runner},
+		}
+	}
+
+	handler, err := NewCommandHandler(newTool("echo hello", "test-remote"), nil, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	output, err := handler.ExecuteCommand(map[string]interface{}{})
+	if err != nil || output != "remote: echo hello" {
+		t.Errorf("Expected the command to be run by the runner, got %q (%v)", output, err)
+	}
+	if !last.prepared {
+		t.Errorf("Expected the runner to be prepared")
+	}
+
+	// the commands are killed when they time out
+	handler, err = NewCommandHandler(newTool("sleep 10", "test-remote"), nil, "sh", testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	if _, err := handler.ExecuteCommand(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected the command to time out, got %v", err)
+	}
+
+	// the runners must be registered, and support the features used by the tools
+	if _, err := NewCommandHandler(newTool("echo hello", "unknown"), nil, "sh", testLogger); err == nil || !strings.Contains(err.Error(), "unknown runner") {
+		t.Errorf("Expected an error for an unknown runner, got %v", err)
+	}
+	tool := newTool("", "test-remote")
+	tool.Config.Exec = []string{"echo", "hello"}
+	if _, err := NewCommandHandler(tool, nil, "sh", testLogger); err == nil {
+		t.Errorf("Expected an error for exec arguments with a runner without the capability")
+	}
+}
+
+func TestValidateRunnerOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  RunnerType
+		options RunnerOptions
+		wantErr string
+	}{
+		{"no options", RunnerTypeDocker, nil, ""},
+		{"valid options", RunnerTypeDocker, RunnerOptions{"image": "alpine", "allow_networking": false, "cap_drop": []interface{}{"ALL"}}, ""},
+		{"unknown option", RunnerTypeDocker, RunnerOptions{"image": "alpine", "imgae": "alpine"}, `unknown field "imgae"`},
+		{"invalid type", RunnerTypeFirejail, RunnerOptions{"allow_networking": "yes"}, "allow_networking"},
+		{"option of another runner", RunnerTypeExec, RunnerOptions{"image": "alpine"}, `unknown field "image"`},
+		{"exec limits", RunnerTypeExec, RunnerOptions{"max_memory": "512M", "max_processes": 10}, ""},
+		{"unknown runner", "unknown", RunnerOptions{"image": "alpine"}, "unknown runner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRunnerOptions(tt.runner, tt.options)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected an error with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// the runners without a schema accept any option
+	if err := RegisterRunner("test-schemaless", RunnerRegistration{
+		New: func(RunnerOptions, *log.Logger) (Runner, error) { return nil, nil },
+	}); err != nil {
+		t.Fatalf("Failed to register runner: %v", err)
+	}
+	if err := ValidateRunnerOptions("test-schemaless", RunnerOptions{"anything": 1}); err != nil {
+		t.Errorf("Unexpected error for a runner without a schema: %v", err)
+	}
+}
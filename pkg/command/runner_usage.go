@@ -0,0 +1,66 @@
+package command
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// ResourceUsage is the resources used by an execution of a tool
+type ResourceUsage struct {
+	WallTime   time.Duration // the time the command took
+	CPUTime    time.Duration // the user and system time of the command (and the processes it waited for)
+	MaxRSS     int64         // the maximum resident set size of the command, in bytes (0 when not available)
+	OutputSize int           // the size of the output of the command, in bytes
+}
+
+// Meta returns the resource usage as the metadata of a tool result
+func (u ResourceUsage) Meta() map[string]interface{} {
+	meta := map[string]interface{}{
+		"wall_time_seconds": u.WallTime.Seconds(),
+		"cpu_time_seconds":  u.CPUTime.Seconds(),
+		"output_bytes":      u.OutputSize,
+	}
+	if u.MaxRSS > 0 {
+		meta["max_rss_bytes"] = u.MaxRSS
+	}
+	return meta
+}
+
+// usageRecorder accumulates the resource usage of the processes run in an execution
+type usageRecorder struct {
+	mu    sync.Mutex
+	usage ResourceUsage
+}
+
+// usageKey is the key of the usage recorder in the context
+type usageKey struct{}
+
+// withUsageRecorder returns a context where the runners record the resource usage
+// of the processes they run
+func withUsageRecorder(ctx context.Context) (context.Context, *usageRecorder) {
+	recorder := &usageRecorder{}
+	return context.WithValue(ctx, usageKey{}, recorder), recorder
+}
+
+// recordProcessUsage adds the resource usage of a process (that has exited) to the
+// recorder in the context, if any
+func recordProcessUsage(ctx context.Context, state *os.ProcessState) {
+	recorder, _ := ctx.Value(usageKey{}).(*usageRecorder)
+	if recorder == nil || state == nil {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.usage.CPUTime += state.UserTime() + state.SystemTime()
+	recorder.usage.MaxRSS = max(recorder.usage.MaxRSS, maxRSS(state))
+}
+
+// get returns the resource usage recorded
+func (r *usageRecorder) get() ResourceUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
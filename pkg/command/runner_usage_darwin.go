@@ -0,0 +1,14 @@
+package command
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size of a process that has exited, in bytes
+func maxRSS(state *os.ProcessState) int64 {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return rusage.Maxrss // already in bytes in macOS
+	}
+	return 0
+}
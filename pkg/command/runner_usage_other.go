@@ -0,0 +1,10 @@
+//go:build !unix
+
+package command
+
+import "os"
+
+// maxRSS is not available in this platform
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix && !darwin
+
+package command
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size of a process that has exited, in bytes
+func maxRSS(state *os.ProcessState) int64 {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return int64(rusage.Maxrss) * 1024 // in kilobytes
+	}
+	return 0
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ComputedConfig defines a value derived from the parameters of a tool,
+// available in templates like any other parameter.
+type ComputedConfig struct {
+	// Name is the name of the value in templates
+	Name string `yaml:"name"`
+
+	// Template is a Go template producing the value (ie, "{{ .registry }}/{{ .name }}")
+	Template string `yaml:"template,omitempty"`
+
+	// Expr is a CEL expression producing the value (ie, 'registry + "/" + name')
+	Expr string `yaml:"expr,omitempty"`
+}
+
+// computedNameRegex matches the valid names for computed values
+var computedNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CompiledComputed holds the computed values of a tool, with the
+// CEL expressions already compiled
+type CompiledComputed struct {
+	computed []ComputedConfig
+	programs []cel.Program // the program for every computed value (nil for templates)
+	params   map[string]ParamConfig
+}
+
+// NewCompiledComputed compiles the computed values of a tool.
+//
+// Computed values are evaluated in order, so every value can use the
+// parameters and the values computed before it.
+//
+// Parameters:
+//   - computed: The computed values definitions
+//   - params: The parameters of the tool
+//
+// Returns:
+//   - The compiled computed values
+//   - An error if some definition is invalid or some expression cannot be compiled
+func NewCompiledComputed(computed []ComputedConfig, params map[string]ParamConfig) (*CompiledComputed, error) {
+	envOpts, err := celParamVariables(params)
+	if err != nil {
+		return nil, err
+	}
+
+	programs := make([]cel.Program, len(computed))
+	for i, c := range computed {
+		if !computedNameRegex.MatchString(c.Name) {
+			return nil, fmt.Errorf("invalid name for computed value: '%s'", c.Name)
+		}
+		if _, exists := params[c.Name]; exists {
+			return nil, fmt.Errorf("computed value '%s' has the same name as a parameter", c.Name)
+		}
+		for _, prev := range computed[:i] {
+			if prev.Name == c.Name {
+				return nil, fmt.Errorf("duplicate computed value '%s'", c.Name)
+			}
+		}
+		if (c.Template == "") == (c.Expr == "") {
+			return nil, fmt.Errorf("computed value '%s' must have either a template or an expression", c.Name)
+		}
+
+		if c.Expr != "" {
+			env, err := cel.NewEnv(envOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+			}
+			ast, issues := env.Compile(c.Expr)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("failed to compile expression for computed value '%s': %w", c.Name, issues.Err())
+			}
+			prg, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create program for computed value '%s': %w", c.Name, err)
+			}
+			programs[i] = prg
+		}
+
+		// the following values can use this one
+		envOpts = append(envOpts, cel.Variable(c.Name, cel.DynType))
+	}
+
+	return &CompiledComputed{
+		computed: computed,
+		programs: programs,
+		params:   params,
+	}, nil
+}
+
+// Templates returns the templates of the computed values
+func (cc *CompiledComputed) Templates() []string {
+	if cc == nil {
+		return nil
+	}
+	var res []string
+	for _, c := range cc.computed {
+		if c.Template != "" {
+			res = append(res, c.Template)
+		}
+	}
+	return res
+}
+
+// Evaluate evaluates all the computed values, adding them to the arguments.
+//
+// Parameters:
+//   - args: Map of argument names to their values (modified in place)
+//
+// Returns:
+//   - An error if some template or expression fails
+func (cc *CompiledComputed) Evaluate(args map[string]interface{}) error {
+	if cc == nil {
+		return nil
+	}
+
+	// the CEL variables for the parameters must always have a value of the right type
+	vars := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		vars[k] = v
+	}
+	for name, param := range cc.params {
+		switch ParamValueType(param.Type) {
+		case "string":
+			if _, exists := vars[name]; !exists {
+				vars[name] = ""
+			}
+		case "number", "integer":
+			if n, ok := schemaNumber(vars[name]); ok {
+				vars[name] = n
+			} else if _, exists := vars[name]; !exists {
+				vars[name] = 0.0
+			}
+		case "boolean":
+			if _, exists := vars[name]; !exists {
+				vars[name] = false
+			}
+		}
+	}
+
+	for i, c := range cc.computed {
+		var value interface{}
+		if cc.programs[i] != nil {
+			out, _, err := cc.programs[i].Eval(vars)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate computed value '%s': %w", c.Name, err)
+			}
+			value = out.Value()
+		} else {
+			res, err := ProcessTemplate(c.Template, args)
+			if err != nil {
+				return fmt.Errorf("failed to process template for computed value '%s': %w", c.Name, err)
+			}
+			value = res
+		}
+
+		args[c.Name] = value
+		vars[c.Name] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestNewCompiledComputed(t *testing.T) {
+	params := map[string]ParamConfig{
+		"name": {Type: "string"},
+		"tag":  {Type: "string"},
+	}
+
+	tests := []struct {
+		name        string
+		computed    []ComputedConfig
+		expectError bool
+	}{
+		{"template", []ComputedConfig{{Name: "image", Template: "{{ .name }}:{{ .tag }}"}}, false},
+		{"expression", []ComputedConfig{{Name: "image", Expr: `name + ":" + tag`}}, false},
+		{"uses previous value", []ComputedConfig{{Name: "image", Expr: `name + ":" + tag`}, {Name: "ref", Expr: `"docker.io/" + image`}}, false},
+		{"uses later value", []ComputedConfig{{Name: "ref", Expr: `"docker.io/" + image`}, {Name: "image", Expr: `name`}}, true},
+		{"invalid expression", []ComputedConfig{{Name: "image", Expr: `name +`}}, true},
+		{"unknown variable", []ComputedConfig{{Name: "image", Expr: `registry + name`}}, true},
+		{"both template and expression", []ComputedConfig{{Name: "image", Template: "{{ .name }}", Expr: "name"}}, true},
+		{"no template or expression", []ComputedConfig{{Name: "image"}}, true},
+		{"same name as parameter", []ComputedConfig{{Name: "name", Expr: "tag"}}, true},
+		{"invalid name", []ComputedConfig{{Name: "my-image", Expr: "name"}}, true},
+		{"duplicate name", []ComputedConfig{{Name: "image", Expr: "name"}, {Name: "image", Expr: "tag"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCompiledComputed(tt.computed, params)
+			if (err != nil) != tt.expectError {
+				t.Errorf("NewCompiledComputed() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestCompiledComputedEvaluate(t *testing.T) {
+	params := map[string]ParamConfig{
+		"registry": {Type: "string"},
+		"name":     {Type: "string"},
+		"tag":      {Type: "string"},
+		"replicas": {Type: "integer"},
+	}
+
+	computed, err := NewCompiledComputed([]ComputedConfig{
+		{Name: "image", Expr: `registry + "/" + name + ":" + (tag == "" ? "latest" : tag)`},
+		{Name: "scaled", Expr: `replicas > 1.0`},
+		{Name: "description", Template: "{{ .image }} ({{ .replicas }} replicas)"},
+	}, params)
+	if err != nil {
+		t.Fatalf("NewCompiledComputed() error = %v", err)
+	}
+
+	args := map[string]interface{}{
+		"registry": "ghcr.io",
+		"name":     "app",
+		"replicas": int64(3),
+	}
+	if err := computed.Evaluate(args); err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"image":       "ghcr.io/app:latest",
+		"scaled":      true,
+		"description": "ghcr.io/app:latest (3 replicas)",
+	}
+	for name, value := range expected {
+		if args[name] != value {
+			t.Errorf("Computed value '%s' = %v, expected %v", name, args[name], value)
+		}
+	}
+
+	// the arguments must not be modified, except for the computed values
+	if _, exists := args["tag"]; exists {
+		t.Errorf("Missing parameter 'tag' was added to the arguments")
+	}
+	if args["replicas"] != int64(3) {
+		t.Errorf("Parameter 'replicas' was modified: %v", args["replicas"])
+	}
+
+	// a nil set of computed values does nothing
+	var none *CompiledComputed
+	if err := none.Evaluate(args); err != nil {
+		t.Errorf("Evaluate() on nil error = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// EvaluateCondition evaluates a CEL expression that decides if some feature
+// (ie, a tool) is enabled. Empty expressions are always true.
+//
+// The expression can use the following variables:
+//   - env: a map with the environment variables (ie, env.HOME, "CI" in env)
+//   - os: the operating system (ie, "linux", "darwin", "windows")
+//   - arch: the architecture (ie, "amd64", "arm64")
+//   - hostname: the host name
+//
+// Parameters:
+//   - expr: The CEL expression, or a literal "true" or "false"
+//
+// Returns:
+//   - The result of the evaluation
+//   - An error if the expression is invalid or does not return a boolean
+func EvaluateCondition(expr string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("env", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("os", cel.StringType),
+		cel.Variable("arch", cel.StringType),
+		cel.Variable("hostname", cel.StringType),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return false, fmt.Errorf("failed to compile condition '%s': %w", expr, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return false, fmt.Errorf("condition '%s' must return a boolean", expr)
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return false, fmt.Errorf("failed to create program for condition '%s': %w", expr, err)
+	}
+
+	vars := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, found := strings.Cut(kv, "="); found {
+			vars[k] = v
+		}
+	}
+	hostname, _ := os.Hostname()
+
+	out, _, err := prg.Eval(map[string]interface{}{
+		"env":      vars,
+		"os":       runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"hostname": hostname,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition '%s': %w", expr, err)
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition '%s' did not return a boolean", expr)
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	t.Setenv("TEST_CONDITION_VAR", "enabled")
+
+	tests := []struct {
+		name        string
+		expr        string
+		expected    bool
+		expectError bool
+	}{
+		{"empty", "", true, false},
+		{"literal true", "true", true, false},
+		{"literal false", "false", false, false},
+		{"os", `os == "` + runtime.GOOS + `"`, true, false},
+		{"other os", `os == "plan9" && arch == "mips"`, false, false},
+		{"env value", `env.TEST_CONDITION_VAR == "enabled"`, true, false},
+		{"env exists", `"TEST_CONDITION_VAR" in env`, true, false},
+		{"env missing", `"TEST_CONDITION_VAR_MISSING" in env`, false, false},
+		{"hostname", `hostname != ""`, true, false},
+		{"not a boolean", `os`, false, true},
+		{"invalid expression", `os ==`, false, true},
+		{"unknown variable", `unknown == "x"`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EvaluateCondition(tt.expr)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("EvaluateCondition() error = %v, expectError %v", err, tt.expectError)
+			}
+			if result != tt.expected {
+				t.Errorf("EvaluateCondition() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
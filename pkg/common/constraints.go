@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/google/cel-go/cel"
 )
@@ -10,10 +11,22 @@ import (
 // CompiledConstraints holds the compiled CEL programs for a tool's constraints
 type CompiledConstraints struct {
 	programs    []cel.Program
-	expressions []string // Original constraint expressions
+	expressions []string   // Original constraint expressions
+	variables   [][]string // The variables referenced by every constraint
+	request     bool       // the constraints can use the context of the call (as "request")
+	host        bool       // the constraints can use the operating system of the host (as "os")
 	logger      *log.Logger
 }
 
+// ConstraintResult is the result of evaluating a constraint, with the values of
+// the variables it references
+type ConstraintResult struct {
+	Expression string
+	Passed     bool
+	Variables  map[string]interface{}
+	Error      error // the constraint could not be evaluated (or it is not a boolean)
+}
+
 // NewCompiledConstraints compiles a list of CEL constraint expressions
 // paramTypes is a map of parameter names to their types
 // logger is required for logging constraint compilation and evaluation information
@@ -28,26 +41,24 @@ func NewCompiledConstraints(constraints []string, paramTypes map[string]ParamCon
 	}
 
 	// Create a new CEL environment with the parameter declarations
-	var envOpts []cel.EnvOption
+	envOpts, err := celParamVariables(paramTypes)
+	if err != nil {
+		return nil, err
+	}
 
-	// Add parameter declarations based on their types
-	for name, param := range paramTypes {
-		paramType := param.Type
-		if paramType == "" {
-			paramType = "string"
-		}
+	// The context of the call is available, unless there is a parameter with the same name
+	_, shadowed := paramTypes[RequestVariable]
+	if !shadowed {
+		envOpts = append(envOpts, cel.Variable(RequestVariable, cel.MapType(cel.StringType, cel.DynType)))
+	}
 
-		switch paramType {
-		case "string":
-			envOpts = append(envOpts, cel.Variable(name, cel.StringType))
-		case "number", "integer":
-			envOpts = append(envOpts, cel.Variable(name, cel.DoubleType))
-		case "boolean":
-			envOpts = append(envOpts, cel.Variable(name, cel.BoolType))
-		default:
-			return nil, fmt.Errorf("unsupported parameter type for CEL: %s", paramType)
-		}
+	// The same for the operating system of the host, while the functions for
+	// inspecting the host (env(), disk_free()) are always available
+	_, hostShadowed := paramTypes[HostVariable]
+	if !hostShadowed {
+		envOpts = append(envOpts, cel.Variable(HostVariable, cel.MapType(cel.StringType, cel.StringType)))
 	}
+	envOpts = append(envOpts, celHostFunctions()...)
 
 	env, err := cel.NewEnv(envOpts...)
 	if err != nil {
@@ -57,6 +68,7 @@ func NewCompiledConstraints(constraints []string, paramTypes map[string]ParamCon
 	// Compile each constraint expression
 	var programs []cel.Program
 	var expressions []string
+	var variables [][]string
 	for _, expr := range constraints {
 		ast, issues := env.Compile(expr)
 		if issues != nil && issues.Err() != nil {
@@ -71,11 +83,15 @@ func NewCompiledConstraints(constraints []string, paramTypes map[string]ParamCon
 
 		programs = append(programs, prg)
 		expressions = append(expressions, expr)
+		variables = append(variables, referencedVariables(ast))
 	}
 
 	return &CompiledConstraints{
 		programs:    programs,
 		expressions: expressions,
+		variables:   variables,
+		request:     !shadowed,
+		host:        !hostShadowed,
 		logger:      logger,
 	}, nil
 }
@@ -92,6 +108,13 @@ func NewCompiledConstraints(constraints []string, paramTypes map[string]ParamCon
 //   - slice of strings containing the failed constraint expressions
 //   - error if evaluation fails or if a required parameter is missing
 func (cc *CompiledConstraints) Evaluate(args map[string]interface{}, params map[string]ParamConfig) (bool, []string, error) {
+	return cc.EvaluateRequest(args, params, nil)
+}
+
+// EvaluateRequest evaluates all compiled constraints like Evaluate, with the context
+// of the tool call in the "request" variable (when it is nil, the call has no client,
+// no session and the current time)
+func (cc *CompiledConstraints) EvaluateRequest(args map[string]interface{}, params map[string]ParamConfig, request *RequestInfo) (bool, []string, error) {
 	if cc == nil {
 		return true, nil, nil
 	}
@@ -104,30 +127,7 @@ func (cc *CompiledConstraints) Evaluate(args map[string]interface{}, params map[
 
 	cc.logger.Printf("Evaluating %d constraints with details", len(cc.programs))
 
-	// Create a copy of args to avoid modifying the original
-	evalArgs := make(map[string]interface{})
-	for k, v := range args {
-		evalArgs[k] = v
-		cc.logger.Printf("Argument provided: %s = %v", k, v)
-	}
-
-	// Ensure all parameters have at least empty values if not provided
-	for name, param := range params {
-		if _, exists := evalArgs[name]; !exists {
-			// Parameter not provided, add default empty value based on type
-			switch param.Type {
-			case "string", "":
-				evalArgs[name] = ""
-				cc.logger.Printf("Adding default empty string for missing parameter: %s", name)
-			case "number", "integer":
-				evalArgs[name] = 0.0
-				cc.logger.Printf("Adding default zero value for missing parameter: %s", name)
-			case "boolean":
-				evalArgs[name] = false
-				cc.logger.Printf("Adding default false value for missing parameter: %s", name)
-			}
-		}
-	}
+	evalArgs := cc.evaluationArgs(args, params, request)
 
 	var failedConstraints []string
 
@@ -150,7 +150,7 @@ func (cc *CompiledConstraints) Evaluate(args map[string]interface{}, params map[
 
 		if !boolVal {
 			// If any constraint fails, add it to the failed constraints list
-			failureMsg := fmt.Sprintf("%s (with values: %s)", cc.expressions[i], formatArgValues(evalArgs))
+			failureMsg := fmt.Sprintf("%s (with values: %s)", cc.expressions[i], formatArgValues(evalArgs, params))
 			failedConstraints = append(failedConstraints, failureMsg)
 			cc.logger.Printf("Constraint #%d failed evaluation: %s", i+1, failureMsg)
 		} else {
@@ -169,10 +169,118 @@ func (cc *CompiledConstraints) Evaluate(args map[string]interface{}, params map[
 	return true, nil, nil
 }
 
-// formatArgValues returns a formatted string of the argument values for error reporting
-func formatArgValues(args map[string]interface{}) string {
+// Explain evaluates all the constraints against the provided arguments (without stopping
+// at the first failure), returning the result of every constraint with the values of the
+// variables it references. It is used for debugging why calls are rejected.
+func (cc *CompiledConstraints) Explain(args map[string]interface{}, params map[string]ParamConfig, request *RequestInfo) []ConstraintResult {
+	if cc == nil {
+		return nil
+	}
+
+	evalArgs := cc.evaluationArgs(args, params, request)
+	results := make([]ConstraintResult, 0, len(cc.programs))
+	for i, prg := range cc.programs {
+		result := ConstraintResult{Expression: cc.expressions[i], Variables: map[string]interface{}{}}
+		for _, name := range cc.variables[i] {
+			result.Variables[name] = evalArgs[name]
+		}
+
+		val, _, err := prg.Eval(evalArgs)
+		switch {
+		case err != nil:
+			result.Error = err
+		default:
+			boolVal, ok := val.Value().(bool)
+			if !ok {
+				result.Error = fmt.Errorf("the constraint did not evaluate to a boolean (got %v)", val.Value())
+			}
+			result.Passed = boolVal
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// evaluationArgs returns the arguments for evaluating the constraints: a copy of the
+// arguments provided, with empty values for the parameters not provided, and the
+// context of the call
+func (cc *CompiledConstraints) evaluationArgs(args map[string]interface{}, params map[string]ParamConfig, request *RequestInfo) map[string]interface{} {
+	// Create a copy of args to avoid modifying the original
+	evalArgs := make(map[string]interface{})
+	for k, v := range args {
+		evalArgs[k] = v
+		cc.logger.Printf("Argument provided: %s = %v", k, v)
+	}
+
+	// Ensure all parameters have at least empty values if not provided
+	for name, param := range params {
+		if _, exists := evalArgs[name]; !exists {
+			// Parameter not provided, add default empty value based on type
+			switch ParamValueType(param.Type) {
+			case "string":
+				evalArgs[name] = ""
+				cc.logger.Printf("Adding default empty string for missing parameter: %s", name)
+			case "number", "integer":
+				evalArgs[name] = 0.0
+				cc.logger.Printf("Adding default zero value for missing parameter: %s", name)
+			case "boolean":
+				evalArgs[name] = false
+				cc.logger.Printf("Adding default false value for missing parameter: %s", name)
+			}
+		}
+	}
+
+	if cc.request {
+		evalArgs[RequestVariable] = request.celValue()
+	}
+	if cc.host {
+		evalArgs[HostVariable] = hostValue()
+	}
+	return evalArgs
+}
+
+// referencedVariables returns the names of the variables referenced by a constraint, sorted
+func referencedVariables(ast *cel.Ast) []string {
+	var names []string
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name != "" && len(ref.OverloadIDs) == 0 && !slices.Contains(names, ref.Name) {
+			names = append(names, ref.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// celParamVariables returns the declarations of the CEL variables
+// for some parameters, based on their types
+func celParamVariables(params map[string]ParamConfig) ([]cel.EnvOption, error) {
+	var envOpts []cel.EnvOption
+	for name, param := range params {
+		paramType := ParamValueType(param.Type)
+
+		switch paramType {
+		case "string":
+			envOpts = append(envOpts, cel.Variable(name, cel.StringType))
+		case "number", "integer":
+			envOpts = append(envOpts, cel.Variable(name, cel.DoubleType))
+		case "boolean":
+			envOpts = append(envOpts, cel.Variable(name, cel.BoolType))
+		default:
+			return nil, fmt.Errorf("unsupported parameter type for CEL: %s", paramType)
+		}
+	}
+	return envOpts, nil
+}
+
+// formatArgValues returns a formatted string of the argument values for error reporting.
+// The context of the call and the details of the host are not included, as the errors
+// are returned to the clients.
+func formatArgValues(args map[string]interface{}, params map[string]ParamConfig) string {
 	result := ""
 	for k, v := range args {
+		if _, isParam := params[k]; !isParam && (k == RequestVariable || k == HostVariable) {
+			continue
+		}
 		if result != "" {
 			result += ", "
 		}
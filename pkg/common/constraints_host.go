@@ -0,0 +1,53 @@
+package common
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// HostVariable is the variable with the operating system of the host in the constraints
+const HostVariable = "os"
+
+// celHostFunctions returns the declarations of the functions for inspecting the host
+// in the constraints:
+//   - env(name): the value of an environment variable (empty when not set)
+//   - disk_free(path): the free space (in bytes) of the filesystem of a path
+func celHostFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("env",
+			cel.Overload("env_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(name ref.Val) ref.Val {
+					return types.String(os.Getenv(string(name.(types.String))))
+				}),
+			),
+		),
+		cel.Function("disk_free",
+			cel.Overload("disk_free_string", []*cel.Type{cel.StringType}, cel.IntType,
+				cel.UnaryBinding(func(path ref.Val) ref.Val {
+					free, err := diskFree(string(path.(types.String)))
+					if err != nil {
+						return types.NewErr("disk_free('%s'): %v", path, err)
+					}
+					return types.Int(int64(min(free, uint64(1<<63-1))))
+				}),
+			),
+		),
+	}
+}
+
+// hostValue returns the value of the "os" variable in the constraints, with the fields:
+//   - name: the operating system (ie, "linux", "darwin", "windows")
+//   - arch: the architecture (ie, "amd64", "arm64")
+//   - hostname: the host name
+func hostValue() map[string]interface{} {
+	hostname, _ := os.Hostname()
+	return map[string]interface{}{
+		"name":     runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"hostname": hostname,
+	}
+}
@@ -3,7 +3,11 @@ package common
 import (
 	"io"
 	"log"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Create a test logger that discards output to keep test output clean
@@ -428,3 +432,155 @@ func TestConstraints(t *testing.T) {
 		}
 	})
 }
+
+func TestConstraints_Explain(t *testing.T) {
+	params := map[string]ParamConfig{
+		"name":  {Type: "string"},
+		"count": {Type: "number"},
+		"force": {Type: "boolean"},
+	}
+	cc, err := NewCompiledConstraints([]string{
+		"name.size() <= 5",
+		"count > 0.0 && count < 10.0",
+		"force || name.startsWith('test')",
+		"['a', 'b'][int(count)] == 'a'",
+	}, params, testLogger)
+	if err != nil {
+		t.Fatalf("NewCompiledConstraints() error = %v", err)
+	}
+
+	results := cc.Explain(map[string]interface{}{"name": "testing", "count": 3.0}, params, nil)
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %+v", results)
+	}
+	if results[0].Passed || results[0].Error != nil || len(results[0].Variables) != 1 || results[0].Variables["name"] != "testing" {
+		t.Errorf("Unexpected result of the first constraint: %+v", results[0])
+	}
+	if !results[1].Passed || len(results[1].Variables) != 1 || results[1].Variables["count"] != 3.0 {
+		t.Errorf("Unexpected result of the second constraint: %+v", results[1])
+	}
+	// the parameters not given are evaluated with empty values
+	if !results[2].Passed || results[2].Variables["force"] != false || results[2].Variables["name"] != "testing" {
+		t.Errorf("Unexpected result of the third constraint: %+v", results[2])
+	}
+	// the evaluation errors do not stop the evaluation of the other constraints
+	if results[3].Passed || results[3].Error == nil {
+		t.Errorf("Expected an error in the fourth constraint, got %+v", results[3])
+	}
+
+	var nilConstraints *CompiledConstraints
+	if results := nilConstraints.Explain(nil, params, nil); results != nil {
+		t.Errorf("Expected no results without constraints, got %+v", results)
+	}
+}
+
+func TestConstraints_Request(t *testing.T) {
+	params := map[string]ParamConfig{
+		"action": {Type: "string"},
+	}
+	cc, err := NewCompiledConstraints([]string{
+		"action != 'delete' || request.client == 'admin'",
+		"action != 'delete' || (request.time.getHours('UTC') >= 9 && request.time.getHours('UTC') < 18)",
+		"request.session_calls <= 3",
+		"request.session_age < duration('1h')",
+		"action != 'deploy' || 'ops' in request.groups",
+	}, params, testLogger)
+	if err != nil {
+		t.Fatalf("NewCompiledConstraints() error = %v", err)
+	}
+
+	morning := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	night := time.Date(2025, 6, 2, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		action  string
+		request *RequestInfo
+		want    bool
+	}{
+		{"admin in business hours", "delete", &RequestInfo{Client: "admin", Time: morning, SessionCalls: 1}, true},
+		{"admin at night", "delete", &RequestInfo{Client: "admin", Time: night, SessionCalls: 1}, false},
+		{"other client", "delete", &RequestInfo{Client: "bob", Time: morning, SessionCalls: 1}, false},
+		{"too many calls", "list", &RequestInfo{Client: "bob", Time: night, SessionCalls: 4}, false},
+		{"old session", "list", &RequestInfo{Time: night, SessionStart: night.Add(-2 * time.Hour), SessionCalls: 1}, false},
+		{"in group", "deploy", &RequestInfo{Groups: []string{"dev", "ops"}, Time: night}, true},
+		{"not in group", "deploy", &RequestInfo{Groups: []string{"dev"}, Time: night}, false},
+		{"no context", "list", nil, true},
+		{"no context for delete", "delete", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := cc.EvaluateRequest(map[string]interface{}{"action": tt.action}, params, tt.request)
+			if err != nil {
+				t.Fatalf("EvaluateRequest() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// a parameter named "request" shadows the context of the call
+	shadowParams := map[string]ParamConfig{"request": {Type: "string"}}
+	shadowed, err := NewCompiledConstraints([]string{"request == 'hello'"}, shadowParams, testLogger)
+	if err != nil {
+		t.Fatalf("NewCompiledConstraints() error = %v", err)
+	}
+	if got, _, err := shadowed.EvaluateRequest(map[string]interface{}{"request": "hello"}, shadowParams, &RequestInfo{Client: "admin"}); err != nil || !got {
+		t.Errorf("EvaluateRequest() = %v, %v, want true", got, err)
+	}
+}
+
+func TestConstraints_Host(t *testing.T) {
+	t.Setenv("MCPSHELL_TEST_STAGE", "production")
+
+	params := map[string]ParamConfig{
+		"path": {Type: "string"},
+	}
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name       string
+		constraint string
+		want       bool
+		wantErr    bool
+	}{
+		{"os name", "os.name == '" + runtime.GOOS + "'", true, false},
+		{"os arch", "os.arch == '" + runtime.GOARCH + "'", true, false},
+		{"hostname", "os.hostname == '" + hostname + "'", true, false},
+		{"hostname guard", "!os.hostname.startsWith('prod-') || !path.startsWith('/')", true, false},
+		{"env", "env('MCPSHELL_TEST_STAGE') == 'production'", true, false},
+		{"env guard", "env('MCPSHELL_TEST_STAGE') != 'production' || path.startsWith('/scratch/')", false, false},
+		{"env not set", "env('MCPSHELL_TEST_NOT_SET') == ''", true, false},
+		{"disk free", "disk_free(path) > 0", true, false},
+		{"disk free of a missing path", "disk_free(path + '/missing/dir') > 0", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc, err := NewCompiledConstraints([]string{tt.constraint}, params, testLogger)
+			if err != nil {
+				t.Fatalf("NewCompiledConstraints() error = %v", err)
+			}
+			got, _, err := cc.Evaluate(map[string]interface{}{"path": t.TempDir()}, params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the details of the host are not included in the errors returned to the clients
+	cc, err := NewCompiledConstraints([]string{"os.hostname == 'none' && path != ''"}, params, testLogger)
+	if err != nil {
+		t.Fatalf("NewCompiledConstraints() error = %v", err)
+	}
+	_, failed, err := cc.Evaluate(map[string]interface{}{"path": "/tmp"}, params)
+	if err != nil || len(failed) != 1 {
+		t.Fatalf("Evaluate() = %v, %v, want one failed constraint", failed, err)
+	}
+	if strings.Contains(failed[0], "hostname=") || !strings.Contains(failed[0], "path=/tmp") {
+		t.Errorf("Unexpected failure message: %s", failed[0])
+	}
+}
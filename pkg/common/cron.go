@@ -0,0 +1,189 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression, with the standard five fields
+// (minute, hour, day of month, month and day of week), or an interval.
+type CronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	anyDay     bool // the day of month is "*"
+	anyWeekday bool // the day of week is "*"
+
+	every time.Duration // the interval, for "@every <duration>"
+}
+
+// cronShortcuts are the predefined schedules
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression.
+//
+// Supported expressions are the standard five fields (with "*", lists, ranges
+// and steps, like "*/15 9-17 * * 1-5"), the shortcuts like "@daily" or "@hourly",
+// and intervals like "@every 5m".
+//
+// Parameters:
+//   - expr: The cron expression
+//
+// Returns:
+//   - The parsed schedule
+//   - An error if the expression is not valid
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if interval, found := strings.CutPrefix(expr, "@every "); found {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("invalid interval in '%s' (it must be at least 1s)", expr)
+		}
+		return &CronSchedule{every: every}, nil
+	}
+	if shortcut, exists := cronShortcuts[expr]; exists {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 fields", expr)
+	}
+
+	var err error
+	s := &CronSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minutes in '%s': %w", expr, err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hours in '%s': %w", expr, err)
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month in '%s': %w", expr, err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month in '%s': %w", expr, err)
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week in '%s': %w", expr, err)
+	}
+	// both 0 and 7 are Sunday
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+
+	return s, nil
+}
+
+// parseCronField parses a field of a cron expression, returning the values it matches
+func parseCronField(field string, minValue int, maxValue int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", stepStr)
+			}
+		}
+
+		var from, to int
+		switch {
+		case rangePart == "*":
+			from, to = minValue, maxValue
+		case strings.Contains(rangePart, "-"):
+			fromStr, toStr, _ := strings.Cut(rangePart, "-")
+			var err1, err2 error
+			from, err1 = strconv.Atoi(fromStr)
+			to, err2 = strconv.Atoi(toStr)
+			if err1 != nil || err2 != nil || from > to {
+				return nil, fmt.Errorf("invalid range '%s'", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", rangePart)
+			}
+			from, to = value, value
+			if hasStep {
+				to = maxValue
+			}
+		}
+
+		if from < minValue || to > maxValue {
+			return nil, fmt.Errorf("'%s' is out of range (%d-%d)", part, minValue, maxValue)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Next returns the next time (after t) matching the schedule
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	// start at the next minute, and check minute by minute (skipping
+	// whole days and hours when possible) for at most five years
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		if !s.months[int(next.Month())] || !s.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, next.Location()).AddDate(0, 0, 1)
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), 0, 0, 0, next.Location()).Add(time.Hour)
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// matchesDay returns true if the day of a time matches the schedule. As in
+// standard cron, when both the day of month and the day of week are restricted,
+// the day matches if any of them matches.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 3, 15, 10, 7, 30, 0, time.UTC) // a Friday
+
+	tests := []struct {
+		name        string
+		expr        string
+		expected    time.Time
+		expectError bool
+	}{
+		{
+			name:     "every minute",
+			expr:     "* * * * *",
+			expected: time.Date(2024, 3, 15, 10, 8, 0, 0, time.UTC),
+		},
+		{
+			name:     "steps",
+			expr:     "*/15 * * * *",
+			expected: time.Date(2024, 3, 15, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "lists and ranges",
+			expr:     "0,30 9-17 * * *",
+			expected: time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "next day",
+			expr:     "0 9 * * *",
+			expected: time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays",
+			expr:     "0 9 * * 1-5",
+			expected: time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			expr:     "0 0 * * 7",
+			expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			expr:     "0 0 1 * 6",
+			expected: time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "shortcut",
+			expr:     "@monthly",
+			expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "interval",
+			expr:     "@every 90s",
+			expected: base.Add(90 * time.Second),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 2 *",
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "too few fields", expr: "* * * *", expectError: true},
+		{name: "out of range", expr: "60 * * * *", expectError: true},
+		{name: "invalid range", expr: "* 5-2 * * *", expectError: true},
+		{name: "invalid step", expr: "*/0 * * * *", expectError: true},
+		{name: "invalid interval", expr: "@every 10ms", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error for '%s'", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next := schedule.Next(base); !next.Equal(tt.expected) {
+				t.Errorf("Next(%s) = %s, expected %s", base, next, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxDiffCells is the maximum size (lines of one text by lines of the other one) of the
+// changed parts of two texts compared line by line. Bigger changes are shown as the
+// removal of all the old lines and the addition of all the new ones.
+var MaxDiffCells = 4 * 1024 * 1024
+
+// diffLine is a line in the differences between two texts
+type diffLine struct {
+	op   byte // ' ' for lines in both texts, '-' for removed lines and '+' for added lines
+	text string
+}
+
+// UnifiedDiff returns the differences between two texts in the unified format, with some
+// lines of context around the changes, or an empty string when the texts are equal.
+//
+// Parameters:
+//   - from: The old text
+//   - to: The new text
+//   - fromName: The name of the old text (in the "---" header)
+//   - toName: The name of the new text (in the "+++" header)
+//   - context: The number of unchanged lines shown around the changes
+//
+// Returns:
+//   - The differences, with a header and the hunks of changed lines
+func UnifiedDiff(from, to string, fromName, toName string, context int) string {
+	if from == to {
+		return ""
+	}
+
+	lines := diffLines(splitDiffLines(from), splitDiffLines(to))
+
+	// the number of lines of every text before every line of the diff
+	fromPos := make([]int, len(lines)+1)
+	toPos := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if line.op != '+' {
+			fromPos[i+1]++
+		}
+		if line.op != '-' {
+			toPos[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// the hunk includes the changes separated by up to twice the context
+		last := i
+		for j := i; j < len(lines) && j-last <= 2*context+1; j++ {
+			if lines[j].op != ' ' {
+				last = j
+			}
+		}
+		start := max(0, i-context)
+		end := min(len(lines), last+context+1)
+
+		fromStart, fromLen := fromPos[start], fromPos[end]-fromPos[start]
+		toStart, toLen := toPos[start], toPos[end]-toPos[start]
+		if fromLen > 0 {
+			fromStart++
+		}
+		if toLen > 0 {
+			toStart++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", fromStart, fromLen, toStart, toLen)
+		for _, line := range lines[start:end] {
+			sb.WriteByte(line.op)
+			sb.WriteString(line.text)
+			sb.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// splitDiffLines splits a text in lines, ignoring the final newline
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines compares two lists of lines, returning the lines in both of them,
+// the lines removed from the first one and the lines added in the second one
+func diffLines(a, b []string) []diffLine {
+	// skip the common prefix and suffix, as most changes are small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	res := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		res = append(res, diffLine{' ', line})
+	}
+
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(am)*len(bm) > MaxDiffCells {
+		for _, line := range am {
+			res = append(res, diffLine{'-', line})
+		}
+		for _, line := range bm {
+			res = append(res, diffLine{'+', line})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of am[i:] and bm[j:]
+		lcs := make([][]int, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				res = append(res, diffLine{' ', am[i]})
+				i++
+				j++
+			case i < len(am) && (j == len(bm) || lcs[i+1][j] >= lcs[i][j+1]):
+				res = append(res, diffLine{'-', am[i]})
+				i++
+			default:
+				res = append(res, diffLine{'+', bm[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		res = append(res, diffLine{' ', line})
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "changed line",
+			from: "a\nb\nc\nd\ne\nf\ng\n",
+			to:   "a\nb\nc\nD\ne\nf\ng\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -2,5 +2,5 @@\n b\n c\n-d\n+D\n e\n f\n",
+		},
+		{
+			name: "added and removed lines",
+			from: "a\nb\nc\n",
+			to:   "b\nc\nd\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,3 +1,3 @@\n-a\n b\n c\n+d\n",
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "a\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name: "merged hunks",
+			from: "1\n2\n3\n4\n5\n6\n",
+			to:   "0\n2\n3\n4\n5\n7\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,6 +1,6 @@\n-1\n+0\n 2\n 3\n 4\n 5\n-6\n+7\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "0\n2\n3\n4\n5\n6\n7\n8\n9\n11\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,3 +1,3 @@\n-1\n+0\n 2\n 3\n" +
+				"@@ -8,3 +8,3 @@\n 8\n 9\n-10\n+11\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.from, tt.to, "old", "new", 2); got != tt.expected {
+				t.Errorf("Unexpected diff:\n%s\nexpected:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff_BigChanges(t *testing.T) {
+	saved := MaxDiffCells
+	MaxDiffCells = 4
+	defer func() { MaxDiffCells = saved }()
+
+	got := UnifiedDiff("x\na\nb\nc\n", "x\nc\nd\ne\n", "old", "new", 1)
+	if !strings.Contains(got, "-a\n-b\n-c\n+c\n+d\n+e\n") {
+		t.Errorf("Expected all the lines to be replaced, got:\n%s", got)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package common
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskFree returns the space available in the filesystem of a path
+func diskFree(path string) (uint64, error) {
+	return 0, fmt.Errorf("not supported in %s", runtime.GOOS)
+}
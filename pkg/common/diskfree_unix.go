@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package common
+
+import "syscall"
+
+// diskFree returns the space available (for unprivileged users) in the filesystem of a path
+func diskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEnumRefresh is the time the allowed values of the dynamic enums are kept
+// before obtaining them again
+const DefaultEnumRefresh = time.Minute
+
+// enumCommandTimeout is the maximum time the commands of the dynamic enums can run
+const enumCommandTimeout = 10 * time.Second
+
+// enumValues are the allowed values of a dynamic enum, obtained at some time
+type enumValues struct {
+	mu      sync.Mutex // held while obtaining the values
+	values  []string
+	err     error
+	updated time.Time
+}
+
+// enumCache keeps the allowed values of the dynamic enums, by their source
+var enumCache = struct {
+	sync.Mutex
+	entries map[string]*enumValues
+}{entries: map[string]*enumValues{}}
+
+// HasEnum returns true if the values of the parameter are restricted to a dynamic enum
+func (p ParamConfig) HasEnum() bool {
+	return p.EnumGlob != "" || p.EnumCommand != ""
+}
+
+// enumRefresh returns the time the allowed values are kept
+func (p ParamConfig) enumRefresh() (time.Duration, error) {
+	if p.EnumRefresh == "" {
+		return DefaultEnumRefresh, nil
+	}
+	refresh, err := time.ParseDuration(p.EnumRefresh)
+	if err != nil || refresh < 0 {
+		return 0, fmt.Errorf("invalid enum refresh '%s': expected a duration like '5m'", p.EnumRefresh)
+	}
+	return refresh, nil
+}
+
+// validateEnum checks the dynamic enum of a parameter is well formed
+func (p ParamConfig) validateEnum() error {
+	if !p.HasEnum() {
+		if p.EnumRefresh != "" {
+			return fmt.Errorf("enum refresh without an enum")
+		}
+		return nil
+	}
+	if ParamValueType(p.Type) != "string" || p.Type == ParamTypeFileContent {
+		return fmt.Errorf("enums are only supported in the parameters of type string")
+	}
+	if p.EnumGlob != "" && p.EnumCommand != "" {
+		return fmt.Errorf("enum_glob and enum_command cannot be used together")
+	}
+	if _, err := filepath.Match(p.EnumGlob, ""); err != nil {
+		return fmt.Errorf("invalid enum glob '%s': %w", p.EnumGlob, err)
+	}
+	_, err := p.enumRefresh()
+	return err
+}
+
+// EnumValues returns the allowed values of a parameter with a dynamic enum (nil when
+// the parameter does not have one), sorted. The values are kept for the refresh time
+// of the parameter, so they track the files (or the output of the command) without
+// obtaining them in every call.
+func (p ParamConfig) EnumValues() ([]string, error) {
+	if !p.HasEnum() {
+		return nil, nil
+	}
+	refresh, err := p.enumRefresh()
+	if err != nil {
+		return nil, err
+	}
+
+	key := "glob:" + p.EnumGlob
+	if p.EnumCommand != "" {
+		key = "command:" + p.EnumCommand
+	}
+	enumCache.Lock()
+	cached, found := enumCache.entries[key]
+	if !found {
+		cached = &enumValues{}
+		enumCache.entries[key] = cached
+	}
+	enumCache.Unlock()
+
+	// the values of other enums can be obtained meanwhile
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if !cached.updated.IsZero() && time.Since(cached.updated) < refresh {
+		return cached.values, cached.err
+	}
+
+	var values []string
+	if p.EnumCommand != "" {
+		values, err = enumCommandValues(p.EnumCommand)
+	} else if values, err = filepath.Glob(p.EnumGlob); err != nil {
+		err = fmt.Errorf("invalid enum glob '%s': %w", p.EnumGlob, err)
+	}
+	slices.Sort(values)
+	cached.values, cached.err, cached.updated = slices.Compact(values), err, time.Now()
+	return cached.values, cached.err
+}
+
+// enumCommandValues runs the command of a dynamic enum in the default shell, returning
+// the lines of its output (without the empty ones)
+func enumCommandValues(command string) ([]string, error) {
+	output, err := RunShellCommand(command, enumCommandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("enum command '%s' failed: %w", command, err)
+	}
+
+	var values []string
+	for _, line := range strings.Split(output, "\n") {
+		if value := strings.TrimSpace(line); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// checkEnum checks a value is one of the allowed values of the dynamic enum of the parameter
+func (p ParamConfig) checkEnum(value string) error {
+	values, err := p.EnumValues()
+	if err != nil {
+		return fmt.Errorf("cannot obtain the allowed values: %w", err)
+	}
+	if !slices.Contains(values, value) {
+		if len(values) == 0 {
+			return fmt.Errorf("value '%s' not allowed: there are no allowed values", value)
+		}
+		return fmt.Errorf("value '%s' not allowed (allowed values: %s)", value, joinValues(values, 10))
+	}
+	return nil
+}
+
+// joinValues joins some values for the messages, with at most max values
+func joinValues(values []string, max int) string {
+	if len(values) <= max {
+		return fmt.Sprintf("%q", values)
+	}
+	return fmt.Sprintf("%q and %d more", values[:max], len(values)-max)
+}
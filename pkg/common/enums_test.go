@@ -0,0 +1,106 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParamConfigEnumValues(t *testing.T) {
+	dir := t.TempDir()
+	writePlaybook := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	writePlaybook("site.yaml")
+	writePlaybook("db.yaml")
+	writePlaybook("README.md")
+
+	glob := filepath.Join(dir, "*.yaml")
+	cached := ParamConfig{Type: "string", EnumGlob: glob, EnumRefresh: "1h"}
+	fresh := ParamConfig{Type: "string", EnumGlob: glob, EnumRefresh: "0s"}
+
+	values, err := cached.EnumValues()
+	expected := []string{filepath.Join(dir, "db.yaml"), filepath.Join(dir, "site.yaml")}
+	if err != nil || !slices.Equal(values, expected) {
+		t.Fatalf("EnumValues() = %q, %v, want %q", values, err, expected)
+	}
+
+	// the values are kept for the refresh time
+	writePlaybook("web.yaml")
+	if values, _ := cached.EnumValues(); len(values) != 2 {
+		t.Errorf("Expected the cached values, got %q", values)
+	}
+	if values, _ := fresh.EnumValues(); len(values) != 3 {
+		t.Errorf("Expected the values to be refreshed, got %q", values)
+	}
+
+	if _, err := fresh.NormalizeValue(filepath.Join(dir, "web.yaml")); err != nil {
+		t.Errorf("Unexpected error for an allowed value: %v", err)
+	}
+	if _, err := fresh.NormalizeValue(filepath.Join(dir, "README.md")); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected an error for a value not allowed, got %v", err)
+	}
+	if _, err := fresh.NormalizeValue(42.0); err == nil {
+		t.Errorf("Expected an error for a value that is not a string")
+	}
+
+	if values, err := (ParamConfig{Type: "string"}).EnumValues(); values != nil || err != nil {
+		t.Errorf("Expected no values for a parameter without an enum, got %q, %v", values, err)
+	}
+}
+
+func TestParamConfigEnumCommand(t *testing.T) {
+	param := ParamConfig{Type: "string", EnumCommand: "printf 'staging\\nprod\\n\\n  dev  \\nprod\\n'"}
+	values, err := param.EnumValues()
+	if err != nil || !slices.Equal(values, []string{"dev", "prod", "staging"}) {
+		t.Fatalf("EnumValues() = %q, %v", values, err)
+	}
+	if normalized, err := param.NormalizeValue("prod"); err != nil || normalized != "prod" {
+		t.Errorf("NormalizeValue() = %v, %v", normalized, err)
+	}
+	if _, err := param.NormalizeValue("qa"); err == nil {
+		t.Errorf("Expected an error for a value not allowed")
+	}
+
+	// the failures are reported (and kept for the refresh time)
+	failing := ParamConfig{Type: "string", EnumCommand: "echo 'no cluster' >&2; exit 3"}
+	if _, err := failing.EnumValues(); err == nil || !strings.Contains(err.Error(), "no cluster") {
+		t.Errorf("Expected the error of the command, got %v", err)
+	}
+	if _, err := failing.NormalizeValue("prod"); err == nil || !strings.Contains(err.Error(), "cannot obtain the allowed values") {
+		t.Errorf("Expected an error for the values not obtained, got %v", err)
+	}
+}
+
+func TestParamConfigValidateEnum(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   ParamConfig
+		wantErr bool
+	}{
+		{"no enum", ParamConfig{Type: "string"}, false},
+		{"glob", ParamConfig{EnumGlob: "/etc/playbooks/*.yaml", EnumRefresh: "5m"}, false},
+		{"glob in a hostname", ParamConfig{Type: ParamTypeHostname, EnumGlob: "/etc/hosts.d/*"}, false},
+		{"command", ParamConfig{EnumCommand: "kubectl config get-contexts -o name"}, false},
+		{"glob and command", ParamConfig{EnumGlob: "/etc/*", EnumCommand: "ls /etc"}, true},
+		{"invalid glob", ParamConfig{EnumGlob: "/etc/[a-"}, true},
+		{"invalid refresh", ParamConfig{EnumGlob: "/etc/*", EnumRefresh: "often"}, true},
+		{"refresh without enum", ParamConfig{EnumRefresh: "5m"}, true},
+		{"glob in a number", ParamConfig{Type: "number", EnumGlob: "/etc/*"}, true},
+		{"glob in a file content", ParamConfig{Type: ParamTypeFileContent, EnumGlob: "/etc/*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParams(map[string]ParamConfig{"param": tt.param})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
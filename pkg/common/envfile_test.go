@@ -0,0 +1,66 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name: "simple assignments",
+			content: `
+# a comment
+FOO=bar
+export BAZ=qux
+
+EMPTY=
+`,
+			expected: []string{"FOO=bar", "BAZ=qux", "EMPTY="},
+		},
+		{
+			name:     "quoted values",
+			content:  "SINGLE='a $literal # value'\nDOUBLE=\"line1\\nline2 \\\"quoted\\\"\"\n",
+			expected: []string{"SINGLE=a $literal # value", "DOUBLE=line1\nline2 \"quoted\""},
+		},
+		{
+			name:     "inline comments and equal signs",
+			content:  "URL=http://host/?a=b # the url\nSPACED = value\n",
+			expected: []string{"URL=http://host/?a=b", "SPACED=value"},
+		},
+		{
+			name:        "missing assignment",
+			content:     "FOO\n",
+			expectError: true,
+		},
+		{
+			name:        "unterminated quote",
+			content:     "FOO=\"bar\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			vars, err := LoadEnvFile(path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("LoadEnvFile() error = %v, expectError %v", err, tt.expectError)
+			}
+			if !tt.expectError && !reflect.DeepEqual(vars, tt.expected) {
+				t.Errorf("LoadEnvFile() = %q, expected %q", vars, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package common
+
+import "context"
+
+// executionIDKey is the key of the ID of the execution of a tool in the contexts
+type executionIDKey struct{}
+
+// WithExecutionID returns a context with the ID of the execution of a tool call,
+// for correlating its logs, its history entry, its metrics and its result
+func WithExecutionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionIDFromContext returns the ID of the execution in a context (empty if none)
+func ExecutionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDKey{}).(string)
+	return id
+}
+
+// EnsureExecutionID returns a context with an ID for the execution, generating
+// a new one when the context does not have one yet
+func EnsureExecutionID(ctx context.Context) (context.Context, string) {
+	if id := ExecutionIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewCorrelationID()
+	return WithExecutionID(ctx, id), id
+}
@@ -0,0 +1,228 @@
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormatAuto detects the format of the output, parsing it when possible
+const OutputFormatAuto = "auto"
+
+// The formats detected in the outputs of the commands
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatCSV    = "csv"
+	FormatHTML   = "html"
+	FormatBinary = "binary"
+)
+
+// FormatTSV is the format of the outputs with fields separated by tabs
+const FormatTSV = "tsv"
+
+// OutputFormats are the valid formats of the outputs
+var OutputFormats = []string{OutputFormatAuto, FormatCSV, FormatTSV}
+
+// CheckOutputFormat checks the format of the output is valid (or empty), it is not
+// used with an output converter, and the CSV options are valid
+func CheckOutputFormat(output OutputConfig) error {
+	switch {
+	case output.Format == "":
+		return nil
+	case !slices.Contains(OutputFormats, output.Format):
+		return fmt.Errorf("unknown output format '%s' (valid formats: %s)", output.Format, strings.Join(OutputFormats, ", "))
+	case output.Convert != "":
+		return fmt.Errorf("the output format cannot be used with an output converter")
+	}
+	if output.Format == FormatCSV || output.Format == FormatTSV {
+		if _, err := output.CSV.delimiter(output.Format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delimiter returns the delimiter of the fields for a format
+func (c CSVOutputConfig) delimiter(format string) (rune, error) {
+	switch c.Delimiter {
+	case "":
+		if format == FormatTSV {
+			return '\t', nil
+		}
+		return ',', nil
+	case "tab", "\t":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(c.Delimiter)
+	if size != len(c.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid CSV delimiter '%s': it must be a single character (or \"tab\")", c.Delimiter)
+	}
+	return r, nil
+}
+
+// ParseDelimitedOutput parses an output with the "csv" or "tsv" format, returning it
+// as a JSON array with an object per row
+func ParseDelimitedOutput(format string, options CSVOutputConfig, output string) (string, error) {
+	delimiter, err := options.delimiter(format)
+	if err != nil {
+		return "", err
+	}
+
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = delimiter != '\t'
+	reader.LazyQuotes = delimiter == '\t'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("invalid %s output: %w", strings.ToUpper(format), err)
+	}
+
+	var header []string
+	if (options.Header == nil || *options.Header) && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+	}
+	width := len(header)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	header = append(header, make([]string, max(width-len(header), 0))...)
+	for i, name := range options.Columns {
+		if i < len(header) {
+			header[i] = name
+		}
+	}
+	return FormatJSONTable(header, rows)
+}
+
+// DetectOutputFormat detects the format of the output of a command: "json", "yaml",
+// "csv", "html", "binary" or "text" (when it is none of the others)
+func DetectOutputFormat(output string) string {
+	if !utf8.ValidString(output) || strings.ContainsRune(output, 0) {
+		return FormatBinary
+	}
+
+	trimmed := strings.TrimSpace(output)
+	switch {
+	case trimmed == "":
+		return FormatText
+	case (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)):
+		return FormatJSON
+	case strings.HasPrefix(http.DetectContentType([]byte(trimmed)), "text/html"):
+		return FormatHTML
+	case isCSV(trimmed):
+		return FormatCSV
+	case isYAML(trimmed):
+		return FormatYAML
+	}
+	return FormatText
+}
+
+// isCSV returns true for texts with several lines with the same number of fields
+// (at least two) separated by commas
+func isCSV(text string) bool {
+	if strings.Count(text, "\n") < 1 || !strings.Contains(text, ",") {
+		return false
+	}
+	header, rows, err := ParseCSV(text)
+	if err != nil || len(header) < 2 || len(rows) == 0 {
+		return false
+	}
+	for _, row := range rows {
+		if len(row) != len(header) {
+			return false
+		}
+	}
+	return true
+}
+
+// isYAML returns true for texts that are YAML documents with mappings (with keys without
+// spaces, unlike the "Some thing: value" lines of many commands) or sequences. Texts with
+// a single line (ie, "Error: not found") are only YAML with an explicit document start.
+func isYAML(text string) bool {
+	if !strings.HasPrefix(text, "---") && strings.Count(text, "\n") < 1 {
+		return false
+	}
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+		return false
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			if strings.ContainsAny(k, " \t") {
+				return false
+			}
+		}
+		return true
+	case map[interface{}]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// OutputContentType returns the MIME type of an output in some format
+func OutputContentType(format string, output string) string {
+	switch format {
+	case FormatJSON, FormatYAML, FormatCSV:
+		// YAML and CSV are converted to JSON
+		return "application/json"
+	case FormatHTML:
+		return "text/html"
+	case FormatBinary:
+		return http.DetectContentType([]byte(output))
+	}
+	return "text/plain"
+}
+
+// ParseOutputFormat applies the parser of a format to an output: YAML documents and CSV
+// tables are converted to JSON, and the other formats are returned unchanged
+func ParseOutputFormat(format string, output string) (string, error) {
+	switch format {
+	case FormatYAML:
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(output), &value); err != nil {
+			return "", fmt.Errorf("invalid YAML: %w", err)
+		}
+		data, err := json.MarshalIndent(jsonCompatible(value), "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatCSV:
+		return ConvertOutput(ConvertCSVToJSON, output)
+	}
+	return output, nil
+}
+
+// jsonCompatible converts the maps with keys of any type decoded from YAML
+// to maps with string keys
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			res[fmt.Sprint(k)] = jsonCompatible(item)
+		}
+		return res
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = jsonCompatible(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDetectOutputFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"empty", "", FormatText},
+		{"text", "hello world", FormatText},
+		{"key value line", "Error: file not found", FormatText},
+		{"text with colons", "Status: ok\nThe pod is running: yes, for now", FormatText},
+		{"json object", `{"name": "web", "replicas": 3}`, FormatJSON},
+		{"json array", "[1, 2, 3]\n", FormatJSON},
+		{"invalid json", `{"name": }`, FormatText},
+		{"yaml", "name: web\nreplicas: 3\nports:\n  - 80\n  - 443", FormatYAML},
+		{"yaml document", "--- \nname: web", FormatYAML},
+		{"csv", "name,cpu,memory\nweb,10m,64Mi\ndb,200m,1Gi", FormatCSV},
+		{"uneven commas", "hello, world\nthis is, some, text", FormatText},
+		{"html", "<!DOCTYPE html>\n<html><body>hi</body></html>", FormatHTML},
+		{"html fragment", "<div><p>hi</p></div>", FormatHTML},
+		{"binary", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", FormatBinary},
+		{"invalid utf8", "abc\xff\xfe", FormatBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectOutputFormat(tt.output); got != tt.want {
+				t.Errorf("DetectOutputFormat(%q) = %s, want %s", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	got, err := ParseOutputFormat(FormatYAML, "name: web\nports:\n  - 80\nlabels:\n  1: one")
+	if err != nil {
+		t.Fatalf("ParseOutputFormat() error = %v", err)
+	}
+	want := "{\n  \"labels\": {\n    \"1\": \"one\"\n  },\n  \"name\": \"web\",\n  \"ports\": [\n    80\n  ]\n}"
+	if got != want {
+		t.Errorf("ParseOutputFormat(yaml) = %s, want %s", got, want)
+	}
+
+	got, err = ParseOutputFormat(FormatCSV, "name,cpu\nweb,10m")
+	if err != nil || got != "[\n  {\"name\": \"web\", \"cpu\": \"10m\"}\n]" {
+		t.Errorf("ParseOutputFormat(csv) = %s, %v", got, err)
+	}
+
+	if got, _ := ParseOutputFormat(FormatHTML, "<p>hi</p>"); got != "<p>hi</p>" {
+		t.Errorf("ParseOutputFormat(html) = %s", got)
+	}
+
+	if OutputContentType(FormatBinary, "\x89PNG\r\n\x1a\n") != "image/png" {
+		t.Errorf("Expected the content type of a PNG image")
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  OutputConfig
+		wantErr bool
+	}{
+		{"none", OutputConfig{}, false},
+		{"auto", OutputConfig{Format: OutputFormatAuto}, false},
+		{"csv", OutputConfig{Format: FormatCSV, CSV: CSVOutputConfig{Delimiter: ";"}}, false},
+		{"tsv", OutputConfig{Format: FormatTSV}, false},
+		{"unknown", OutputConfig{Format: "xml"}, true},
+		{"with converter", OutputConfig{Format: OutputFormatAuto, Convert: ConvertCSVToJSON}, true},
+		{"long delimiter", OutputConfig{Format: FormatCSV, CSV: CSVOutputConfig{Delimiter: "::"}}, true},
+		{"quote delimiter", OutputConfig{Format: FormatCSV, CSV: CSVOutputConfig{Delimiter: `"`}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckOutputFormat(tt.output); (err != nil) != tt.wantErr {
+				t.Errorf("CheckOutputFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDelimitedOutput(t *testing.T) {
+	noHeader := false
+	tests := []struct {
+		name    string
+		format  string
+		options CSVOutputConfig
+		output  string
+		want    string
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			output: "name, cpu\nweb, 10m\n\"db, primary\", 200m",
+			want:   "[\n  {\"name\": \"web\", \"cpu\": \"10m\"},\n  {\"name\": \"db, primary\", \"cpu\": \"200m\"}\n]",
+		},
+		{
+			name:   "tsv",
+			format: FormatTSV,
+			output: "name\tcomment\nweb\tsays \"hi\"",
+			want:   "[\n  {\"name\": \"web\", \"comment\": \"says \\\"hi\\\"\"}\n]",
+		},
+		{
+			name:    "delimiter",
+			format:  FormatCSV,
+			options: CSVOutputConfig{Delimiter: ";"},
+			output:  "name;cpu\nweb;10m",
+			want:    "[\n  {\"name\": \"web\", \"cpu\": \"10m\"}\n]",
+		},
+		{
+			name:    "without header",
+			format:  FormatCSV,
+			options: CSVOutputConfig{Header: &noHeader, Columns: []string{"user"}},
+			output:  "root,0\ndaemon,1",
+			want:    "[\n  {\"user\": \"root\", \"column_2\": \"0\"},\n  {\"user\": \"daemon\", \"column_2\": \"1\"}\n]",
+		},
+		{
+			name:    "renamed columns",
+			format:  FormatTSV,
+			options: CSVOutputConfig{Delimiter: "tab", Columns: []string{"id", "size"}},
+			output:  "ID\tSIZE (MB)\n1\t20",
+			want:    "[\n  {\"id\": \"1\", \"size\": \"20\"}\n]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDelimitedOutput(tt.format, tt.options, tt.output)
+			if err != nil {
+				t.Fatalf("ParseDelimitedOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDelimitedOutput() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseDelimitedOutput(FormatCSV, CSVOutputConfig{}, "a,\"b\nc"); err == nil {
+		t.Errorf("ParseDelimitedOutput() of an invalid CSV: expected an error")
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// NormalizeHostname checks a host name is valid (RFC 1123) and returns it in lower
+// case, without the final dot
+func NormalizeHostname(value string) (string, error) {
+	host := strings.TrimSuffix(strings.ToLower(value), ".")
+	if host == "" || len(host) > 253 {
+		return "", fmt.Errorf("invalid host name '%s'", value)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("invalid host name '%s'", value)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", fmt.Errorf("invalid host name '%s': invalid character %q", value, c)
+			}
+		}
+	}
+	if isNumericHost(host) {
+		// the top-level domains are never numbers (ie, "10.0.0.1" is an IP address)
+		return "", fmt.Errorf("invalid host name '%s'", value)
+	}
+	return host, nil
+}
+
+// NormalizeEmail checks an email address (like "john@example.com", without a display
+// name or quotes) is valid and returns it in lower case
+func NormalizeEmail(value string) (string, error) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value || strings.ContainsAny(value, "\"\\") {
+		return "", fmt.Errorf("invalid email address '%s'", value)
+	}
+
+	at := strings.LastIndex(value, "@")
+	domain, err := NormalizeHostname(value[at+1:])
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", fmt.Errorf("invalid email address '%s': invalid domain", value)
+	}
+	return strings.ToLower(value[:at]) + "@" + domain, nil
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHostname(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"example.com", "example.com", false},
+		{"API.Example.COM", "api.example.com", false},
+		{"example.com.", "example.com", false},
+		{"localhost", "localhost", false},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", false},
+		{"a-b.c-d.io", "a-b.c-d.io", false},
+		{"3com.com", "3com.com", false},
+		{"", "", true},
+		{".", "", true},
+		{"example..com", "", true},
+		{"-example.com", "", true},
+		{"example-.com", "", true},
+		{"under_score.com", "", true},
+		{"exa mple.com", "", true},
+		{"example.com;ls", "", true},
+		{"bücher.example", "", true},
+		{"10.0.0.1", "", true},
+		{strings.Repeat("a", 64) + ".com", "", true},
+		{strings.Repeat("a.", 127) + "com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := NormalizeHostname(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeHostname(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("NormalizeHostname(%q) = %q, want %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"john@example.com", "john@example.com", false},
+		{"John.Doe+alerts@Example.COM", "john.doe+alerts@example.com", false},
+		{"ops-team@mail.example.co.uk", "ops-team@mail.example.co.uk", false},
+		{"john", "", true},
+		{"john@", "", true},
+		{"@example.com", "", true},
+		{"john@localhost", "", true},
+		{"john@example..com", "", true},
+		{"john@[10.0.0.1]", "", true},
+		{"John <john@example.com>", "", true},
+		{"\"john doe\"@example.com", "", true},
+		{" john@example.com", "", true},
+		{"john@example.com, jane@example.com", "", true},
+		{"john@example.com\nBcc: jane@example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := NormalizeEmail(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEmail(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
@@ -42,6 +42,15 @@ func LogLevelFromString(level string) LogLevel {
 	}
 }
 
+// CheckLogLevel checks a log level is valid (or empty)
+func CheckLogLevel(level string) error {
+	switch level {
+	case "", "debug", "info", "error", "none":
+		return nil
+	}
+	return fmt.Errorf("invalid log level '%s' (valid levels: none, error, info, debug)", level)
+}
+
 // Logger provides a structured logging interface for the application
 type Logger struct {
 	// The underlying Go logger
@@ -52,6 +61,8 @@ type Logger struct {
 	filePath string
 	// The log file handle (if used)
 	file *os.File
+	// The tag added to all the messages (ie, the ID of an execution)
+	tag string
 }
 
 // NewLogger creates a new Logger instance
@@ -94,9 +105,9 @@ func NewLogger(prefix string, filePath string, level LogLevel, truncate bool) (*
 		writer = os.Stderr
 	}
 
-	// Create the logger
+	// Create the logger (redacting any secrets from the messages)
 	logger := &Logger{
-		Logger:   log.New(writer, prefix, log.Ldate|log.Ltime|log.Lshortfile),
+		Logger:   log.New(redactingWriter{writer}, prefix, log.Ldate|log.Ltime|log.Lshortfile),
 		level:    level,
 		filePath: filePath,
 		file:     file,
@@ -122,21 +133,21 @@ func (l *Logger) Close() error {
 // Debug logs a message at debug level
 func (l *Logger) Debug(format string, v ...interface{}) {
 	if l.level >= LogLevelDebug {
-		l.Printf("[DEBUG] "+format, v...)
+		l.Printf("[DEBUG] "+l.tag+format, v...)
 	}
 }
 
 // Info logs a message at info level
 func (l *Logger) Info(format string, v ...interface{}) {
 	if l.level >= LogLevelInfo {
-		l.Printf("[INFO] "+format, v...)
+		l.Printf("[INFO] "+l.tag+format, v...)
 	}
 }
 
 // Error logs a message at error level
 func (l *Logger) Error(format string, v ...interface{}) {
 	if l.level >= LogLevelError {
-		l.Printf("[ERROR] "+format, v...)
+		l.Printf("[ERROR] "+l.tag+format, v...)
 	}
 }
 
@@ -155,6 +166,25 @@ func (l *Logger) SetLevel(level LogLevel) {
 	l.level = level
 }
 
+// WithLevel returns a logger writing to the same destination with another log level
+// (ie, for logging the debug messages of a single tool)
+func (l *Logger) WithLevel(level LogLevel) *Logger {
+	return &Logger{
+		Logger:   l.Logger,
+		level:    level,
+		filePath: l.filePath,
+		tag:      l.tag,
+	}
+}
+
+// WithExecutionID returns a logger adding the ID of an execution to all the messages,
+// so all the logs of a tool call can be found
+func (l *Logger) WithExecutionID(id string) *Logger {
+	res := l.WithLevel(l.level)
+	res.tag = "[exec:" + id + "] "
+	return res
+}
+
 //////////////////////////////////////////////////////////////////////
 
 // GetLogger returns the global application logger.
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// NetworkConfig defines the addresses allowed in the values of the "ip" and "cidr"
+// parameters (ie, so a tool running nmap can only scan the networks of a lab).
+//
+// The ranges are IP addresses (like "192.168.1.1") or networks (like "10.0.0.0/8").
+type NetworkConfig struct {
+	// AllowedRanges are the ranges allowed (all the addresses when empty). The networks
+	// of the "cidr" parameters must be inside one of them.
+	AllowedRanges []string `yaml:"allowed_ranges,omitempty"`
+
+	// DeniedRanges are the ranges denied, with precedence over the allowed ones. The
+	// networks of the "cidr" parameters cannot overlap any of them.
+	DeniedRanges []string `yaml:"denied_ranges,omitempty"`
+}
+
+// parseRange parses an IP address or a network, returning a network
+func parseRange(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			// ie, "::ffff:10.0.0.0/104" is "10.0.0.0/8"
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := parseIP(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// parseIP parses an IP address (without zone), with the IPv4 addresses mapped to IPv6
+// converted to IPv4
+func parseIP(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("IP addresses with zones are not supported")
+	}
+	return addr.Unmap(), nil
+}
+
+// validate checks the ranges are valid
+func (c *NetworkConfig) validate() error {
+	for _, r := range append(slices.Clone(c.AllowedRanges), c.DeniedRanges...) {
+		if _, err := parseRange(r); err != nil {
+			return fmt.Errorf("invalid range '%s': %w", r, err)
+		}
+	}
+	return nil
+}
+
+// ranges returns the networks of some ranges (ignoring the invalid ones, rejected
+// when validating the configuration)
+func ranges(values []string) []netip.Prefix {
+	var res []netip.Prefix
+	for _, value := range values {
+		if prefix, err := parseRange(value); err == nil {
+			res = append(res, prefix)
+		}
+	}
+	return res
+}
+
+// CheckIP returns an error if an IP address is not valid or not allowed. A nil
+// configuration allows all the addresses.
+func (c *NetworkConfig) CheckIP(value string) error {
+	addr, err := parseIP(value)
+	if err != nil {
+		return fmt.Errorf("invalid IP address '%s'", value)
+	}
+	if c == nil {
+		return nil
+	}
+
+	for _, denied := range ranges(c.DeniedRanges) {
+		if denied.Contains(addr) {
+			return fmt.Errorf("IP address '%s' not allowed", value)
+		}
+	}
+	if allowed := ranges(c.AllowedRanges); len(allowed) > 0 && !slices.ContainsFunc(allowed, func(r netip.Prefix) bool {
+		return r.Contains(addr)
+	}) {
+		return fmt.Errorf("IP address '%s' not allowed (allowed ranges: %s)", value, strings.Join(c.AllowedRanges, ", "))
+	}
+	return nil
+}
+
+// CheckCIDR returns an error if a network (like "10.0.0.0/24") is not valid or not
+// allowed. A nil configuration allows all the networks.
+func (c *NetworkConfig) CheckCIDR(value string) error {
+	if !strings.Contains(value, "/") {
+		return fmt.Errorf("invalid network '%s': expected a CIDR like '10.0.0.0/24'", value)
+	}
+	prefix, err := parseRange(value)
+	if err != nil {
+		return fmt.Errorf("invalid network '%s': expected a CIDR like '10.0.0.0/24'", value)
+	}
+	if c == nil {
+		return nil
+	}
+
+	for _, denied := range ranges(c.DeniedRanges) {
+		if denied.Overlaps(prefix) {
+			return fmt.Errorf("network '%s' not allowed", value)
+		}
+	}
+	if allowed := ranges(c.AllowedRanges); len(allowed) > 0 && !slices.ContainsFunc(allowed, func(r netip.Prefix) bool {
+		return r.Bits() <= prefix.Bits() && r.Contains(prefix.Addr())
+	}) {
+		return fmt.Errorf("network '%s' not allowed (allowed ranges: %s)", value, strings.Join(c.AllowedRanges, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package common
+
+import "testing"
+
+func TestNetworkConfigCheck(t *testing.T) {
+	lab := &NetworkConfig{
+		AllowedRanges: []string{"192.168.0.0/16", "10.1.2.3", "fd00::/8"},
+		DeniedRanges:  []string{"192.168.100.0/24"},
+	}
+
+	tests := []struct {
+		name    string
+		config  *NetworkConfig
+		ip      bool // check an IP address (or a network otherwise)
+		value   string
+		wantErr bool
+	}{
+		{"any address", nil, true, "8.8.8.8", false},
+		{"any IPv6 address", nil, true, "2001:db8::1", false},
+		{"invalid address", nil, true, "8.8.8", true},
+		{"address with leading zeros", nil, true, "010.0.0.1", true},
+		{"address with zone", nil, true, "fe80::1%eth0", true},
+		{"host name", nil, true, "example.com", true},
+		{"network as address", nil, true, "10.0.0.0/8", true},
+		{"allowed address", lab, true, "192.168.1.10", false},
+		{"allowed single address", lab, true, "10.1.2.3", false},
+		{"allowed mapped address", lab, true, "::ffff:192.168.1.10", false},
+		{"allowed IPv6 address", lab, true, "fd12::1", false},
+		{"address not allowed", lab, true, "10.1.2.4", true},
+		{"denied address", lab, true, "192.168.100.7", true},
+
+		{"any network", nil, false, "0.0.0.0/0", false},
+		{"network not masked", nil, false, "10.0.0.1/24", false},
+		{"address as network", nil, false, "10.0.0.1", true},
+		{"invalid network", nil, false, "10.0.0.0/33", true},
+		{"allowed network", lab, false, "192.168.1.0/24", false},
+		{"allowed single address network", lab, false, "10.1.2.3/32", false},
+		{"network bigger than the allowed range", lab, false, "192.168.0.0/15", true},
+		{"network overlapping the denied range", lab, false, "192.168.96.0/20", true},
+		{"network outside of the allowed ranges", lab, false, "172.16.0.0/12", true},
+		{"mapped network", lab, false, "::ffff:192.168.1.0/120", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.ip {
+				err = tt.config.CheckIP(tt.value)
+			} else {
+				err = tt.config.CheckCIDR(tt.value)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@
 package common
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"runtime/debug"
@@ -31,3 +33,46 @@ func RecoverPanic() bool {
 
 	return false
 }
+
+// InternalError is the error of an operation that panicked, with an ID
+// for finding the details (ie, the stack trace) in the logs
+type InternalError struct {
+	CorrelationID string      // the ID of the error in the logs
+	Panic         interface{} // the value of the panic
+}
+
+// Error returns the message of the error, without the details of the panic
+func (e *InternalError) Error() string {
+	return fmt.Sprintf("internal error (correlation ID: %s)", e.CorrelationID)
+}
+
+// NewCorrelationID returns a random ID for correlating an error with the logs
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// CatchPanic recovers from a panic, logging it with its stack trace and returning
+// an InternalError in err, so the panic only fails the operation in progress.
+//
+// This function must be deferred directly (ie, "defer common.CatchPanic(&err)").
+func CatchPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	internalErr := &InternalError{CorrelationID: NewCorrelationID(), Panic: r}
+	if logger := GetLogger(); logger != nil {
+		logger.Error("PANIC RECOVERED [%s]: %v", internalErr.CorrelationID, r)
+		logger.Error("Stack trace [%s]:\n%s", internalErr.CorrelationID, debug.Stack())
+	}
+	fmt.Fprintf(os.Stderr, "PANIC RECOVERED [%s]: %v\n", internalErr.CorrelationID, r)
+
+	if err != nil {
+		*err = internalErr
+	}
+}
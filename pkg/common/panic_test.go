@@ -0,0 +1,31 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatchPanic(t *testing.T) {
+	run := func(f func()) (err error) {
+		defer CatchPanic(&err)
+		f()
+		return nil
+	}
+
+	if err := run(func() {}); err != nil {
+		t.Errorf("Expected no error without a panic, got %v", err)
+	}
+
+	err := run(func() { panic("boom") })
+	var internalErr *InternalError
+	if !errors.As(err, &internalErr) {
+		t.Fatalf("Expected an internal error, got %v", err)
+	}
+	if internalErr.CorrelationID == "" || internalErr.Panic != "boom" {
+		t.Errorf("Unexpected internal error: %+v", internalErr)
+	}
+
+	if NewCorrelationID() == NewCorrelationID() {
+		t.Errorf("Expected different correlation IDs")
+	}
+}
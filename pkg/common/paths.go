@@ -0,0 +1,35 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePath cleans a path and resolves any symlinks in it (when it exists).
+func ResolvePath(path string) string {
+	path = filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// IsPathInRoots checks if a path is inside (or is the same as) any of the roots given,
+// resolving the symlinks in them. Any path is accepted when there are no roots.
+func IsPathInRoots(path string, roots []string) bool {
+	if len(roots) == 0 {
+		return true
+	}
+
+	path = ResolvePath(path)
+	for _, root := range roots {
+		rel, err := filepath.Rel(ResolvePath(root), path)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
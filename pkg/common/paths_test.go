@@ -0,0 +1,42 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsPathInRoots(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create sub directory: %v", err)
+	}
+	if err := os.Symlink("/", filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		roots []string
+		want  bool
+	}{
+		{"no roots", "/etc", nil, true},
+		{"the root", root, []string{root}, true},
+		{"inside the root", filepath.Join(root, "sub"), []string{root}, true},
+		{"not existing", filepath.Join(root, "sub", "new"), []string{root}, true},
+		{"outside the root", "/etc", []string{root}, false},
+		{"parent of the root", filepath.Dir(root), []string{root}, false},
+		{"dot-dot", filepath.Join(root, "sub", "..", ".."), []string{root}, false},
+		{"prefix of the name", root + "-other", []string{root}, false},
+		{"symlink out of the root", filepath.Join(root, "escape", "etc"), []string{root}, false},
+		{"any of the roots", "/etc", []string{root, "/etc"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPathInRoots(tt.path, tt.roots); got != tt.want {
+				t.Errorf("IsPathInRoots(%q, %v) = %v, want %v", tt.path, tt.roots, got, tt.want)
+			}
+		})
+	}
+}
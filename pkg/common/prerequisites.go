@@ -2,8 +2,19 @@
 package common
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // CheckExecutableExists checks if a command is available in the system PATH.
@@ -36,3 +47,163 @@ func CheckOSMatches(requiredOS string) bool {
 	// Check if the current OS matches the required OS
 	return runtime.GOOS == requiredOS
 }
+
+// CheckFileExists checks a file (or directory) exists. Environment variables
+// and a leading "~" (the home directory) in the path are expanded.
+func CheckFileExists(path string) error {
+	expanded := os.ExpandEnv(path)
+	if expanded == "~" || strings.HasPrefix(expanded, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		expanded = home + expanded[1:]
+	}
+	if _, err := os.Stat(expanded); err != nil {
+		return fmt.Errorf("'%s' not found", path)
+	}
+	return nil
+}
+
+// CheckEnvSet checks an environment variable is set (and not empty)
+func CheckEnvSet(name string) error {
+	if os.Getenv(name) == "" {
+		return fmt.Errorf("environment variable '%s' not set", name)
+	}
+	return nil
+}
+
+// CheckConnectivity checks an address can be reached: a TCP address ("host:port") must
+// accept connections, and an http(s) URL must return a response (with any status).
+func CheckConnectivity(address string, timeout time.Duration) error {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(address)
+		if err != nil {
+			return fmt.Errorf("'%s' not reachable: %w", address, err)
+		}
+		_ = resp.Body.Close()
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return fmt.Errorf("'%s' not reachable: %w", address, err)
+	}
+	_ = conn.Close()
+	return nil
+}
+
+// DefaultVersionArgs are the arguments for getting the version of a binary
+// when no others are given
+var DefaultVersionArgs = []string{"--version"}
+
+// versionCommandTimeout is the maximum time for getting the version of a binary
+const versionCommandTimeout = 10 * time.Second
+
+// defaultVersionRegex matches the first version number in the output of a version command
+var defaultVersionRegex = regexp.MustCompile(`v?(\d+(?:\.\d+){0,2}(?:-[0-9A-Za-z.-]+)?)`)
+
+// binaryVersion is the version of a binary (or the error obtaining it)
+type binaryVersion struct {
+	version *semver.Version
+	err     error
+}
+
+// binaryVersions caches the versions of the binaries, by version command and pattern
+var binaryVersions sync.Map
+
+// GetBinaryVersion returns the version of a binary, running it with some arguments
+// (ie, "terraform --version") and parsing the first version found in its output.
+// Results are cached, so every version command is run only once.
+//
+// Parameters:
+//   - binary: The name (or path) of the binary
+//   - args: The arguments for printing the version (DefaultVersionArgs when empty)
+//   - pattern: A regular expression matching the version in the output, with the version
+//     in the first group (optional)
+//
+// Returns:
+//   - The version of the binary
+//   - An error if the binary is not found, or no version is found in its output
+func GetBinaryVersion(binary string, args []string, pattern string) (*semver.Version, error) {
+	if len(args) == 0 {
+		args = DefaultVersionArgs
+	}
+	key := strings.Join(append([]string{binary, pattern}, args...), "\x00")
+	if cached, ok := binaryVersions.Load(key); ok {
+		return cached.(binaryVersion).version, cached.(binaryVersion).err
+	}
+
+	version, err := getBinaryVersion(binary, args, pattern)
+	binaryVersions.Store(key, binaryVersion{version: version, err: err})
+	return version, err
+}
+
+// getBinaryVersion runs the version command of a binary, parsing its output
+func getBinaryVersion(binary string, args []string, pattern string) (*semver.Version, error) {
+	re := defaultVersionRegex
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid version regex '%s': %w", pattern, err)
+		}
+	}
+
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' not found", binary)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionCommandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("failed to get the version of '%s': %w", binary, err)
+	}
+
+	match := re.FindStringSubmatch(string(output))
+	if match == nil {
+		return nil, fmt.Errorf("no version found in the output of '%s %s'", binary, strings.Join(args, " "))
+	}
+	found := match[0]
+	if len(match) > 1 {
+		found = match[1]
+	}
+
+	version, err := semver.NewVersion(found)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version '%s' in the output of '%s %s'", found, binary, strings.Join(args, " "))
+	}
+	return version, nil
+}
+
+// CheckBinaryVersion checks a binary is installed with a version satisfying a
+// constraint (ie, ">=1.5" or "~1.6"). See GetBinaryVersion for the parameters.
+//
+// Returns:
+//   - nil if the version of the binary satisfies the constraint
+//   - An error explaining why the requirement is not met otherwise
+func CheckBinaryVersion(binary string, constraint string, args []string, pattern string) error {
+	if constraint == "" {
+		if !CheckExecutableExists(binary) {
+			return fmt.Errorf("'%s' not found", binary)
+		}
+		return nil
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("invalid version constraint '%s': %w", constraint, err)
+	}
+
+	version, err := GetBinaryVersion(binary, args, pattern)
+	if err != nil {
+		return err
+	}
+	if !c.Check(version) {
+		return fmt.Errorf("'%s' %s does not satisfy '%s'", binary, version, constraint)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// RedactedText is the text that replaces the redacted values
+const RedactedText = "[REDACTED]"
+
+// redactedValues is the list of values that must never be shown in logs or outputs
+var (
+	redactedValues   []string
+	redactedValuesMu sync.RWMutex
+)
+
+// AddRedactedValue registers a value (ie, a secret) that will be redacted
+// from all the logs and outputs from now on. Empty values are ignored.
+func AddRedactedValue(value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+
+	redactedValuesMu.Lock()
+	defer redactedValuesMu.Unlock()
+
+	for _, v := range redactedValues {
+		if v == value {
+			return
+		}
+	}
+	redactedValues = append(redactedValues, value)
+}
+
+// Redact replaces all the registered values found in a text by RedactedText
+func Redact(text string) string {
+	redactedValuesMu.RLock()
+	defer redactedValuesMu.RUnlock()
+
+	for _, v := range redactedValues {
+		text = strings.ReplaceAll(text, v, RedactedText)
+	}
+	return text
+}
+
+// redactingWriter is a writer that redacts the registered values before
+// writing to the underlying writer
+type redactingWriter struct {
+	w io.Writer
+}
+
+// Write implements the io.Writer interface
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write([]byte(Redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// RequestVariable is the variable with the context of the tool call in the constraints
+const RequestVariable = "request"
+
+// RequestInfo is the context of a tool call, available in the constraints as the
+// "request" variable (ie, for allowing some calls only to some clients, or at some hours)
+type RequestInfo struct {
+	Client       string    // the name of the authenticated client (empty when not authenticated)
+	Groups       []string  // the groups of the client
+	Session      string    // the ID of the MCP session
+	SessionStart time.Time // the time the session started
+	SessionCalls int       // the number of tool calls in the session, including this one
+	Time         time.Time // the time of the call
+}
+
+// requestInfoKey is the key of the context of a tool call in the contexts
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a context with the context of a tool call, for the constraints
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the context of a tool call in a context (nil if none)
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+// celValue returns the value of the "request" variable in the constraints. Calls without
+// context (ie, from the command line) have no client, no session and the current time.
+//
+// The variable has the fields:
+//   - client: the name of the client
+//   - groups: the groups of the client
+//   - session: the ID of the session
+//   - session_age: the time since the session started (a duration)
+//   - session_calls: the number of tool calls in the session, including this one
+//   - time: the time of the call (a timestamp)
+func (r *RequestInfo) celValue() map[string]interface{} {
+	info := RequestInfo{Time: time.Now()}
+	if r != nil {
+		info = *r
+	}
+	if info.Time.IsZero() {
+		info.Time = time.Now()
+	}
+	if info.Groups == nil {
+		info.Groups = []string{}
+	}
+	var age time.Duration
+	if !info.SessionStart.IsZero() {
+		age = info.Time.Sub(info.SessionStart)
+	}
+	return map[string]interface{}{
+		"client":        info.Client,
+		"groups":        info.Groups,
+		"session":       info.Session,
+		"session_age":   age,
+		"session_calls": info.SessionCalls,
+		"time":          info.Time,
+	}
+}
@@ -0,0 +1,355 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONSchema is a JSON Schema document, as decoded from YAML or JSON.
+//
+// Only a subset of JSON Schema is supported for validation: type, enum, const,
+// properties, required, additionalProperties, items, minimum, maximum,
+// minLength, maxLength, pattern, minItems and maxItems. Other keywords are
+// advertised to clients but ignored when validating.
+type JSONSchema map[string]interface{}
+
+// UnmarshalYAML decodes a schema from YAML, making sure nested schemas
+// are decoded as plain maps (as encoding/json would do)
+func (s *JSONSchema) UnmarshalYAML(value *yaml.Node) error {
+	var m map[string]interface{}
+	if err := value.Decode(&m); err != nil {
+		return err
+	}
+	*s = m
+	return nil
+}
+
+// jsonSchemaTypes are the valid values for the "type" keyword
+var jsonSchemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Check verifies the schema is well formed (as far as the supported keywords are concerned).
+func (s JSONSchema) Check() error {
+	return checkJSONSchema(s, "")
+}
+
+// Validate validates a value (as decoded by encoding/json) against the schema.
+//
+// Returns:
+//   - nil if the value conforms to the schema
+//   - An error describing the first violation found otherwise
+func (s JSONSchema) Validate(value interface{}) error {
+	return validateJSONSchema(s, value, "$")
+}
+
+// ValidateJSON parses a JSON document and validates it against the schema.
+func (s JSONSchema) ValidateJSON(data string) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return fmt.Errorf("output is not valid JSON: %w", err)
+	}
+	return s.Validate(value)
+}
+
+// String returns the schema as an indented JSON document
+func (s JSONSchema) String() string {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", map[string]interface{}(s))
+	}
+	return string(data)
+}
+
+// checkJSONSchema checks a (sub)schema, where path is used for error messages
+func checkJSONSchema(schema map[string]interface{}, path string) error {
+	where := func() string {
+		if path == "" {
+			return "schema"
+		}
+		return fmt.Sprintf("schema at %s", path)
+	}
+
+	if t, ok := schema["type"]; ok {
+		for _, name := range schemaTypes(t) {
+			if !containsString(jsonSchemaTypes, name) {
+				return fmt.Errorf("%s: unknown type '%s'", where(), name)
+			}
+		}
+		if len(schemaTypes(t)) == 0 {
+			return fmt.Errorf("%s: invalid type %v", where(), t)
+		}
+	}
+
+	if pattern, ok := schema["pattern"]; ok {
+		str, ok := pattern.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", where())
+		}
+		if _, err := regexp.Compile(str); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", where(), err)
+		}
+	}
+
+	if props, ok := schema["properties"]; ok {
+		propsMap, ok := props.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", where())
+		}
+		for name, prop := range propsMap {
+			propSchema, ok := prop.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: property '%s' must be an object", where(), name)
+			}
+			if err := checkJSONSchema(propSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		itemsSchema, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: items must be an object", where())
+		}
+		if err := checkJSONSchema(itemsSchema, path+"[]"); err != nil {
+			return err
+		}
+	}
+
+	if required, ok := schema["required"]; ok {
+		if _, ok := required.([]interface{}); !ok {
+			return fmt.Errorf("%s: required must be a list", where())
+		}
+	}
+
+	return nil
+}
+
+// validateJSONSchema validates a value against a (sub)schema, where path
+// is the location of the value in the document
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		matches := false
+		for _, name := range types {
+			if matchesSchemaType(name, value) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		return fmt.Errorf("%s: value %v is not %v", path, value, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateJSONObject(schema, v, path)
+
+	case []interface{}:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, min, len(v))
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, max, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+			return fmt.Errorf("%s: expected at least %v characters, got %v", path, min, length)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+			return fmt.Errorf("%s: expected at most %v characters, got %v", path, max, length)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern in schema: %w", path, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: value %q does not match pattern %q", path, v, pattern)
+			}
+		}
+
+	case float64:
+		if min, ok := schemaNumber(schema["minimum"]); ok && v < min {
+			return fmt.Errorf("%s: value %v is less than the minimum %v", path, v, min)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && v > max {
+			return fmt.Errorf("%s: value %v is greater than the maximum %v", path, v, max)
+		}
+	}
+
+	return nil
+}
+
+// validateJSONObject validates the properties of an object
+func validateJSONObject(schema map[string]interface{}, obj map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name := fmt.Sprintf("%v", r)
+			if _, exists := obj[name]; !exists {
+				return fmt.Errorf("%s: missing required property '%s'", path, name)
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+
+	// validate in a stable order, so errors are reproducible
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propSchema, ok := props[name].(map[string]interface{})
+		if !ok {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property '%s'", path, name)
+			}
+			if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				propSchema = additional
+			} else {
+				continue
+			}
+		}
+		if err := validateJSONSchema(propSchema, obj[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes returns the types in a "type" keyword (a string or a list of strings)
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var res []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// matchesSchemaType checks if a JSON value is of a schema type
+func matchesSchemaType(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON type name of a value
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaNumber converts a number in a schema (decoded from YAML or JSON) to a float64
+func schemaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// jsonEqual compares a value in a schema (decoded from YAML or JSON) with a JSON value
+func jsonEqual(schemaValue interface{}, value interface{}) bool {
+	if n, ok := schemaNumber(schemaValue); ok {
+		f, ok := value.(float64)
+		return ok && f == n
+	}
+	a, errA := json.Marshal(schemaValue)
+	b, errB := json.Marshal(value)
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+// containsString checks if a slice contains a string
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		expectError bool
+	}{
+		{"simple type", "type: string", false},
+		{"list of types", "type: [string, 'null']", false},
+		{"nested properties", "type: object\nproperties:\n  items:\n    type: array\n    items:\n      type: integer", false},
+		{"unknown type", "type: text", true},
+		{"unknown nested type", "type: object\nproperties:\n  name:\n    type: text", true},
+		{"invalid pattern", "type: string\npattern: '[a-'", true},
+		{"invalid required", "type: object\nrequired: name", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema JSONSchema
+			if err := yaml.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatalf("Failed to parse schema: %v", err)
+			}
+			err := schema.Check()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Check() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaValidateJSON(t *testing.T) {
+	// schemas usually come from YAML configuration files
+	var schema JSONSchema
+	err := yaml.Unmarshal([]byte(`
+type: object
+required: [name, pods]
+additionalProperties: false
+properties:
+  name:
+    type: string
+    pattern: "^[a-z-]+$"
+  status:
+    enum: [running, stopped]
+  pods:
+    type: array
+    maxItems: 2
+    items:
+      type: object
+      properties:
+        restarts:
+          type: integer
+          minimum: 0
+`), &schema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		data        string
+		expectError bool
+	}{
+		{"valid", `{"name": "web", "status": "running", "pods": [{"restarts": 0}, {"restarts": 3}]}`, false},
+		{"missing required", `{"name": "web"}`, true},
+		{"unexpected property", `{"name": "web", "pods": [], "extra": 1}`, true},
+		{"pattern mismatch", `{"name": "Web", "pods": []}`, true},
+		{"not in enum", `{"name": "web", "status": "unknown", "pods": []}`, true},
+		{"too many items", `{"name": "web", "pods": [{}, {}, {}]}`, true},
+		{"not an integer", `{"name": "web", "pods": [{"restarts": 1.5}]}`, true},
+		{"below minimum", `{"name": "web", "pods": [{"restarts": -1}]}`, true},
+		{"wrong type", `["web"]`, true},
+		{"invalid JSON", `name: web`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateJSON(tt.data)
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateJSON() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
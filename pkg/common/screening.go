@@ -0,0 +1,94 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The modes of the screening of the outputs
+const (
+	// ScreenFlag returns the suspicious outputs with a warning
+	ScreenFlag = "flag"
+
+	// ScreenQuarantine withholds the suspicious outputs from the model, returning them
+	// only for the user
+	ScreenQuarantine = "quarantine"
+)
+
+// ScreeningFinding is some suspicious content found in an output
+type ScreeningFinding struct {
+	Rule  string `json:"rule"`  // the name of the rule matching the content
+	Match string `json:"match"` // the content matched (truncated)
+}
+
+// screeningRule is a rule for detecting prompt injections in the outputs
+type screeningRule struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+// screeningRules are the rules for detecting the usual prompt injections: instructions
+// for the model, links sending data to other sites and hidden characters
+var screeningRules = []screeningRule{
+	{
+		name: "instruction_override",
+		regex: regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+|the\s+|your\s+)*` +
+			`(previous|prior|above|earlier|preceding|system|original)\s+(instructions|directions|prompts?|rules|context)`),
+	},
+	{
+		name:  "new_instructions",
+		regex: regexp.MustCompile(`(?i)(\bnew\s+instructions\s*:|\bsystem\s+prompt\s*:|<\s*/?\s*system\s*>|\[\s*(system|inst)\s*\])`),
+	},
+	{
+		name:  "role_change",
+		regex: regexp.MustCompile(`(?i)\b(you\s+are\s+now|from\s+now\s+on,?\s+you|act\s+as\s+if\s+you\s+are|pretend\s+(to\s+be|you\s+are))\b`),
+	},
+	{
+		name:  "hidden_request",
+		regex: regexp.MustCompile(`(?i)\b(do\s+not|don't|never)\s+(tell|inform|mention|show|reveal)(\s+this)?\s+(to\s+)?(the\s+)?user\b`),
+	},
+	{
+		// images (loaded automatically by the clients) with parameters, or links with long parameters
+		name: "markdown_exfiltration",
+		regex: regexp.MustCompile(`!\[[^\]]*\]\(\s*https?://[^\s)]*\?[^\s)]*=[^\s)]*\)|` +
+			`\[[^\]]*\]\(\s*https?://[^\s)]*\?([^\s)]*&)?[^\s)=&]+=[^\s)&]{20,}[^\s)]*\)`),
+	},
+	{
+		name:  "invisible_characters",
+		regex: regexp.MustCompile(`[\x{E0000}-\x{E007F}\x{200B}-\x{200F}\x{202A}-\x{202E}\x{2066}-\x{2069}]+`),
+	},
+}
+
+// screeningMatchSize is the maximum size of the matches in the findings
+const screeningMatchSize = 80
+
+// CheckScreenMode checks the mode of the screening of an output is valid
+func CheckScreenMode(mode string) error {
+	switch mode {
+	case "", ScreenFlag, ScreenQuarantine:
+		return nil
+	}
+	return fmt.Errorf("invalid output screening '%s': it must be '%s' or '%s'", mode, ScreenFlag, ScreenQuarantine)
+}
+
+// ScreenOutput looks for prompt injections in the output of a tool (ie, instructions for
+// the model or links sending data to other sites), returning the suspicious content found
+// (with a finding per rule)
+func ScreenOutput(output string) []ScreeningFinding {
+	var res []ScreeningFinding
+	for _, rule := range screeningRules {
+		match := rule.regex.FindString(output)
+		if match == "" {
+			continue
+		}
+		if rule.name == "invisible_characters" {
+			match = fmt.Sprintf("%q", match)
+		}
+		if len(match) > screeningMatchSize {
+			match = strings.ToValidUTF8(match[:screeningMatchSize], "") + "..."
+		}
+		res = append(res, ScreeningFinding{Rule: rule.name, Match: match})
+	}
+	return res
+}
@@ -0,0 +1,387 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret providers
+const (
+	// SecretProviderEnv reads secrets from environment variables
+	SecretProviderEnv = "env"
+	// SecretProviderFile reads secrets from files
+	SecretProviderFile = "file"
+	// SecretProviderVault reads secrets from HashiCorp Vault
+	SecretProviderVault = "vault"
+	// SecretProviderAWS reads secrets from AWS Secrets Manager
+	SecretProviderAWS = "aws"
+	// SecretProviderKeyring reads secrets from the OS keyring
+	SecretProviderKeyring = "keyring"
+)
+
+// KeyringScheme is the scheme of keyring references (ie, keyring://service/account)
+const KeyringScheme = "keyring://"
+
+// SecretsTimeout is the timeout for obtaining a secret from a remote provider
+const SecretsTimeout = 30 * time.Second
+
+// SecretsParam is the name of the template variable holding the secrets values
+const SecretsParam = "Secrets"
+
+// secretNameRegex is the regular expression secret names must match,
+// so they can be used in templates as {{ .Secrets.NAME }}
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretRefRegex finds the references to secrets in templates
+var secretRefRegex = regexp.MustCompile(`\.` + SecretsParam + `\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// SecretConfig represents a secret obtained from some provider.
+type SecretConfig struct {
+	// Name is the name used for referencing the secret in templates
+	Name string `yaml:"name"`
+
+	// Provider is the secrets provider: "env", "file", "vault", "aws" or "keyring".
+	// It can be omitted when the path is a keyring://service/account reference.
+	Provider string `yaml:"provider,omitempty"`
+
+	// Path is the location of the secret: the file path for the "file" provider,
+	// the secret path for "vault", the secret ID (or ARN) for "aws" and
+	// the keyring://service/account reference for "keyring"
+	Path string `yaml:"path,omitempty"`
+
+	// Key is the environment variable for the "env" provider (defaults to the name).
+	// For the other providers, it is the field to extract from the (JSON) secret.
+	Key string `yaml:"key,omitempty"`
+
+	// Address is the Vault server address (defaults to $VAULT_ADDR)
+	Address string `yaml:"address,omitempty"`
+
+	// Region is the AWS region (defaults to the AWS CLI configuration)
+	Region string `yaml:"region,omitempty"`
+}
+
+// Validate checks the secret configuration is valid
+func (c SecretConfig) Validate() error {
+	if !secretNameRegex.MatchString(c.Name) {
+		return fmt.Errorf("invalid secret name '%s': only letters, digits and underscores are allowed", c.Name)
+	}
+
+	switch c.Provider {
+	case SecretProviderEnv:
+	case SecretProviderKeyring:
+		if _, _, err := parseKeyringReference(c.Path); err != nil {
+			return fmt.Errorf("secret '%s': %w", c.Name, err)
+		}
+	case SecretProviderFile, SecretProviderAWS:
+		if c.Path == "" {
+			return fmt.Errorf("secret '%s': a path is required for the '%s' provider", c.Name, c.Provider)
+		}
+	case SecretProviderVault:
+		if c.Path == "" || c.Key == "" {
+			return fmt.Errorf("secret '%s': a path and a key are required for the '%s' provider", c.Name, c.Provider)
+		}
+	default:
+		return fmt.Errorf("secret '%s': unknown provider '%s'", c.Name, c.Provider)
+	}
+
+	return nil
+}
+
+// Secrets resolves secrets from their providers, caching the values obtained.
+// All the values resolved are registered for being redacted from logs and outputs.
+type Secrets struct {
+	configs map[string]SecretConfig
+	values  map[string]string
+	mu      sync.Mutex
+}
+
+// NewSecrets creates a new Secrets from a list of secret configurations.
+//
+// Parameters:
+//   - configs: The secrets configurations
+//
+// Returns:
+//   - A new Secrets instance
+//   - An error if some configuration is invalid or a name is duplicated
+func NewSecrets(configs []SecretConfig) (*Secrets, error) {
+	s := &Secrets{
+		configs: map[string]SecretConfig{},
+		values:  map[string]string{},
+	}
+
+	for _, c := range configs {
+		if c.Provider == "" && strings.HasPrefix(c.Path, KeyringScheme) {
+			c.Provider = SecretProviderKeyring
+		}
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := s.configs[c.Name]; exists {
+			return nil, fmt.Errorf("duplicate secret '%s'", c.Name)
+		}
+		s.configs[c.Name] = c
+	}
+
+	return s, nil
+}
+
+// Names returns the (sorted) names of all the secrets
+func (s *Secrets) Names() []string {
+	names := make([]string, 0, len(s.configs))
+	for name := range s.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the value of a secret, obtaining it from its provider
+// the first time it is requested.
+func (s *Secrets) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, exists := s.values[name]; exists {
+		return value, nil
+	}
+
+	c, exists := s.configs[name]
+	if !exists {
+		return "", fmt.Errorf("unknown secret '%s'", name)
+	}
+
+	value, err := resolveSecret(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain secret '%s' from '%s': %w", name, c.Provider, err)
+	}
+
+	s.values[name] = value
+	AddRedactedValue(value)
+
+	return value, nil
+}
+
+// Resolve obtains the values of all the secrets referenced in some templates,
+// returning them as a map suitable for {{ .Secrets.NAME }} references.
+func (s *Secrets) Resolve(ctx context.Context, templates ...string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, name := range SecretReferences(templates...) {
+		value, err := s.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = value
+	}
+	return res, nil
+}
+
+// SecretReferences returns the (sorted, unique) names of the secrets
+// referenced as {{ .Secrets.NAME }} in some templates.
+func SecretReferences(templates ...string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, t := range templates {
+		for _, match := range secretRefRegex.FindAllStringSubmatch(t, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveSecret obtains the value of a secret from its provider
+func resolveSecret(ctx context.Context, c SecretConfig) (string, error) {
+	switch c.Provider {
+	case SecretProviderEnv:
+		key := c.Key
+		if key == "" {
+			key = c.Name
+		}
+		value, exists := os.LookupEnv(key)
+		if !exists {
+			return "", fmt.Errorf("environment variable %s is not set", key)
+		}
+		return value, nil
+
+	case SecretProviderFile:
+		data, err := os.ReadFile(c.Path)
+		if err != nil {
+			return "", err
+		}
+		return extractSecretKey(strings.TrimRight(string(data), "\r\n"), c.Key)
+
+	case SecretProviderVault:
+		return resolveVaultSecret(ctx, c)
+
+	case SecretProviderAWS:
+		return resolveAWSSecret(ctx, c)
+
+	case SecretProviderKeyring:
+		service, account, err := parseKeyringReference(c.Path)
+		if err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, SecretsTimeout)
+		defer cancel()
+		value, err := readKeyring(ctx, service, account)
+		if err != nil {
+			return "", err
+		}
+		return extractSecretKey(value, c.Key)
+	}
+
+	return "", fmt.Errorf("unknown provider '%s'", c.Provider)
+}
+
+// extractSecretKey extracts a field from a secret stored as a JSON object.
+// When no key is provided, the secret is returned as it is.
+func extractSecretKey(secret string, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot extract key '%s'", key)
+	}
+
+	value, exists := fields[key]
+	if !exists {
+		return "", fmt.Errorf("key '%s' not found in secret", key)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// resolveVaultSecret reads a secret from HashiCorp Vault, using the token in
+// $VAULT_TOKEN (or ~/.vault-token). Both KV v1 and v2 secret engines are supported.
+func resolveVaultSecret(ctx context.Context, c SecretConfig) (string, error) {
+	address := c.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return "", fmt.Errorf("no Vault address provided and VAULT_ADDR is not set")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("no Vault token found in VAULT_TOKEN or ~/.vault-token")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SecretsTimeout)
+	defer cancel()
+
+	url := strings.TrimRight(address, "/") + "/v1/" + strings.TrimLeft(c.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Vault request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("Vault request returned non-success status: %s", resp.Status)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse Vault response: %w", err)
+	}
+
+	// KV v2 engines nest the secret data in another "data" field
+	data := response.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, isMetadata := data["metadata"]; isMetadata {
+			data = nested
+		}
+	}
+
+	value, exists := data[c.Key]
+	if !exists {
+		return "", fmt.Errorf("key '%s' not found in Vault secret %s", c.Key, c.Path)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// resolveAWSSecret reads a secret from AWS Secrets Manager using the AWS CLI,
+// so the usual AWS credentials and profiles configuration is honored.
+func resolveAWSSecret(ctx context.Context, c SecretConfig) (string, error) {
+	if !CheckExecutableExists("aws") {
+		return "", fmt.Errorf("the AWS CLI (aws) is required for the '%s' provider", SecretProviderAWS)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SecretsTimeout)
+	defer cancel()
+
+	args := []string{"secretsmanager", "get-secret-value",
+		"--secret-id", c.Path, "--query", "SecretString", "--output", "text"}
+	if c.Region != "" {
+		args = append(args, "--region", c.Region)
+	}
+
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+
+	return extractSecretKey(strings.TrimRight(string(out), "\r\n"), c.Key)
+}
+
+// parseKeyringReference parses a keyring reference, returning the service and account.
+// References can be "keyring://service/account" or just "service/account".
+func parseKeyringReference(ref string) (string, string, error) {
+	service, account, found := strings.Cut(strings.TrimPrefix(ref, KeyringScheme), "/")
+	if !found || service == "" || account == "" {
+		return "", "", fmt.Errorf("invalid keyring reference '%s': expected %sservice/account", ref, KeyringScheme)
+	}
+	return service, account, nil
+}
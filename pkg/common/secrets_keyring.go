@@ -0,0 +1,43 @@
+//go:build !windows
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// readKeyring reads a password from the OS keyring: the Keychain in macOS
+// (with the "security" tool) or the Secret Service (ie, GNOME Keyring or KWallet)
+// in Linux (with the "secret-tool" tool, from libsecret).
+func readKeyring(ctx context.Context, service string, account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	default:
+		if !CheckExecutableExists("secret-tool") {
+			return "", fmt.Errorf("secret-tool (from libsecret) is required for accessing the keyring")
+		}
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", fmt.Errorf("secret not found in keyring: %w", err)
+	}
+
+	password := strings.TrimRight(string(out), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("secret not found in keyring")
+	}
+	return password, nil
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32     = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead = advapi32.NewProc("CredReadW")
+	procCredFree = advapi32.NewProc("CredFree")
+)
+
+// credTypeGeneric is the CRED_TYPE_GENERIC credentials type
+const credTypeGeneric = 1
+
+// credential is the CREDENTIALW structure returned by CredReadW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// readKeyring reads a password from the Windows Credential Manager, looking
+// for a generic credential with the "service:account" target name.
+func readKeyring(_ context.Context, service string, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		return "", fmt.Errorf("secret not found in Credential Manager: %w", err)
+	}
+	defer func() {
+		_, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	}()
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+
+	// credentials can be stored as UTF-16 (ie, by cmdkey) or UTF-8 strings
+	if isUTF16(blob) {
+		utf16 := unsafe.Slice((*uint16)(unsafe.Pointer(cred.CredentialBlob)), len(blob)/2)
+		return syscall.UTF16ToString(utf16), nil
+	}
+	return string(blob), nil
+}
+
+// isUTF16 guesses if some bytes are a UTF-16 (little endian) string,
+// checking for the zero high bytes of ASCII characters
+func isUTF16(blob []byte) bool {
+	if len(blob)%2 != 0 {
+		return false
+	}
+	for i := 1; i < len(blob); i += 2 {
+		if blob[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
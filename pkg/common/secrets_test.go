@@ -0,0 +1,174 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		configs     []SecretConfig
+		expectError bool
+	}{
+		{"env secret", []SecretConfig{{Name: "token", Provider: "env"}}, false},
+		{"file secret", []SecretConfig{{Name: "token", Provider: "file", Path: "/tmp/token"}}, false},
+		{"vault secret", []SecretConfig{{Name: "token", Provider: "vault", Path: "secret/data/app", Key: "token"}}, false},
+		{"aws secret", []SecretConfig{{Name: "token", Provider: "aws", Path: "prod/token"}}, false},
+		{"keyring secret", []SecretConfig{{Name: "token", Provider: "keyring", Path: "gh/user"}}, false},
+		{"keyring reference", []SecretConfig{{Name: "token", Path: "keyring://gh/user"}}, false},
+		{"invalid keyring reference", []SecretConfig{{Name: "token", Path: "keyring://gh"}}, true},
+		{"missing provider", []SecretConfig{{Name: "token", Path: "/tmp/token"}}, true},
+		{"invalid name", []SecretConfig{{Name: "my-token", Provider: "env"}}, true},
+		{"unknown provider", []SecretConfig{{Name: "token", Provider: "unknown"}}, true},
+		{"file without path", []SecretConfig{{Name: "token", Provider: "file"}}, true},
+		{"vault without key", []SecretConfig{{Name: "token", Provider: "vault", Path: "secret/data/app"}}, true},
+		{"duplicate name", []SecretConfig{{Name: "token", Provider: "env"}, {Name: "token", Provider: "env"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSecrets(tt.configs)
+			if (err != nil) != tt.expectError {
+				t.Errorf("NewSecrets() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestSecretsGet(t *testing.T) {
+	dir := t.TempDir()
+	plainFile := filepath.Join(dir, "plain")
+	jsonFile := filepath.Join(dir, "json")
+	if err := os.WriteFile(plainFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonFile, []byte(`{"user": "admin", "password": "json-secret"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "vault-v2-secret"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data": {"password": "vault-v1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	t.Setenv("TEST_SECRET_VAR", "env-secret")
+	t.Setenv("VAULT_TOKEN", "vault-token")
+
+	secrets, err := NewSecrets([]SecretConfig{
+		{Name: "env_default", Provider: "env", Key: "TEST_SECRET_VAR"},
+		{Name: "env_missing", Provider: "env", Key: "TEST_SECRET_VAR_MISSING"},
+		{Name: "plain_file", Provider: "file", Path: plainFile},
+		{Name: "json_file", Provider: "file", Path: jsonFile, Key: "password"},
+		{Name: "json_file_missing", Provider: "file", Path: jsonFile, Key: "missing"},
+		{Name: "vault_v2", Provider: "vault", Address: vault.URL, Path: "secret/data/app", Key: "password"},
+		{Name: "vault_v1", Provider: "vault", Address: vault.URL, Path: "kv/app", Key: "password"},
+		{Name: "vault_missing", Provider: "vault", Address: vault.URL, Path: "kv/missing", Key: "password"},
+	})
+	if err != nil {
+		t.Fatalf("NewSecrets() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		expected    string
+		expectError bool
+	}{
+		{"env_default", "env-secret", false},
+		{"env_missing", "", true},
+		{"plain_file", "file-secret", false},
+		{"json_file", "json-secret", false},
+		{"json_file_missing", "", true},
+		{"vault_v2", "vault-v2-secret", false},
+		{"vault_v1", "vault-v1-secret", false},
+		{"vault_missing", "", true},
+		{"unknown", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := secrets.Get(context.Background(), tt.name)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Get() error = %v, expectError %v", err, tt.expectError)
+			}
+			if value != tt.expected {
+				t.Errorf("Get() = %q, expected %q", value, tt.expected)
+			}
+			if !tt.expectError && strings.Contains(Redact("value: "+value), value) {
+				t.Errorf("Secret %q was not registered for redaction", tt.name)
+			}
+		})
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	names := SecretReferences(
+		"curl -H 'Authorization: {{ .Secrets.api_token }}' {{ .url }}",
+		"DB_PASSWORD={{ .Secrets.db_password }}",
+		"echo {{.Secrets.api_token}}",
+	)
+	expected := []string{"api_token", "db_password"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("SecretReferences() = %v, expected %v", names, expected)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	AddRedactedValue("super-secret-value")
+	AddRedactedValue("  ")
+
+	got := Redact("the token is super-secret-value, right?")
+	expected := "the token is " + RedactedText + ", right?"
+	if got != expected {
+		t.Errorf("Redact() = %q, expected %q", got, expected)
+	}
+
+	if got := Redact("nothing to hide here"); got != "nothing to hide here" {
+		t.Errorf("Redact() modified a text without secrets: %q", got)
+	}
+}
+
+func TestParseKeyringReference(t *testing.T) {
+	tests := []struct {
+		ref         string
+		service     string
+		account     string
+		expectError bool
+	}{
+		{"keyring://github/user", "github", "user", false},
+		{"github/user", "github", "user", false},
+		{"keyring://my-service/user@example.com", "my-service", "user@example.com", false},
+		{"keyring://github", "", "", true},
+		{"keyring:///user", "", "", true},
+		{"keyring://github/", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			service, account, err := parseKeyringReference(tt.ref)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseKeyringReference() error = %v, expectError %v", err, tt.expectError)
+			}
+			if service != tt.service || account != tt.account {
+				t.Errorf("parseKeyringReference() = (%q, %q), expected (%q, %q)", service, account, tt.service, tt.account)
+			}
+		})
+	}
+}
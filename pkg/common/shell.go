@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ShellKind is the family of a shell, deciding how commands are passed
+// to it and how arguments are quoted
+type ShellKind string
+
+const (
+	// ShellPOSIX is a POSIX-like shell (sh, bash, zsh...)
+	ShellPOSIX ShellKind = "posix"
+
+	// ShellCmd is the Windows command interpreter (cmd.exe)
+	ShellCmd ShellKind = "cmd"
+
+	// ShellPowerShell is Windows PowerShell (powershell.exe) or PowerShell Core (pwsh)
+	ShellPowerShell ShellKind = "powershell"
+)
+
+// GetShellKind returns the family of a shell, from its name or path
+// (ie, "/bin/bash", "cmd.exe" or "C:\Program Files\PowerShell\7\pwsh.exe")
+func GetShellKind(shell string) ShellKind {
+	// paths can use any separator, whatever the platform we are running on
+	base := shell[strings.LastIndexAny(shell, `/\`)+1:]
+	base = strings.TrimSuffix(strings.ToLower(base), ".exe")
+
+	switch base {
+	case "cmd":
+		return ShellCmd
+	case "powershell", "pwsh":
+		return ShellPowerShell
+	default:
+		return ShellPOSIX
+	}
+}
+
+// DefaultShell returns the shell used when none is configured: the SHELL
+// environment variable, the command interpreter (ComSpec) in Windows,
+// and /bin/sh as a last resort.
+func DefaultShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("ComSpec"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+
+	return "/bin/sh"
+}
+
+// RunShellCommand runs a command in the default shell, returning its output. The errors
+// include the error output of the command (if any).
+func RunShellCommand(command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shell := DefaultShell()
+	var cmd *exec.Cmd
+	switch GetShellKind(shell) {
+	case ShellCmd:
+		cmd = exec.CommandContext(ctx, shell, "/C", command)
+	case ShellPowerShell:
+		cmd = exec.CommandContext(ctx, shell, "-NoProfile", "-NonInteractive", "-Command", command)
+	default:
+		cmd = exec.CommandContext(ctx, shell, "-c", command)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// QuoteShellArg quotes a string so a shell of some kind passes it as a single,
+// literal argument to the commands (ie, without expanding variables or globs)
+func QuoteShellArg(kind ShellKind, arg string) string {
+	switch kind {
+	case ShellPowerShell:
+		// single-quoted strings are literal, with the quotes doubled (including the typographic ones)
+		var sb strings.Builder
+		sb.WriteByte('\'')
+		for _, r := range arg {
+			if r == '\'' || r == '\u2018' || r == '\u2019' || r == '\u201a' || r == '\u201b' {
+				sb.WriteRune(r)
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('\'')
+		return sb.String()
+
+	case ShellCmd:
+		// quote the argument for the program (as parsed by CommandLineToArgvW), and
+		// then escape all the characters that cmd.exe would interpret (including the quotes)
+		quoted := quoteWindowsArg(arg)
+		var sb strings.Builder
+		for _, r := range quoted {
+			if strings.ContainsRune(`()%!^"<>&|`, r) {
+				sb.WriteByte('^')
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+
+	default:
+		return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+}
+
+// quoteWindowsArg quotes an argument following the rules of CommandLineToArgvW
+func quoteWindowsArg(arg string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	backslashes := 0
+	for _, r := range arg {
+		switch r {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			// the backslashes before a quote (and the quote itself) must be escaped
+			sb.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			sb.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		sb.WriteRune(r)
+	}
+	// the backslashes before the closing quote must be escaped
+	sb.WriteString(strings.Repeat(`\`, 2*backslashes))
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// QuoteShellPath quotes a path for running it as a command in the default shell
+// of the current platform
+func QuoteShellPath(path string) string {
+	if runtime.GOOS == "windows" {
+		return quoteWindowsArg(filepath.FromSlash(path))
+	}
+	return QuoteShellArg(ShellPOSIX, path)
+}
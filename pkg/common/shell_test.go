@@ -0,0 +1,75 @@
+package common
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+func TestGetShellKind(t *testing.T) {
+	tests := []struct {
+		shell    string
+		expected ShellKind
+	}{
+		{"/bin/sh", ShellPOSIX},
+		{"/usr/bin/bash", ShellPOSIX},
+		{"zsh", ShellPOSIX},
+		{"cmd", ShellCmd},
+		{"cmd.exe", ShellCmd},
+		{`C:\Windows\System32\CMD.EXE`, ShellCmd},
+		{"powershell.exe", ShellPowerShell},
+		{`C:\Program Files\PowerShell\7\pwsh.exe`, ShellPowerShell},
+		{"/usr/local/bin/pwsh", ShellPowerShell},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			if got := GetShellKind(tt.shell); got != tt.expected {
+				t.Errorf("GetShellKind(%q) = %q, expected %q", tt.shell, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteShellArg(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     ShellKind
+		arg      string
+		expected string
+	}{
+		{"posix simple", ShellPOSIX, "hello world", "'hello world'"},
+		{"posix quotes", ShellPOSIX, "it's $HOME", `'it'\''s $HOME'`},
+		{"powershell simple", ShellPowerShell, "hello $env:PATH", "'hello $env:PATH'"},
+		{"powershell quotes", ShellPowerShell, "it's", "'it''s'"},
+		{"powershell typographic quotes", ShellPowerShell, "it\u2019s", "'it\u2019\u2019s'"},
+		{"cmd simple", ShellCmd, "hello world", `^"hello world^"`},
+		{"cmd metacharacters", ShellCmd, "a & b | %PATH%", `^"a ^& b ^| ^%PATH^%^"`},
+		{"cmd quotes and backslashes", ShellCmd, `C:\dir\ "x"`, `^"C:\dir\ \^"x\^"^"`},
+		{"cmd trailing backslash", ShellCmd, `C:\dir\`, `^"C:\dir\\^"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuoteShellArg(tt.kind, tt.arg); got != tt.expected {
+				t.Errorf("QuoteShellArg(%q, %q) = %q, expected %q", tt.kind, tt.arg, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteShellArg_POSIXRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX shell in Windows")
+	}
+
+	for _, arg := range []string{"simple", "with spaces", "it's", `$HOME "quoted" $(ls) ; rm -rf /`, "*", ""} {
+		out, err := exec.Command("/bin/sh", "-c", "printf %s "+QuoteShellArg(ShellPOSIX, arg)).Output()
+		if err != nil {
+			t.Fatalf("Failed to run the shell: %v", err)
+		}
+		if string(out) != arg {
+			t.Errorf("Expected %q, got %q", arg, string(out))
+		}
+	}
+}
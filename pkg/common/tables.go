@@ -0,0 +1,251 @@
+package common
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The converters of the output of the commands
+const (
+	ConvertTableToMarkdown = "table-to-markdown" // columns separated by whitespace, to a markdown table
+	ConvertTableToJSON     = "table-to-json"     // columns separated by whitespace, to a JSON array
+	ConvertCSVToMarkdown   = "csv-to-markdown"   // CSV, to a markdown table
+	ConvertCSVToJSON       = "csv-to-json"       // CSV, to a JSON array
+)
+
+// OutputConverters are the valid output converters
+var OutputConverters = []string{ConvertTableToMarkdown, ConvertTableToJSON, ConvertCSVToMarkdown, ConvertCSVToJSON}
+
+// CheckOutputConverter checks the name of an output converter is valid (or empty)
+func CheckOutputConverter(convert string) error {
+	if convert == "" {
+		return nil
+	}
+	for _, c := range OutputConverters {
+		if c == convert {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output converter '%s' (valid converters: %s)", convert, strings.Join(OutputConverters, ", "))
+}
+
+// ConvertOutput converts the tabular output of a command with a converter.
+// The first line (or record) of the output is the header of the table.
+//
+// Parameters:
+//   - convert: The converter (ie, "table-to-markdown")
+//   - output: The output of the command
+//
+// Returns:
+//   - The converted output
+//   - An error if the output cannot be parsed
+func ConvertOutput(convert string, output string) (string, error) {
+	var header []string
+	var rows [][]string
+	var err error
+
+	switch convert {
+	case ConvertTableToMarkdown, ConvertTableToJSON:
+		header, rows = ParseTable(output)
+	case ConvertCSVToMarkdown, ConvertCSVToJSON:
+		header, rows, err = ParseCSV(output)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", CheckOutputConverter(convert)
+	}
+
+	if strings.HasSuffix(convert, "-to-json") {
+		return FormatJSONTable(header, rows)
+	}
+	return FormatMarkdownTable(header, rows), nil
+}
+
+// ParseTable parses a table with columns aligned with whitespace (like the output of
+// "ps", "df" or "kubectl get"), returning the header (the first line) and the rows.
+//
+// The columns are found from the positions that are blank in all the lines, so values
+// can be aligned to the left or to the right. Columns without a header (ie, because
+// some values in the last column contain spaces) are merged with the previous one.
+func ParseTable(text string) ([]string, [][]string) {
+	var lines [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "        "), " \r")
+		if line != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	// find the positions that are blank in all the lines
+	blank := make([]bool, width)
+	for i := range blank {
+		blank[i] = true
+		for _, line := range lines {
+			if i < len(line) && line[i] != ' ' {
+				blank[i] = false
+				break
+			}
+		}
+	}
+
+	// the columns are the runs of non-blank positions
+	type column struct{ start, end int }
+	var columns []column
+	for i := 0; i < width; i++ {
+		if blank[i] {
+			continue
+		}
+		start := i
+		for i < width && !blank[i] {
+			i++
+		}
+		columns = append(columns, column{start, i})
+	}
+
+	slice := func(line []rune, c column) string {
+		if c.start >= len(line) {
+			return ""
+		}
+		return strings.TrimSpace(string(line[c.start:min(c.end, len(line))]))
+	}
+
+	// merge the columns without a header with the previous one
+	var merged []column
+	for _, c := range columns {
+		if len(merged) > 0 && slice(lines[0], c) == "" {
+			merged[len(merged)-1].end = c.end
+			continue
+		}
+		merged = append(merged, c)
+	}
+
+	header := make([]string, len(merged))
+	for i, c := range merged {
+		header[i] = slice(lines[0], c)
+	}
+
+	rows := make([][]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		row := make([]string, len(merged))
+		for i, c := range merged {
+			row[i] = slice(line, c)
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows
+}
+
+// ParseCSV parses a CSV text, returning the header (the first record) and the rows
+func ParseCSV(text string) ([]string, [][]string, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV output: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+	return records[0], records[1:], nil
+}
+
+// FormatMarkdownTable formats a table in markdown
+func FormatMarkdownTable(header []string, rows [][]string) string {
+	if len(header) == 0 {
+		return ""
+	}
+
+	escape := func(s string) string {
+		s = strings.ReplaceAll(s, "|", `\|`)
+		return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", " "), "\n", " ")
+	}
+
+	var sb strings.Builder
+	writeRow := func(values []string) {
+		sb.WriteString("|")
+		for i := range header {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			sb.WriteString(" " + escape(value) + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(header)
+	sb.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// FormatJSONTable formats a table as a JSON array of objects, with the values of
+// the header as keys (in the same order). Empty or repeated keys are renamed.
+func FormatJSONTable(header []string, rows [][]string) (string, error) {
+	keys := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, h := range header {
+		base := h
+		if base == "" {
+			base = fmt.Sprintf("column_%d", i+1)
+		}
+		key := base
+		for n := 2; seen[key]; n++ {
+			key = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for r, row := range rows {
+		if r > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for i, key := range keys {
+			value := ""
+			if i < len(row) {
+				value = row[i]
+			}
+			k, err := json.Marshal(key)
+			if err != nil {
+				return "", err
+			}
+			v, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.Write(k)
+			buf.WriteString(": ")
+			buf.Write(v)
+		}
+		buf.WriteString("}")
+	}
+	if len(rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+	return buf.String(), nil
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantHeader []string
+		wantRows   [][]string
+	}{
+		{
+			name: "kubectl",
+			text: `NAME                     READY   STATUS    RESTARTS   AGE
+nginx-7c5ddbdf54-8x2kq   1/1     Running   0          3d
+redis-0                  0/1     Pending   12         5m
+`,
+			wantHeader: []string{"NAME", "READY", "STATUS", "RESTARTS", "AGE"},
+			wantRows: [][]string{
+				{"nginx-7c5ddbdf54-8x2kq", "1/1", "Running", "0", "3d"},
+				{"redis-0", "0/1", "Pending", "12", "5m"},
+			},
+		},
+		{
+			name: "right aligned and empty values",
+			text: `Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        50G   20G   30G  40% /
+tmpfs           7.8G     0  7.8G   0% /dev/shm
+`,
+			wantHeader: []string{"Filesystem", "Size", "Used", "Avail", "Use%", "Mounted on"},
+			wantRows: [][]string{
+				{"/dev/sda1", "50G", "20G", "30G", "40%", "/"},
+				{"tmpfs", "7.8G", "0", "7.8G", "0%", "/dev/shm"},
+			},
+		},
+		{
+			name: "values with spaces in the last column",
+			text: `PID TTY          TIME CMD
+  1 ?        00:00:01 /sbin/init splash
+ 42 pts/0    00:00:00 bash
+`,
+			wantHeader: []string{"PID", "TTY", "TIME", "CMD"},
+			wantRows: [][]string{
+				{"1", "?", "00:00:01", "/sbin/init splash"},
+				{"42", "pts/0", "00:00:00", "bash"},
+			},
+		},
+		{
+			name:       "empty",
+			text:       "\n\n",
+			wantHeader: nil,
+			wantRows:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows := ParseTable(tt.text)
+			if !reflect.DeepEqual(header, tt.wantHeader) {
+				t.Errorf("header = %q, want %q", header, tt.wantHeader)
+			}
+			if len(rows) != len(tt.wantRows) || (len(rows) > 0 && !reflect.DeepEqual(rows, tt.wantRows)) {
+				t.Errorf("rows = %q, want %q", rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestConvertOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "table to markdown",
+			convert: ConvertTableToMarkdown,
+			output:  "NAME   STATUS\nweb    Running\ndb     Failed | exit 1\n",
+			want:    "| NAME | STATUS |\n| --- | --- |\n| web | Running |\n| db | Failed \\| exit 1 |",
+		},
+		{
+			name:    "csv to json",
+			convert: ConvertCSVToJSON,
+			output:  "name,age,name\n\"Smith, John\",42,x\nJane,,\n",
+			want:    "[\n  {\"name\": \"Smith, John\", \"age\": \"42\", \"name_2\": \"x\"},\n  {\"name\": \"Jane\", \"age\": \"\", \"name_2\": \"\"}\n]",
+		},
+		{
+			name:    "csv to markdown with missing fields",
+			convert: ConvertCSVToMarkdown,
+			output:  "a,b\n1\n",
+			want:    "| a | b |\n| --- | --- |\n| 1 |  |",
+		},
+		{
+			name:    "table to json without rows",
+			convert: ConvertTableToJSON,
+			output:  "NAME   STATUS\n",
+			want:    "[]",
+		},
+		{
+			name:    "invalid csv",
+			convert: ConvertCSVToJSON,
+			output:  "a,\"b\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown converter",
+			convert: "xml-to-json",
+			output:  "a",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertOutput(tt.convert, tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ConvertOutput() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if tt.convert == ConvertCSVToJSON || tt.convert == ConvertTableToJSON {
+				var decoded []map[string]string
+				if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+					t.Errorf("Invalid JSON output: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -2,12 +2,54 @@ package common
 
 import (
 	"bytes"
+	"fmt"
+	"slices"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/Masterminds/sprig/v3"
 )
 
+// templateFunctions are the names of the functions available in the templates, in
+// addition to the builtin functions of text/template. They are a curated subset of the
+// sprig library (https://masterminds.github.io/sprig/), so configurations do not depend
+// on the whole library: new functions can be added here, but not removed.
+var templateFunctions = []string{
+	// strings
+	"trim", "trimAll", "trimPrefix", "trimSuffix", "lower", "upper", "title",
+	"replace", "contains", "hasPrefix", "hasSuffix", "repeat", "substr", "trunc",
+	"quote", "squote", "indent", "nindent", "toString",
+	// lists
+	"list", "join", "splitList", "first", "last", "has",
+	// defaults and conditions
+	"default", "empty", "coalesce", "ternary",
+	// encoding
+	"b64enc", "b64dec", "toJson", "toPrettyJson",
+	// regular expressions
+	"regexMatch", "regexFind", "regexFindAll", "regexReplaceAll", "regexReplaceAllLiteral",
+	// numbers
+	"add", "sub", "mul", "div", "mod", "max", "min", "atoi", "int",
+	// environment
+	"env",
+}
+
+// templateFuncs are the functions available in the templates
+var templateFuncs = func() template.FuncMap {
+	all := sprig.TxtFuncMap()
+	funcs := template.FuncMap{}
+	for _, name := range templateFunctions {
+		funcs[name] = all[name]
+	}
+	return funcs
+}()
+
+// TemplateFunctions returns the names of the functions available in the templates
+// (in addition to the builtin functions of text/template)
+func TemplateFunctions() []string {
+	return slices.Clone(templateFunctions)
+}
+
 // ProcessTemplate processes a template with the given arguments.
 // It uses Go's template engine to substitute variables in the template.
 //
@@ -22,7 +64,7 @@ func ProcessTemplate(text string, args map[string]interface{}) (string, error) {
 	// Create a template from the command string
 	tmpl, err := template.New("command").
 		Option("missingkey=zero").
-		Funcs(sprig.FuncMap()).
+		Funcs(templateFuncs).
 		Parse(text)
 	if err != nil {
 		return "", err
@@ -41,6 +83,129 @@ func ProcessTemplate(text string, args map[string]interface{}) (string, error) {
 	return res, nil
 }
 
+// IsConditionalTemplate returns true if a template is wholly conditional: it only has
+// "if", "with" or "range" blocks (and spaces around them), like
+// "{{ if .count }}--max-count={{ .count }}{{ end }}".
+func IsConditionalTemplate(text string) bool {
+	tmpl, err := template.New("command").Funcs(templateFuncs).Parse(text)
+	if err != nil || tmpl.Tree == nil {
+		return false
+	}
+
+	blocks := 0
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.IfNode, *parse.WithNode, *parse.RangeNode:
+			blocks++
+		case *parse.TextNode:
+			if strings.TrimSpace(string(n.Text)) != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return blocks > 0
+}
+
+// ProcessShellTemplate processes the template of a shell command with the given arguments.
+// Besides the functions of ProcessTemplate, the template can use "shellQuote" for quoting
+// a value for the shell, and "raw" for using a value as it is.
+//
+// When quote is enabled, the result of every substitution (ie, "{{ .name }}") is quoted
+// automatically for the shell, so values are always passed as a single, literal argument.
+// Substitutions ending in "raw" (ie, "{{ .flags | raw }}") are not quoted.
+//
+// Parameters:
+//   - text: The template to process
+//   - args: Map of variable names to their values
+//   - kind: The kind of shell that will run the command
+//   - quote: Quote the substitutions automatically
+//
+// Returns:
+//   - The processed template string with substituted variables
+//   - An error if template processing fails
+func ProcessShellTemplate(text string, args map[string]interface{}, kind ShellKind, quote bool) (string, error) {
+	tmpl, err := template.New("command").
+		Option("missingkey=zero").
+		Funcs(templateFuncs).
+		Funcs(template.FuncMap{
+			"shellQuote": func(value interface{}) string {
+				if value == nil {
+					return QuoteShellArg(kind, "")
+				}
+				return QuoteShellArg(kind, fmt.Sprint(value))
+			},
+			"raw": func(value interface{}) interface{} {
+				return value
+			},
+		}).
+		Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	if quote {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				quoteActions(t.Tree, t.Tree.Root)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, args); err != nil {
+		return "", err
+	}
+
+	// fix https://github.com/golang/go/issues/24963
+	res := buf.String()
+	res = strings.ReplaceAll(res, "<no value>", "")
+
+	return res, nil
+}
+
+// quoteActions adds "shellQuote" to the pipelines of all the actions printing
+// some value, unless they are already quoted or they are explicitly "raw"
+func quoteActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			quoteActions(tree, child)
+		}
+
+	case *parse.ActionNode:
+		// variable declarations and assignments do not print anything
+		if n.Pipe == nil || len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if len(last.Args) > 0 {
+			if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "raw" || ident.Ident == "shellQuote") {
+				return
+			}
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier("shellQuote").SetTree(tree).SetPos(n.Pos)},
+		})
+
+	case *parse.IfNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	case *parse.WithNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	}
+}
+
 // ProcessTemplateListFlexible processes a list of templates with the given arguments.
 // It uses Go's template engine to substitute variables in the templates.
 // If the template processing fails, the original text is added to the result list.
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestTemplateFunctionsAreDefined(t *testing.T) {
+	for _, name := range TemplateFunctions() {
+		if templateFuncs[name] == nil {
+			t.Errorf("template function '%s' is not defined", name)
+		}
+	}
+}
+
+func TestProcessTemplateFunctions(t *testing.T) {
+	t.Setenv("MCPSHELL_TEST_VAR", "from-env")
+
+	tests := []struct {
+		name     string
+		template string
+		args     map[string]interface{}
+		want     string
+		wantErr  bool
+	}{
+		{"trim", `{{ .s | trim }}`, map[string]interface{}{"s": "  hello  "}, "hello", false},
+		{"default with missing value", `{{ .s | default "none" }}`, map[string]interface{}{}, "none", false},
+		{"default with value", `{{ .s | default "none" }}`, map[string]interface{}{"s": "some"}, "some", false},
+		{"quote", `{{ .s | quote }}`, map[string]interface{}{"s": `say "hi"`}, `"say \"hi\""`, false},
+		{"join", `{{ .l | join "," }}`, map[string]interface{}{"l": []interface{}{"a", "b", "c"}}, "a,b,c", false},
+		{"b64enc", `{{ .s | b64enc }}`, map[string]interface{}{"s": "hello"}, "aGVsbG8=", false},
+		{"regexReplaceAll", `{{ regexReplaceAll "[^a-z]" .s "" }}`, map[string]interface{}{"s": "a1b2;c"}, "abc", false},
+		{"ternary true", `{{ ternary "yes" "no" .b }}`, map[string]interface{}{"b": true}, "yes", false},
+		{"ternary false", `{{ ternary "yes" "no" .b }}`, map[string]interface{}{"b": false}, "no", false},
+		{"env", `{{ env "MCPSHELL_TEST_VAR" }}`, map[string]interface{}{}, "from-env", false},
+		{"builtin functions", `{{ printf "%s-%d" .s (len .l) }}`, map[string]interface{}{"s": "n", "l": []interface{}{1, 2}}, "n-2", false},
+		{"function not available", `{{ .s | sha256sum }}`, map[string]interface{}{"s": "hello"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessTemplate(tt.template, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProcessTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessShellTemplate(t *testing.T) {
+	args := map[string]interface{}{
+		"name":  "it's; rm -rf /",
+		"flags": "-l -a",
+		"count": 3,
+		"all":   true,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		kind     ShellKind
+		quote    bool
+		want     string
+	}{
+		{"not quoted", `echo {{ .name }}`, ShellPOSIX, false, `echo it's; rm -rf /`},
+		{"explicit quoting", `echo {{ .name | shellQuote }}`, ShellPOSIX, false, `echo 'it'\''s; rm -rf /'`},
+		{"automatic quoting", `echo {{ .name }}`, ShellPOSIX, true, `echo 'it'\''s; rm -rf /'`},
+		{"no double quoting", `echo {{ .name | shellQuote }}`, ShellPOSIX, true, `echo 'it'\''s; rm -rf /'`},
+		{"raw values", `ls {{ .flags | raw }}`, ShellPOSIX, true, `ls -l -a`},
+		{"numbers", `head -n {{ .count }}`, ShellPOSIX, true, `head -n '3'`},
+		{"missing values", `echo {{ .missing }}`, ShellPOSIX, true, `echo ''`},
+		{"functions", `echo {{ printf "%s!" .flags }}`, ShellPOSIX, true, `echo '-l -a!'`},
+		{"conditions", `ls{{ if .all }} -a{{ end }} {{ .flags }}`, ShellPOSIX, true, `ls -a '-l -a'`},
+		{"else branches", `{{ if .missing }}{{ .missing }}{{ else }}{{ .count }}{{ end }}`, ShellPOSIX, true, `'3'`},
+		{"variables", `{{ $n := .name }}echo {{ $n }}`, ShellPOSIX, true, `echo 'it'\''s; rm -rf /'`},
+		{"powershell", `Write-Output {{ .name }}`, ShellPowerShell, true, `Write-Output 'it''s; rm -rf /'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessShellTemplate(tt.template, args, tt.kind, tt.quote)
+			if err != nil {
+				t.Fatalf("ProcessShellTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProcessShellTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ranges over lists", func(t *testing.T) {
+		got, err := ProcessShellTemplate(`rm{{ range .files }} {{ . }}{{ end }}`,
+			map[string]interface{}{"files": []interface{}{"a b", "$(id)"}}, ShellPOSIX, true)
+		if err != nil {
+			t.Fatalf("ProcessShellTemplate() error = %v", err)
+		}
+		if want := `rm 'a b' '$(id)'`; got != want {
+			t.Errorf("ProcessShellTemplate() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestIsConditionalTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		want     bool
+	}{
+		{`{{ if .count }}--max-count={{ .count }}{{ end }}`, true},
+		{` {{- if .all }}-a{{ end -}} `, true},
+		{`{{ with .file }}{{ . }}{{ end }}`, true},
+		{`{{ range .files }}{{ . }}{{ end }}`, true},
+		{`{{ if .a }}-a{{ end }}{{ if .b }}-b{{ end }}`, true},
+		{`{{ .file }}`, false},
+		{`--count={{ if .count }}{{ .count }}{{ end }}`, false},
+		{`{{ if .all }}-a{{ end }} {{ .file }}`, false},
+		{`log`, false},
+		{``, false},
+		{`{{ if }}`, false},
+	}
+	for _, tt := range tests {
+		if got := IsConditionalTemplate(tt.template); got != tt.want {
+			t.Errorf("IsConditionalTemplate(%q) = %v, want %v", tt.template, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdayNames are the names of the days of the week accepted in the time windows
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// TimeWindow is a set of hours of some days of the week when something is allowed
+// (ie, the maintenance windows when a destructive tool can be called)
+type TimeWindow struct {
+	hours    [][2]int              // the ranges of minutes of the day (the end is excluded)
+	days     map[time.Weekday]bool // the days of the week (nil for all of them)
+	location *time.Location
+
+	description string // the description of the window, for the errors
+}
+
+// NewTimeWindow creates a time window.
+//
+// Parameters:
+//   - hours: The ranges of hours, like "09:00-18:00" (all day when empty). The ranges
+//     ending before they start go past midnight, like "22:00-06:00".
+//   - days: The days of the week, like "mon", "friday" or "mon-fri" (all of them when empty)
+//   - timezone: The IANA time zone of the hours and the days, like "Europe/Madrid"
+//     (the local time zone when empty)
+//
+// Returns:
+//   - The time window
+//   - An error if any of the hours, days or time zone is not valid
+func NewTimeWindow(hours []string, days []string, timezone string) (*TimeWindow, error) {
+	w := &TimeWindow{location: time.Local}
+
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+		}
+		w.location = location
+	}
+
+	for _, hoursRange := range hours {
+		fromStr, toStr, found := strings.Cut(hoursRange, "-")
+		if !found {
+			return nil, fmt.Errorf("invalid hours '%s': expected a range like '09:00-18:00'", hoursRange)
+		}
+		from, err1 := parseTimeOfDay(fromStr)
+		to, err2 := parseTimeOfDay(toStr)
+		if err1 != nil || err2 != nil || from == to {
+			return nil, fmt.Errorf("invalid hours '%s': expected a range like '09:00-18:00'", hoursRange)
+		}
+		if to <= from {
+			// the range goes past midnight
+			w.hours = append(w.hours, [2]int{from, 24 * 60}, [2]int{0, to})
+			continue
+		}
+		w.hours = append(w.hours, [2]int{from, to})
+	}
+
+	if len(days) > 0 {
+		w.days = map[time.Weekday]bool{}
+	}
+	for _, daysRange := range days {
+		fromStr, toStr, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(daysRange)), "-")
+		from, found := weekdayNames[strings.TrimSpace(fromStr)]
+		if !found {
+			return nil, fmt.Errorf("invalid day '%s': expected a day like 'mon' or a range like 'mon-fri'", daysRange)
+		}
+		to := from
+		if isRange {
+			if to, found = weekdayNames[strings.TrimSpace(toStr)]; !found {
+				return nil, fmt.Errorf("invalid day '%s': expected a day like 'mon' or a range like 'mon-fri'", daysRange)
+			}
+		}
+		// the ranges can wrap around the end of the week (ie, "fri-mon")
+		for day := from; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+
+	var description []string
+	if len(days) > 0 {
+		description = append(description, strings.Join(days, ", "))
+	}
+	if len(hours) > 0 {
+		description = append(description, strings.Join(hours, ", "))
+	}
+	w.description = fmt.Sprintf("%s (%s)", strings.Join(description, " "), w.location)
+
+	return w, nil
+}
+
+// parseTimeOfDay parses a time of the day ("HH:MM", where "24:00" is the end of the day),
+// returning the minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &hour, &minute); err != nil {
+		return 0, err
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("invalid time of the day '%s'", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Allows returns true if a time is in the window
+func (w *TimeWindow) Allows(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	t = t.In(w.location)
+	if w.days != nil && !w.days[t.Weekday()] {
+		return false
+	}
+	if len(w.hours) == 0 {
+		return true
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	for _, hoursRange := range w.hours {
+		if minutes >= hoursRange[0] && minutes < hoursRange[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a description of the window, like "mon-fri 09:00-18:00 (Europe/Madrid)"
+func (w *TimeWindow) String() string {
+	if w == nil {
+		return "always"
+	}
+	return w.description
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	friday := time.Date(2024, 3, 15, 0, 0, 0, 0, madrid)
+	at := func(day time.Time, hour int, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	tests := []struct {
+		name     string
+		hours    []string
+		days     []string
+		times    map[time.Time]bool
+		expected string
+	}{
+		{
+			name:  "business hours",
+			hours: []string{"09:00-18:00"},
+			days:  []string{"mon-fri"},
+			times: map[time.Time]bool{
+				at(friday, 9, 0):                   true,
+				at(friday, 17, 59):                 true,
+				at(friday, 18, 0):                  false,
+				at(friday, 8, 59):                  false,
+				at(friday.AddDate(0, 0, 1), 10, 0): false, // saturday
+				at(friday.AddDate(0, 0, 3), 10, 0): true,  // monday
+			},
+			expected: "mon-fri 09:00-18:00 (Europe/Madrid)",
+		},
+		{
+			name:  "overnight",
+			hours: []string{"22:00-06:00"},
+			times: map[time.Time]bool{
+				at(friday, 23, 0): true,
+				at(friday, 5, 59): true,
+				at(friday, 6, 0):  false,
+				at(friday, 12, 0): false,
+			},
+		},
+		{
+			name:  "several ranges",
+			hours: []string{"09:00-13:00", "15:00-24:00"},
+			times: map[time.Time]bool{
+				at(friday, 12, 0):  true,
+				at(friday, 14, 0):  false,
+				at(friday, 23, 59): true,
+			},
+		},
+		{
+			name: "days wrapping around the week",
+			days: []string{"Friday-Mon"},
+			times: map[time.Time]bool{
+				at(friday, 3, 0):                   true,
+				at(friday.AddDate(0, 0, 2), 3, 0):  true,  // sunday
+				at(friday.AddDate(0, 0, 4), 12, 0): false, // tuesday
+			},
+		},
+		{
+			name:  "other time zones",
+			hours: []string{"09:00-18:00"},
+			times: map[time.Time]bool{
+				time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC):  true, // 09:30 in Madrid
+				time.Date(2024, 3, 15, 17, 30, 0, 0, time.UTC): false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewTimeWindow(tt.hours, tt.days, "Europe/Madrid")
+			if err != nil {
+				t.Fatalf("NewTimeWindow() error = %v", err)
+			}
+			for tm, expected := range tt.times {
+				if got := w.Allows(tm); got != expected {
+					t.Errorf("Allows(%s) = %v, want %v", tm, got, expected)
+				}
+			}
+			if tt.expected != "" && w.String() != tt.expected {
+				t.Errorf("String() = %q, want %q", w.String(), tt.expected)
+			}
+		})
+	}
+
+	var always *TimeWindow
+	if !always.Allows(time.Now()) {
+		t.Errorf("A nil window must allow any time")
+	}
+}
+
+func TestNewTimeWindow_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    []string
+		days     []string
+		timezone string
+	}{
+		{"not a range", []string{"09:00"}, nil, ""},
+		{"invalid hour", []string{"09:00-25:00"}, nil, ""},
+		{"invalid minutes", []string{"09:60-10:00"}, nil, ""},
+		{"empty range", []string{"09:00-09:00"}, nil, ""},
+		{"invalid day", nil, []string{"someday"}, ""},
+		{"invalid range of days", nil, []string{"mon-someday"}, ""},
+		{"invalid time zone", []string{"09:00-18:00"}, nil, "Mars/Olympus"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTimeWindow(tt.hours, tt.days, tt.timezone); err == nil {
+				t.Errorf("NewTimeWindow() expected an error")
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package common
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // OutputConfig defines how tool output should be formatted before being returned.
@@ -12,21 +14,274 @@ type OutputConfig struct {
 	// Prefix is a template string that gets prepended to the command output.
 	// It can use the same template variables as the command itself.
 	Prefix string `yaml:"prefix,omitempty"`
+
+	// Convert is the converter for tabular outputs (ie, "table-to-markdown" or "csv-to-json").
+	// See OutputConverters for the valid values.
+	Convert string `yaml:"convert,omitempty"`
+
+	// Files is a list of glob patterns for files produced by the command that should
+	// be returned to the client. Relative patterns are relative to the working directory
+	// (or the workspace). Patterns can use the same template variables as the command.
+	Files []string `yaml:"files,omitempty"`
+
+	// Validate enables the validation of the command output against the
+	// output schema of the tool, failing the execution when it does not conform
+	Validate bool `yaml:"validate,omitempty"`
+
+	// MaxSize is the maximum size (in bytes) of the output returned to MCP clients (0 for no limit).
+	// Bigger outputs are returned in pages, that can be read with the built-in "read_more" tool.
+	MaxSize int `yaml:"max_size,omitempty"`
+
+	// Diff returns the differences with the previous successful output of the tool
+	// for the same arguments (as a unified diff), followed by the new output
+	Diff bool `yaml:"diff,omitempty"`
+
+	// Screen looks for prompt injections in the output (ie, instructions for the model):
+	// "flag" returns the suspicious outputs with a warning, and "quarantine" returns them
+	// only for the user (not for the model). See ScreenOutput.
+	Screen string `yaml:"screen,omitempty"`
+
+	// Format is the format of the output: "auto" detects it (JSON, YAML, CSV, HTML or binary),
+	// converting YAML and CSV to JSON and returning binary outputs as blobs, and reports
+	// its content type to the clients. "csv" and "tsv" parse the output as a table (with
+	// the CSV options), returning it as a JSON array of objects.
+	Format string `yaml:"format,omitempty"`
+
+	// CSV are the options for parsing the outputs with the "csv" and "tsv" formats
+	CSV CSVOutputConfig `yaml:"csv,omitempty"`
+
+	// ImageFile is the path of an image produced by the command (ie, a plot), returned to
+	// the client as an image. It can use the same template variables as the command, and
+	// relative paths are relative to the working directory (or the workspace).
+	ImageFile string `yaml:"image_file,omitempty"`
+
+	// AudioFile is the path of an audio file produced by the command (ie, speech), returned
+	// to the client as an audio, like ImageFile
+	AudioFile string `yaml:"audio_file,omitempty"`
+}
+
+// CSVOutputConfig defines how the outputs with the "csv" and "tsv" formats are parsed.
+type CSVOutputConfig struct {
+	// Delimiter is the character separating the fields, or "tab"
+	// (by default, "," for the "csv" format and "tab" for the "tsv" format)
+	Delimiter string `yaml:"delimiter,omitempty"`
+
+	// Header is false when the first line is not a header with the names of the columns
+	// (true by default)
+	Header *bool `yaml:"header,omitempty"`
+
+	// Columns are the names of the columns, replacing the ones in the header (if any).
+	// Columns without a name are named "column_<n>".
+	Columns []string `yaml:"columns,omitempty"`
 }
 
 // ParamConfig defines the configuration for a single parameter in a tool.
 type ParamConfig struct {
-	// Type specifies the parameter data type. Valid values: "string" (default), "number"/"integer", "boolean"
+	// Type specifies the parameter data type. Valid values: "string" (default), "number"/"integer", "boolean",
+	// "file_content" (a string written to a temporary file, replaced by the file path in templates),
+	// "url" (a string with an absolute URL, checked with the URL options), "ip" (an IP address)
+	// or "cidr" (a network like "10.0.0.0/24"), checked with the network options, "email"
+	// (an email address) or "hostname" (a host name), normalized to lower case
 	Type string `yaml:"type,omitempty"`
 
 	// Description provides information about the parameter's purpose
 	Description string `yaml:"description"`
 
+	// Descriptions are the translations of the description, by locale (ie, "es" or "pt-BR")
+	Descriptions map[string]string `yaml:"descriptions,omitempty"`
+
 	// Required indicates whether the parameter must be provided
 	Required bool `yaml:"required,omitempty"`
 
 	// Default specifies a default value to use when the parameter is not provided
 	Default interface{} `yaml:"default,omitempty"`
+
+	// DefaultFromEnv is the name of an environment variable (of the MCPShell process)
+	// whose value is used when the parameter is not provided. It has precedence over
+	// Default, that is used when the variable is not set.
+	DefaultFromEnv string `yaml:"default_from_env,omitempty"`
+
+	// DefaultCommand is a command (ie, "git branch --show-current") run when the configuration
+	// is loaded: its output (trimmed) is used as the Default value. When it fails, Default
+	// is kept.
+	DefaultCommand string `yaml:"default_command,omitempty"`
+
+	// Encoding is the encoding used for "file_content" values. Valid values: "" (plain text), "base64"
+	Encoding string `yaml:"encoding,omitempty"`
+
+	// URL are the schemes and hosts allowed in the values of the "url" parameters
+	URL *URLConfig `yaml:"url,omitempty"`
+
+	// Network are the ranges of addresses allowed in the values of the "ip" and "cidr" parameters
+	Network *NetworkConfig `yaml:"network,omitempty"`
+
+	// EnumGlob is a glob pattern for files (ie, "/etc/playbooks/*.yaml"): the paths of the
+	// files matching it are the only values allowed
+	EnumGlob string `yaml:"enum_glob,omitempty"`
+
+	// EnumCommand is a command (ie, "kubectl config get-contexts -o name"): the lines of
+	// its output are the only values allowed
+	EnumCommand string `yaml:"enum_command,omitempty"`
+
+	// EnumRefresh is the time the allowed values are kept before obtaining them again
+	// (ie, "5m"), one minute by default
+	EnumRefresh string `yaml:"enum_refresh,omitempty"`
+
+	// Hidden parameters are not shown to clients, and always get their default value
+	// (ie, API endpoints or internal flags that the model should never change)
+	Hidden bool `yaml:"hidden,omitempty"`
+}
+
+// ValidateParams checks the parameters configuration of a tool.
+//
+// Returns:
+//   - An error if some parameter is invalid (ie, a hidden parameter without a value)
+func ValidateParams(params map[string]ParamConfig) error {
+	for name, param := range params {
+		if param.Hidden && param.Default == nil && param.DefaultFromEnv == "" && param.DefaultCommand == "" {
+			return fmt.Errorf("hidden parameter '%s' must have a default value", name)
+		}
+		if param.URL != nil {
+			if param.Type != ParamTypeURL {
+				return fmt.Errorf("parameter '%s' has URL options, but it is not of type %s", name, ParamTypeURL)
+			}
+			if err := param.URL.validate(); err != nil {
+				return fmt.Errorf("parameter '%s': %w", name, err)
+			}
+		}
+		if err := param.validateEnum(); err != nil {
+			return fmt.Errorf("parameter '%s': %w", name, err)
+		}
+		if param.Network != nil {
+			if param.Type != ParamTypeIP && param.Type != ParamTypeCIDR {
+				return fmt.Errorf("parameter '%s' has network options, but it is not of type %s or %s",
+					name, ParamTypeIP, ParamTypeCIDR)
+			}
+			if err := param.Network.validate(); err != nil {
+				return fmt.Errorf("parameter '%s': %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetDefault returns the default value for the parameter: the value of the
+// DefaultFromEnv environment variable (converted to the parameter type) when
+// it is set, or the Default value otherwise.
+//
+// Returns:
+//   - The default value, or nil when the parameter has no default
+//   - An error if the value in the environment cannot be converted to the parameter type
+func (p ParamConfig) GetDefault() (interface{}, error) {
+	if p.DefaultFromEnv != "" {
+		if value, exists := os.LookupEnv(p.DefaultFromEnv); exists {
+			converted, err := ConvertStringToType(value, p.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in environment variable %s: %w", p.DefaultFromEnv, err)
+			}
+			return converted, nil
+		}
+	}
+	return p.Default, nil
+}
+
+// ResolveDefaultCommand runs the DefaultCommand of the parameter (if any), using its
+// output (trimmed and converted to the type of the parameter) as the Default value.
+//
+// Returns:
+//   - An error if the command fails, has no output or its output cannot be converted
+//     (keeping the Default value)
+func (p *ParamConfig) ResolveDefaultCommand() error {
+	if p.DefaultCommand == "" {
+		return nil
+	}
+
+	output, err := RunShellCommand(p.DefaultCommand, defaultCommandTimeout)
+	if err != nil {
+		return fmt.Errorf("default command '%s' failed: %w", p.DefaultCommand, err)
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return fmt.Errorf("default command '%s' has no output", p.DefaultCommand)
+	}
+	value, err := ConvertStringToType(output, p.Type)
+	if err != nil {
+		return fmt.Errorf("invalid output of default command '%s': %w", p.DefaultCommand, err)
+	}
+	p.Default = value
+	return nil
+}
+
+// NormalizeValue checks the value of a parameter is valid for its type (ie, the URLs of
+// the "url" parameters are allowed) and its enum, returning the value normalized (ie, the
+// email addresses and the host names in lower case)
+func (p ParamConfig) NormalizeValue(value interface{}) (interface{}, error) {
+	var normalize func(string) (string, error)
+	switch p.Type {
+	case ParamTypeURL:
+		normalize = checkWith(p.URL.Check)
+	case ParamTypeIP:
+		normalize = checkWith(p.Network.CheckIP)
+	case ParamTypeCIDR:
+		normalize = checkWith(p.Network.CheckCIDR)
+	case ParamTypeEmail:
+		normalize = NormalizeEmail
+	case ParamTypeHostname:
+		normalize = NormalizeHostname
+	default:
+		if !p.HasEnum() {
+			return value, nil
+		}
+		normalize = func(value string) (string, error) { return value, nil }
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string, got %T", value)
+	}
+	normalized, err := normalize(str)
+	if err != nil {
+		return nil, err
+	}
+	if p.HasEnum() {
+		if err := p.checkEnum(normalized); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
+}
+
+// checkWith returns a normalization that only checks the values
+func checkWith(check func(string) error) func(string) (string, error) {
+	return func(value string) (string, error) {
+		return value, check(value)
+	}
+}
+
+// defaultCommandTimeout is the maximum time the commands of the defaults can run
+const defaultCommandTimeout = 10 * time.Second
+
+// ParamTypeFileContent is the type for parameters whose value is written to a temporary file
+const ParamTypeFileContent = "file_content"
+
+// The types for parameters with a format
+const (
+	ParamTypeURL  = "url"  // a URL
+	ParamTypeIP   = "ip"   // an IP address
+	ParamTypeCIDR = "cidr" // a network, like "10.0.0.0/24"
+
+	ParamTypeEmail    = "email"    // an email address
+	ParamTypeHostname = "hostname" // a host name
+)
+
+// ParamValueType returns the type of the values of a parameter type: "string" for the
+// types with string values (ie, "file_content" or "url"), or the type itself otherwise
+func ParamValueType(paramType string) string {
+	switch paramType {
+	case "", "string", ParamTypeFileContent, ParamTypeURL, ParamTypeIP, ParamTypeCIDR, ParamTypeEmail, ParamTypeHostname:
+		return "string"
+	}
+	return paramType
 }
 
 // LoggingConfig defines configuration options for application logging.
@@ -34,7 +289,8 @@ type LoggingConfig struct {
 	// File is the path to the log file
 	File string
 
-	// Level sets the logging verbosity (e.g., "info", "debug", "error")
+	// Level sets the logging verbosity (e.g., "info", "debug", "error"). In the tools,
+	// it overrides the level of the logs of the tool.
 	Level string `yaml:"level,omitempty"`
 }
 
@@ -43,18 +299,13 @@ type LoggingConfig struct {
 //
 // Parameters:
 //   - value: The string value to convert
-//   - paramType: The parameter type ("string", "number", "integer", "boolean")
+//   - paramType: The parameter type ("string", "number", "integer", "boolean", or a type with string values)
 //
 // Returns:
 //   - The converted value
 //   - An error if the conversion fails
 func ConvertStringToType(value string, paramType string) (interface{}, error) {
-	// Default to string if type is not specified
-	if paramType == "" {
-		paramType = "string"
-	}
-
-	switch paramType {
+	switch ParamValueType(paramType) {
 	case "string":
 		return value, nil
 	case "number":
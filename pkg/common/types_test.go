@@ -204,3 +204,131 @@ func TestParamConfigDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestParamConfigGetDefault(t *testing.T) {
+	t.Setenv("TEST_DEFAULT_REGION", "eu-west-1")
+	t.Setenv("TEST_DEFAULT_REPLICAS", "3")
+	t.Setenv("TEST_DEFAULT_INVALID", "many")
+
+	tests := []struct {
+		name        string
+		param       ParamConfig
+		expected    interface{}
+		expectError bool
+	}{
+		{"static default", ParamConfig{Default: "us-east-1"}, "us-east-1", false},
+		{"no default", ParamConfig{}, nil, false},
+		{"from env", ParamConfig{DefaultFromEnv: "TEST_DEFAULT_REGION", Default: "us-east-1"}, "eu-west-1", false},
+		{"from env converted", ParamConfig{Type: "integer", DefaultFromEnv: "TEST_DEFAULT_REPLICAS"}, int64(3), false},
+		{"env not set", ParamConfig{DefaultFromEnv: "TEST_DEFAULT_MISSING", Default: "us-east-1"}, "us-east-1", false},
+		{"env not set without default", ParamConfig{DefaultFromEnv: "TEST_DEFAULT_MISSING"}, nil, false},
+		{"invalid env value", ParamConfig{Type: "number", DefaultFromEnv: "TEST_DEFAULT_INVALID"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.param.GetDefault()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("GetDefault() error = %v, expectError %v", err, tt.expectError)
+			}
+			if value != tt.expected {
+				t.Errorf("GetDefault() = %v (%T), expected %v (%T)", value, value, tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParamConfigResolveDefaultCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		param       ParamConfig
+		expected    interface{}
+		expectError bool
+	}{
+		{"no command", ParamConfig{Default: "main"}, "main", false},
+		{"output trimmed", ParamConfig{DefaultCommand: "printf '  feature/x\\n\\n'", Default: "main"}, "feature/x", false},
+		{"output converted", ParamConfig{Type: "integer", DefaultCommand: "echo 4"}, int64(4), false},
+		{"failing command", ParamConfig{DefaultCommand: "echo 'not a repo' >&2; exit 1", Default: "main"}, "main", true},
+		{"no output", ParamConfig{DefaultCommand: "true"}, nil, true},
+		{"invalid output", ParamConfig{Type: "number", DefaultCommand: "echo many"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param := tt.param
+			err := param.ResolveDefaultCommand()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ResolveDefaultCommand() error = %v, expectError %v", err, tt.expectError)
+			}
+			if param.Default != tt.expected {
+				t.Errorf("Default = %v (%T), expected %v (%T)", param.Default, param.Default, tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      map[string]ParamConfig
+		expectError bool
+	}{
+		{"no params", nil, false},
+		{"visible param", map[string]ParamConfig{"name": {Type: "string"}}, false},
+		{"hidden with default", map[string]ParamConfig{"endpoint": {Hidden: true, Default: "https://api.local"}}, false},
+		{"hidden with env default", map[string]ParamConfig{"endpoint": {Hidden: true, DefaultFromEnv: "API_ENDPOINT"}}, false},
+		{"hidden with command default", map[string]ParamConfig{"endpoint": {Hidden: true, DefaultCommand: "cat /etc/endpoint"}}, false},
+		{"hidden without default", map[string]ParamConfig{"endpoint": {Hidden: true}}, true},
+		{"url with options", map[string]ParamConfig{"target": {Type: ParamTypeURL, URL: &URLConfig{DeniedHosts: []string{"10.0.0.0/8", "*.internal"}}}}, false},
+		{"url options in a string", map[string]ParamConfig{"target": {Type: "string", URL: &URLConfig{}}}, true},
+		{"invalid network", map[string]ParamConfig{"target": {Type: ParamTypeURL, URL: &URLConfig{DeniedHosts: []string{"10.0.0.0/99"}}}}, true},
+		{"ip with ranges", map[string]ParamConfig{"target": {Type: ParamTypeIP, Network: &NetworkConfig{AllowedRanges: []string{"10.0.0.0/8", "192.168.1.1"}}}}, false},
+		{"cidr with ranges", map[string]ParamConfig{"target": {Type: ParamTypeCIDR, Network: &NetworkConfig{DeniedRanges: []string{"fd00::/8"}}}}, false},
+		{"network options in a url", map[string]ParamConfig{"target": {Type: ParamTypeURL, Network: &NetworkConfig{}}}, true},
+		{"invalid range", map[string]ParamConfig{"target": {Type: ParamTypeIP, Network: &NetworkConfig{AllowedRanges: []string{"10.0.0/8"}}}}, true},
+		{"invalid host pattern", map[string]ParamConfig{"target": {Type: ParamTypeURL, URL: &URLConfig{AllowedHosts: []string{"[a-"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParams(tt.params)
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateParams() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestParamConfigNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    ParamConfig
+		value    interface{}
+		expected interface{}
+		wantErr  bool
+	}{
+		{"string", ParamConfig{Type: "string"}, "http://169.254.169.254/", "http://169.254.169.254/", false},
+		{"number", ParamConfig{Type: "number"}, 42.0, 42.0, false},
+		{"url", ParamConfig{Type: ParamTypeURL}, "https://example.com", "https://example.com", false},
+		{"denied url", ParamConfig{Type: ParamTypeURL, URL: &URLConfig{DeniedHosts: []string{"169.254.169.254"}}}, "http://169.254.169.254/", nil, true},
+		{"url not a string", ParamConfig{Type: ParamTypeURL}, 42.0, nil, true},
+		{"ip", ParamConfig{Type: ParamTypeIP}, "10.0.0.1", "10.0.0.1", false},
+		{"cidr not allowed", ParamConfig{Type: ParamTypeCIDR, Network: &NetworkConfig{AllowedRanges: []string{"10.0.0.0/8"}}}, "10.0.0.0/7", nil, true},
+		{"email", ParamConfig{Type: ParamTypeEmail}, "John.Doe@Example.COM", "john.doe@example.com", false},
+		{"invalid email", ParamConfig{Type: ParamTypeEmail}, "john@", nil, true},
+		{"hostname", ParamConfig{Type: ParamTypeHostname}, "WWW.Example.com.", "www.example.com", false},
+		{"invalid hostname", ParamConfig{Type: ParamTypeHostname}, "example.com; rm -rf /", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.param.NormalizeValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeValue(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && result != tt.expected {
+				t.Errorf("NormalizeValue(%v) = %v, want %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
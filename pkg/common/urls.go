@@ -0,0 +1,134 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultURLSchemes are the schemes allowed in the URLs by default
+var DefaultURLSchemes = []string{"http", "https"}
+
+// URLConfig defines the URLs allowed in the values of the "url" parameters
+// (ie, so a tool running curl cannot be pointed at the metadata endpoints of the cloud).
+//
+// The host patterns are glob patterns for the host names (like "*.example.com"),
+// IP addresses (like "169.254.169.254") or networks (like "10.0.0.0/8").
+type URLConfig struct {
+	// Schemes are the schemes allowed (http and https by default)
+	Schemes []string `yaml:"schemes,omitempty"`
+
+	// AllowedHosts are the patterns of the hosts allowed (all of them when empty)
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty"`
+
+	// DeniedHosts are the patterns of the hosts denied, with precedence over the allowed ones
+	DeniedHosts []string `yaml:"denied_hosts,omitempty"`
+
+	// Resolve checks the addresses of the host names against the IP addresses and the
+	// networks of the patterns (ie, for denying the names resolving to private addresses)
+	Resolve bool `yaml:"resolve,omitempty"`
+}
+
+// lookupIP resolves the addresses of the host names (replaced in the tests)
+var lookupIP = net.LookupIP
+
+// validate checks the host patterns are valid
+func (c *URLConfig) validate() error {
+	for _, pattern := range append(slices.Clone(c.AllowedHosts), c.DeniedHosts...) {
+		if strings.Contains(pattern, "/") {
+			if _, _, err := net.ParseCIDR(pattern); err != nil {
+				return fmt.Errorf("invalid network '%s': %w", pattern, err)
+			}
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid host pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Check returns an error if a URL is not valid or not allowed. A nil configuration
+// allows the absolute URLs with the default schemes.
+func (c *URLConfig) Check(value string) error {
+	if c == nil {
+		c = &URLConfig{}
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s'", value)
+	}
+	if u.Scheme == "" || u.Hostname() == "" {
+		return fmt.Errorf("invalid URL '%s': expected an absolute URL, like 'https://example.com/path'", value)
+	}
+
+	schemes := c.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultURLSchemes
+	}
+	if !slices.ContainsFunc(schemes, func(scheme string) bool { return strings.EqualFold(scheme, u.Scheme) }) {
+		return fmt.Errorf("URL scheme '%s' not allowed (allowed schemes: %s)", u.Scheme, strings.Join(schemes, ", "))
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else if isNumericHost(host) {
+		// the clients can read them as IP addresses (ie, "2852039166" is 169.254.169.254)
+		return fmt.Errorf("invalid host '%s' in URL: use the usual notation for the IP addresses", host)
+	} else if c.Resolve {
+		if ips, err = lookupIP(host); err != nil || len(ips) == 0 {
+			return fmt.Errorf("cannot resolve host '%s' in URL", host)
+		}
+	}
+
+	for _, pattern := range c.DeniedHosts {
+		if matchHostPattern(pattern, host, ips, false) {
+			return fmt.Errorf("host '%s' not allowed in URL", host)
+		}
+	}
+	if len(c.AllowedHosts) > 0 && !slices.ContainsFunc(c.AllowedHosts, func(pattern string) bool {
+		return matchHostPattern(pattern, host, ips, true)
+	}) {
+		return fmt.Errorf("host '%s' not allowed in URL", host)
+	}
+	return nil
+}
+
+// isNumericHost returns true for the hosts ending in a number (like "127.1" or "0x7f.1"),
+// that are not valid host names but some clients read as IP addresses
+func isNumericHost(host string) bool {
+	last := host[strings.LastIndex(host, ".")+1:]
+	if strings.HasPrefix(last, "0x") {
+		return true
+	}
+	return last != "" && strings.Trim(last, "0123456789") == ""
+}
+
+// matchHostPattern returns true if a host (or its addresses) matches a pattern. For the
+// IP addresses and the networks, all the addresses must match (when allowing) or any of
+// them (when denying).
+func matchHostPattern(pattern string, host string, ips []net.IP, all bool) bool {
+	var contains func(ip net.IP) bool
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		contains = network.Contains
+	} else if patternIP := net.ParseIP(pattern); patternIP != nil {
+		contains = patternIP.Equal
+	} else {
+		matched, _ := path.Match(strings.ToLower(pattern), host)
+		return matched
+	}
+
+	if len(ips) == 0 {
+		return false
+	}
+	if all {
+		return !slices.ContainsFunc(ips, func(ip net.IP) bool { return !contains(ip) })
+	}
+	return slices.ContainsFunc(ips, contains)
+}
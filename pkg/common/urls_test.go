@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestURLConfigCheck(t *testing.T) {
+	lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "metadata.example.com":
+			return []net.IP{net.ParseIP("169.254.169.254")}, nil
+		case "api.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "mixed.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.1")}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	denyInternal := &URLConfig{
+		DeniedHosts: []string{"169.254.0.0/16", "10.0.0.0/8", "::1", "localhost", "*.internal"},
+		Resolve:     true,
+	}
+	allowPublic := &URLConfig{
+		Schemes:      []string{"https"},
+		AllowedHosts: []string{"*.example.com", "93.184.216.0/24"},
+	}
+
+	tests := []struct {
+		name    string
+		config  *URLConfig
+		value   string
+		wantErr bool
+	}{
+		{"default config", nil, "https://example.com/path?q=1", false},
+		{"default schemes", nil, "ftp://example.com/file", true},
+		{"relative URL", nil, "/path", true},
+		{"no host", nil, "https:///path", true},
+		{"not a URL", nil, "http://[::1", true},
+		{"public host", denyInternal, "http://api.example.com/v1", false},
+		{"denied address", denyInternal, "http://169.254.169.254/latest/meta-data/", true},
+		{"denied IPv6 address", denyInternal, "http://[::1]:8080/", true},
+		{"denied mapped address", denyInternal, "http://[::ffff:169.254.169.254]/", true},
+		{"denied name", denyInternal, "http://LOCALHOST./admin", true},
+		{"denied glob", denyInternal, "http://vault.corp.internal/", true},
+		{"denied with user info", denyInternal, "http://api.example.com@169.254.169.254/", true},
+		{"name resolving to a denied address", denyInternal, "http://metadata.example.com/", true},
+		{"name resolving to a denied address and others", denyInternal, "http://mixed.example.com/", true},
+		{"name not resolved", denyInternal, "http://unknown.example.org/", true},
+		{"decimal address", denyInternal, "http://2852039166/", true},
+		{"short address", denyInternal, "http://127.1/", true},
+		{"hex address", denyInternal, "http://0xa9.254.169.254/", true},
+		{"allowed glob", allowPublic, "https://api.example.com/", false},
+		{"allowed glob in upper case", allowPublic, "https://API.Example.COM/", false},
+		{"allowed network", allowPublic, "https://93.184.216.34/", false},
+		{"scheme not allowed", allowPublic, "http://api.example.com/", true},
+		{"host not allowed", allowPublic, "https://example.org/", true},
+		{"glob not matching the domain", allowPublic, "https://example.com/", true},
+		{"address not allowed", allowPublic, "https://10.0.0.1/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Check(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// applyDescriptionTemplates resolves the templates in the descriptions of the tools
+func (c *ToolsConfig) applyDescriptionTemplates() error {
+	return renderDescriptions(c.MCP.Tools, c.MCP.Namespace)
+}
+
+// renderDescriptions resolves the templates in the descriptions of some tools (and their
+// parameters), so they can show details of the environment where they run. Besides the
+// template functions (ie, "env"), the templates can use:
+//
+//   - .os, .arch and .hostname: the operating system, the architecture and the host name
+//   - .tool: the name of the tool
+//   - .namespace: the namespace of the tools (can be empty)
+//   - .defaults: the default values of the parameters, by name
+func renderDescriptions(tools []MCPToolConfig, namespace string) error {
+	hostname, _ := os.Hostname()
+
+	for i := range tools {
+		tool := &tools[i]
+
+		defaults := map[string]interface{}{}
+		for name, param := range tool.Params {
+			if value, err := param.GetDefault(); err == nil && value != nil {
+				defaults[name] = value
+			}
+		}
+		data := map[string]interface{}{
+			"os":        runtime.GOOS,
+			"arch":      runtime.GOARCH,
+			"hostname":  hostname,
+			"tool":      tool.Name,
+			"namespace": namespace,
+			"defaults":  defaults,
+		}
+
+		description, err := renderDescription(tool.Description, data)
+		if err != nil {
+			return fmt.Errorf("invalid description of tool '%s': %w", tool.Name, err)
+		}
+		tool.Description = description
+
+		for name, param := range tool.Params {
+			description, err := renderDescription(param.Description, data)
+			if err != nil {
+				return fmt.Errorf("invalid description of parameter '%s' in tool '%s': %w", name, tool.Name, err)
+			}
+			param.Description = description
+			tool.Params[name] = param
+		}
+	}
+	return nil
+}
+
+// renderDescription resolves the template in a description, if it has any
+func renderDescription(description string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(description, "{{") {
+		return description, nil
+	}
+	return common.ProcessTemplate(description, data)
+}
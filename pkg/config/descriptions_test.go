@@ -0,0 +1,71 @@
+package config
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigFromFile_DescriptionTemplates(t *testing.T) {
+	t.Setenv("CLUSTER", "production")
+
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  namespace: k8s
+  tools:
+    - name: "pods"
+      description: "List the pods of {{ .tool }} in cluster {{ env `+"`CLUSTER`"+` }} ({{ .os }})"
+      params:
+        namespace:
+          type: string
+          default: "default"
+          description: "The namespace (by default, {{ .defaults.namespace }})"
+        plain:
+          type: string
+          description: "Not a {template}"
+      run:
+        command: "echo {{ .namespace }}"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tool := cfg.MCP.Tools[0]
+	if expected := "List the pods of k8s__pods in cluster production (" + runtime.GOOS + ")"; tool.Description != expected {
+		t.Errorf("Unexpected description: %q, expected %q", tool.Description, expected)
+	}
+	if got := tool.Params["namespace"].Description; got != "The namespace (by default, default)" {
+		t.Errorf("Unexpected parameter description: %q", got)
+	}
+	if got := tool.Params["plain"].Description; got != "Not a {template}" {
+		t.Errorf("Unexpected parameter description: %q", got)
+	}
+	// the command is not a description, so it is not rendered when loading
+	if got := tool.Run.Command; got != "echo {{ .namespace }}" {
+		t.Errorf("Unexpected command: %q", got)
+	}
+}
+
+func TestNewConfigFromFile_InvalidDescriptionTemplate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "broken"
+      description: "Broken {{ .tool"
+      run:
+        command: "echo broken"
+`)
+
+	_, err := NewConfigFromFile(file)
+	if err == nil {
+		t.Fatal("Expected an error for an invalid description template")
+	}
+	if !strings.Contains(err.Error(), "invalid description of tool 'broken'") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
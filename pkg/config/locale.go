@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strings"
+)
+
+// Locale is the locale of the descriptions of the tools and their parameters (ie, "es"
+// or "pt-BR"). When empty, the locale in every configuration file is used.
+var Locale = ""
+
+// getLocale returns the locale selected for the tools in this configuration
+func (c *ToolsConfig) getLocale() string {
+	if Locale != "" {
+		return Locale
+	}
+	return c.MCP.Locale
+}
+
+// applyLocale replaces the descriptions of the tools by their translations
+// for the selected locale
+func (c *ToolsConfig) applyLocale() {
+	localizeTools(c.MCP.Tools, c.getLocale())
+}
+
+// localizeTools replaces the descriptions of some tools (and their parameters) by their
+// translations for a locale, keeping the default ones when there are none
+func localizeTools(tools []MCPToolConfig, locale string) {
+	if locale == "" {
+		return
+	}
+
+	for i := range tools {
+		tool := &tools[i]
+		if description, found := localize(tool.Descriptions, locale); found {
+			tool.Description = description
+		}
+		for name, param := range tool.Params {
+			if description, found := localize(param.Descriptions, locale); found {
+				param.Description = description
+				tool.Params[name] = param
+			}
+		}
+	}
+}
+
+// localize returns the text for a locale, falling back to the text for its language
+// (ie, "es" for "es-MX"). Locales are compared ignoring the case, the separator and
+// the encoding (so "es_ES.UTF-8" matches "es-ES").
+func localize(texts map[string]string, locale string) (string, bool) {
+	if len(texts) == 0 {
+		return "", false
+	}
+
+	normalize := func(l string) string {
+		l, _, _ = strings.Cut(strings.TrimSpace(l), ".") // ie, "es_ES.UTF-8"
+		return strings.ToLower(strings.ReplaceAll(l, "_", "-"))
+	}
+	wanted := normalize(locale)
+	language, _, _ := strings.Cut(wanted, "-")
+
+	var fallback string
+	var hasFallback bool
+	for l, text := range texts {
+		switch normalize(l) {
+		case wanted:
+			return text, true
+		case language:
+			fallback, hasFallback = text, true
+		}
+	}
+	return fallback, hasFallback
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	texts := map[string]string{
+		"es":    "Hola",
+		"pt-BR": "Olá",
+	}
+
+	tests := []struct {
+		locale   string
+		expected string
+		found    bool
+	}{
+		{"es", "Hola", true},
+		{"es-MX", "Hola", true},
+		{"es_ES.UTF-8", "Hola", true},
+		{"pt_br", "Olá", true},
+		{"pt-PT", "", false},
+		{"fr", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, found := localize(texts, tt.locale)
+			if got != tt.expected || found != tt.found {
+				t.Errorf("localize(%q) = %q, %v, expected %q, %v", tt.locale, got, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromFile_Locale(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  locale: es
+  tools:
+    - name: "greet"
+      description: "Say hello"
+      descriptions:
+        es: "Saludar"
+        fr: "Dire bonjour"
+      params:
+        name:
+          type: string
+          description: "The name of the person"
+          descriptions:
+            es: "El nombre de la persona"
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "untranslated"
+      description: "Not translated"
+      run:
+        command: "echo untranslated"
+`)
+
+	load := func() *ToolsConfig {
+		cfg, err := NewConfigFromFile(file)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		return cfg
+	}
+
+	// the locale in the file is used by default
+	cfg := load()
+	if got := cfg.MCP.Tools[0].Description; got != "Saludar" {
+		t.Errorf("Unexpected description: %q", got)
+	}
+	if got := cfg.MCP.Tools[0].Params["name"].Description; got != "El nombre de la persona" {
+		t.Errorf("Unexpected parameter description: %q", got)
+	}
+	if got := cfg.MCP.Tools[1].Description; got != "Not translated" {
+		t.Errorf("Unexpected description for a tool without translations: %q", got)
+	}
+
+	// ... unless another locale is selected
+	Locale = "fr-FR"
+	t.Cleanup(func() { Locale = "" })
+
+	cfg = load()
+	if got := cfg.MCP.Tools[0].Description; got != "Dire bonjour" {
+		t.Errorf("Unexpected description: %q", got)
+	}
+	if got := cfg.MCP.Tools[0].Params["name"].Description; got != "The name of the person" {
+		t.Errorf("Unexpected parameter description: %q", got)
+	}
+}
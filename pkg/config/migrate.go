@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// CurrentConfigVersion is the version of the format of the configuration files.
+// Files without a version are considered to be in the first version of the format.
+const CurrentConfigVersion = 2
+
+// configMigration migrates the configuration files to a version of the format
+// from the previous one
+type configMigration struct {
+	version int // the version the files are migrated to
+
+	// migrate modifies the document, returning a description of the changes made
+	migrate func(root *yaml.Node) ([]string, error)
+}
+
+// configMigrations are the migrations between the versions of the format, in order
+var configMigrations = []configMigration{
+	{version: 2, migrate: migrateToolRunners},
+}
+
+// MigrateConfig migrates a configuration file to the current version of the format,
+// keeping the comments and the order of the fields. Documents already in the current
+// version are returned unchanged.
+//
+// Parameters:
+//   - data: The content of the configuration file
+//
+// Returns:
+//   - The migrated content of the configuration file
+//   - The version of the file (before migrating it)
+//   - A description of the changes made (empty when only the version has been set)
+//   - An error if the version is not supported or the file cannot be migrated
+func MigrateConfig(data []byte) ([]byte, int, []string, error) {
+	if isSopsEncrypted(data) {
+		return nil, 0, nil, fmt.Errorf("the file is encrypted with SOPS: decrypt it before migrating it")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, CurrentConfigVersion, nil, nil
+	}
+	root := doc.Content[0]
+
+	version := 1
+	if node := mappingValue(root, "version"); node != nil {
+		v, err := strconv.Atoi(node.Value)
+		if err != nil || v < 1 {
+			return nil, 0, nil, fmt.Errorf("invalid version '%s'", node.Value)
+		}
+		version = v
+	}
+	if version > CurrentConfigVersion {
+		return nil, version, nil, fmt.Errorf("version %d of the configuration format is not supported "+
+			"(the latest version supported is %d): upgrade MCPShell", version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return data, version, nil, nil
+	}
+
+	var changes []string
+	for _, migration := range configMigrations {
+		if migration.version <= version {
+			continue
+		}
+		migrationChanges, err := migration.migrate(root)
+		if err != nil {
+			return nil, version, nil, fmt.Errorf("failed to migrate to version %d: %w", migration.version, err)
+		}
+		changes = append(changes, migrationChanges...)
+	}
+
+	// set the version, at the top of the document
+	versionNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(CurrentConfigVersion)}
+	if node := mappingValue(root, "version"); node != nil {
+		*node = *versionNode
+	} else {
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+		if len(root.Content) > 0 {
+			// keep the comments at the top of the document before the version
+			keyNode.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{keyNode, versionNode}, root.Content...)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, version, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, version, nil, err
+	}
+
+	return buf.Bytes(), version, changes, nil
+}
+
+// migrateConfigData migrates the content of a configuration file when loading it,
+// logging the changes made (as the file should be migrated)
+func migrateConfigData(data []byte, configFile string) ([]byte, error) {
+	migrated, version, changes, err := MigrateConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		// nothing to migrate but the version (ie, in files without a version)
+		return data, nil
+	}
+
+	logger := common.GetLogger()
+	logger.Info("Config file %s uses version %d of the format: run 'mcpshell config migrate' for updating it", configFile, version)
+	for _, change := range changes {
+		logger.Debug("... %s", change)
+	}
+	return migrated, nil
+}
+
+// migrateToolRunners migrates the tools with a single runner ("runner" and "options" in
+// "run", and "requirements" in the tool) to the list of runners ("runners" in "run")
+func migrateToolRunners(root *yaml.Node) ([]string, error) {
+	mcp := mappingValue(root, "mcp")
+	if mcp == nil {
+		return nil, nil
+	}
+	tools := mappingValue(mcp, "tools")
+	if tools == nil || tools.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+
+	var changes []string
+	for _, tool := range tools.Content {
+		if tool.Kind != yaml.MappingNode {
+			continue
+		}
+		name := "(unnamed)"
+		if node := mappingValue(tool, "name"); node != nil {
+			name = node.Value
+		}
+
+		run := mappingValue(tool, "run")
+		requirements := removeMappingKey(tool, "requirements")
+		var runner, options *yaml.Node
+		if run != nil && run.Kind == yaml.MappingNode {
+			runner = removeMappingKey(run, "runner")
+			options = removeMappingKey(run, "options")
+		}
+		if runner == nil && options == nil && requirements == nil {
+			continue
+		}
+
+		if run == nil || run.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("tool '%s' has requirements but no run configuration", name)
+		}
+		if mappingValue(run, "runners") != nil {
+			return nil, fmt.Errorf("tool '%s' cannot have both a runner and a list of runners", name)
+		}
+
+		entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		runnerName := "exec"
+		if runner != nil {
+			runnerName = runner.Value
+		}
+		setMappingValue(entry, "name", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: runnerName})
+		if requirements != nil {
+			setMappingValue(entry, "requirements", requirements)
+		}
+		if options != nil {
+			setMappingValue(entry, "options", options)
+		}
+		setMappingValue(run, "runners", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{entry}})
+
+		changes = append(changes, fmt.Sprintf("tool '%s': runner, options and requirements moved to run.runners", name))
+	}
+	return changes, nil
+}
+
+// mappingValue returns the value of a key in a mapping node (or nil when not found)
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey removes a key from a mapping node, returning its value (or nil when not found)
+func removeMappingKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of a key in a mapping node, adding the key at the end
+// when it is not found
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	if existing := mappingValue(node, key); existing != nil {
+		*existing = *value
+		return
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const legacyConfig = `# Legacy tools
+mcp:
+  tools:
+    - name: "list_files"
+      requirements:
+        os: darwin
+        executables: [ls]
+      run:
+        command: "ls -l"
+        runner: sandbox-exec
+        options:
+          allow_networking: false # no network
+    - name: "with_requirements"
+      requirements:
+        executables: [echo]
+      run:
+        command: "echo hello"
+    - name: "plain"
+      run:
+        command: "echo plain"
+`
+
+func TestMigrateConfig(t *testing.T) {
+	migrated, version, changes, err := MigrateConfig([]byte(legacyConfig))
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1, got %d", version)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %v", changes)
+	}
+	if !strings.HasPrefix(string(migrated), "# Legacy tools\nversion: 2\n") {
+		t.Errorf("Expected the version after the comments, got:\n%s", migrated)
+	}
+	if !strings.Contains(string(migrated), "# no network") {
+		t.Errorf("Expected the comments to be kept, got:\n%s", migrated)
+	}
+
+	var cfg ToolsConfig
+	if err := yaml.Unmarshal(migrated, &cfg); err != nil {
+		t.Fatalf("Invalid migrated config: %v", err)
+	}
+	if cfg.Version != CurrentConfigVersion {
+		t.Errorf("Expected version %d, got %d", CurrentConfigVersion, cfg.Version)
+	}
+
+	expected := []MCPToolRunner{{
+		Name:         "sandbox-exec",
+		Requirements: MCPToolRequirements{OS: "darwin", Executables: []string{"ls"}},
+		Options:      map[string]interface{}{"allow_networking": false},
+	}}
+	if !reflect.DeepEqual(cfg.MCP.Tools[0].Run.Runners, expected) {
+		t.Errorf("Unexpected runners: %+v", cfg.MCP.Tools[0].Run.Runners)
+	}
+	expected = []MCPToolRunner{{
+		Name:         "exec",
+		Requirements: MCPToolRequirements{Executables: []string{"echo"}},
+	}}
+	if !reflect.DeepEqual(cfg.MCP.Tools[1].Run.Runners, expected) {
+		t.Errorf("Unexpected runners: %+v", cfg.MCP.Tools[1].Run.Runners)
+	}
+	if len(cfg.MCP.Tools[2].Run.Runners) != 0 {
+		t.Errorf("Unexpected runners: %+v", cfg.MCP.Tools[2].Run.Runners)
+	}
+
+	// migrating again does nothing
+	again, _, changes, err := MigrateConfig(migrated)
+	if err != nil || string(again) != string(migrated) || len(changes) != 0 {
+		t.Errorf("Expected the migrated config to be unchanged, got %v, %v:\n%s", err, changes, again)
+	}
+}
+
+func TestMigrateConfig_Versions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		wantErr string
+	}{
+		{
+			name:    "without version",
+			data:    "mcp:\n  tools: []\n",
+			version: 1,
+		},
+		{
+			name:    "current version",
+			data:    "version: 2\nmcp:\n  tools: []\n",
+			version: 2,
+		},
+		{
+			name:    "future version",
+			data:    "version: 99\nmcp:\n  tools: []\n",
+			wantErr: "upgrade MCPShell",
+		},
+		{
+			name:    "invalid version",
+			data:    "version: latest\nmcp:\n  tools: []\n",
+			wantErr: "invalid version",
+		},
+		{
+			name: "runner and runners",
+			data: `mcp:
+  tools:
+    - name: "both"
+      run:
+        command: "true"
+        runner: exec
+        runners: [{name: exec}]
+`,
+			wantErr: "both a runner and a list of runners",
+		},
+		{
+			name:    "encrypted",
+			data:    "mcp:\n  tools: []\nsops:\n  mac: ENC[AES256_GCM,data:bWFj,type:str]\n  version: 3.9.0\n",
+			wantErr: "SOPS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, version, changes, err := MigrateConfig([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if version != tt.version {
+				t.Errorf("Expected version %d, got %d", tt.version, version)
+			}
+			if len(changes) != 0 {
+				t.Errorf("Unexpected changes: %v", changes)
+			}
+			if !strings.HasPrefix(string(migrated), "version: 2\n") {
+				t.Errorf("Expected the current version, got:\n%s", migrated)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromFile_Legacy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, legacyConfig)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.MCP.Tools) != 3 || len(cfg.MCP.Tools[0].Run.Runners) != 1 || cfg.MCP.Tools[0].Run.Runners[0].Name != "sandbox-exec" {
+		t.Errorf("Expected the legacy runner to be migrated, got %+v", cfg.MCP.Tools)
+	}
+
+	writeFile(t, file, "version: 3\nmcp:\n  tools: []\n")
+	if _, err := NewConfigFromFile(file); err == nil {
+		t.Errorf("Expected an error for a future version")
+	}
+}
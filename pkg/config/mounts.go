@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// MCPToolMountConfig represents a directory of the host (or a temporary filesystem)
+// mounted in the containers of the container runners.
+type MCPToolMountConfig struct {
+	// HostPath is the path in the host (it can use template variables from the tool
+	// parameters). Relative paths are relative to the configuration file.
+	HostPath string `yaml:"host_path,omitempty"`
+
+	// ContainerPath is the (absolute) path in the container
+	ContainerPath string `yaml:"container_path"`
+
+	// ReadOnly mounts the path as read-only
+	ReadOnly bool `yaml:"read_only,omitempty"`
+
+	// Tmpfs mounts a temporary filesystem in the container path (without a host path)
+	Tmpfs bool `yaml:"tmpfs,omitempty"`
+}
+
+// applyMountPaths resolves the relative host paths of the mounts (and their roots) from
+// the directory of the configuration file
+func applyMountPaths(run *MCPToolRunConfig, configDir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) || strings.Contains(path, "{{") {
+			return path
+		}
+		return filepath.Join(configDir, path)
+	}
+
+	for i := range run.Mounts {
+		run.Mounts[i].HostPath = resolve(run.Mounts[i].HostPath)
+	}
+	for i := range run.MountRoots {
+		run.MountRoots[i] = resolve(run.MountRoots[i])
+	}
+}
+
+// checkToolMounts checks the mounts of the tools: the container paths must be absolute, and
+// the host paths (the ones without templates, as the others are checked when the tools are
+// called) must be inside the mount roots
+func checkToolMounts(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		for _, mount := range tool.Run.Mounts {
+			if !strings.HasPrefix(mount.ContainerPath, "/") {
+				return fmt.Errorf("tool '%s': the container path of the mounts must be absolute: '%s'", tool.Name, mount.ContainerPath)
+			}
+
+			switch {
+			case mount.Tmpfs && mount.HostPath != "":
+				return fmt.Errorf("tool '%s': the temporary filesystem in '%s' cannot have a host path", tool.Name, mount.ContainerPath)
+			case mount.Tmpfs:
+				continue
+			case mount.HostPath == "":
+				return fmt.Errorf("tool '%s': the mount in '%s' has no host path", tool.Name, mount.ContainerPath)
+			case strings.Contains(mount.HostPath, "{{"):
+				continue
+			}
+
+			if !filepath.IsAbs(mount.HostPath) {
+				return fmt.Errorf("tool '%s': the host path of the mounts must be absolute: '%s'", tool.Name, mount.HostPath)
+			}
+			if !common.IsPathInRoots(mount.HostPath, tool.Run.MountRoots) {
+				return fmt.Errorf("tool '%s': host path '%s' is not inside any of the mount roots: %s",
+					tool.Name, mount.HostPath, strings.Join(tool.Run.MountRoots, ", "))
+			}
+		}
+	}
+	return nil
+}
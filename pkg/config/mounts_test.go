@@ -0,0 +1,86 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigFromFile_Mounts(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  run:
+    mount_roots: ["data"]
+  tools:
+    - name: "analyze"
+      description: "Analyze the data"
+      run:
+        command: "analyze /data"
+        mounts:
+          - host_path: "data/reports"
+            container_path: "/data"
+            read_only: true
+          - host_path: "/srv/projects/{{ .project }}"
+            container_path: "/src"
+          - container_path: "/tmp"
+            tmpfs: true
+        runners:
+          - name: docker
+            options:
+              image: "alpine"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	run := cfg.MCP.Tools[0].Run
+	if !reflect.DeepEqual(run.MountRoots, []string{filepath.Join(dir, "data")}) {
+		t.Errorf("Unexpected mount roots: %v", run.MountRoots)
+	}
+	expected := []MCPToolMountConfig{
+		{HostPath: filepath.Join(dir, "data", "reports"), ContainerPath: "/data", ReadOnly: true},
+		{HostPath: "/srv/projects/{{ .project }}", ContainerPath: "/src"},
+		{ContainerPath: "/tmp", Tmpfs: true},
+	}
+	if !reflect.DeepEqual(run.Mounts, expected) {
+		t.Errorf("Unexpected mounts: %+v", run.Mounts)
+	}
+}
+
+func TestNewConfigFromFile_InvalidMounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		mounts  string
+		wantErr string
+	}{
+		{"relative container path", `[{host_path: "/data", container_path: "data"}]`, "must be absolute"},
+		{"no host path", `[{container_path: "/data"}]`, "has no host path"},
+		{"tmpfs with host path", `[{host_path: "/data", container_path: "/data", tmpfs: true}]`, "cannot have a host path"},
+		{"outside the roots", `[{host_path: "/etc", container_path: "/etc"}]`, "not inside any of the mount roots"},
+		{"escaping the roots", `[{host_path: "/srv/data/../../etc", container_path: "/etc"}]`, "not inside any of the mount roots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tools.yaml")
+			writeFile(t, file, `
+mcp:
+  tools:
+    - name: "analyze"
+      description: "Analyze the data"
+      run:
+        command: "analyze /data"
+        mount_roots: ["/srv/data"]
+        mounts: `+tt.mounts+`
+`)
+			if _, err := NewConfigFromFile(file); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
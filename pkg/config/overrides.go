@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overrides are the values replacing the ones in the configuration files when they are
+// loaded (ie, with the --set flag), as "path=value". The path has the keys separated by
+// dots, relative to the "mcp" section unless it starts with another top-level section,
+// and the items of the lists are addressed by their name (or by their index), so
+// "tools.deploy.run.timeout=30s" sets the timeout of the "deploy" tool.
+var Overrides []string
+
+// topLevelSections are the sections of the files that can start the path of an override
+var topLevelSections = []string{"version", "prompts", "secrets", "mcp"}
+
+// override is an override parsed
+type override struct {
+	raw   string
+	path  []string
+	value *yaml.Node
+}
+
+// parseOverride parses an override in the form "path=value", where the value
+// is a YAML value (ie, "30s", "true" or "[a, b]")
+func parseOverride(raw string) (override, error) {
+	path, value, found := strings.Cut(raw, "=")
+	path = strings.TrimSpace(path)
+	if !found || path == "" {
+		return override{}, fmt.Errorf("invalid override '%s': it must be path=value", raw)
+	}
+
+	res := override{raw: raw, path: strings.Split(path, ".")}
+	if slices.Contains(res.path, "") {
+		return override{}, fmt.Errorf("invalid override '%s': empty key in the path", raw)
+	}
+	if !slices.Contains(topLevelSections, res.path[0]) {
+		res.path = append([]string{"mcp"}, res.path...)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return override{}, fmt.Errorf("invalid value in override '%s': %w", raw, err)
+	}
+	if len(doc.Content) > 0 {
+		res.value = doc.Content[0]
+	} else {
+		res.value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+	}
+	return res, nil
+}
+
+// applyOverrides applies some overrides to the data of a configuration file, failing
+// when they do not match the structure of the configuration or an item of a list
+func applyOverrides(data []byte, overrides []string) ([]byte, error) {
+	if len(overrides) == 0 {
+		return data, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+
+	// the tools can be addressed by their name with the namespace of the file too
+	var namespace string
+	if ns := mappingValue(mappingValue(root, "mcp"), "namespace"); ns != nil {
+		namespace = ns.Value
+	}
+
+	for _, raw := range overrides {
+		o, err := parseOverride(raw)
+		if err != nil {
+			return nil, err
+		}
+		found, err := setOverride(root, reflect.TypeOf(ToolsConfig{}), o.path, o.value, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override '%s': %w", o.raw, err)
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid override '%s': no item found for '%s'", o.raw, strings.Join(o.path, "."))
+		}
+	}
+
+	return yaml.Marshal(&doc)
+}
+
+// setOverride sets the value of a path in a node with the type given, creating the
+// sections missing. It returns false when an item of a list is not found.
+func setOverride(node *yaml.Node, t reflect.Type, path []string, value *yaml.Node, namespace string) (bool, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	key := path[0]
+
+	var child reflect.Type
+	switch t.Kind() {
+	case reflect.Struct:
+		var ok bool
+		if child, ok = yamlFieldType(t, key); !ok {
+			return false, fmt.Errorf("unknown key '%s'", key)
+		}
+	case reflect.Map:
+		child = t.Elem()
+	case reflect.Interface:
+		child = t
+		if node.Kind == yaml.SequenceNode {
+			return setSequenceOverride(node, child, path, value, namespace)
+		}
+	case reflect.Slice, reflect.Array:
+		return setSequenceOverride(node, t.Elem(), path, value, namespace)
+	default:
+		return false, fmt.Errorf("'%s' is not in a section", key)
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode:
+	case node.Kind == 0 || node.Tag == "!!null":
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	default:
+		return false, fmt.Errorf("'%s' is not in a section", key)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if len(path) == 1 {
+			node.Content[i+1] = value
+			return true, nil
+		}
+		return setOverride(node.Content[i+1], child, path[1:], value, namespace)
+	}
+
+	// the key is not in the file: add it (with the sections in the path)
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if len(path) == 1 {
+		node.Content = append(node.Content, keyNode, value)
+		return true, nil
+	}
+	valueNode := &yaml.Node{}
+	found, err := setOverride(valueNode, child, path[1:], value, namespace)
+	if found && err == nil {
+		node.Content = append(node.Content, keyNode, valueNode)
+	}
+	return found, err
+}
+
+// setSequenceOverride sets the value of a path in a list, where the item is addressed
+// by its name (with or without the namespace) or by its index
+func setSequenceOverride(node *yaml.Node, t reflect.Type, path []string, value *yaml.Node, namespace string) (bool, error) {
+	if node.Kind != yaml.SequenceNode {
+		return false, nil
+	}
+
+	key := path[0]
+	var item int
+	if index, err := strconv.Atoi(key); err == nil {
+		if index < 0 || index >= len(node.Content) {
+			return false, nil
+		}
+		item = index
+	} else {
+		item = slices.IndexFunc(node.Content, func(n *yaml.Node) bool {
+			name := mappingValue(n, "name")
+			return name != nil && (name.Value == key || (namespace != "" && namespace+NamespaceSeparator+name.Value == key))
+		})
+		if item < 0 {
+			return false, nil
+		}
+	}
+
+	if len(path) == 1 {
+		node.Content[item] = value
+		return true, nil
+	}
+	return setOverride(node.Content[item], t, path[1:], value, namespace)
+}
+
+// yamlFieldType returns the type of the field of a struct with some YAML key,
+// looking in the structs inlined too
+func yamlFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			inlined := field.Type
+			for inlined.Kind() == reflect.Ptr {
+				inlined = inlined.Elem()
+			}
+			if inlined.Kind() == reflect.Struct {
+				if res, ok := yamlFieldType(inlined, key); ok {
+					return res, true
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigFromFile_Overrides(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  namespace: k8s
+  tools:
+    - name: "get_pods"
+      description: "Get the pods"
+      run:
+        command: "kubectl get pods"
+        timeout: "10s"
+    - name: "deploy"
+      description: "Deploy"
+      run:
+        command: "kubectl apply"
+`)
+
+	defer func() { Overrides = nil }()
+	Overrides = []string{
+		"tools.get_pods.run.timeout=30s",
+		"mcp.tools.k8s__deploy.run.timeout=5m",
+		"tools.1.run.env=[KUBECONFIG]",
+		"description=Kubernetes tools",
+		"run.shell=bash",
+	}
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("NewConfigFromFile() error = %v", err)
+	}
+	if got := cfg.MCP.Tools[0].Run.Timeout; got != "30s" {
+		t.Errorf("Expected the timeout of get_pods to be overridden, got %q", got)
+	}
+	if got := cfg.MCP.Tools[1].Run.Timeout; got != "5m" {
+		t.Errorf("Expected the timeout of deploy to be added, got %q", got)
+	}
+	if got := cfg.MCP.Tools[1].Run.Env; len(got) != 1 || got[0] != "KUBECONFIG" {
+		t.Errorf("Expected the env of deploy to be set, got %v", got)
+	}
+	if cfg.MCP.Description != "Kubernetes tools" || cfg.MCP.Run.Shell != "bash" {
+		t.Errorf("Expected the MCP settings to be overridden, got %q, %q", cfg.MCP.Description, cfg.MCP.Run.Shell)
+	}
+	if cfg.MCP.Tools[1].Name != "k8s__deploy" {
+		t.Errorf("Expected the namespace to be applied, got %q", cfg.MCP.Tools[1].Name)
+	}
+}
+
+func TestNewConfigFromFile_InvalidOverrides(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "greet"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`)
+
+	tests := []struct {
+		override string
+		wantErr  string
+	}{
+		{"tools.greet.run.timeout", "it must be path=value"},
+		{"tools..run.timeout=1s", "empty key"},
+		{"tools.greet.run.tiemout=1s", "unknown key 'tiemout'"},
+		{"tools.missing.run.timeout=1s", "no item found"},
+		{"tools.5.run.timeout=1s", "no item found"},
+		{"tools.greet.description.text=hi", "not in a section"},
+		{"tools.greet.run.timeout=[1s", "invalid value"},
+	}
+
+	defer func() { Overrides = nil }()
+	for _, tt := range tests {
+		t.Run(tt.override, func(t *testing.T) {
+			Overrides = []string{tt.override}
+			_, err := NewConfigFromFile(file)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewConfigFromFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAndMergeConfigs_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	writeFile(t, first, `
+mcp:
+  tools:
+    - name: "first"
+      description: "First"
+      run:
+        command: "echo first"
+`)
+	second := filepath.Join(dir, "second.yaml")
+	writeFile(t, second, `
+mcp:
+  tools:
+    - name: "second"
+      description: "Second"
+      run:
+        command: "echo second"
+`)
+
+	// the overrides are applied to the merged configuration, so they can
+	// address the tools of any file
+	defer func() { Overrides = nil }()
+	Overrides = []string{"tools.second.run.timeout=1m"}
+
+	merged, err := LoadAndMergeConfigs([]string{first, second})
+	if err != nil {
+		t.Fatalf("LoadAndMergeConfigs() error = %v", err)
+	}
+	data, err := merged.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML() error = %v", err)
+	}
+	mergedFile := filepath.Join(dir, "merged.yaml")
+	writeFile(t, mergedFile, string(data))
+
+	cfg, err := NewConfigFromFile(mergedFile)
+	if err != nil {
+		t.Fatalf("NewConfigFromFile() error = %v", err)
+	}
+	if got := cfg.MCP.Tools[1].Run.Timeout; got != "1m" {
+		t.Errorf("Expected the timeout of the second tool to be overridden, got %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// The policies for the prerequisites that are not met
+const (
+	PrerequisitePolicyFail = "fail" // the server does not start
+	PrerequisitePolicySkip = "skip" // the tool is disabled
+	PrerequisitePolicyWarn = "warn" // the tool is available anyway, with a warning in the logs
+)
+
+// PrerequisitePolicies are the valid policies for the prerequisites
+var PrerequisitePolicies = []string{PrerequisitePolicyFail, PrerequisitePolicySkip, PrerequisitePolicyWarn}
+
+// defaultProbeTimeout is the timeout of the connectivity probes when not configured
+const defaultProbeTimeout = 5 * time.Second
+
+// MCPToolPrerequisite is a condition checked when a tool is loaded, with exactly one
+// of binary, file, env or probe, and the policy applied when it is not met.
+type MCPToolPrerequisite struct {
+	// Binary is an executable that must be found in the PATH
+	Binary string `yaml:"binary,omitempty"`
+
+	// File is a file or directory that must exist (ie, "~/.kube/config")
+	File string `yaml:"file,omitempty"`
+
+	// Env is an environment variable that must be set
+	Env string `yaml:"env,omitempty"`
+
+	// Probe is an address that must be reachable: a TCP address ("host:port")
+	// or an http(s) URL
+	Probe string `yaml:"probe,omitempty"`
+
+	// Timeout is the timeout of the probe (default: 5s)
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// Policy is what happens when the prerequisite is not met: "fail" (the server does
+	// not start), "skip" (the tool is disabled, the default) or "warn"
+	Policy string `yaml:"policy,omitempty"`
+}
+
+// String returns a short description of the prerequisite (ie, "binary 'terraform'")
+func (p MCPToolPrerequisite) String() string {
+	switch {
+	case p.Binary != "":
+		return fmt.Sprintf("binary '%s'", p.Binary)
+	case p.File != "":
+		return fmt.Sprintf("file '%s'", p.File)
+	case p.Env != "":
+		return fmt.Sprintf("env '%s'", p.Env)
+	case p.Probe != "":
+		return fmt.Sprintf("probe '%s'", p.Probe)
+	default:
+		return "empty prerequisite"
+	}
+}
+
+// GetPolicy returns the policy of the prerequisite (skip by default)
+func (p MCPToolPrerequisite) GetPolicy() string {
+	if p.Policy == "" {
+		return PrerequisitePolicySkip
+	}
+	return p.Policy
+}
+
+// validate checks the prerequisite is well formed, without checking it
+func (p MCPToolPrerequisite) validate() error {
+	kinds := 0
+	for _, value := range []string{p.Binary, p.File, p.Env, p.Probe} {
+		if value != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("prerequisites must have exactly one of binary, file, env or probe")
+	}
+	if p.Timeout != "" {
+		if p.Probe == "" {
+			return fmt.Errorf("%s: timeout is only valid for probes", p)
+		}
+		if _, err := time.ParseDuration(p.Timeout); err != nil {
+			return fmt.Errorf("%s: invalid timeout '%s': %w", p, p.Timeout, err)
+		}
+	}
+	if p.Policy != "" && !slices.Contains(PrerequisitePolicies, p.Policy) {
+		return fmt.Errorf("%s: invalid policy '%s' (valid policies: %s)",
+			p, p.Policy, strings.Join(PrerequisitePolicies, ", "))
+	}
+	return nil
+}
+
+// prerequisiteResults caches the results of the prerequisites, so they are only
+// checked once (ie, when starting the server), by their description
+var prerequisiteResults sync.Map
+
+// Check checks the prerequisite, returning an error when it is not met.
+// The result is cached, so every prerequisite is only checked once.
+func (p MCPToolPrerequisite) Check() error {
+	key := p.String() + "\x00" + p.Timeout
+	if cached, ok := prerequisiteResults.Load(key); ok {
+		err, _ := cached.(error)
+		return err
+	}
+
+	err := p.check()
+	if err != nil {
+		prerequisiteResults.Store(key, err)
+	} else {
+		prerequisiteResults.Store(key, true)
+	}
+	return err
+}
+
+// check checks the prerequisite
+func (p MCPToolPrerequisite) check() error {
+	switch {
+	case p.Binary != "":
+		if !common.CheckExecutableExists(p.Binary) {
+			return fmt.Errorf("'%s' not found", p.Binary)
+		}
+		return nil
+	case p.File != "":
+		return common.CheckFileExists(p.File)
+	case p.Env != "":
+		return common.CheckEnvSet(p.Env)
+	case p.Probe != "":
+		timeout := defaultProbeTimeout
+		if p.Timeout != "" {
+			if d, err := time.ParseDuration(p.Timeout); err == nil {
+				timeout = d
+			}
+		}
+		return common.CheckConnectivity(p.Probe, timeout)
+	default:
+		return fmt.Errorf("empty prerequisite")
+	}
+}
+
+// PrerequisiteResult is the result of checking a prerequisite of a tool
+type PrerequisiteResult struct {
+	Prerequisite MCPToolPrerequisite
+	Err          error // nil when the prerequisite is met
+}
+
+// CheckPrerequisites checks all the prerequisites of the tool
+func (c MCPToolConfig) CheckPrerequisites() []PrerequisiteResult {
+	res := make([]PrerequisiteResult, 0, len(c.Prerequisites))
+	for _, p := range c.Prerequisites {
+		res = append(res, PrerequisiteResult{Prerequisite: p, Err: p.Check()})
+	}
+	return res
+}
+
+// checkPrerequisites checks the prerequisites of a tool when loading it, returning
+// false when the tool must be disabled. The prerequisites not met are logged.
+func checkPrerequisites(toolConfig MCPToolConfig) bool {
+	ok := true
+	for _, result := range toolConfig.CheckPrerequisites() {
+		if result.Err == nil {
+			continue
+		}
+		if result.Prerequisite.GetPolicy() == PrerequisitePolicyWarn {
+			common.GetLogger().Info("Tool '%s': prerequisite %s not met (ignored): %v",
+				toolConfig.Name, result.Prerequisite, result.Err)
+			continue
+		}
+		common.GetLogger().Info("Tool '%s' disabled: prerequisite %s not met: %v",
+			toolConfig.Name, result.Prerequisite, result.Err)
+		ok = false
+	}
+	return ok
+}
+
+// CheckPrerequisites checks the prerequisites of the enabled tools with the "fail"
+// policy, returning an error for the first one that is not met. Servers must not
+// start with these errors.
+func (c *ToolsConfig) CheckPrerequisites() error {
+	for _, toolConfig := range c.MCP.Tools {
+		if enabled, err := toolConfig.IsEnabled(); err != nil || !enabled {
+			continue
+		}
+		// the prerequisites of the tools running in the workers are not met in this host
+		if toolConfig.RunsOn != "" {
+			continue
+		}
+		for _, result := range toolConfig.CheckPrerequisites() {
+			if result.Err != nil && result.Prerequisite.GetPolicy() == PrerequisitePolicyFail {
+				return fmt.Errorf("tool '%s': prerequisite %s not met: %w",
+					toolConfig.Name, result.Prerequisite, result.Err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkToolPrerequisites checks the prerequisites of the tools are well formed
+func checkToolPrerequisites(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		for _, p := range tool.Prerequisites {
+			if err := p.validate(); err != nil {
+				return fmt.Errorf("tool '%s': %w", tool.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -50,6 +50,9 @@ func ResolveConfigPath(configPath string, logger *common.Logger) (string, func()
 		if err != nil {
 			return "", noopCleanup, err
 		}
+		if err := verifyConfigFile(resolvedPath, resolvedPath); err != nil {
+			return "", noopCleanup, err
+		}
 
 		logger.Info("Using local configuration file: %s", resolvedPath)
 		return resolvedPath, noopCleanup, nil
@@ -108,6 +111,11 @@ func ResolveConfigPath(configPath string, logger *common.Logger) (string, func()
 			return "", noopCleanup, fmt.Errorf("failed to close temporary file: %w", err)
 		}
 
+		if err := verifyConfigFile(configPath, tmpFilePath); err != nil {
+			cleanup()
+			return "", noopCleanup, err
+		}
+
 		logger.Info("Downloaded configuration to temporary file: %s", tmpFilePath)
 		return tmpFilePath, cleanup, nil
 	}
@@ -152,6 +160,12 @@ func resolveConfigDirectory(dirPath string, logger *common.Logger) (string, func
 
 	logger.Info("Found %d YAML files in directory", len(yamlFiles))
 
+	for _, yamlFile := range yamlFiles {
+		if err := verifyConfigFile(yamlFile, yamlFile); err != nil {
+			return "", func() {}, err
+		}
+	}
+
 	// If there's only one file, return it directly
 	if len(yamlFiles) == 1 {
 		logger.Info("Using single configuration file: %s", yamlFiles[0])
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// ScriptHeaderDelimiter is the line (after the comment marker) that starts
+// and ends the header of a script
+const ScriptHeaderDelimiter = "---"
+
+// ScriptParamEnvPrefix is the prefix of the environment variables used
+// for passing the parameters to scripts (ie, the "path" parameter is
+// passed in PARAM_PATH)
+const ScriptParamEnvPrefix = "PARAM_"
+
+// invalidToolNameChars matches the characters that cannot be used in tool names
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// MCPScriptsConfig represents a directory with executable scripts that are
+// exposed as tools. Scripts must contain a YAML header in their comments.
+type MCPScriptsConfig struct {
+	// Dir is the directory with the scripts.
+	// Relative paths are relative to the configuration file.
+	Dir string `yaml:"dir"`
+
+	// Namespace is a prefix added to the names of the tools
+	// (defaults to the namespace of the configuration file)
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// GetScriptTools scans all the scripts directories in the configuration,
+// returning the tools found.
+//
+// Returns:
+//   - A slice of tool configurations
+//   - An error if some directory cannot be read or some header is invalid
+func (c *ToolsConfig) GetScriptTools() ([]MCPToolConfig, error) {
+	var tools []MCPToolConfig
+	for _, scripts := range c.MCP.Scripts {
+		found, err := scripts.LoadTools(c.MCP.Run)
+		if err != nil {
+			return nil, err
+		}
+		localizeTools(found, c.getLocale())
+		resolveDefaultCommands(found)
+		if err := renderDescriptions(found, scripts.Namespace); err != nil {
+			return nil, err
+		}
+		tools = append(tools, found...)
+	}
+	return tools, nil
+}
+
+// ScriptsFingerprint returns a fingerprint of the scripts in all the
+// directories (names, sizes and modification times) that changes when
+// any script is added, removed or modified.
+func (c *ToolsConfig) ScriptsFingerprint() string {
+	h := sha256.New()
+	for _, scripts := range c.MCP.Scripts {
+		entries, err := os.ReadDir(scripts.Dir)
+		if err != nil {
+			fmt.Fprintf(h, "%s: %v\n", scripts.Dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(h, "%s/%s %d %d %v\n", scripts.Dir, entry.Name(), info.Size(), info.ModTime().UnixNano(), info.Mode())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LoadTools scans the directory, returning a tool for every executable
+// script with a header. Other files are ignored.
+//
+// Parameters:
+//   - run: The global run configuration, used as defaults for the tools
+//
+// Returns:
+//   - A slice of tool configurations (sorted by name)
+//   - An error if the directory cannot be read or some header is invalid
+func (s MCPScriptsConfig) LoadTools(run MCPRunConfig) ([]MCPToolConfig, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scripts directory %s: %w", s.Dir, err)
+	}
+
+	var tools []MCPToolConfig
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0o111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(s.Dir, entry.Name())
+		tool, found, err := loadScriptTool(path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		if s.Namespace != "" {
+			tool.Name = s.Namespace + NamespaceSeparator + tool.Name
+			for i := range tool.Aliases {
+				tool.Aliases[i] = s.Namespace + NamespaceSeparator + tool.Aliases[i]
+			}
+		}
+		if len(tool.Run.EnvPassthrough) == 0 {
+			tool.Run.EnvPassthrough = run.EnvPassthrough
+		}
+
+		tools = append(tools, tool)
+	}
+	applyRunners(tools, run)
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, nil
+}
+
+// loadScriptTool reads the header of a script, returning the tool configuration.
+// It returns false if the script has no header.
+//
+// The header is a YAML document with the same fields as the tools in configuration
+// files (except the name and command), placed in comments between "# ---" lines:
+//
+//	#!/bin/sh
+//	# ---
+//	# description: Show the disk usage of a directory
+//	# params:
+//	#   path:
+//	#     type: string
+//	#     description: The directory
+//	#     required: true
+//	# ---
+//	du -sh "$PARAM_PATH"
+//
+// The tool name is the script name (without the extension), and the parameters are
+// passed to the script as PARAM_<NAME> environment variables.
+func loadScriptTool(path string) (MCPToolConfig, bool, error) {
+	header, found, err := readScriptHeader(path)
+	if err != nil || !found {
+		return MCPToolConfig{}, found, err
+	}
+	if err := verifyConfigFile(path, path); err != nil {
+		return MCPToolConfig{}, false, err
+	}
+
+	var tool MCPToolConfig
+	if err := yaml.Unmarshal([]byte(header), &tool); err != nil {
+		return MCPToolConfig{}, false, fmt.Errorf("invalid header in script %s: %w", path, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	tool.Name = invalidToolNameChars.ReplaceAllString(name, "_")
+
+	// run the script directly, passing the parameters in the environment
+	tool.Run.Command = common.QuoteShellPath(path)
+	paramNames := make([]string, 0, len(tool.Params))
+	for paramName := range tool.Params {
+		paramNames = append(paramNames, paramName)
+	}
+	sort.Strings(paramNames)
+	for _, paramName := range paramNames {
+		envName := ScriptParamEnvPrefix + strings.ToUpper(invalidToolNameChars.ReplaceAllString(paramName, "_"))
+		tool.Run.Env = append(tool.Run.Env, fmt.Sprintf("%s={{ .%s }}", envName, paramName))
+	}
+
+	return tool, true, nil
+}
+
+// readScriptHeader reads the YAML header in the comments of a script
+func readScriptHeader(path string) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var lines []string
+	inHeader := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			// the header must be in the first block of comments
+			if inHeader || strings.TrimSpace(line) != "" {
+				break
+			}
+			continue
+		}
+
+		content := strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+		if strings.TrimSpace(content) == ScriptHeaderDelimiter {
+			if inHeader {
+				return strings.Join(lines, "\n"), true, nil
+			}
+			inHeader = true
+			continue
+		}
+		if inHeader {
+			lines = append(lines, content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read script %s: %w", path, err)
+	}
+	if inHeader {
+		return "", false, fmt.Errorf("unterminated header in script %s", path)
+	}
+
+	return "", false, nil
+}
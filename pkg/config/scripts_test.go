@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMCPScriptsConfig_LoadTools(t *testing.T) {
+	dir := t.TempDir()
+
+	writeScript := func(name string, content string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+			t.Fatalf("Failed to write script %s: %v", name, err)
+		}
+	}
+
+	writeScript("disk-usage.sh", `#!/bin/sh
+# ---
+# description: Show the disk usage of a directory
+# params:
+#   path:
+#     type: string
+#     description: The directory
+#     required: true
+#   max_depth:
+#     type: integer
+#     description: Maximum depth
+# constraints:
+#   - "path.startsWith('/')"
+# ---
+du -sh "$PARAM_PATH"
+`, 0o755)
+	writeScript("no-header.sh", "#!/bin/sh\n# just a script\necho hello\n", 0o755)
+	writeScript("not-executable.sh", "#!/bin/sh\n# ---\n# description: Not executable\n# ---\n", 0o644)
+	writeScript("late-header.sh", "#!/bin/sh\necho hello\n# ---\n# description: Not a header\n# ---\n", 0o755)
+
+	scripts := MCPScriptsConfig{Dir: dir, Namespace: "sys"}
+	tools, err := scripts.LoadTools(MCPRunConfig{EnvPassthrough: []string{"PATH"}})
+	if err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d: %+v", len(tools), tools)
+	}
+
+	tool := tools[0]
+	if tool.Name != "sys__disk-usage" {
+		t.Errorf("Expected tool name 'sys__disk-usage', got '%s'", tool.Name)
+	}
+	if tool.Description != "Show the disk usage of a directory" {
+		t.Errorf("Unexpected description: %q", tool.Description)
+	}
+	if len(tool.Params) != 2 || !tool.Params["path"].Required {
+		t.Errorf("Unexpected params: %+v", tool.Params)
+	}
+	if !reflect.DeepEqual(tool.Constraints, []string{"path.startsWith('/')"}) {
+		t.Errorf("Unexpected constraints: %v", tool.Constraints)
+	}
+	expectedCommand := "'" + filepath.Join(dir, "disk-usage.sh") + "'"
+	if tool.Run.Command != expectedCommand {
+		t.Errorf("Expected command %q, got %q", expectedCommand, tool.Run.Command)
+	}
+	expectedEnv := []string{"PARAM_MAX_DEPTH={{ .max_depth }}", "PARAM_PATH={{ .path }}"}
+	if !reflect.DeepEqual(tool.Run.Env, expectedEnv) {
+		t.Errorf("Expected env %v, got %v", expectedEnv, tool.Run.Env)
+	}
+	if !reflect.DeepEqual(tool.Run.EnvPassthrough, []string{"PATH"}) {
+		t.Errorf("Expected the global env passthrough, got %v", tool.Run.EnvPassthrough)
+	}
+
+	// invalid headers are reported
+	writeScript("invalid.sh", "#!/bin/sh\n# ---\n# description: [unterminated\n# ---\n", 0o755)
+	if _, err := scripts.LoadTools(MCPRunConfig{}); err == nil {
+		t.Errorf("Expected an error for an invalid header")
+	}
+}
+
+func TestGetTools_Scripts(t *testing.T) {
+	dir := t.TempDir()
+	scriptsDir := filepath.Join(dir, "scripts")
+	if err := os.Mkdir(scriptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"hello.sh", "static.sh"} {
+		content := "#!/bin/sh\n# ---\n# description: A script\n# ---\necho hello\n"
+		if err := os.WriteFile(filepath.Join(scriptsDir, name), []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  scripts:
+    - dir: scripts
+  tools:
+    - name: "static"
+      run:
+        command: "echo static"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MCP.Scripts[0].Dir != scriptsDir {
+		t.Errorf("Expected scripts dir %s, got %s", scriptsDir, cfg.MCP.Scripts[0].Dir)
+	}
+
+	var names []string
+	for _, tool := range cfg.GetTools() {
+		names = append(names, tool.MCPTool.Name)
+	}
+
+	// the "static" script is ignored, as there is a tool with the same name
+	expected := []string{"static", "hello"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The built-in seccomp profiles of the tools (any other value is the path to a JSON profile)
+const (
+	// SeccompDefault is the default seccomp profile of the runner
+	SeccompDefault = "default"
+
+	// SeccompNoNetworkNoExec is a curated profile denying the network (but Unix sockets)
+	// and running other programs, for the tools processing untrusted input
+	SeccompNoNetworkNoExec = "no-network-no-exec"
+)
+
+// IsBuiltinSeccomp returns true if a seccomp profile is one of the built-in profiles
+func IsBuiltinSeccomp(profile string) bool {
+	return profile == SeccompDefault || profile == SeccompNoNetworkNoExec
+}
+
+// applySeccompPath resolves the relative path of the seccomp profile from the directory
+// of the configuration file
+func applySeccompPath(run *MCPToolRunConfig, configDir string) {
+	if run.Seccomp != "" && !IsBuiltinSeccomp(run.Seccomp) && !filepath.IsAbs(run.Seccomp) {
+		run.Seccomp = filepath.Join(configDir, run.Seccomp)
+	}
+}
+
+// checkToolSeccomp checks the custom seccomp profiles of the tools are valid JSON documents
+func checkToolSeccomp(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		profile := tool.Run.Seccomp
+		if profile == "" || IsBuiltinSeccomp(profile) {
+			continue
+		}
+
+		data, err := os.ReadFile(profile)
+		if err != nil {
+			return fmt.Errorf("tool '%s': failed to read seccomp profile: %w", tool.Name, err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("tool '%s': invalid seccomp profile %s: %w", tool.Name, profile, err)
+		}
+	}
+	return nil
+}
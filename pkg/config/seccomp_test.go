@@ -0,0 +1,56 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigFromFile_Seccomp(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "seccomp.json"), `{"defaultAction": "SCMP_ACT_ALLOW"}`)
+	writeFile(t, filepath.Join(dir, "invalid.json"), `defaultAction: allow`)
+
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "custom"
+      description: "A tool with a custom profile"
+      run:
+        command: "ls"
+        seccomp: "seccomp.json"
+    - name: "builtin"
+      description: "A tool with a built-in profile"
+      run:
+        command: "ls"
+        seccomp: "no-network-no-exec"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.MCP.Tools[0].Run.Seccomp; got != filepath.Join(dir, "seccomp.json") {
+		t.Errorf("Unexpected seccomp profile: %s", got)
+	}
+	if got := cfg.MCP.Tools[1].Run.Seccomp; got != SeccompNoNetworkNoExec {
+		t.Errorf("Unexpected seccomp profile: %s", got)
+	}
+
+	// the custom profiles must exist, and be JSON documents
+	for profile, wantErr := range map[string]string{"missing.json": "failed to read", "invalid.json": "invalid seccomp profile"} {
+		writeFile(t, file, `
+mcp:
+  tools:
+    - name: "custom"
+      description: "A tool with a custom profile"
+      run:
+        command: "ls"
+        seccomp: "`+profile+`"
+`)
+		if _, err := NewConfigFromFile(file); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected an error with %q for %s, got %v", wantErr, profile, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// VerifyKeys are the public keys (or the files with them) for verifying the signatures of
+// the configuration files. When not empty, the configuration files (and the scripts) without
+// a valid signature from one of these keys are refused.
+var VerifyKeys []string
+
+// The extensions of the detached signatures, next to the signed files
+const (
+	MinisignSignatureExt = ".minisig" // for minisign keys
+	CosignSignatureExt   = ".sig"     // for PEM keys (ie, signed with "cosign sign-blob")
+)
+
+// verifyKey is a public key for verifying the signatures of the configuration files
+type verifyKey struct {
+	minisign *minisignKey     // for minisign keys
+	pem      crypto.PublicKey // for PEM keys (ECDSA, Ed25519 or RSA)
+}
+
+// minisignKey is a minisign public key
+type minisignKey struct {
+	keyNum []byte
+	key    ed25519.PublicKey
+}
+
+// signatureExt returns the extension of the signatures for the key
+func (k verifyKey) signatureExt() string {
+	if k.minisign != nil {
+		return MinisignSignatureExt
+	}
+	return CosignSignatureExt
+}
+
+// verify checks a signature of some data
+func (k verifyKey) verify(data, sig []byte) error {
+	if k.minisign != nil {
+		return k.minisign.verify(data, sig)
+	}
+
+	// cosign-style signatures are base64-encoded, but raw signatures are accepted too
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	digest := sha256.Sum256(data)
+
+	switch key := k.pem.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", k.pem)
+	}
+	return nil
+}
+
+// parseVerifyKey parses a public key: a minisign public key (the key itself or the file
+// generated by "minisign -G"), or a PEM public key (ie, the "cosign.pub" generated by
+// "cosign generate-key-pair")
+func parseVerifyKey(keyOrFile string) (verifyKey, error) {
+	data := []byte(keyOrFile)
+	if content, err := os.ReadFile(keyOrFile); err == nil {
+		data = content
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return verifyKey{}, fmt.Errorf("invalid PEM public key %s: %w", keyOrFile, err)
+		}
+		return verifyKey{pem: key}, nil
+	}
+
+	var encoded string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+			break
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return verifyKey{}, fmt.Errorf("invalid public key %s: expected a minisign or a PEM public key", keyOrFile)
+	}
+	return verifyKey{minisign: &minisignKey{keyNum: raw[2:10], key: ed25519.PublicKey(raw[10:])}}, nil
+}
+
+// verify checks a minisign signature, with the signature of the trusted comment
+func (k *minisignKey) verify(data, sig []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("invalid minisign signature")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("invalid minisign signature")
+	}
+	algorithm, keyNum, signature := string(raw[:2]), raw[2:10], raw[10:]
+	if !bytes.Equal(keyNum, k.keyNum) {
+		return fmt.Errorf("signed with a different key")
+	}
+
+	switch algorithm {
+	case "ED": // the default, signing the BLAKE2b-512 hash of the file
+		hash := blake2b.Sum512(data)
+		data = hash[:]
+	case "Ed": // legacy
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm '%s'", algorithm)
+	}
+	if !ed25519.Verify(k.key, data, signature) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	comment, found := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if !found || err != nil || !ed25519.Verify(k.key, append(signature, comment...), globalSignature) {
+		return fmt.Errorf("invalid signature of the trusted comment")
+	}
+	return nil
+}
+
+// verifyConfigFile checks a configuration file (or a script) has a valid signature from
+// one of the verify keys, in a detached signature next to it. The source is the path or
+// the URL the file was obtained from, where the signature is looked for.
+//
+// Parameters:
+//   - source: The path or the URL of the file
+//   - file: The local file (ie, the file downloaded from the URL)
+//
+// Returns:
+//   - An error if there are verify keys and the file is not signed by any of them
+func verifyConfigFile(source, file string) error {
+	if len(VerifyKeys) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var errs []string
+	for _, keyOrFile := range VerifyKeys {
+		key, err := parseVerifyKey(keyOrFile)
+		if err != nil {
+			return err
+		}
+
+		sig, err := readSignature(source, key.signatureExt())
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := key.verify(data, sig); err != nil {
+			errs = append(errs, fmt.Sprintf("%s%s: %v", source, key.signatureExt(), err))
+			continue
+		}
+
+		common.GetLogger().Debug("Verified the signature of %s", source)
+		return nil
+	}
+
+	return fmt.Errorf("refusing %s: no valid signature found (%s)", source, strings.Join(errs, "; "))
+}
+
+// readSignature reads the detached signature of a file or an URL
+func readSignature(source, ext string) ([]byte, error) {
+	parsedURL, err := url.Parse(source)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		sig, err := os.ReadFile(source + ext)
+		if err != nil {
+			return nil, fmt.Errorf("signature %s%s not found", source, ext)
+		}
+		return sig, nil
+	}
+
+	parsedURL.Path += ext
+	client := &http.Client{Timeout: common.DownloadTimeout}
+	resp, err := client.Get(parsedURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the signature %s: %w", parsedURL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signature %s not found (status code: %d)", parsedURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
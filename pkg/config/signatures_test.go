@@ -0,0 +1,222 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// minisignSigner signs files like minisign does
+type minisignSigner struct {
+	keyNum  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func newMinisignSigner(t *testing.T) *minisignSigner {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return &minisignSigner{keyNum: []byte("12345678"), private: private, public: public}
+}
+
+// publicKey returns the public key file
+func (s *minisignSigner) publicKey() string {
+	raw := append(append([]byte("Ed"), s.keyNum...), s.public...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+}
+
+// sign returns the signature file of some data
+func (s *minisignSigner) sign(data []byte) string {
+	hash := blake2b.Sum512(data)
+	signature := ed25519.Sign(s.private, hash[:])
+	raw := append(append([]byte("ED"), s.keyNum...), signature...)
+	comment := "timestamp:1700000000\tfile:tools.yaml"
+	global := ed25519.Sign(s.private, append(signature, comment...))
+	return "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(raw) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n"
+}
+
+func TestVerifyConfigFile_Minisign(t *testing.T) {
+	signer := newMinisignSigner(t)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "minisign.pub")
+	writeFile(t, keyFile, signer.publicKey())
+
+	content := []byte("mcp:\n  tools: []\n")
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, string(content))
+
+	// no keys: nothing is verified
+	if err := verifyConfigFile(file, file); err != nil {
+		t.Errorf("Expected no verification without keys, got %v", err)
+	}
+
+	VerifyKeys = []string{keyFile}
+	defer func() { VerifyKeys = nil }()
+
+	if err := verifyConfigFile(file, file); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an error for the unsigned file, got %v", err)
+	}
+
+	writeFile(t, file+MinisignSignatureExt, signer.sign(content))
+	if err := verifyConfigFile(file, file); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+
+	// the key can also be given directly
+	VerifyKeys = []string{strings.Split(signer.publicKey(), "\n")[1]}
+	if err := verifyConfigFile(file, file); err != nil {
+		t.Errorf("Expected a valid signature with the inline key, got %v", err)
+	}
+
+	// tampered files are refused
+	writeFile(t, file, "mcp:\n  tools:\n    - name: evil\n")
+	if err := verifyConfigFile(file, file); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("Expected an error for the tampered file, got %v", err)
+	}
+
+	// ... as well as the files signed with other keys
+	other := newMinisignSigner(t)
+	other.keyNum = []byte("87654321")
+	writeFile(t, file, string(content))
+	writeFile(t, file+MinisignSignatureExt, other.sign(content))
+	if err := verifyConfigFile(file, file); err == nil || !strings.Contains(err.Error(), "different key") {
+		t.Errorf("Expected an error for the signature of another key, got %v", err)
+	}
+}
+
+func TestVerifyConfigFile_Cosign(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "cosign.pub")
+	writeFile(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+	content := []byte("mcp:\n  tools: []\n")
+	digest := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, private, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, string(content))
+	writeFile(t, file+CosignSignatureExt, base64.StdEncoding.EncodeToString(sig))
+
+	VerifyKeys = []string{keyFile}
+	defer func() { VerifyKeys = nil }()
+
+	if err := verifyConfigFile(file, file); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+
+	writeFile(t, file, "mcp:\n  tools:\n    - name: evil\n")
+	if err := verifyConfigFile(file, file); err == nil {
+		t.Errorf("Expected an error for the tampered file")
+	}
+}
+
+func TestResolveConfigPath_Signatures(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	signer := newMinisignSigner(t)
+	VerifyKeys = []string{strings.Split(signer.publicKey(), "\n")[1]}
+	defer func() { VerifyKeys = nil }()
+
+	signed := []byte("mcp:\n  tools:\n    - name: signed\n      run:\n        command: echo signed\n")
+	unsigned := []byte("mcp:\n  tools:\n    - name: unsigned\n      run:\n        command: echo unsigned\n")
+
+	t.Run("local files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "signed.yaml"), string(signed))
+		writeFile(t, filepath.Join(dir, "signed.yaml"+MinisignSignatureExt), signer.sign(signed))
+		writeFile(t, filepath.Join(dir, "unsigned.yaml"), string(unsigned))
+
+		if _, _, err := ResolveConfigPath(filepath.Join(dir, "signed.yaml"), logger); err != nil {
+			t.Errorf("Expected the signed file to be accepted, got %v", err)
+		}
+		if _, _, err := ResolveConfigPath(filepath.Join(dir, "unsigned.yaml"), logger); err == nil {
+			t.Errorf("Expected the unsigned file to be refused")
+		}
+
+		// all the files in a directory must be signed
+		if _, cleanup, err := ResolveConfigPath(dir, logger); err == nil {
+			cleanup()
+			t.Errorf("Expected the directory with an unsigned file to be refused")
+		}
+	})
+
+	t.Run("URLs", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/signed.yaml":
+				_, _ = w.Write(signed)
+			case "/signed.yaml" + MinisignSignatureExt:
+				_, _ = w.Write([]byte(signer.sign(signed)))
+			case "/unsigned.yaml":
+				_, _ = w.Write(unsigned)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer srv.Close()
+
+		path, cleanup, err := ResolveConfigPath(srv.URL+"/signed.yaml", logger)
+		if err != nil {
+			t.Fatalf("Expected the signed URL to be accepted, got %v", err)
+		}
+		cleanup()
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected the downloaded file to be removed")
+		}
+
+		if _, _, err := ResolveConfigPath(srv.URL+"/unsigned.yaml", logger); err == nil {
+			t.Errorf("Expected the unsigned URL to be refused")
+		}
+	})
+
+	t.Run("scripts", func(t *testing.T) {
+		dir := t.TempDir()
+		script := "#!/bin/sh\n# ---\n# description: Say hello\n# ---\necho hello\n"
+		writeFile(t, filepath.Join(dir, "hello.sh"), script)
+
+		if _, _, err := loadScriptTool(filepath.Join(dir, "hello.sh")); err == nil {
+			t.Errorf("Expected the unsigned script to be refused")
+		}
+
+		writeFile(t, filepath.Join(dir, "hello.sh"+MinisignSignatureExt), signer.sign([]byte(script)))
+		if _, found, err := loadScriptTool(filepath.Join(dir, "hello.sh")); err != nil || !found {
+			t.Errorf("Expected the signed script to be loaded, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// sopsTimeout is the maximum time for decrypting a configuration file (ie, for
+// obtaining the data key from a KMS)
+const sopsTimeout = 30 * time.Second
+
+// isSopsEncrypted returns true if a YAML document has been encrypted with SOPS,
+// detected by the metadata of the encryption in the top-level "sops" key
+func isSopsEncrypted(data []byte) bool {
+	var doc struct {
+		Sops *struct {
+			Mac     string `yaml:"mac"`
+			Version string `yaml:"version"`
+		} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.Sops != nil && doc.Sops.Mac != "" && doc.Sops.Version != ""
+}
+
+// decryptSopsFile decrypts a SOPS-encrypted YAML file with the sops CLI, so all the
+// keys supported by sops (age, PGP, AWS/GCP KMS, Azure Key Vault...) can be used,
+// with their usual configuration (ie, SOPS_AGE_KEY_FILE or the AWS profiles).
+func decryptSopsFile(configFile string) ([]byte, error) {
+	if !common.CheckExecutableExists("sops") {
+		return nil, fmt.Errorf("the file is encrypted with SOPS, but sops is not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sopsTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", configFile)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to decrypt with sops: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to decrypt with sops: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsSopsEncrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{
+			name: "plain config",
+			data: "mcp:\n  tools: []\n",
+			want: false,
+		},
+		{
+			name: "encrypted config",
+			data: "mcp:\n  tools: []\nsops:\n  mac: ENC[AES256_GCM,data:abc,type:str]\n  version: 3.9.0\n",
+			want: true,
+		},
+		{
+			name: "sops key without metadata",
+			data: "sops:\n  enabled: true\n",
+			want: false,
+		},
+		{
+			name: "invalid YAML",
+			data: "mcp: [",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSopsEncrypted([]byte(tt.data)); got != tt.want {
+				t.Errorf("isSopsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromFile_Sops(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows")
+	}
+
+	encrypted := `
+mcp:
+  tools:
+    - name: "deploy"
+      run:
+        command: "deploy.sh"
+        env:
+          - ENC[AES256_GCM,data:c2VjcmV0,type:str]
+sops:
+  age:
+    - recipient: age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+  mac: ENC[AES256_GCM,data:bWFj,type:str]
+  version: 3.9.0
+`
+	decrypted := `
+mcp:
+  tools:
+    - name: "deploy"
+      run:
+        command: "deploy.sh"
+        env:
+          - API_TOKEN=s3cr3t
+`
+
+	// a fake sops, printing the decrypted file
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "decrypted.yaml"), decrypted)
+	writeFile(t, filepath.Join(binDir, "sops"), `#!/bin/sh
+[ "$1" = "--decrypt" ] || { echo "unexpected arguments: $*" >&2; exit 1; }
+grep -q "^sops:" "$6" || { echo "not encrypted" >&2; exit 1; }
+cat "`+filepath.Join(binDir, "decrypted.yaml")+`"
+`)
+	if err := os.Chmod(filepath.Join(binDir, "sops"), 0755); err != nil {
+		t.Fatalf("Failed to make the binary executable: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, encrypted)
+
+	t.Run("decrypted with sops", func(t *testing.T) {
+		t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		cfg, err := NewConfigFromFile(file)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if len(cfg.MCP.Tools) != 1 || len(cfg.MCP.Tools[0].Run.Env) != 1 || cfg.MCP.Tools[0].Run.Env[0] != "API_TOKEN=s3cr3t" {
+			t.Errorf("Expected the decrypted environment, got %+v", cfg.MCP.Tools)
+		}
+	})
+
+	t.Run("sops not installed", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		_, err := NewConfigFromFile(file)
+		if err == nil || !strings.Contains(err.Error(), "sops is not installed") {
+			t.Errorf("Expected an error about sops, got %v", err)
+		}
+	})
+}
@@ -1,11 +1,18 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/mark3labs/mcp-go/mcp"
 
 	"github.com/inercia/MCPShell/pkg/common"
 )
 
+// WorkerRunner is the runner of the tools running in the remote workers (with "runs_on")
+const WorkerRunner = "worker"
+
 // Tool holds an MCP tool and its associated handling information.
 type Tool struct {
 	// MCPTool is the MCP client-facing tool definition
@@ -17,6 +24,9 @@ type Tool struct {
 	// SelectedRunner is the runner that will be used to execute the tool command
 	// This is set during validation when a suitable runner is found
 	SelectedRunner *MCPToolRunner
+
+	// Secrets are the secrets that can be referenced by the tool (can be nil)
+	Secrets *common.Secrets
 }
 
 // checkToolRequirements checks if the tool has at least one runner that meets
@@ -25,6 +35,11 @@ type Tool struct {
 // Returns:
 //   - true if a suitable runner is found, false otherwise
 func (t *Tool) checkToolRequirements() bool {
+	// The tools running in the workers do not use the runners of this host
+	if t.Config.RunsOn != "" {
+		return true
+	}
+
 	// With the removal of deprecated fields, we now only support
 	// the runners mechanism
 	return t.findSuitableRunner()
@@ -88,6 +103,11 @@ func (t *Tool) GetEffectiveCommand() string {
 
 // GetEffectiveRunner returns the runner type that should be used.
 func (t *Tool) GetEffectiveRunner() string {
+	// The tools running in the workers are sent to them by the worker runner
+	if t.Config.RunsOn != "" {
+		return WorkerRunner
+	}
+
 	// Return the selected runner's name if we have one
 	if t.SelectedRunner != nil && t.SelectedRunner.Name != "" {
 		return t.SelectedRunner.Name
@@ -99,6 +119,11 @@ func (t *Tool) GetEffectiveRunner() string {
 
 // GetEffectiveOptions returns the runner options from the selected runner.
 func (t *Tool) GetEffectiveOptions() map[string]interface{} {
+	// The worker runner gets the label of the workers
+	if t.Config.RunsOn != "" {
+		return map[string]interface{}{"runs_on": t.Config.RunsOn}
+	}
+
 	// Return the selected runner's options if we have them
 	if t.SelectedRunner != nil && t.SelectedRunner.Options != nil {
 		return t.SelectedRunner.Options
@@ -108,6 +133,35 @@ func (t *Tool) GetEffectiveOptions() map[string]interface{} {
 	return nil
 }
 
+// GetEffectiveRunnerConstraints returns the constraints of the options of the selected runner.
+func (t *Tool) GetEffectiveRunnerConstraints() []string {
+	if t.Config.RunsOn != "" || t.SelectedRunner == nil {
+		return nil
+	}
+	return t.SelectedRunner.Constraints
+}
+
+// GetAliasTools returns the MCP tools for the aliases of the tool. They are
+// copies of the tool with a different name, and a note in the description.
+func (t *Tool) GetAliasTools() []mcp.Tool {
+	var res []mcp.Tool
+	for _, alias := range t.Config.Aliases {
+		aliasTool := t.MCPTool
+		aliasTool.Name = alias
+		aliasTool.Description = fmt.Sprintf("%s\n\n(alias of '%s': please use that name instead)",
+			strings.TrimSpace(t.MCPTool.Description), t.MCPTool.Name)
+		res = append(res, aliasTool)
+	}
+	return res
+}
+
+// paramFormats are the JSON Schema formats of the types of parameters with a format
+var paramFormats = map[string]string{
+	common.ParamTypeURL:      "uri",
+	common.ParamTypeEmail:    "email",
+	common.ParamTypeHostname: "hostname",
+}
+
 // CreateMCPTool creates an MCP tool from a tool configuration.
 //
 // Parameters:
@@ -118,25 +172,57 @@ func (t *Tool) GetEffectiveOptions() map[string]interface{} {
 func CreateMCPTool(config MCPToolConfig) mcp.Tool {
 	var options []mcp.ToolOption
 
-	// Add description
-	options = append(options, mcp.WithDescription(config.Description))
+	// Add description, advertising the output schema (if any)
+	description := config.Description
+	if config.OutputSchema != nil {
+		description = fmt.Sprintf("%s\n\nThe output is a JSON document with this JSON Schema:\n%s",
+			strings.TrimSpace(description), config.OutputSchema)
+	}
+	if config.Async {
+		description = fmt.Sprintf("%s\n\nThis tool runs in background: it returns a job ID immediately, "+
+			"that can be used with the 'job_status', 'job_logs' and 'job_kill' tools.",
+			strings.TrimSpace(description))
+	}
+	if config.Cost > 0 {
+		description = fmt.Sprintf("%s\n\nEvery call of this tool has an estimated cost of %g.",
+			strings.TrimSpace(description), config.Cost)
+	}
+	if config.Deprecated != nil {
+		description = fmt.Sprintf("%s\n\nDEPRECATED: %s",
+			strings.TrimSpace(description), config.Deprecated.Notice(config.Name))
+	}
+	options = append(options, mcp.WithDescription(description))
 
 	// Add parameters
 	for name, param := range config.Params {
-		// If type is not specified, default to "string"
-		paramType := param.Type
-		if paramType == "" {
-			paramType = "string"
+		// Hidden parameters are never shown to clients
+		if param.Hidden {
+			continue
 		}
 
+		// The types with string values (or no type) are strings in the schema
+		paramType := common.ParamValueType(param.Type)
+
 		// Create options for the parameter
 		var paramOptions []mcp.PropertyOption
 
 		// Add description
 		paramOptions = append(paramOptions, mcp.Description(param.Description))
 
-		// Add required option if needed
-		if param.Required {
+		// Add the format of the values
+		if format, found := paramFormats[param.Type]; found {
+			paramOptions = append(paramOptions, func(schema map[string]interface{}) {
+				schema["format"] = format
+			})
+		}
+
+		// Add the values allowed currently (the MCP server updates them when listing the tools)
+		if values, err := param.EnumValues(); err == nil && len(values) > 0 {
+			paramOptions = append(paramOptions, mcp.Enum(values...))
+		}
+
+		// Add required option if needed (unless the default is taken from the environment)
+		if param.Required && !hasEnvDefault(param) {
 			paramOptions = append(paramOptions, mcp.Required())
 		}
 
@@ -173,5 +259,47 @@ func CreateMCPTool(config MCPToolConfig) mcp.Tool {
 		}
 	}
 
+	// Add the annotations
+	options = append(options, mcp.WithToolAnnotation(createToolAnnotation(config.Annotations)))
+
 	return mcp.NewTool(config.Name, options...)
 }
+
+// hasEnvDefault returns true if the default value of a parameter
+// is taken from an environment variable that is currently set
+func hasEnvDefault(param common.ParamConfig) bool {
+	if param.DefaultFromEnv == "" {
+		return false
+	}
+	_, exists := os.LookupEnv(param.DefaultFromEnv)
+	return exists
+}
+
+// createToolAnnotation creates the MCP annotation for some annotations configuration,
+// using the MCP defaults for the hints that are not set.
+func createToolAnnotation(annotations MCPToolAnnotations) mcp.ToolAnnotation {
+	res := mcp.ToolAnnotation{
+		Title:           annotations.Title,
+		ReadOnlyHint:    false,
+		DestructiveHint: true,
+		IdempotentHint:  false,
+		OpenWorldHint:   true,
+	}
+
+	if annotations.ReadOnlyHint != nil {
+		res.ReadOnlyHint = *annotations.ReadOnlyHint
+		// read-only tools cannot be destructive
+		res.DestructiveHint = !res.ReadOnlyHint
+	}
+	if annotations.DestructiveHint != nil {
+		res.DestructiveHint = *annotations.DestructiveHint
+	}
+	if annotations.IdempotentHint != nil {
+		res.IdempotentHint = *annotations.IdempotentHint
+	}
+	if annotations.OpenWorldHint != nil {
+		res.OpenWorldHint = *annotations.OpenWorldHint
+	}
+
+	return res
+}
@@ -8,8 +8,15 @@ package config
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"gopkg.in/yaml.v3"
 
 	"github.com/inercia/MCPShell/pkg/common"
@@ -17,9 +24,16 @@ import (
 
 // ToolsConfig represents the top-level configuration structure for the application.
 type ToolsConfig struct {
+	// Version is the version of the format of the file (the current version when empty).
+	// Files in older versions are migrated when loaded.
+	Version int `yaml:"version,omitempty"`
+
 	// Prompts is a prompt configuration that will be provided to clients
 	Prompts common.PromptsConfig `yaml:"prompts,omitempty"`
 
+	// Secrets are the secrets that can be referenced by tools as {{ .Secrets.NAME }}
+	Secrets []common.SecretConfig `yaml:"secrets,omitempty"`
+
 	// MCP contains the configuration specific to the MCP server and tools
 	MCP MCPConfig `yaml:"mcp"`
 }
@@ -29,17 +43,276 @@ type MCPConfig struct {
 	// Description is a text shown to AI clients that explains what this server does
 	Description string `yaml:"description,omitempty"`
 
+	// Namespace is a prefix added to the names of all the tools in this file
+	// (ie, "k8s" turns "get_pods" into "k8s__get_pods")
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Locale is the locale of the descriptions of the tools in this file (ie, "es"),
+	// unless another one is selected globally (ie, with the --locale flag)
+	Locale string `yaml:"locale,omitempty"`
+
 	// Run contains runtime configuration
 	Run MCPRunConfig `yaml:"run,omitempty"`
 
 	// Tools is a list of tool definitions that will be provided to clients
 	Tools []MCPToolConfig `yaml:"tools"`
+
+	// Scripts is a list of directories with scripts that are exposed as tools
+	Scripts []MCPScriptsConfig `yaml:"scripts,omitempty"`
+
+	// State enables the built-in tools for keeping a key/value state in every session
+	State bool `yaml:"state,omitempty"`
+
+	// Schedules is a list of tools run periodically, with their results published as resources
+	Schedules []MCPScheduleConfig `yaml:"schedules,omitempty"`
+
+	// Auth configures the authentication of the clients of the HTTP transport,
+	// and the tools every client can see and call
+	Auth MCPAuthConfig `yaml:"auth,omitempty"`
+
+	// Idempotency configures the calls with idempotency keys
+	Idempotency MCPIdempotencyConfig `yaml:"idempotency,omitempty"`
+
+	// Workers configures the remote workers running the tools with "runs_on"
+	Workers MCPWorkersConfig `yaml:"workers,omitempty"`
+
+	// Jobs configures where the background jobs of the async tools are kept
+	Jobs MCPJobsConfig `yaml:"jobs,omitempty"`
+
+	// History configures where the history of the calls of the tools is kept
+	History MCPHistoryConfig `yaml:"history,omitempty"`
+
+	// Chaos configures the faults injected in the results of the tools (for testing)
+	Chaos MCPChaosConfig `yaml:"chaos,omitempty"`
+
+	// Quotas configures where the counters of the quotas of the tools are kept
+	Quotas MCPQuotasConfig `yaml:"quotas,omitempty"`
+
+	// Budget limits the estimated cost of the calls of the tools
+	Budget MCPBudgetConfig `yaml:"budget,omitempty"`
+}
+
+// MCPBudgetConfig represents the maximum estimated cost of the calls of the tools (with
+// a cost) in a session and in a day, with warnings to the clients as the limits approach
+type MCPBudgetConfig struct {
+	// Unit is the unit of the costs (ie, "USD" or "credits"), for the messages
+	Unit string `yaml:"unit,omitempty"`
+
+	// PerSession is the maximum cost of the calls in a MCP session (unlimited when 0)
+	PerSession float64 `yaml:"per_session,omitempty"`
+
+	// Daily is the maximum cost of the calls in a day (unlimited when 0)
+	Daily float64 `yaml:"daily,omitempty"`
+
+	// WarnAt is the fraction of the budgets (from 0 to 1) spent when the clients are
+	// warned (0.8 by default)
+	WarnAt float64 `yaml:"warn_at,omitempty"`
+}
+
+// Enabled returns true when there is some budget
+func (b MCPBudgetConfig) Enabled() bool {
+	return b.PerSession > 0 || b.Daily > 0
+}
+
+// MCPQuotasConfig represents the store of the counters of the quotas of the tools,
+// kept across the restarts of the server
+type MCPQuotasConfig struct {
+	// Backend is the type of store: "file" (the default, ~/.mcpshell/quotas.json)
+	Backend string `yaml:"backend,omitempty"`
+
+	// Options are the options of the store (ie, the "path" of the file)
+	Options map[string]interface{} `yaml:"options,omitempty"`
+}
+
+// MCPChaosConfig represents the faults injected randomly in the calls of the tools, so
+// the developers of the agents can check how they handle the failures. It is a testing
+// mode: it must not be used in production.
+type MCPChaosConfig struct {
+	// Seed is the seed of the random faults, for reproducing them (random by default)
+	Seed int64 `yaml:"seed,omitempty"`
+
+	// Latency is the probability (from 0 to 1) of delaying the result of a call
+	Latency float64 `yaml:"latency,omitempty"`
+
+	// MaxLatency is the maximum delay of the results, and the time the calls take
+	// before timing out ("5s" by default)
+	MaxLatency string `yaml:"max_latency,omitempty"`
+
+	// Truncation is the probability of truncating the output of a call
+	Truncation float64 `yaml:"truncation,omitempty"`
+
+	// Failure is the probability of failing a call with a non-zero exit status
+	Failure float64 `yaml:"failure,omitempty"`
+
+	// Timeout is the probability of failing a call with a timeout
+	Timeout float64 `yaml:"timeout,omitempty"`
+
+	// Tools are the tools with faults (all the tools by default)
+	Tools []string `yaml:"tools,omitempty"`
+}
+
+// Enabled returns true when some fault can be injected
+func (c MCPChaosConfig) Enabled() bool {
+	return c.Latency > 0 || c.Truncation > 0 || c.Failure > 0 || c.Timeout > 0
+}
+
+// MCPHistoryConfig represents the store of the history of the calls of the tools (the audit
+// trail of the calls of the clients and the results of the schedules). With a database,
+// the history is shared by all the instances of the server using it.
+type MCPHistoryConfig struct {
+	// Backend is the type of store: "dir", "sqlite", "postgres" or "sql"
+	Backend string `yaml:"backend,omitempty"`
+
+	// Options are the options of the store (ie, the "dir" or the "dsn" of the database)
+	Options map[string]interface{} `yaml:"options,omitempty"`
+}
+
+// MCPJobsConfig represents the backend of the background jobs. With an external backend
+// (ie, Redis) the jobs are queued, they survive restarts and they are shared by all the
+// instances of the server using it (ie, behind a load balancer).
+type MCPJobsConfig struct {
+	// Backend is the type of backend: "dir" (the default, a local directory) or "redis"
+	Backend string `yaml:"backend,omitempty"`
+
+	// Options are the options of the backend (ie, the "url" of Redis)
+	Options map[string]interface{} `yaml:"options,omitempty"`
+
+	// Concurrency is the number of queued jobs run at the same time by this instance
+	Concurrency int `yaml:"concurrency,omitempty"`
+}
+
+// MCPWorkersConfig represents the pool of remote workers coordinated by the server
+// (in HTTP mode), where the tools with "runs_on" are run
+type MCPWorkersConfig struct {
+	// TokenEnv is the environment variable with the token the workers must use for
+	// joining the pool. The workers are disabled when it is not set.
+	TokenEnv string `yaml:"token_env,omitempty"`
+}
+
+// MCPIdempotencyConfig represents the handling of the tool calls with idempotency keys:
+// the calls repeated with the same key return the original result, instead of running
+// the tools again (ie, when clients retry after a transport error).
+type MCPIdempotencyConfig struct {
+	// Window is the time the results are kept for the calls repeated (ie, "10m").
+	// "0" disables the idempotency keys.
+	Window string `yaml:"window,omitempty"`
+}
+
+// MCPAuthConfig represents the authentication of the clients and their access to the tools.
+// Clients are authenticated with tokens, or by a trusted proxy that sets their identity
+// in some headers (ie, with OAuth). Stdio clients are not authenticated.
+type MCPAuthConfig struct {
+	// Clients are the clients accepted, identified by their tokens
+	Clients []MCPAuthClientConfig `yaml:"clients,omitempty"`
+
+	// UserHeader is the header with the name of the user (ie, the OAuth subject),
+	// set by a trusted authenticating proxy
+	UserHeader string `yaml:"user_header,omitempty"`
+
+	// GroupsHeader is the header with the groups of the user (separated by commas),
+	// set by a trusted authenticating proxy
+	GroupsHeader string `yaml:"groups_header,omitempty"`
+
+	// TrustedProxies are the addresses (or networks, like "10.0.0.0/8") of the proxies
+	// setting the user headers, that are ignored in the requests from other addresses
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
+
+	// ACLs are the rules for the tools the clients can use. When there are no rules,
+	// all the clients can use all the tools.
+	ACLs []MCPACLConfig `yaml:"acls,omitempty"`
+
+	// AuditDir is a directory where the calls of every client are recorded,
+	// in a separate file per client (optional)
+	AuditDir string `yaml:"audit_dir,omitempty"`
+}
+
+// MCPAuthClientConfig represents a client authenticated with a bearer token.
+type MCPAuthClientConfig struct {
+	// Name is the identity of the client, used in the ACLs
+	Name string `yaml:"name"`
+
+	// TokenEnv is the environment variable with the token of the client
+	TokenEnv string `yaml:"token_env,omitempty"`
+
+	// TokenSecret is the name of the secret with the token of the client
+	TokenSecret string `yaml:"token_secret,omitempty"`
+
+	// Groups are the groups of the client, used in the ACLs
+	Groups []string `yaml:"groups,omitempty"`
+
+	// Tools are the names of the tools this client can use, as glob patterns
+	// (in addition to the tools allowed by the ACLs)
+	Tools []string `yaml:"tools,omitempty"`
+
+	// Tags are the tags of the tools this client can use
+	// (in addition to the tools allowed by the ACLs)
+	Tags []string `yaml:"tags,omitempty"`
+
+	// RateLimit is the maximum number of calls of the client, like "100/h" or "10/m"
+	RateLimit string `yaml:"rate_limit,omitempty"`
+
+	// Env are environment variables ("NAME=value") for the commands run for this client,
+	// overriding the variables of the tools
+	Env []string `yaml:"env,omitempty"`
+}
+
+// MCPACLConfig represents a rule giving some clients access to some tools.
+type MCPACLConfig struct {
+	// Clients are the names of the clients (or users) this rule applies to ("*" for everyone)
+	Clients []string `yaml:"clients,omitempty"`
+
+	// Groups are the groups this rule applies to
+	Groups []string `yaml:"groups,omitempty"`
+
+	// Tools are the names of the tools allowed, as glob patterns (ie, "k8s__*")
+	Tools []string `yaml:"tools,omitempty"`
+
+	// Tags are the tags of the tools allowed
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// MCPScheduleConfig represents a tool run periodically with fixed parameters.
+type MCPScheduleConfig struct {
+	// Name is the unique identifier of the schedule
+	Name string `yaml:"name"`
+
+	// Tool is the name of the tool to run (in the same file)
+	Tool string `yaml:"tool"`
+
+	// Cron is the cron expression for the runs (ie, "*/15 * * * *" or "@every 5m")
+	Cron string `yaml:"cron"`
+
+	// Params are the arguments passed to the tool
+	Params map[string]interface{} `yaml:"params,omitempty"`
 }
 
 // MCPRunConfig represents run-specific configuration options.
 type MCPRunConfig struct {
 	// Shell is the shell to use for executing commands (e.g., bash, sh, zsh)
 	Shell string `yaml:"shell,omitempty"`
+
+	// EnvPassthrough is the list of environment variables passed from the parent
+	// process to all the tools in this file (unless a tool sets its own list)
+	EnvPassthrough []string `yaml:"env_passthrough,omitempty"`
+
+	// EnvFile is a list of .env files with variables for all the tools in this file
+	EnvFile []string `yaml:"env_file,omitempty"`
+
+	// QuoteParams quotes all the substitutions in the commands of all the tools in
+	// this file (unless a tool sets its own value)
+	QuoteParams bool `yaml:"quote_params,omitempty"`
+
+	// Runner is the runner of the tools in this file without their own runners
+	// ("exec" by default)
+	Runner string `yaml:"runner,omitempty"`
+
+	// RunnerOptions are the options for every type of runner (ie, "docker"), used by
+	// the runners of that type in all the tools of this file (unless a runner sets them)
+	RunnerOptions map[string]map[string]interface{} `yaml:"runner_options,omitempty"`
+
+	// MountRoots is the default list of directories the host paths of the mounts of
+	// the tools must be contained in (for the tools without their own list)
+	MountRoots []string `yaml:"mount_roots,omitempty"`
 }
 
 // MCPToolConfig represents a single tool configuration.
@@ -50,17 +323,242 @@ type MCPToolConfig struct {
 	// Description explains what the tool does (shown to AI clients)
 	Description string `yaml:"description"`
 
+	// Descriptions are the translations of the description, by locale (ie, "es" or "pt-BR")
+	Descriptions map[string]string `yaml:"descriptions,omitempty"`
+
+	// Guidance explains when and how the tool should be used (ie, "always check
+	// the status before restarting a service"), added to the system prompt
+	Guidance string `yaml:"guidance,omitempty"`
+
+	// Type is the type of tool: empty for running a command in every call, or
+	// "shell_session" for running the commands in a long-lived shell per session
+	Type string `yaml:"type,omitempty"`
+
+	// Async runs the tool as a background job: calls return a job ID immediately,
+	// and the job can be managed with the built-in job tools
+	Async bool `yaml:"async,omitempty"`
+
+	// Aliases are additional names for the tool, sharing the same implementation
+	// (ie, the old names of renamed tools)
+	Aliases []string `yaml:"aliases,omitempty"`
+
+	// Tags are labels for organizing the tools by domain (ie, "k8s", "git"),
+	// usable for selecting tools
+	Tags []string `yaml:"tags,omitempty"`
+
+	// Deprecated marks the tool as deprecated, advertised in the description
+	// and returned as a warning with the results
+	Deprecated *MCPToolDeprecation `yaml:"deprecated,omitempty"`
+
+	// Enabled is a boolean or a CEL expression evaluated when loading the tool
+	// (with the env, os, arch and hostname variables). Tools are enabled by default.
+	Enabled string `yaml:"enabled,omitempty"`
+
+	// Requires are the binaries (and their versions) needed by the tool, checked when
+	// loading the tool. The tool is disabled when any of them is not met.
+	Requires MCPToolBinaryRequirements `yaml:"requires,omitempty"`
+
+	// Prerequisites are conditions (binaries, files, environment variables or connectivity)
+	// checked when loading the tool, with a policy for when they are not met
+	Prerequisites []MCPToolPrerequisite `yaml:"prerequisites,omitempty"`
+
 	// Params defines the parameters that the tool accepts
 	Params map[string]common.ParamConfig `yaml:"params"`
 
 	// Constraints are expressions that limit when the tool can be executed
 	Constraints []string `yaml:"constraints,omitempty"`
 
+	// AllowedHours are the ranges of hours when the tool can be called (ie, "09:00-18:00"),
+	// for restricting the destructive tools to the maintenance windows
+	AllowedHours []string `yaml:"allowed_hours,omitempty"`
+
+	// AllowedDays are the days of the week when the tool can be called (ie, "mon-fri")
+	AllowedDays []string `yaml:"allowed_days,omitempty"`
+
+	// Timezone is the time zone of the allowed hours and days (ie, "Europe/Madrid"),
+	// the local time zone by default
+	Timezone string `yaml:"timezone,omitempty"`
+
+	// MaxCallsPerSession is the maximum number of calls of the tool in a MCP session
+	// (ie, so an agent in a loop cannot restart a service fifty times), unlimited by default
+	MaxCallsPerSession int `yaml:"max_calls_per_session,omitempty"`
+
+	// Quota is the maximum number of calls of the tool per day and per week (ie, for
+	// tools using rate-limited or billable external APIs), kept across restarts
+	Quota MCPToolQuota `yaml:"quota,omitempty"`
+
+	// Cost is the estimated cost of every call of the tool (in the unit of the budget),
+	// counted in the budgets of the sessions and the days
+	Cost float64 `yaml:"cost,omitempty"`
+
+	// Computed are values derived from the parameters (with templates or CEL
+	// expressions), available in the command template like any other parameter
+	Computed []common.ComputedConfig `yaml:"computed,omitempty"`
+
 	// Run specifies how to execute the tool
 	Run MCPToolRunConfig `yaml:"run"`
 
+	// RunsOn is the label of the remote workers where the tool runs (ie, "gpu"),
+	// instead of this host. The runners, requirements and prerequisites of the tool
+	// are not checked in this host.
+	RunsOn string `yaml:"runs_on,omitempty"`
+
+	// Exec is the command to execute as a list of arguments (the program and its
+	// arguments), as an alternative to the command in Run. Every argument is a template,
+	// and the program is executed without any shell, so the values of the parameters are
+	// never interpreted. The rest of the settings in Run still apply.
+	Exec []string `yaml:"exec,omitempty"`
+
 	// Output specifies how to format the tool's output
 	Output common.OutputConfig `yaml:"output,omitempty"`
+
+	// OutputSchema is the JSON Schema of the output of the tool (optional),
+	// advertised to clients and validated when output.validate is enabled
+	OutputSchema common.JSONSchema `yaml:"output_schema,omitempty"`
+
+	// Annotations are hints about the tool behavior, shown to clients
+	Annotations MCPToolAnnotations `yaml:"annotations,omitempty"`
+
+	// Logging overrides the level of the logs of this tool (ie, "debug" for
+	// diagnosing a tool without the debug logs of all the others)
+	Logging common.LoggingConfig `yaml:"logging,omitempty"`
+
+	// SmokeTest is a call of the tool run when starting the server with the self-test,
+	// so the tool is not advertised when it fails (ie, in a broken environment)
+	SmokeTest *MCPToolSmokeTest `yaml:"smoke_test,omitempty"`
+}
+
+// TimeWindow returns the window of time when the tool can be called,
+// or nil when it can be called at any time
+func (t MCPToolConfig) TimeWindow() (*common.TimeWindow, error) {
+	if len(t.AllowedHours) == 0 && len(t.AllowedDays) == 0 {
+		if t.Timezone != "" {
+			return nil, fmt.Errorf("timezone without allowed_hours nor allowed_days")
+		}
+		return nil, nil
+	}
+	return common.NewTimeWindow(t.AllowedHours, t.AllowedDays, t.Timezone)
+}
+
+// MCPToolQuota represents the maximum number of calls of a tool in the current day and
+// in the current week (in UTC). The calls of all the clients count against the same quota,
+// unless the quota is per client.
+type MCPToolQuota struct {
+	// Daily is the maximum number of calls per day (0 for no limit)
+	Daily int `yaml:"daily,omitempty"`
+
+	// Weekly is the maximum number of calls per week, starting on Monday (0 for no limit)
+	Weekly int `yaml:"weekly,omitempty"`
+
+	// PerClient keeps a quota for every client, instead of a quota shared by all of them
+	PerClient bool `yaml:"per_client,omitempty"`
+}
+
+// Enabled returns true if the tool has some quota
+func (q MCPToolQuota) Enabled() bool {
+	return q.Daily > 0 || q.Weekly > 0
+}
+
+// MCPToolSmokeTest represents the smoke test of a tool: a call with some canned
+// parameters that must succeed. In YAML, it can be "true" (a call with the
+// default values of the parameters) or the details of the call.
+type MCPToolSmokeTest struct {
+	// Params are the parameters of the call
+	Params map[string]interface{} `yaml:"params,omitempty"`
+
+	// Expect is a regular expression the output must match (optional)
+	Expect string `yaml:"expect,omitempty"`
+}
+
+// UnmarshalYAML decodes a boolean or the details of the smoke test
+func (t *MCPToolSmokeTest) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!bool" {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil {
+			return err
+		}
+		if !enabled {
+			return fmt.Errorf("smoke_test must be true or the details of the test (remove it for disabling the test)")
+		}
+		*t = MCPToolSmokeTest{}
+		return nil
+	}
+
+	type plain MCPToolSmokeTest
+	return value.Decode((*plain)(t))
+}
+
+// MCPToolDeprecation represents the deprecation of a tool, so clients can
+// migrate to other tools before it is removed.
+type MCPToolDeprecation struct {
+	// ReplacedBy is the name of the tool that should be used instead
+	ReplacedBy string `yaml:"replaced_by,omitempty"`
+
+	// Message is an explanation for the clients (ie, how to migrate)
+	Message string `yaml:"message,omitempty"`
+
+	// SunsetDate is the date the tool will be removed (YYYY-MM-DD)
+	SunsetDate string `yaml:"sunset_date,omitempty"`
+}
+
+// Notice returns the deprecation notice of a tool, shown to the clients
+func (d MCPToolDeprecation) Notice(tool string) string {
+	notice := fmt.Sprintf("Tool '%s' is deprecated", tool)
+	if d.SunsetDate != "" {
+		notice += fmt.Sprintf(" and will be removed on %s", d.SunsetDate)
+	}
+	notice += "."
+	if d.ReplacedBy != "" {
+		notice += fmt.Sprintf(" Use '%s' instead.", d.ReplacedBy)
+	}
+	if d.Message != "" {
+		notice += " " + strings.TrimSpace(d.Message)
+	}
+	return notice
+}
+
+// MCPToolAnnotations represents the standard MCP annotations describing the
+// behavior of a tool. Clients can use them for applying their own policies
+// (ie, asking for confirmation before running destructive tools).
+// Unset hints use the MCP defaults.
+type MCPToolAnnotations struct {
+	// Title is a human-readable title for the tool
+	Title string `yaml:"title,omitempty"`
+
+	// ReadOnlyHint indicates the tool does not modify its environment (default: false)
+	ReadOnlyHint *bool `yaml:"read_only_hint,omitempty"`
+
+	// DestructiveHint indicates the tool may perform destructive updates
+	// (default: true, unless the tool is read-only)
+	DestructiveHint *bool `yaml:"destructive_hint,omitempty"`
+
+	// IdempotentHint indicates repeated calls with the same arguments have
+	// no additional effect (default: false)
+	IdempotentHint *bool `yaml:"idempotent_hint,omitempty"`
+
+	// OpenWorldHint indicates the tool interacts with external entities (default: true)
+	OpenWorldHint *bool `yaml:"open_world_hint,omitempty"`
+}
+
+// Names returns all the names of the tool: its name and its aliases
+func (c MCPToolConfig) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// HasAnyTag returns true if the tool has any of the tags given
+func (c MCPToolConfig) HasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(c.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled evaluates the enabled condition of the tool, returning
+// true if the tool should be available in the current environment.
+func (c MCPToolConfig) IsEnabled() (bool, error) {
+	return common.EvaluateCondition(c.Enabled)
 }
 
 // MCPToolRequirements represents a prerequisite tool configuration.
@@ -75,6 +573,79 @@ type MCPToolRequirements struct {
 	Executables []string `yaml:"executables"`
 }
 
+// MCPToolBinaryRequirement represents a binary needed by a tool, with an optional
+// version constraint checked with the output of a version command.
+type MCPToolBinaryRequirement struct {
+	// Binary is the name (or path) of the binary
+	Binary string `yaml:"binary"`
+
+	// Version is a semver constraint for the version of the binary (ie, ">=1.5")
+	Version string `yaml:"version,omitempty"`
+
+	// VersionArgs are the arguments for printing the version (default: "--version")
+	VersionArgs []string `yaml:"version_args,omitempty"`
+
+	// VersionRegex matches the version in the output of the version command, with the
+	// version in the first group (default: the first version number found)
+	VersionRegex string `yaml:"version_regex,omitempty"`
+}
+
+// Check checks the binary is installed with a version satisfying the constraint
+func (r MCPToolBinaryRequirement) Check() error {
+	return common.CheckBinaryVersion(r.Binary, r.Version, r.VersionArgs, r.VersionRegex)
+}
+
+// validate checks the requirement is well formed, without running the binary
+func (r MCPToolBinaryRequirement) validate() error {
+	if r.Binary == "" {
+		return fmt.Errorf("requirement without a binary")
+	}
+	if r.Version != "" {
+		if _, err := semver.NewConstraint(r.Version); err != nil {
+			return fmt.Errorf("invalid version constraint '%s' for '%s': %w", r.Version, r.Binary, err)
+		}
+	}
+	if r.VersionRegex != "" {
+		if _, err := regexp.Compile(r.VersionRegex); err != nil {
+			return fmt.Errorf("invalid version regex '%s' for '%s': %w", r.VersionRegex, r.Binary, err)
+		}
+	}
+	return nil
+}
+
+// MCPToolBinaryRequirements are the binaries needed by a tool. In YAML, it can be
+// a single requirement or a list of requirements.
+type MCPToolBinaryRequirements []MCPToolBinaryRequirement
+
+// UnmarshalYAML decodes a single requirement or a list of requirements
+func (r *MCPToolBinaryRequirements) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var req MCPToolBinaryRequirement
+		if err := value.Decode(&req); err != nil {
+			return err
+		}
+		*r = MCPToolBinaryRequirements{req}
+		return nil
+	}
+
+	var reqs []MCPToolBinaryRequirement
+	if err := value.Decode(&reqs); err != nil {
+		return err
+	}
+	*r = reqs
+	return nil
+}
+
+// Check checks all the requirements, returning the first one not met
+func (r MCPToolBinaryRequirements) Check() error {
+	for _, req := range r {
+		if err := req.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MCPToolRunner represents a specific execution environment for a tool.
 type MCPToolRunner struct {
 	// Name is the identifier for this runner (e.g., "osx", "linux")
@@ -83,8 +654,13 @@ type MCPToolRunner struct {
 	// Requirements are the prerequisites for this runner to be used
 	Requirements MCPToolRequirements `yaml:"requirements,omitempty"`
 
-	// Options for the runner
+	// Options for the runner. The string values can be templates with the parameters
+	// of the tool (ie, "image: registry.example.com/{{ .image }}").
 	Options map[string]interface{} `yaml:"options,omitempty"`
+
+	// Constraints are CEL expressions over the (rendered) string options of the runner,
+	// that must be true for running the command (ie, "image.startsWith('registry.example.com/')")
+	Constraints []string `yaml:"constraints,omitempty"`
 }
 
 // MCPToolRunConfig represents the run configuration for a tool.
@@ -92,29 +668,136 @@ type MCPToolRunConfig struct {
 	// Command is a template for the shell command to execute
 	Command string `yaml:"command"`
 
+	// QuoteParams quotes the result of all the substitutions in the command for the
+	// shell (ie, "{{ .name }}"), except for the ones ending in "raw". When not set,
+	// the global setting is used.
+	QuoteParams *bool `yaml:"quote_params,omitempty"`
+
 	// Env is a list of environment variable names to pass from the parent process
 	Env []string `yaml:"env,omitempty"`
 
+	// EnvPassthrough is the list of environment variables inherited from the parent process.
+	// When empty, the global list (or a minimal default list) is used.
+	EnvPassthrough []string `yaml:"env_passthrough,omitempty"`
+
+	// EnvFile is a list of .env files with variables for the command.
+	// Relative paths are relative to the configuration file.
+	EnvFile []string `yaml:"env_file,omitempty"`
+
+	// Workdir is the working directory where the command will be executed.
+	// It can use template variables from the tool parameters.
+	Workdir string `yaml:"workdir,omitempty"`
+
+	// WorkdirRoots is a list of directories the (processed) working directory
+	// must be contained in. If empty, any absolute directory is accepted.
+	WorkdirRoots []string `yaml:"workdir_roots,omitempty"`
+
+	// Workspace configures a temporary directory created for each execution
+	Workspace MCPToolWorkspaceConfig `yaml:"workspace,omitempty"`
+
+	// Stdin is a template for the standard input of the command (ie, "{{ .content }}").
+	// When empty, the standard input is empty.
+	Stdin string `yaml:"stdin,omitempty"`
+
+	// PTY runs the command in a pseudo-terminal, for commands that behave
+	// differently (or refuse to run) when they are not attached to a terminal
+	PTY bool `yaml:"pty,omitempty"`
+
+	// Terminal configures the size of the pseudo-terminal (when PTY is enabled)
+	Terminal MCPToolTerminalConfig `yaml:"terminal,omitempty"`
+
+	// Timeout is the maximum time the command can run (ie, "30s" or "5m").
+	// When empty, the command can run forever.
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// Termination is the sequence of signals sent to the command when it times
+	// out or it is cancelled. When empty, SIGTERM is sent, and then SIGKILL after 5s.
+	Termination []MCPToolTerminationConfig `yaml:"termination,omitempty"`
+
+	// Expect is a list of prompts to answer, in order, for commands that insist
+	// on asking questions. Commands with prompts are run in a pseudo-terminal.
+	Expect []MCPToolExpectConfig `yaml:"expect,omitempty"`
+
+	// Mounts are the host paths (or temporary filesystems) mounted in the containers,
+	// for the runners supporting them (ie, "docker")
+	Mounts []MCPToolMountConfig `yaml:"mounts,omitempty"`
+
+	// MountRoots is a list of directories the host paths of the mounts must be
+	// contained in. If empty, the global list is used (and any path if there is none).
+	MountRoots []string `yaml:"mount_roots,omitempty"`
+
+	// Seccomp is the seccomp profile applied by the runners supporting them (ie, "docker"
+	// or "firejail"): "default", "no-network-no-exec" or the path to a JSON profile
+	Seccomp string `yaml:"seccomp,omitempty"`
+
 	// Runners is a list of possible runner configurations
 	Runners []MCPToolRunner `yaml:"runners,omitempty"`
 }
 
+// MCPToolTerminalConfig represents the size of the pseudo-terminal
+// where the commands of a tool are run.
+type MCPToolTerminalConfig struct {
+	// Rows is the number of rows of the terminal (24 by default)
+	Rows int `yaml:"rows,omitempty"`
+
+	// Cols is the number of columns of the terminal (80 by default)
+	Cols int `yaml:"cols,omitempty"`
+}
+
+// MCPToolTerminationConfig represents a step in the termination of a command:
+// a signal, and the time to wait for the command to exit before the next step.
+type MCPToolTerminationConfig struct {
+	// Signal is the signal name (ie, "SIGINT")
+	Signal string `yaml:"signal"`
+
+	// Wait is the time to wait before the next step (ie, "5s")
+	Wait string `yaml:"wait,omitempty"`
+}
+
+// MCPToolExpectConfig represents a prompt expected in the output of a
+// command, and the response sent when it shows up.
+type MCPToolExpectConfig struct {
+	// Expect is a regular expression matching the prompt
+	Expect string `yaml:"expect"`
+
+	// Send is a template for the response (a newline is added)
+	Send string `yaml:"send"`
+}
+
+// MCPToolWorkspaceConfig represents the configuration of the ephemeral workspace
+// directory created for every execution of a tool.
+type MCPToolWorkspaceConfig struct {
+	// Enabled creates a temporary directory for every execution, available in
+	// templates as {{ .Workspace }}
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// RetainOnFailure keeps the workspace directory when the execution fails,
+	// so its contents can be inspected for debugging
+	RetainOnFailure bool `yaml:"retain_on_failure,omitempty"`
+}
+
 ////////////////////////////////////////////////////////////////////////////////////
 
-// NewConfigFromFile loads the configuration from a YAML file at the specified path.
+// NewConfigFromFile loads the configuration from a YAML file at the specified path,
+// applying the Overrides given (ie, with the --set flag).
 // The file path should already be resolved (use ResolveConfigPath for URL/directory resolution).
 //
 // Parameters:
-//   - filepath: Path to the YAML configuration file (should be absolute and resolved)
+//   - configFile: Path to the YAML configuration file (should be absolute and resolved)
 //
 // Returns:
 //   - A pointer to the loaded Config structure
 //   - An error if loading or parsing fails
-func NewConfigFromFile(filepath string) (*ToolsConfig, error) {
+func NewConfigFromFile(configFile string) (*ToolsConfig, error) {
+	return loadConfigFile(configFile, Overrides)
+}
+
+// loadConfigFile loads the configuration from a YAML file, applying some overrides
+func loadConfigFile(configFile string, overrides []string) (*ToolsConfig, error) {
 	// Open the configuration file
-	file, err := os.Open(filepath)
+	file, err := os.Open(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open config file %s: %w", filepath, err)
+		return nil, fmt.Errorf("failed to open config file %s: %w", configFile, err)
 	}
 	defer func() {
 		_ = file.Close()
@@ -123,28 +806,440 @@ func NewConfigFromFile(filepath string) (*ToolsConfig, error) {
 	// Read the file content
 	data, err := io.ReadAll(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", filepath, err)
+		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
+	}
+
+	// Decrypt the files encrypted with SOPS
+	if isSopsEncrypted(data) {
+		common.GetLogger().Debug("Decrypting SOPS-encrypted config file %s", configFile)
+		data, err = decryptSopsFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt config file %s: %w", configFile, err)
+		}
+	}
+
+	// Migrate the files in older versions of the format
+	data, err = migrateConfigData(data, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	// Apply the overrides of the values in the file
+	data, err = applyOverrides(data, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the overrides to config file %s: %w", configFile, err)
 	}
 
 	// Parse the YAML content
 	var config ToolsConfig
 	err = yaml.Unmarshal(data, &config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", filepath, err)
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+	}
+
+	config.applyRunDefaults(filepath.Dir(configFile))
+	config.applyLocale()
+
+	if err := config.applyToolTypes(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := config.applyNamespace(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	resolveDefaultCommands(config.MCP.Tools)
+	if err := config.applyDescriptionTemplates(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkDuplicateTools(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolDeprecations(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolRequires(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolSmokeTests(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolTimeWindows(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolMounts(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolSeccomp(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := checkToolPrerequisites(config.MCP.Tools); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
 	}
 
 	return &config, nil
 }
 
+// ToolTypeShellSession is the type of the tools that run the commands in a
+// long-lived shell per session, keeping the working directory and environment
+const ToolTypeShellSession = "shell_session"
+
+// ShellSessionCommandParam is the parameter with the command for shell session tools
+const ShellSessionCommandParam = "command"
+
+// applyToolTypes checks the types of the tools, applying the defaults of every type
+func (c *ToolsConfig) applyToolTypes() error {
+	for i := range c.MCP.Tools {
+		tool := &c.MCP.Tools[i]
+		if len(tool.Exec) > 0 && tool.Run.Command != "" {
+			return fmt.Errorf("tool '%s' cannot have both a command and exec arguments", tool.Name)
+		}
+
+		switch tool.Type {
+		case "":
+		case ToolTypeShellSession:
+			if len(tool.Exec) > 0 {
+				return fmt.Errorf("shell session tool '%s' cannot use exec arguments", tool.Name)
+			}
+			if len(tool.Params) == 0 {
+				tool.Params = map[string]common.ParamConfig{
+					ShellSessionCommandParam: {
+						Type:        "string",
+						Description: "The command to run in the shell (the working directory and environment are kept between calls)",
+						Required:    true,
+					},
+				}
+			}
+			if tool.Run.Command == "" {
+				tool.Run.Command = "{{ ." + ShellSessionCommandParam + " }}"
+			}
+		default:
+			return fmt.Errorf("unknown type '%s' for tool '%s'", tool.Type, tool.Name)
+		}
+	}
+	return nil
+}
+
+// NamespaceSeparator is the separator between the namespace and the tool name
+const NamespaceSeparator = "__"
+
+// namespaceRegex is the regular expression namespaces must match
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// resolveDefaultCommands runs the commands of the defaults of the parameters of some
+// tools. The failures are only logged, as the parameters keep their static defaults (if any).
+func resolveDefaultCommands(tools []MCPToolConfig) {
+	for i := range tools {
+		tool := &tools[i]
+		for name, param := range tool.Params {
+			if param.DefaultCommand == "" {
+				continue
+			}
+			if err := param.ResolveDefaultCommand(); err != nil {
+				common.GetLogger().Info("Tool '%s': default command of parameter '%s' ignored: %v", tool.Name, name, err)
+				continue
+			}
+			tool.Params[name] = param
+		}
+	}
+}
+
+// applyRunners selects the default runner for the tools without their own runners (except
+// for the shell sessions and the tools running in the workers, that do not use the runners),
+// and adds the options for every type of runner to the runners of that type in the tools
+func applyRunners(tools []MCPToolConfig, run MCPRunConfig) {
+	for i := range tools {
+		tool := &tools[i]
+		if len(tool.Run.Runners) == 0 && tool.Type != ToolTypeShellSession && tool.RunsOn == "" {
+			name := run.Runner
+			if _, found := run.RunnerOptions["exec"]; name == "" && found {
+				name = "exec"
+			}
+			if name != "" {
+				tool.Run.Runners = []MCPToolRunner{{Name: name}}
+			}
+		}
+
+		for j := range tool.Run.Runners {
+			runner := &tool.Run.Runners[j]
+			defaults := run.RunnerOptions[runner.Name]
+			if len(defaults) == 0 {
+				continue
+			}
+			options := maps.Clone(defaults)
+			maps.Copy(options, runner.Options)
+			runner.Options = options
+		}
+	}
+}
+
+// applyNamespace prefixes the names of all the tools with the namespace.
+// The namespace is removed once applied, so it is not applied again
+// when the configuration is serialized and loaded again.
+func (c *ToolsConfig) applyNamespace() error {
+	ns := c.MCP.Namespace
+	if ns == "" {
+		return nil
+	}
+	if !namespaceRegex.MatchString(ns) {
+		return fmt.Errorf("invalid namespace '%s': only letters, digits, '_' and '-' are allowed", ns)
+	}
+
+	for i := range c.MCP.Scripts {
+		if c.MCP.Scripts[i].Namespace == "" {
+			c.MCP.Scripts[i].Namespace = ns
+		}
+	}
+
+	for i := range c.MCP.Tools {
+		tool := &c.MCP.Tools[i]
+		tool.Name = ns + NamespaceSeparator + tool.Name
+		for j := range tool.Aliases {
+			tool.Aliases[j] = ns + NamespaceSeparator + tool.Aliases[j]
+		}
+	}
+
+	// schedules refer to the tools in the same file
+	for i := range c.MCP.Schedules {
+		c.MCP.Schedules[i].Tool = ns + NamespaceSeparator + c.MCP.Schedules[i].Tool
+	}
+	c.MCP.Namespace = ""
+
+	return nil
+}
+
+// checkDuplicateTools checks there are no tools (or aliases) with the same name
+func checkDuplicateTools(tools []MCPToolConfig) error {
+	seen := map[string]bool{}
+	for _, tool := range tools {
+		for _, name := range tool.Names() {
+			if seen[name] {
+				return fmt.Errorf("duplicate tool '%s' (consider using a namespace)", name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// checkToolDeprecations checks the deprecations of the tools are well formed
+func checkToolDeprecations(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		if tool.Deprecated == nil || tool.Deprecated.SunsetDate == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, tool.Deprecated.SunsetDate); err != nil {
+			return fmt.Errorf("tool '%s': invalid sunset date '%s' (expected YYYY-MM-DD)",
+				tool.Name, tool.Deprecated.SunsetDate)
+		}
+	}
+	return nil
+}
+
+// checkToolRequires checks the binary requirements of the tools are well formed
+func checkToolRequires(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		for _, req := range tool.Requires {
+			if err := req.validate(); err != nil {
+				return fmt.Errorf("tool '%s': %w", tool.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkToolSmokeTests checks the smoke tests of the tools are well formed
+func checkToolSmokeTests(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		if tool.SmokeTest == nil {
+			continue
+		}
+		for name := range tool.SmokeTest.Params {
+			if _, exists := tool.Params[name]; !exists {
+				return fmt.Errorf("tool '%s': smoke test with an unknown parameter '%s'", tool.Name, name)
+			}
+		}
+		if tool.SmokeTest.Expect != "" {
+			if _, err := regexp.Compile(tool.SmokeTest.Expect); err != nil {
+				return fmt.Errorf("tool '%s': invalid expected output of the smoke test: %w", tool.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkToolTimeWindows checks the allowed hours and days of the tools are valid,
+// as well as their limits of calls
+func checkToolTimeWindows(tools []MCPToolConfig) error {
+	for _, tool := range tools {
+		if _, err := tool.TimeWindow(); err != nil {
+			return fmt.Errorf("tool '%s': %w", tool.Name, err)
+		}
+		if tool.MaxCallsPerSession < 0 || tool.Quota.Daily < 0 || tool.Quota.Weekly < 0 {
+			return fmt.Errorf("tool '%s': the limits of calls cannot be negative", tool.Name)
+		}
+		if tool.Cost < 0 {
+			return fmt.Errorf("tool '%s': the cost cannot be negative", tool.Name)
+		}
+	}
+	return nil
+}
+
+// applyRunDefaults copies the global run settings to the tools that do not
+// set their own values, so they are kept when merging several files.
+// Relative env files are resolved from the directory of the configuration file.
+func (c *ToolsConfig) applyRunDefaults(configDir string) {
+	resolve := func(paths []string) []string {
+		res := make([]string, 0, len(paths))
+		for _, path := range paths {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(configDir, path)
+			}
+			res = append(res, path)
+		}
+		return res
+	}
+
+	globalEnvFiles := resolve(c.MCP.Run.EnvFile)
+	for i := range c.MCP.Tools {
+		run := &c.MCP.Tools[i].Run
+		if len(run.EnvPassthrough) == 0 {
+			run.EnvPassthrough = c.MCP.Run.EnvPassthrough
+		}
+		if run.QuoteParams == nil && c.MCP.Run.QuoteParams {
+			quote := true
+			run.QuoteParams = &quote
+		}
+		// global env files are loaded first, so tool env files take precedence
+		if envFiles := append(append([]string{}, globalEnvFiles...), resolve(run.EnvFile)...); len(envFiles) > 0 {
+			run.EnvFile = envFiles
+		}
+		if len(run.MountRoots) == 0 {
+			run.MountRoots = slices.Clone(c.MCP.Run.MountRoots)
+		}
+		applyMountPaths(run, configDir)
+		applySeccompPath(run, configDir)
+	}
+	applyRunners(c.MCP.Tools, c.MCP.Run)
+
+	// the global settings have been applied to all the tools
+	c.MCP.Run.EnvFile = nil
+
+	for i := range c.MCP.Scripts {
+		if dir := c.MCP.Scripts[i].Dir; dir != "" && !filepath.IsAbs(dir) {
+			c.MCP.Scripts[i].Dir = filepath.Join(configDir, dir)
+		}
+	}
+}
+
 // GetTools converts the configuration's tool definitions into a list of
 // executable ToolDefinition objects ready to be registered with the MCP server.
+// This includes the tools found in the scripts directories.
 //
 // Returns:
 //   - A slice of ToolDefinition objects
 func (c *ToolsConfig) GetTools() []Tool {
+	toolConfigs := c.MCP.Tools
+
+	// Add the tools in the scripts directories, unless their names are already in use
+	if len(c.MCP.Scripts) > 0 {
+		scriptTools, err := c.GetScriptTools()
+		if err != nil {
+			common.GetLogger().Error("Failed to load tools from scripts: %v", err)
+		}
+
+		names := map[string]bool{}
+		for _, toolConfig := range c.MCP.Tools {
+			for _, name := range toolConfig.Names() {
+				names[name] = true
+			}
+		}
+		for _, scriptTool := range scriptTools {
+			if names[scriptTool.Name] {
+				common.GetLogger().Error("Script tool '%s' ignored: there is another tool with the same name", scriptTool.Name)
+				continue
+			}
+			names[scriptTool.Name] = true
+			toolConfigs = append(toolConfigs, scriptTool)
+		}
+	}
+
+	return NewTools(toolConfigs)
+}
+
+// GetSystemPrompt returns the system prompt for the tools: the system prompts
+// in the configuration followed by the usage guidance of the tools (if any).
+//
+// Parameters:
+//   - tools: The tools available
+//
+// Returns:
+//   - The system prompt, or an empty string when there is none
+func (c *ToolsConfig) GetSystemPrompt(tools []Tool) string {
+	var sb strings.Builder
+	sb.WriteString(c.Prompts.GetSystemPrompts())
+
+	first := true
+	for _, tool := range tools {
+		guidance := strings.TrimSpace(tool.Config.Guidance)
+		if guidance == "" {
+			continue
+		}
+		if first {
+			if sb.Len() > 0 {
+				sb.WriteString("\n\n")
+			}
+			sb.WriteString("Guidance for using the tools:\n")
+			first = false
+		}
+		fmt.Fprintf(&sb, "\n- %s: %s", tool.MCPTool.Name, strings.ReplaceAll(guidance, "\n", "\n  "))
+	}
+
+	return sb.String()
+}
+
+// NewTools creates the tools for some tool configurations, skipping the tools
+// that are not enabled or whose prerequisites are not met.
+//
+// Parameters:
+//   - toolConfigs: The tools configurations
+//
+// Returns:
+//   - A slice of ToolDefinition objects
+func NewTools(toolConfigs []MCPToolConfig) []Tool {
 	var tools []Tool
 
-	for _, toolConfig := range c.MCP.Tools {
+	for _, toolConfig := range toolConfigs {
+		// Skip the tools that are not enabled in this environment
+		enabled, err := toolConfig.IsEnabled()
+		if err != nil {
+			common.GetLogger().Error("Tool '%s' disabled: %v", toolConfig.Name, err)
+			continue
+		}
+		if !enabled {
+			continue
+		}
+
+		// Skip the tools whose binaries are not installed (or too old), or whose
+		// prerequisites are not met (unless they only warn), when they run in this host
+		if toolConfig.RunsOn == "" {
+			if err := toolConfig.Requires.Check(); err != nil {
+				common.GetLogger().Info("Tool '%s' disabled: %v", toolConfig.Name, err)
+				continue
+			}
+			if !checkPrerequisites(toolConfig) {
+				continue
+			}
+		}
+
 		tool := Tool{
 			MCPTool: CreateMCPTool(toolConfig),
 			Config:  toolConfig,
@@ -161,6 +1256,63 @@ func (c *ToolsConfig) GetTools() []Tool {
 	return tools
 }
 
+// NewTool creates a tool from a configuration defined in code (ie, by the programs
+// embedding MCPShell), checking it like the tools in the configuration files.
+//
+// Parameters:
+//   - toolConfig: The tool configuration
+//
+// Returns:
+//   - The tool
+//   - An error if the tool is invalid, is not enabled or its prerequisites are not met
+func NewTool(toolConfig MCPToolConfig) (Tool, error) {
+	if toolConfig.Name == "" {
+		return Tool{}, fmt.Errorf("the tool has no name")
+	}
+
+	config := ToolsConfig{}
+	config.MCP.Tools = []MCPToolConfig{toolConfig}
+	config.applyLocale()
+
+	if err := config.applyToolTypes(); err != nil {
+		return Tool{}, err
+	}
+	resolveDefaultCommands(config.MCP.Tools)
+	if err := config.applyDescriptionTemplates(); err != nil {
+		return Tool{}, err
+	}
+	if err := checkDuplicateTools(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolDeprecations(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolRequires(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolSmokeTests(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolTimeWindows(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolMounts(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolSeccomp(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+	if err := checkToolPrerequisites(config.MCP.Tools); err != nil {
+		return Tool{}, err
+	}
+
+	tools := NewTools(config.MCP.Tools)
+	if len(tools) == 0 {
+		return Tool{}, fmt.Errorf("tool '%s' is not enabled, or its prerequisites are not met", toolConfig.Name)
+	}
+	return tools[0], nil
+}
+
 // ToYAML serializes the configuration back to YAML format.
 //
 // Returns:
@@ -173,9 +1325,11 @@ func (c *ToolsConfig) ToYAML() ([]byte, error) {
 // LoadAndMergeConfigs loads multiple configuration files and merges them into a single configuration.
 // The merging strategy is:
 // - Prompts are concatenated from all files
+// - Secrets are concatenated from all files
 // - MCP description from the first file is used (others are ignored)
 // - MCP run config from the first file is used (others are ignored)
-// - Tools from all files are combined
+// - The session state is enabled if any file enables it
+// - Tools from all files are combined, failing if a name (or alias) is found in more than one file
 //
 // Parameters:
 //   - filepaths: List of paths to YAML configuration files
@@ -188,11 +1342,13 @@ func LoadAndMergeConfigs(filepaths []string) (*ToolsConfig, error) {
 		return nil, fmt.Errorf("no configuration files provided")
 	}
 
-	var mergedConfig ToolsConfig
+	mergedConfig := ToolsConfig{Version: CurrentConfigVersion}
 	var isFirstFile = true
+	toolSources := map[string]string{}
 
 	for _, filepath := range filepaths {
-		config, err := NewConfigFromFile(filepath)
+		// the overrides are applied when loading the merged configuration
+		config, err := loadConfigFile(filepath, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load config file %s: %w", filepath, err)
 		}
@@ -201,14 +1357,84 @@ func LoadAndMergeConfigs(filepaths []string) (*ToolsConfig, error) {
 		mergedConfig.Prompts.System = append(mergedConfig.Prompts.System, config.Prompts.System...)
 		mergedConfig.Prompts.User = append(mergedConfig.Prompts.User, config.Prompts.User...)
 
+		// Merge secrets (duplicates are detected when creating the secrets)
+		mergedConfig.Secrets = append(mergedConfig.Secrets, config.Secrets...)
+
 		// For MCP config, use the first file's description and run config
 		if isFirstFile {
 			mergedConfig.MCP.Description = config.MCP.Description
+			mergedConfig.MCP.Locale = config.MCP.Locale
 			mergedConfig.MCP.Run = config.MCP.Run
+			// ... except for the settings already applied to the tools of the first file
+			mergedConfig.MCP.Run.EnvPassthrough = nil
+			mergedConfig.MCP.Run.QuoteParams = false
 			isFirstFile = false
 		}
 
-		// Merge tools (combine from all files)
+		mergedConfig.MCP.State = mergedConfig.MCP.State || config.MCP.State
+
+		// Merge the clients and the ACLs, using the first headers found
+		mergedConfig.MCP.Auth.Clients = append(mergedConfig.MCP.Auth.Clients, config.MCP.Auth.Clients...)
+		mergedConfig.MCP.Auth.ACLs = append(mergedConfig.MCP.Auth.ACLs, config.MCP.Auth.ACLs...)
+		if mergedConfig.MCP.Auth.UserHeader == "" {
+			mergedConfig.MCP.Auth.UserHeader = config.MCP.Auth.UserHeader
+			mergedConfig.MCP.Auth.GroupsHeader = config.MCP.Auth.GroupsHeader
+			mergedConfig.MCP.Auth.TrustedProxies = config.MCP.Auth.TrustedProxies
+		}
+		if mergedConfig.MCP.Auth.AuditDir == "" {
+			mergedConfig.MCP.Auth.AuditDir = config.MCP.Auth.AuditDir
+		}
+
+		// Use the first idempotency window found
+		if mergedConfig.MCP.Idempotency.Window == "" {
+			mergedConfig.MCP.Idempotency.Window = config.MCP.Idempotency.Window
+		}
+
+		// Use the first jobs backend found
+		if mergedConfig.MCP.Jobs.Backend == "" {
+			mergedConfig.MCP.Jobs = config.MCP.Jobs
+		}
+
+		// Use the first history store found
+		if mergedConfig.MCP.History.Backend == "" {
+			mergedConfig.MCP.History = config.MCP.History
+		}
+
+		// Use the first quotas store found
+		if mergedConfig.MCP.Quotas.Backend == "" {
+			mergedConfig.MCP.Quotas = config.MCP.Quotas
+		}
+
+		// Use the first budget found
+		if !mergedConfig.MCP.Budget.Enabled() {
+			mergedConfig.MCP.Budget = config.MCP.Budget
+		}
+
+		// Use the first chaos configuration found
+		if !mergedConfig.MCP.Chaos.Enabled() {
+			mergedConfig.MCP.Chaos = config.MCP.Chaos
+		}
+
+		// Use the first workers configuration found
+		if mergedConfig.MCP.Workers.TokenEnv == "" {
+			mergedConfig.MCP.Workers = config.MCP.Workers
+		}
+
+		// Merge schedules (duplicates are detected when starting them)
+		mergedConfig.MCP.Schedules = append(mergedConfig.MCP.Schedules, config.MCP.Schedules...)
+
+		// Merge scripts directories (combine from all files)
+		mergedConfig.MCP.Scripts = append(mergedConfig.MCP.Scripts, config.MCP.Scripts...)
+
+		// Merge tools (combine from all files), detecting collisions
+		for _, tool := range config.MCP.Tools {
+			for _, name := range tool.Names() {
+				if source, exists := toolSources[name]; exists {
+					return nil, fmt.Errorf("tool '%s' is defined in both %s and %s (consider using a namespace)", name, source, filepath)
+				}
+				toolSources[name] = filepath
+			}
+		}
 		mergedConfig.MCP.Tools = append(mergedConfig.MCP.Tools, config.MCP.Tools...)
 	}
 
@@ -1,8 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestCheckToolPrerequisites(t *testing.T) {
@@ -157,3 +165,910 @@ func TestCreateTools_Prerequisites(t *testing.T) {
 		t.Errorf("Expected tool named 'tool1', got '%s'", tools[0].MCPTool.Name)
 	}
 }
+
+func TestCreateTools_Enabled(t *testing.T) {
+	t.Setenv("TEST_TOOLS_ENABLED", "yes")
+
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "default"
+      run:
+        command: "echo default"
+    - name: "enabled"
+      enabled: true
+      run:
+        command: "echo enabled"
+    - name: "disabled"
+      enabled: false
+      run:
+        command: "echo disabled"
+    - name: "env_condition"
+      enabled: env.TEST_TOOLS_ENABLED == "yes"
+      run:
+        command: "echo env"
+    - name: "os_condition"
+      enabled: os == "plan9"
+      run:
+        command: "echo plan9"
+    - name: "invalid_condition"
+      enabled: "os =="
+      run:
+        command: "echo invalid"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var names []string
+	for _, tool := range cfg.GetTools() {
+		names = append(names, tool.MCPTool.Name)
+	}
+
+	expected := []string{"default", "enabled", "env_condition"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+}
+
+func TestCreateTools_Annotations(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "default"
+      run:
+        command: "echo default"
+    - name: "read_only"
+      annotations:
+        title: "Read Only"
+        read_only_hint: true
+        idempotent_hint: true
+      run:
+        command: "echo read_only"
+    - name: "local"
+      annotations:
+        destructive_hint: false
+        open_world_hint: false
+      run:
+        command: "echo local"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string]mcp.ToolAnnotation{
+		"default":   {DestructiveHint: true, OpenWorldHint: true},
+		"read_only": {Title: "Read Only", ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: true},
+		"local":     {},
+	}
+	for _, tool := range cfg.GetTools() {
+		if !reflect.DeepEqual(tool.MCPTool.Annotations, expected[tool.MCPTool.Name]) {
+			t.Errorf("Tool '%s': expected annotations %+v, got %+v", tool.MCPTool.Name, expected[tool.MCPTool.Name], tool.MCPTool.Annotations)
+		}
+	}
+}
+
+func TestCreateTools_OutputSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "disk_usage"
+      description: "Show the disk usage"
+      output_schema:
+        type: object
+        properties:
+          used:
+            type: integer
+      output:
+        validate: true
+      run:
+        command: "echo '{\"used\": 10}'"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tools := cfg.GetTools()
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(tools))
+	}
+	if !tools[0].Config.Output.Validate {
+		t.Errorf("Expected output validation to be enabled")
+	}
+	if err := tools[0].Config.OutputSchema.Check(); err != nil {
+		t.Errorf("Unexpected error checking the output schema: %v", err)
+	}
+
+	description := tools[0].MCPTool.Description
+	if !strings.HasPrefix(description, "Show the disk usage\n\n") || !strings.Contains(description, `"used": {`) {
+		t.Errorf("Output schema not advertised in the description: %q", description)
+	}
+}
+
+func TestCreateTools_HiddenParams(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "api_get"
+      params:
+        path:
+          type: string
+          required: true
+        endpoint:
+          type: string
+          hidden: true
+          default: "https://api.internal"
+      run:
+        command: "curl {{ .endpoint }}/{{ .path }}"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tools := cfg.GetTools()
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(tools))
+	}
+	if _, exists := tools[0].MCPTool.InputSchema.Properties["path"]; !exists {
+		t.Errorf("Expected parameter 'path' in the input schema")
+	}
+	if _, exists := tools[0].MCPTool.InputSchema.Properties["endpoint"]; exists {
+		t.Errorf("Hidden parameter 'endpoint' shown in the input schema")
+	}
+}
+
+func TestNewConfigFromFile_ToolTypes(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "shell"
+      type: shell_session
+      description: "A persistent shell"
+    - name: "echo"
+      run:
+        command: "echo hello"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	shell := cfg.MCP.Tools[0]
+	if shell.Run.Command != "{{ .command }}" {
+		t.Errorf("Expected the default command for shell sessions, got %q", shell.Run.Command)
+	}
+	if param, exists := shell.Params[ShellSessionCommandParam]; !exists || !param.Required {
+		t.Errorf("Expected a required '%s' parameter for shell sessions, got %v", ShellSessionCommandParam, shell.Params)
+	}
+	if cfg.MCP.Tools[1].Params != nil {
+		t.Errorf("Unexpected parameters added to a regular tool: %v", cfg.MCP.Tools[1].Params)
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	writeFile(t, invalid, `
+mcp:
+  tools:
+    - name: "unknown"
+      type: unknown
+      run:
+        command: "echo hello"
+`)
+	if _, err := NewConfigFromFile(invalid); err == nil {
+		t.Errorf("Expected an error for an unknown tool type")
+	}
+}
+
+func TestCreateTools_Requires(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows")
+	}
+
+	// a fake binary, printing its version in a couple of ways
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "fakeform"), `#!/bin/sh
+if [ "$1" = "version" ]; then echo "fakeform release 2.0.1"; exit 0; fi
+echo "Fakeform v1.6.2"
+echo "on linux_amd64"
+`)
+	if err := os.Chmod(filepath.Join(binDir, "fakeform"), 0755); err != nil {
+		t.Fatalf("Failed to make the binary executable: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "new_enough"
+      requires: { binary: fakeform, version: ">=1.5" }
+      run:
+        command: "fakeform apply"
+    - name: "too_old"
+      requires:
+        - binary: fakeform
+          version: ">=1.5"
+        - binary: fakeform
+          version: "<1.6"
+      run:
+        command: "fakeform apply"
+    - name: "custom_command"
+      requires:
+        binary: fakeform
+        version: "^2"
+        version_args: ["version"]
+        version_regex: 'release (\S+)'
+      run:
+        command: "fakeform apply"
+    - name: "missing"
+      requires: { binary: "mcpshell-missing-binary" }
+      run:
+        command: "mcpshell-missing-binary"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var names []string
+	for _, tool := range cfg.GetTools() {
+		names = append(names, tool.MCPTool.Name)
+	}
+
+	expected := []string{"new_enough", "custom_command"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+
+	// invalid requirements are rejected when loading the configuration
+	for name, requires := range map[string]string{
+		"no binary":          `{ version: ">=1.5" }`,
+		"invalid constraint": `{ binary: fakeform, version: "newer than 1.5" }`,
+		"invalid regex":      `{ binary: fakeform, version_regex: "[" }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+			writeFile(t, invalid, fmt.Sprintf(`
+mcp:
+  tools:
+    - name: "invalid"
+      requires: %s
+      run:
+        command: "fakeform apply"
+`, requires))
+			if _, err := NewConfigFromFile(invalid); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestCreateTools_ToolPrerequisites(t *testing.T) {
+	t.Setenv("MCPSHELL_TEST_PREREQ_SET", "yes")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "kubeconfig"), "")
+
+	// a listener, for the connectivity probes
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	file := filepath.Join(dir, "tools.yaml")
+	writeFile(t, file, fmt.Sprintf(`
+mcp:
+  tools:
+    - name: "all_met"
+      prerequisites:
+        - env: MCPSHELL_TEST_PREREQ_SET
+        - file: %q
+        - probe: %q
+      run:
+        command: "echo ok"
+    - name: "missing_file"
+      prerequisites:
+        - file: %q
+      run:
+        command: "echo ok"
+    - name: "missing_env_warn"
+      prerequisites:
+        - env: MCPSHELL_TEST_PREREQ_UNSET
+          policy: warn
+      run:
+        command: "echo ok"
+    - name: "missing_binary_fail"
+      prerequisites:
+        - binary: mcpshell-missing-binary
+          policy: fail
+      run:
+        command: "echo ok"
+`, filepath.Join(dir, "kubeconfig"), listener.Addr().String(), filepath.Join(dir, "missing")))
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var names []string
+	for _, tool := range cfg.GetTools() {
+		names = append(names, tool.MCPTool.Name)
+	}
+	expected := []string{"all_met", "missing_env_warn"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+
+	// the prerequisites with the "fail" policy prevent starting
+	if err := cfg.CheckPrerequisites(); err == nil || !strings.Contains(err.Error(), "missing_binary_fail") {
+		t.Errorf("Expected an error for the failed prerequisite, got %v", err)
+	}
+
+	// invalid prerequisites are rejected when loading the configuration
+	for name, prerequisite := range map[string]string{
+		"empty":           `{ policy: warn }`,
+		"several kinds":   `{ env: HOME, file: /tmp }`,
+		"invalid policy":  `{ env: HOME, policy: ignore }`,
+		"invalid timeout": `{ probe: "localhost:1", timeout: soon }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+			writeFile(t, invalid, fmt.Sprintf(`
+mcp:
+  tools:
+    - name: "invalid"
+      prerequisites: [%s]
+      run:
+        command: "echo ok"
+`, prerequisite))
+			if _, err := NewConfigFromFile(invalid); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestCreateTools_Deprecated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "old_tool"
+      description: "Does something"
+      deprecated:
+        replaced_by: "new_tool"
+        message: "The new tool also supports dry runs."
+        sunset_date: "2030-01-01"
+      run:
+        command: "echo old"
+`)
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tools := cfg.GetTools()
+	if len(tools) != 1 {
+		t.Fatalf("Expected the deprecated tool to be available, got %d tools", len(tools))
+	}
+	want := "Does something\n\nDEPRECATED: Tool 'old_tool' is deprecated and will be removed on 2030-01-01. " +
+		"Use 'new_tool' instead. The new tool also supports dry runs."
+	if tools[0].MCPTool.Description != want {
+		t.Errorf("Unexpected description:\n%s", tools[0].MCPTool.Description)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	writeFile(t, invalid, `
+mcp:
+  tools:
+    - name: "old_tool"
+      deprecated:
+        sunset_date: "next year"
+      run:
+        command: "echo old"
+`)
+	if _, err := NewConfigFromFile(invalid); err == nil {
+		t.Errorf("Expected an error for an invalid sunset date")
+	}
+}
+
+func TestNewConfigFromFile_Exec(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "list_files"
+      exec: ["ls", "-l", "{{ .path }}"]
+      run:
+        timeout: "10s"
+`)
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.MCP.Tools[0].Exec, []string{"ls", "-l", "{{ .path }}"}) {
+		t.Errorf("Unexpected exec arguments: %v", cfg.MCP.Tools[0].Exec)
+	}
+
+	tests := map[string]string{
+		"command and exec": `
+mcp:
+  tools:
+    - name: "both"
+      exec: ["ls"]
+      run:
+        command: "ls"
+`,
+		"shell session with exec": `
+mcp:
+  tools:
+    - name: "shell"
+      type: shell_session
+      exec: ["sh"]
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(dir, "invalid.yaml")
+			writeFile(t, invalid, content)
+			if _, err := NewConfigFromFile(invalid); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewConfigFromFile_SmokeTest(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "simple"
+      smoke_test: true
+      run:
+        command: "echo ok"
+    - name: "greet"
+      params:
+        name:
+          type: string
+      smoke_test:
+        params: { name: "smoke" }
+        expect: "^hello"
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "untested"
+      run:
+        command: "echo ok"
+`)
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if test := cfg.MCP.Tools[0].SmokeTest; test == nil || len(test.Params) != 0 {
+		t.Errorf("Unexpected smoke test for 'smoke_test: true': %+v", test)
+	}
+	if test := cfg.MCP.Tools[1].SmokeTest; test == nil || test.Params["name"] != "smoke" || test.Expect != "^hello" {
+		t.Errorf("Unexpected smoke test: %+v", test)
+	}
+	if cfg.MCP.Tools[2].SmokeTest != nil {
+		t.Errorf("Expected no smoke test, got %+v", cfg.MCP.Tools[2].SmokeTest)
+	}
+
+	tests := map[string]string{
+		"false": `
+mcp:
+  tools:
+    - name: "tool"
+      smoke_test: false
+      run:
+        command: "echo ok"
+`,
+		"unknown parameter": `
+mcp:
+  tools:
+    - name: "tool"
+      smoke_test:
+        params: { name: "smoke" }
+      run:
+        command: "echo ok"
+`,
+		"invalid expect": `
+mcp:
+  tools:
+    - name: "tool"
+      smoke_test:
+        expect: "[ok"
+      run:
+        command: "echo ok"
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(dir, "invalid.yaml")
+			writeFile(t, invalid, content)
+			if _, err := NewConfigFromFile(invalid); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewConfigFromFile_TimeWindow(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "drop_table"
+      allowed_hours: ["22:00-06:00"]
+      allowed_days: ["sat", "sun"]
+      timezone: "UTC"
+      run:
+        command: "echo dropped"
+`)
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	window, err := cfg.MCP.Tools[0].TimeWindow()
+	if err != nil || window.String() != "sat, sun 22:00-06:00 (UTC)" {
+		t.Errorf("Unexpected time window: %v, %v", window, err)
+	}
+
+	tests := map[string]string{
+		"invalid hours": `
+mcp:
+  tools:
+    - name: "tool"
+      allowed_hours: ["9-18"]
+      run:
+        command: "echo ok"
+`,
+		"invalid timezone": `
+mcp:
+  tools:
+    - name: "tool"
+      allowed_days: ["mon-fri"]
+      timezone: "Nowhere/City"
+      run:
+        command: "echo ok"
+`,
+		"timezone without window": `
+mcp:
+  tools:
+    - name: "tool"
+      timezone: "UTC"
+      run:
+        command: "echo ok"
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			invalid := filepath.Join(dir, "invalid.yaml")
+			writeFile(t, invalid, content)
+			if _, err := NewConfigFromFile(invalid); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadAndMergeConfigs_RunDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	writeFile(t, file1, `
+mcp:
+  run:
+    env_passthrough: [PATH, KUBECONFIG]
+    env_file: [global.env]
+    quote_params: true
+  tools:
+    - name: "tool1"
+      run:
+        command: "kubectl get pods"
+    - name: "tool2"
+      run:
+        command: "echo hello"
+        quote_params: false
+        env_passthrough: [PATH]
+        env_file: [/etc/tool2.env]
+`)
+	writeFile(t, file2, `
+mcp:
+  tools:
+    - name: "tool3"
+      run:
+        command: "echo hello"
+        env_file: [tool3.env]
+`)
+
+	cfg, err := LoadAndMergeConfigs([]string{file1, file2})
+	if err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	// the merged configuration is written and loaded again, so it must not change
+	merged := filepath.Join(dir, "merged.yaml")
+	data, err := cfg.ToYAML()
+	if err != nil {
+		t.Fatalf("Failed to serialize merged config: %v", err)
+	}
+	writeFile(t, merged, string(data))
+	reloaded, err := NewConfigFromFile(merged)
+	if err != nil {
+		t.Fatalf("Failed to reload merged config: %v", err)
+	}
+
+	expectedPassthrough := map[string][]string{
+		"tool1": {"PATH", "KUBECONFIG"},
+		"tool2": {"PATH"},
+		"tool3": nil,
+	}
+	expectedEnvFile := map[string][]string{
+		"tool1": {filepath.Join(dir, "global.env")},
+		"tool2": {filepath.Join(dir, "global.env"), "/etc/tool2.env"},
+		"tool3": {filepath.Join(dir, "tool3.env")},
+	}
+	expectedQuote := map[string]string{
+		"tool1": "true",
+		"tool2": "false",
+		"tool3": "unset",
+	}
+	for _, c := range []*ToolsConfig{cfg, reloaded} {
+		for _, tool := range c.MCP.Tools {
+			quote := "unset"
+			if tool.Run.QuoteParams != nil {
+				quote = fmt.Sprint(*tool.Run.QuoteParams)
+			}
+			if quote != expectedQuote[tool.Name] {
+				t.Errorf("Tool '%s': expected quote_params %s, got %s", tool.Name, expectedQuote[tool.Name], quote)
+			}
+			if !reflect.DeepEqual(tool.Run.EnvPassthrough, expectedPassthrough[tool.Name]) {
+				t.Errorf("Tool '%s': expected env passthrough %v, got %v", tool.Name, expectedPassthrough[tool.Name], tool.Run.EnvPassthrough)
+			}
+			if !reflect.DeepEqual(tool.Run.EnvFile, expectedEnvFile[tool.Name]) {
+				t.Errorf("Tool '%s': expected env files %v, got %v", tool.Name, expectedEnvFile[tool.Name], tool.Run.EnvFile)
+			}
+		}
+	}
+}
+
+func TestLoadAndMergeConfigs_Namespaces(t *testing.T) {
+	dir := t.TempDir()
+
+	k8s := filepath.Join(dir, "k8s.yaml")
+	aws := filepath.Join(dir, "aws.yaml")
+	other := filepath.Join(dir, "other.yaml")
+	writeFile(t, k8s, `
+mcp:
+  namespace: k8s
+  tools:
+    - name: "get_logs"
+      aliases: ["logs"]
+      run:
+        command: "kubectl logs {{ .pod }}"
+`)
+	writeFile(t, aws, `
+mcp:
+  namespace: aws
+  tools:
+    - name: "get_logs"
+      run:
+        command: "aws logs tail {{ .group }}"
+`)
+	writeFile(t, other, `
+mcp:
+  tools:
+    - name: "k8s__get_logs"
+      run:
+        command: "echo shadowing"
+`)
+
+	cfg, err := LoadAndMergeConfigs([]string{k8s, aws})
+	if err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	var names []string
+	for _, tool := range cfg.MCP.Tools {
+		names = append(names, tool.Name)
+	}
+	expected := []string{"k8s__get_logs", "aws__get_logs"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+
+	if !reflect.DeepEqual(cfg.MCP.Tools[0].Aliases, []string{"k8s__logs"}) {
+		t.Errorf("Expected namespaced aliases, got %v", cfg.MCP.Tools[0].Aliases)
+	}
+
+	if _, err := LoadAndMergeConfigs([]string{k8s, aws, other}); err == nil {
+		t.Errorf("Expected an error for tools with the same name in different files")
+	}
+
+	alias := filepath.Join(dir, "alias.yaml")
+	writeFile(t, alias, `
+mcp:
+  tools:
+    - name: "new_logs"
+      aliases: ["k8s__logs"]
+      run:
+        command: "echo"
+`)
+	if _, err := LoadAndMergeConfigs([]string{k8s, alias}); err == nil {
+		t.Errorf("Expected an error for an alias with the same name as a tool in a different file")
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	writeFile(t, invalid, `
+mcp:
+  namespace: "my tools"
+  tools:
+    - name: "tool"
+      run:
+        command: "echo"
+`)
+	if _, err := NewConfigFromFile(invalid); err == nil {
+		t.Errorf("Expected an error for an invalid namespace")
+	}
+
+	duplicated := filepath.Join(dir, "duplicated.yaml")
+	writeFile(t, duplicated, `
+mcp:
+  tools:
+    - name: "tool"
+      run:
+        command: "echo 1"
+    - name: "tool"
+      run:
+        command: "echo 2"
+`)
+	if _, err := NewConfigFromFile(duplicated); err == nil {
+		t.Errorf("Expected an error for duplicated tools in the same file")
+	}
+}
+
+// writeFile writes some content to a file, failing the test on errors
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestToolsConfig_GetSystemPrompt(t *testing.T) {
+	cfg := &ToolsConfig{}
+	cfg.Prompts.System = []string{"You are a system administrator."}
+	tools := NewTools([]MCPToolConfig{
+		{Name: "restart_service", Description: "Restart a service", Guidance: "Check the status of the service first.\nNever restart sshd."},
+		{Name: "service_status", Description: "Show the status of a service"},
+	})
+
+	expected := "You are a system administrator.\n\nGuidance for using the tools:\n\n" +
+		"- restart_service: Check the status of the service first.\n  Never restart sshd."
+	if prompt := cfg.GetSystemPrompt(tools); prompt != expected {
+		t.Errorf("Expected %q, got %q", expected, prompt)
+	}
+
+	// without system prompts nor guidance
+	if prompt := (&ToolsConfig{}).GetSystemPrompt(tools[1:]); prompt != "" {
+		t.Errorf("Expected an empty prompt, got %q", prompt)
+	}
+}
+
+func TestNewConfigFromFile_DefaultCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  tools:
+    - name: "deploy"
+      description: "Deploy a branch"
+      params:
+        branch:
+          type: string
+          default_command: "echo feature/login"
+          description: "The branch (by default, {{ .defaults.branch }})"
+        replicas:
+          type: integer
+          default: 1
+          default_command: "exit 1"
+          description: "The number of replicas"
+        region:
+          type: string
+          default_command: "printf ''"
+          description: "The region"
+      run:
+        command: "echo {{ .branch }}"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	params := cfg.MCP.Tools[0].Params
+	if got := params["branch"].Default; got != "feature/login" {
+		t.Errorf("Unexpected default for branch: %v", got)
+	}
+	if got := params["branch"].Description; got != "The branch (by default, feature/login)" {
+		t.Errorf("Unexpected description for branch: %q", got)
+	}
+	// the failures keep the static defaults
+	if got := params["replicas"].Default; got != 1 {
+		t.Errorf("Unexpected default for replicas: %v (%T)", got, got)
+	}
+	if got := params["region"].Default; got != nil {
+		t.Errorf("Unexpected default for region: %v", got)
+	}
+}
+
+func TestNewConfigFromFile_DefaultRunner(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	writeFile(t, file, `
+mcp:
+  run:
+    runner: firejail
+    runner_options:
+      firejail:
+        allow_networking: false
+      docker:
+        image: "alpine:3.20"
+        allow_networking: false
+  tools:
+    - name: "sandboxed"
+      description: "Uses the default runner"
+      run:
+        command: "echo sandboxed"
+    - name: "container"
+      description: "Selects its own runner"
+      run:
+        command: "echo container"
+        runners:
+          - name: docker
+            options:
+              allow_networking: true
+    - name: "shell"
+      type: shell_session
+      description: "Always runs in the host"
+`)
+
+	cfg, err := NewConfigFromFile(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string][]MCPToolRunner{
+		"sandboxed": {{Name: "firejail", Options: map[string]interface{}{"allow_networking": false}}},
+		"container": {{Name: "docker", Options: map[string]interface{}{"image": "alpine:3.20", "allow_networking": true}}},
+		"shell":     nil,
+	}
+	for _, tool := range cfg.MCP.Tools {
+		if !reflect.DeepEqual(tool.Run.Runners, expected[tool.Name]) {
+			t.Errorf("Unexpected runners for tool '%s': %+v", tool.Name, tool.Run.Runners)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package mcpshell is the API for embedding MCPShell in other Go programs.
+//
+// It loads MCPShell configurations, with the tools they define, and serves
+// them in-process over stdio or HTTP:
+//
+//	srv, err := mcpshell.Load("tools.yaml", mcpshell.WithVersion("1.0.0"))
+//	if err != nil {
+//		return err
+//	}
+//	defer srv.Close()
+//
+//	return srv.ServeStdio(ctx)
+package mcpshell
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/server"
+)
+
+// DefaultDrainTimeout is the time for finishing the tool calls in progress
+// when stopping the server, unless another one is set with WithDrainTimeout
+const DefaultDrainTimeout = 30 * time.Second
+
+// Server is an MCPShell server, hosting the tools of a configuration
+type Server struct {
+	srv *server.Server
+
+	cleanup     func() // removes the temporary files of the configuration
+	cleanupOnce sync.Once
+}
+
+// options are the options for loading a server
+type options struct {
+	logger       *common.Logger
+	shell        string
+	version      string
+	descriptions []string
+	drainTimeout time.Duration
+	runner       command.Runner
+	middlewares  []Middleware
+	rest         bool
+	restInsecure bool
+}
+
+// Option is an option for loading a server
+type Option func(*options)
+
+// WithLogger sets the logger of the server (by default, the global MCPShell logger)
+func WithLogger(logger *common.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithShell sets the shell for running the commands, instead of the shell in the configuration
+func WithShell(shell string) Option {
+	return func(o *options) {
+		o.shell = shell
+	}
+}
+
+// WithVersion sets the version of the server reported to the clients
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithDescription adds a description of the server, shown to the clients along
+// with the description in the configuration (it can be used multiple times)
+func WithDescription(description string) Option {
+	return func(o *options) {
+		o.descriptions = append(o.descriptions, description)
+	}
+}
+
+// WithDrainTimeout sets the time for finishing the tool calls in progress when
+// stopping the server, before terminating them
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.drainTimeout = timeout
+	}
+}
+
+// WithRunner sets the runner for the commands of all the tools, instead of the
+// runners in the configuration (ie, for running them in a remote executor)
+func WithRunner(runner command.Runner) Option {
+	return func(o *options) {
+		o.runner = runner
+	}
+}
+
+// Middleware wraps the handler of the tool calls, for intercepting all the calls. The
+// server package provides middlewares for logging, metrics, authorization and rate limiting.
+type Middleware = server.Middleware
+
+// WithMiddleware adds some middlewares applied to all the tool calls, in order (the
+// first one is the outermost). They can be used multiple times.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithREST serves the tools as REST endpoints too (in HTTP mode), in "GET /tools"
+// and "POST /tools/<name>"
+func WithREST() Option {
+	return func(o *options) {
+		o.rest = true
+	}
+}
+
+// WithRESTInsecure serves the REST endpoints without authenticating the clients in the
+// configuration (ie, when the program embedding MCPShell authenticates them)
+func WithRESTInsecure() Option {
+	return func(o *options) {
+		o.restInsecure = true
+	}
+}
+
+// Load loads a configuration, creating a server with its tools.
+//
+// Parameters:
+//   - configPath: The configuration: a file, a directory with files or a URL
+//   - opts: The options for the server
+//
+// Returns:
+//   - The server, that must be closed when no longer needed
+//   - An error if the configuration cannot be loaded
+func Load(configPath string, opts ...Option) (*Server, error) {
+	o := options{drainTimeout: DefaultDrainTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = common.GetLogger()
+	}
+
+	localConfigPath, cleanup, err := config.ResolveMultipleConfigPaths([]string{configPath}, o.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	srv := server.New(server.Config{
+		ConfigFile:   localConfigPath,
+		Shell:        o.shell,
+		Logger:       o.logger,
+		Version:      o.version,
+		Descriptions: o.descriptions,
+		DrainTimeout: o.drainTimeout,
+		Runner:       o.runner,
+		Middlewares:  o.middlewares,
+		REST:         o.rest,
+		RESTInsecure: o.restInsecure,
+	})
+	if err := srv.CreateServer(); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	return &Server{srv: srv, cleanup: cleanup}, nil
+}
+
+// ToolFunc is a function implementing a tool in Go, instead of a command. It receives the
+// arguments of the call (once checked, and with the computed values) and returns the output.
+type ToolFunc = command.ToolFunc
+
+// RegisterTool registers a tool defined in code, in addition to the tools in the
+// configuration. The tool is checked like the tools in the configuration files.
+func (s *Server) RegisterTool(tool config.MCPToolConfig) error {
+	return s.srv.RegisterTool(tool)
+}
+
+// RegisterFunc registers a tool implemented by a Go function, in addition to the tools in
+// the configuration. The tool configuration has no command, but the parameters, the
+// constraints and the output are handled like in the tools running commands.
+func (s *Server) RegisterFunc(tool config.MCPToolConfig, fn ToolFunc) error {
+	return s.srv.RegisterFunc(tool, fn)
+}
+
+// CallTool calls a tool in-process, returning its output
+func (s *Server) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	return s.srv.ExecuteTool(ctx, name, args)
+}
+
+// ServeStdio serves the tools over the standard input and output, until the input
+// is closed or the context is done. The tool calls in progress are drained before
+// returning, and the server is closed.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	defer s.Close()
+	return s.srv.ServeStdio(ctx, os.Stdin, os.Stdout)
+}
+
+// ServeHTTP serves the tools over HTTP/SSE in an address (ie, ":8080"), until the
+// context is done. The tool calls in progress are drained before returning, and
+// the server is closed.
+func (s *Server) ServeHTTP(ctx context.Context, addr string) error {
+	defer s.Close()
+	return s.srv.ServeHTTP(ctx, addr)
+}
+
+// Handler returns the HTTP handler of the server, for serving the tools in another
+// HTTP server: the MCP protocol in /sse, the health (/healthz) and readiness
+// (/readyz) endpoints, and the REST endpoints in /tools (with WithREST).
+func (s *Server) Handler() http.Handler {
+	return s.srv.HTTPHandler()
+}
+
+// Close releases the resources used by the server, stopping the scheduled tools,
+// the shell sessions and removing the temporary files of the configuration
+func (s *Server) Close() {
+	s.cleanupOnce.Do(func() {
+		s.srv.Close()
+		s.cleanup()
+	})
+}
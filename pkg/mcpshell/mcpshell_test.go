@@ -0,0 +1,181 @@
+package mcpshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+const testConfig = `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+
+func newTestLogger(t *testing.T) *common.Logger {
+	t.Helper()
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return logger
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return file
+}
+
+// fakeRunner is a runner returning the commands instead of running them
+type fakeRunner struct {
+	commands []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, shell string, command string, env []string, params map[string]interface{}, tmpfile bool) (string, error) {
+	r.commands = append(r.commands, command)
+	return "ran: " + command, nil
+}
+
+func (r *fakeRunner) CheckImplicitRequirements() error {
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml"), WithLogger(newTestLogger(t))); err == nil {
+		t.Errorf("Expected an error for a missing configuration")
+	}
+
+	srv, err := Load(writeConfig(t, testConfig), WithLogger(newTestLogger(t)), WithShell("sh"), WithVersion("1.2.3"))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	defer srv.Close()
+
+	err = srv.RegisterTool(config.MCPToolConfig{
+		Name:        "greet",
+		Description: "Greet someone",
+		Params: map[string]common.ParamConfig{
+			"name": {Type: "string", Description: "The name", Required: true},
+		},
+		Run: config.MCPToolRunConfig{Command: "echo hello {{ .name }}"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register tool: %v", err)
+	}
+
+	for name, expected := range map[string]string{"hello": "hello", "greet": "hello world"} {
+		output, err := srv.CallTool(context.Background(), name, map[string]interface{}{"name": "world"})
+		if err != nil || output != expected {
+			t.Errorf("Tool '%s': expected %q, got %q (%v)", name, expected, output, err)
+		}
+	}
+}
+
+func TestLoad_WithRunner(t *testing.T) {
+	runner := &fakeRunner{}
+	srv, err := Load(writeConfig(t, testConfig), WithLogger(newTestLogger(t)), WithRunner(runner))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	defer srv.Close()
+
+	output, err := srv.CallTool(context.Background(), "hello", map[string]interface{}{})
+	if err != nil || output != "ran: echo hello" {
+		t.Errorf("Expected the command to be run by the runner, got %q (%v)", output, err)
+	}
+	if len(runner.commands) != 1 {
+		t.Errorf("Expected 1 command, got %v", runner.commands)
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	srv, err := Load(writeConfig(t, testConfig), WithLogger(newTestLogger(t)), WithShell("sh"))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	defer srv.Close()
+
+	httpServer := httptest.NewServer(srv.Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Post(httpServer.URL+"/sse", "application/json",
+		strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "hello", "arguments": {}}}`))
+	if err != nil {
+		t.Fatalf("Failed to call the tool: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "hello") {
+		t.Errorf("Expected the output of the tool, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(httpServer.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("Failed to check readiness: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the server to be ready, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_RegisterFunc(t *testing.T) {
+	srv, err := Load(writeConfig(t, testConfig), WithLogger(newTestLogger(t)), WithShell("sh"))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	defer srv.Close()
+
+	tool := config.MCPToolConfig{
+		Name:        "add",
+		Description: "Add two numbers",
+		Params: map[string]common.ParamConfig{
+			"a": {Type: "number", Description: "The first number", Required: true},
+			"b": {Type: "number", Description: "The second number", Required: true},
+		},
+		Constraints: []string{"a >= 0.0 && b >= 0.0"},
+	}
+	err = srv.RegisterFunc(tool, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		return fmt.Sprintf("%v", params["a"].(float64)+params["b"].(float64)), nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+
+	output, err := srv.CallTool(context.Background(), "add", map[string]interface{}{"a": 1, "b": 2})
+	if err != nil || output != "3" {
+		t.Errorf("Expected '3', got %q (%v)", output, err)
+	}
+	output, _ = srv.CallTool(context.Background(), "add", map[string]interface{}{"a": -1, "b": 2})
+	if !strings.Contains(output, "constraint") {
+		t.Errorf("Expected the constraints to be checked, got %q", output)
+	}
+
+	// the names in use are refused
+	if err := srv.RegisterFunc(config.MCPToolConfig{Name: "hello"}, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		return "", nil
+	}); err == nil {
+		t.Errorf("Expected an error for a name in use")
+	}
+}
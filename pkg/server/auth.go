@@ -0,0 +1,354 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// ClientIdentity is the identity of an authenticated client
+type ClientIdentity struct {
+	Name   string   // the name of the client (or the user, when authenticated by a proxy)
+	Groups []string // the groups of the client
+
+	client *authClient // the client configuration (for clients authenticated with tokens)
+}
+
+// internalIdentity is the identity of the calls made by the server itself (ie, the smoke
+// tests), that are not counted in the limits of the clients
+var internalIdentity = &ClientIdentity{Name: "mcpshell-internal"}
+
+// isInternalCall returns true if a call has been made by the server itself
+func isInternalCall(ctx context.Context) bool {
+	return ClientIdentityFromContext(ctx) == internalIdentity
+}
+
+// clientIdentityKey is the key of the identity of the client in the context
+type clientIdentityKey struct{}
+
+// WithClientIdentity returns a context with the identity of the client
+func WithClientIdentity(ctx context.Context, identity *ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// ClientIdentityFromContext returns the identity of the client in a context,
+// or nil when the client has not been authenticated (ie, in the stdio transport)
+func ClientIdentityFromContext(ctx context.Context) *ClientIdentity {
+	identity, _ := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return identity
+}
+
+// authClient is a client authenticated with a token
+type authClient struct {
+	name    string
+	groups  []string
+	token   string
+	limiter *rateLimiter // the limit of calls (can be nil)
+	env     []string     // the environment variables for the commands
+}
+
+// authorizer authenticates the clients of the HTTP transport, and checks
+// the tools they can use
+type authorizer struct {
+	clients        []*authClient
+	userHeader     string
+	groupsHeader   string
+	trustedProxies []netip.Prefix // the proxies setting the user headers
+	acls           []config.MCPACLConfig
+}
+
+// newAuthorizer creates an authorizer from the auth configuration, obtaining the
+// tokens of the clients. It returns nil when there is nothing configured.
+func newAuthorizer(ctx context.Context, cfg config.MCPAuthConfig, secrets *common.Secrets) (*authorizer, error) {
+	if len(cfg.Clients) == 0 && cfg.UserHeader == "" && len(cfg.ACLs) == 0 {
+		return nil, nil
+	}
+	if len(cfg.Clients) == 0 && cfg.UserHeader == "" {
+		return nil, fmt.Errorf("ACLs require clients or a user header for authenticating the clients")
+	}
+	if cfg.GroupsHeader != "" && cfg.UserHeader == "" {
+		return nil, fmt.Errorf("the groups header requires a user header")
+	}
+	if cfg.UserHeader != "" && len(cfg.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("the user header requires the trusted proxies setting it")
+	}
+
+	a := &authorizer{
+		userHeader:   cfg.UserHeader,
+		groupsHeader: cfg.GroupsHeader,
+		acls:         slices.Clone(cfg.ACLs),
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		prefix, err := parseTrustedProxy(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+		}
+		a.trustedProxies = append(a.trustedProxies, prefix)
+	}
+
+	var names []string
+	for _, client := range cfg.Clients {
+		if client.Name == "" {
+			return nil, fmt.Errorf("client without a name")
+		}
+		if slices.Contains(names, client.Name) {
+			return nil, fmt.Errorf("duplicate client '%s'", client.Name)
+		}
+		names = append(names, client.Name)
+
+		var token string
+		var err error
+		switch {
+		case client.TokenEnv != "" && client.TokenSecret != "":
+			return nil, fmt.Errorf("client '%s' must use either token_env or token_secret, not both", client.Name)
+		case client.TokenEnv != "":
+			token = os.Getenv(client.TokenEnv)
+		case client.TokenSecret != "":
+			if secrets == nil {
+				return nil, fmt.Errorf("client '%s' uses an unknown secret '%s'", client.Name, client.TokenSecret)
+			}
+			token, err = secrets.Get(ctx, client.TokenSecret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to obtain the token of client '%s': %w", client.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("client '%s' has no token_env or token_secret", client.Name)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("empty token for client '%s'", client.Name)
+		}
+
+		var limiter *rateLimiter
+		if client.RateLimit != "" {
+			limiter, err = parseRateLimit(client.RateLimit)
+			if err != nil {
+				return nil, fmt.Errorf("client '%s': %w", client.Name, err)
+			}
+		}
+		for _, e := range client.Env {
+			if name, _, found := strings.Cut(e, "="); !found || name == "" {
+				return nil, fmt.Errorf("client '%s': invalid environment variable '%s' (expected NAME=value)", client.Name, e)
+			}
+		}
+
+		a.clients = append(a.clients, &authClient{
+			name:    client.Name,
+			groups:  client.Groups,
+			token:   token,
+			limiter: limiter,
+			env:     client.Env,
+		})
+
+		// the tools of the client are a rule only for this client
+		if len(client.Tools) > 0 || len(client.Tags) > 0 {
+			a.acls = append(a.acls, config.MCPACLConfig{
+				Clients: []string{client.Name},
+				Tools:   client.Tools,
+				Tags:    client.Tags,
+			})
+		}
+	}
+
+	for i, acl := range a.acls {
+		if len(acl.Clients) == 0 && len(acl.Groups) == 0 {
+			return nil, fmt.Errorf("ACL %d does not apply to any client or group", i+1)
+		}
+		if len(acl.Tools) == 0 && len(acl.Tags) == 0 {
+			return nil, fmt.Errorf("ACL %d does not allow any tool or tag", i+1)
+		}
+		for _, pattern := range acl.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid tool pattern '%s' in ACL %d: %w", pattern, i+1, err)
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// parseTrustedProxy parses the address (or the network) of a trusted proxy
+func parseTrustedProxy(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isTrustedProxy returns true if a request comes from one of the trusted proxies
+func (a *authorizer) isTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(a.trustedProxies, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// authenticate returns the identity of the client making a request, with the bearer
+// token in the Authorization header or the headers set by a trusted proxy. The users
+// authenticated by the proxy cannot use the names of the clients with tokens.
+func (a *authorizer) authenticate(r *http.Request) (*ClientIdentity, bool) {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && token != "" {
+		for _, client := range a.clients {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(client.token)) == 1 {
+				return &ClientIdentity{Name: client.name, Groups: client.groups, client: client}, true
+			}
+		}
+		return nil, false
+	}
+
+	if a.userHeader != "" && a.isTrustedProxy(r) {
+		if user := strings.TrimSpace(r.Header.Get(a.userHeader)); user != "" {
+			if slices.ContainsFunc(a.clients, func(client *authClient) bool { return client.name == user }) {
+				return nil, false
+			}
+			identity := &ClientIdentity{Name: user}
+			if a.groupsHeader != "" {
+				for _, group := range strings.Split(r.Header.Get(a.groupsHeader), ",") {
+					if group = strings.TrimSpace(group); group != "" {
+						identity.Groups = append(identity.Groups, group)
+					}
+				}
+			}
+			return identity, true
+		}
+	}
+
+	return nil, false
+}
+
+// allows returns true if a client can use a tool (with some tags). Clients that have not
+// been authenticated (ie, in the stdio transport) can use all the tools, as well as all the
+// clients when there are no ACLs.
+func (a *authorizer) allows(identity *ClientIdentity, tool string, tags []string) bool {
+	if a == nil || len(a.acls) == 0 || identity == nil {
+		return true
+	}
+
+	for _, acl := range a.acls {
+		applies := slices.Contains(acl.Clients, "*") || slices.Contains(acl.Clients, identity.Name)
+		for _, group := range identity.Groups {
+			applies = applies || slices.Contains(acl.Groups, group)
+		}
+		if !applies {
+			continue
+		}
+
+		for _, pattern := range acl.Tools {
+			if matched, _ := path.Match(pattern, tool); matched {
+				return true
+			}
+		}
+		for _, tag := range tags {
+			if slices.Contains(acl.Tags, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isToolAllowed returns true if the client in the context can use a tool.
+// The built-in tools (not registered from the configuration) are always allowed.
+func (s *Server) isToolAllowed(ctx context.Context, name string) bool {
+	s.toolTagsMu.RLock()
+	tags, found := s.toolTags[name]
+	s.toolTagsMu.RUnlock()
+	if !found {
+		return true
+	}
+	return s.auth.allows(ClientIdentityFromContext(ctx), name, tags)
+}
+
+// filterTools removes the tools the client cannot use from the list of tools
+func (s *Server) filterTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	res := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if s.isToolAllowed(ctx, tool.Name) {
+			res = append(res, tool)
+		}
+	}
+	return res
+}
+
+// authorizeToolCall is a middleware rejecting the calls to the tools the client cannot use,
+// and applying the rate limits, the environment and the access to the jobs of the client.
+// The calls are recorded in the history.
+func (s *Server) authorizeToolCall(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		identity := ClientIdentityFromContext(ctx)
+		start := time.Now()
+
+		audit := func(status string, result *mcp.CallToolResult, err error) {
+			if s.history == nil {
+				return
+			}
+			entry := newCallEntry(identity, request, start, status, result, err)
+			entry.ExecutionID = common.ExecutionIDFromContext(ctx)
+			if historyErr := s.history.Record(entry); historyErr != nil {
+				s.logger.Error("Failed to record the call in the history: %v", historyErr)
+			}
+		}
+
+		if !s.isToolAllowed(ctx, request.Params.Name) {
+			s.callLogger(ctx).Info("Client '%s' is not allowed to call tool '%s'", identity.Name, request.Params.Name)
+			audit("denied", nil, nil)
+			return nil, fmt.Errorf("tool '%s' not found", request.Params.Name)
+		}
+
+		if identity != nil && identity.client != nil {
+			if identity.client.limiter != nil && !identity.client.limiter.allow(start) {
+				s.callLogger(ctx).Info("Client '%s' exceeded its rate limit", identity.Name)
+				audit("rate_limited", nil, nil)
+				return nil, fmt.Errorf("rate limit exceeded: try again later")
+			}
+			if len(identity.client.env) > 0 {
+				ctx = command.WithExtraEnv(ctx, identity.client.env)
+			}
+		}
+
+		// The background jobs are owned by the client (or by the session, when not authenticated),
+		// and they can only be accessed while the client can use their tools
+		owner := ""
+		if identity != nil {
+			owner = identity.Name
+		}
+		allowed := func(tool string) bool { return s.isToolAllowed(ctx, tool) }
+		ctx = command.WithJobsAccess(ctx, owner, allowed)
+
+		result, err := next(ctx, request)
+		audit("ok", result, err)
+		return result, err
+	}
+}
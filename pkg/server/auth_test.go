@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestAuthorizer_Allows(t *testing.T) {
+	a := &authorizer{
+		acls: []config.MCPACLConfig{
+			{Groups: []string{"sre"}, Tools: []string{"*"}},
+			{Clients: []string{"*"}, Tags: []string{"read-only"}},
+			{Clients: []string{"alice"}, Tools: []string{"k8s__*"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		identity *ClientIdentity
+		tool     string
+		tags     []string
+		expected bool
+	}{
+		{"not authenticated", nil, "restart", nil, true},
+		{"group with all the tools", &ClientIdentity{Name: "bob", Groups: []string{"sre"}}, "restart", nil, true},
+		{"tool with tag for everyone", &ClientIdentity{Name: "carol"}, "status", []string{"read-only"}, true},
+		{"tool without tag", &ClientIdentity{Name: "carol"}, "restart", []string{"admin"}, false},
+		{"tool matching pattern", &ClientIdentity{Name: "alice"}, "k8s__get_pods", nil, true},
+		{"tool not matching pattern", &ClientIdentity{Name: "alice"}, "restart", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.allows(tt.identity, tt.tool, tt.tags); got != tt.expected {
+				t.Errorf("allows() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	var none *authorizer
+	if !none.allows(&ClientIdentity{Name: "alice"}, "restart", nil) {
+		t.Errorf("Expected all the tools allowed without an authorizer")
+	}
+}
+
+func TestNewAuthorizer_Invalid(t *testing.T) {
+	t.Setenv("MCPSHELL_TEST_TOKEN", "secret")
+
+	tests := map[string]config.MCPAuthConfig{
+		"ACLs without authentication": {
+			ACLs: []config.MCPACLConfig{{Clients: []string{"*"}, Tools: []string{"*"}}},
+		},
+		"client without token": {
+			Clients: []config.MCPAuthClientConfig{{Name: "alice"}},
+		},
+		"empty token": {
+			Clients: []config.MCPAuthClientConfig{{Name: "alice", TokenEnv: "MCPSHELL_TEST_UNSET_TOKEN"}},
+		},
+		"duplicate clients": {
+			Clients: []config.MCPAuthClientConfig{
+				{Name: "alice", TokenEnv: "MCPSHELL_TEST_TOKEN"},
+				{Name: "alice", TokenEnv: "MCPSHELL_TEST_TOKEN"},
+			},
+		},
+		"ACL without tools": {
+			UserHeader:     "X-Forwarded-User",
+			TrustedProxies: []string{"10.0.0.1"},
+			ACLs:           []config.MCPACLConfig{{Clients: []string{"*"}}},
+		},
+		"invalid pattern": {
+			UserHeader:     "X-Forwarded-User",
+			TrustedProxies: []string{"10.0.0.1"},
+			ACLs:           []config.MCPACLConfig{{Clients: []string{"*"}, Tools: []string{"[a-"}}},
+		},
+		"user header without trusted proxies": {
+			UserHeader: "X-Forwarded-User",
+		},
+		"invalid trusted proxy": {
+			UserHeader:     "X-Forwarded-User",
+			TrustedProxies: []string{"10.0.0.0/33"},
+		},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newAuthorizer(context.Background(), cfg, nil); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestServer_HTTPAuth(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	t.Setenv("MCPSHELL_TEST_SRE_TOKEN", "sre-token")
+	t.Setenv("MCPSHELL_TEST_DEV_TOKEN", "dev-token")
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  auth:
+    clients:
+      - name: "sre-bot"
+        token_env: "MCPSHELL_TEST_SRE_TOKEN"
+        groups: ["sre"]
+      - name: "dev-bot"
+        token_env: "MCPSHELL_TEST_DEV_TOKEN"
+    user_header: "X-Forwarded-User"
+    groups_header: "X-Forwarded-Groups"
+    trusted_proxies: ["192.0.2.0/24"] # the address of the test requests
+    acls:
+      - groups: ["sre"]
+        tools: ["*"]
+      - clients: ["*"]
+        tags: ["read-only"]
+  tools:
+    - name: "status"
+      description: "Show the status"
+      tags: ["read-only"]
+      run:
+        command: "echo running"
+    - name: "restart"
+      description: "Restart the service"
+      run:
+        command: "echo restarted"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	post := func(headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.handleMCPHTTP(rec, req)
+		return rec
+	}
+
+	listTools := func(headers map[string]string) []string {
+		rec := post(headers, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Result struct {
+				Tools []struct {
+					Name string `json:"name"`
+				} `json:"tools"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		var names []string
+		for _, tool := range resp.Result.Tools {
+			names = append(names, tool.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	// clients must be authenticated
+	if rec := post(nil, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized status without credentials, got %d", rec.Code)
+	}
+	if rec := post(map[string]string{"Authorization": "Bearer wrong"}, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized status with a wrong token, got %d", rec.Code)
+	}
+
+	// every client sees its own tools
+	if got := listTools(map[string]string{"Authorization": "Bearer sre-token"}); !slices.Equal(got, []string{"restart", "status"}) {
+		t.Errorf("Unexpected tools for the SRE client: %v", got)
+	}
+	if got := listTools(map[string]string{"Authorization": "Bearer dev-token"}); !slices.Equal(got, []string{"status"}) {
+		t.Errorf("Unexpected tools for the dev client: %v", got)
+	}
+	if got := listTools(map[string]string{"X-Forwarded-User": "alice", "X-Forwarded-Groups": "dev, sre"}); !slices.Equal(got, []string{"restart", "status"}) {
+		t.Errorf("Unexpected tools for the proxied user: %v", got)
+	}
+
+	// the user headers are only trusted from the proxies...
+	req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`))
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set("X-Forwarded-User", "alice")
+	rec := httptest.NewRecorder()
+	srv.handleMCPHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized status for a user header from other address, got %d", rec.Code)
+	}
+
+	// ... and the users cannot be the clients with tokens
+	if rec := post(map[string]string{"X-Forwarded-User": "sre-bot"}, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized status for a user with the name of a client, got %d", rec.Code)
+	}
+
+	// ... and it cannot call the other tools
+	rec = post(map[string]string{"Authorization": "Bearer dev-token"},
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "restart", "arguments": {}}}`)
+	if strings.Contains(rec.Body.String(), "restarted") || !strings.Contains(rec.Body.String(), "error") {
+		t.Errorf("Expected an error calling a tool not allowed, got %s", rec.Body.String())
+	}
+	rec = post(map[string]string{"Authorization": "Bearer sre-token"},
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "restart", "arguments": {}}}`)
+	if !strings.Contains(rec.Body.String(), "restarted") {
+		t.Errorf("Expected the tool to be called, got %s", rec.Body.String())
+	}
+}
+
+func TestServer_HTTPAuthJobs(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	oldDir := command.JobsDir
+	command.JobsDir = t.TempDir()
+	defer func() { command.JobsDir = oldDir }()
+
+	t.Setenv("MCPSHELL_TEST_SRE_TOKEN", "sre-token")
+	t.Setenv("MCPSHELL_TEST_DEV_TOKEN", "dev-token")
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  auth:
+    clients:
+      - name: "sre-bot"
+        token_env: "MCPSHELL_TEST_SRE_TOKEN"
+        groups: ["sre"]
+      - name: "dev-bot"
+        token_env: "MCPSHELL_TEST_DEV_TOKEN"
+        groups: ["sre"]
+    user_header: "X-Forwarded-User"
+    groups_header: "X-Forwarded-Groups"
+    trusted_proxies: ["192.0.2.0/24"] # the address of the test requests
+    acls:
+      - groups: ["sre"]
+        tools: ["*"]
+  tools:
+    - name: "restart"
+      description: "Restart the service"
+      async: true
+      run:
+        command: "echo restarted"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	callTool := func(headers map[string]string, name string, args string) string {
+		body := `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "` + name + `", "arguments": ` + args + `}}`
+		req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.handleMCPHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	sre := map[string]string{"Authorization": "Bearer sre-token"}
+	match := regexp.MustCompile(`job ([a-f0-9]{12})`).FindStringSubmatch(callTool(sre, "restart", `{}`))
+	if match == nil {
+		t.Fatalf("Failed to start job")
+	}
+	id := match[1]
+	jobArgs := `{"job_id": "` + id + `"}`
+
+	// the jobs are available for the client that started them...
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		got := callTool(sre, command.JobStatusToolName, jobArgs)
+		if !strings.Contains(got, "Tool: restart") {
+			t.Fatalf("Expected the status of the job for its owner, got %s", got)
+		}
+		if strings.Contains(got, "Status: "+command.JobStatusSucceeded) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %s did not finish: %s", id, got)
+		}
+	}
+
+	// ... but not for other clients, even when they can use the tool
+	dev := map[string]string{"Authorization": "Bearer dev-token"}
+	for _, tool := range []string{command.JobStatusToolName, command.JobLogsToolName, command.JobKillToolName} {
+		if got := callTool(dev, tool, jobArgs); !strings.Contains(got, "unknown job") {
+			t.Errorf("Expected an unknown job in '%s' for another client, got %s", tool, got)
+		}
+	}
+	if got := callTool(dev, command.JobStatusToolName, `{}`); strings.Contains(got, id) {
+		t.Errorf("Unexpected job of another client in the list: %s", got)
+	}
+
+	// ... or when the client cannot use the tool of the job anymore
+	proxied := map[string]string{"X-Forwarded-User": "alice", "X-Forwarded-Groups": "sre"}
+	match = regexp.MustCompile(`job ([a-f0-9]{12})`).FindStringSubmatch(callTool(proxied, "restart", `{}`))
+	if match == nil {
+		t.Fatalf("Failed to start job")
+	}
+	jobArgs = `{"job_id": "` + match[1] + `"}`
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		got := callTool(proxied, command.JobStatusToolName, jobArgs)
+		if strings.Contains(got, "Status: "+command.JobStatusSucceeded) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %s did not finish: %s", match[1], got)
+		}
+	}
+	proxied["X-Forwarded-Groups"] = "dev"
+	if got := callTool(proxied, command.JobStatusToolName, jobArgs); !strings.Contains(got, "unknown job") {
+		t.Errorf("Expected an unknown job for a client not allowed to use its tool, got %s", got)
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// DefaultBudgetWarnAt is the fraction of the budgets spent when the clients are warned
+const DefaultBudgetWarnAt = 0.8
+
+// The scopes of the budgets
+const (
+	BudgetSession = "session"
+	BudgetDay     = "day"
+)
+
+// BudgetExceededError is the error of a call rejected because its cost would exceed a budget
+type BudgetExceededError struct {
+	Tool   string  // the name of the tool
+	Scope  string  // the scope of the budget ("session" or "day")
+	Budget float64 // the maximum cost in the scope
+	Spent  float64 // the cost already spent in the scope
+	Cost   float64 // the cost of the call
+	Unit   string  // the unit of the costs (can be empty)
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: the call to tool '%s' costs %s, but only %s of the %s budget of the %s are left",
+		e.Tool, formatCost(e.Cost, e.Unit), formatCost(max(e.Budget-e.Spent, 0), e.Unit),
+		formatCost(e.Budget, e.Unit), e.Scope)
+}
+
+// Meta returns the details of the error, for the metadata of the results
+func (e *BudgetExceededError) Meta() map[string]interface{} {
+	meta := map[string]interface{}{
+		"error":  "budget_exceeded",
+		"tool":   e.Tool,
+		"scope":  e.Scope,
+		"budget": e.Budget,
+		"spent":  e.Spent,
+		"cost":   e.Cost,
+	}
+	if e.Unit != "" {
+		meta["unit"] = e.Unit
+	}
+	return meta
+}
+
+// formatCost formats a cost with its unit (if any)
+func formatCost(cost float64, unit string) string {
+	if unit == "" {
+		return fmt.Sprintf("%g", cost)
+	}
+	return fmt.Sprintf("%g %s", cost, unit)
+}
+
+// budgetTracker keeps the cost of the calls of the tools in every session and in the
+// current day (in UTC). The costs are kept in memory.
+type budgetTracker struct {
+	config config.MCPBudgetConfig
+
+	mu       sync.Mutex
+	sessions map[string]float64
+	day      time.Time // the start of the current day
+	daily    float64   // the cost spent in the current day
+}
+
+// budgetSpend is the cost spent in the scope of a budget
+type budgetSpend struct {
+	scope  string
+	budget float64
+	spent  float64
+}
+
+// newBudgetTracker creates the tracker of a budget
+func newBudgetTracker(cfg config.MCPBudgetConfig) (*budgetTracker, error) {
+	if cfg.PerSession < 0 || cfg.Daily < 0 {
+		return nil, fmt.Errorf("the budgets cannot be negative")
+	}
+	if cfg.WarnAt < 0 || cfg.WarnAt > 1 {
+		return nil, fmt.Errorf("invalid warn_at %g: it must be between 0 and 1", cfg.WarnAt)
+	}
+	if cfg.WarnAt == 0 {
+		cfg.WarnAt = DefaultBudgetWarnAt
+	}
+	return &budgetTracker{config: cfg, sessions: map[string]float64{}}, nil
+}
+
+// spend counts the cost of a call in a session (empty for the calls without a session),
+// unless it would exceed any of the budgets. It returns the cost spent in the scopes of
+// the budgets (including this call), or the budget that would be exceeded.
+func (b *budgetTracker) spend(session string, cost float64, now time.Time) ([]budgetSpend, *budgetSpend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if day := quotaPeriodStart(QuotaDay, now); !day.Equal(b.day) {
+		b.day, b.daily = day, 0
+	}
+
+	var spends []budgetSpend
+	if b.config.PerSession > 0 && session != "" {
+		spends = append(spends, budgetSpend{scope: BudgetSession, budget: b.config.PerSession, spent: b.sessions[session]})
+	}
+	if b.config.Daily > 0 {
+		spends = append(spends, budgetSpend{scope: BudgetDay, budget: b.config.Daily, spent: b.daily})
+	}
+	for i := range spends {
+		if spends[i].spent+cost > spends[i].budget {
+			exceeded := spends[i]
+			return nil, &exceeded
+		}
+	}
+
+	if session != "" {
+		b.sessions[session] += cost
+	}
+	b.daily += cost
+	for i := range spends {
+		spends[i].spent += cost
+	}
+	return spends, nil
+}
+
+// forget removes the cost of a session that has ended
+func (b *budgetTracker) forget(session string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, session)
+}
+
+// warnings returns the warnings for the budgets almost spent
+func (b *budgetTracker) warnings(spends []budgetSpend) []string {
+	var res []string
+	for _, spend := range spends {
+		if spend.spent >= b.config.WarnAt*spend.budget {
+			res = append(res, fmt.Sprintf("Warning: %.0f%% of the budget of the %s has been spent (%s of %s).",
+				100*spend.spent/spend.budget, spend.scope, formatCost(spend.spent, b.config.Unit),
+				formatCost(spend.budget, b.config.Unit)))
+		}
+	}
+	return res
+}
+
+// enforceBudget wraps the handler of a tool with a cost, rejecting the calls that would
+// exceed the budgets with a budget exceeded error, and warning the clients (along with the
+// results) when the budgets are almost spent. All the calls count, even the ones failing,
+// but the internal calls (ie, the smoke tests).
+func (s *Server) enforceBudget(tool string, cost float64, next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if isInternalCall(ctx) {
+			return next(ctx, request)
+		}
+
+		var session string
+		if clientSession := mcpserver.ClientSessionFromContext(ctx); clientSession != nil {
+			session = clientSession.SessionID()
+		}
+
+		spends, exceeded := s.budget.spend(session, cost, time.Now())
+		if exceeded != nil {
+			budgetErr := &BudgetExceededError{
+				Tool:   tool,
+				Scope:  exceeded.scope,
+				Budget: exceeded.budget,
+				Spent:  exceeded.spent,
+				Cost:   cost,
+				Unit:   s.budget.config.Unit,
+			}
+			s.callLogger(ctx).Info("Rejecting call to tool '%s': %v", tool, budgetErr)
+			result := mcp.NewToolResultError(budgetErr.Error())
+			result.Meta = budgetErr.Meta()
+			return result, nil
+		}
+
+		result, err := next(ctx, request)
+		if result == nil {
+			return result, err
+		}
+
+		if result.Meta == nil {
+			result.Meta = map[string]interface{}{}
+		}
+		budget := map[string]interface{}{"cost": cost}
+		if s.budget.config.Unit != "" {
+			budget["unit"] = s.budget.config.Unit
+		}
+		for _, spend := range spends {
+			budget[spend.scope] = map[string]interface{}{"spent": spend.spent, "budget": spend.budget}
+		}
+		result.Meta["budget"] = budget
+
+		if warnings := s.budget.warnings(spends); len(warnings) > 0 {
+			s.callLogger(ctx).Info("Budget almost spent: %s", strings.Join(warnings, " "))
+			for _, warning := range warnings {
+				result.Content = append(result.Content, mcp.NewTextContent(warning))
+			}
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	if _, err := newBudgetTracker(config.MCPBudgetConfig{Daily: 10, WarnAt: 1.5}); err == nil {
+		t.Errorf("Expected an error for an invalid warn_at")
+	}
+
+	budget, err := newBudgetTracker(config.MCPBudgetConfig{PerSession: 1, Daily: 2, Unit: "USD"})
+	if err != nil {
+		t.Fatalf("Failed to create the budget: %v", err)
+	}
+
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	spends, exceeded := budget.spend("s1", 0.5, now)
+	if exceeded != nil || len(spends) != 2 || budget.warnings(spends) != nil {
+		t.Fatalf("Unexpected spend: %+v, %+v", spends, exceeded)
+	}
+	spends, exceeded = budget.spend("s1", 0.4, now)
+	if exceeded != nil {
+		t.Fatalf("Unexpected budget exceeded: %+v", exceeded)
+	}
+	warnings := budget.warnings(spends)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "90% of the budget of the session has been spent (0.9 USD of 1 USD)") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+
+	// the calls exceeding the budget are not counted
+	if _, exceeded = budget.spend("s1", 0.2, now); exceeded == nil || exceeded.scope != BudgetSession {
+		t.Fatalf("Expected the budget of the session to be exceeded, got %+v", exceeded)
+	}
+	if _, exceeded = budget.spend("s1", 0.1, now); exceeded != nil {
+		t.Errorf("Expected the call to fit in the budget of the session, got %+v", exceeded)
+	}
+
+	// the calls without a session only count in the day
+	if _, exceeded = budget.spend("", 0.9, now); exceeded != nil {
+		t.Errorf("Unexpected budget exceeded: %+v", exceeded)
+	}
+	if _, exceeded = budget.spend("s2", 0.5, now); exceeded == nil || exceeded.scope != BudgetDay || exceeded.spent != 1.9 {
+		t.Errorf("Expected the budget of the day to be exceeded, got %+v", exceeded)
+	}
+
+	// the budget of the day is reset in the next day, and the sessions forgotten
+	budget.forget("s1")
+	if _, exceeded = budget.spend("s1", 0.5, now.Add(24*time.Hour)); exceeded != nil {
+		t.Errorf("Expected a new budget in the next day, got %+v", exceeded)
+	}
+}
+
+func TestServer_Budget(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  budget:
+    unit: "credits"
+    per_session: 10
+    warn_at: 0.5
+  tools:
+    - name: "translate"
+      description: "A tool using a paid API"
+      cost: 4
+      run:
+        command: "echo translated"
+    - name: "free"
+      description: "A tool without a cost"
+      run:
+        command: "echo free"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	tools, err := srv.GetTools()
+	if err != nil {
+		t.Fatalf("GetTools() error = %v", err)
+	}
+	for _, tool := range tools {
+		if tool.Name == "translate" && !strings.Contains(tool.Description, "estimated cost of 4") {
+			t.Errorf("Expected the cost in the description, got %q", tool.Description)
+		}
+	}
+
+	ctx := srv.mcpServer.WithContext(context.Background(), testSession{id: "budget-session"})
+
+	result, err := srv.CallTool(ctx, "translate", map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("First call failed: %v, %v", result, err)
+	}
+	if strings.Contains(resultText(result), "Warning") {
+		t.Errorf("Unexpected warning: %q", resultText(result))
+	}
+
+	result, err = srv.CallTool(ctx, "translate", map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("Second call failed: %v, %v", result, err)
+	}
+	if text := resultText(result); !strings.Contains(text, "Warning: 80% of the budget of the session has been spent (8 credits of 10 credits)") {
+		t.Errorf("Expected a warning about the budget, got %q", text)
+	}
+
+	result, err = srv.CallTool(ctx, "translate", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !result.IsError || result.Meta["error"] != "budget_exceeded" || result.Meta["scope"] != BudgetSession {
+		t.Errorf("Expected a budget exceeded error, got %+v", result)
+	}
+
+	// the tools without a cost are not limited
+	if result, err := srv.CallTool(ctx, "free", map[string]interface{}{}); err != nil || result.IsError {
+		t.Errorf("Expected the tool without a cost to succeed: %v, %v", result, err)
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// DefaultChaosMaxLatency is the maximum delay injected in the results of the calls,
+// unless another one is configured
+const DefaultChaosMaxLatency = 5 * time.Second
+
+// The faults injected in the calls of the tools
+const (
+	chaosLatency    = "latency"
+	chaosTruncation = "truncation"
+	chaosFailure    = "failure"
+	chaosTimeout    = "timeout"
+)
+
+// chaosInjector injects random faults in the calls of the tools
+type chaosInjector struct {
+	mu         sync.Mutex
+	rand       *rand.Rand
+	seed       int64
+	config     config.MCPChaosConfig
+	maxLatency time.Duration
+}
+
+// newChaosInjector creates an injector of the faults configured
+func newChaosInjector(cfg config.MCPChaosConfig) (*chaosInjector, error) {
+	for name, probability := range map[string]float64{
+		chaosLatency:    cfg.Latency,
+		chaosTruncation: cfg.Truncation,
+		chaosFailure:    cfg.Failure,
+		chaosTimeout:    cfg.Timeout,
+	} {
+		if probability < 0 || probability > 1 {
+			return nil, fmt.Errorf("invalid probability of %s: %v (it must be between 0 and 1)", name, probability)
+		}
+	}
+
+	maxLatency := DefaultChaosMaxLatency
+	if cfg.MaxLatency != "" {
+		var err error
+		maxLatency, err = time.ParseDuration(cfg.MaxLatency)
+		if err != nil || maxLatency <= 0 {
+			return nil, fmt.Errorf("invalid maximum latency: '%s'", cfg.MaxLatency)
+		}
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &chaosInjector{
+		rand:       rand.New(rand.NewSource(seed)),
+		seed:       seed,
+		config:     cfg,
+		maxLatency: maxLatency,
+	}, nil
+}
+
+// chaosFaults are the faults to inject in a call
+type chaosFaults struct {
+	latency    time.Duration // the delay of the result (if any)
+	truncation float64       // the fraction of the output kept (1 when not truncated)
+	failure    bool
+	timeout    bool
+}
+
+// names returns the names of the faults, for the logs
+func (f chaosFaults) names() []string {
+	var names []string
+	if f.latency > 0 {
+		names = append(names, chaosLatency)
+	}
+	if f.truncation < 1 {
+		names = append(names, chaosTruncation)
+	}
+	if f.failure {
+		names = append(names, chaosFailure)
+	}
+	if f.timeout {
+		names = append(names, chaosTimeout)
+	}
+	return names
+}
+
+// next returns the faults to inject in the next call of a tool. All the random
+// numbers are always drawn, so the same seed produces the same faults for the
+// same sequence of calls.
+func (c *chaosInjector) next(tool string) chaosFaults {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	latency, truncation, failure, timeout := c.rand.Float64(), c.rand.Float64(), c.rand.Float64(), c.rand.Float64()
+	delay, kept := c.rand.Int63n(int64(c.maxLatency)), c.rand.Float64()
+	if len(c.config.Tools) > 0 && !slices.Contains(c.config.Tools, tool) {
+		return chaosFaults{truncation: 1}
+	}
+
+	faults := chaosFaults{truncation: 1}
+	if latency < c.config.Latency {
+		faults.latency = time.Duration(delay)
+	}
+	if truncation < c.config.Truncation {
+		faults.truncation = kept
+	}
+	faults.failure = failure < c.config.Failure
+	faults.timeout = timeout < c.config.Timeout
+	return faults
+}
+
+// injectFaults is a middleware injecting random faults in the tool calls: the results
+// are delayed or truncated, and the calls fail with a non-zero exit status or a timeout
+// (without running the command)
+func (s *Server) injectFaults(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		faults := s.chaos.next(request.Params.Name)
+		if names := faults.names(); len(names) > 0 {
+			s.callLogger(ctx).Info("Injecting faults in the call to '%s': %v", request.Params.Name, names)
+		}
+
+		// the timeouts take the maximum latency, like the commands that never finish
+		delay := faults.latency
+		if faults.timeout {
+			delay = s.chaos.maxLatency
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		switch {
+		case faults.timeout:
+			return mcp.NewToolResultError(fmt.Sprintf("command timed out after %s: signal: killed", s.chaos.maxLatency)), nil
+		case faults.failure:
+			return mcp.NewToolResultError("exit status 1"), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || faults.truncation >= 1 {
+			return result, err
+		}
+		truncated := *result
+		truncated.Content = make([]mcp.Content, len(result.Content))
+		for i, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				end := int(float64(len(text.Text)) * faults.truncation)
+				for end > 0 && !utf8.RuneStart(text.Text[end]) {
+					end--
+				}
+				text.Text = text.Text[:end]
+				content = text
+			}
+			truncated.Content[i] = content
+		}
+		return &truncated, nil
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+func TestServer_ChaosFaults(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		chaos    string
+		tool     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "no faults", chaos: "seed: 1", tool: "greet", expected: "hello world"},
+		{name: "failure", chaos: "failure: 1", tool: "greet", expected: "exit status 1"},
+		{name: "timeout", chaos: "timeout: 1\n    max_latency: 10ms", tool: "greet", expected: "command timed out after 10ms"},
+		{name: "latency", chaos: "latency: 1\n    max_latency: 10ms", tool: "greet", expected: "hello world"},
+		{name: "other tools", chaos: "failure: 1\n    tools: [other]", tool: "greet", expected: "hello world"},
+		{name: "invalid probability", chaos: "failure: 2", tool: "greet", wantErr: true},
+		{name: "invalid latency", chaos: "latency: 0.5\n    max_latency: soon", tool: "greet", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+			configContent := `mcp:
+  chaos:
+    ` + tt.chaos + `
+  tools:
+    - name: "greet"
+      description: "Greet the world"
+      run:
+        command: "echo hello world"
+`
+			if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("Failed to write test config file: %v", err)
+			}
+
+			srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+			err := srv.CreateServer()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error creating the server")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer srv.Close()
+
+			output, _ := srv.ExecuteTool(context.Background(), tt.tool, nil)
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected %q in the output, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestChaosInjector_Seed(t *testing.T) {
+	cfg := config.MCPChaosConfig{Seed: 42, Latency: 0.5, Truncation: 0.5, Failure: 0.5, Timeout: 0.5}
+
+	// the same seed injects the same faults
+	first, err := newChaosInjector(cfg)
+	if err != nil {
+		t.Fatalf("newChaosInjector() error = %v", err)
+	}
+	second, err := newChaosInjector(cfg)
+	if err != nil {
+		t.Fatalf("newChaosInjector() error = %v", err)
+	}
+	injected := 0
+	for i := 0; i < 20; i++ {
+		faults := first.next("greet")
+		if other := second.next("greet"); other != faults {
+			t.Fatalf("Expected the same faults in the call %d, got %+v and %+v", i, faults, other)
+		}
+		if faults.truncation < 0 || faults.truncation > 1 || faults.latency < 0 || faults.latency >= DefaultChaosMaxLatency {
+			t.Errorf("Invalid faults: %+v", faults)
+		}
+		injected += len(faults.names())
+	}
+	if injected == 0 {
+		t.Errorf("Expected some faults to be injected")
+	}
+}
+
+func TestServer_ChaosTruncation(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  chaos:
+    seed: 7
+    truncation: 1
+  tools:
+    - name: "count"
+      description: "Count to a hundred"
+      run:
+        command: "seq 1 100"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	output, err := srv.ExecuteTool(context.Background(), "count", nil)
+	if err != nil {
+		t.Fatalf("Failed to call the tool: %v", err)
+	}
+	if !strings.HasPrefix("1\n2\n3\n", output[:min(len(output), 6)]) || strings.HasSuffix(output, "100") {
+		t.Errorf("Expected a truncated output, got %q", output)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"maps"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// refreshEnums is a tool filter updating the values allowed in the parameters with
+// dynamic enums (ie, the files matching a glob), so the clients see the current values
+// every time they list the tools
+func (s *Server) refreshEnums(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	s.toolTagsMu.RLock()
+	defer s.toolTagsMu.RUnlock()
+	if len(s.toolEnums) == 0 {
+		return tools
+	}
+
+	res := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		enums, found := s.toolEnums[tool.Name]
+		if !found {
+			res = append(res, tool)
+			continue
+		}
+
+		// do not modify the schema of the tool registered
+		tool.InputSchema.Properties = maps.Clone(tool.InputSchema.Properties)
+		for name, param := range enums {
+			property, ok := tool.InputSchema.Properties[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			property = maps.Clone(property)
+			values, err := param.EnumValues()
+			if err != nil {
+				s.logger.Error("Failed to obtain the values of parameter '%s' of tool '%s': %v", name, tool.Name, err)
+			}
+			if len(values) > 0 {
+				property["enum"] = values
+			} else {
+				delete(property, "enum")
+			}
+			tool.InputSchema.Properties[name] = property
+		}
+		res = append(res, tool)
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_DynamicEnums(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	playbooks := filepath.Join(dir, "playbooks")
+	if err := os.Mkdir(playbooks, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writePlaybook := func(name string) string {
+		t.Helper()
+		path := filepath.Join(playbooks, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("Failed to write playbook: %v", err)
+		}
+		return path
+	}
+	site := writePlaybook("site.yaml")
+
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "run_playbook"
+      description: "Run an Ansible playbook"
+      aliases: ["playbook"]
+      params:
+        playbook:
+          type: string
+          description: "The playbook to run"
+          required: true
+          enum_glob: "` + filepath.ToSlash(filepath.Join(playbooks, "*.yaml")) + `"
+          enum_refresh: "0s"
+      run:
+        command: "echo running {{ .playbook }}"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	listEnum := func(tool string) []string {
+		t.Helper()
+		response := srv.mcpServer.HandleMessage(context.Background(), mustMarshalJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  "tools/list",
+		}))
+		var decoded struct {
+			Result struct {
+				Tools []struct {
+					Name        string `json:"name"`
+					InputSchema struct {
+						Properties map[string]struct {
+							Enum []string `json:"enum"`
+						} `json:"properties"`
+					} `json:"inputSchema"`
+				} `json:"tools"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(mustMarshalJSON(response), &decoded); err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+		for _, listed := range decoded.Result.Tools {
+			if listed.Name == tool {
+				return listed.InputSchema.Properties["playbook"].Enum
+			}
+		}
+		t.Fatalf("Tool %s not listed", tool)
+		return nil
+	}
+
+	if values := listEnum("run_playbook"); !slices.Equal(values, []string{site}) {
+		t.Errorf("Unexpected values: %q", values)
+	}
+
+	// the new files are listed (and accepted) without restarting the server
+	db := writePlaybook("db.yaml")
+	for _, tool := range []string{"run_playbook", "playbook"} {
+		if values := listEnum(tool); !slices.Equal(values, []string{db, site}) {
+			t.Errorf("Unexpected values in %s: %q", tool, values)
+		}
+	}
+	if result, err := srv.CallTool(context.Background(), "run_playbook", map[string]interface{}{"playbook": db}); err != nil || result.IsError {
+		t.Errorf("Expected the call to succeed: %v, %v", result, err)
+	}
+	if result, err := srv.CallTool(context.Background(), "run_playbook", map[string]interface{}{"playbook": "/etc/passwd"}); err != nil || !result.IsError {
+		t.Errorf("Expected the call with a value not allowed to fail: %v, %v", result, err)
+	}
+}
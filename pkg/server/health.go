@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthStatus is the response of the health and readiness endpoints
+type healthStatus struct {
+	Status string `json:"status"`
+	Tools  int    `json:"tools,omitempty"` // the number of tools registered from the configuration (only when ready)
+}
+
+// handleHealthz reports the server process is alive, even while it is still
+// loading the configuration
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthStatus(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// handleReadyz reports the server can accept requests: the configuration has been
+// loaded, the prerequisites of the tools have been checked and the tools registered
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeHealthStatus(w, http.StatusServiceUnavailable, healthStatus{Status: "not ready"})
+		return
+	}
+
+	s.toolTagsMu.RLock()
+	tools := len(s.toolTags)
+	s.toolTagsMu.RUnlock()
+
+	writeHealthStatus(w, http.StatusOK, healthStatus{Status: "ready", Tools: tools})
+}
+
+// writeHealthStatus writes the response of a health or readiness endpoint
+func writeHealthStatus(w http.ResponseWriter, code int, status healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
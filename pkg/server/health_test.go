@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_HealthAndReadiness(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+
+	get := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// the server is alive but not ready before loading the configuration
+	if rec := get(srv.handleHealthz, "/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the server to be alive, got %d", rec.Code)
+	}
+	if rec := get(srv.handleReadyz, "/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the server not to be ready, got %d", rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.handleMCPHTTP(rec, httptest.NewRequest(http.MethodPost, "/sse",
+		strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected MCP requests to be rejected before being ready, got %d", rec.Code)
+	}
+
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	rec = get(srv.handleReadyz, "/readyz")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the server to be ready, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"status":"ready"`) || !strings.Contains(body, `"tools":1`) {
+		t.Errorf("Unexpected readiness status: %s", body)
+	}
+
+	// ... and it is not ready once closed
+	srv.Close()
+	if rec := get(srv.handleReadyz, "/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the server not to be ready once closed, got %d", rec.Code)
+	}
+}
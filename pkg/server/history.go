@@ -0,0 +1,297 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// HistoryEntry is a call of a tool (by a client or by a schedule) recorded in the history
+type HistoryEntry struct {
+	Time        time.Time              `json:"time"`
+	ExecutionID string                 `json:"execution_id,omitempty"` // the ID of the execution, as in the logs and the result
+	Client      string                 `json:"client,omitempty"`       // the client calling the tool (if authenticated)
+	Schedule    string                 `json:"schedule,omitempty"`     // the schedule running the tool (if any)
+	Tool        string                 `json:"tool"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	Status      string                 `json:"status"` // "ok", "error", "denied" or "rate_limited"
+	Error       string                 `json:"error,omitempty"`
+	Output      string                 `json:"output,omitempty"` // the output of the scheduled runs
+	Duration    float64                `json:"duration_seconds"`
+	Usage       map[string]interface{} `json:"usage,omitempty"` // the resources used by the command
+}
+
+// HistoryFilter selects the entries returned by HistoryStore.Query. Empty fields match
+// all the entries.
+type HistoryFilter struct {
+	Client   string
+	Schedule string
+	Tool     string
+	Limit    int // the maximum number of entries (0 for all)
+}
+
+// matches returns true if an entry is selected by the filter
+func (f HistoryFilter) matches(entry HistoryEntry) bool {
+	return (f.Client == "" || entry.Client == f.Client) &&
+		(f.Schedule == "" || entry.Schedule == f.Schedule) &&
+		(f.Tool == "" || entry.Tool == f.Tool)
+}
+
+// HistoryStore keeps the history of the calls of the tools: the audit trail of the calls
+// of the clients and the results of the schedules. With a shared store (ie, a database),
+// all the instances of the server have the same history.
+type HistoryStore interface {
+	// Record adds an entry to the history
+	Record(entry HistoryEntry) error
+
+	// Query returns the entries selected by a filter, the most recent first
+	Query(filter HistoryFilter) ([]HistoryEntry, error)
+
+	// Close releases the resources of the store
+	Close() error
+}
+
+// HistoryStoreFactory creates a store with its options
+type HistoryStoreFactory func(options map[string]interface{}) (HistoryStore, error)
+
+var (
+	historyStoresMu sync.RWMutex
+	historyStores   = map[string]HistoryStoreFactory{
+		"dir": func(options map[string]interface{}) (HistoryStore, error) {
+			dir, _ := options["dir"].(string)
+			return newDirHistoryStore(dir)
+		},
+		"sql": func(options map[string]interface{}) (HistoryStore, error) {
+			return newSQLHistoryStore(nil, false, options)
+		},
+		"sqlite": func(options map[string]interface{}) (HistoryStore, error) {
+			return newSQLHistoryStore([]string{"sqlite", "sqlite3"}, false, options)
+		},
+		"postgres": func(options map[string]interface{}) (HistoryStore, error) {
+			return newSQLHistoryStore([]string{"postgres", "pgx"}, true, options)
+		},
+	}
+)
+
+// RegisterHistoryStore registers a type of store for the history, so it can be selected
+// by name in the configuration (ie, for adding stores from programs embedding MCPShell)
+//
+// Parameters:
+//   - name: The name of the store
+//   - factory: The function creating the store with its options
+//
+// Returns:
+//   - An error if there is another store with that name
+func RegisterHistoryStore(name string, factory HistoryStoreFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("invalid history store")
+	}
+
+	historyStoresMu.Lock()
+	defer historyStoresMu.Unlock()
+	if _, found := historyStores[name]; found {
+		return fmt.Errorf("history store '%s' already registered", name)
+	}
+	historyStores[name] = factory
+	return nil
+}
+
+// NewHistoryStore creates a store for the history of some type
+func NewHistoryStore(name string, options map[string]interface{}) (HistoryStore, error) {
+	historyStoresMu.RLock()
+	factory, found := historyStores[name]
+	var names []string
+	for n := range historyStores {
+		names = append(names, n)
+	}
+	historyStoresMu.RUnlock()
+
+	if !found {
+		slices.Sort(names)
+		return nil, fmt.Errorf("unknown history store '%s' (available stores: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(options)
+}
+
+// newCallEntry creates the history entry of the call of a tool by a client
+func newCallEntry(identity *ClientIdentity, request mcp.CallToolRequest, start time.Time,
+	status string, result *mcp.CallToolResult, err error,
+) HistoryEntry {
+	entry := HistoryEntry{
+		Time:      start.UTC(),
+		Tool:      request.Params.Name,
+		Arguments: redactArguments(request.Params.Arguments),
+		Status:    status,
+		Duration:  time.Since(start).Seconds(),
+	}
+	if identity != nil {
+		entry.Client = identity.Name
+	}
+	if result != nil {
+		entry.Usage, _ = result.Meta["usage"].(map[string]interface{})
+	}
+	switch {
+	case err != nil:
+		entry.Status = "error"
+		entry.Error = common.Redact(err.Error())
+	case result != nil && result.IsError:
+		entry.Status = "error"
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				entry.Error = common.Redact(text.Text)
+				break
+			}
+		}
+	}
+	return entry
+}
+
+// redactArguments returns a copy of the arguments of a call with the secrets redacted,
+// as clients can pass them in any argument
+func redactArguments(arguments map[string]interface{}) map[string]interface{} {
+	if arguments == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		redacted[name] = redactValue(value)
+	}
+	return redacted
+}
+
+// redactValue redacts the secrets in the strings of a value, including the ones in lists and objects
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return common.Redact(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+		return redacted
+	case map[string]interface{}:
+		return redactArguments(v)
+	default:
+		return value
+	}
+}
+
+// historyFileRegex matches the characters not allowed in the names of the history files
+var historyFileRegex = regexp.MustCompile(`[^a-zA-Z0-9._@-]`)
+
+// dirHistoryStore keeps the history in a directory, with a JSONL file per client
+// (<client>.jsonl, or anonymous.jsonl for the clients not authenticated) and per
+// schedule (schedules/<schedule>.jsonl)
+type dirHistoryStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// newDirHistoryStore creates the store in a directory
+func newDirHistoryStore(dir string) (*dirHistoryStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the dir history store requires a dir")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &dirHistoryStore{dir: dir}, nil
+}
+
+// path returns the path of the file of a client or a schedule
+func (d *dirHistoryStore) path(client string, schedule string) string {
+	switch {
+	case schedule != "":
+		return filepath.Join(d.dir, "schedules", historyFileRegex.ReplaceAllString(schedule, "_")+".jsonl")
+	case client != "":
+		return filepath.Join(d.dir, historyFileRegex.ReplaceAllString(client, "_")+".jsonl")
+	default:
+		return filepath.Join(d.dir, "anonymous.jsonl")
+	}
+}
+
+func (d *dirHistoryStore) Record(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := d.path(entry.Client, entry.Schedule)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (d *dirHistoryStore) Query(filter HistoryFilter) ([]HistoryEntry, error) {
+	var files []string
+	if filter.Client != "" || filter.Schedule != "" {
+		files = []string{d.path(filter.Client, filter.Schedule)}
+	} else {
+		for _, pattern := range []string{"*.jsonl", filepath.Join("schedules", "*.jsonl")} {
+			matches, err := filepath.Glob(filepath.Join(d.dir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var res []HistoryEntry
+	for _, file := range files {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16*1024*1024)
+		for scanner.Scan() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && filter.matches(entry) {
+				res = append(res, entry)
+			}
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+	}
+
+	slices.SortStableFunc(res, func(a, b HistoryEntry) int { return b.Time.Compare(a.Time) })
+	if filter.Limit > 0 && len(res) > filter.Limit {
+		res = res[:filter.Limit]
+	}
+	return res, nil
+}
+
+func (d *dirHistoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sqlTableRegex matches the valid names for the table of the history
+var sqlTableRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// sqlHistoryStore keeps the history in a table of a SQL database (ie, SQLite or Postgres),
+// with the entries as JSON and some columns for filtering them. The driver of the
+// database must be linked in the binary (ie, imported by the program embedding MCPShell).
+type sqlHistoryStore struct {
+	db     *sql.DB
+	table  string
+	dollar bool // the driver uses $1, $2... for the parameters (instead of ?)
+}
+
+// newSQLHistoryStore creates a SQL store (for Postgres when dollar is true) with the options:
+//
+//   - driver: the name of the database/sql driver (the first one registered of the
+//     drivers given by default)
+//   - dsn: the data source name (ie, a file for SQLite or "postgres://..." for Postgres)
+//   - dsn_env: the environment variable with the data source name (instead of dsn)
+//   - table: the table of the history ("mcpshell_history" by default), created if needed
+func newSQLHistoryStore(drivers []string, dollar bool, options map[string]interface{}) (*sqlHistoryStore, error) {
+	driver, _ := options["driver"].(string)
+	if driver == "" {
+		for _, name := range drivers {
+			if slices.Contains(sql.Drivers(), name) {
+				driver = name
+				break
+			}
+		}
+	}
+	if driver == "" {
+		if len(drivers) == 0 {
+			return nil, fmt.Errorf("the SQL history store requires a driver")
+		}
+		return nil, fmt.Errorf("no SQL driver available for the history (%s): it must be imported "+
+			"by the program embedding MCPShell", strings.Join(drivers, " or "))
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("the SQL driver '%s' is not available: it must be imported "+
+			"by the program embedding MCPShell", driver)
+	}
+
+	dsn, _ := options["dsn"].(string)
+	if env, _ := options["dsn_env"].(string); env != "" {
+		dsn = os.Getenv(env)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("the SQL history store requires a dsn")
+	}
+
+	table, _ := options["table"].(string)
+	if table == "" {
+		table = "mcpshell_history"
+	}
+	if !sqlTableRegex.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name '%s'", table)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the history database: %w", err)
+	}
+	s := &sqlHistoryStore{db: db, table: table, dollar: dollar || driver == "postgres" || driver == "pgx"}
+
+	for _, stmt := range []string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (started BIGINT NOT NULL, client TEXT NOT NULL, " +
+			"schedule TEXT NOT NULL, tool TEXT NOT NULL, status TEXT NOT NULL, entry TEXT NOT NULL)",
+		"CREATE INDEX IF NOT EXISTS " + table + "_started ON " + table + " (started)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to create the history table: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// rebind replaces the ? in a query by the parameters of the driver
+func (s *sqlHistoryStore) rebind(query string) string {
+	if !s.dollar {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (s *sqlHistoryStore) Record(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.rebind("INSERT INTO "+s.table+" (started, client, schedule, tool, status, entry) VALUES (?, ?, ?, ?, ?, ?)"),
+		entry.Time.UnixNano(), entry.Client, entry.Schedule, entry.Tool, entry.Status, string(data))
+	return err
+}
+
+func (s *sqlHistoryStore) Query(filter HistoryFilter) ([]HistoryEntry, error) {
+	query := "SELECT entry FROM " + s.table
+	var conditions []string
+	var args []interface{}
+	for _, column := range []struct{ name, value string }{
+		{"client", filter.Client},
+		{"schedule", filter.Schedule},
+		{"tool", filter.Tool},
+	} {
+		if column.value != "" {
+			conditions = append(conditions, column.name+" = ?")
+			args = append(args, column.value)
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY started DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := s.db.Query(s.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var res []HistoryEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to query the history: %w", err)
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("invalid entry in the history: %w", err)
+		}
+		res = append(res, entry)
+	}
+	return res, rows.Err()
+}
+
+func (s *sqlHistoryStore) Close() error {
+	return s.db.Close()
+}
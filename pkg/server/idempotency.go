@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// IdempotencyKeyArgument is the argument with the idempotency key of a tool call
+const IdempotencyKeyArgument = "_idempotency_key"
+
+// IdempotencyKeyHeader is the HTTP header with the idempotency key of a tool call
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKeyMeta is the field of the "_meta" of a tool call with its idempotency key
+const idempotencyKeyMeta = "idempotencyKey"
+
+// DefaultIdempotencyWindow is the time the results of the calls with idempotency keys
+// are kept, unless another one is configured
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+// maxIdempotentCalls is the maximum number of results kept for the calls with
+// idempotency keys. The oldest ones are discarded when the limit is reached.
+var maxIdempotentCalls = 10000
+
+// idempotencyKeyCtxKey is the key of the idempotency key of a call in the context
+type idempotencyKeyCtxKey struct{}
+
+// withIdempotencyKey returns a context with the idempotency key of a call
+// (ie, obtained from a header of the HTTP request)
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// httpIdempotencyKey returns the idempotency key of an HTTP request, from its header
+// or the "_meta" of the JSON-RPC request (if any)
+func httpIdempotencyKey(r *http.Request, req map[string]interface{}) string {
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		return key
+	}
+	if params, ok := req["params"].(map[string]interface{}); ok {
+		if meta, ok := params["_meta"].(map[string]interface{}); ok {
+			key, _ := meta[idempotencyKeyMeta].(string)
+			return key
+		}
+	}
+	return ""
+}
+
+// idempotentCall is a call with an idempotency key, in progress or finished
+type idempotentCall struct {
+	args    string        // the arguments of the call, for detecting the reuse of keys
+	done    chan struct{} // closed when the call finishes
+	result  *mcp.CallToolResult
+	created time.Time
+}
+
+// idempotencyStore keeps the results of the calls with idempotency keys
+type idempotencyStore struct {
+	mu     sync.Mutex
+	window time.Duration
+	calls  map[string]*idempotentCall
+}
+
+// newIdempotencyStore creates a store keeping the results for some time
+func newIdempotencyStore(window time.Duration) *idempotencyStore {
+	return &idempotencyStore{window: window, calls: map[string]*idempotentCall{}}
+}
+
+// begin returns the call for a key, creating it when there is no call for it (or it
+// has expired). It returns true when the call has been created, and it must be run.
+func (s *idempotencyStore) begin(key string, args string) (*idempotentCall, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, call := range s.calls {
+		if now.Sub(call.created) > s.window {
+			delete(s.calls, k)
+		}
+	}
+
+	if call, found := s.calls[key]; found {
+		return call, false
+	}
+
+	for len(s.calls) >= maxIdempotentCalls && len(s.calls) > 0 {
+		oldestKey := ""
+		for k, call := range s.calls {
+			if oldestKey == "" || call.created.Before(s.calls[oldestKey].created) {
+				oldestKey = k
+			}
+		}
+		delete(s.calls, oldestKey)
+	}
+
+	call := &idempotentCall{args: args, done: make(chan struct{}), created: now}
+	s.calls[key] = call
+	return call, true
+}
+
+// finish records the result of a call, or forgets it when it failed (so it can be retried)
+func (s *idempotencyStore) finish(key string, call *idempotentCall, result *mcp.CallToolResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil || result == nil {
+		if s.calls[key] == call {
+			delete(s.calls, key)
+		}
+	} else {
+		call.result = result
+	}
+	close(call.done)
+}
+
+// idempotencyKey returns the idempotency key of a call (from the arguments or the context),
+// and the arguments without it
+func idempotencyKey(ctx context.Context, request mcp.CallToolRequest) (string, map[string]interface{}) {
+	args := request.Params.Arguments
+	key, _ := ctx.Value(idempotencyKeyCtxKey{}).(string)
+
+	if value, found := args[IdempotencyKeyArgument]; found {
+		if s, ok := value.(string); ok && s != "" {
+			key = s
+		}
+		args = make(map[string]interface{}, len(request.Params.Arguments))
+		for k, v := range request.Params.Arguments {
+			if k != IdempotencyKeyArgument {
+				args[k] = v
+			}
+		}
+	}
+	return key, args
+}
+
+// idempotentToolCall returns the original result of the calls repeated with the same
+// idempotency key (by the same client) while the result is kept, instead of running
+// the tools again. Repeated calls arriving while the original one is still running wait
+// for its result. Calls failing with an error (ie, rejected) are not kept.
+func (s *Server) idempotentToolCall(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key, args := idempotencyKey(ctx, request)
+		request.Params.Arguments = args
+		if key == "" || s.idempotency == nil {
+			return next(ctx, request)
+		}
+
+		client := ""
+		if identity := ClientIdentityFromContext(ctx); identity != nil {
+			client = identity.Name
+		}
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		storeKey := client + "\x00" + request.Params.Name + "\x00" + key
+
+		for {
+			call, created := s.idempotency.begin(storeKey, string(data))
+			if created {
+				result, err := next(ctx, request)
+				s.idempotency.finish(storeKey, call, result, err)
+				return result, err
+			}
+
+			if call.args != string(data) {
+				return nil, fmt.Errorf("idempotency key '%s' already used with other arguments", key)
+			}
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if call.result == nil {
+				// the original call failed, so this one can be run
+				continue
+			}
+
+			s.callLogger(ctx).Info("Returning the result of the previous call to '%s' with idempotency key '%s'", request.Params.Name, key)
+			replayed := *call.result
+			replayed.Meta = map[string]interface{}{}
+			for k, v := range call.result.Meta {
+				replayed.Meta[k] = v
+			}
+			replayed.Meta["idempotent_replay"] = true
+			return &replayed, nil
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_IdempotencyKeys(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "increment"
+      description: "Increment the counter"
+      params:
+        step:
+          type: string
+          description: "The step"
+      run:
+        command: "echo {{ .step }} >> ` + counter + ` && wc -l < ` + counter + ` | tr -d ' '"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, REST: true, RESTInsecure: true})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	call := func(args map[string]interface{}) string {
+		output, err := srv.ExecuteTool(context.Background(), "increment", args)
+		if err != nil {
+			t.Fatalf("Failed to call the tool: %v", err)
+		}
+		return output
+	}
+
+	// the calls without keys are always run
+	if got := call(map[string]interface{}{"step": "a"}); got != "1" {
+		t.Errorf("Expected '1', got %q", got)
+	}
+	if got := call(map[string]interface{}{"step": "a"}); got != "2" {
+		t.Errorf("Expected '2', got %q", got)
+	}
+
+	// ... but the calls repeated with the same key get the original result
+	if got := call(map[string]interface{}{"step": "a", IdempotencyKeyArgument: "k1"}); got != "3" {
+		t.Errorf("Expected '3', got %q", got)
+	}
+	if got := call(map[string]interface{}{"step": "a", IdempotencyKeyArgument: "k1"}); got != "3" {
+		t.Errorf("Expected the original result '3', got %q", got)
+	}
+	if got := call(map[string]interface{}{"step": "a", IdempotencyKeyArgument: "k2"}); got != "4" {
+		t.Errorf("Expected '4', got %q", got)
+	}
+
+	// the keys cannot be reused with other arguments
+	if _, err := srv.ExecuteTool(context.Background(), "increment",
+		map[string]interface{}{"step": "b", IdempotencyKeyArgument: "k1"}); err == nil || !strings.Contains(err.Error(), "other arguments") {
+		t.Errorf("Expected an error reusing a key with other arguments, got %v", err)
+	}
+
+	// the keys can be given in a header of the REST requests
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/tools/increment", strings.NewReader(`{"step": "c"}`))
+		req.Header.Set(IdempotencyKeyHeader, "k3")
+		rec := httptest.NewRecorder()
+		srv.HTTPHandler().ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), `"output":"5"`) {
+			t.Errorf("Expected the output '5', got %s", rec.Body.String())
+		}
+	}
+
+	// ... or in the "_meta" of the MCP requests
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", `+
+			`"params": {"name": "increment", "arguments": {"step": "d"}, "_meta": {"idempotencyKey": "k4"}}}`))
+		rec := httptest.NewRecorder()
+		srv.HTTPHandler().ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), `"text":"6"`) {
+			t.Errorf("Expected the output '6', got %s", rec.Body.String())
+		}
+	}
+}
+
+func TestServer_IdempotencyDisabled(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  idempotency:
+    window: "0"
+  tools:
+    - name: "increment"
+      description: "Increment the counter"
+      run:
+        command: "echo x >> ` + counter + ` && wc -l < ` + counter + ` | tr -d ' '"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	for _, expected := range []string{"1", "2"} {
+		output, err := srv.ExecuteTool(context.Background(), "increment", map[string]interface{}{IdempotencyKeyArgument: "k1"})
+		if err != nil || output != expected {
+			t.Errorf("Expected %q, got %q (%v)", expected, output, err)
+		}
+	}
+}
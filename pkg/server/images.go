@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// prePullTimeout is the maximum time for pulling an image when the server starts
+const prePullTimeout = 10 * time.Minute
+
+// prePullImages pulls the images of the tools running in containers with the "pre_pull"
+// option (following their pull policies), so the first calls are not slowed down (or
+// broken) by pulling them. Failures are only logged, as the tools can still pull the
+// images when called.
+func (s *Server) prePullImages(toolDefs []config.Tool) {
+	pulled := map[string]bool{}
+	for _, toolDef := range toolDefs {
+		if command.RunnerType(toolDef.GetEffectiveRunner()) != command.RunnerTypeDocker {
+			continue
+		}
+		opts, err := command.NewDockerRunnerOptions(toolDef.GetEffectiveOptions())
+		if err != nil || !opts.PrePull {
+			continue
+		}
+
+		ref := opts.ImageRef()
+		if strings.Contains(ref, "{{") {
+			s.logger.Info("Tool '%s': image '%s' not pulled, as it depends on the parameters", toolDef.MCPTool.Name, ref)
+			continue
+		}
+		if pulled[ref] {
+			continue
+		}
+		pulled[ref] = true
+
+		s.logger.Info("Pulling image '%s' for tool '%s'", ref, toolDef.MCPTool.Name)
+		ctx, cancel := context.WithTimeout(context.Background(), prePullTimeout)
+		err = opts.PullImage(ctx)
+		cancel()
+		if err != nil {
+			s.logger.Error("Failed to pull the image of tool '%s': %v", toolDef.MCPTool.Name, err)
+		}
+	}
+}
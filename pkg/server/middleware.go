@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// ToolHandler handles the calls to the tools
+type ToolHandler = mcpserver.ToolHandlerFunc
+
+// Middleware wraps the handler of the tool calls, for intercepting all the calls
+// (ie, for logging, metrics, authorization or rate limiting). Middlewares can modify
+// the context and the request before calling the next handler, as well as the result,
+// or they can reject the call by returning an error without calling it.
+type Middleware func(next ToolHandler) ToolHandler
+
+// LoggingMiddleware logs the calls to the tools, with their durations and results
+func LoggingMiddleware(logger *common.Logger) Middleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			client := "-"
+			if identity := ClientIdentityFromContext(ctx); identity != nil {
+				client = identity.Name
+			}
+			start := time.Now()
+
+			result, err := next(ctx, request)
+
+			status := callStatus(result, err)
+			callLogger := logger
+			if id := common.ExecutionIDFromContext(ctx); id != "" {
+				callLogger = logger.WithExecutionID(id)
+			}
+			callLogger.Info("Call to tool '%s' (client: %s): %s in %.3fs", request.Params.Name, client, status, time.Since(start).Seconds())
+			return result, err
+		}
+	}
+}
+
+// AuthMiddleware rejects the calls to the tools not allowed by a function,
+// as if the tools did not exist
+func AuthMiddleware(allowed func(ctx context.Context, tool string) bool) Middleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if !allowed(ctx, request.Params.Name) {
+				return nil, fmt.Errorf("tool '%s' not found", request.Params.Name)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// RateLimitMiddleware limits the calls to all the tools (from all the clients),
+// with a limit like "100/h", "10/m" or "1/s"
+func RateLimitMiddleware(limit string) (Middleware, error) {
+	limiter, err := parseRateLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if !limiter.allow(time.Now()) {
+				return nil, fmt.Errorf("rate limit exceeded: try again later")
+			}
+			return next(ctx, request)
+		}
+	}, nil
+}
+
+// ToolMetrics are the metrics of the calls to a tool
+type ToolMetrics struct {
+	Calls    int64         // the number of calls
+	Errors   int64         // the number of calls that failed
+	Duration time.Duration // the total duration of the calls
+
+	// the last calls (and failed calls), as exemplars of the metrics
+	LastCall  ToolCallExemplar
+	LastError ToolCallExemplar
+}
+
+// ToolCallExemplar is a call to a tool, identified by the ID of its execution
+type ToolCallExemplar struct {
+	ExecutionID string
+	Duration    time.Duration
+	Time        time.Time
+}
+
+// Metrics collects the metrics of the calls to the tools, with the MetricsMiddleware.
+// It is an HTTP handler too, serving the metrics in the Prometheus text format.
+type Metrics struct {
+	mu    sync.Mutex
+	tools map[string]*ToolMetrics
+}
+
+// NewMetrics creates an empty collection of metrics
+func NewMetrics() *Metrics {
+	return &Metrics{tools: map[string]*ToolMetrics{}}
+}
+
+// Get returns a copy of the metrics of the tools, by name
+func (m *Metrics) Get() map[string]ToolMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make(map[string]ToolMetrics, len(m.tools))
+	for name, metrics := range m.tools {
+		res[name] = *metrics
+	}
+	return res
+}
+
+// record records a call to a tool
+func (m *Metrics) record(tool string, executionID string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics, found := m.tools[tool]
+	if !found {
+		metrics = &ToolMetrics{}
+		m.tools[tool] = metrics
+	}
+	metrics.Calls++
+	metrics.Duration += duration
+	exemplar := ToolCallExemplar{ExecutionID: executionID, Duration: duration, Time: time.Now()}
+	if executionID != "" {
+		metrics.LastCall = exemplar
+	}
+	if failed {
+		metrics.Errors++
+		if executionID != "" {
+			metrics.LastError = exemplar
+		}
+	}
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format, or in the OpenMetrics format
+// (with the IDs of the executions of the last calls as exemplars) when it is accepted
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics := m.Get()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	}
+	series := []struct {
+		name, help, kind string
+		value            func(ToolMetrics) string
+		exemplar         func(ToolMetrics) (ToolCallExemplar, float64)
+	}{
+		{"mcpshell_tool_calls_total", "The number of calls to the tools.", "counter",
+			func(m ToolMetrics) string { return fmt.Sprintf("%d", m.Calls) },
+			func(m ToolMetrics) (ToolCallExemplar, float64) { return m.LastCall, 1 }},
+		{"mcpshell_tool_errors_total", "The number of calls to the tools that failed.", "counter",
+			func(m ToolMetrics) string { return fmt.Sprintf("%d", m.Errors) },
+			func(m ToolMetrics) (ToolCallExemplar, float64) { return m.LastError, 1 }},
+		{"mcpshell_tool_duration_seconds_total", "The total duration of the calls to the tools.", "counter",
+			func(m ToolMetrics) string { return fmt.Sprintf("%g", m.Duration.Seconds()) },
+			func(m ToolMetrics) (ToolCallExemplar, float64) { return m.LastCall, m.LastCall.Duration.Seconds() }},
+	}
+	for _, s := range series {
+		family := s.name
+		if openMetrics {
+			// the families of the counters do not have the _total suffix in OpenMetrics
+			family = strings.TrimSuffix(s.name, "_total")
+		}
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family, s.help, family, s.kind)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(w, "%s{tool=%q} %s", s.name, name, s.value(metrics[name]))
+			if exemplar, value := s.exemplar(metrics[name]); openMetrics && exemplar.ExecutionID != "" {
+				_, _ = fmt.Fprintf(w, " # {execution_id=%q} %g %.3f", exemplar.ExecutionID, value,
+					float64(exemplar.Time.UnixMilli())/1000)
+			}
+			_, _ = fmt.Fprint(w, "\n")
+		}
+	}
+	if openMetrics {
+		_, _ = fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+// MetricsMiddleware collects the metrics of the calls to the tools
+func MetricsMiddleware(metrics *Metrics) Middleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+			metrics.record(request.Params.Name, common.ExecutionIDFromContext(ctx), time.Since(start), callStatus(result, err) != "ok")
+			return result, err
+		}
+	}
+}
+
+// callStatus returns the status of a call: "ok" or "error"
+func callStatus(result *mcp.CallToolResult, err error) string {
+	if err != nil || (result != nil && result.IsError) {
+		return "error"
+	}
+	return "ok"
+}
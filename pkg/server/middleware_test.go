@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_Middlewares(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+    - name: "secret"
+      description: "A secret tool"
+      run:
+        command: "echo secret"
+    - name: "fail"
+      description: "Always fails"
+      run:
+        command: "exit 1"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	// a middleware recording the calls, in order
+	var calls []string
+	recorder := func(name string) Middleware {
+		return func(next ToolHandler) ToolHandler {
+			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, name+":"+request.Params.Name)
+				return next(ctx, request)
+			}
+		}
+	}
+
+	rateLimit, err := RateLimitMiddleware("3/h")
+	if err != nil {
+		t.Fatalf("Failed to create rate limit middleware: %v", err)
+	}
+	metrics := NewMetrics()
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+		Middlewares: []Middleware{
+			recorder("first"),
+			LoggingMiddleware(logger),
+			MetricsMiddleware(metrics),
+			AuthMiddleware(func(ctx context.Context, tool string) bool { return tool != "secret" }),
+			rateLimit,
+			recorder("last"),
+		},
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	output, err := srv.ExecuteTool(context.Background(), "hello", map[string]interface{}{})
+	if err != nil || output != "hello" {
+		t.Errorf("Expected 'hello', got %q (%v)", output, err)
+	}
+	if strings.Join(calls, ",") != "first:hello,last:hello" {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+
+	// the calls can be rejected by the middlewares
+	calls = nil
+	if _, err := srv.ExecuteTool(context.Background(), "secret", map[string]interface{}{}); err == nil {
+		t.Errorf("Expected the call to be rejected")
+	}
+	if strings.Join(calls, ",") != "first:secret" {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+
+	_, _ = srv.ExecuteTool(context.Background(), "fail", map[string]interface{}{})
+	_, _ = srv.ExecuteTool(context.Background(), "hello", map[string]interface{}{})
+	if _, err := srv.ExecuteTool(context.Background(), "hello", map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("Expected the rate limit to be exceeded, got %v", err)
+	}
+
+	got := metrics.Get()
+	if got["hello"].Calls != 3 || got["hello"].Errors != 1 {
+		t.Errorf("Unexpected metrics for 'hello': %+v", got["hello"])
+	}
+	if got["secret"].Calls != 1 || got["secret"].Errors != 1 {
+		t.Errorf("Unexpected metrics for 'secret': %+v", got["secret"])
+	}
+	if got["fail"].Calls != 1 || got["fail"].Errors != 1 {
+		t.Errorf("Unexpected metrics for 'fail': %+v", got["fail"])
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `mcpshell_tool_calls_total{tool="hello"} 3`) {
+		t.Errorf("Unexpected metrics:\n%s", rec.Body.String())
+	}
+}
+
+func TestRateLimitMiddleware_Invalid(t *testing.T) {
+	if _, err := RateLimitMiddleware("fast"); err == nil {
+		t.Errorf("Expected an error for an invalid rate limit")
+	}
+}
+
+func TestServer_ExecutionIDs(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "mcpshell.log")
+	logger, err := common.NewLogger("", logFile, common.LogLevelInfo, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer func() { _ = logger.Close() }()
+
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  history:
+    backend: "dir"
+    options:
+      dir: "` + filepath.Join(dir, "history") + `"
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	metrics := NewMetrics()
+	srv := New(Config{
+		ConfigFile:  testConfigFile,
+		Shell:       "sh",
+		Logger:      logger,
+		Middlewares: []Middleware{LoggingMiddleware(logger), MetricsMiddleware(metrics)},
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	// the ID of the execution is returned in the metadata of the result
+	response := srv.mcpServer.HandleMessage(context.Background(), mustMarshalJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": "hello", "arguments": map[string]interface{}{}},
+	}))
+	var decoded struct {
+		Result struct {
+			Meta map[string]interface{} `json:"_meta"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(mustMarshalJSON(response), &decoded); err != nil {
+		t.Fatalf("Failed to decode the response: %v", err)
+	}
+	id, _ := decoded.Result.Meta["execution_id"].(string)
+	if id == "" {
+		t.Fatalf("Expected an execution ID in the result, got %+v", decoded.Result.Meta)
+	}
+
+	// ... and it is found in the logs, the history and the metrics
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read the logs: %v", err)
+	}
+	if !strings.Contains(string(data), "[exec:"+id+"] Call to tool 'hello'") {
+		t.Errorf("Expected the execution ID in the logs, got:\n%s", data)
+	}
+
+	entries, err := srv.history.Query(HistoryFilter{Tool: "hello"})
+	if err != nil || len(entries) != 1 || entries[0].ExecutionID != id {
+		t.Errorf("Expected the execution ID in the history, got %+v (%v)", entries, err)
+	}
+
+	if got := metrics.Get()["hello"].LastCall.ExecutionID; got != id {
+		t.Errorf("Expected the execution ID in the metrics, got %q", got)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	metrics.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, `mcpshell_tool_calls_total{tool="hello"} 1 # {execution_id="`+id+`"} 1 `) ||
+		!strings.Contains(body, "# TYPE mcpshell_tool_calls counter") || !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("Unexpected OpenMetrics:\n%s", body)
+	}
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/utils"
+)
+
+// The periods of the quotas
+const (
+	QuotaDay  = "day"
+	QuotaWeek = "week"
+)
+
+// QuotaFile is the name of the file with the counters of the quotas in the MCPShell home
+const QuotaFile = "quotas.json"
+
+// QuotaCounter is the number of calls of a tool in a period (by a client, for the
+// quotas per client)
+type QuotaCounter struct {
+	Tool   string    `json:"tool"`
+	Client string    `json:"client,omitempty"`
+	Period string    `json:"period"` // "day" or "week"
+	Start  time.Time `json:"start"`  // the start of the period
+	Calls  int       `json:"calls"`
+}
+
+// sameCounter returns true if two counters are for the same tool, client and period
+func (c QuotaCounter) sameCounter(other QuotaCounter) bool {
+	return c.Tool == other.Tool && c.Client == other.Client && c.Period == other.Period && c.Start.Equal(other.Start)
+}
+
+// End returns the end of the period of the counter
+func (c QuotaCounter) End() time.Time {
+	if c.Period == QuotaWeek {
+		return c.Start.AddDate(0, 0, 7)
+	}
+	return c.Start.AddDate(0, 0, 1)
+}
+
+// QuotaLimit is the maximum number of calls in a counter
+type QuotaLimit struct {
+	QuotaCounter
+	Limit int
+}
+
+// QuotaFilter selects the counters of the quotas. Empty fields match all the counters.
+type QuotaFilter struct {
+	Tool   string
+	Client string
+	Period string
+}
+
+// matches returns true if a counter is selected by the filter
+func (f QuotaFilter) matches(counter QuotaCounter) bool {
+	return (f.Tool == "" || counter.Tool == f.Tool) &&
+		(f.Client == "" || counter.Client == f.Client) &&
+		(f.Period == "" || counter.Period == f.Period)
+}
+
+// QuotaStore keeps the counters of the quotas of the tools, so they survive the
+// restarts of the server. The counters of the periods that have ended are discarded.
+type QuotaStore interface {
+	// Take counts a call in some counters, unless any of them has reached its limit.
+	// It returns the limit reached (nil when the call is counted).
+	Take(limits []QuotaLimit) (*QuotaLimit, error)
+
+	// List returns the counters of the current periods selected by a filter
+	List(filter QuotaFilter) ([]QuotaCounter, error)
+
+	// Reset removes the counters selected by a filter, returning how many were removed
+	Reset(filter QuotaFilter) (int, error)
+
+	// Close releases the resources of the store
+	Close() error
+}
+
+// QuotaStoreFactory creates a store with its options
+type QuotaStoreFactory func(options map[string]interface{}) (QuotaStore, error)
+
+var (
+	quotaStoresMu sync.RWMutex
+	quotaStores   = map[string]QuotaStoreFactory{
+		"file": func(options map[string]interface{}) (QuotaStore, error) {
+			path, _ := options["path"].(string)
+			return newFileQuotaStore(path)
+		},
+	}
+)
+
+// RegisterQuotaStore registers a type of store for the quotas, so it can be selected
+// by name in the configuration (ie, for keeping the quotas in a database shared by
+// several instances of the server)
+//
+// Parameters:
+//   - name: The name of the store
+//   - factory: The function creating the store with its options
+//
+// Returns:
+//   - An error if there is another store with that name
+func RegisterQuotaStore(name string, factory QuotaStoreFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("invalid quota store")
+	}
+
+	quotaStoresMu.Lock()
+	defer quotaStoresMu.Unlock()
+	if _, found := quotaStores[name]; found {
+		return fmt.Errorf("quota store '%s' already registered", name)
+	}
+	quotaStores[name] = factory
+	return nil
+}
+
+// NewQuotaStore creates a store for the quotas of some type ("file" when empty)
+func NewQuotaStore(name string, options map[string]interface{}) (QuotaStore, error) {
+	if name == "" {
+		name = "file"
+	}
+
+	quotaStoresMu.RLock()
+	factory, found := quotaStores[name]
+	var names []string
+	for n := range quotaStores {
+		names = append(names, n)
+	}
+	quotaStoresMu.RUnlock()
+
+	if !found {
+		slices.Sort(names)
+		return nil, fmt.Errorf("unknown quota store '%s' (available stores: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(options)
+}
+
+// quotaPeriodStart returns the start of the period (in UTC) containing a time.
+// The weeks start on Monday.
+func quotaPeriodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == QuotaWeek {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+// quotaLimits returns the limits of the quota of a tool for a call by a client
+func quotaLimits(tool string, client string, quota config.MCPToolQuota, now time.Time) []QuotaLimit {
+	if !quota.PerClient {
+		client = ""
+	}
+	var limits []QuotaLimit
+	for _, period := range []struct {
+		name  string
+		limit int
+	}{
+		{QuotaDay, quota.Daily},
+		{QuotaWeek, quota.Weekly},
+	} {
+		if period.limit > 0 {
+			limits = append(limits, QuotaLimit{
+				QuotaCounter: QuotaCounter{Tool: tool, Client: client, Period: period.name, Start: quotaPeriodStart(period.name, now)},
+				Limit:        period.limit,
+			})
+		}
+	}
+	return limits
+}
+
+// enforceQuota wraps the handler of a tool, rejecting the calls over its quota with a
+// limit exceeded error. All the calls count, even the ones failing, but the internal
+// calls (ie, the smoke tests).
+func (s *Server) enforceQuota(tool string, quota config.MCPToolQuota, next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if isInternalCall(ctx) {
+			return next(ctx, request)
+		}
+
+		var client string
+		if identity := ClientIdentityFromContext(ctx); identity != nil {
+			client = identity.Name
+		}
+
+		exceeded, err := s.quotas.Take(quotaLimits(tool, client, quota, time.Now()))
+		if err != nil {
+			// do not run the calls that cannot be counted
+			s.callLogger(ctx).Error("Failed to check the quota of tool '%s': %v", tool, err)
+			return nil, fmt.Errorf("failed to check the quota of tool '%s'", tool)
+		}
+		if exceeded != nil {
+			limitErr := &command.LimitExceededError{
+				Tool:   tool,
+				Scope:  exceeded.Period,
+				Limit:  exceeded.Limit,
+				Client: exceeded.Client,
+				Reset:  exceeded.End(),
+			}
+			s.callLogger(ctx).Info("Rejecting call to tool '%s': %v", tool, limitErr)
+			result := mcp.NewToolResultError(limitErr.Error())
+			result.Meta = limitErr.Meta()
+			return result, nil
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// fileQuotaStore keeps the counters of the quotas in a JSON file. The file is read in
+// every call, so the counters reset from the command line are seen by the servers running.
+// The updates take a lock in a file next to it, so the servers sharing the file (and the
+// command line) do not lose each other's calls.
+type fileQuotaStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newFileQuotaStore creates the store in a file (~/.mcpshell/quotas.json by default)
+func newFileQuotaStore(path string) (*fileQuotaStore, error) {
+	if path == "" {
+		home, err := utils.GetMCPShellHome()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the MCPShell home: %w", err)
+		}
+		path = filepath.Join(home, QuotaFile)
+	}
+	return &fileQuotaStore{path: path}, nil
+}
+
+// load reads the counters of the current periods
+func (f *fileQuotaStore) load(now time.Time) ([]QuotaCounter, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the quotas: %w", err)
+	}
+
+	var counters []QuotaCounter
+	if err := json.Unmarshal(data, &counters); err != nil {
+		return nil, fmt.Errorf("invalid quotas file %s: %w", f.path, err)
+	}
+	return slices.DeleteFunc(counters, func(c QuotaCounter) bool {
+		return !now.Before(c.End())
+	}), nil
+}
+
+// lock takes the lock of the quotas file between the processes, returning the
+// function for releasing it
+func (f *fileQuotaStore) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the quotas directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the quotas lock: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to lock the quotas: %w", err)
+	}
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+// save writes the counters, through a temporary file in the same directory
+// so the readers never see a partial file
+func (f *fileQuotaStore) save(counters []QuotaCounter) error {
+	if counters == nil {
+		counters = []QuotaCounter{}
+	}
+	data, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save the quotas: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save the quotas: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save the quotas: %w", err)
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *fileQuotaStore) Take(limits []QuotaLimit) (*QuotaLimit, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	counters, err := f.load(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// check all the limits before counting the call in any of the counters
+	indexes := make([]int, len(limits))
+	for i, limit := range limits {
+		indexes[i] = slices.IndexFunc(counters, limit.sameCounter)
+		if indexes[i] >= 0 && counters[indexes[i]].Calls >= limit.Limit {
+			exceeded := limit
+			return &exceeded, nil
+		}
+	}
+	for i, limit := range limits {
+		if indexes[i] < 0 {
+			counter := limit.QuotaCounter
+			counter.Calls = 0
+			counters = append(counters, counter)
+			indexes[i] = len(counters) - 1
+		}
+		counters[indexes[i]].Calls++
+	}
+	return nil, f.save(counters)
+}
+
+func (f *fileQuotaStore) List(filter QuotaFilter) ([]QuotaCounter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counters, err := f.load(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(counters, func(c QuotaCounter) bool { return !filter.matches(c) }), nil
+}
+
+func (f *fileQuotaStore) Reset(filter QuotaFilter) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	counters, err := f.load(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	kept := slices.DeleteFunc(slices.Clone(counters), filter.matches)
+	if err := f.save(kept); err != nil {
+		return 0, err
+	}
+	return len(counters) - len(kept), nil
+}
+
+func (f *fileQuotaStore) Close() error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package server
+
+import "os"
+
+// lockFile does nothing in the platforms without file locks, where only the
+// calls of the same process are serialized
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile releases the lock taken with lockFile
+func unlockFile(file *os.File) error {
+	return nil
+}
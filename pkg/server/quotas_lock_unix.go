@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock of a file, waiting for other processes holding it
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock taken with lockFile
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
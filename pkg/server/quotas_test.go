@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestQuotaPeriodStart(t *testing.T) {
+	// a Friday
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.FixedZone("CET", 3600))
+	if got := quotaPeriodStart(QuotaDay, now); !got.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected start of the day: %s", got)
+	}
+	if got := quotaPeriodStart(QuotaWeek, now); !got.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected start of the week: %s", got)
+	}
+	sunday := time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC)
+	if got := quotaPeriodStart(QuotaWeek, sunday); !got.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected start of the week for a Sunday: %s", got)
+	}
+}
+
+func TestFileQuotaStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas", "quotas.json")
+	store, err := NewQuotaStore("file", map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatalf("Failed to create the store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Now()
+	limits := func(client string) []QuotaLimit {
+		return []QuotaLimit{
+			{QuotaCounter: QuotaCounter{Tool: "geocode", Client: client, Period: QuotaDay, Start: quotaPeriodStart(QuotaDay, now)}, Limit: 2},
+			{QuotaCounter: QuotaCounter{Tool: "geocode", Client: client, Period: QuotaWeek, Start: quotaPeriodStart(QuotaWeek, now)}, Limit: 3},
+		}
+	}
+
+	for i := 1; i <= 2; i++ {
+		if exceeded, err := store.Take(limits("ci")); err != nil || exceeded != nil {
+			t.Fatalf("Call %d: exceeded = %v, error = %v", i, exceeded, err)
+		}
+	}
+	exceeded, err := store.Take(limits("ci"))
+	if err != nil || exceeded == nil || exceeded.Period != QuotaDay {
+		t.Fatalf("Expected the daily limit to be exceeded, got %v, %v", exceeded, err)
+	}
+
+	// the calls rejected are not counted in any counter
+	counters, err := store.List(QuotaFilter{Client: "ci"})
+	if err != nil || len(counters) != 2 || counters[0].Calls != 2 || counters[1].Calls != 2 {
+		t.Fatalf("Unexpected counters: %+v, %v", counters, err)
+	}
+
+	// the counters survive restarts (they are read again from the file)
+	reopened, err := NewQuotaStore("file", map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatalf("Failed to reopen the store: %v", err)
+	}
+	if exceeded, _ := reopened.Take(limits("ci")); exceeded == nil {
+		t.Errorf("Expected the limit to be exceeded after reopening the store")
+	}
+	if exceeded, _ := reopened.Take(limits("other")); exceeded != nil {
+		t.Errorf("Expected the calls of other clients to be counted separately")
+	}
+
+	// the counters can be reset
+	removed, err := reopened.Reset(QuotaFilter{Client: "ci", Period: QuotaDay})
+	if err != nil || removed != 1 {
+		t.Fatalf("Reset() = %d, %v, want 1", removed, err)
+	}
+	if exceeded, _ := store.Take(limits("ci")); exceeded != nil {
+		t.Errorf("Expected the call to be counted after resetting the daily counter")
+	}
+	if exceeded, _ := store.Take(limits("ci")); exceeded == nil || exceeded.Period != QuotaWeek {
+		t.Errorf("Expected the weekly limit to be exceeded, got %v", exceeded)
+	}
+
+	// the counters of the periods that have ended are discarded
+	old := []QuotaLimit{{QuotaCounter: QuotaCounter{Tool: "geocode", Period: QuotaDay, Start: quotaPeriodStart(QuotaDay, now.AddDate(0, 0, -2))}, Limit: 1}}
+	if exceeded, err := store.Take(old); err != nil || exceeded != nil {
+		t.Fatalf("Take() = %v, %v", exceeded, err)
+	}
+	if counters, _ := store.List(QuotaFilter{Tool: "geocode"}); len(counters) != 4 {
+		t.Errorf("Expected only the counters of the current periods, got %+v", counters)
+	}
+
+	if _, err := NewQuotaStore("unknown", nil); err == nil || !strings.Contains(err.Error(), "available stores: file") {
+		t.Errorf("Expected an error for an unknown store, got %v", err)
+	}
+}
+
+// TestFileQuotaStoreConcurrent checks the stores sharing a file (like several servers)
+// do not lose each other's calls
+func TestFileQuotaStoreConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "quotas.json")
+	limits := []QuotaLimit{{QuotaCounter: QuotaCounter{Tool: "geocode", Period: QuotaDay, Start: quotaPeriodStart(QuotaDay, time.Now())}, Limit: 100}}
+
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, err := newFileQuotaStore(path)
+			if err != nil {
+				t.Errorf("Failed to create the store: %v", err)
+				return
+			}
+			for j := 0; j < 20; j++ {
+				exceeded, err := store.Take(limits)
+				if err != nil {
+					t.Errorf("Take() failed: %v", err)
+				} else if exceeded == nil {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted.Load() != 100 {
+		t.Errorf("Expected 100 calls accepted, got %d", accepted.Load())
+	}
+	store, _ := newFileQuotaStore(path)
+	if counters, err := store.List(QuotaFilter{}); err != nil || len(counters) != 1 || counters[0].Calls != 100 {
+		t.Errorf("Unexpected counters: %+v, %v", counters, err)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmps) > 0 {
+		t.Errorf("Unexpected temporary files left: %v", tmps)
+	}
+}
+
+func TestServer_Quotas(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  quotas:
+    backend: file
+    options:
+      path: "` + filepath.ToSlash(filepath.Join(dir, "quotas.json")) + `"
+  tools:
+    - name: "geocode"
+      description: "A tool using a billable API"
+      aliases: ["locate"]
+      quota:
+        daily: 2
+        per_client: true
+      run:
+        command: "echo found"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	ctxCI := WithClientIdentity(context.Background(), &ClientIdentity{Name: "ci"})
+	ctxBot := WithClientIdentity(context.Background(), &ClientIdentity{Name: "bot"})
+
+	// the aliases share the quota of the tool
+	for _, tool := range []string{"geocode", "locate"} {
+		if result, err := srv.CallTool(ctxCI, tool, map[string]interface{}{}); err != nil || result.IsError {
+			t.Fatalf("Call to %s failed: %v, %v", tool, result, err)
+		}
+	}
+	result, err := srv.CallTool(ctxCI, "geocode", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !result.IsError || result.Meta["error"] != "limit_exceeded" || result.Meta["scope"] != QuotaDay || result.Meta["client"] != "ci" {
+		t.Errorf("Expected a limit exceeded error, got %+v", result)
+	}
+	if text := resultText(result); !strings.Contains(text, "can only be called 2 times per day by client 'ci'") {
+		t.Errorf("Unexpected error: %q", text)
+	}
+
+	// every client has its own quota
+	if result, err := srv.CallTool(ctxBot, "geocode", map[string]interface{}{}); err != nil || result.IsError {
+		t.Errorf("Expected the call of another client to succeed: %v, %v", result, err)
+	}
+}
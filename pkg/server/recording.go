@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// The types of the events in the recordings of the sessions
+const (
+	RecordedRequest  = "request"
+	RecordedResponse = "response"
+	RecordedError    = "error"
+	RecordedCommand  = "command"
+)
+
+// RecordedEvent is an event in the recording of a session: a MCP request, its response
+// (or error), or a command run for a tool call
+type RecordedEvent struct {
+	Time        time.Time               `json:"time"`
+	Type        string                  `json:"type"`
+	Session     string                  `json:"session,omitempty"`      // the ID of the MCP session
+	ID          interface{}             `json:"id,omitempty"`           // the ID of the JSON-RPC request
+	Method      string                  `json:"method,omitempty"`       // the MCP method (ie, "tools/call")
+	Request     json.RawMessage         `json:"request,omitempty"`      // the request (for requests)
+	Result      json.RawMessage         `json:"result,omitempty"`       // the result (for responses)
+	Error       string                  `json:"error,omitempty"`        // the error (for errors)
+	ExecutionID string                  `json:"execution_id,omitempty"` // the ID of the execution (for commands)
+	Command     *RecordedCommandDetails `json:"command,omitempty"`      // the command run (for commands)
+}
+
+// RecordedCommandDetails is a command run for a tool call, in a recording
+type RecordedCommandDetails struct {
+	Tool     string   `json:"tool"`
+	Runner   string   `json:"runner"`
+	Command  string   `json:"command"`
+	Argv     []string `json:"argv,omitempty"`
+	Output   string   `json:"output"`
+	Error    string   `json:"error,omitempty"`
+	Duration float64  `json:"duration_seconds"`
+}
+
+// SessionRecorder records the MCP requests and responses of the sessions, as well as the
+// commands run, in a JSON lines file that can be replayed (with "mcpshell replay").
+// The secrets are redacted.
+type SessionRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewSessionRecorder creates a recorder appending the events to a file
+func NewSessionRecorder(path string) (*SessionRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the recording file: %w", err)
+	}
+	return &SessionRecorder{file: file}, nil
+}
+
+// record writes an event to the file
+func (r *SessionRecorder) record(ctx context.Context, event RecordedEvent) {
+	event.Time = time.Now().UTC()
+	if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+		event.Session = session.SessionID()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		common.GetLogger().Error("Failed to record a %s: %v", event.Type, err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.WriteString(common.Redact(string(data)) + "\n"); err != nil {
+		common.GetLogger().Error("Failed to record a %s: %v", event.Type, err)
+	}
+}
+
+// hooks adds the hooks recording the requests and the responses
+func (r *SessionRecorder) hooks(hooks *mcpserver.Hooks) {
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		data, _ := json.Marshal(message)
+		r.record(ctx, RecordedEvent{Type: RecordedRequest, ID: id, Method: string(method), Request: data})
+	})
+	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
+		data, _ := json.Marshal(result)
+		r.record(ctx, RecordedEvent{Type: RecordedResponse, ID: id, Method: string(method), Result: data})
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		r.record(ctx, RecordedEvent{Type: RecordedError, ID: id, Method: string(method), Error: err.Error()})
+	})
+}
+
+// middleware records the commands run for the tool calls
+func (r *SessionRecorder) middleware(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx = command.WithCommandObserver(ctx, func(ctx context.Context, cmd command.ExecutedCommand) {
+			r.record(ctx, RecordedEvent{
+				Type:        RecordedCommand,
+				ExecutionID: common.ExecutionIDFromContext(ctx),
+				Command: &RecordedCommandDetails{
+					Tool:     cmd.Tool,
+					Runner:   cmd.Runner,
+					Command:  cmd.Command,
+					Argv:     cmd.Argv,
+					Output:   cmd.Output,
+					Error:    cmd.Error,
+					Duration: cmd.Duration.Seconds(),
+				},
+			})
+		})
+		return next(ctx, request)
+	}
+}
+
+// Close closes the file of the recording
+func (r *SessionRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// RecordedToolCall is a tool call found in a recording, with its recorded result
+type RecordedToolCall struct {
+	Name      string
+	Arguments map[string]interface{}
+	Output    string // the text of the result (empty when there is no response)
+	IsError   bool   // the call failed (with an error result or a protocol error)
+	Answered  bool   // the response (or the error) of the call is in the recording
+}
+
+// ReadRecordedToolCalls reads the tool calls in a recording, in the order they were received
+func ReadRecordedToolCalls(path string) ([]RecordedToolCall, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the recording: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var calls []RecordedToolCall
+	pending := map[string]int{} // the index of the calls without response, by session and request ID
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid event in line %d of the recording: %w", line, err)
+		}
+		if event.Method != string(mcp.MethodToolsCall) {
+			continue
+		}
+		key := fmt.Sprintf("%s/%v", event.Session, event.ID)
+
+		switch event.Type {
+		case RecordedRequest:
+			var request mcp.CallToolRequest
+			if err := json.Unmarshal(event.Request, &request); err != nil {
+				return nil, fmt.Errorf("invalid tool call in line %d of the recording: %w", line, err)
+			}
+			pending[key] = len(calls)
+			calls = append(calls, RecordedToolCall{Name: request.Params.Name, Arguments: request.Params.Arguments})
+		case RecordedResponse, RecordedError:
+			i, found := pending[key]
+			if !found {
+				continue
+			}
+			delete(pending, key)
+			calls[i].Answered = true
+			if event.Type == RecordedError {
+				calls[i].Output, calls[i].IsError = event.Error, true
+				continue
+			}
+			if result, err := mcp.ParseCallToolResult(&event.Result); err == nil {
+				calls[i].Output, calls[i].IsError = resultText(result), result.IsError
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the recording: %w", err)
+	}
+	return calls, nil
+}
+
+// resultText returns the text contents of a result
+func resultText(result *mcp.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if t, ok := content.(mcp.TextContent); ok {
+			text += t.Text
+		}
+	}
+	return text
+}
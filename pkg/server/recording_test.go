@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_RecordSessions(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	recording := filepath.Join(dir, "session.jsonl")
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "greet"
+      description: "Greet someone"
+      params:
+        name:
+          type: string
+          required: true
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "fail"
+      description: "Always fails"
+      run:
+        command: "echo failed; exit 1"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, RecordFile: recording})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	for _, call := range []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"greet", map[string]interface{}{"name": "John"}},
+		{"fail", nil},
+		{"missing", nil},
+	} {
+		_, _ = srv.ExecuteTool(context.Background(), call.name, call.args)
+	}
+	srv.Close()
+
+	// the commands are recorded with the ID of their execution
+	file, err := os.Open(recording)
+	if err != nil {
+		t.Fatalf("Failed to open the recording: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	var commands []RecordedEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid event %q: %v", scanner.Text(), err)
+		}
+		if event.Type == RecordedCommand {
+			commands = append(commands, event)
+		}
+	}
+	if len(commands) != 2 || commands[0].Command.Command != "echo hello John" ||
+		commands[0].Command.Output != "hello John" || commands[0].ExecutionID == "" || commands[1].Command.Error == "" {
+		t.Errorf("Unexpected commands in the recording: %+v", commands)
+	}
+
+	// ... and the tool calls can be read with their results
+	calls, err := ReadRecordedToolCalls(recording)
+	if err != nil {
+		t.Fatalf("ReadRecordedToolCalls() error = %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 tool calls, got %+v", calls)
+	}
+	if calls[0].Name != "greet" || calls[0].Arguments["name"] != "John" || calls[0].Output != "hello John" || calls[0].IsError {
+		t.Errorf("Unexpected first call: %+v", calls[0])
+	}
+	if calls[1].Name != "fail" || !calls[1].IsError || !calls[1].Answered {
+		t.Errorf("Unexpected second call: %+v", calls[1])
+	}
+	if calls[2].Name != "missing" || !calls[2].IsError || calls[2].Output == "" {
+		t.Errorf("Unexpected third call: %+v", calls[2])
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RESTPathPrefix is the prefix of the REST endpoints of the tools (ie, "/tools/list_files")
+const RESTPathPrefix = "/tools"
+
+// restTool is a tool in the list of tools of the REST API
+type restTool struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	InputSchema mcp.ToolInputSchema `json:"input_schema"`
+	Tags        []string            `json:"tags,omitempty"`
+}
+
+// restResult is the result of a call to a tool in the REST API
+type restResult struct {
+	Output  string                 `json:"output"`
+	IsError bool                   `json:"is_error"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// restError is an error in the REST API
+type restError struct {
+	Error string `json:"error"`
+}
+
+// handleREST serves the tools as REST endpoints: "GET /tools" lists the tools the client
+// can use, and "POST /tools/<name>" calls a tool with the arguments in a JSON object.
+// The calls go through the same checks as the calls from the MCP clients: the
+// authentication, the middlewares and the validation of the arguments.
+func (s *Server) handleREST(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeRESTError(w, http.StatusServiceUnavailable, "server not ready")
+		return
+	}
+
+	ctx, ok := s.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, RESTPathPrefix), "/")
+	if name == "" {
+		if r.Method != http.MethodGet {
+			writeRESTError(w, http.StatusMethodNotAllowed, "only GET allowed")
+			return
+		}
+		request := mustMarshalJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "tools/list"})
+		resp, ok := s.mcpServer.HandleMessage(ctx, request).(mcp.JSONRPCResponse)
+		if !ok {
+			writeRESTError(w, http.StatusInternalServerError, "failed to list the tools")
+			return
+		}
+		var tags []string
+		if query := r.URL.Query().Get("tags"); query != "" {
+			tags = strings.Split(query, ",")
+		}
+		res := []restTool{}
+		if list, ok := resp.Result.(mcp.ListToolsResult); ok {
+			for _, tool := range list.Tools {
+				toolTags := s.getToolTags(tool.Name)
+				if len(tags) > 0 && !hasAnyTag(toolTags, tags) {
+					continue
+				}
+				res = append(res, restTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema, Tags: toolTags})
+			}
+		}
+		writeRESTResponse(w, http.StatusOK, map[string]interface{}{"tools": res})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeRESTError(w, http.StatusMethodNotAllowed, "only POST allowed")
+		return
+	}
+
+	// The arguments are a JSON object (or nothing, for tools without parameters)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	args := map[string]interface{}{}
+	if strings.TrimSpace(string(body)) != "" {
+		if err := json.Unmarshal(body, &args); err != nil {
+			writeRESTError(w, http.StatusBadRequest, "the arguments must be a JSON object")
+			return
+		}
+	}
+
+	s.logger.Info("REST call to tool '%s' from %s", name, r.RemoteAddr)
+	ctx = withIdempotencyKey(ctx, httpIdempotencyKey(r, nil))
+	request := mustMarshalJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": args},
+	})
+
+	switch resp := s.mcpServer.HandleMessage(ctx, request).(type) {
+	case mcp.JSONRPCError:
+		writeRESTError(w, restErrorStatus(resp.Error.Message), resp.Error.Message)
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.CallToolResult)
+		if !ok {
+			writeRESTError(w, http.StatusInternalServerError, "unexpected result")
+			return
+		}
+
+		var texts []string
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+		res := restResult{Output: strings.Join(texts, "\n"), IsError: result.IsError, Meta: result.Meta}
+
+		status := http.StatusOK
+		switch {
+		case result.IsError && result.Meta["error"] == "internal_error":
+			status = http.StatusInternalServerError
+		case result.IsError && (result.Meta["error"] == "limit_exceeded" || result.Meta["error"] == "budget_exceeded"):
+			status = http.StatusTooManyRequests
+		case result.IsError && result.Meta["usage"] == nil:
+			// the call was rejected before running the command (ie, invalid arguments)
+			status = http.StatusBadRequest
+		}
+		writeRESTResponse(w, status, res)
+	default:
+		writeRESTError(w, http.StatusInternalServerError, "unexpected response")
+	}
+}
+
+// restErrorStatus returns the HTTP status code for the error of a call
+func restErrorStatus(message string) int {
+	switch {
+	case strings.Contains(message, "not found"):
+		return http.StatusNotFound
+	case strings.Contains(message, "rate limit"):
+		return http.StatusTooManyRequests
+	case strings.Contains(message, "shutting down"):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeRESTResponse writes a JSON response
+func writeRESTResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeRESTError writes an error as a JSON response
+func writeRESTError(w http.ResponseWriter, status int, message string) {
+	writeRESTResponse(w, status, restError{Error: message})
+}
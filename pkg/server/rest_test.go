@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_REST(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	t.Setenv("MCPSHELL_TEST_REST_TOKEN", "rest-token")
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  auth:
+    clients:
+      - name: "ci"
+        token_env: "MCPSHELL_TEST_REST_TOKEN"
+    acls:
+      - clients: ["ci"]
+        tools: ["greet"]
+  tools:
+    - name: "greet"
+      description: "Greet someone"
+      params:
+        name:
+          type: string
+          description: "The name"
+          required: true
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "restart"
+      description: "Restart the service"
+      run:
+        command: "echo restarted"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+		REST:       true,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	handler := srv.HTTPHandler()
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// clients must be authenticated
+	if rec := do(http.MethodPost, "/tools/greet", "", `{"name": "world"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthorized status without credentials, got %d", rec.Code)
+	}
+
+	// the list only has the tools allowed for the client
+	rec := do(http.MethodGet, "/tools", "rest-token", "")
+	var list struct {
+		Tools []restTool `json:"tools"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected list of tools (%d): %s", rec.Code, rec.Body.String())
+	}
+	if len(list.Tools) != 1 || list.Tools[0].Name != "greet" || len(list.Tools[0].InputSchema.Required) != 1 {
+		t.Errorf("Unexpected list of tools: %+v", list.Tools)
+	}
+
+	rec = do(http.MethodPost, "/tools/greet", "rest-token", `{"name": "world"}`)
+	var result restResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if rec.Code != http.StatusOK || result.IsError || result.Output != "hello world" {
+		t.Errorf("Unexpected result (%d): %+v", rec.Code, result)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+	}{
+		{"missing argument", http.MethodPost, "/tools/greet", `{}`, http.StatusBadRequest},
+		{"invalid JSON", http.MethodPost, "/tools/greet", `["world"]`, http.StatusBadRequest},
+		{"unknown tool", http.MethodPost, "/tools/unknown", `{}`, http.StatusNotFound},
+		{"tool not allowed", http.MethodPost, "/tools/restart", `{}`, http.StatusNotFound},
+		{"wrong method", http.MethodGet, "/tools/greet", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.path, "rest-token", tt.body)
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_RESTDisabled(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	// the REST endpoints require the authentication of the clients
+	if err := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, REST: true}).CreateServer(); err == nil {
+		t.Errorf("Expected an error serving the REST endpoints without authentication")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tools/hello", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the REST endpoints to be disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// ScheduleHistorySize is the number of results kept for every schedule
+var ScheduleHistorySize = 20
+
+// scheduleURIPrefix is the prefix of the URIs of the resources with the results of the schedules
+const scheduleURIPrefix = "schedule://"
+
+// scheduleNameRegex matches the valid names for schedules
+var scheduleNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// scheduleRun is the result of a scheduled run of a tool
+type scheduleRun struct {
+	started  time.Time
+	duration time.Duration
+	output   string
+	failed   bool
+}
+
+// schedule is a tool run periodically
+type schedule struct {
+	config  config.MCPScheduleConfig
+	cron    *common.CronSchedule
+	handler mcpserver.ToolHandlerFunc
+	store   HistoryStore // the store with the results of the runs (can be nil)
+
+	mu      sync.Mutex
+	history []scheduleRun // the most recent first
+	next    time.Time     // the time of the next run
+}
+
+// checkSchedules checks the schedules in the configuration are valid, returning
+// the parsed cron expressions (by schedule name)
+func checkSchedules(cfg *config.ToolsConfig) (map[string]*common.CronSchedule, error) {
+	res := map[string]*common.CronSchedule{}
+
+	for _, scheduleConfig := range cfg.MCP.Schedules {
+		if !scheduleNameRegex.MatchString(scheduleConfig.Name) {
+			return nil, fmt.Errorf("invalid schedule name '%s': only letters, digits, '_' and '-' are allowed", scheduleConfig.Name)
+		}
+		if _, exists := res[scheduleConfig.Name]; exists {
+			return nil, fmt.Errorf("duplicate schedule '%s'", scheduleConfig.Name)
+		}
+
+		cron, err := common.ParseCron(scheduleConfig.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron for schedule '%s': %w", scheduleConfig.Name, err)
+		}
+
+		if !isStaticTool(cfg, scheduleConfig.Tool) {
+			return nil, fmt.Errorf("schedule '%s' refers to an unknown tool '%s'", scheduleConfig.Name, scheduleConfig.Tool)
+		}
+
+		res[scheduleConfig.Name] = cron
+	}
+
+	return res, nil
+}
+
+// newSchedules checks the schedules in the configuration, creating the handlers of
+// their tools. Schedules for tools that are not available (ie, because their
+// prerequisites are not met) are skipped.
+func (s *Server) newSchedules(cfg *config.ToolsConfig, toolDefs []config.Tool) ([]*schedule, error) {
+	crons, err := checkSchedules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*schedule
+	for _, scheduleConfig := range cfg.MCP.Schedules {
+		var toolDef *config.Tool
+		for i := range toolDefs {
+			if slices.Contains(toolDefs[i].Config.Names(), scheduleConfig.Tool) {
+				toolDef = &toolDefs[i]
+				break
+			}
+		}
+		if toolDef == nil {
+			s.logger.Info("Schedule '%s' skipped: tool '%s' is not available", scheduleConfig.Name, scheduleConfig.Tool)
+			continue
+		}
+
+		toolDef.Secrets = s.secrets
+		cmdHandler, err := command.NewCommandHandler(*toolDef, toolDef.Config.Params, s.shell, s.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create handler for schedule '%s': %w", scheduleConfig.Name, err)
+		}
+		if s.runner != nil {
+			cmdHandler.SetRunner(s.runner)
+		}
+
+		res = append(res, &schedule{
+			config:  scheduleConfig,
+			cron:    crons[scheduleConfig.Name],
+			handler: s.wrapHandlerWithPanicRecovery(cmdHandler.GetMCPHandler()),
+			store:   s.history,
+		})
+	}
+
+	return res, nil
+}
+
+// startSchedules registers the resources with the results of the schedules,
+// and starts running the tools in the background.
+func (s *Server) startSchedules(schedules []*schedule) {
+	stop := make(chan struct{})
+	s.stopSchedules = stop
+	s.schedules = schedules
+
+	for _, sch := range schedules {
+		s.logger.Info("Scheduling tool '%s' with '%s' (schedule '%s')", sch.config.Tool, sch.config.Cron, sch.config.Name)
+
+		resource := mcp.NewResource(sch.uri(), "Schedule "+sch.config.Name,
+			mcp.WithResourceDescription(fmt.Sprintf("The results of the scheduled runs of the tool '%s' (%s)",
+				sch.config.Tool, sch.config.Cron)),
+			mcp.WithMIMEType("text/plain"))
+		s.mcpServer.AddResource(resource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: sch.uri(), MIMEType: "text/plain", Text: sch.String()},
+			}, nil
+		})
+
+		go func(sch *schedule) {
+			for {
+				next := sch.cron.Next(time.Now())
+				if next.IsZero() {
+					s.logger.Error("Schedule '%s' will never run again", sch.config.Name)
+					return
+				}
+				sch.mu.Lock()
+				sch.next = next
+				sch.mu.Unlock()
+
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-stop:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				s.runSchedule(sch)
+			}
+		}(sch)
+	}
+}
+
+// runSchedule runs the tool of a schedule, keeping the result and notifying the clients
+func (s *Server) runSchedule(sch *schedule) {
+	ctx, executionID := common.EnsureExecutionID(context.Background())
+	logger := s.logger.WithExecutionID(executionID)
+	logger.Info("Running tool '%s' for schedule '%s'", sch.config.Tool, sch.config.Name)
+
+	args := make(map[string]interface{}, len(sch.config.Params))
+	for k, v := range sch.config.Params {
+		args[k] = v
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = sch.config.Tool
+	request.Params.Arguments = args
+
+	run := scheduleRun{started: time.Now()}
+	result, err := sch.handler(ctx, request)
+	run.duration = time.Since(run.started)
+	switch {
+	case err != nil:
+		run.output, run.failed = err.Error(), true
+	case result != nil:
+		run.failed = result.IsError
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				run.output += text.Text
+			}
+		}
+	}
+	if run.failed {
+		logger.Error("Schedule '%s' failed: %s", sch.config.Name, run.output)
+	}
+
+	sch.mu.Lock()
+	sch.history = append([]scheduleRun{run}, sch.history...)
+	if len(sch.history) > ScheduleHistorySize {
+		sch.history = sch.history[:ScheduleHistorySize]
+	}
+	sch.mu.Unlock()
+
+	if sch.store != nil {
+		entry := HistoryEntry{
+			Time:        run.started.UTC(),
+			ExecutionID: executionID,
+			Schedule:    sch.config.Name,
+			Tool:        sch.config.Tool,
+			Arguments:   args,
+			Status:      "ok",
+			Output:      run.output,
+			Duration:    run.duration.Seconds(),
+		}
+		if run.failed {
+			entry.Status, entry.Output, entry.Error = "error", "", common.Redact(run.output)
+		}
+		if err := sch.store.Record(entry); err != nil {
+			logger.Error("Failed to record the run of schedule '%s' in the history: %v", sch.config.Name, err)
+		}
+	}
+
+	s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": sch.uri()})
+}
+
+// uri returns the URI of the resource with the results of the schedule
+func (sch *schedule) uri() string {
+	return scheduleURIPrefix + sch.config.Name
+}
+
+// runs returns the results of the schedule, the most recent first: from the history
+// store when there is one (with the runs of all the instances sharing it)
+func (sch *schedule) runs() []scheduleRun {
+	if sch.store != nil {
+		entries, err := sch.store.Query(HistoryFilter{Schedule: sch.config.Name, Limit: ScheduleHistorySize})
+		if err == nil {
+			res := make([]scheduleRun, 0, len(entries))
+			for _, entry := range entries {
+				run := scheduleRun{
+					started:  entry.Time.Local(),
+					duration: time.Duration(entry.Duration * float64(time.Second)),
+					output:   entry.Output,
+					failed:   entry.Status != "ok",
+				}
+				if run.failed {
+					run.output = entry.Error
+				}
+				res = append(res, run)
+			}
+			return res
+		}
+	}
+
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	return slices.Clone(sch.history)
+}
+
+// String returns the results of the schedule, the most recent first
+func (sch *schedule) String() string {
+	history := sch.runs()
+
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Schedule: %s\n", sch.config.Name)
+	fmt.Fprintf(&sb, "Tool: %s\n", sch.config.Tool)
+	fmt.Fprintf(&sb, "Cron: %s\n", sch.config.Cron)
+	if !sch.next.IsZero() {
+		fmt.Fprintf(&sb, "Next run: %s\n", sch.next.Format(time.RFC3339))
+	}
+
+	if len(history) == 0 {
+		sb.WriteString("\nNo runs yet\n")
+	}
+	for _, run := range history {
+		status := "succeeded"
+		if run.failed {
+			status = "failed"
+		}
+		fmt.Fprintf(&sb, "\n## %s (%s, took %s)\n\n%s\n", run.started.Format(time.RFC3339), status,
+			run.duration.Round(time.Millisecond), run.output)
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// ScriptsRescanInterval is the interval for checking for changes in the scripts directories
+var ScriptsRescanInterval = 5 * time.Second
+
+// watchScripts starts watching the scripts directories in the background,
+// registering the tools again when the scripts change.
+func (s *Server) watchScripts(cfg *config.ToolsConfig) {
+	stop := make(chan struct{})
+	s.stopScripts = stop
+	fingerprint := cfg.ScriptsFingerprint()
+
+	go func() {
+		ticker := time.NewTicker(ScriptsRescanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current := cfg.ScriptsFingerprint()
+				if current == fingerprint {
+					continue
+				}
+				fingerprint = current
+
+				s.logger.Info("Scripts directories changed: reloading tools from scripts")
+				s.reloadScripts(cfg)
+			}
+		}
+	}()
+}
+
+// reloadScripts scans the scripts directories, replacing the tools registered from scripts
+func (s *Server) reloadScripts(cfg *config.ToolsConfig) {
+	scriptTools, err := cfg.GetScriptTools()
+	if err != nil {
+		// keep the current tools until the scripts are fixed
+		s.logger.Error("Failed to load tools from scripts: %v", err)
+		return
+	}
+
+	// Skip the tools that would shadow the tools in the configuration
+	var toolConfigs []config.MCPToolConfig
+	for _, scriptTool := range scriptTools {
+		if isStaticTool(cfg, scriptTool.Name) {
+			s.logger.Error("Script tool '%s' ignored: there is another tool with the same name", scriptTool.Name)
+			continue
+		}
+		toolConfigs = append(toolConfigs, scriptTool)
+	}
+
+	if len(s.scriptTools) > 0 {
+		s.logger.Debug("Removing %d tools from scripts", len(s.scriptTools))
+		s.mcpServer.DeleteTools(s.scriptTools...)
+		s.toolTagsMu.Lock()
+		for _, name := range s.scriptTools {
+			delete(s.toolTags, name)
+			delete(s.toolEnums, name)
+		}
+		s.toolTagsMu.Unlock()
+		s.scriptTools = nil
+	}
+
+	for _, toolDef := range s.selectTools(config.NewTools(toolConfigs)) {
+		names, err := s.registerTool(toolDef, nil)
+		if err != nil {
+			s.logger.Error("Failed to register tool '%s': %v", toolDef.MCPTool.Name, err)
+			continue
+		}
+		s.scriptTools = append(s.scriptTools, names...)
+	}
+
+	s.logger.Info("Registered %d tools from scripts", len(s.scriptTools))
+}
+
+// isStaticTool returns true if a name is used by a tool (or alias) in the configuration file
+func isStaticTool(cfg *config.ToolsConfig, name string) bool {
+	for _, toolConfig := range cfg.MCP.Tools {
+		for _, n := range toolConfig.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_Scripts(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	ScriptsRescanInterval = 50 * time.Millisecond
+
+	dir := t.TempDir()
+	scriptsDir := filepath.Join(dir, "scripts")
+	if err := os.Mkdir(scriptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeScript := func(name string, output string) {
+		t.Helper()
+		content := `#!/bin/sh
+# ---
+# description: Greets someone
+# params:
+#   name:
+#     type: string
+#     required: true
+# ---
+echo "` + output + ` $PARAM_NAME"
+`
+		if err := os.WriteFile(filepath.Join(scriptsDir, name), []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeScript("hello.sh", "hello")
+
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configFile, []byte("mcp:\n  scripts:\n    - dir: scripts\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(Config{
+		ConfigFile: configFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	output, err := srv.ExecuteTool(context.Background(), "hello", map[string]interface{}{"name": "world"})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if output != "hello world" {
+		t.Errorf("Expected 'hello world', got %q", output)
+	}
+
+	// add a new script, and wait for it to be registered
+	writeScript("bye.sh", "bye")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		output, err = srv.ExecuteTool(context.Background(), "bye", map[string]interface{}{"name": "world"})
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to execute the new tool: %v", err)
+	}
+	if output != "bye world" {
+		t.Errorf("Expected 'bye world', got %q", output)
+	}
+}
@@ -7,10 +7,19 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	mcpserver "github.com/mark3labs/mcp-go/server"
@@ -19,18 +28,60 @@ import (
 	"github.com/inercia/MCPShell/pkg/command"
 	"github.com/inercia/MCPShell/pkg/common"
 	"github.com/inercia/MCPShell/pkg/config"
+	"github.com/inercia/MCPShell/pkg/workers"
 )
 
+// GuidancePromptName is the name of the prompt with the system prompt and the
+// usage guidance of the tools
+const GuidancePromptName = "tools_guidance"
+
 // Server represents the MCPShell server that handles tool registration
 // and request processing.
 type Server struct {
-	configFile  string
-	shell       string
-	version     string
-	description string
+	configFile   string
+	shell        string
+	version      string
+	description  string
+	runner       command.Runner // the runner for all the commands (can be nil)
+	middlewares  []Middleware   // the middlewares applied to the tool calls
+	rest         bool           // serve the tools as REST endpoints too (in HTTP mode)
+	restInsecure bool           // serve the REST endpoints without authenticating the clients
+	recordFile   string         // the file where the sessions are recorded (empty when not recording)
+	selfTest     bool           // run the smoke tests of the tools before advertising them
+
+	onlyTags     []string // only serve the tools with any of these tags (all when empty)
+	excludeTools []string // the names (or glob patterns) of the tools not served
 
 	mcpServer *mcpserver.MCPServer // MCP server instance
 
+	secrets      *common.Secrets // secrets available to the tools
+	systemPrompt string          // system prompt for the tools, with their usage guidance
+	scriptTools  []string        // names of the tools registered from scripts
+	stopScripts  chan struct{}   // closed to stop watching the scripts directories
+
+	schedules     []*schedule   // the tools run periodically
+	stopSchedules chan struct{} // closed to stop running the schedules
+
+	stopJobs context.CancelFunc // stops running the queued jobs (nil when not running them)
+
+	auth        *authorizer                              // authenticates the clients and checks their tools (can be nil)
+	workers     http.Handler                             // the coordinator of the remote workers (nil when disabled)
+	idempotency *idempotencyStore                        // the results of the calls with idempotency keys (nil when disabled)
+	chaos       *chaosInjector                           // the faults injected in the calls (nil when disabled)
+	history     HistoryStore                             // the history of the calls of the tools (nil when disabled)
+	quotas      QuotaStore                               // the counters of the quotas of the tools (nil when not available)
+	budget      *budgetTracker                           // the cost spent in the calls of the tools (nil when there is no budget)
+	recorder    *SessionRecorder                         // the recorder of the sessions (nil when not recording)
+	toolTags    map[string][]string                      // the tags of the tools registered from the configuration
+	toolEnums   map[string]map[string]common.ParamConfig // the parameters with dynamic enums of the tools
+	toolTagsMu  sync.RWMutex                             // protects the tags and the enums of the tools
+
+	ready atomic.Bool // true once the tools have been loaded and registered
+
+	calls        callTracker    // the tool calls in progress
+	sessions     sessionTracker // the start times and the calls of the sessions
+	drainTimeout time.Duration  // the time for finishing the tool calls in progress when shutting down
+
 	logger *common.Logger
 }
 
@@ -43,6 +94,15 @@ type Config struct {
 	Descriptions        []string       // Descriptions shown to AI clients (can be specified multiple times)
 	DescriptionFiles    []string       // Paths to files containing descriptions (can be specified multiple times)
 	DescriptionOverride bool           // Whether to override the description in the config file
+	DrainTimeout        time.Duration  // Time for finishing the tool calls in progress when shutting down
+	Runner              command.Runner // Runner for all the commands, instead of the runners configured (optional)
+	Middlewares         []Middleware   // Middlewares applied to the tool calls, in order (optional)
+	REST                bool           // Whether to serve the tools as REST endpoints too (in HTTP mode)
+	RESTInsecure        bool           // Whether to serve the REST endpoints without authenticating the clients
+	RecordFile          string         // File where the requests, the responses and the commands are recorded (optional)
+	SelfTest            bool           // Whether to run the smoke tests of the tools, not advertising the tools failing them
+	OnlyTags            []string       // Only serve the tools with any of these tags (optional)
+	ExcludeTools        []string       // Names (or glob patterns) of the tools not served (optional)
 }
 
 // New creates a new Server instance with the provided configuration
@@ -64,11 +124,20 @@ func New(cfg Config) *Server {
 	}
 
 	return &Server{
-		configFile:  cfg.ConfigFile,
-		shell:       cfg.Shell,
-		logger:      cfg.Logger,
-		version:     cfg.Version,
-		description: finalDescription,
+		configFile:   cfg.ConfigFile,
+		shell:        cfg.Shell,
+		logger:       cfg.Logger,
+		version:      cfg.Version,
+		description:  finalDescription,
+		drainTimeout: cfg.DrainTimeout,
+		runner:       cfg.Runner,
+		middlewares:  cfg.Middlewares,
+		rest:         cfg.REST,
+		restInsecure: cfg.RESTInsecure,
+		recordFile:   cfg.RecordFile,
+		selfTest:     cfg.SelfTest,
+		onlyTags:     cfg.OnlyTags,
+		excludeTools: cfg.ExcludeTools,
 	}
 }
 
@@ -89,26 +158,80 @@ func (s *Server) Validate() error {
 	}
 
 	// Check if there are any tools defined
-	if len(cfg.MCP.Tools) == 0 {
+	if len(cfg.MCP.Tools) == 0 && len(cfg.MCP.Scripts) == 0 {
 		s.logger.Error("No tools defined in the configuration file")
 		return fmt.Errorf("no tools defined in the configuration file")
 	}
 
 	s.logger.Info("Found %d tools in configuration", len(cfg.MCP.Tools))
 
+	// Check the scripts directories can be read
+	if _, err := cfg.GetScriptTools(); err != nil {
+		s.logger.Error("Invalid scripts: %v", err)
+		return fmt.Errorf("invalid scripts: %w", err)
+	}
+
+	// Validate the secrets definitions (without obtaining their values)
+	if _, err := common.NewSecrets(cfg.Secrets); err != nil {
+		s.logger.Error("Invalid secrets configuration: %v", err)
+		return fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+
 	// Use shell from config if present and no shell is explicitly set
 	shell := s.shell
 	if shell == "" && cfg.MCP.Run.Shell != "" {
 		s.logger.Debug("Using shell from config: %s", cfg.MCP.Run.Shell)
 	}
 
+	// Check the schedules are valid
+	if _, err := checkSchedules(cfg); err != nil {
+		s.logger.Error("Invalid schedules: %v", err)
+		return fmt.Errorf("invalid schedules: %w", err)
+	}
+
+	// Check the faults of the chaos testing mode are valid
+	if _, err := newChaosInjector(cfg.MCP.Chaos); err != nil {
+		s.logger.Error("Invalid chaos configuration: %v", err)
+		return fmt.Errorf("invalid chaos configuration: %w", err)
+	}
+
+	// Check the budget is valid
+	if _, err := newBudgetTracker(cfg.MCP.Budget); err != nil {
+		s.logger.Error("Invalid budget: %v", err)
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+
+	// Check the options for every type of runner
+	for name, options := range cfg.MCP.Run.RunnerOptions {
+		if err := command.ValidateRunnerOptions(command.RunnerType(name), options); err != nil {
+			s.logger.Error("Invalid runner options: %v", err)
+			return fmt.Errorf("invalid runner options: %w", err)
+		}
+	}
+
+	// Check the enabled conditions are valid
+	for _, toolConfig := range cfg.MCP.Tools {
+		if _, err := toolConfig.IsEnabled(); err != nil {
+			s.logger.Error("Invalid enabled condition for tool '%s': %v", toolConfig.Name, err)
+			return fmt.Errorf("invalid enabled condition for tool '%s': %w", toolConfig.Name, err)
+		}
+	}
+
+	// The tools running in the workers need the pool of workers
+	for _, toolConfig := range cfg.MCP.Tools {
+		if toolConfig.RunsOn != "" && cfg.MCP.Workers.TokenEnv == "" {
+			s.logger.Error("Tool '%s' runs on workers, but the workers are not configured", toolConfig.Name)
+			return fmt.Errorf("tool '%s' runs on workers, but the workers are not configured", toolConfig.Name)
+		}
+	}
+
 	// Get filtered tool definitions based on prerequisites
 	toolDefs := cfg.GetTools()
 
 	// Check if some tools were filtered out due to prerequisites not met
 	if len(toolDefs) < len(cfg.MCP.Tools) {
 		skippedCount := len(cfg.MCP.Tools) - len(toolDefs)
-		s.logger.Info("%d tool(s) would be skipped due to unmet prerequisites", skippedCount)
+		s.logger.Info("%d tool(s) would be skipped due to unmet prerequisites or being disabled", skippedCount)
 
 		// Log which tools were skipped
 		for _, toolConfig := range cfg.MCP.Tools {
@@ -121,7 +244,7 @@ func (s *Server) Validate() error {
 			}
 
 			if !found {
-				s.logger.Info("Tool '%s' would be skipped due to unmet prerequisites", toolConfig.Name)
+				s.logger.Info("Tool '%s' would be skipped due to unmet prerequisites or being disabled", toolConfig.Name)
 			}
 		}
 	}
@@ -132,14 +255,14 @@ func (s *Server) Validate() error {
 	for _, toolDef := range toolDefs {
 		s.logger.Debug("Validating tool '%s'", toolDef.MCPTool.Name)
 
-		// Find the original tool config
-		toolIndex := s.findToolByName(cfg.MCP.Tools, toolDef.MCPTool.Name)
-		if toolIndex == -1 {
-			return fmt.Errorf("internal error: tool '%s' not found in configuration after creation", toolDef.MCPTool.Name)
-		}
-
 		// Get parameter types for constraint validation
-		paramTypes := cfg.MCP.Tools[toolIndex].Params
+		paramTypes := toolDef.Config.Params
+
+		// Validate the parameters definitions
+		if err := common.ValidateParams(paramTypes); err != nil {
+			s.logger.Error("Invalid parameters for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid parameters for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
 
 		// Validate constraints by attempting to compile them
 		if len(toolDef.Config.Constraints) > 0 {
@@ -152,12 +275,58 @@ func (s *Server) Validate() error {
 			s.logger.Debug("All constraints for tool '%s' compiled successfully", toolDef.MCPTool.Name)
 		}
 
+		// Validate the computed values
+		if len(toolDef.Config.Computed) > 0 {
+			if _, err := common.NewCompiledComputed(toolDef.Config.Computed, paramTypes); err != nil {
+				s.logger.Error("Invalid computed values for tool '%s': %v", toolDef.MCPTool.Name, err)
+				return fmt.Errorf("invalid computed values for tool '%s': %w", toolDef.MCPTool.Name, err)
+			}
+		}
+
+		// Validate the output schema
+		if toolDef.Config.OutputSchema != nil {
+			if err := toolDef.Config.OutputSchema.Check(); err != nil {
+				s.logger.Error("Invalid output schema for tool '%s': %v", toolDef.MCPTool.Name, err)
+				return fmt.Errorf("invalid output schema for tool '%s': %w", toolDef.MCPTool.Name, err)
+			}
+		}
+
+		// Validate the output converter, screening and format
+		if err := common.CheckOutputConverter(toolDef.Config.Output.Convert); err != nil {
+			s.logger.Error("Invalid output converter for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid output for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
+		if err := common.CheckScreenMode(toolDef.Config.Output.Screen); err != nil {
+			s.logger.Error("Invalid output screening for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid output for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
+		if err := common.CheckOutputFormat(toolDef.Config.Output); err != nil {
+			s.logger.Error("Invalid output format for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid output for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
+
+		// Validate the log level of the tool
+		if err := common.CheckLogLevel(toolDef.Config.Logging.Level); err != nil {
+			s.logger.Error("Invalid logging for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid logging for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
+
 		// Validate command template
-		if toolDef.Config.Run.Command == "" {
+		if toolDef.Config.Run.Command == "" && len(toolDef.Config.Exec) == 0 {
 			s.logger.Error("Empty command template for tool '%s'", toolDef.MCPTool.Name)
 			return fmt.Errorf("empty command template for tool '%s'", toolDef.MCPTool.Name)
 		}
 
+		// Validate the runner is registered
+		if _, found := command.GetRunnerRegistration(command.RunnerType(toolDef.GetEffectiveRunner())); !found {
+			s.logger.Error("Unknown runner '%s' for tool '%s'", toolDef.GetEffectiveRunner(), toolDef.MCPTool.Name)
+			return fmt.Errorf("unknown runner '%s' for tool '%s'", toolDef.GetEffectiveRunner(), toolDef.MCPTool.Name)
+		}
+		if err := command.ValidateRunnerOptions(command.RunnerType(toolDef.GetEffectiveRunner()), toolDef.GetEffectiveOptions()); err != nil {
+			s.logger.Error("Invalid runner options for tool '%s': %v", toolDef.MCPTool.Name, err)
+			return fmt.Errorf("invalid runner options for tool '%s': %w", toolDef.MCPTool.Name, err)
+		}
+
 		// Format constraint information for display
 		var constraintInfo string
 		if len(toolDef.Config.Constraints) > 0 {
@@ -175,6 +344,7 @@ func (s *Server) Validate() error {
 
 // Start initializes the MCP server, loads tools from the configuration file,
 // and starts listening for client connections.
+// On SIGTERM (or an interrupt), the tool calls in progress are drained before stopping.
 //
 // Returns:
 //   - An error if server initialization or startup fails
@@ -186,10 +356,49 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return s.ServeStdio(ctx, os.Stdin, os.Stdout)
+}
+
+// ServeStdio serves the MCP protocol over some input and output (ie, the standard
+// input and output) until the input is closed or the context is done. In that case,
+// the tool calls in progress are drained before stopping. The server must have been
+// created with CreateServer, and it is closed when returning.
+//
+// Parameters:
+//   - ctx: The context for stopping the server
+//   - in: The input the requests are read from
+//   - out: The output the responses are written to
+//
+// Returns:
+//   - An error if the server fails
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	if s.mcpServer == nil {
+		return fmt.Errorf("server not initialized")
+	}
+	defer s.Close()
+
 	s.logger.Info("Starting MCP server with stdio handler")
 
-	// Start the stdio server
-	if err := mcpserver.ServeStdio(s.mcpServer); err != nil {
+	listenCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Drain the tool calls in progress before stopping the server
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Shutting down")
+			s.Shutdown(s.drainTimeout)
+			cancel()
+		case <-listenCtx.Done():
+		}
+	}()
+
+	stdioServer := mcpserver.NewStdioServer(s.mcpServer)
+	stdioServer.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+	if err := stdioServer.Listen(listenCtx, in, out); err != nil && !errors.Is(err, context.Canceled) {
 		s.logger.Error("Server error: %v", err)
 		return fmt.Errorf("server error: %v", err)
 	}
@@ -210,6 +419,12 @@ func (s *Server) CreateServer() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Do not start when the prerequisites with the "fail" policy are not met
+	if err := cfg.CheckPrerequisites(); err != nil {
+		s.logger.Error("Prerequisites not met: %v", err)
+		return fmt.Errorf("prerequisites not met: %w", err)
+	}
+
 	// Use shell from config if present and no shell is explicitly set
 	if s.shell == "" && cfg.MCP.Run.Shell != "" {
 		s.shell = cfg.MCP.Run.Shell
@@ -222,35 +437,219 @@ func (s *Server) CreateServer() error {
 		options = append(options, mcpserver.WithInstructions(s.description))
 	}
 
+	// Notify clients when the tools in the scripts directories change
+	if len(cfg.MCP.Scripts) > 0 {
+		options = append(options, mcpserver.WithToolCapabilities(true))
+	}
+
+	// Forget the state and the shells of the sessions when they end
+	hooks := &mcpserver.Hooks{}
+	hooks.AddOnRegisterSession(func(ctx context.Context, session mcpserver.ClientSession) {
+		s.sessions.start(session.SessionID(), time.Now())
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session mcpserver.ClientSession) {
+		command.ClearSessionState(session.SessionID())
+		command.CloseShellSessions(session.SessionID())
+		s.sessions.forget(session.SessionID())
+		if s.budget != nil {
+			s.budget.forget(session.SessionID())
+		}
+	})
+
+	// Add the tags of the tools to the lists of tools
+	hooks.AddAfterListTools(s.addToolTags)
+
+	// Record the requests and the responses of the sessions
+	if s.recordFile != "" {
+		s.recorder, err = NewSessionRecorder(s.recordFile)
+		if err != nil {
+			s.logger.Error("Failed to record the sessions: %v", err)
+			return err
+		}
+		s.recorder.hooks(hooks)
+		s.logger.Info("Recording the sessions in %s", s.recordFile)
+	}
+	options = append(options, mcpserver.WithHooks(hooks))
+
+	// Identify every tool call with an execution ID, for correlating its logs, its entry
+	// in the history, its metrics and its result
+	options = append(options, mcpserver.WithToolHandlerMiddleware(s.identifyToolCall))
+	if s.recorder != nil {
+		options = append(options, mcpserver.WithToolHandlerMiddleware(s.recorder.middleware))
+	}
+
+	// Keep the tool calls in progress, so they can be drained when shutting down
+	options = append(options, mcpserver.WithToolHandlerMiddleware(s.trackToolCall))
+
+	// Only show (and run) the tools allowed for every client
+	options = append(options, mcpserver.WithToolFilter(s.filterTools))
+
+	// Advertise the values allowed currently in the parameters with dynamic enums
+	options = append(options, mcpserver.WithToolFilter(s.refreshEnums))
+	options = append(options, mcpserver.WithToolHandlerMiddleware(s.authorizeToolCall))
+
+	// Make the context of the calls (client, session, time) available in the constraints
+	options = append(options, mcpserver.WithToolHandlerMiddleware(s.describeRequest))
+
+	// Inject random faults in the calls, for testing the handling of the errors of the agents
+	if cfg.MCP.Chaos.Enabled() {
+		s.chaos, err = newChaosInjector(cfg.MCP.Chaos)
+		if err != nil {
+			s.logger.Error("Invalid chaos configuration: %v", err)
+			return fmt.Errorf("invalid chaos configuration: %w", err)
+		}
+		s.logger.Info("Chaos testing mode enabled: injecting random faults in the tool calls (seed %d)", s.chaos.seed)
+		options = append(options, mcpserver.WithToolHandlerMiddleware(s.injectFaults))
+	}
+
+	// Return the original results of the calls repeated with the same idempotency key
+	idempotencyWindow := DefaultIdempotencyWindow
+	if cfg.MCP.Idempotency.Window != "" {
+		idempotencyWindow, err = time.ParseDuration(cfg.MCP.Idempotency.Window)
+		if err != nil || idempotencyWindow < 0 {
+			s.logger.Error("Invalid idempotency window: %s", cfg.MCP.Idempotency.Window)
+			return fmt.Errorf("invalid idempotency window: '%s'", cfg.MCP.Idempotency.Window)
+		}
+	}
+	if idempotencyWindow > 0 {
+		s.idempotency = newIdempotencyStore(idempotencyWindow)
+	}
+	options = append(options, mcpserver.WithToolHandlerMiddleware(s.idempotentToolCall))
+
+	// Keep the background jobs in the backend configured
+	var jobsBackend command.JobsBackend
+	if cfg.MCP.Jobs.Backend != "" {
+		jobsBackend, err = command.NewJobsBackend(cfg.MCP.Jobs.Backend, cfg.MCP.Jobs.Options)
+		if err != nil {
+			s.logger.Error("Invalid jobs backend: %v", err)
+			return fmt.Errorf("invalid jobs backend: %w", err)
+		}
+		command.SetJobsBackend(jobsBackend)
+	}
+
+	// Record the calls of the tools and the results of the schedules in the history
+	// (with the audit directory as the history when there is no other store)
+	switch {
+	case cfg.MCP.History.Backend != "":
+		s.history, err = NewHistoryStore(cfg.MCP.History.Backend, cfg.MCP.History.Options)
+	case cfg.MCP.Auth.AuditDir != "":
+		s.history, err = newDirHistoryStore(cfg.MCP.Auth.AuditDir)
+	}
+	if err != nil {
+		s.logger.Error("Invalid history store: %v", err)
+		return fmt.Errorf("invalid history store: %w", err)
+	}
+
+	// Keep the counters of the quotas of the tools across restarts (in a file in the
+	// MCPShell home when there is no other store)
+	s.quotas, err = NewQuotaStore(cfg.MCP.Quotas.Backend, cfg.MCP.Quotas.Options)
+	if err != nil {
+		if cfg.MCP.Quotas.Backend != "" {
+			s.logger.Error("Invalid quota store: %v", err)
+			return fmt.Errorf("invalid quota store: %w", err)
+		}
+		s.logger.Debug("No store for the quotas of the tools: %v", err)
+	}
+
+	// Limit the cost of the calls of the tools in the sessions and in the days
+	if cfg.MCP.Budget.Enabled() {
+		s.budget, err = newBudgetTracker(cfg.MCP.Budget)
+		if err != nil {
+			s.logger.Error("Invalid budget: %v", err)
+			return fmt.Errorf("invalid budget: %w", err)
+		}
+	}
+
+	// Coordinate the remote workers running the tools with "runs_on" (in HTTP mode)
+	if cfg.MCP.Workers.TokenEnv != "" {
+		token := os.Getenv(cfg.MCP.Workers.TokenEnv)
+		if token == "" {
+			s.logger.Error("The token of the workers is not set in %s", cfg.MCP.Workers.TokenEnv)
+			return fmt.Errorf("the token of the workers is not set in %s", cfg.MCP.Workers.TokenEnv)
+		}
+		s.workers = workers.Handler(token)
+	}
+
+	// Apply the other middlewares once the calls are authorized
+	for _, middleware := range s.middlewares {
+		options = append(options, mcpserver.WithToolHandlerMiddleware(mcpserver.ToolHandlerMiddleware(middleware)))
+	}
+
 	// Initialize the MCP server BEFORE loading tools
 	s.mcpServer = mcpserver.NewMCPServer(serverName, s.version, options...)
 
 	// Now load tools after the server is initialized
+	if err := checkToolPatterns(s.excludeTools); err != nil {
+		s.logger.Error("Invalid excluded tools: %v", err)
+		return fmt.Errorf("invalid excluded tools: %w", err)
+	}
 	if err := s.loadTools(cfg); err != nil {
 		s.logger.Error("Failed to load tools: %v", err)
 		return err
 	}
 
+	// Run the queued jobs (by this or other instances) once the tools are loaded
+	if _, queued := jobsBackend.(command.JobsQueue); queued {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopJobs = cancel
+		go command.RunJobsConsumers(ctx, cfg.MCP.Jobs.Concurrency, s.logger)
+	}
+
+	// Prepare the authentication of the clients (once the secrets are available)
+	s.auth, err = newAuthorizer(context.Background(), cfg.MCP.Auth, s.secrets)
+	if err != nil {
+		s.logger.Error("Invalid auth configuration: %v", err)
+		return fmt.Errorf("invalid auth configuration: %w", err)
+	}
+	if s.rest && s.auth == nil && !s.restInsecure {
+		s.logger.Error("The REST endpoints require the authentication of the clients")
+		return fmt.Errorf("the REST endpoints require the authentication of the clients (in the auth section)")
+	}
+
+	// Re-scan the scripts directories periodically
+	if len(cfg.MCP.Scripts) > 0 {
+		s.watchScripts(cfg)
+	}
+
+	// Run the scheduled tools in the background
+	if len(cfg.MCP.Schedules) > 0 {
+		schedules, err := s.newSchedules(cfg, s.selectTools(cfg.GetTools()))
+		if err != nil {
+			s.logger.Error("Failed to create schedules: %v", err)
+			return fmt.Errorf("failed to create schedules: %w", err)
+		}
+		s.startSchedules(schedules)
+	}
+
+	s.ready.Store(true)
 	return nil
 }
 
 // loadTools loads tools from the configuration and registers them with the server
 func (s *Server) loadTools(cfg *config.ToolsConfig) error {
 	// Check if there are any tools defined
-	if len(cfg.MCP.Tools) == 0 {
+	if len(cfg.MCP.Tools) == 0 && len(cfg.MCP.Scripts) == 0 {
 		s.logger.Error("No tools defined in the configuration file")
 		return fmt.Errorf("no tools defined in the configuration file")
 	}
 
 	s.logger.Info("Found %d tools in configuration", len(cfg.MCP.Tools))
 
+	// Create the secrets (that will be obtained when first used)
+	secrets, err := common.NewSecrets(cfg.Secrets)
+	if err != nil {
+		s.logger.Error("Invalid secrets configuration: %v", err)
+		return fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+	s.secrets = secrets
+
 	// Create and register tools
 	toolDefs := cfg.GetTools()
 
 	// Check if some tools were filtered out due to prerequisites not met
 	if len(toolDefs) < len(cfg.MCP.Tools) {
 		skippedCount := len(cfg.MCP.Tools) - len(toolDefs)
-		s.logger.Info("Skipped %d tool(s) due to unmet prerequisites", skippedCount)
+		s.logger.Info("Skipped %d tool(s) due to unmet prerequisites or being disabled", skippedCount)
 
 		// Log which tools were skipped
 		for _, toolConfig := range cfg.MCP.Tools {
@@ -263,58 +662,318 @@ func (s *Server) loadTools(cfg *config.ToolsConfig) error {
 			}
 
 			if !found {
-				s.logger.Info("Tool '%s' was skipped due to unmet prerequisites", toolConfig.Name)
+				s.logger.Info("Tool '%s' was skipped due to unmet prerequisites or being disabled", toolConfig.Name)
 			}
 		}
 	}
 
-	s.logger.Info("Registering %d tools after checking prerequisites", len(toolDefs))
+	// Skip the tools not selected for serving
+	toolDefs = s.selectTools(toolDefs)
 
-	for _, toolDef := range toolDefs {
-		s.logger.Debug("Registering tool '%s'", toolDef.MCPTool.Name)
+	// Pull the images of the tools running in containers before serving them
+	s.prePullImages(toolDefs)
 
-		// Get the parameter types for this tool
-		params := cfg.MCP.Tools[s.findToolByName(cfg.MCP.Tools, toolDef.MCPTool.Name)].Params
+	s.logger.Info("Registering %d tools after checking prerequisites", len(toolDefs))
 
-		// Create a new command handler instance
-		cmdHandler, err := command.NewCommandHandler(toolDef, params, s.shell, s.logger)
+	var registered []string
+	paginated := false
+	async := false
+	for _, toolDef := range toolDefs {
+		names, err := s.registerTool(toolDef, nil)
 		if err != nil {
-			s.logger.Error("Failed to create handler for tool '%s': %v", toolDef.MCPTool.Name, err)
-			return fmt.Errorf("failed to create handler for tool '%s': %w", toolDef.MCPTool.Name, err)
+			return err
 		}
+		if !isStaticTool(cfg, toolDef.MCPTool.Name) {
+			s.scriptTools = append(s.scriptTools, names...)
+		}
+		registered = append(registered, names...)
+		paginated = paginated || toolDef.Config.Output.MaxSize > 0
+		async = async || toolDef.Config.Async
+	}
 
-		// Get the MCP handler and wrap it with panic recovery
-		safeHandler := s.wrapHandlerWithPanicRecovery(cmdHandler.GetMCPHandler())
+	// Publish the system prompt and the usage guidance of the tools (if any)
+	s.systemPrompt = cfg.GetSystemPrompt(toolDefs)
+	if s.systemPrompt != "" {
+		s.logger.Info("Registering prompt '%s'", GuidancePromptName)
+		s.mcpServer.AddPrompt(mcp.NewPrompt(GuidancePromptName,
+			mcp.WithPromptDescription("Instructions and guidance for using the tools of this server"),
+		), s.guidancePromptHandler)
+	}
 
-		// Add the tool to the server
-		s.mcpServer.AddTool(toolDef.MCPTool, safeHandler)
+	// Register the tools for the session state (when enabled)
+	if cfg.MCP.State {
+		handlers := map[string]mcpserver.ToolHandlerFunc{
+			command.StateSetToolName: command.StateSetHandler,
+			command.StateGetToolName: command.StateGetHandler,
+		}
+		for _, tool := range command.GetStateTools() {
+			if slices.Contains(registered, tool.Name) {
+				s.logger.Error("Built-in tool '%s' not registered: there is another tool with the same name", tool.Name)
+				continue
+			}
+			s.logger.Info("Registering built-in tool '%s'", tool.Name)
+			s.mcpServer.AddTool(tool, s.wrapHandlerWithPanicRecovery(handlers[tool.Name]))
+		}
+	}
 
-		// Print whether constraints are enabled
-		if len(toolDef.Config.Constraints) > 0 {
-			msg := fmt.Sprintf("Registered tool: '%s' (with %d constraints)", toolDef.MCPTool.Name, len(toolDef.Config.Constraints))
-			s.logger.Info(msg)
+	// Register the tools for managing background jobs (when needed)
+	if async {
+		handlers := map[string]mcpserver.ToolHandlerFunc{
+			command.JobStatusToolName: command.JobStatusHandler,
+			command.JobLogsToolName:   command.JobLogsHandler,
+			command.JobKillToolName:   command.JobKillHandler,
+		}
+		for _, tool := range command.GetJobTools() {
+			if slices.Contains(registered, tool.Name) {
+				s.logger.Error("Built-in tool '%s' not registered: there is another tool with the same name", tool.Name)
+				continue
+			}
+			s.logger.Info("Registering built-in tool '%s'", tool.Name)
+			s.mcpServer.AddTool(tool, s.wrapHandlerWithPanicRecovery(handlers[tool.Name]))
+		}
+	}
+
+	// Register the tool for reading the pages of big outputs (when needed)
+	if paginated {
+		if slices.Contains(registered, command.ReadMoreToolName) {
+			s.logger.Error("Built-in tool '%s' not registered: there is another tool with the same name", command.ReadMoreToolName)
 		} else {
-			msg := fmt.Sprintf("Registered tool: '%s'", toolDef.MCPTool.Name)
-			s.logger.Info(msg)
+			s.logger.Info("Registering built-in tool '%s'", command.ReadMoreToolName)
+			s.mcpServer.AddTool(command.GetReadMoreTool(), s.wrapHandlerWithPanicRecovery(command.ReadMoreHandler))
+		}
+	}
+
+	return nil
+}
+
+// guidancePromptHandler returns the system prompt and the usage guidance of the tools
+func (s *Server) guidancePromptHandler(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return mcp.NewGetPromptResult("Instructions and guidance for using the tools",
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(s.systemPrompt))},
+	), nil
+}
+
+// GetSystemPrompt returns the system prompt defined in the configuration, followed
+// by the usage guidance of the tools. It is empty when there is none.
+func (s *Server) GetSystemPrompt() string {
+	return s.systemPrompt
+}
+
+// RegisterTool registers a tool defined in code (ie, by a program embedding MCPShell), in
+// addition to the tools in the configuration file. The tool is checked like the tools in
+// the configuration files, and the server must have been created with CreateServer.
+//
+// Parameters:
+//   - toolConfig: The tool configuration
+//
+// Returns:
+//   - An error if the tool is invalid, is not available or its name is already in use
+func (s *Server) RegisterTool(toolConfig config.MCPToolConfig) error {
+	return s.registerCodeTool(toolConfig, nil)
+}
+
+// RegisterFunc registers a tool implemented by a Go function instead of a command, in
+// addition to the tools in the configuration file. The parameters, the constraints and
+// the output of the tool are handled like in the tools running commands, and the server
+// must have been created with CreateServer.
+//
+// Parameters:
+//   - toolConfig: The tool configuration, without a command
+//   - fn: The function implementing the tool
+//
+// Returns:
+//   - An error if the tool is invalid, is not available or its name is already in use
+func (s *Server) RegisterFunc(toolConfig config.MCPToolConfig, fn command.ToolFunc) error {
+	if fn == nil {
+		return fmt.Errorf("no function for tool '%s'", toolConfig.Name)
+	}
+	return s.registerCodeTool(toolConfig, fn)
+}
+
+// registerCodeTool registers a tool defined in code, implemented by a command
+// or by a function (when not nil)
+func (s *Server) registerCodeTool(toolConfig config.MCPToolConfig, fn command.ToolFunc) error {
+	if s.mcpServer == nil {
+		return fmt.Errorf("server not initialized")
+	}
+
+	toolDef, err := config.NewTool(toolConfig)
+	if err != nil {
+		return fmt.Errorf("invalid tool: %w", err)
+	}
+
+	for _, name := range toolDef.Config.Names() {
+		if s.isToolRegistered(name) {
+			return fmt.Errorf("there is another tool with the name '%s'", name)
 		}
 	}
 
+	if _, err := s.registerTool(toolDef, fn); err != nil {
+		return err
+	}
+
+	// Register the built-in tools needed by the tool (unless their names are in use)
+	if toolDef.Config.Async {
+		handlers := map[string]mcpserver.ToolHandlerFunc{
+			command.JobStatusToolName: command.JobStatusHandler,
+			command.JobLogsToolName:   command.JobLogsHandler,
+			command.JobKillToolName:   command.JobKillHandler,
+		}
+		for _, tool := range command.GetJobTools() {
+			if !s.isToolRegistered(tool.Name) {
+				s.mcpServer.AddTool(tool, s.wrapHandlerWithPanicRecovery(handlers[tool.Name]))
+			}
+		}
+	}
+	if toolDef.Config.Output.MaxSize > 0 && !s.isToolRegistered(command.ReadMoreToolName) {
+		s.mcpServer.AddTool(command.GetReadMoreTool(), s.wrapHandlerWithPanicRecovery(command.ReadMoreHandler))
+	}
+
 	return nil
 }
 
-// wrapHandlerWithPanicRecovery adds panic recovery to a tool handler
+// isToolRegistered returns true when a tool (not a built-in tool) has been registered with a name
+func (s *Server) isToolRegistered(name string) bool {
+	s.toolTagsMu.RLock()
+	defer s.toolTagsMu.RUnlock()
+	_, found := s.toolTags[name]
+	return found
+}
+
+// registerTool creates the handler for a tool and registers it (and its aliases)
+// with the server, returning all the names registered. The tool is implemented
+// by a function when fn is not nil.
+func (s *Server) registerTool(toolDef config.Tool, fn command.ToolFunc) ([]string, error) {
+	s.logger.Debug("Registering tool '%s'", toolDef.MCPTool.Name)
+
+	// Create a new command handler instance
+	toolDef.Secrets = s.secrets
+	var cmdHandler *command.CommandHandler
+	var err error
+	if fn != nil {
+		cmdHandler, err = command.NewFuncHandler(toolDef, fn, s.logger)
+	} else {
+		cmdHandler, err = command.NewCommandHandler(toolDef, toolDef.Config.Params, s.shell, s.logger)
+	}
+	if err != nil {
+		s.logger.Error("Failed to create handler for tool '%s': %v", toolDef.MCPTool.Name, err)
+		return nil, fmt.Errorf("failed to create handler for tool '%s': %w", toolDef.MCPTool.Name, err)
+	}
+	if s.runner != nil && fn == nil {
+		cmdHandler.SetRunner(s.runner)
+	}
+
+	// Get the MCP handler and wrap it with panic recovery
+	safeHandler := s.wrapHandlerWithPanicRecovery(cmdHandler.GetMCPHandler())
+
+	// Reject the calls over the quota of the tool (shared by its aliases)
+	if toolDef.Config.Quota.Enabled() {
+		if s.quotas == nil {
+			s.logger.Error("Tool '%s' has a quota, but there is no store for the quotas", toolDef.MCPTool.Name)
+			return nil, fmt.Errorf("no store for the quota of tool '%s'", toolDef.MCPTool.Name)
+		}
+		safeHandler = s.enforceQuota(toolDef.MCPTool.Name, toolDef.Config.Quota, safeHandler)
+	}
+
+	// Reject the calls that would exceed the budgets (before counting them in the quotas)
+	if toolDef.Config.Cost > 0 && s.budget != nil {
+		safeHandler = s.enforceBudget(toolDef.MCPTool.Name, toolDef.Config.Cost, safeHandler)
+	}
+
+	// Do not advertise the tools failing their smoke tests (ie, in broken environments)
+	if s.selfTest && toolDef.Config.SmokeTest != nil {
+		if err := s.runSmokeTest(toolDef, safeHandler); err != nil {
+			s.logger.Error("Tool '%s' not registered: its smoke test failed: %v", toolDef.MCPTool.Name, err)
+			return nil, nil
+		}
+		s.logger.Info("Smoke test of tool '%s' passed", toolDef.MCPTool.Name)
+	}
+
+	// Add the tool to the server
+	s.mcpServer.AddTool(toolDef.MCPTool, safeHandler)
+	names := []string{toolDef.MCPTool.Name}
+
+	// ... as well as its aliases, sharing the same handler
+	for _, aliasTool := range toolDef.GetAliasTools() {
+		s.logger.Debug("Registering alias '%s' for tool '%s'", aliasTool.Name, toolDef.MCPTool.Name)
+		s.mcpServer.AddTool(aliasTool, safeHandler)
+		names = append(names, aliasTool.Name)
+	}
+
+	// Remember the tags, for checking the tools the clients can use, and the parameters
+	// with dynamic enums, for updating their values when listing the tools
+	enums := map[string]common.ParamConfig{}
+	for paramName, param := range toolDef.Config.Params {
+		if param.HasEnum() && !param.Hidden {
+			enums[paramName] = param
+		}
+	}
+	s.toolTagsMu.Lock()
+	if s.toolTags == nil {
+		s.toolTags = map[string][]string{}
+		s.toolEnums = map[string]map[string]common.ParamConfig{}
+	}
+	for _, name := range names {
+		s.toolTags[name] = toolDef.Config.Tags
+		if len(enums) > 0 {
+			s.toolEnums[name] = enums
+		} else {
+			delete(s.toolEnums, name)
+		}
+	}
+	s.toolTagsMu.Unlock()
+
+	// Print whether constraints are enabled
+	if len(toolDef.Config.Constraints) > 0 {
+		msg := fmt.Sprintf("Registered tool: '%s' (with %d constraints)", toolDef.MCPTool.Name, len(toolDef.Config.Constraints))
+		s.logger.Info(msg)
+	} else {
+		msg := fmt.Sprintf("Registered tool: '%s'", toolDef.MCPTool.Name)
+		s.logger.Info(msg)
+	}
+
+	return names, nil
+}
+
+// identifyToolCall is a middleware adding an execution ID to the context of the tool calls
+// (unless they already have one)
+func (s *Server) identifyToolCall(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, _ = common.EnsureExecutionID(ctx)
+		return next(ctx, request)
+	}
+}
+
+// callLogger returns the logger for a tool call, with the ID of its execution (if any)
+func (s *Server) callLogger(ctx context.Context) *common.Logger {
+	if id := common.ExecutionIDFromContext(ctx); id != "" {
+		return s.logger.WithExecutionID(id)
+	}
+	return s.logger
+}
+
+// wrapHandlerWithPanicRecovery adds panic recovery to a tool handler, so a panic
+// (ie, in a template, a runner or the processing of the output) only fails that call,
+// returning an internal error with the correlation ID for finding the details in the logs
 func (s *Server) wrapHandlerWithPanicRecovery(handler mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
-		// Set up panic recovery
+		// Return an error result instead of crashing
 		defer func() {
-			if r := recover(); r != nil {
-				// Use the common panic recovery logic but don't exit
-				common.RecoverPanic()
-
-				// Return an error instead of crashing
-				err = fmt.Errorf("tool execution failed: internal server error")
+			var internalErr *common.InternalError
+			if errors.As(err, &internalErr) {
+				s.callLogger(ctx).Error("Tool '%s' failed with an internal error [%s]", request.Params.Name, internalErr.CorrelationID)
+				result = mcp.NewToolResultError(fmt.Sprintf("tool execution failed: %v", internalErr))
+				result.Meta = map[string]interface{}{
+					"error":          "internal_error",
+					"correlation_id": internalErr.CorrelationID,
+				}
+				if id := common.ExecutionIDFromContext(ctx); id != "" {
+					result.Meta["execution_id"] = id
+				}
+				err = nil
 			}
 		}()
+		defer common.CatchPanic(&err)
 
 		// Call the original handler
 		return handler(ctx, request)
@@ -357,6 +1016,10 @@ func (s *Server) GetTools() ([]mcp.Tool, error) {
 	tools := make([]mcp.Tool, 0, len(toolDefs))
 
 	for _, toolDef := range toolDefs {
+		// skip the tools not registered (ie, because their smoke tests failed)
+		if !s.isToolRegistered(toolDef.MCPTool.Name) {
+			continue
+		}
 		tools = append(tools, toolDef.MCPTool)
 	}
 
@@ -427,6 +1090,34 @@ func (s *Server) GetOpenAITools() ([]openai.Tool, error) {
 	return openaiTools, nil
 }
 
+// CallTool calls a tool with some arguments, returning its whole result (unlike ExecuteTool,
+// the results of the tools that fail are not errors: they have IsError set). The errors are
+// the calls rejected by the protocol (ie, for tools not found).
+func (s *Server) CallTool(ctx context.Context, toolName string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	if s.mcpServer == nil {
+		return nil, fmt.Errorf("server not initialized")
+	}
+
+	// The in-process calls share a local session (ie, for the state and the shells)
+	ctx = command.WithLocalSession(ctx)
+
+	request := mustMarshalJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": toolName, "arguments": args},
+	})
+	switch resp := s.mcpServer.HandleMessage(ctx, request).(type) {
+	case mcp.JSONRPCError:
+		return nil, fmt.Errorf("error executing tool '%s': %s", toolName, resp.Error.Message)
+	case mcp.JSONRPCResponse:
+		if result, ok := resp.Result.(mcp.CallToolResult); ok {
+			return &result, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected response calling tool '%s'", toolName)
+}
+
 // ExecuteTool executes a specific tool with the given parameters
 // Used by the agent to execute tools requested by the LLM
 func (s *Server) ExecuteTool(ctx context.Context, toolName string, args map[string]interface{}) (string, error) {
@@ -457,7 +1148,8 @@ func (s *Server) ExecuteTool(ctx context.Context, toolName string, args map[stri
 	s.logger.Info("Executing tool: %s", toolName)
 
 	// We need to handle the request manually since we don't have direct access to tool handlers
-	jsonMsg := s.mcpServer.HandleMessage(ctx, mustMarshalJSON(jsonRpcRequest))
+	// (the in-process calls share a local session, ie, for the state and the shells)
+	jsonMsg := s.mcpServer.HandleMessage(command.WithLocalSession(ctx), mustMarshalJSON(jsonRpcRequest))
 
 	// Convert the response to JSON bytes - handle different possible types
 	var responseBytes []byte
@@ -596,16 +1288,77 @@ func mustMarshalJSON(v interface{}) json.RawMessage {
 	return data
 }
 
-// StartHTTP initializes the MCP server and starts an HTTP server for MCP protocol over HTTP/SSE
+// StartHTTP starts an HTTP server for MCP protocol over HTTP/SSE and initializes the MCP server.
+// On SIGTERM (or an interrupt), the tool calls in progress are drained before closing the server.
 func (s *Server) StartHTTP(port int) error {
 	s.logger.Info("Initializing MCP HTTP server on port %d", port)
-	if err := s.CreateServer(); err != nil {
-		return err
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return s.ServeHTTP(ctx, fmt.Sprintf(":%d", port))
+}
+
+// ServeHTTP serves the MCP protocol over HTTP/SSE in an address (ie, ":8080") until the
+// context is done. In that case, the tool calls in progress are drained before closing
+// the server. The HTTP server is started first, so the health (/healthz) and readiness
+// (/readyz) endpoints can be probed while the server is created (when it has not been
+// created with CreateServer yet). The server is closed when returning.
+//
+// Parameters:
+//   - ctx: The context for stopping the server
+//   - addr: The address to listen on
+//
+// Returns:
+//   - An error if the server fails
+func (s *Server) ServeHTTP(ctx context.Context, addr string) error {
+	httpServer := &http.Server{Addr: addr, Handler: s.HTTPHandler()}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	if s.mcpServer == nil {
+		if err := s.CreateServer(); err != nil {
+			_ = httpServer.Close()
+			return err
+		}
 	}
-	http.HandleFunc("/sse", s.handleMCPHTTP)
-	addr := fmt.Sprintf(":%d", port)
+	defer s.Close()
+
 	s.logger.Info("MCP HTTP server listening on http://localhost%s/sse", addr)
-	return http.ListenAndServe(addr, nil)
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		s.logger.Info("Shutting down")
+	}
+
+	s.Shutdown(s.drainTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+// HTTPHandler returns the handler of the MCP protocol over HTTP (in /sse), with the
+// health (/healthz) and readiness (/readyz) endpoints, for serving them in another
+// HTTP server. The requests are refused until the server is created with CreateServer.
+// The REST endpoints of the tools (in /tools) are included when enabled.
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", s.handleMCPHTTP)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	if s.rest {
+		mux.HandleFunc(RESTPathPrefix, s.handleREST)
+		mux.HandleFunc(RESTPathPrefix+"/", s.handleREST)
+	}
+	if s.workers != nil {
+		mux.Handle(workers.PathPrefix, s.workers)
+	}
+	return mux
 }
 
 // handleMCPHTTP handles HTTP POST requests for MCP protocol
@@ -615,6 +1368,16 @@ func (s *Server) handleMCPHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !s.ready.Load() {
+		http.Error(w, "Server not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Authenticate the client (when configured)
+	ctx, ok := s.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read body", http.StatusBadRequest)
@@ -658,7 +1421,7 @@ func (s *Server) handleMCPHTTP(w http.ResponseWriter, r *http.Request) {
 				},
 				"capabilities": map[string]interface{}{
 					"tools": map[string]interface{}{
-						"allowedTools": s.getAllowedToolNames(),
+						"allowedTools": s.getAllowedToolNames(ctx),
 					},
 				},
 				"sessionId":       "local",
@@ -682,7 +1445,7 @@ func (s *Server) handleMCPHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Fallback to normal MCP handling
 	s.logger.Info("Received MCP request from %s: method=%v id=%v", r.RemoteAddr, req["method"], req["id"])
-	ctx := r.Context()
+	ctx = withIdempotencyKey(ctx, httpIdempotencyKey(r, req))
 	resp := s.mcpServer.HandleMessage(ctx, body)
 	var respBytes []byte
 	switch v := resp.(type) {
@@ -707,15 +1470,72 @@ func (s *Server) handleMCPHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Helper to get tool names
-func (s *Server) getAllowedToolNames() []string {
+// authenticateRequest authenticates the client of an HTTP request (when configured), returning
+// a context with its identity. The request is replied, returning false, when the client
+// cannot be authenticated.
+func (s *Server) authenticateRequest(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx := r.Context()
+	if s.auth == nil {
+		return ctx, true
+	}
+
+	identity, ok := s.auth.authenticate(r)
+	if !ok {
+		s.logger.Info("Unauthenticated request from %s", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	s.logger.Debug("Request from client '%s' (groups: %v)", identity.Name, identity.Groups)
+	return WithClientIdentity(ctx, identity), true
+}
+
+// Helper to get the names of the tools the client can use
+func (s *Server) getAllowedToolNames(ctx context.Context) []string {
 	tools, err := s.GetTools()
 	if err != nil {
 		return []string{}
 	}
 	names := make([]string, 0, len(tools))
-	for _, t := range tools {
+	for _, t := range s.filterTools(ctx, tools) {
 		names = append(names, t.Name)
 	}
 	return names
 }
+
+// Close releases the resources used by the server, like the scripts watcher
+// or the processes of the shell sessions
+func (s *Server) Close() {
+	s.ready.Store(false)
+	if s.stopScripts != nil {
+		close(s.stopScripts)
+		s.stopScripts = nil
+	}
+	if s.stopSchedules != nil {
+		close(s.stopSchedules)
+		s.stopSchedules = nil
+	}
+	if s.stopJobs != nil {
+		s.stopJobs()
+		s.stopJobs = nil
+	}
+	if s.history != nil {
+		if err := s.history.Close(); err != nil {
+			s.logger.Error("Failed to close the history: %v", err)
+		}
+		s.history = nil
+	}
+	if s.quotas != nil {
+		if err := s.quotas.Close(); err != nil {
+			s.logger.Error("Failed to close the quotas: %v", err)
+		}
+		s.quotas = nil
+	}
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			s.logger.Error("Failed to close the recording: %v", err)
+		}
+		s.recorder = nil
+	}
+	command.CloseAllShellSessions()
+}
@@ -1,10 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/command"
 	"github.com/inercia/MCPShell/pkg/common"
 	"github.com/inercia/MCPShell/pkg/config"
 )
@@ -135,3 +145,520 @@ func TestServer_loadTools(t *testing.T) {
 	// Skip actually loading the tools to avoid running commands
 	t.Skip("loadTools() is tested in integration tests")
 }
+
+func TestServer_Aliases(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "new_name"
+      aliases: ["old_name"]
+      description: "Test tool"
+      run:
+        command: "echo 'renamed'"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	for _, name := range []string{"new_name", "old_name"} {
+		output, err := srv.ExecuteTool(context.Background(), name, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Failed to execute tool '%s': %v", name, err)
+		}
+		if output != "renamed" {
+			t.Errorf("Tool '%s': expected output 'renamed', got %q", name, output)
+		}
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "numbers"
+      description: "Test tool"
+      output:
+        max_size: 20
+      run:
+        command: "seq 1 30"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	cursorRegex := regexp.MustCompile(`\[Output truncated: .* with cursor "([^"]+)" for reading more\]$`)
+
+	var lines []string
+	output, err := srv.ExecuteTool(context.Background(), "numbers", map[string]interface{}{})
+	for pages := 1; ; pages++ {
+		if err != nil {
+			t.Fatalf("Failed to execute tool: %v", err)
+		}
+		if pages > 20 {
+			t.Fatalf("Too many pages")
+		}
+
+		page, note, _ := strings.Cut(output, "\n\n[")
+		if len(page) > 20 {
+			t.Errorf("Page %d is bigger than the maximum size: %q", pages, page)
+		}
+		lines = append(lines, strings.Fields(page)...)
+
+		matches := cursorRegex.FindStringSubmatch("[" + note)
+		if matches == nil {
+			if !strings.HasPrefix(note, "End of output") {
+				t.Fatalf("Unexpected note at the end of page %d: %q", pages, note)
+			}
+			break
+		}
+		output, err = srv.ExecuteTool(context.Background(), command.ReadMoreToolName, map[string]interface{}{"cursor": matches[1]})
+	}
+
+	if len(lines) != 30 || lines[0] != "1" || lines[29] != "30" {
+		t.Errorf("Unexpected output read in pages: %v", lines)
+	}
+
+	// invalid cursors are reported to clients
+	output, err = srv.ExecuteTool(context.Background(), command.ReadMoreToolName, map[string]interface{}{"cursor": "unknown:0"})
+	if err == nil && !strings.Contains(output, "unknown or expired cursor") {
+		t.Errorf("Expected an error for an unknown cursor, got %q", output)
+	}
+}
+
+func TestServer_State(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  state: true
+  tools:
+    - name: "ping"
+      description: "Test tool"
+      run:
+        command: "echo 'ping {{ .State.target }}'"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if _, err := srv.ExecuteTool(context.Background(), command.StateSetToolName, map[string]interface{}{"key": "target", "value": "db1"}); err != nil {
+		t.Fatalf("Failed to set the state: %v", err)
+	}
+
+	output, err := srv.ExecuteTool(context.Background(), "ping", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if output != "ping db1" {
+		t.Errorf("Expected 'ping db1', got %q", output)
+	}
+}
+
+func TestServer_Schedules(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "greet"
+      description: "Test tool"
+      params:
+        who:
+          type: string
+          required: true
+      run:
+        command: "echo 'hello {{ .who }}'"
+  schedules:
+    - name: "greetings"
+      tool: "greet"
+      cron: "@every 1s"
+      params:
+        who: "world"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.Validate(); err != nil {
+		t.Fatalf("Failed to validate the configuration: %v", err)
+	}
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	if len(srv.schedules) != 1 {
+		t.Fatalf("Expected 1 schedule, got %d", len(srv.schedules))
+	}
+	sch := srv.schedules[0]
+	if sch.uri() != "schedule://greetings" {
+		t.Errorf("Unexpected URI %q", sch.uri())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(sch.String(), "hello world") {
+		if time.Now().After(deadline) {
+			t.Fatalf("The schedule did not run: %s", sch.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if strings.Contains(sch.String(), "failed") {
+		t.Errorf("Expected the run to succeed: %s", sch.String())
+	}
+}
+
+func TestCheckSchedules(t *testing.T) {
+	tools := []config.MCPToolConfig{{Name: "greet"}}
+	tests := []struct {
+		name      string
+		schedules []config.MCPScheduleConfig
+		wantErr   bool
+	}{
+		{"valid", []config.MCPScheduleConfig{{Name: "s1", Tool: "greet", Cron: "*/5 * * * *"}}, false},
+		{"invalid name", []config.MCPScheduleConfig{{Name: "s 1", Tool: "greet", Cron: "@hourly"}}, true},
+		{"duplicate", []config.MCPScheduleConfig{
+			{Name: "s1", Tool: "greet", Cron: "@hourly"},
+			{Name: "s1", Tool: "greet", Cron: "@daily"},
+		}, true},
+		{"invalid cron", []config.MCPScheduleConfig{{Name: "s1", Tool: "greet", Cron: "61 * * * *"}}, true},
+		{"unknown tool", []config.MCPScheduleConfig{{Name: "s1", Tool: "other", Cron: "@hourly"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ToolsConfig{}
+			cfg.MCP.Tools = tools
+			cfg.MCP.Schedules = tt.schedules
+			_, err := checkSchedules(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSchedules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_GuidancePrompt(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `prompts:
+  system:
+    - "You are a careful operator."
+mcp:
+  tools:
+    - name: "cleanup"
+      description: "Remove temporary files"
+      guidance: "Ask the user before running it."
+      run:
+        command: "echo 'cleaned'"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	prompt := srv.GetSystemPrompt()
+	if !strings.HasPrefix(prompt, "You are a careful operator.") || !strings.Contains(prompt, "- cleanup: Ask the user before running it.") {
+		t.Errorf("Unexpected system prompt: %q", prompt)
+	}
+
+	result, err := srv.guidancePromptHandler(context.Background(), mcp.GetPromptRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get the prompt: %v", err)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].Content.(mcp.TextContent).Text != prompt {
+		t.Errorf("Unexpected prompt result: %+v", result)
+	}
+}
+
+func TestServer_PanicRecovery(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	srv := New(Config{Logger: logger})
+
+	calls := 0
+	handler := srv.wrapHandlerWithPanicRecovery(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		if calls == 1 {
+			panic("something went wrong")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "broken"
+
+	// a panic returns an internal error, with a correlation ID
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected an error result, got error %v", err)
+	}
+	if result == nil || !result.IsError {
+		t.Fatalf("Expected an error result, got %+v", result)
+	}
+	id, _ := result.Meta["correlation_id"].(string)
+	if id == "" || result.Meta["error"] != "internal_error" {
+		t.Errorf("Expected the correlation ID in the metadata, got %v", result.Meta)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, id) || strings.Contains(text, "something went wrong") {
+		t.Errorf("Expected the correlation ID (and not the panic) in the error, got %q", text)
+	}
+
+	// ... and the next calls work
+	result, err = handler(context.Background(), request)
+	if err != nil || result.IsError {
+		t.Errorf("Expected the next call to succeed, got %+v, %v", result, err)
+	}
+}
+
+func TestServer_RegisterTool(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+
+	tool := config.MCPToolConfig{
+		Name:        "greet",
+		Description: "Greet someone",
+		Params: map[string]common.ParamConfig{
+			"name": {Type: "string", Description: "The name", Required: true},
+		},
+		Constraints: []string{"name.size() <= 10"},
+		Run:         config.MCPToolRunConfig{Command: "echo hello {{ .name }}"},
+	}
+	if err := srv.RegisterTool(tool); err == nil {
+		t.Errorf("Expected an error before creating the server")
+	}
+
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := srv.RegisterTool(tool); err != nil {
+		t.Fatalf("Failed to register tool: %v", err)
+	}
+
+	output, err := srv.ExecuteTool(context.Background(), "greet", map[string]interface{}{"name": "world"})
+	if err != nil || output != "hello world" {
+		t.Errorf("Expected 'hello world', got %q (%v)", output, err)
+	}
+	output, _ = srv.ExecuteTool(context.Background(), "greet", map[string]interface{}{"name": "a very long name"})
+	if !strings.Contains(output, "constraint") {
+		t.Errorf("Expected the constraints of the tool to be checked, got %q", output)
+	}
+
+	// the names in use are refused, as well as the invalid tools
+	if err := srv.RegisterTool(config.MCPToolConfig{Name: "hello", Run: config.MCPToolRunConfig{Command: "true"}}); err == nil {
+		t.Errorf("Expected an error for a name in use")
+	}
+	if err := srv.RegisterTool(config.MCPToolConfig{Name: "invalid", Type: "unknown"}); err == nil {
+		t.Errorf("Expected an error for an invalid tool")
+	}
+}
+
+func TestServer_ServeStdio(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "hello"
+      description: "Say hello"
+      run:
+        command: "echo hello"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.ServeStdio(context.Background(), strings.NewReader(""), io.Discard); err == nil {
+		t.Errorf("Expected an error before creating the server")
+	}
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	inReader, inWriter := io.Pipe()
+	var out safeBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ServeStdio(ctx, inReader, &out)
+	}()
+
+	_, _ = inWriter.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "hello", "arguments": {}}}` + "\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "hello") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(out.String(), `"text":"hello`) {
+		t.Errorf("Expected the output of the tool, got %q", out.String())
+	}
+
+	// the server stops when the context is done
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The server did not stop")
+	}
+	_ = inWriter.Close()
+}
+
+// safeBuffer is a buffer that can be written and read concurrently
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServer_ValidateRunnerOptions(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		run     string
+		wantErr string
+	}{
+		{"valid options", `
+    runner_options:
+      exec:
+        max_processes: 10`, ""},
+		{"unknown option", `
+    runner_options:
+      exec:
+        max_process: 10`, `unknown field "max_process"`},
+		{"unknown runner", `
+    runner_options:
+      dokcer:
+        image: alpine`, "unknown runner 'dokcer'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+			configContent := `mcp:
+  run:` + tt.run + `
+  tools:
+    - name: "greet"
+      description: "Test tool"
+      run:
+        command: "echo hello"
+`
+			if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("Failed to write test config file: %v", err)
+			}
+
+			err := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger}).Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected an error with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// sessionTracker keeps the start time and the number of tool calls of the MCP sessions,
+// for the context of the calls available in the constraints
+type sessionTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionStats
+}
+
+// sessionStats are the statistics of a session
+type sessionStats struct {
+	started time.Time
+	calls   int
+}
+
+// start registers a new session
+func (t *sessionTracker) start(id string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessions == nil {
+		t.sessions = map[string]*sessionStats{}
+	}
+	if _, found := t.sessions[id]; !found {
+		t.sessions[id] = &sessionStats{started: now}
+	}
+}
+
+// call counts a new tool call in a session, returning the time the session started
+// and the number of calls in the session (including this one). The sessions not
+// registered start with their first call.
+func (t *sessionTracker) call(id string, now time.Time) (time.Time, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessions == nil {
+		t.sessions = map[string]*sessionStats{}
+	}
+	stats, found := t.sessions[id]
+	if !found {
+		stats = &sessionStats{started: now}
+		t.sessions[id] = stats
+	}
+	stats.calls++
+	return stats.started, stats.calls
+}
+
+// forget removes a session that has ended
+func (t *sessionTracker) forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, id)
+}
+
+// describeRequest is a middleware adding the context of the tool call (the client,
+// the session and the time) to the context, for the constraints of the tools
+func (s *Server) describeRequest(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		info := &common.RequestInfo{Time: time.Now()}
+		if identity := ClientIdentityFromContext(ctx); identity != nil {
+			info.Client, info.Groups = identity.Name, identity.Groups
+		}
+		if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+			info.Session = session.SessionID()
+			info.SessionStart, info.SessionCalls = s.sessions.call(info.Session, info.Time)
+		}
+		return next(common.WithRequestInfo(ctx, info), request)
+	}
+}
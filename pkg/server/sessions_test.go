@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// testSession is a MCP client session for the tests
+type testSession struct {
+	id string
+}
+
+func (s testSession) Initialize()                                         {}
+func (s testSession) Initialized() bool                                   { return true }
+func (s testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s testSession) SessionID() string                                   { return s.id }
+
+func TestServer_RequestConstraints(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "limited"
+      description: "A tool with a limit of calls per session"
+      constraints:
+        - "request.session_calls <= 2"
+      run:
+        command: "echo ok"
+    - name: "admin_only"
+      description: "A tool only for the admins"
+      constraints:
+        - "request.client == 'admin' || 'admins' in request.groups"
+      run:
+        command: "echo done"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	call := func(ctx context.Context, tool string) (string, bool) {
+		t.Helper()
+		result, err := srv.CallTool(ctx, tool, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("CallTool(%s) error = %v", tool, err)
+		}
+		return resultText(result), result.IsError
+	}
+
+	// the calls are counted by session
+	ctxA := srv.mcpServer.WithContext(context.Background(), testSession{id: "session-a"})
+	ctxB := srv.mcpServer.WithContext(context.Background(), testSession{id: "session-b"})
+	for i := 1; i <= 2; i++ {
+		if output, failed := call(ctxA, "limited"); failed || !strings.Contains(output, "ok") {
+			t.Fatalf("Call %d in session A failed: %s", i, output)
+		}
+	}
+	if output, failed := call(ctxA, "limited"); !failed || !strings.Contains(output, "constraint") {
+		t.Errorf("Expected the third call in session A to be rejected, got %q", output)
+	}
+	if output, failed := call(ctxB, "limited"); failed {
+		t.Errorf("Call in session B failed: %s", output)
+	}
+
+	// the identity of the client
+	if _, failed := call(context.Background(), "admin_only"); !failed {
+		t.Errorf("Expected the call without client to be rejected")
+	}
+	ctxAdmin := WithClientIdentity(context.Background(), &ClientIdentity{Name: "alice", Groups: []string{"admins"}})
+	if output, failed := call(ctxAdmin, "admin_only"); failed || !strings.Contains(output, "done") {
+		t.Errorf("Call from an admin failed: %s", output)
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// terminationTimeout is the time the cancelled tool calls have for finishing
+// (ie, for running the termination sequences of their commands) when shutting down
+const terminationTimeout = 30 * time.Second
+
+// callTracker keeps the tool calls in progress, so they can be drained
+// (and cancelled) when the server is shut down
+type callTracker struct {
+	mu       sync.Mutex
+	draining bool
+	calls    map[uint64]context.CancelFunc // the cancel functions of the calls in progress
+	next     uint64
+	idle     chan struct{} // closed when draining and there are no calls in progress
+}
+
+// begin registers a new call, returning its (cancellable) context and the function
+// to call when it finishes. It returns false when the server is being shut down.
+func (t *callTracker) begin(ctx context.Context) (context.Context, func(), bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return ctx, nil, false
+	}
+	if t.calls == nil {
+		t.calls = map[uint64]context.CancelFunc{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := t.next
+	t.next++
+	t.calls[id] = cancel
+
+	return ctx, func() {
+		cancel()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.calls, id)
+		if t.draining && len(t.calls) == 0 {
+			t.closeIdle()
+		}
+	}, true
+}
+
+// drain stops accepting new calls, returning the number of calls in progress and a
+// channel that is closed once all of them have finished
+func (t *callTracker) drain() (int, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if len(t.calls) == 0 {
+			t.closeIdle()
+		}
+	}
+	return len(t.calls), t.idle
+}
+
+// closeIdle closes the idle channel (once). It must be called with the lock held.
+func (t *callTracker) closeIdle() {
+	select {
+	case <-t.idle:
+	default:
+		close(t.idle)
+	}
+}
+
+// cancelAll cancels all the calls in progress, returning how many there were
+func (t *callTracker) cancelAll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cancel := range t.calls {
+		cancel()
+	}
+	return len(t.calls)
+}
+
+// trackToolCall is a middleware keeping the tool calls in progress,
+// and rejecting the new calls when the server is being shut down
+func (s *Server) trackToolCall(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, done, ok := s.calls.begin(ctx)
+		if !ok {
+			s.callLogger(ctx).Info("Rejecting call to tool '%s': the server is shutting down", request.Params.Name)
+			return nil, fmt.Errorf("the server is shutting down: try again later")
+		}
+		defer done()
+
+		return next(ctx, request)
+	}
+}
+
+// Shutdown stops accepting new tool calls and waits up to a drain timeout for the calls
+// in progress. The calls still running after the timeout are cancelled, so their commands
+// are terminated (with the termination sequences of the tools) instead of being killed.
+//
+// Parameters:
+//   - drainTimeout: The maximum time to wait for the calls in progress
+func (s *Server) Shutdown(drainTimeout time.Duration) {
+	s.ready.Store(false)
+
+	running, idle := s.calls.drain()
+	if running > 0 {
+		s.logger.Info("Waiting up to %s for %d tool call(s) in progress", drainTimeout, running)
+	}
+
+	select {
+	case <-idle:
+		s.logger.Info("All the tool calls have finished")
+		return
+	case <-time.After(drainTimeout):
+	}
+
+	cancelled := s.calls.cancelAll()
+	s.logger.Info("Drain timeout expired: terminating %d tool call(s) in progress", cancelled)
+
+	select {
+	case <-idle:
+		s.logger.Info("All the tool calls have been terminated")
+	case <-time.After(terminationTimeout):
+		s.logger.Error("Some tool calls did not finish after being terminated")
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "short"
+      description: "A short operation"
+      run:
+        command: "sleep 0.5; echo finished"
+    - name: "long"
+      description: "A long operation"
+      run:
+        command: "sleep 30; echo finished"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	newServer := func() *Server {
+		srv := New(Config{
+			ConfigFile: testConfigFile,
+			Shell:      "sh",
+			Logger:     logger,
+		})
+		if err := srv.CreateServer(); err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		return srv
+	}
+
+	call := func(srv *Server, tool string) string {
+		req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(
+			`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "`+tool+`", "arguments": {}}}`))
+		rec := httptest.NewRecorder()
+		srv.handleMCPHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	// starts a call in background, waiting until it is in progress
+	start := func(srv *Server, tool string) <-chan string {
+		res := make(chan string, 1)
+		go func() { res <- call(srv, tool) }()
+		for i := 0; i < 100; i++ {
+			srv.calls.mu.Lock()
+			n := len(srv.calls.calls)
+			srv.calls.mu.Unlock()
+			if n > 0 {
+				return res
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("The call to '%s' did not start", tool)
+		return nil
+	}
+
+	t.Run("drains the calls in progress", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+
+		res := start(srv, "short")
+		srv.Shutdown(10 * time.Second)
+
+		if out := <-res; !strings.Contains(out, "finished") {
+			t.Errorf("Expected the call to finish, got %s", out)
+		}
+		if out := call(srv, "short"); strings.Contains(out, "finished") {
+			t.Errorf("Expected new calls to be rejected, got %s", out)
+		}
+	})
+
+	t.Run("terminates the calls after the drain timeout", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+
+		res := start(srv, "long")
+		started := time.Now()
+		srv.Shutdown(100 * time.Millisecond)
+
+		if elapsed := time.Since(started); elapsed > 10*time.Second {
+			t.Errorf("Expected the call to be terminated, but shutting down took %s", elapsed)
+		}
+		if out := <-res; strings.Contains(out, "finished") {
+			t.Errorf("Expected the call to be terminated, got %s", out)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// smokeTestSession is the MCP session of the smoke tests
+type smokeTestSession struct{}
+
+func (smokeTestSession) Initialize()                                         {}
+func (smokeTestSession) Initialized() bool                                   { return true }
+func (smokeTestSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (smokeTestSession) SessionID() string                                   { return "mcpshell-smoke-test" }
+
+// runSmokeTest calls a tool with the parameters of its smoke test, failing when the call
+// fails or the output does not match the output expected. The call is made with the internal
+// identity, in a session of its own, so it is not counted in the limits of the clients.
+func (s *Server) runSmokeTest(toolDef config.Tool, handler mcpserver.ToolHandlerFunc) error {
+	test := toolDef.Config.SmokeTest
+	session := smokeTestSession{}
+	defer func() {
+		command.ClearSessionState(session.SessionID())
+		command.CloseShellSessions(session.SessionID())
+		s.sessions.forget(session.SessionID())
+	}()
+
+	ctx := s.mcpServer.WithContext(WithClientIdentity(context.Background(), internalIdentity), session)
+	ctx, executionID := common.EnsureExecutionID(ctx)
+	s.logger.WithExecutionID(executionID).Info("Running the smoke test of tool '%s'", toolDef.MCPTool.Name)
+
+	args := make(map[string]interface{}, len(test.Params))
+	for k, v := range test.Params {
+		args[k] = v
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = toolDef.MCPTool.Name
+	request.Params.Arguments = args
+
+	result, err := handler(ctx, request)
+	if err != nil {
+		return err
+	}
+	var output string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			output += text.Text
+		}
+	}
+	if result.IsError {
+		return fmt.Errorf("%s", strings.TrimSpace(output))
+	}
+	if test.Expect != "" {
+		expect, err := regexp.Compile(test.Expect)
+		if err != nil {
+			return fmt.Errorf("invalid expected output: %w", err)
+		}
+		if !expect.MatchString(output) {
+			return fmt.Errorf("the output does not match '%s'", test.Expect)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_SelfTest(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "working"
+      description: "A tool that works"
+      smoke_test: true
+      run:
+        command: "echo ok"
+    - name: "greet"
+      description: "Greet someone"
+      params:
+        name:
+          type: string
+          required: true
+      smoke_test:
+        params:
+          name: "smoke"
+        expect: "^hello smoke"
+      run:
+        command: "echo hello {{ .name }}"
+    - name: "broken"
+      description: "A tool in a broken environment"
+      smoke_test: true
+      run:
+        command: "missing-binary-for-the-smoke-test"
+    - name: "unexpected"
+      description: "A tool with an unexpected output"
+      smoke_test:
+        expect: "ready"
+      run:
+        command: "echo not yet"
+    - name: "untested"
+      description: "A tool without smoke test"
+      run:
+        command: "exit 1"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		selfTest bool
+		expected []string
+	}{
+		{"without self-test", false, []string{"broken", "greet", "unexpected", "untested", "working"}},
+		{"with self-test", true, []string{"greet", "untested", "working"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, SelfTest: tt.selfTest})
+			if err := srv.CreateServer(); err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer srv.Close()
+
+			tools, err := srv.GetTools()
+			if err != nil {
+				t.Fatalf("Failed to get the tools: %v", err)
+			}
+			var names []string
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("Expected the tools %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestServer_SelfTestLimits(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	dir := t.TempDir()
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  budget:
+    daily: 4
+  quotas:
+    backend: file
+    options:
+      path: "` + filepath.ToSlash(filepath.Join(dir, "quotas.json")) + `"
+  tools:
+    - name: "geocode"
+      description: "A tool using a billable API"
+      smoke_test: true
+      quota:
+        daily: 1
+      cost: 4
+      run:
+        command: "echo found"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, SelfTest: true})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	// the smoke test does not count in the quota nor in the budget...
+	if result, err := srv.CallTool(context.Background(), "geocode", map[string]interface{}{}); err != nil || result.IsError {
+		t.Fatalf("Expected the first call to succeed: %v, %v", result, err)
+	}
+
+	// ... but the calls of the clients do
+	result, err := srv.CallTool(context.Background(), "geocode", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !result.IsError {
+		t.Errorf("Expected the second call to be rejected, got %+v", result)
+	}
+}
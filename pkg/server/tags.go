@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// toolTagsMeta is the field of the "_meta" of the lists of tools with the tags of the tools
+const toolTagsMeta = "tags"
+
+// getToolTags returns the tags of a tool registered from the configuration
+func (s *Server) getToolTags(name string) []string {
+	s.toolTagsMu.RLock()
+	defer s.toolTagsMu.RUnlock()
+	return s.toolTags[name]
+}
+
+// hasAnyTag returns true if the tags of a tool include any of the tags given
+func hasAnyTag(toolTags []string, tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(toolTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// addToolTags adds the tags of the tools listed to the metadata of the list (as a map
+// from the names of the tools to their tags), so clients can organize them by domain
+func (s *Server) addToolTags(ctx context.Context, id any, request *mcp.ListToolsRequest, result *mcp.ListToolsResult) {
+	if result == nil {
+		return
+	}
+
+	tags := map[string][]string{}
+	for _, tool := range result.Tools {
+		if toolTags := s.getToolTags(tool.Name); len(toolTags) > 0 {
+			tags[tool.Name] = slices.Clone(toolTags)
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+
+	if result.Meta == nil {
+		result.Meta = map[string]interface{}{}
+	}
+	result.Meta[toolTagsMeta] = tags
+}
+
+// checkToolPatterns checks the glob patterns for the names of the tools are valid
+func checkToolPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// selectTools returns the tools served, skipping the ones without any of the tags selected
+// (if any) and the ones excluded by name (or by any of their aliases)
+func (s *Server) selectTools(toolDefs []config.Tool) []config.Tool {
+	if len(s.onlyTags) == 0 && len(s.excludeTools) == 0 {
+		return toolDefs
+	}
+
+	res := make([]config.Tool, 0, len(toolDefs))
+	for _, toolDef := range toolDefs {
+		if len(s.onlyTags) > 0 && !hasAnyTag(toolDef.Config.Tags, s.onlyTags) {
+			s.logger.Info("Tool '%s' not served: it has none of the tags %s", toolDef.Config.Name, strings.Join(s.onlyTags, ", "))
+			continue
+		}
+		if pattern, excluded := s.isToolExcluded(toolDef.Config); excluded {
+			s.logger.Info("Tool '%s' not served: excluded by '%s'", toolDef.Config.Name, pattern)
+			continue
+		}
+		res = append(res, toolDef)
+	}
+	return res
+}
+
+// isToolExcluded returns the pattern excluding a tool (by its name or any of its aliases), if any
+func (s *Server) isToolExcluded(toolConfig config.MCPToolConfig) (string, bool) {
+	for _, pattern := range s.excludeTools {
+		for _, name := range toolConfig.Names() {
+			if matched, _ := path.Match(pattern, name); matched {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
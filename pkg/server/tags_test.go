@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestServer_ToolTags(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "get_pods"
+      description: "List the pods"
+      tags: ["k8s", "read-only"]
+      aliases: ["pods"]
+      run:
+        command: "echo pods"
+    - name: "git_log"
+      description: "Show the commits"
+      tags: ["git"]
+      run:
+        command: "echo commits"
+    - name: "untagged"
+      description: "A tool without tags"
+      run:
+        command: "echo untagged"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, REST: true, RESTInsecure: true})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	// the tags are in the metadata of the list of tools
+	response := srv.mcpServer.HandleMessage(context.Background(), mustMarshalJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/list",
+	}))
+	var decoded struct {
+		Result struct {
+			Meta struct {
+				Tags map[string][]string `json:"tags"`
+			} `json:"_meta"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(mustMarshalJSON(response), &decoded); err != nil {
+		t.Fatalf("Failed to decode the list of tools: %v", err)
+	}
+	expected := map[string][]string{
+		"get_pods": {"k8s", "read-only"},
+		"pods":     {"k8s", "read-only"},
+		"git_log":  {"git"},
+	}
+	if !reflect.DeepEqual(decoded.Result.Meta.Tags, expected) {
+		t.Errorf("Unexpected tags in the metadata: %v", decoded.Result.Meta.Tags)
+	}
+
+	// ... and the REST API can filter the tools by tags
+	rec := httptest.NewRecorder()
+	srv.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tools?tags=git,read-only", nil))
+	var list struct {
+		Tools []restTool `json:"tools"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected list of tools (%d): %s", rec.Code, rec.Body.String())
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"get_pods", "git_log", "pods"}) {
+		t.Errorf("Unexpected tools with the tags: %v", names)
+	}
+	if !reflect.DeepEqual(list.Tools[1].Tags, []string{"git"}) {
+		t.Errorf("Unexpected tags in the REST API: %v", list.Tools[1].Tags)
+	}
+}
+
+func TestServer_SelectTools(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "get_pods"
+      description: "List the pods"
+      tags: ["k8s", "read-only"]
+      aliases: ["pods"]
+      run:
+        command: "echo pods"
+    - name: "get_nodes"
+      description: "List the nodes"
+      tags: ["k8s", "read-only"]
+      run:
+        command: "echo nodes"
+    - name: "git_log"
+      description: "Show the commits"
+      tags: ["git", "read-only"]
+      run:
+        command: "echo commits"
+    - name: "reboot_host"
+      description: "Reboot the host"
+      run:
+        command: "echo rebooting"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		onlyTags     []string
+		excludeTools []string
+		expected     []string
+	}{
+		{"all the tools", nil, nil, []string{"get_nodes", "get_pods", "git_log", "pods", "reboot_host"}},
+		{"only tags", []string{"read-only"}, nil, []string{"get_nodes", "get_pods", "git_log", "pods"}},
+		{"excluded by name", nil, []string{"reboot_host"}, []string{"get_nodes", "get_pods", "git_log", "pods"}},
+		{"excluded by alias", nil, []string{"pods"}, []string{"get_nodes", "git_log", "reboot_host"}},
+		{"tags and patterns", []string{"k8s"}, []string{"get_*"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(Config{
+				ConfigFile:   testConfigFile,
+				Shell:        "sh",
+				Logger:       logger,
+				OnlyTags:     tt.onlyTags,
+				ExcludeTools: tt.excludeTools,
+			})
+			if err := srv.CreateServer(); err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer srv.Close()
+
+			response := srv.mcpServer.HandleMessage(context.Background(), mustMarshalJSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"method":  "tools/list",
+			}))
+			var decoded struct {
+				Result struct {
+					Tools []struct {
+						Name string `json:"name"`
+					} `json:"tools"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(mustMarshalJSON(response), &decoded); err != nil {
+				t.Fatalf("Failed to decode the list of tools: %v", err)
+			}
+			var names []string
+			for _, tool := range decoded.Result.Tools {
+				names = append(names, tool.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Unexpected tools served: %v, expected %v", names, tt.expected)
+			}
+
+			// the tools not served cannot be called
+			if !slices.Contains(tt.expected, "reboot_host") {
+				if result, err := srv.CallTool(context.Background(), "reboot_host", nil); err == nil && !result.IsError {
+					t.Errorf("Expected an error calling a tool not served")
+				}
+			}
+		})
+	}
+
+	// the patterns must be valid
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger, ExcludeTools: []string{"reboot_["}})
+	if err := srv.CreateServer(); err == nil {
+		srv.Close()
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
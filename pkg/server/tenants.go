@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the number of calls of a client in a period of time,
+// with a token bucket that is refilled continuously
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64   // the maximum number of calls in a period
+	rate     float64   // the calls added to the bucket per second
+	tokens   float64   // the calls available
+	last     time.Time // the last time the bucket was refilled
+}
+
+// parseRateLimit parses a rate limit like "100/h", "10/m" or "1/s"
+func parseRateLimit(limit string) (*rateLimiter, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(limit), "/")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if !found || err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid rate limit '%s': expected something like '100/h'", limit)
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s", "sec", "second":
+		period = time.Second
+	case "m", "min", "minute":
+		period = time.Minute
+	case "h", "hour":
+		period = time.Hour
+	case "d", "day":
+		period = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("invalid rate limit '%s': the unit must be s, m, h or d", limit)
+	}
+
+	return &rateLimiter{
+		capacity: float64(n),
+		rate:     float64(n) / period.Seconds(),
+		tokens:   float64(n),
+	}, nil
+}
+
+// allow returns true if a call can be made now, consuming it
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	for _, limit := range []string{"", "10", "0/m", "-1/m", "x/m", "10/week"} {
+		if _, err := parseRateLimit(limit); err == nil {
+			t.Errorf("Expected an error for rate limit %q", limit)
+		}
+	}
+
+	limiter, err := parseRateLimit("2/m")
+	if err != nil {
+		t.Fatalf("Failed to parse rate limit: %v", err)
+	}
+
+	now := time.Now()
+	if !limiter.allow(now) || !limiter.allow(now) {
+		t.Errorf("Expected the first calls to be allowed")
+	}
+	if limiter.allow(now.Add(time.Second)) {
+		t.Errorf("Expected the third call to be rejected")
+	}
+	if !limiter.allow(now.Add(31 * time.Second)) {
+		t.Errorf("Expected a call to be allowed once the bucket is refilled")
+	}
+}
+
+func TestServer_Tenants(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	t.Setenv("MCPSHELL_TEST_TEAM_A_TOKEN", "team-a-token")
+	t.Setenv("MCPSHELL_TEST_TEAM_B_TOKEN", "team-b-token")
+
+	dir := t.TempDir()
+	auditDir := filepath.Join(dir, "audit")
+	testConfigFile := filepath.Join(dir, "config.yaml")
+	configContent := `mcp:
+  auth:
+    audit_dir: "` + auditDir + `"
+    clients:
+      - name: "team-a"
+        token_env: "MCPSHELL_TEST_TEAM_A_TOKEN"
+        tools: ["*"]
+        env: ["TEAM=a"]
+      - name: "team-b"
+        token_env: "MCPSHELL_TEST_TEAM_B_TOKEN"
+        tags: ["read-only"]
+        rate_limit: "1/h"
+        env: ["TEAM=b"]
+  tools:
+    - name: "whoami"
+      description: "Show the team"
+      tags: ["read-only"]
+      run:
+        command: "echo team-$TEAM"
+    - name: "deploy"
+      description: "Deploy"
+      run:
+        command: "echo deployed"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	call := func(token string, tool string) string {
+		req := httptest.NewRequest(http.MethodPost, "/sse", strings.NewReader(
+			`{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "`+tool+`", "arguments": {}}}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.handleMCPHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	// every team gets its own environment
+	if out := call("team-a-token", "whoami"); !strings.Contains(out, "team-a") {
+		t.Errorf("Expected the environment of team A, got %s", out)
+	}
+	if out := call("team-a-token", "deploy"); !strings.Contains(out, "deployed") {
+		t.Errorf("Expected team A to deploy, got %s", out)
+	}
+	if out := call("team-b-token", "whoami"); !strings.Contains(out, "team-b") {
+		t.Errorf("Expected the environment of team B, got %s", out)
+	}
+
+	// ... its own tools and its own rate limits
+	if out := call("team-b-token", "deploy"); strings.Contains(out, "deployed") {
+		t.Errorf("Expected team B not to deploy, got %s", out)
+	}
+	if out := call("team-b-token", "whoami"); !strings.Contains(out, "rate limit exceeded") {
+		t.Errorf("Expected the rate limit of team B to be exceeded, got %s", out)
+	}
+
+	// ... and its own audit log
+	readAudit := func(client string) []HistoryEntry {
+		f, err := os.Open(filepath.Join(auditDir, client+".jsonl"))
+		if err != nil {
+			t.Fatalf("Failed to open the audit log: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+
+		var entries []HistoryEntry
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("Invalid audit entry: %v", err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	var statuses []string
+	for _, entry := range readAudit("team-a") {
+		statuses = append(statuses, entry.Tool+":"+entry.Status)
+		if _, ok := entry.Usage["output_bytes"]; !ok {
+			t.Errorf("Expected the resource usage in the audit entry, got %v", entry.Usage)
+		}
+	}
+	if strings.Join(statuses, ",") != "whoami:ok,deploy:ok" {
+		t.Errorf("Unexpected audit log for team A: %v", statuses)
+	}
+
+	statuses = nil
+	for _, entry := range readAudit("team-b") {
+		statuses = append(statuses, entry.Tool+":"+entry.Status)
+	}
+	if strings.Join(statuses, ",") != "whoami:ok,deploy:denied,whoami:rate_limited" {
+		t.Errorf("Unexpected audit log for team B: %v", statuses)
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/common"
+	"github.com/inercia/MCPShell/pkg/workers"
+)
+
+func TestServer_Workers(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	t.Setenv("MCPSHELL_TEST_WORKERS_TOKEN", "workers-token")
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  workers:
+    token_env: "MCPSHELL_TEST_WORKERS_TOKEN"
+  tools:
+    - name: "train"
+      description: "Train a model"
+      runs_on: "gpu"
+      requires:
+        - binary: "some-binary-only-in-the-workers"
+      params:
+        model:
+          type: string
+          description: "The model"
+          required: true
+      run:
+        command: "echo training {{ .model }}"
+    - name: "deploy"
+      description: "Deploy to production"
+      runs_on: "prod-bastion"
+      run:
+        command: "echo deployed"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{
+		ConfigFile: testConfigFile,
+		Shell:      "sh",
+		Logger:     logger,
+	})
+	if err := srv.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err := srv.CreateServer(); err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	httpServer := httptest.NewServer(srv.HTTPHandler())
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = workers.RunWorker(ctx, workers.WorkerOptions{
+			URL:    httpServer.URL,
+			Name:   "gpu-1",
+			Labels: []string{"gpu"},
+			Token:  "workers-token",
+			Logger: logger,
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// the tool runs once the worker has joined the pool
+	var output string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		output, err = srv.ExecuteTool(context.Background(), "train", map[string]interface{}{"model": "llama"})
+		if err != nil {
+			t.Fatalf("ExecuteTool() error = %v", err)
+		}
+		if !strings.Contains(output, "no workers available") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if output != "training llama" {
+		t.Errorf("ExecuteTool() output = %q, want %q", output, "training llama")
+	}
+
+	// there are no workers for the other tool
+	output, err = srv.ExecuteTool(context.Background(), "deploy", nil)
+	if err != nil {
+		t.Fatalf("ExecuteTool() error = %v", err)
+	}
+	if !strings.Contains(output, "no workers available with label 'prod-bastion'") {
+		t.Errorf("ExecuteTool() output = %q, want no workers available", output)
+	}
+}
+
+func TestServer_WorkersNotConfigured(t *testing.T) {
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	testConfigFile := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `mcp:
+  tools:
+    - name: "train"
+      description: "Train a model"
+      runs_on: "gpu"
+      run:
+        command: "echo training"
+`
+	if err := os.WriteFile(testConfigFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	srv := New(Config{ConfigFile: testConfigFile, Shell: "sh", Logger: logger})
+	if err := srv.Validate(); err == nil || !strings.Contains(err.Error(), "workers are not configured") {
+		t.Errorf("Validate() error = %v, want the workers are not configured", err)
+	}
+}
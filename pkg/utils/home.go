@@ -17,6 +17,10 @@ const (
 	MCPShellHome = ".mcpshell"
 	// MCPShellToolsDir is the name of the tools directory within MCPShell home
 	MCPShellToolsDir = "tools"
+	// MCPShellJobsDir is the name of the background jobs directory within MCPShell home
+	MCPShellJobsDir = "jobs"
+	// MCPShellConversationsDir is the name of the agent conversations directory within MCPShell home
+	MCPShellConversationsDir = "conversations"
 )
 
 // GetHome returns the user's home directory in a portable way
@@ -70,3 +74,25 @@ func GetMCPShellToolsDir() (string, error) {
 	toolsDir := filepath.Join(mcpShellHome, MCPShellToolsDir)
 	return toolsDir, nil
 }
+
+// GetMCPShellJobsDir returns the directory where the background jobs are kept
+// This is typically ~/.mcpshell/jobs on Unix-like systems or %USERPROFILE%\.mcpshell\jobs on Windows
+func GetMCPShellJobsDir() (string, error) {
+	mcpShellHome, err := GetMCPShellHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(mcpShellHome, MCPShellJobsDir), nil
+}
+
+// GetMCPShellConversationsDir returns the directory where the agent conversations are kept
+// This is typically ~/.mcpshell/conversations on Unix-like systems or %USERPROFILE%\.mcpshell\conversations on Windows
+func GetMCPShellConversationsDir() (string, error) {
+	mcpShellHome, err := GetMCPShellHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(mcpShellHome, MCPShellConversationsDir), nil
+}
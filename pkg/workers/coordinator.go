@@ -0,0 +1,249 @@
+package workers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// workerInfo is a worker known by the coordinator
+type workerInfo struct {
+	labels   []string
+	lastSeen time.Time
+}
+
+// poolJob is a job in the pool, waiting for a worker or running in one
+type poolJob struct {
+	Job
+	runsOn        string
+	worker        string // the worker running the job (empty while it is waiting for one)
+	lastHeartbeat time.Time
+	cancelled     bool
+	result        chan JobResult // receives the result of the job
+}
+
+// Pool is the coordinator of the workers: it keeps the workers seen and the jobs
+// waiting for a worker with some label, or running in one
+type Pool struct {
+	mu      sync.Mutex
+	workers map[string]*workerInfo
+	jobs    map[string]*poolJob // the jobs by ID
+	pending []*poolJob          // the jobs waiting for a worker, oldest first
+	changed chan struct{}       // closed (and replaced) when new jobs are pending
+}
+
+// NewPool creates an empty pool of workers
+func NewPool() *Pool {
+	return &Pool{
+		workers: map[string]*workerInfo{},
+		jobs:    map[string]*poolJob{},
+		changed: make(chan struct{}),
+	}
+}
+
+// available returns true when a worker with a label has been seen recently
+func (p *Pool) available(label string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.availableLocked(label)
+}
+
+func (p *Pool) availableLocked(label string) bool {
+	for _, worker := range p.workers {
+		if time.Since(worker.lastSeen) < WorkerTimeout && slices.Contains(worker.labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// submit adds a job for the workers with a label
+func (p *Pool) submit(job Job, runsOn string) (*poolJob, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to create job ID: %w", err)
+	}
+	job.ID = hex.EncodeToString(idBytes)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pj := &poolJob{Job: job, runsOn: runsOn, result: make(chan JobResult, 1)}
+	p.jobs[job.ID] = pj
+	p.pending = append(p.pending, pj)
+	close(p.changed)
+	p.changed = make(chan struct{})
+	return pj, nil
+}
+
+// cancel removes a job from the pool. The worker running it (if any) is told to
+// stop it on its next heartbeat.
+func (p *Pool) cancel(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pj, found := p.jobs[id]; found {
+		pj.cancelled = true
+		delete(p.jobs, id)
+		p.pending = slices.DeleteFunc(p.pending, func(other *poolJob) bool { return other == pj })
+	}
+}
+
+// check returns an error when a job cannot finish: when it is running in a worker that
+// stopped sending heartbeats, or it is waiting and there are no workers for it
+func (p *Pool) check(pj *poolJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case pj.worker != "" && time.Since(pj.lastHeartbeat) > WorkerTimeout:
+		return fmt.Errorf("worker '%s' stopped responding", pj.worker)
+	case pj.worker == "" && !p.availableLocked(pj.runsOn):
+		return fmt.Errorf("no workers available with label '%s'", pj.runsOn)
+	}
+	return nil
+}
+
+// poll registers a worker with some labels, and returns the oldest job for any of its
+// labels, waiting for one until the timeout or the context is done
+func (p *Pool) poll(ctx context.Context, worker string, labels []string, timeout time.Duration) *Job {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		p.workers[worker] = &workerInfo{labels: labels, lastSeen: time.Now()}
+		for i, pj := range p.pending {
+			if slices.Contains(labels, pj.runsOn) {
+				p.pending = slices.Delete(p.pending, i, i+1)
+				pj.worker, pj.lastHeartbeat = worker, time.Now()
+				p.mu.Unlock()
+				job := pj.Job
+				return &job
+			}
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// heartbeat records a worker is still running a job. It returns false when the
+// job has been cancelled (or it is unknown).
+func (p *Pool) heartbeat(worker string, id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if info, found := p.workers[worker]; found {
+		info.lastSeen = time.Now()
+	}
+	pj, found := p.jobs[id]
+	if !found || pj.cancelled || pj.worker != worker {
+		return false
+	}
+	pj.lastHeartbeat = time.Now()
+	return true
+}
+
+// finish records the result of a job. It returns false when the job is unknown
+// (ie, it has been cancelled).
+func (p *Pool) finish(worker string, id string, result JobResult) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pj, found := p.jobs[id]
+	if !found || pj.worker != worker {
+		return false
+	}
+	delete(p.jobs, id)
+	pj.result <- result
+	return true
+}
+
+// Handler returns the HTTP handler of the coordinator of the workers of this process,
+// to be mounted at PathPrefix. The workers must authenticate with the token given.
+//
+// The endpoints are:
+//   - POST /workers/poll: registers a worker and returns a job for it (or 204 No Content)
+//   - POST /workers/jobs/<id>/heartbeat: the worker is still running a job (or 410 Gone
+//     when the job has been cancelled)
+//   - POST /workers/jobs/<id>/result: the result of a job
+func Handler(token string) http.Handler {
+	return defaultPool.Handler(token)
+}
+
+// Handler returns the HTTP handler of the coordinator of the pool, to be mounted at
+// PathPrefix. The workers must authenticate with the token given.
+func (p *Pool) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		worker := r.Header.Get(WorkerHeader)
+		if worker == "" {
+			http.Error(w, "Missing worker name", http.StatusBadRequest)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, PathPrefix)
+		if path == "poll" {
+			var req pollRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			job := p.poll(r.Context(), worker, req.Labels, PollTimeout)
+			if job == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(job)
+			return
+		}
+
+		id, action, ok := strings.Cut(strings.TrimPrefix(path, "jobs/"), "/")
+		if !ok || !strings.HasPrefix(path, "jobs/") {
+			http.NotFound(w, r)
+			return
+		}
+		switch action {
+		case "heartbeat":
+			if !p.heartbeat(worker, id) {
+				http.Error(w, "Job cancelled", http.StatusGone)
+				return
+			}
+		case "result":
+			var result JobResult
+			if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if !p.finish(worker, id, result) {
+				http.Error(w, "Job cancelled", http.StatusGone)
+				return
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
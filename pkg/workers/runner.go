@@ -0,0 +1,99 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+)
+
+// runnerWorker runs the commands in the workers of a pool with some label
+type runnerWorker struct {
+	pool    *Pool
+	runsOn  string
+	options map[string]interface{} // the options for the exec runner of the worker
+	logger  *log.Logger
+
+	job atomic.Pointer[poolJob] // the job of the command, once submitted
+}
+
+// newRunnerWorker creates a runner for the workers with the label in the "runs_on" option
+func newRunnerWorker(pool *Pool, options command.RunnerOptions, logger *log.Logger) (*runnerWorker, error) {
+	if logger == nil {
+		logger = log.New(os.Stderr, "runner-worker: ", log.LstdFlags)
+	}
+
+	runsOn, _ := options[RunsOnOption].(string)
+	if runsOn == "" {
+		return nil, fmt.Errorf("the worker runner requires the '%s' option", RunsOnOption)
+	}
+
+	// the other options are used by the exec runner in the worker
+	execOptions := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		if k != RunsOnOption {
+			execOptions[k] = v
+		}
+	}
+
+	return &runnerWorker{pool: pool, runsOn: runsOn, options: execOptions, logger: logger}, nil
+}
+
+// CheckImplicitRequirements checks there are workers for running the commands
+func (r *runnerWorker) CheckImplicitRequirements() error {
+	if !r.pool.available(r.runsOn) {
+		return fmt.Errorf("no workers available with label '%s'", r.runsOn)
+	}
+	return nil
+}
+
+// Run sends a command to the workers with the label of the runner, and waits for its output
+func (r *runnerWorker) Run(ctx context.Context, shell string, cmd string, env []string,
+	params map[string]interface{}, tmpfile bool,
+) (string, error) {
+	job, err := r.pool.submit(Job{
+		Shell:   shell,
+		Command: cmd,
+		Env:     env,
+		TmpFile: tmpfile,
+		Options: r.options,
+	}, r.runsOn)
+	if err != nil {
+		return "", err
+	}
+	r.job.Store(job)
+	r.logger.Printf("Submitted job %s for the workers with label '%s'", job.ID, r.runsOn)
+
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case result := <-job.result:
+			if result.Error != "" {
+				return result.Output, errors.New(result.Error)
+			}
+			return result.Output, nil
+		case <-ticker.C:
+			if err := r.pool.check(job); err != nil {
+				r.pool.cancel(job.ID)
+				return "", err
+			}
+		case <-ctx.Done():
+			r.pool.cancel(job.ID)
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Kill cancels the job of the command, so the worker running it stops it
+func (r *runnerWorker) Kill() error {
+	if job := r.job.Load(); job != nil {
+		r.pool.cancel(job.ID)
+	}
+	return nil
+}
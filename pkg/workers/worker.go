@@ -0,0 +1,205 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// WorkerOptions are the options of a worker
+type WorkerOptions struct {
+	URL         string   // the URL of the MCP server coordinating the workers
+	Name        string   // the name of the worker (unique in the pool)
+	Labels      []string // the labels of the worker, matched with the "runs_on" of the tools
+	Token       string   // the token for authenticating with the coordinator
+	Concurrency int      // the number of jobs run at the same time (1 when not set)
+	Logger      *common.Logger
+}
+
+// worker is a worker running the jobs of a coordinator
+type worker struct {
+	WorkerOptions
+	client *http.Client
+}
+
+// RunWorker joins the pool of workers of an MCP server, and runs the jobs it gets
+// until the context is done
+//
+// Parameters:
+//   - ctx: The context, that stops the worker when it is done
+//   - options: The options of the worker
+//
+// Returns:
+//   - An error if the options are not valid
+func RunWorker(ctx context.Context, options WorkerOptions) error {
+	if options.URL == "" {
+		return fmt.Errorf("the URL of the server is required")
+	}
+	if options.Name == "" {
+		return fmt.Errorf("the name of the worker is required")
+	}
+	if len(options.Labels) == 0 {
+		return fmt.Errorf("the worker needs some labels")
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.Logger == nil {
+		options.Logger = common.GetLogger()
+	}
+	options.URL = strings.TrimSuffix(options.URL, "/")
+
+	w := &worker{WorkerOptions: options, client: &http.Client{}}
+	w.Logger.Info("Worker '%s' joining %s with labels %v", w.Name, w.URL, w.Labels)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// loop polls the coordinator for jobs and runs them, until the context is done
+func (w *worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.Logger.Error("Failed to poll %s: %v", w.URL, err)
+			select {
+			case <-time.After(HeartbeatInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		if job != nil {
+			w.run(ctx, job)
+		}
+	}
+}
+
+// poll asks the coordinator for a job, returning nil when there are none
+func (w *worker) poll(ctx context.Context) (*Job, error) {
+	resp, err := w.post(ctx, "poll", pollRequest{Labels: w.Labels})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusOK:
+		var job Job
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			return nil, fmt.Errorf("invalid job: %w", err)
+		}
+		return &job, nil
+	default:
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+// run runs a job with the exec runner, sending heartbeats while it runs, and sends its result
+func (w *worker) run(ctx context.Context, job *Job) {
+	w.Logger.Info("Running job %s: %s", job.ID, job.Command)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stop the command when the job is cancelled in the coordinator
+	var heartbeats sync.WaitGroup
+	defer heartbeats.Wait()
+	done := make(chan struct{})
+	defer close(done)
+	heartbeats.Add(1)
+	go func() {
+		defer heartbeats.Done()
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				resp, err := w.post(jobCtx, "jobs/"+job.ID+"/heartbeat", nil)
+				if err != nil {
+					w.Logger.Error("Failed to send heartbeat of job %s: %v", job.ID, err)
+					continue
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode == http.StatusGone {
+					w.Logger.Info("Job %s cancelled: stopping it", job.ID)
+					cancel()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var result JobResult
+	runner, err := command.NewRunner(command.RunnerTypeExec, command.RunnerOptions(job.Options), w.Logger.Logger)
+	if err == nil {
+		result.Output, err = runner.Run(jobCtx, job.Shell, job.Command, job.Env, nil, job.TmpFile)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if jobCtx.Err() != nil {
+		return
+	}
+
+	resp, err := w.post(ctx, "jobs/"+job.ID+"/result", result)
+	if err != nil {
+		w.Logger.Error("Failed to send the result of job %s: %v", job.ID, err)
+		return
+	}
+	_ = resp.Body.Close()
+	w.Logger.Info("Job %s finished", job.ID)
+}
+
+// post sends a request to an endpoint of the coordinator
+func (w *worker) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL+PathPrefix+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+w.Token)
+	req.Header.Set(WorkerHeader, w.Name)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unauthorized: check the token of the worker")
+	}
+	return resp, nil
+}
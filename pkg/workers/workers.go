@@ -0,0 +1,77 @@
+// Package workers runs the commands of the tools in a pool of remote workers.
+//
+// The MCP server acts as the coordinator of the pool: the workers (started with
+// "mcpshell worker --join <server>") register with it over HTTP, with some labels
+// (ie, "gpu" or "prod-bastion"), and they poll it for the commands of the tools
+// that must run on any of their labels (the tools with "runs_on"). The workers run
+// the commands with the exec runner, and they send back their outputs.
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/config"
+)
+
+// RunnerTypeWorker is the runner of the tools running in the workers (with "runs_on")
+const RunnerTypeWorker = command.RunnerType(config.WorkerRunner)
+
+// PathPrefix is the prefix of the HTTP endpoints of the coordinator of the workers
+const PathPrefix = "/workers/"
+
+// WorkerHeader is the HTTP header with the name of the worker in its requests
+const WorkerHeader = "X-MCPShell-Worker"
+
+// RunsOnOption is the option of the worker runner with the label of the workers
+// that can run the command
+const RunsOnOption = "runs_on"
+
+var (
+	// PollTimeout is the time a poll of a worker waits for a job before returning without one
+	PollTimeout = 20 * time.Second
+
+	// HeartbeatInterval is the time between the heartbeats sent by the workers while
+	// they run a job
+	HeartbeatInterval = 5 * time.Second
+
+	// WorkerTimeout is the time after which a worker that has not polled the coordinator
+	// (or sent a heartbeat) is considered gone, failing the jobs it was running
+	WorkerTimeout = time.Minute
+)
+
+// Job is a command sent to a worker
+type Job struct {
+	ID      string                 `json:"id"`
+	Shell   string                 `json:"shell,omitempty"`
+	Command string                 `json:"command"`
+	Env     []string               `json:"env,omitempty"`
+	TmpFile bool                   `json:"tmpfile,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"` // the options of the exec runner
+}
+
+// JobResult is the result of a job, sent by the worker that run it
+type JobResult struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// pollRequest is the request of a worker polling for a job
+type pollRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// defaultPool is the pool of workers of this process, used by the worker runner
+var defaultPool = NewPool()
+
+func init() {
+	err := command.RegisterRunner(RunnerTypeWorker, command.RunnerRegistration{
+		New: func(options command.RunnerOptions, logger *log.Logger) (command.Runner, error) {
+			return newRunnerWorker(defaultPool, options, logger)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
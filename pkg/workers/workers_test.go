@@ -0,0 +1,155 @@
+package workers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/MCPShell/pkg/command"
+	"github.com/inercia/MCPShell/pkg/common"
+)
+
+// startWorkers starts a coordinator with a pool and a worker with some labels,
+// returning the pool
+func startWorkers(t *testing.T, labels ...string) *Pool {
+	t.Helper()
+
+	oldPoll, oldHeartbeat := PollTimeout, HeartbeatInterval
+	PollTimeout, HeartbeatInterval = 500*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { PollTimeout, HeartbeatInterval = oldPoll, oldHeartbeat })
+
+	pool := NewPool()
+	mux := http.NewServeMux()
+	mux.Handle(PathPrefix, pool.Handler("secret"))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	logger, err := common.NewLogger("", "", common.LogLevelNone, false)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := RunWorker(ctx, WorkerOptions{
+			URL:    srv.URL,
+			Name:   "worker-1",
+			Labels: labels,
+			Token:  "secret",
+			Logger: logger,
+		})
+		if err != nil {
+			t.Errorf("RunWorker() error = %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// wait for the worker to join the pool
+	deadline := time.Now().Add(5 * time.Second)
+	for !pool.available(labels[0]) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the worker did not join the pool")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return pool
+}
+
+func TestRunnerWorker(t *testing.T) {
+	pool := startWorkers(t, "gpu", "linux")
+
+	runner, err := newRunnerWorker(pool, command.RunnerOptions{RunsOnOption: "gpu"}, nil)
+	if err != nil {
+		t.Fatalf("newRunnerWorker() error = %v", err)
+	}
+	if err := runner.CheckImplicitRequirements(); err != nil {
+		t.Fatalf("CheckImplicitRequirements() error = %v", err)
+	}
+
+	output, err := runner.Run(context.Background(), "sh", "echo hello from $NAME", []string{"NAME=worker"}, nil, true)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if strings.TrimSpace(output) != "hello from worker" {
+		t.Errorf("Run() output = %q, want %q", output, "hello from worker")
+	}
+
+	// the errors of the commands are returned
+	if _, err := runner.Run(context.Background(), "sh", "exit 3", nil, nil, true); err == nil {
+		t.Errorf("Run() of a failing command: expected an error")
+	}
+}
+
+func TestRunnerWorker_NoWorkers(t *testing.T) {
+	pool := startWorkers(t, "gpu")
+
+	runner, err := newRunnerWorker(pool, command.RunnerOptions{RunsOnOption: "prod-bastion"}, nil)
+	if err != nil {
+		t.Fatalf("newRunnerWorker() error = %v", err)
+	}
+	err = runner.CheckImplicitRequirements()
+	if err == nil || !strings.Contains(err.Error(), "no workers available with label 'prod-bastion'") {
+		t.Errorf("CheckImplicitRequirements() error = %v, want no workers available", err)
+	}
+
+	if _, err := newRunnerWorker(pool, command.RunnerOptions{}, nil); err == nil {
+		t.Errorf("newRunnerWorker() without runs_on: expected an error")
+	}
+}
+
+func TestRunnerWorker_Cancel(t *testing.T) {
+	pool := startWorkers(t, "gpu")
+
+	runner, err := newRunnerWorker(pool, command.RunnerOptions{RunsOnOption: "gpu"}, nil)
+	if err != nil {
+		t.Fatalf("newRunnerWorker() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := runner.Run(ctx, "sh", "sleep 10", nil, nil, true); err == nil {
+		t.Fatalf("Run() expected an error when cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s after being cancelled", elapsed)
+	}
+
+	// the worker stops the command, and it can run other jobs
+	output, err := runner.Run(context.Background(), "sh", "echo done", nil, nil, true)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if strings.TrimSpace(output) != "done" {
+		t.Errorf("Run() output = %q, want %q", output, "done")
+	}
+}
+
+func TestPoolHandler_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(NewPool().Handler("secret"))
+	defer srv.Close()
+
+	for _, token := range []string{"", "wrong"} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+PathPrefix+"poll", strings.NewReader(`{"labels":["gpu"]}`))
+		req.Header.Set(WorkerHeader, "worker-1")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want %d", token, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}